@@ -58,6 +58,17 @@ const (
 	ErrTemplateNotFound                = Error("template not found")
 	ErrMLNxRstNotFound                 = Error("MLNxRet not found")
 	ErrDLNxRstNotFound                 = Error("DLNxRet not found")
+	ErrRevisionNotFound                = Error("revision not found")
+	ErrTrashItemNotFound               = Error("trash item not found")
+	ErrTrashRestoreConflict            = Error("a resource with the same ID already exists")
+	ErrVersionConflict                 = Error("resource has been modified since it was read")
+	ErrJobNotFound                     = Error("job not found")
+	ErrOrgTemplateNotFound             = Error("organization template not found")
+	ErrProtoboardReadOnly              = Error("protoboard is read-only")
+	ErrReportNotFound                  = Error("report not found")
+	ErrSnapshotNotFound                = Error("snapshot not found")
+	ErrFolderNotFound                  = Error("folder not found")
+	ErrFolderForbidden                 = Error("role is not allowed by the folder permissions")
 )
 
 // Error is a domain error encountered while processing CloudHub requests
@@ -526,6 +537,35 @@ type UserQuery struct {
 	Scheme   *string
 }
 
+// PageQuery selects a page of the records of a store, in the order they are stored
+type PageQuery[T any] struct {
+	Limit int          // Limit is the maximum number of records of the page, all of them when zero
+	After string       // After is the ID of the last record of the previous page, none for the first page
+	Match func(T) bool // Match selects the records of the page, all of them when nil
+}
+
+// PageOf returns the page q selects of the items of a store holding its
+// records in memory, in the order of the items. The page of a missing After
+// item is empty.
+func PageOf[T any](items []T, q PageQuery[T], id func(T) string) []T {
+	page := []T{}
+	started := q.After == ""
+	for _, item := range items {
+		if !started {
+			started = id(item) == q.After
+			continue
+		}
+		if q.Match != nil && !q.Match(item) {
+			continue
+		}
+		if q.Limit > 0 && len(page) == q.Limit {
+			break
+		}
+		page = append(page, item)
+	}
+	return page
+}
+
 // UsersStore is the Storage and retrieval of authentication information
 //
 // While not necessary for the app to function correctly, it is
@@ -535,6 +575,8 @@ type UserQuery struct {
 type UsersStore interface {
 	// All lists all users from the UsersStore
 	All(context.Context) ([]User, error)
+	// Page lists a page of the users of the UsersStore
+	Page(context.Context, PageQuery[User]) ([]User, error)
 	// Create a new User in the UsersStore
 	Add(context.Context, *User) (*User, error)
 	// Delete the User from the UsersStore
@@ -668,6 +710,10 @@ type Dashboard struct {
 	Templates    []Template      `json:"templates"`
 	Name         string          `json:"name"`
 	Organization string          `json:"organization"` // Organization is the organization ID that resource belongs to
+	Version      uint64          `json:"version"`      // Version is incremented by the store on each update and used for optimistic concurrency
+	Folder       string          `json:"folder"`       // Folder is the ID of the folder of the dashboard, none at the root
+	Tags         []string        `json:"tags"`         // Tags are free-form labels of the dashboard
+	Protoboard   string          `json:"protoboard"`   // Protoboard is the ID of the protoboard the dashboard was created from, if any
 }
 
 // UnmarshalJSON unmarshals a string ID into a DashboardID (int).
@@ -797,6 +843,8 @@ type DecimalPlaces struct {
 type DashboardsStore interface {
 	// All lists all dashboards from the DashboardStore
 	All(context.Context) ([]Dashboard, error)
+	// Page lists a page of the dashboards of the DashboardStore
+	Page(context.Context, PageQuery[Dashboard]) ([]Dashboard, error)
 	// Create a new Dashboard in the DashboardStore
 	Add(context.Context, Dashboard) (Dashboard, error)
 	// Delete the Dashboard from the DashboardStore if `ID` exists.
@@ -807,6 +855,38 @@ type DashboardsStore interface {
 	Update(context.Context, Dashboard) error
 }
 
+// Folder groups the dashboards of an organization. Folders nest within their
+// parent folder and their permissions apply to the dashboards within them.
+type Folder struct {
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Parent       string            `json:"parent"`       // Parent is the ID of the folder containing this one, none at the root
+	Organization string            `json:"organization"` // Organization is the organization ID that resource belongs to
+	Permissions  FolderPermissions `json:"permissions"`  // Permissions restrict the access to the dashboards of the folder
+}
+
+// FolderPermissions are the lowest roles allowed to view and to edit the dashboards
+// of a folder. An empty role is inherited from the parent folder; at the root, the
+// role of the user in the organization is enough.
+type FolderPermissions struct {
+	View string `json:"view"` // View is the lowest role viewing the dashboards, e.g. editor
+	Edit string `json:"edit"` // Edit is the lowest role editing the dashboards, e.g. admin
+}
+
+// FoldersStore is the storage and retrieval of dashboard folders
+type FoldersStore interface {
+	// All lists all folders in the store
+	All(context.Context) ([]Folder, error)
+	// Add creates a new folder in the store and returns it with its ID
+	Add(context.Context, *Folder) (*Folder, error)
+	// Get retrieves a folder if `ID` exists
+	Get(ctx context.Context, ID string) (*Folder, error)
+	// Update replaces the folder
+	Update(context.Context, *Folder) error
+	// Delete removes the folder from the store
+	Delete(context.Context, *Folder) error
+}
+
 // Cell is a rectangle and multiple time series queries to visualize.
 type Cell struct {
 	X            int32            `json:"x"`
@@ -915,12 +995,19 @@ type Protoboard struct {
 	Data ProtoboardData `json:"data"`
 }
 
-// ProtoboardsStore stores protoboards that can be instantiated into dashboards
+// ProtoboardsStore stores protoboards that can be instantiated into dashboards.
+// Protoboards shipped with CloudHub are read-only.
 type ProtoboardsStore interface {
 	// All returns all protoboards in the store
 	All(context.Context) ([]Protoboard, error)
+	// Add creates a new protoboard in the store and returns it with its ID
+	Add(context.Context, Protoboard) (Protoboard, error)
+	// Delete removes the protoboard from the store
+	Delete(context.Context, Protoboard) error
 	// Get returns the specified protoboard from the store
 	Get(ctx context.Context, ID string) (Protoboard, error)
+	// Update replaces the protoboard in the store
+	Update(context.Context, Protoboard) error
 }
 
 // MappingWildcard is the wildcard value for mappings
@@ -971,6 +1058,20 @@ type Organization struct {
 	Name string `json:"name"`
 	// DefaultRole is the name of the role that is the default for any users added to the organization
 	DefaultRole string `json:"defaultRole,omitempty"`
+	// Quotas limits the resources of the organization; nil means unlimited
+	Quotas *OrganizationQuotas `json:"quotas,omitempty"`
+}
+
+// OrganizationQuotas are the maximum numbers of resources an organization may hold.
+// A zero quota is unlimited.
+type OrganizationQuotas struct {
+	Dashboards        int `json:"dashboards"`
+	CellsPerDashboard int `json:"cellsPerDashboard"`
+	Sources           int `json:"sources"`
+	NetworkDevices    int `json:"networkDevices"`
+	LearnedDevices    int `json:"learnedDevices"`
+	Topologies        int `json:"topologies"`
+	TerminalSessions  int `json:"terminalSessions"`
 }
 
 // OrganizationQuery represents the attributes that a organization may be retrieved by.
@@ -1128,6 +1229,7 @@ type Topology struct {
 	Diagram         string          `json:"diagram,string,omitempty"`  // diagram xml
 	Preferences     []string        `json:"preferences,omitempty"`     // User preferences
 	TopologyOptions TopologyOptions `json:"topologyOptions,omitempty"` // Configuration options for the topology, defined in TopologyOptions
+	Version         uint64          `json:"version"`                   // Version is incremented by the store on each update and used for optimistic concurrency
 }
 
 // TopologyOptions represents various settings for displaying elements of the topology.
@@ -1204,6 +1306,8 @@ type CSP struct {
 type CSPStore interface {
 	// All lists all CSP from the CSPStore
 	All(context.Context) ([]CSP, error)
+	// Page lists a page of the CSP of the CSPStore
+	Page(context.Context, PageQuery[CSP]) ([]CSP, error)
 	// Create a new CSP in the CSPStore
 	Add(context.Context, *CSP) (*CSP, error)
 	// Delete the CSP from the CSPStore
@@ -1214,6 +1318,328 @@ type CSPStore interface {
 	Update(context.Context, *CSP) error
 }
 
+// The kinds of resources that keep a revision history.
+const (
+	RevisionDashboard = "dashboard"
+	RevisionTopology  = "topology"
+)
+
+// Revision is an immutable snapshot of a resource stored each time the resource is updated
+type Revision struct {
+	ID           string    `json:"id"`
+	ResourceType string    `json:"resourceType"` // ResourceType is the kind of the revisioned resource, e.g. dashboard or topology
+	ResourceID   string    `json:"resourceID"`   // ResourceID is the ID of the revisioned resource
+	Organization string    `json:"organization"` // Organization is the organization ID that resource belongs to
+	Author       string    `json:"author"`       // Author is the name of the user who made the change
+	Message      string    `json:"message"`      // Message describes the change
+	CreatedAt    time.Time `json:"createdAt"`    // CreatedAt is the time the revision was stored
+	Content      []byte    `json:"-"`            // Content is the JSON encoded resource at this revision
+}
+
+// RevisionQuery represents the resource whose revisions are retrieved.
+// It is predominantly used in the RevisionsStore methods.
+type RevisionQuery struct {
+	ResourceType string
+	ResourceID   string
+}
+
+// RevisionsStore is the storage and retrieval of resource revisions.
+// Revisions are added by the stores of revisioned resources on update
+// and are never modified afterwards.
+type RevisionsStore interface {
+	// All lists the revisions of a resource, oldest first
+	All(ctx context.Context, q RevisionQuery) ([]Revision, error)
+	// Add stores a new revision of a resource
+	Add(context.Context, *Revision) (*Revision, error)
+	// Get retrieves a revision of a resource if `ID` exists
+	Get(ctx context.Context, q RevisionQuery, ID string) (*Revision, error)
+}
+
+// RevisionInfo describes who made a change to a revisioned resource and why
+type RevisionInfo struct {
+	Author  string
+	Message string
+}
+
+type revisionContextKey string
+
+// RevisionContextKey is the context key for the RevisionInfo recorded
+// with the revisions created while handling a request
+const RevisionContextKey = revisionContextKey("revision")
+
+// The kinds of resources moved to the trash when deleted.
+const (
+	TrashDashboard     = "dashboard"
+	TrashTopology      = "topology"
+	TrashNetworkDevice = "network_device"
+	TrashCSP           = "csp"
+)
+
+// TrashItem is a deleted resource kept in the trash of its organization until
+// it is restored or purged
+type TrashItem struct {
+	ID           string    `json:"id"`
+	ResourceType string    `json:"resourceType"` // ResourceType is the kind of the deleted resource, e.g. dashboard or csp
+	ResourceID   string    `json:"resourceID"`   // ResourceID is the ID of the deleted resource
+	Organization string    `json:"organization"` // Organization is the organization ID that resource belonged to
+	Name         string    `json:"name"`         // Name is a human readable name of the deleted resource
+	DeletedBy    string    `json:"deletedBy"`    // DeletedBy is the name of the user who deleted the resource
+	DeletedAt    time.Time `json:"deletedAt"`    // DeletedAt is the time the resource was deleted
+	Folder       string    `json:"folder"`       // Folder is the folder ID of a deleted dashboard
+	Content      []byte    `json:"-"`            // Content is the stored value of the resource at deletion
+}
+
+// TrashStore is the storage and retrieval of deleted resources.
+// Items are added by the stores of trashable resources on delete.
+type TrashStore interface {
+	// All lists the items in the trash, oldest first
+	All(context.Context) ([]TrashItem, error)
+	// Get retrieves a trash item if `ID` exists
+	Get(ctx context.Context, ID string) (*TrashItem, error)
+	// Restore puts the resource back into its store and removes the item from the trash
+	Restore(context.Context, *TrashItem) error
+	// Delete permanently removes an item from the trash
+	Delete(context.Context, *TrashItem) error
+	// Purge permanently removes the items deleted before `before` and returns their number
+	Purge(ctx context.Context, before time.Time) (int, error)
+}
+
+type trashContextKey string
+
+// TrashContextKey is the context key for the name of the user
+// recorded as the deleter of the resources trashed by a request
+const TrashContextKey = trashContextKey("trash")
+
+// Severities of integrity issues
+const (
+	// IntegritySeverityError is a reference to a resource that does not exist
+	IntegritySeverityError = "error"
+	// IntegritySeverityWarning is a resource whose owning organization does not exist
+	IntegritySeverityWarning = "warning"
+)
+
+// IntegrityIssue is a dangling reference or an orphaned resource found in the stores
+type IntegrityIssue struct {
+	Severity     string `json:"severity"`               // Severity is either IntegritySeverityError or IntegritySeverityWarning
+	ResourceType string `json:"resourceType"`           // ResourceType is the kind of resource holding the reference, e.g. "user"
+	ResourceID   string `json:"resourceID"`             // ResourceID is the ID of the resource holding the reference
+	Field        string `json:"field"`                  // Field is the name of the referencing field
+	Reference    string `json:"reference"`              // Reference is the ID that cannot be resolved
+	Message      string `json:"message"`                // Message describes the issue
+	Repair       string `json:"repair"`                 // Repair describes what a repair does about the issue
+	Repaired     bool   `json:"repaired"`               // Repaired is true once the repair has been applied
+	Organization string `json:"organization,omitempty"` // Organization is the organization the resource belongs to, if any
+}
+
+// IntegrityChecker scans the stores for references between resources that cannot be resolved
+type IntegrityChecker interface {
+	// Check reports the dangling references and orphaned resources without changing anything
+	Check(context.Context) ([]IntegrityIssue, error)
+	// Repair removes or re-links the dangling references and orphaned resources
+	// in a single transaction and reports what was repaired
+	Repair(context.Context) ([]IntegrityIssue, error)
+}
+
+// Job statuses, also used for the status of job steps
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// JobOrganizationDeletion is the type of jobs deleting an organization with everything it owns
+const JobOrganizationDeletion = "organization_deletion"
+
+// JobStep is a single unit of work of a Job that can be retried on its own
+type JobStep struct {
+	ID          int    `json:"id"`              // ID is the position of the step in the job
+	Kind        string `json:"kind"`            // Kind is the system the step acts on, e.g. "store" or "kapacitor"
+	Resource    string `json:"resource"`        // Resource is the kind of resource the step acts on
+	Target      string `json:"target"`          // Target identifies what the step acts on within its kind
+	Description string `json:"description"`     // Description is a human readable summary of the step
+	Status      string `json:"status"`          // Status is one of the job statuses
+	Error       string `json:"error,omitempty"` // Error is the error of the last failed attempt
+	Attempts    int    `json:"attempts"`        // Attempts is the number of times the step has been run
+}
+
+// Job is a tracked long-running operation made of steps
+type Job struct {
+	ID           string    `json:"id"`           // ID is the unique ID of the job
+	Type         string    `json:"type"`         // Type is the kind of job, e.g. JobOrganizationDeletion
+	Organization string    `json:"organization"` // Organization is the organization ID the job acts on
+	Name         string    `json:"name"`         // Name is a human readable name of what the job acts on
+	Status       string    `json:"status"`       // Status is one of the job statuses
+	CreatedBy    string    `json:"createdBy"`    // CreatedBy is the name of the user who started the job
+	CreatedAt    time.Time `json:"createdAt"`    // CreatedAt is the time the job was created
+	UpdatedAt    time.Time `json:"updatedAt"`    // UpdatedAt is the time the job was last updated
+	Steps        []JobStep `json:"steps"`        // Steps are run in order
+}
+
+// JobsStore is the storage and retrieval of tracked jobs
+type JobsStore interface {
+	// All lists all jobs in the store, oldest first
+	All(context.Context) ([]Job, error)
+	// Add creates a new job in the store and returns it with its ID
+	Add(context.Context, *Job) (*Job, error)
+	// Get retrieves a job if `ID` exists
+	Get(ctx context.Context, ID string) (*Job, error)
+	// Update replaces the job
+	Update(context.Context, *Job) error
+	// Delete removes the job from the store
+	Delete(context.Context, *Job) error
+}
+
+// OrgTemplate is a saved snapshot of the configuration of an organization
+// from which new organizations are instantiated. The resources keep the IDs they
+// had in the organization they were saved from so that references between them
+// can be remapped to the IDs of the new organization.
+type OrgTemplate struct {
+	ID               string              `json:"id"`                         // ID is the unique ID of the template
+	Name             string              `json:"name"`                       // Name is the user-facing name of the template
+	Organization     string              `json:"organization"`               // Organization is the ID of the organization the template was saved from
+	DefaultRole      string              `json:"defaultRole"`                // DefaultRole is the default role of the instantiated organizations
+	Quotas           *OrganizationQuotas `json:"quotas,omitempty"`           // Quotas are the quotas of the instantiated organizations
+	Sources          []Source            `json:"sources"`                    // Sources are the data sources of the organization
+	Dashboards       []Dashboard         `json:"dashboards"`                 // Dashboards are the dashboards of the organization
+	Topologies       []Topology          `json:"topologies"`                 // Topologies are the topologies of the organization
+	LogViewer        LogViewerConfig     `json:"logViewer"`                  // LogViewer is the log viewer configuration of the organization
+	NetworkDeviceOrg *NetworkDeviceOrg   `json:"networkDeviceOrg,omitempty"` // NetworkDeviceOrg are the network device settings of the organization, if any
+	CreatedBy        string              `json:"createdBy"`                  // CreatedBy is the name of the user who saved the template
+	CreatedAt        time.Time           `json:"createdAt"`                  // CreatedAt is the time the template was saved
+}
+
+// OrgTemplatesStore is the storage and retrieval of organization templates
+type OrgTemplatesStore interface {
+	// All lists all templates in the store
+	All(context.Context) ([]OrgTemplate, error)
+	// Add creates a new template in the store and returns it with its ID
+	Add(context.Context, *OrgTemplate) (*OrgTemplate, error)
+	// Get retrieves a template if `ID` exists
+	Get(ctx context.Context, ID string) (*OrgTemplate, error)
+	// Delete removes the template from the store
+	Delete(context.Context, *OrgTemplate) error
+}
+
+// Report formats
+const (
+	ReportHTML = "html"
+	ReportPDF  = "pdf"
+)
+
+// Report run triggers
+const (
+	ReportTriggerSchedule = "schedule"
+	ReportTriggerManual   = "manual"
+)
+
+// MaxReportRuns is the number of runs kept in the history of a report
+const MaxReportRuns = 20
+
+// ReportRun is a single run of a report
+type ReportRun struct {
+	Time    time.Time `json:"time"`            // Time is the time the run started
+	Trigger string    `json:"trigger"`         // Trigger is either ReportTriggerSchedule or ReportTriggerManual
+	Status  string    `json:"status"`          // Status is either JobSucceeded or JobFailed
+	Error   string    `json:"error,omitempty"` // Error is the reason the run failed
+}
+
+// Report is a dashboard rendered on a schedule and delivered by e-mail or webhook
+type Report struct {
+	ID           string            `json:"id"`           // ID is the unique ID of the report
+	Name         string            `json:"name"`         // Name is the user-facing name of the report
+	Organization string            `json:"organization"` // Organization is the organization ID the report belongs to
+	Dashboard    DashboardID       `json:"dashboard"`    // Dashboard is the ID of the dashboard rendered by the report
+	Variables    map[string]string `json:"variables"`    // Variables are the values of the template variables of the dashboard, by variable, e.g. :host:
+	TimeRange    string            `json:"timeRange"`    // TimeRange is the duration before the run the report covers, e.g. 24h
+	Schedule     string            `json:"schedule"`     // Schedule is a cron expression, e.g. 0 8 * * MON
+	TimeZone     string            `json:"timeZone"`     // TimeZone is the time zone of the schedule and of the times in the report
+	Format       string            `json:"format"`       // Format is either ReportHTML or ReportPDF
+	Recipients   []string          `json:"recipients"`   // Recipients are the e-mail addresses the report is sent to
+	Webhook      string            `json:"webhook"`      // Webhook is the URL the report is posted to
+	CreatedBy    string            `json:"createdBy"`    // CreatedBy is the name of the user who created the report
+	CreatedAt    time.Time         `json:"createdAt"`    // CreatedAt is the time the report was created
+	NextRun      time.Time         `json:"nextRun"`      // NextRun is the next time of the schedule
+	Runs         []ReportRun       `json:"runs"`         // Runs are the last MaxReportRuns runs of the report, oldest first
+}
+
+// ReportsStore is the storage and retrieval of reports
+type ReportsStore interface {
+	// All lists all reports in the store
+	All(context.Context) ([]Report, error)
+	// Add creates a new report in the store and returns it with its ID
+	Add(context.Context, *Report) (*Report, error)
+	// Get retrieves a report if `ID` exists
+	Get(ctx context.Context, ID string) (*Report, error)
+	// Update replaces the report
+	Update(context.Context, *Report) error
+	// Delete removes the report from the store
+	Delete(context.Context, *Report) error
+}
+
+// SnapshotCell holds the results of the queries of a cell of a snapshot
+type SnapshotCell struct {
+	CellID  string            `json:"cellID"`          // CellID is the ID of the cell in the dashboard of the snapshot
+	Results []json.RawMessage `json:"results"`         // Results are the InfluxQL results of the queries of the cell, in order, with times in epoch milliseconds
+	Error   string            `json:"error,omitempty"` // Error is the reason the results of the cell could not be captured
+}
+
+// Snapshot is an immutable copy of a dashboard with the results of its queries
+// over a time range. It is shared read-only through a secret token.
+type Snapshot struct {
+	ID           string         `json:"id"`           // ID is the unique ID of the snapshot
+	Name         string         `json:"name"`         // Name is the user-facing name of the snapshot
+	Organization string         `json:"organization"` // Organization is the organization ID the snapshot belongs to
+	Dashboard    Dashboard      `json:"dashboard"`    // Dashboard is the dashboard as it was captured
+	Lower        time.Time      `json:"lower"`        // Lower is the start of the time range of the results
+	Upper        time.Time      `json:"upper"`        // Upper is the end of the time range of the results
+	Cells        []SnapshotCell `json:"cells"`        // Cells are the results of the cells of the dashboard
+	TokenHash    string         `json:"-"`            // TokenHash is the hex SHA-256 hash of the token of the share link
+	CreatedBy    string         `json:"createdBy"`    // CreatedBy is the name of the user who captured the snapshot
+	CreatedAt    time.Time      `json:"createdAt"`    // CreatedAt is the time the snapshot was captured
+	ExpiresAt    time.Time      `json:"expiresAt"`    // ExpiresAt is the time the share link stops working
+}
+
+// SnapshotsStore is the storage and retrieval of dashboard snapshots
+type SnapshotsStore interface {
+	// All lists all snapshots in the store, without the results of their cells
+	All(context.Context) ([]Snapshot, error)
+	// Add creates a new snapshot in the store and returns it with its ID
+	Add(context.Context, *Snapshot) (*Snapshot, error)
+	// Get retrieves a snapshot if `ID` exists
+	Get(ctx context.Context, ID string) (*Snapshot, error)
+	// GetByTokenHash retrieves the snapshot of the share link whose token has the hash
+	GetByTokenHash(ctx context.Context, hash string) (*Snapshot, error)
+	// Delete removes the snapshot from the store, revoking its share link
+	Delete(context.Context, *Snapshot) error
+}
+
+// Coordinator coordinates the background work of the CloudHub instances sharing
+// a store so that scheduled and long-running work runs exactly once across them.
+type Coordinator interface {
+	// Lock blocks until the named lock is held or ctx is done and returns the function releasing it
+	Lock(ctx context.Context, name string) (unlock func(), err error)
+	// TryLock acquires the named lock unless it is held elsewhere, in which case ok is false
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
+	// Campaign blocks until this instance is elected the leader of the named election or ctx is done.
+	// The returned context is canceled once the leadership is lost and resign gives it up.
+	Campaign(ctx context.Context, name string) (leaderCtx context.Context, resign func(), err error)
+}
+
+// Change is a resource added, updated or removed from the store
+type Change struct {
+	Resource string // Resource is the kind of resource changed, e.g. dashboards
+	ID       string // ID is the ID of the resource changed
+	Deleted  bool   // Deleted is true if the resource was removed
+}
+
+// ChangeFeed publishes the changes committed to the store by this instance
+type ChangeFeed interface {
+	// Subscribe calls fn with each change once committed until unsubscribe is called.
+	// fn is called by the goroutine writing to the store and must not block.
+	Subscribe(fn func(Change)) (unsubscribe func())
+}
+
 // KVClient defines what each kv store should be capable of.
 type KVClient interface {
 	// ConfigStore returns the kv's ConfigStore type.
@@ -1244,6 +1670,26 @@ type KVClient interface {
 	NetworkDeviceOrgStore() NetworkDeviceOrgStore
 	// MLNxRstStore returns the kv's MLNxRstStore type.
 	MLNxRstStore() MLNxRstStore
+	// RevisionsStore returns the kv's RevisionsStore type.
+	RevisionsStore() RevisionsStore
+	// TrashStore returns the kv's TrashStore type.
+	TrashStore() TrashStore
+	// IntegrityChecker returns the kv's IntegrityChecker type.
+	IntegrityChecker() IntegrityChecker
+	// JobsStore returns the kv's JobsStore type.
+	JobsStore() JobsStore
+	// OrgTemplatesStore returns the kv's OrgTemplatesStore type.
+	OrgTemplatesStore() OrgTemplatesStore
+	// ProtoboardsStore returns the kv's ProtoboardsStore type.
+	ProtoboardsStore() ProtoboardsStore
+	// ReportsStore returns the kv's ReportsStore type.
+	ReportsStore() ReportsStore
+	// SnapshotsStore returns the kv's SnapshotsStore type.
+	SnapshotsStore() SnapshotsStore
+	// FoldersStore returns the kv's FoldersStore type.
+	FoldersStore() FoldersStore
+	// ChangeFeed returns the kv's ChangeFeed type.
+	ChangeFeed() ChangeFeed
 }
 
 // NetworkDeviceOrgQuery represents the attributes that a networkDeviceOrg may be retrieved by.
@@ -1288,6 +1734,7 @@ type NetworkDeviceOrg struct {
 	AIKapacitor         AIKapacitor `json:"ai_kapacitor"`
 	LearningCron        string      `json:"learning_cron"`
 	ProcCnt             int         `json:"process_count"`
+	Version             uint64      `json:"version"` // Version is incremented by the store on each update and used for optimistic concurrency
 }
 
 // NetworkDeviceOrgStore is the Storage and retrieval of information
@@ -1310,13 +1757,14 @@ var DeviceCategoryMap = map[string]string{
 }
 
 // NetworkDeviceQuery represents the attributes that a NetworkDevice may be retrieved by.
-// It is predominantly used in the NetworkDeviceStore.Get method.
+// It is predominantly used in the NetworkDeviceStore.Get and Find methods.
 //
-// It is expected that only one of ID or Organization will be
-// specified, but all are provided NetworkDeviceStore should prefer ID.
+// Get expects one of ID or DeviceIP to be specified and prefers ID.
+// Find returns the NetworkDevices matching every attribute specified.
 type NetworkDeviceQuery struct {
 	ID           *string
 	Organization *string
+	DeviceIP     *string
 }
 
 // SSHConfig is Connection Config
@@ -1371,6 +1819,8 @@ type NetworkDeviceStore interface {
 
 	Get(ctx context.Context, q NetworkDeviceQuery) (*NetworkDevice, error)
 
+	Find(ctx context.Context, q NetworkDeviceQuery) ([]NetworkDevice, error)
+
 	Update(context.Context, *NetworkDevice) error
 }
 
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// sessionCookie is the name of the cookie holding the session token of the server
+const sessionCookie = "session"
+
+// apiClient performs requests against the /cloudhub/v1 API of a running server
+type apiClient struct {
+	url    *url.URL
+	client *http.Client
+}
+
+// newAPIClient authenticates to the server of the options with the session token
+// or by a basic login, and switches to the organization of the options.
+func newAPIClient(ctx context.Context, opts *Options) (*apiClient, error) {
+	u, err := url.Parse(opts.Server)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid server url '%s', must begin with http:// or https://", opts.Server)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	c := &apiClient{
+		url: u,
+		client: &http.Client{
+			Jar:     jar,
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify},
+			},
+		},
+	}
+
+	switch {
+	case opts.Token != "":
+		jar.SetCookies(u, []*http.Cookie{{Name: sessionCookie, Value: opts.Token}})
+	case opts.Username != "":
+		if err := c.login(ctx, opts.Username, opts.Password); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("either --token or --username must be given to use a server")
+	}
+
+	if opts.Organization != "" {
+		req := map[string]string{"organization": opts.Organization}
+		if err := c.do(ctx, http.MethodPut, "/cloudhub/v1/me", req, nil); err != nil {
+			return nil, fmt.Errorf("unable to switch to organization %s: %s", opts.Organization, err)
+		}
+	}
+	return c, nil
+}
+
+// login authenticates as a basic user; the session cookie is kept by the cookie jar
+func (c *apiClient) login(ctx context.Context, name, password string) error {
+	req := map[string]string{"name": name, "password": password}
+	var res struct {
+		PasswordResetFlag string `json:"passwordResetFlag"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/basic/login", req, &res); err != nil {
+		return fmt.Errorf("unable to log in as %s: %s", name, err)
+	}
+	if res.PasswordResetFlag == "Y" {
+		return fmt.Errorf("the password of %s must be reset before logging in", name)
+	}
+	return nil
+}
+
+// do sends body as JSON to the path and decodes the response into v, if v is not nil.
+// The path may be a link returned by the server.
+func (c *apiClient) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.endpoint(path), r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	data, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		var e struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &e) == nil && e.Message != "" {
+			return fmt.Errorf("%s %s: %s (%d)", method, path, e.Message, res.StatusCode)
+		}
+		return fmt.Errorf("%s %s: %s", method, path, res.Status)
+	}
+
+	if v == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+// endpoint returns the URL of the path. Links returned by the server already contain
+// the base path the server is mounted at.
+func (c *apiClient) endpoint(path string) string {
+	u := *c.url
+	if c.url.Path == "" || !strings.HasPrefix(path, c.url.Path+"/") {
+		path = c.url.Path + path
+	}
+	u.Path = path
+	return u.String()
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func init() {
+	parser.AddCommand("export-dashboard",
+		"Export a dashboard",
+		"The export-dashboard command writes a dashboard in the file format of the dashboard export of the UI",
+		&exportDashboardCommand{})
+	parser.AddCommand("import-dashboard",
+		"Import a dashboard",
+		"The import-dashboard command creates a dashboard from a file exported by the UI or by export-dashboard",
+		&importDashboardCommand{})
+}
+
+// dashboardFile is the file format of exported dashboards
+type dashboardFile struct {
+	Meta      dashboardFileMeta `json:"meta"`
+	Dashboard json.RawMessage   `json:"dashboard"`
+}
+
+type dashboardFileMeta struct {
+	Sources map[string]dashboardFileSource `json:"sources"`
+}
+
+// dashboardFileSource lets the sources of the queries be identified on import
+type dashboardFileSource struct {
+	Name string `json:"name"`
+	Link string `json:"link"`
+}
+
+type exportDashboardCommand struct {
+	dbOptions
+	File string `short:"f" long:"file" description:"File to write the dashboard to, stdout by default"`
+	Args struct {
+		ID string `positional-arg-name:"id" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+func (c *exportDashboardCommand) Execute(args []string) error {
+	ctx := context.Background()
+
+	var file *dashboardFile
+	var err error
+	if options.Server != "" {
+		file, err = c.remote(ctx)
+	} else {
+		file, err = c.local(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if c.File == "" {
+		_, err = fmt.Println(string(data))
+		return err
+	}
+	return os.WriteFile(c.File, append(data, '\n'), 0600)
+}
+
+func (c *exportDashboardCommand) local(ctx context.Context) (*dashboardFile, error) {
+	svc, err := c.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer svc.Close()
+
+	id, err := strconv.Atoi(c.Args.ID)
+	if err != nil {
+		return nil, cloudhub.ErrDashboardNotFound
+	}
+	d, err := svc.DashboardsStore().Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		return nil, err
+	}
+	dashboard, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	srcs, err := svc.SourcesStore().All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sources := map[string]dashboardFileSource{}
+	for _, src := range srcs {
+		id := strconv.Itoa(src.ID)
+		sources[id] = dashboardFileSource{Name: src.Name, Link: "/cloudhub/v1/sources/" + id}
+	}
+
+	return &dashboardFile{
+		Meta:      dashboardFileMeta{Sources: sources},
+		Dashboard: dashboard,
+	}, nil
+}
+
+func (c *exportDashboardCommand) remote(ctx context.Context) (*dashboardFile, error) {
+	client, err := newAPIClient(ctx, &options)
+	if err != nil {
+		return nil, err
+	}
+
+	var dashboard json.RawMessage
+	if err := client.do(ctx, http.MethodGet, "/cloudhub/v1/dashboards/"+url.PathEscape(c.Args.ID), nil, &dashboard); err != nil {
+		return nil, err
+	}
+
+	srcs, err := collection(ctx, client, "/cloudhub/v1/sources", "sources", "id")
+	if err != nil {
+		return nil, err
+	}
+	sources := map[string]dashboardFileSource{}
+	for _, raw := range srcs {
+		var src struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Links struct {
+				Self string `json:"self"`
+			} `json:"links"`
+		}
+		if err := json.Unmarshal(raw, &src); err != nil {
+			return nil, err
+		}
+		sources[src.ID] = dashboardFileSource{Name: src.Name, Link: src.Links.Self}
+	}
+
+	return &dashboardFile{
+		Meta:      dashboardFileMeta{Sources: sources},
+		Dashboard: dashboard,
+	}, nil
+}
+
+type importDashboardCommand struct {
+	resourceOptions
+	documentOptions
+	Source string `long:"source" description:"ID of the source to switch all queries of the dashboard to"`
+}
+
+func (c *importDashboardCommand) Execute(args []string) error {
+	data, err := c.read()
+	if err != nil {
+		return err
+	}
+
+	var doc map[string]interface{}
+	if err := decodeDocument(data, &doc); err != nil {
+		return err
+	}
+	// files exported by the UI hold the dashboard next to its meta data
+	if d, ok := doc["dashboard"].(map[string]interface{}); ok {
+		doc = d
+	}
+	delete(doc, "id")
+	delete(doc, "links")
+	if c.Source != "" {
+		switchQuerySources(doc, "/cloudhub/v1/sources/"+c.Source)
+	}
+
+	return c.run("dashboards", func(ctx context.Context, res resources, p *printer) error {
+		if options.Server == "" {
+			if options.Organization != "" {
+				doc["organization"] = options.Organization
+			} else if org, _ := doc["organization"].(string); org == "" {
+				doc["organization"] = "default"
+			}
+		}
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		return res.create(ctx, data, p)
+	})
+}
+
+// switchQuerySources sets the source of the queries of all cells of the dashboard document
+func switchQuerySources(dashboard map[string]interface{}, link string) {
+	cells, _ := dashboard["cells"].([]interface{})
+	for _, cell := range cells {
+		cell, ok := cell.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		queries, _ := cell["queries"].([]interface{})
+		for _, query := range queries {
+			if query, ok := query.(map[string]interface{}); ok {
+				query["source"] = link
+			}
+		}
+	}
+}
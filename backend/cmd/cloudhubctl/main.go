@@ -9,6 +9,12 @@ import (
 
 // Options Flags
 type Options struct {
+	Server             string `long:"server" description:"URL of a running CloudHub server (e.g. 'https://cloudhub.example'); resources are then managed through its API instead of the db" env:"CLOUDHUB_SERVER"`
+	Token              string `long:"token" description:"Session token of the server, the value of the 'session' cookie" env:"CLOUDHUB_TOKEN"`
+	Username           string `long:"username" description:"Name of the basic user to log in to the server" env:"CLOUDHUB_USERNAME"`
+	Password           string `long:"password" description:"Password of the basic user" env:"CLOUDHUB_PASSWORD"`
+	Organization       string `long:"org" description:"ID of the organization to act in; switches the current organization on the server and is the organization of imported dashboards"`
+	InsecureSkipVerify bool   `long:"insecure-skip-verify" description:"Accept any certificate presented by the server"`
 }
 
 var options Options
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// kind is a type of resource of a manifest
+type kind struct {
+	name string // name of the kind in plans
+	key  string // key of the resources of the kind in their parent
+	// single is set if the parent holds one resource of the kind rather than a list
+	single bool
+	// identity returns the identifier of a resource within its parent, from its manifest
+	// entry or its live document
+	identity func(doc document) string
+	// secrets are write-only fields, which are set on create but not compared
+	secrets  []string
+	children []*kind
+}
+
+var (
+	ruleKind = &kind{
+		name:     "rule",
+		key:      "rules",
+		identity: fieldIdentity("name"),
+	}
+	serverKind = &kind{
+		name:     "server",
+		key:      "servers",
+		identity: fieldIdentity("name"),
+		secrets:  []string{"password"},
+		children: []*kind{ruleKind},
+	}
+	sourceKind = &kind{
+		name:     "source",
+		key:      "sources",
+		identity: fieldIdentity("name"),
+		secrets:  []string{"password", "sharedSecret"},
+		children: []*kind{serverKind},
+	}
+	mappingKind = &kind{
+		name:     "mapping",
+		key:      "mappings",
+		identity: fieldIdentity("provider", "scheme", "providerOrganization"),
+	}
+	dashboardKind = &kind{
+		name:     "dashboard",
+		key:      "dashboards",
+		identity: fieldIdentity("name"),
+	}
+	topologyKind = &kind{
+		name:     "topology",
+		key:      "topology",
+		single:   true,
+		identity: func(document) string { return "topology" },
+	}
+	deviceKind = &kind{
+		name:     "device",
+		key:      "devices",
+		identity: fieldIdentity("device_ip"),
+		secrets:  []string{"ssh_config", "snmp_config"},
+	}
+	// organizationKind is the root of manifests; sources come before dashboards,
+	// whose queries refer to them
+	organizationKind = &kind{
+		name:     "organization",
+		key:      "organizations",
+		identity: fieldIdentity("name"),
+		children: []*kind{mappingKind, sourceKind, dashboardKind, topologyKind, deviceKind},
+	}
+)
+
+// holds reports whether the resources of the kind c are children of resources of the kind
+func (k *kind) holds(c *kind) bool {
+	for _, child := range k.children {
+		if child == c {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldIdentity identifies resources by the values of the fields joined by ':'
+func fieldIdentity(fields ...string) func(doc document) string {
+	return func(doc document) string {
+		values := make([]string, len(fields))
+		empty := true
+		for i, f := range fields {
+			if v, ok := doc[f]; ok && v != nil {
+				values[i] = fmt.Sprint(v)
+				empty = empty && values[i] == ""
+			}
+		}
+		if empty {
+			return ""
+		}
+		return strings.Join(values, ":")
+	}
+}
+
+// node is a resource of a manifest
+type node struct {
+	kind *kind
+	// path is the external identifier of the resource, made of the identities of the
+	// resource and its parents, e.g. acme/sources/influx/servers/kapacitor
+	path     string
+	identity string
+	spec     document // spec are the fields of the resource without its children
+	children map[*kind][]*node
+}
+
+// readManifest reads a YAML, JSON or TOML manifest; the format is chosen by the file extension
+func readManifest(path string) ([]*node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %s", path, err)
+	}
+
+	// the manifest is normalized to JSON values, as the live documents are
+	var root document
+	if err := fromDocument(raw, &root); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %s", path, err)
+	}
+	for k := range root {
+		if k != organizationKind.key {
+			return nil, fmt.Errorf("invalid manifest %s: unknown key %q", path, k)
+		}
+	}
+	return manifestNodes(organizationKind, "", root[organizationKind.key])
+}
+
+// manifestNodes returns the resources of the kind from the value of its key in the parent
+func manifestNodes(k *kind, parent string, v interface{}) ([]*node, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	var entries []interface{}
+	if k.single {
+		entries = []interface{}{v}
+	} else if list, ok := v.([]interface{}); ok {
+		entries = list
+	} else {
+		return nil, fmt.Errorf("%s%s must be a list", parent, k.key)
+	}
+
+	nodes := []*node{}
+	seen := map[string]bool{}
+	for i, e := range entries {
+		spec, ok := e.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s%s[%d] must be a map", parent, k.key, i)
+		}
+
+		n := &node{
+			kind:     k,
+			identity: k.identity(spec),
+			spec:     spec,
+			children: map[*kind][]*node{},
+		}
+		if n.identity == "" {
+			return nil, fmt.Errorf("%s%s[%d] has no identifier", parent, k.key, i)
+		}
+		n.path = parent + n.identity
+		if k != organizationKind {
+			n.path = parent + k.key + "/" + n.identity
+		}
+		if k.single {
+			n.path = parent + k.key
+		}
+		if seen[n.identity] {
+			return nil, fmt.Errorf("%s is defined more than once", n.path)
+		}
+		seen[n.identity] = true
+
+		for _, child := range k.children {
+			children, err := manifestNodes(child, n.path+"/", spec[child.key])
+			if err != nil {
+				return nil, err
+			}
+			n.children[child] = children
+			delete(spec, child.key)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// matches reports whether the live value has all the values of the manifest. Missing
+// live fields match zero values, which are left out of the documents.
+func matches(spec, live interface{}) bool {
+	switch s := spec.(type) {
+	case map[string]interface{}:
+		l, _ := live.(map[string]interface{})
+		for k, v := range s {
+			if !matches(v, l[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		l, _ := live.([]interface{})
+		if len(s) != len(l) {
+			return false
+		}
+		for i := range s {
+			if !matches(s[i], l[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		if live == nil {
+			return isZero(spec)
+		}
+		a, _ := json.Marshal(spec)
+		b, _ := json.Marshal(live)
+		return string(a) == string(b)
+	}
+}
+
+func isZero(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case float64:
+		return v == 0
+	}
+	return false
+}
+
+// changes returns the fields of the manifest that differ from the live resource
+func changes(k *kind, spec, live document) []string {
+	fields := []string{}
+	for f, v := range spec {
+		if contains(k.secrets, f) {
+			continue
+		}
+		if !matches(v, live[f]) {
+			fields = append(fields, f)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeManifest writes the manifest to a file with the name in a temp directory
+func writeManifest(t *testing.T, name, manifest string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(manifest), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// paths returns the paths of the nodes and their children, depth first
+func paths(nodes []*node) []string {
+	ps := []string{}
+	for _, n := range nodes {
+		ps = append(ps, n.path)
+		for _, k := range n.kind.children {
+			ps = append(ps, paths(n.children[k])...)
+		}
+	}
+	return ps
+}
+
+func Test_readManifest(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		manifest  string
+		wantPaths []string
+		wantErr   string
+	}{
+		{
+			name: "yaml",
+			file: "cloudhub.yaml",
+			manifest: `
+organizations:
+  - name: acme
+    defaultRole: viewer
+    mappings:
+      - provider: github
+        scheme: oauth2
+        providerOrganization: acme
+    sources:
+      - name: influx
+        url: http://influxdb:8086
+        servers:
+          - name: kapacitor
+            url: http://kapacitor:9092
+            rules:
+              - name: cpu
+    dashboards:
+      - name: hosts
+        source: influx
+    topology:
+      diagram: "<mxGraphModel/>"
+`,
+			wantPaths: []string{
+				"acme",
+				"acme/mappings/github:oauth2:acme",
+				"acme/sources/influx",
+				"acme/sources/influx/servers/kapacitor",
+				"acme/sources/influx/servers/kapacitor/rules/cpu",
+				"acme/dashboards/hosts",
+				"acme/topology",
+			},
+		},
+		{
+			name:      "json",
+			file:      "cloudhub.json",
+			manifest:  `{"organizations": [{"name": "acme", "devices": [{"device_ip": "10.0.0.1"}]}]}`,
+			wantPaths: []string{"acme", "acme/devices/10.0.0.1"},
+		},
+		{
+			name: "toml",
+			file: "cloudhub.toml",
+			manifest: `
+[[organizations]]
+name = "acme"
+
+[[organizations.sources]]
+name = "influx"
+url = "http://influxdb:8086"
+`,
+			wantPaths: []string{"acme", "acme/sources/influx"},
+		},
+		{
+			name:      "empty organization",
+			file:      "cloudhub.yaml",
+			manifest:  "organizations:\n  - name: acme\n",
+			wantPaths: []string{"acme"},
+		},
+		{
+			name:     "unknown key",
+			file:     "cloudhub.yaml",
+			manifest: "organizations: []\nsources: []\n",
+			wantErr:  `unknown key "sources"`,
+		},
+		{
+			name:     "invalid yaml",
+			file:     "cloudhub.yaml",
+			manifest: "organizations: [",
+			wantErr:  "invalid manifest",
+		},
+		{
+			name:     "resources which are not a list",
+			file:     "cloudhub.yaml",
+			manifest: "organizations:\n  - name: acme\n    sources:\n      name: influx\n",
+			wantErr:  "acme/sources must be a list",
+		},
+		{
+			name:     "resource which is not a map",
+			file:     "cloudhub.yaml",
+			manifest: "organizations:\n  - acme\n",
+			wantErr:  "organizations[0] must be a map",
+		},
+		{
+			name:     "resource without identifier",
+			file:     "cloudhub.yaml",
+			manifest: "organizations:\n  - name: acme\n    dashboards:\n      - cells: []\n",
+			wantErr:  "acme/dashboards[0] has no identifier",
+		},
+		{
+			name:     "resource defined twice",
+			file:     "cloudhub.yaml",
+			manifest: "organizations:\n  - name: acme\n    sources:\n      - name: influx\n      - name: influx\n",
+			wantErr:  "acme/sources/influx is defined more than once",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := readManifest(writeManifest(t, tt.file, tt.manifest))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readManifest() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readManifest() error = %v", err)
+			}
+			if got := paths(nodes); strings.Join(got, ",") != strings.Join(tt.wantPaths, ",") {
+				t.Errorf("readManifest() paths = %v, want %v", got, tt.wantPaths)
+			}
+		})
+	}
+}
+
+// The children of the resources are not part of their spec
+func Test_readManifest_spec(t *testing.T) {
+	nodes, err := readManifest(writeManifest(t, "cloudhub.yaml", `
+organizations:
+  - name: acme
+    sources:
+      - name: influx
+        default: true
+        servers:
+          - name: kapacitor
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	source := nodes[0].children[sourceKind][0]
+	if _, ok := source.spec["servers"]; ok {
+		t.Errorf("spec of %s = %v, want it without servers", source.path, source.spec)
+	}
+	if source.spec["default"] != true || source.spec["name"] != "influx" {
+		t.Errorf("spec of %s = %v", source.path, source.spec)
+	}
+}
+
+func Test_matches(t *testing.T) {
+	tests := []struct {
+		name string
+		spec interface{}
+		live interface{}
+		want bool
+	}{
+		{name: "equal strings", spec: "influx", live: "influx", want: true},
+		{name: "different strings", spec: "influx", live: "telegraf", want: false},
+		{name: "numbers", spec: float64(3), live: float64(3), want: true},
+		{name: "missing zero value", spec: "", live: nil, want: true},
+		{name: "missing false", spec: false, live: nil, want: true},
+		{name: "missing value", spec: "influx", live: nil, want: false},
+		{name: "extra live fields", spec: map[string]interface{}{"name": "a"}, live: map[string]interface{}{"name": "a", "id": "1"}, want: true},
+		{name: "different nested field", spec: map[string]interface{}{"axes": map[string]interface{}{"y": "1"}}, live: map[string]interface{}{"axes": map[string]interface{}{"y": "2"}}, want: false},
+		{name: "lists", spec: []interface{}{"a", "b"}, live: []interface{}{"a", "b"}, want: true},
+		{name: "lists of different lengths", spec: []interface{}{"a"}, live: []interface{}{"a", "b"}, want: false},
+		{name: "lists in a different order", spec: []interface{}{"b", "a"}, live: []interface{}{"a", "b"}, want: false},
+	}
+	for _, tt := range tests {
+		if got := matches(tt.spec, tt.live); got != tt.want {
+			t.Errorf("matches() of %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_changes(t *testing.T) {
+	tests := []struct {
+		name string
+		kind *kind
+		spec document
+		live document
+		want []string
+	}{
+		{
+			name: "no changes",
+			kind: sourceKind,
+			spec: document{"name": "influx", "telegraf": "telegraf"},
+			live: document{"id": "1", "name": "influx", "telegraf": "telegraf"},
+			want: []string{},
+		},
+		{
+			name: "sorted fields",
+			kind: sourceKind,
+			spec: document{"name": "influx", "url": "http://influxdb:8086", "telegraf": "metrics", "default": true},
+			live: document{"id": "1", "name": "influx", "url": "http://localhost:8086", "telegraf": "telegraf"},
+			want: []string{"default", "telegraf", "url"},
+		},
+		{
+			name: "secrets are not compared",
+			kind: sourceKind,
+			spec: document{"name": "influx", "password": "secret", "sharedSecret": "shared"},
+			live: document{"id": "1", "name": "influx"},
+			want: []string{},
+		},
+		{
+			name: "secrets of another kind are compared",
+			kind: dashboardKind,
+			spec: document{"name": "hosts", "password": "secret"},
+			live: document{"id": "1", "name": "hosts"},
+			want: []string{"password"},
+		},
+	}
+	for _, tt := range tests {
+		if got := changes(tt.kind, tt.spec, tt.live); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("changes() of %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func Test_fieldIdentity(t *testing.T) {
+	tests := []struct {
+		doc  document
+		want string
+	}{
+		{doc: document{"provider": "github", "scheme": "oauth2", "providerOrganization": "acme"}, want: "github:oauth2:acme"},
+		{doc: document{"provider": "*", "scheme": "*"}, want: "*:*:"},
+		{doc: document{"provider": ""}, want: ""},
+		{doc: document{}, want: ""},
+	}
+	for _, tt := range tests {
+		if got := mappingKind.identity(tt.doc); got != tt.want {
+			t.Errorf("identity of %v = %q, want %q", tt.doc, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of the resource commands
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// printer writes resources to stdout in the selected output format
+type printer struct {
+	format string
+	out    io.Writer
+}
+
+func newPrinter(format string) *printer {
+	return &printer{format: format, out: os.Stdout}
+}
+
+// print writes v as a document, or calls table to write its tabular form
+func (p *printer) print(v interface{}, table func(w io.Writer)) error {
+	switch p.format {
+	case outputJSON:
+		enc := json.NewEncoder(p.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case outputYAML:
+		node, err := yamlNode(v)
+		if err != nil {
+			return err
+		}
+		enc := yaml.NewEncoder(p.out)
+		enc.SetIndent(2)
+		if err := enc.Encode(node); err != nil {
+			return err
+		}
+		return enc.Close()
+	case outputTable, "":
+		w := NewTabWriter()
+		table(w)
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown output format %q, must be one of table, json, yaml", p.format)
+	}
+}
+
+// yamlNode converts v to YAML through its JSON encoding, so that the documents use the
+// same field names and field order as the API.
+func yamlNode(v interface{}) (*yaml.Node, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+	return &node, nil
+}
+
+// blockStyle resets the flow style of the parsed JSON to the YAML block style
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		blockStyle(n)
+	}
+}
+
+// document is a resource in its JSON representation
+type document = map[string]interface{}
+
+// toDocument returns the JSON document of v
+func toDocument(v interface{}) (document, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// fromDocument decodes the JSON document into v
+func fromDocument(doc document, v interface{}) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeDocument decodes a JSON or YAML document into v using the JSON field names of v
+func decodeDocument(data []byte, v interface{}) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid document: %s", err)
+	}
+	if doc == nil {
+		return fmt.Errorf("empty document")
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("invalid document: %s", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid document: %s", err)
+	}
+	return nil
+}
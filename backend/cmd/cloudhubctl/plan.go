@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func init() {
+	parser.AddCommand("plan",
+		"Show the changes of a manifest",
+		"The plan command compares a YAML, JSON or TOML manifest with the live configuration and shows the resources that apply would create, update and delete",
+		&planCommand{})
+	parser.AddCommand("apply",
+		"Apply a manifest",
+		"The apply command creates, updates and, with --prune, deletes resources until the live configuration matches a YAML, JSON or TOML manifest",
+		&applyCommand{})
+}
+
+// manifestOptions are the flags of the plan and apply commands
+type manifestOptions struct {
+	dbOptions
+	Prune bool `long:"prune" description:"Delete the resources of the organizations of the manifest which are not in the manifest"`
+	Args  struct {
+		Manifest string `positional-arg-name:"manifest" required:"yes"`
+	} `positional-args:"yes"`
+}
+
+type planCommand struct {
+	manifestOptions
+}
+
+func (c *planCommand) Execute(args []string) error {
+	return c.run(false)
+}
+
+type applyCommand struct {
+	manifestOptions
+}
+
+func (c *applyCommand) Execute(args []string) error {
+	return c.run(true)
+}
+
+// run plans the manifest against the db, or the API of the server with --server,
+// applying the changes if apply is set
+func (o *manifestOptions) run(apply bool) error {
+	orgs, err := readManifest(o.Args.Manifest)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.WithValue(context.Background(), cloudhub.TrashContextKey, "cloudhubctl")
+	var t target
+	if options.Server != "" {
+		c, err := newAPIClient(ctx, &options)
+		if err != nil {
+			return err
+		}
+		t = &apiTarget{client: c, org: options.Organization}
+	} else {
+		svc, err := o.open(ctx)
+		if err != nil {
+			return err
+		}
+		defer svc.Close()
+		t = &storeTarget{svc: svc}
+	}
+
+	p := &planner{target: t, apply: apply, prune: o.Prune, out: os.Stdout}
+	if err := p.walk(ctx, organizationKind, nil, "", orgs); err != nil {
+		return err
+	}
+	p.summary()
+	return nil
+}
+
+// planner walks the resources of a manifest with their live resources, showing the
+// changes and making them if apply is set
+type planner struct {
+	target target
+	apply  bool
+	prune  bool
+	out    io.Writer
+
+	// sources are the links of the sources of the organization being walked by name,
+	// which the queries of its dashboards refer to
+	sources map[string]string
+
+	created, updated, deleted int
+}
+
+// unknown is shown in place of the values which are known once the resources are created
+const unknown = "(known after apply)"
+
+// walk plans the resources of the kind of the parent at the path. parent is nil for
+// organizations, and for resources of parents which do not exist yet.
+func (p *planner) walk(ctx context.Context, k *kind, parent document, path string, nodes []*node) error {
+	live := []document{}
+	if parent != nil || k == organizationKind {
+		var err error
+		if live, err = p.target.list(ctx, k, parent); err != nil {
+			return fmt.Errorf("unable to list %s: %s", k.key, err)
+		}
+	}
+	byIdentity := map[string]document{}
+	for _, doc := range live {
+		byIdentity[k.identity(doc)] = doc
+	}
+
+	seen := map[string]bool{}
+	for _, n := range nodes {
+		seen[n.identity] = true
+		if k == organizationKind {
+			p.sources = map[string]string{}
+		}
+
+		spec, err := p.resolve(n)
+		if err != nil {
+			return err
+		}
+
+		doc, exists := byIdentity[n.identity]
+		if !exists {
+			p.change("+", "create", n, nil)
+			p.created++
+			doc = nil
+			if p.apply {
+				if doc, err = p.target.create(ctx, k, parent, spec); err != nil {
+					return fmt.Errorf("unable to create %s %s: %s", k.name, n.path, err)
+				}
+			}
+		} else if fields := changes(k, spec, doc); len(fields) > 0 {
+			p.change("~", "update", n, fields)
+			p.updated++
+			if p.apply {
+				if err := p.target.update(ctx, k, parent, doc, spec); err != nil {
+					return fmt.Errorf("unable to update %s %s: %s", k.name, n.path, err)
+				}
+			}
+		}
+
+		if k == sourceKind {
+			p.sources[n.identity] = sourceLink(doc)
+		}
+		for _, child := range k.children {
+			if err := p.walk(ctx, child, doc, n.path+"/", n.children[child]); err != nil {
+				return err
+			}
+		}
+	}
+
+	// organizations are never deleted, only the resources of the organizations of the manifest
+	if !p.prune || k == organizationKind {
+		return nil
+	}
+	for _, doc := range live {
+		id := k.identity(doc)
+		if seen[id] {
+			continue
+		}
+		docPath := path + k.key + "/" + id
+		if k.single {
+			docPath = path + k.key
+		}
+		fmt.Fprintf(p.out, "  - delete %s %s\n", k.name, docPath)
+		p.deleted++
+		if p.apply {
+			if err := p.target.remove(ctx, k, parent, doc); err != nil {
+				return fmt.Errorf("unable to delete %s %s: %s", k.name, docPath, err)
+			}
+		}
+	}
+	return nil
+}
+
+// resolve returns the spec of the resource as applied; the source of a dashboard is
+// given by name and set on the queries without source
+func (p *planner) resolve(n *node) (document, error) {
+	if n.kind != dashboardKind || n.spec["source"] == nil {
+		return n.spec, nil
+	}
+
+	spec := document{}
+	if err := fromDocument(n.spec, &spec); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprint(spec["source"])
+	delete(spec, "source")
+	link, ok := p.sources[name]
+	if !ok {
+		return nil, fmt.Errorf("%s refers to unknown source %q", n.path, name)
+	}
+
+	cells, _ := spec["cells"].([]interface{})
+	for _, cell := range cells {
+		cell, _ := cell.(map[string]interface{})
+		queries, _ := cell["queries"].([]interface{})
+		for _, query := range queries {
+			if query, ok := query.(map[string]interface{}); ok && query["source"] == nil {
+				query["source"] = link
+			}
+		}
+	}
+	return spec, nil
+}
+
+// sourceLink returns the link of the live source, which is unknown until it is created
+func sourceLink(doc document) string {
+	if doc == nil {
+		return unknown
+	}
+	if self := link(doc, "self"); self != "" {
+		return self
+	}
+	return "/cloudhub/v1/sources/" + documentID(doc)
+}
+
+func (p *planner) change(symbol, action string, n *node, fields []string) {
+	if len(fields) > 0 {
+		fmt.Fprintf(p.out, "  %s %s %s %s (%s)\n", symbol, action, n.kind.name, n.path, strings.Join(fields, ", "))
+		return
+	}
+	fmt.Fprintf(p.out, "  %s %s %s %s\n", symbol, action, n.kind.name, n.path)
+}
+
+func (p *planner) summary() {
+	switch {
+	case p.created+p.updated+p.deleted == 0:
+		fmt.Fprintln(p.out, "No changes. The live configuration matches the manifest.")
+	case p.apply:
+		fmt.Fprintf(p.out, "Apply complete: %d created, %d updated, %d deleted.\n", p.created, p.updated, p.deleted)
+	default:
+		fmt.Fprintf(p.out, "Plan: %d to create, %d to update, %d to delete.\n", p.created, p.updated, p.deleted)
+	}
+}
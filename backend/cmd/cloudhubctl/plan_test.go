@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// memoryTarget is a target keeping the live documents in memory, by kind and ID of
+// their parent
+type memoryTarget struct {
+	docs   map[string][]document
+	nextID int
+}
+
+func newMemoryTarget() *memoryTarget {
+	return &memoryTarget{docs: map[string][]document{}}
+}
+
+func (m *memoryTarget) key(k *kind, parent document) string {
+	return k.key + "@" + documentID(parent)
+}
+
+// add adds a live document of the kind in the parent, returning it
+func (m *memoryTarget) add(k *kind, parent, doc document) document {
+	m.nextID++
+	id := fmt.Sprint(m.nextID)
+	live := document{"id": id, "links": map[string]interface{}{"self": "/cloudhub/v1/" + k.key + "/" + id}}
+	for f, v := range doc {
+		live[f] = v
+	}
+	m.docs[m.key(k, parent)] = append(m.docs[m.key(k, parent)], live)
+	return live
+}
+
+func (m *memoryTarget) list(ctx context.Context, k *kind, parent document) ([]document, error) {
+	return append([]document{}, m.docs[m.key(k, parent)]...), nil
+}
+
+func (m *memoryTarget) create(ctx context.Context, k *kind, parent, spec document) (document, error) {
+	return m.add(k, parent, spec), nil
+}
+
+func (m *memoryTarget) update(ctx context.Context, k *kind, parent, live, spec document) error {
+	for f, v := range spec {
+		live[f] = v
+	}
+	return nil
+}
+
+func (m *memoryTarget) remove(ctx context.Context, k *kind, parent, live document) error {
+	docs := m.docs[m.key(k, parent)]
+	for i, doc := range docs {
+		if documentID(doc) == documentID(live) {
+			m.docs[m.key(k, parent)] = append(docs[:i], docs[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s not found", k.name, documentID(live))
+}
+
+const planManifest = `
+organizations:
+  - name: acme
+    defaultRole: viewer
+    sources:
+      - name: influx
+        url: http://influxdb:8086
+        telegraf: telegraf
+        password: secret
+    dashboards:
+      - name: hosts
+        source: influx
+        cells:
+          - name: cpu
+            queries:
+              - query: SELECT mean("usage_user") FROM "cpu"
+`
+
+func Test_planner_walk(t *testing.T) {
+	tests := []struct {
+		name  string
+		prune bool
+		// live adds the live resources to the target
+		live     func(m *memoryTarget)
+		wantPlan string
+	}{
+		{
+			name: "create",
+			live: func(m *memoryTarget) {},
+			wantPlan: `  + create organization acme
+  + create source acme/sources/influx
+  + create dashboard acme/dashboards/hosts
+Plan: 3 to create, 0 to update, 0 to delete.
+`,
+		},
+		{
+			name: "no changes",
+			live: func(m *memoryTarget) {
+				org := m.add(organizationKind, nil, document{"name": "acme", "defaultRole": "viewer"})
+				source := m.add(sourceKind, org, document{"name": "influx", "url": "http://influxdb:8086", "telegraf": "telegraf"})
+				m.add(dashboardKind, org, document{"name": "hosts", "cells": []interface{}{
+					map[string]interface{}{"name": "cpu", "queries": []interface{}{
+						map[string]interface{}{"query": `SELECT mean("usage_user") FROM "cpu"`, "source": link(source, "self")},
+					}},
+				}})
+			},
+			wantPlan: "No changes. The live configuration matches the manifest.\n",
+		},
+		{
+			name: "update",
+			live: func(m *memoryTarget) {
+				org := m.add(organizationKind, nil, document{"name": "acme", "defaultRole": "editor"})
+				m.add(sourceKind, org, document{"name": "influx", "url": "http://localhost:8086", "telegraf": "metrics"})
+				m.add(dashboardKind, org, document{"name": "hosts", "cells": []interface{}{
+					map[string]interface{}{"name": "cpu", "queries": []interface{}{
+						map[string]interface{}{"query": `SELECT mean("usage_user") FROM "cpu"`, "source": "/cloudhub/v1/sources/9"},
+					}},
+				}})
+			},
+			wantPlan: `  ~ update organization acme (defaultRole)
+  ~ update source acme/sources/influx (telegraf, url)
+  ~ update dashboard acme/dashboards/hosts (cells)
+Plan: 0 to create, 3 to update, 0 to delete.
+`,
+		},
+		{
+			name: "resources which are not in the manifest are kept without prune",
+			live: func(m *memoryTarget) {
+				org := m.add(organizationKind, nil, document{"name": "acme", "defaultRole": "viewer"})
+				m.add(organizationKind, nil, document{"name": "other"})
+				m.add(sourceKind, org, document{"name": "influx", "url": "http://influxdb:8086", "telegraf": "telegraf"})
+				m.add(dashboardKind, org, document{"name": "old"})
+			},
+			wantPlan: `  + create dashboard acme/dashboards/hosts
+Plan: 1 to create, 0 to update, 0 to delete.
+`,
+		},
+		{
+			name:  "delete",
+			prune: true,
+			live: func(m *memoryTarget) {
+				org := m.add(organizationKind, nil, document{"name": "acme", "defaultRole": "viewer"})
+				m.add(organizationKind, nil, document{"name": "other"})
+				m.add(sourceKind, org, document{"name": "influx", "url": "http://influxdb:8086", "telegraf": "telegraf"})
+				m.add(dashboardKind, org, document{"name": "old"})
+				m.add(topologyKind, org, document{"diagram": "<mxGraphModel/>"})
+			},
+			wantPlan: `  + create dashboard acme/dashboards/hosts
+  - delete dashboard acme/dashboards/old
+  - delete topology acme/topology
+Plan: 1 to create, 0 to update, 2 to delete.
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			nodes, err := readManifest(writeManifest(t, "cloudhub.yaml", planManifest))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := newMemoryTarget()
+			tt.live(m)
+
+			var out bytes.Buffer
+			p := &planner{target: m, prune: tt.prune, out: &out}
+			if err := p.walk(ctx, organizationKind, nil, "", nodes); err != nil {
+				t.Fatalf("walk() error = %v", err)
+			}
+			p.summary()
+			if out.String() != tt.wantPlan {
+				t.Errorf("plan =\n%s\nwant\n%s", out.String(), tt.wantPlan)
+			}
+
+			// applying the manifest makes the changes of the plan, leaving none to plan
+			out.Reset()
+			p = &planner{target: m, apply: true, prune: tt.prune, out: &out}
+			if err := p.walk(ctx, organizationKind, nil, "", nodes); err != nil {
+				t.Fatalf("walk() to apply error = %v", err)
+			}
+			p.summary()
+			want := fmt.Sprintf("Apply complete: %d created, %d updated, %d deleted.\n", p.created, p.updated, p.deleted)
+			if p.created+p.updated+p.deleted == 0 {
+				want = "No changes. The live configuration matches the manifest.\n"
+			}
+			if changes := strings.TrimSuffix(tt.wantPlan, lastLine(tt.wantPlan)); out.String() != changes+want {
+				t.Errorf("apply =\n%s\nwant\n%s", out.String(), changes+want)
+			}
+			out.Reset()
+			p = &planner{target: m, prune: tt.prune, out: &out}
+			if err := p.walk(ctx, organizationKind, nil, "", nodes); err != nil {
+				t.Fatalf("walk() after apply error = %v", err)
+			}
+			p.summary()
+			if want := "No changes. The live configuration matches the manifest.\n"; out.String() != want {
+				t.Errorf("plan after apply =\n%s\nwant\n%s", out.String(), want)
+			}
+		})
+	}
+}
+
+// lastLine returns the last line of the text ending with a new line
+func lastLine(text string) string {
+	lines := strings.SplitAfter(strings.TrimSuffix(text, "\n"), "\n")
+	return lines[len(lines)-1] + "\n"
+}
+
+// querySources returns the sources of the queries of the cells of the dashboard
+func querySources(spec document) []string {
+	sources := []string{}
+	cells, _ := spec["cells"].([]interface{})
+	for _, cell := range cells {
+		queries, _ := cell.(map[string]interface{})["queries"].([]interface{})
+		for _, q := range queries {
+			sources = append(sources, fmt.Sprint(q.(map[string]interface{})["source"]))
+		}
+	}
+	return sources
+}
+
+func Test_planner_resolve(t *testing.T) {
+	dashboard := func(source interface{}) *node {
+		spec := document{"name": "hosts", "cells": []interface{}{
+			map[string]interface{}{"queries": []interface{}{
+				map[string]interface{}{"query": "SELECT 1"},
+				map[string]interface{}{"query": "SELECT 2", "source": "/cloudhub/v1/sources/7"},
+			}},
+		}}
+		if source != nil {
+			spec["source"] = source
+		}
+		return &node{kind: dashboardKind, path: "acme/dashboards/hosts", spec: spec}
+	}
+	tests := []struct {
+		name        string
+		node        *node
+		sources     map[string]string
+		wantSources []string
+		wantErr     string
+	}{
+		{
+			name:        "dashboard without source",
+			node:        dashboard(nil),
+			wantSources: []string{"<nil>", "/cloudhub/v1/sources/7"},
+		},
+		{
+			name:        "live source",
+			node:        dashboard("influx"),
+			sources:     map[string]string{"influx": "/cloudhub/v1/sources/1"},
+			wantSources: []string{"/cloudhub/v1/sources/1", "/cloudhub/v1/sources/7"},
+		},
+		{
+			name:        "source to create",
+			node:        dashboard("influx"),
+			sources:     map[string]string{"influx": unknown},
+			wantSources: []string{unknown, "/cloudhub/v1/sources/7"},
+		},
+		{
+			name:    "unknown source",
+			node:    dashboard("telegraf"),
+			sources: map[string]string{"influx": "/cloudhub/v1/sources/1"},
+			wantErr: `acme/dashboards/hosts refers to unknown source "telegraf"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &planner{sources: tt.sources}
+			spec, err := p.resolve(tt.node)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("resolve() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve() error = %v", err)
+			}
+			if _, ok := spec["source"]; ok {
+				t.Errorf("resolve() = %v, want it without source", spec)
+			}
+			if got := querySources(spec); strings.Join(got, ",") != strings.Join(tt.wantSources, ",") {
+				t.Errorf("resolve() sources of the queries = %v, want %v", got, tt.wantSources)
+			}
+			if got := querySources(tt.node.spec); got[0] != "<nil>" {
+				t.Errorf("resolve() changed the spec of the manifest, sources of the queries = %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// remoteResource describes the API endpoints of a resource type. The resources are
+// handled as the JSON documents of the API, so that they are shown as the UI sees them.
+type remoteResource struct {
+	path string // path of the collection
+	// key of the resources in the response of the collection; empty if the collection
+	// responds with the single resource of the current organization
+	key string
+	// id is the field holding the ID, "id" if empty
+	id string
+	// lookup is set if the resources have no endpoint of their own and are looked up in the collection
+	lookup bool
+	// replace is set if updates replace the whole resource rather than patching it
+	replace bool
+	columns []string
+
+	// all, create and remove override the requests of resources with irregular endpoints
+	all    func(ctx context.Context, c *apiClient) ([]json.RawMessage, error)
+	create func(ctx context.Context, c *apiClient, doc map[string]interface{}) (json.RawMessage, error)
+	remove func(ctx context.Context, c *apiClient, id string) error
+}
+
+// remoteResourceTypes are the resources managed through the API of a server
+var remoteResourceTypes = map[string]*remoteResource{
+	"organizations": {
+		path:    "/cloudhub/v1/organizations",
+		key:     "organizations",
+		columns: []string{"id", "name", "defaultRole"},
+	},
+	"users": {
+		path:    "/cloudhub/v1/users",
+		key:     "users",
+		columns: []string{"id", "name", "provider", "scheme", "superAdmin", "roles"},
+	},
+	"sources": {
+		path:    "/cloudhub/v1/sources",
+		key:     "sources",
+		columns: []string{"id", "name", "type", "url", "telegraf", "defaultRP", "default", "organization"},
+	},
+	"servers": {
+		lookup:  true,
+		columns: []string{"id", "name", "url", "active", "insecureSkipVerify"},
+		all:     allKapacitors,
+		create:  createKapacitor,
+	},
+	"dashboards": {
+		path:    "/cloudhub/v1/dashboards",
+		key:     "dashboards",
+		columns: []string{"id", "name", "organization", "templates", "cells"},
+	},
+	"mappings": {
+		path:    "/cloudhub/v1/mappings",
+		key:     "mappings",
+		lookup:  true,
+		replace: true,
+		columns: []string{"id", "organizationId", "provider", "scheme", "providerOrganization"},
+	},
+	"topologies": {
+		path:    "/cloudhub/v1/topologies",
+		lookup:  true,
+		columns: []string{"id", "organization"},
+	},
+	"csps": {
+		path:    "/cloudhub/v1/csp",
+		key:     "CSPs",
+		columns: []string{"id", "provider", "namespace", "accesskey", "organization", "minion"},
+	},
+	"devices": {
+		path:    "/cloudhub/v1/ai/network/managements/devices",
+		key:     "devices",
+		columns: []string{"id", "organization", "device_ip", "hostname", "device_type", "device_vendor", "device_os", "learning_state"},
+		create:  createDevice,
+		remove:  removeDevice,
+	},
+	"device-orgs": {
+		path:    "/cloudhub/v1/ai/network/managements/orgs",
+		key:     "organizations",
+		id:      "organization",
+		columns: []string{"organization", "load_module", "ml_function", "data_duration", "collector_server", "learned_devices_ids", "collected_devices_ids", "learning_cron"},
+		create: func(ctx context.Context, c *apiClient, doc map[string]interface{}) (json.RawMessage, error) {
+			var res json.RawMessage
+			err := c.do(ctx, http.MethodPost, "/cloudhub/v1/ai/network/managements/orgs/", doc, &res)
+			return res, err
+		},
+	},
+}
+
+// lookupRemoteResource returns the remote resource of the given name or alias
+func lookupRemoteResource(name string) (*remoteResource, error) {
+	name = strings.ToLower(name)
+	if alias, ok := resourceAliases[name]; ok {
+		name = alias
+	}
+	if r, ok := remoteResourceTypes[name]; ok {
+		return r, nil
+	}
+
+	names := make([]string, 0, len(remoteResourceTypes))
+	for n := range remoteResourceTypes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown resource type %q, must be one of %s", name, strings.Join(names, ", "))
+}
+
+// apiResources manages the resources of a type through the API of a server
+type apiResources struct {
+	client *apiClient
+	r      *remoteResource
+}
+
+func (a *apiResources) list(ctx context.Context, p *printer) error {
+	items, err := a.all(ctx)
+	if err != nil {
+		return err
+	}
+	return p.print(items, func(w io.Writer) {
+		a.writeHeaders(w)
+		for _, item := range items {
+			a.write(w, item)
+		}
+	})
+}
+
+func (a *apiResources) get(ctx context.Context, id string, p *printer) error {
+	item, err := a.find(ctx, id)
+	if err != nil {
+		return err
+	}
+	return a.print(item, p)
+}
+
+func (a *apiResources) create(ctx context.Context, data []byte, p *printer) error {
+	var doc map[string]interface{}
+	if err := decodeDocument(data, &doc); err != nil {
+		return err
+	}
+
+	var item json.RawMessage
+	var err error
+	if a.r.create != nil {
+		item, err = a.r.create(ctx, a.client, doc)
+	} else {
+		err = a.client.do(ctx, http.MethodPost, a.r.path, doc, &item)
+	}
+	if err != nil {
+		return err
+	}
+	return a.print(item, p)
+}
+
+// update patches the resource with the document; resources that can only be replaced
+// are replaced by the document merged into the current resource.
+func (a *apiResources) update(ctx context.Context, id string, data []byte, p *printer) error {
+	var doc map[string]interface{}
+	if err := decodeDocument(data, &doc); err != nil {
+		return err
+	}
+
+	current, err := a.find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	method, body := http.MethodPatch, doc
+	if a.r.replace {
+		body = map[string]interface{}{}
+		if err := json.Unmarshal(current, &body); err != nil {
+			return err
+		}
+		for k, v := range doc {
+			body[k] = v
+		}
+		method = http.MethodPut
+	}
+	delete(body, "links")
+	body[a.idField()] = fieldString(current, a.idField())
+
+	var item json.RawMessage
+	if err := a.client.do(ctx, method, a.link(current, id), body, &item); err != nil {
+		return err
+	}
+	return a.print(item, p)
+}
+
+func (a *apiResources) remove(ctx context.Context, id string) error {
+	if a.r.remove != nil {
+		return a.r.remove(ctx, a.client, id)
+	}
+
+	path := a.r.path + "/" + url.PathEscape(id)
+	if a.r.lookup {
+		item, err := a.find(ctx, id)
+		if err != nil {
+			return err
+		}
+		path = a.link(item, id)
+	}
+	return a.client.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// all returns the resources of the collection
+func (a *apiResources) all(ctx context.Context) ([]json.RawMessage, error) {
+	if a.r.all != nil {
+		return a.r.all(ctx, a.client)
+	}
+	return collection(ctx, a.client, a.r.path, a.r.key, a.idField())
+}
+
+// find returns the resource with the given id
+func (a *apiResources) find(ctx context.Context, id string) (json.RawMessage, error) {
+	if !a.r.lookup {
+		var item json.RawMessage
+		err := a.client.do(ctx, http.MethodGet, a.r.path+"/"+url.PathEscape(id), nil, &item)
+		return item, err
+	}
+
+	items, err := a.all(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if fieldString(item, a.idField()) == id {
+			return item, nil
+		}
+	}
+	return nil, fmt.Errorf("ID %s not found", id)
+}
+
+// link returns the self link of the item, or its path in the collection
+func (a *apiResources) link(item json.RawMessage, id string) string {
+	var links struct {
+		Links struct {
+			Self string `json:"self"`
+		} `json:"links"`
+	}
+	if json.Unmarshal(item, &links) == nil && links.Links.Self != "" {
+		return links.Links.Self
+	}
+	return a.r.path + "/" + url.PathEscape(id)
+}
+
+func (a *apiResources) idField() string {
+	if a.r.id == "" {
+		return "id"
+	}
+	return a.r.id
+}
+
+func (a *apiResources) print(item json.RawMessage, p *printer) error {
+	return p.print(item, func(w io.Writer) {
+		a.writeHeaders(w)
+		a.write(w, item)
+	})
+}
+
+func (a *apiResources) writeHeaders(w io.Writer) {
+	fmt.Fprintln(w, strings.Join(a.r.columns, "\t"))
+}
+
+// write writes the columns of the item; lists of names or values are joined by comma
+func (a *apiResources) write(w io.Writer, item json.RawMessage) {
+	var fields map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(item))
+	d.UseNumber()
+	d.Decode(&fields)
+
+	values := make([]string, len(a.r.columns))
+	for i, column := range a.r.columns {
+		values[i] = columnValue(fields[column])
+	}
+	fmt.Fprintln(w, strings.Join(values, "\t"))
+}
+
+func columnValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, e := range v {
+			values[i] = columnValue(e)
+		}
+		return strings.Join(values, ",")
+	case map[string]interface{}:
+		for _, k := range []string{"name", "label", "organization"} {
+			if name, ok := v[k]; ok {
+				return columnValue(name)
+			}
+		}
+		b, _ := json.Marshal(v)
+		return string(b)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// collection returns the resources listed under key in the response of the path.
+// Without a key, the response is the single resource of the current organization,
+// which is left out if it does not exist yet.
+func collection(ctx context.Context, c *apiClient, path, key, id string) ([]json.RawMessage, error) {
+	if key == "" {
+		var item json.RawMessage
+		if err := c.do(ctx, http.MethodGet, path, nil, &item); err != nil {
+			return nil, err
+		}
+		if fieldString(item, id) == "" {
+			return []json.RawMessage{}, nil
+		}
+		return []json.RawMessage{item}, nil
+	}
+
+	var res map[string]json.RawMessage
+	if err := c.do(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return nil, err
+	}
+	items := []json.RawMessage{}
+	if raw, ok := res[key]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &items); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// fieldString returns a field of the item as string
+func fieldString(item json.RawMessage, field string) string {
+	var fields map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(item))
+	d.UseNumber()
+	if err := d.Decode(&fields); err != nil {
+		return ""
+	}
+	if v, ok := fields[field]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// allKapacitors returns the kapacitors of all sources
+func allKapacitors(ctx context.Context, c *apiClient) ([]json.RawMessage, error) {
+	srcs, err := collection(ctx, c, "/cloudhub/v1/sources", "sources", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	kapacitors := []json.RawMessage{}
+	for _, src := range srcs {
+		var links struct {
+			Links struct {
+				Kapacitors string `json:"kapacitors"`
+			} `json:"links"`
+		}
+		if err := json.Unmarshal(src, &links); err != nil || links.Links.Kapacitors == "" {
+			continue
+		}
+		items, err := collection(ctx, c, links.Links.Kapacitors, "kapacitors", "id")
+		if err != nil {
+			return nil, err
+		}
+		kapacitors = append(kapacitors, items...)
+	}
+	return kapacitors, nil
+}
+
+// createKapacitor creates a kapacitor of the source given by the srcId field of the document
+func createKapacitor(ctx context.Context, c *apiClient, doc map[string]interface{}) (json.RawMessage, error) {
+	srcID, ok := doc["srcId"]
+	if !ok {
+		return nil, fmt.Errorf("the srcId of the source of the server is required")
+	}
+	delete(doc, "srcId")
+
+	var res json.RawMessage
+	path := fmt.Sprintf("/cloudhub/v1/sources/%v/kapacitors", srcID)
+	err := c.do(ctx, http.MethodPost, path, doc, &res)
+	return res, err
+}
+
+// failedDevices is the response of the bulk requests of network devices
+type failedDevices struct {
+	FailedDevices []struct {
+		ErrorMessage string `json:"errorMessage"`
+	} `json:"failed_devices"`
+}
+
+func (f *failedDevices) err() error {
+	if len(f.FailedDevices) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", f.FailedDevices[0].ErrorMessage)
+}
+
+// createDevice creates a network device; the API creates devices in bulk and does not
+// return them, so the device is looked up by its IP.
+func createDevice(ctx context.Context, c *apiClient, doc map[string]interface{}) (json.RawMessage, error) {
+	const path = "/cloudhub/v1/ai/network/managements/devices"
+	var res failedDevices
+	if err := c.do(ctx, http.MethodPost, path, []interface{}{doc}, &res); err != nil {
+		return nil, err
+	}
+	if err := res.err(); err != nil {
+		return nil, fmt.Errorf("unable to create device: %s", err)
+	}
+
+	devices, err := collection(ctx, c, path, "devices", "id")
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range devices {
+		if fieldString(device, "device_ip") == fmt.Sprint(doc["device_ip"]) {
+			return device, nil
+		}
+	}
+	return nil, fmt.Errorf("created device %v not found", doc["device_ip"])
+}
+
+// removeDevice removes a network device; the API removes devices in bulk
+func removeDevice(ctx context.Context, c *apiClient, id string) error {
+	req := map[string][]string{"devices_ids": {id}}
+	var res failedDevices
+	if err := c.do(ctx, http.MethodDelete, "/cloudhub/v1/ai/network/managements/devices", req, &res); err != nil {
+		return err
+	}
+	if err := res.err(); err != nil {
+		return fmt.Errorf("unable to delete device %s: %s", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// fakeAPI is a server answering the requests of the API from a map of responses by
+// method and path, recording the requests and their bodies
+type fakeAPI struct {
+	*httptest.Server
+	responses map[string]string
+	requests  []string
+	bodies    map[string]document
+}
+
+func newFakeAPI(t *testing.T, responses map[string]string) *fakeAPI {
+	f := &fakeAPI{responses: responses, bodies: map[string]document{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.Path
+		f.requests = append(f.requests, key)
+		if data, _ := io.ReadAll(r.Body); len(data) > 0 {
+			var body document
+			json.Unmarshal(data, &body)
+			f.bodies[key] = body
+		}
+		if key == "POST /basic/login" {
+			http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "logged-in", Path: "/"})
+		}
+		res, ok := responses[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "not found"}`))
+			return
+		}
+		if strings.HasPrefix(res, "!") {
+			w.WriteHeader(http.StatusUnauthorized)
+			res = res[1:]
+		}
+		w.Write([]byte(res))
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func Test_newAPIClient(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         Options
+		responses    map[string]string
+		wantErr      string
+		wantRequests []string
+	}{
+		{
+			name:    "server url without scheme",
+			opts:    Options{Server: "cloudhub.example", Token: "t"},
+			wantErr: "must begin with http:// or https://",
+		},
+		{
+			name:    "no credentials",
+			opts:    Options{Server: "http://cloudhub.example"},
+			wantErr: "either --token or --username must be given",
+		},
+		{
+			name:      "token",
+			opts:      Options{Token: "t"},
+			responses: map[string]string{},
+		},
+		{
+			name:         "token with an organization",
+			opts:         Options{Token: "t", Organization: "2"},
+			responses:    map[string]string{"PUT /cloudhub/v1/me": `{}`},
+			wantRequests: []string{"PUT /cloudhub/v1/me"},
+		},
+		{
+			name:         "basic login",
+			opts:         Options{Username: "admin", Password: "secret"},
+			responses:    map[string]string{"POST /basic/login": `{"passwordResetFlag": "N"}`},
+			wantRequests: []string{"POST /basic/login"},
+		},
+		{
+			name:      "basic login with a password to reset",
+			opts:      Options{Username: "admin", Password: "secret"},
+			responses: map[string]string{"POST /basic/login": `{"passwordResetFlag": "Y"}`},
+			wantErr:   "the password of admin must be reset",
+		},
+		{
+			name:      "basic login refused",
+			opts:      Options{Username: "admin", Password: "wrong"},
+			responses: map[string]string{"POST /basic/login": `!{"message": "invalid password"}`},
+			wantErr:   "unable to log in as admin: POST /basic/login: invalid password (401)",
+		},
+		{
+			name:      "unknown organization",
+			opts:      Options{Token: "t", Organization: "9"},
+			responses: map[string]string{},
+			wantErr:   "unable to switch to organization 9",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			var api *fakeAPI
+			if tt.responses != nil {
+				api = newFakeAPI(t, tt.responses)
+				opts.Server = api.URL
+			}
+
+			_, err := newAPIClient(context.Background(), &opts)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newAPIClient() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newAPIClient() error = %v", err)
+			}
+			if strings.Join(api.requests, ",") != strings.Join(tt.wantRequests, ",") {
+				t.Errorf("newAPIClient() requests = %v, want %v", api.requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func Test_apiClient_endpoint(t *testing.T) {
+	tests := []struct {
+		server string
+		path   string
+		want   string
+	}{
+		{"https://cloudhub.example", "/cloudhub/v1/sources", "https://cloudhub.example/cloudhub/v1/sources"},
+		{"https://cloudhub.example/", "/cloudhub/v1/sources", "https://cloudhub.example/cloudhub/v1/sources"},
+		{"https://cloudhub.example/hub", "/cloudhub/v1/sources", "https://cloudhub.example/hub/cloudhub/v1/sources"},
+		{"https://cloudhub.example/hub/", "/hub/cloudhub/v1/sources/1", "https://cloudhub.example/hub/cloudhub/v1/sources/1"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.server)
+		if err != nil {
+			t.Fatal(err)
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		c := &apiClient{url: u}
+		if got := c.endpoint(tt.path); got != tt.want {
+			t.Errorf("endpoint(%s) of %s = %s, want %s", tt.path, tt.server, got, tt.want)
+		}
+	}
+}
+
+// Every resource type of the db is managed through the API as well
+func Test_lookupRemoteResource(t *testing.T) {
+	for name := range resourceTypes {
+		if _, err := lookupRemoteResource(name); err != nil {
+			t.Errorf("lookupRemoteResource(%s) error = %v", name, err)
+		}
+	}
+	for alias, name := range resourceAliases {
+		got, err := lookupRemoteResource(alias)
+		if err != nil || got != remoteResourceTypes[name] {
+			t.Errorf("lookupRemoteResource(%s) = %v, %v, want the resource of %s", alias, got, err, name)
+		}
+	}
+	if _, err := lookupRemoteResource("layouts"); err == nil {
+		t.Error("lookupRemoteResource(layouts) expected an error")
+	}
+}
+
+func Test_apiResources(t *testing.T) {
+	tests := []struct {
+		name      string
+		resource  string
+		run       func(ctx context.Context, a *apiResources, p *printer) error
+		responses map[string]string
+		// wantRequest is the last request, and wantBody its body
+		wantRequest string
+		wantBody    document
+		wantOutput  string
+	}{
+		{
+			name:     "list",
+			resource: "sources",
+			run: func(ctx context.Context, a *apiResources, p *printer) error {
+				return a.list(ctx, p)
+			},
+			responses: map[string]string{
+				"GET /cloudhub/v1/sources": `{"sources": [{"id": "1", "name": "influx", "telegraf": "telegraf"}]}`,
+			},
+			wantRequest: "GET /cloudhub/v1/sources",
+			wantOutput:  `[{"id":"1","name":"influx","telegraf":"telegraf"}]`,
+		},
+		{
+			name:     "update patches the resource",
+			resource: "sources",
+			run: func(ctx context.Context, a *apiResources, p *printer) error {
+				return a.update(ctx, "1", []byte("telegraf: metrics\n"), p)
+			},
+			responses: map[string]string{
+				"GET /cloudhub/v1/sources/1":   `{"id": "1", "name": "influx", "links": {"self": "/cloudhub/v1/sources/1"}}`,
+				"PATCH /cloudhub/v1/sources/1": `{"id": "1", "name": "influx", "telegraf": "metrics"}`,
+			},
+			wantRequest: "PATCH /cloudhub/v1/sources/1",
+			wantBody:    document{"id": "1", "telegraf": "metrics"},
+			wantOutput:  `{"id":"1","name":"influx","telegraf":"metrics"}`,
+		},
+		{
+			name:     "update replaces the resources looked up in their collection",
+			resource: "mappings",
+			run: func(ctx context.Context, a *apiResources, p *printer) error {
+				return a.update(ctx, "3", []byte(`{"providerOrganization": "ops"}`), p)
+			},
+			responses: map[string]string{
+				"GET /cloudhub/v1/mappings":   `{"mappings": [{"id": "3", "organizationId": "1", "provider": "github", "scheme": "oauth2", "providerOrganization": "dev", "links": {"self": "/cloudhub/v1/mappings/3"}}]}`,
+				"PUT /cloudhub/v1/mappings/3": `{"id": "3"}`,
+			},
+			wantRequest: "PUT /cloudhub/v1/mappings/3",
+			wantBody:    document{"id": "3", "organizationId": "1", "provider": "github", "scheme": "oauth2", "providerOrganization": "ops"},
+			wantOutput:  `{"id":"3"}`,
+		},
+		{
+			name:     "create a server of a source",
+			resource: "servers",
+			run: func(ctx context.Context, a *apiResources, p *printer) error {
+				return a.create(ctx, []byte(`{"srcId": 2, "name": "kapa", "url": "http://kapacitor:9092"}`), p)
+			},
+			responses: map[string]string{
+				"POST /cloudhub/v1/sources/2/kapacitors": `{"id": "5", "name": "kapa"}`,
+			},
+			wantRequest: "POST /cloudhub/v1/sources/2/kapacitors",
+			wantBody:    document{"name": "kapa", "url": "http://kapacitor:9092"},
+			wantOutput:  `{"id":"5","name":"kapa"}`,
+		},
+		{
+			name:     "remove a resource looked up in its collection",
+			resource: "topologies",
+			run: func(ctx context.Context, a *apiResources, p *printer) error {
+				return a.remove(ctx, "7")
+			},
+			responses: map[string]string{
+				"GET /cloudhub/v1/topologies":      `{"id": "7", "organization": "1", "links": {"self": "/cloudhub/v1/topologies/7"}}`,
+				"DELETE /cloudhub/v1/topologies/7": ``,
+			},
+			wantRequest: "DELETE /cloudhub/v1/topologies/7",
+		},
+		{
+			name:     "remove a network device",
+			resource: "devices",
+			run: func(ctx context.Context, a *apiResources, p *printer) error {
+				return a.remove(ctx, "4")
+			},
+			responses: map[string]string{
+				"DELETE /cloudhub/v1/ai/network/managements/devices": `{"failed_devices": []}`,
+			},
+			wantRequest: "DELETE /cloudhub/v1/ai/network/managements/devices",
+			wantBody:    document{"devices_ids": []interface{}{"4"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI(t, tt.responses)
+			c, err := newAPIClient(context.Background(), &Options{Server: api.URL, Token: "t"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := lookupRemoteResource(tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := tt.run(context.Background(), &apiResources{client: c, r: r}, &printer{format: outputJSON, out: &out}); err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if last := api.requests[len(api.requests)-1]; last != tt.wantRequest {
+				t.Errorf("requests = %v, want last %s", api.requests, tt.wantRequest)
+			}
+			if tt.wantBody != nil && !matches(tt.wantBody, api.bodies[tt.wantRequest]) {
+				t.Errorf("body of %s = %v, want %v", tt.wantRequest, api.bodies[tt.wantRequest], tt.wantBody)
+			}
+			if _, ok := api.bodies[tt.wantRequest]["links"]; ok {
+				t.Errorf("body of %s must not send the links of the resource", tt.wantRequest)
+			}
+			var compact bytes.Buffer
+			if out.Len() > 0 {
+				json.Compact(&compact, out.Bytes())
+			}
+			if compact.String() != tt.wantOutput {
+				t.Errorf("output = %s, want %s", compact.String(), tt.wantOutput)
+			}
+		})
+	}
+}
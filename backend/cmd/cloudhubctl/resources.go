@@ -0,0 +1,439 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv"
+)
+
+// resourceType is a kind of stored resource that can be managed from the command line
+type resourceType interface {
+	list(ctx context.Context, svc *kv.Service, p *printer) error
+	get(ctx context.Context, svc *kv.Service, id string, p *printer) error
+	create(ctx context.Context, svc *kv.Service, data []byte, p *printer) error
+	update(ctx context.Context, svc *kv.Service, id string, data []byte, p *printer) error
+	remove(ctx context.Context, svc *kv.Service, id string) error
+
+	// documents, addDocument and updateDocument work on the JSON documents of the resources
+	documents(ctx context.Context, svc *kv.Service) ([]document, error)
+	addDocument(ctx context.Context, svc *kv.Service, doc document) (document, error)
+	updateDocument(ctx context.Context, svc *kv.Service, id string, doc document) error
+}
+
+// storeResources manages the resources of a type in the db
+type storeResources struct {
+	svc *kv.Service
+	r   resourceType
+}
+
+func (s *storeResources) list(ctx context.Context, p *printer) error {
+	return s.r.list(ctx, s.svc, p)
+}
+
+func (s *storeResources) get(ctx context.Context, id string, p *printer) error {
+	return s.r.get(ctx, s.svc, id, p)
+}
+
+func (s *storeResources) create(ctx context.Context, data []byte, p *printer) error {
+	return s.r.create(ctx, s.svc, data, p)
+}
+
+func (s *storeResources) update(ctx context.Context, id string, data []byte, p *printer) error {
+	return s.r.update(ctx, s.svc, id, data, p)
+}
+
+func (s *storeResources) remove(ctx context.Context, id string) error {
+	return s.r.remove(ctx, s.svc, id)
+}
+
+// resource adapts the store of T to a resourceType
+type resource[T any] struct {
+	all    func(ctx context.Context, svc *kv.Service) ([]T, error)
+	find   func(ctx context.Context, svc *kv.Service, id string) (*T, error)
+	add    func(ctx context.Context, svc *kv.Service, t *T) (*T, error)
+	put    func(ctx context.Context, svc *kv.Service, t *T) error
+	delete func(ctx context.Context, svc *kv.Service, t *T) error
+	// setID restores the ID of an updated resource, so that it cannot be changed by the document
+	setID   func(t *T, id string)
+	headers func(w io.Writer)
+	write   func(w io.Writer, t *T)
+}
+
+func (r *resource[T]) list(ctx context.Context, svc *kv.Service, p *printer) error {
+	items, err := r.all(ctx, svc)
+	if err != nil {
+		return err
+	}
+	return p.print(items, func(w io.Writer) {
+		r.headers(w)
+		for i := range items {
+			r.write(w, &items[i])
+		}
+	})
+}
+
+func (r *resource[T]) get(ctx context.Context, svc *kv.Service, id string, p *printer) error {
+	t, err := r.find(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+	return r.print(t, p)
+}
+
+func (r *resource[T]) create(ctx context.Context, svc *kv.Service, data []byte, p *printer) error {
+	var t T
+	if err := decodeDocument(data, &t); err != nil {
+		return err
+	}
+	created, err := r.add(ctx, svc, &t)
+	if err != nil {
+		return err
+	}
+	return r.print(created, p)
+}
+
+// update merges the document into the stored resource, so that only the given fields change
+func (r *resource[T]) update(ctx context.Context, svc *kv.Service, id string, data []byte, p *printer) error {
+	t, err := r.find(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+	if err := decodeDocument(data, t); err != nil {
+		return err
+	}
+	r.setID(t, id)
+	if err := r.put(ctx, svc, t); err != nil {
+		return err
+	}
+	return r.print(t, p)
+}
+
+func (r *resource[T]) remove(ctx context.Context, svc *kv.Service, id string) error {
+	t, err := r.find(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+	return r.delete(ctx, svc, t)
+}
+
+func (r *resource[T]) documents(ctx context.Context, svc *kv.Service) ([]document, error) {
+	items, err := r.all(ctx, svc)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]document, len(items))
+	for i := range items {
+		if docs[i], err = toDocument(&items[i]); err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+func (r *resource[T]) addDocument(ctx context.Context, svc *kv.Service, doc document) (document, error) {
+	var t T
+	if err := fromDocument(doc, &t); err != nil {
+		return nil, err
+	}
+	created, err := r.add(ctx, svc, &t)
+	if err != nil {
+		return nil, err
+	}
+	return toDocument(created)
+}
+
+// updateDocument merges the document into the stored resource
+func (r *resource[T]) updateDocument(ctx context.Context, svc *kv.Service, id string, doc document) error {
+	t, err := r.find(ctx, svc, id)
+	if err != nil {
+		return err
+	}
+	if err := fromDocument(doc, t); err != nil {
+		return err
+	}
+	r.setID(t, id)
+	return r.put(ctx, svc, t)
+}
+
+func (r *resource[T]) print(t *T, p *printer) error {
+	return p.print(t, func(w io.Writer) {
+		r.headers(w)
+		r.write(w, t)
+	})
+}
+
+// resourceTypes are the resources managed by the list, get, create, update and delete commands
+var resourceTypes = map[string]resourceType{
+	"organizations": &resource[cloudhub.Organization]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.Organization, error) {
+			return svc.OrganizationsStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.Organization, error) {
+			return svc.OrganizationsStore().Get(ctx, cloudhub.OrganizationQuery{ID: &id})
+		},
+		add: func(ctx context.Context, svc *kv.Service, o *cloudhub.Organization) (*cloudhub.Organization, error) {
+			return svc.OrganizationsStore().Add(ctx, o)
+		},
+		put: func(ctx context.Context, svc *kv.Service, o *cloudhub.Organization) error {
+			return svc.OrganizationsStore().Update(ctx, o)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, o *cloudhub.Organization) error {
+			return svc.OrganizationsStore().Delete(ctx, o)
+		},
+		setID:   func(o *cloudhub.Organization, id string) { o.ID = id },
+		headers: WriteOrganizationsHeaders,
+		write:   WriteOrganization,
+	},
+	"users": &resource[cloudhub.User]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.User, error) {
+			return svc.UsersStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.User, error) {
+			uid, err := strconv.ParseUint(id, 10, 64)
+			if err != nil {
+				return nil, cloudhub.ErrUserNotFound
+			}
+			return svc.UsersStore().Get(ctx, cloudhub.UserQuery{ID: &uid})
+		},
+		add: func(ctx context.Context, svc *kv.Service, u *cloudhub.User) (*cloudhub.User, error) {
+			return svc.UsersStore().Add(ctx, u)
+		},
+		put: func(ctx context.Context, svc *kv.Service, u *cloudhub.User) error {
+			return svc.UsersStore().Update(ctx, u)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, u *cloudhub.User) error {
+			return svc.UsersStore().Delete(ctx, u)
+		},
+		setID: func(u *cloudhub.User, id string) {
+			u.ID, _ = strconv.ParseUint(id, 10, 64)
+		},
+		headers: WriteUserHeaders,
+		write:   WriteUser,
+	},
+	"sources": &resource[cloudhub.Source]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.Source, error) {
+			return svc.SourcesStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.Source, error) {
+			sid, err := strconv.Atoi(id)
+			if err != nil {
+				return nil, cloudhub.ErrSourceNotFound
+			}
+			src, err := svc.SourcesStore().Get(ctx, sid)
+			return &src, err
+		},
+		add: func(ctx context.Context, svc *kv.Service, src *cloudhub.Source) (*cloudhub.Source, error) {
+			created, err := svc.SourcesStore().Add(ctx, *src)
+			return &created, err
+		},
+		put: func(ctx context.Context, svc *kv.Service, src *cloudhub.Source) error {
+			return svc.SourcesStore().Update(ctx, *src)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, src *cloudhub.Source) error {
+			return svc.SourcesStore().Delete(ctx, *src)
+		},
+		setID: func(src *cloudhub.Source, id string) {
+			src.ID, _ = strconv.Atoi(id)
+		},
+		headers: WriteSourceHeaders,
+		write:   WriteSource,
+	},
+	"servers": &resource[cloudhub.Server]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.Server, error) {
+			return svc.ServersStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.Server, error) {
+			sid, err := strconv.Atoi(id)
+			if err != nil {
+				return nil, cloudhub.ErrServerNotFound
+			}
+			srv, err := svc.ServersStore().Get(ctx, sid)
+			return &srv, err
+		},
+		add: func(ctx context.Context, svc *kv.Service, srv *cloudhub.Server) (*cloudhub.Server, error) {
+			created, err := svc.ServersStore().Add(ctx, *srv)
+			return &created, err
+		},
+		put: func(ctx context.Context, svc *kv.Service, srv *cloudhub.Server) error {
+			return svc.ServersStore().Update(ctx, *srv)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, srv *cloudhub.Server) error {
+			return svc.ServersStore().Delete(ctx, *srv)
+		},
+		setID: func(srv *cloudhub.Server, id string) {
+			srv.ID, _ = strconv.Atoi(id)
+		},
+		headers: WriteServerHeaders,
+		write:   WriteServer,
+	},
+	"dashboards": &resource[cloudhub.Dashboard]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.Dashboard, error) {
+			return svc.DashboardsStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.Dashboard, error) {
+			did, err := strconv.Atoi(id)
+			if err != nil {
+				return nil, cloudhub.ErrDashboardNotFound
+			}
+			d, err := svc.DashboardsStore().Get(ctx, cloudhub.DashboardID(did))
+			return &d, err
+		},
+		add: func(ctx context.Context, svc *kv.Service, d *cloudhub.Dashboard) (*cloudhub.Dashboard, error) {
+			created, err := svc.DashboardsStore().Add(ctx, *d)
+			return &created, err
+		},
+		put: func(ctx context.Context, svc *kv.Service, d *cloudhub.Dashboard) error {
+			return svc.DashboardsStore().Update(ctx, *d)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, d *cloudhub.Dashboard) error {
+			return svc.DashboardsStore().Delete(ctx, *d)
+		},
+		setID: func(d *cloudhub.Dashboard, id string) {
+			did, _ := strconv.Atoi(id)
+			d.ID = cloudhub.DashboardID(did)
+		},
+		headers: WriteDashboardsHeaders,
+		write:   WriteDashboard,
+	},
+	"mappings": &resource[cloudhub.Mapping]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.Mapping, error) {
+			return svc.MappingsStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.Mapping, error) {
+			return svc.MappingsStore().Get(ctx, id)
+		},
+		add: func(ctx context.Context, svc *kv.Service, m *cloudhub.Mapping) (*cloudhub.Mapping, error) {
+			return svc.MappingsStore().Add(ctx, m)
+		},
+		put: func(ctx context.Context, svc *kv.Service, m *cloudhub.Mapping) error {
+			return svc.MappingsStore().Update(ctx, m)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, m *cloudhub.Mapping) error {
+			return svc.MappingsStore().Delete(ctx, m)
+		},
+		setID:   func(m *cloudhub.Mapping, id string) { m.ID = id },
+		headers: WriteMappingsHeaders,
+		write:   WriteMappings,
+	},
+	"topologies": &resource[cloudhub.Topology]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.Topology, error) {
+			return svc.TopologiesStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.Topology, error) {
+			return svc.TopologiesStore().Get(ctx, cloudhub.TopologyQuery{ID: &id})
+		},
+		add: func(ctx context.Context, svc *kv.Service, t *cloudhub.Topology) (*cloudhub.Topology, error) {
+			return svc.TopologiesStore().Add(ctx, t)
+		},
+		put: func(ctx context.Context, svc *kv.Service, t *cloudhub.Topology) error {
+			return svc.TopologiesStore().Update(ctx, t)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, t *cloudhub.Topology) error {
+			return svc.TopologiesStore().Delete(ctx, t)
+		},
+		setID:   func(t *cloudhub.Topology, id string) { t.ID = id },
+		headers: WriteTopologyHeaders,
+		write:   WriteTopology,
+	},
+	"csps": &resource[cloudhub.CSP]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.CSP, error) {
+			return svc.CSPStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.CSP, error) {
+			return svc.CSPStore().Get(ctx, cloudhub.CSPQuery{ID: &id})
+		},
+		add: func(ctx context.Context, svc *kv.Service, c *cloudhub.CSP) (*cloudhub.CSP, error) {
+			return svc.CSPStore().Add(ctx, c)
+		},
+		put: func(ctx context.Context, svc *kv.Service, c *cloudhub.CSP) error {
+			return svc.CSPStore().Update(ctx, c)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, c *cloudhub.CSP) error {
+			return svc.CSPStore().Delete(ctx, c)
+		},
+		setID:   func(c *cloudhub.CSP, id string) { c.ID = id },
+		headers: WriteCSPHeaders,
+		write:   WriteCSP,
+	},
+	"devices": &resource[cloudhub.NetworkDevice]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.NetworkDevice, error) {
+			return svc.NetworkDeviceStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.NetworkDevice, error) {
+			return svc.NetworkDeviceStore().Get(ctx, cloudhub.NetworkDeviceQuery{ID: &id})
+		},
+		add: func(ctx context.Context, svc *kv.Service, d *cloudhub.NetworkDevice) (*cloudhub.NetworkDevice, error) {
+			return svc.NetworkDeviceStore().Add(ctx, d)
+		},
+		put: func(ctx context.Context, svc *kv.Service, d *cloudhub.NetworkDevice) error {
+			return svc.NetworkDeviceStore().Update(ctx, d)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, d *cloudhub.NetworkDevice) error {
+			return svc.NetworkDeviceStore().Delete(ctx, d)
+		},
+		setID:   func(d *cloudhub.NetworkDevice, id string) { d.ID = id },
+		headers: WriteNetworkDeviceHeaders,
+		write:   WriteNetworkDevice,
+	},
+	"device-orgs": &resource[cloudhub.NetworkDeviceOrg]{
+		all: func(ctx context.Context, svc *kv.Service) ([]cloudhub.NetworkDeviceOrg, error) {
+			return svc.NetworkDeviceOrgStore().All(ctx)
+		},
+		find: func(ctx context.Context, svc *kv.Service, id string) (*cloudhub.NetworkDeviceOrg, error) {
+			return svc.NetworkDeviceOrgStore().Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &id})
+		},
+		add: func(ctx context.Context, svc *kv.Service, o *cloudhub.NetworkDeviceOrg) (*cloudhub.NetworkDeviceOrg, error) {
+			return svc.NetworkDeviceOrgStore().Add(ctx, o)
+		},
+		put: func(ctx context.Context, svc *kv.Service, o *cloudhub.NetworkDeviceOrg) error {
+			return svc.NetworkDeviceOrgStore().Update(ctx, o)
+		},
+		delete: func(ctx context.Context, svc *kv.Service, o *cloudhub.NetworkDeviceOrg) error {
+			return svc.NetworkDeviceOrgStore().Delete(ctx, o)
+		},
+		setID:   func(o *cloudhub.NetworkDeviceOrg, id string) { o.ID = id },
+		headers: WriteNetworkDeviceOrgHeaders,
+		write:   WriteNetworkDeviceOrg,
+	},
+}
+
+// resourceAliases are the accepted alternative names of the resource types
+var resourceAliases = map[string]string{
+	"organization":    "organizations",
+	"orgs":            "organizations",
+	"org":             "organizations",
+	"user":            "users",
+	"source":          "sources",
+	"server":          "servers",
+	"dashboard":       "dashboards",
+	"mapping":         "mappings",
+	"topology":        "topologies",
+	"csp":             "csps",
+	"device":          "devices",
+	"network-devices": "devices",
+	"device-org":      "device-orgs",
+}
+
+// lookupResourceType returns the resource type of the given name or alias
+func lookupResourceType(name string) (resourceType, error) {
+	name = strings.ToLower(name)
+	if alias, ok := resourceAliases[name]; ok {
+		name = alias
+	}
+	if r, ok := resourceTypes[name]; ok {
+		return r, nil
+	}
+
+	names := make([]string, 0, len(resourceTypes))
+	for n := range resourceTypes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown resource type %q, must be one of %s", name, strings.Join(names, ", "))
+}
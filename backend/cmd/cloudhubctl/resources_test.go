@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func Test_lookupResourceType(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "organizations", want: "organizations"},
+		{name: "org", want: "organizations"},
+		{name: "Orgs", want: "organizations"},
+		{name: "device-org", want: "device-orgs"},
+		{name: "network-devices", want: "devices"},
+		{name: "TOPOLOGY", want: "topologies"},
+		{name: "layouts", wantErr: true},
+		{name: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := lookupResourceType(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("lookupResourceType(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != resourceTypes[tt.want] {
+			t.Errorf("lookupResourceType(%q) is not the resource type of %s", tt.name, tt.want)
+		}
+	}
+}
+
+func Test_openService(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		db      string
+		wantErr string
+	}{
+		{name: "bolt url", db: "bolt://" + filepath.Join(dir, "a.db")},
+		{name: "boltdb url", db: "boltdb://" + filepath.Join(dir, "b.db")},
+		{name: "bolt path", db: filepath.Join(dir, "c.db")},
+		{name: "bolt url with a host", db: "bolt://localhost/cloudhub-v1.db", wantErr: "ambiguous uri"},
+		{name: "unknown scheme", db: "mysql://localhost/cloudhub", wantErr: "invalid uri scheme 'mysql'"},
+		{name: "invalid url", db: ":", wantErr: "missing protocol scheme"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := (&dbOptions{DB: tt.db}).open(context.Background())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("open(%s) error = %v, want %s", tt.db, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("open(%s) error = %v", tt.db, err)
+			}
+			svc.Close()
+		})
+	}
+}
+
+func Test_storeResources(t *testing.T) {
+	ctx := context.Background()
+	svc, err := (&dbOptions{DB: filepath.Join(t.TempDir(), "cloudhub-v1.db")}).open(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svc.Close()
+
+	tests := []struct {
+		resource string
+		create   string
+		update   string
+		want     func(doc document) bool
+	}{
+		{
+			resource: "organizations",
+			create:   "name: acme\ndefaultRole: viewer\n",
+			update:   `{"defaultRole": "editor"}`,
+			want: func(doc document) bool {
+				return doc["name"] == "acme" && doc["defaultRole"] == "editor"
+			},
+		},
+		{
+			resource: "sources",
+			create:   `{"name": "influx", "url": "http://localhost:8086", "telegraf": "telegraf", "organization": "default"}`,
+			update:   "telegraf: metrics\n",
+			want: func(doc document) bool {
+				return doc["name"] == "influx" && doc["telegraf"] == "metrics" && doc["url"] == "http://localhost:8086"
+			},
+		},
+		{
+			resource: "dashboards",
+			create:   "name: hosts\norganization: default\n",
+			update:   "name: servers\n",
+			want: func(doc document) bool {
+				return doc["name"] == "servers" && doc["organization"] == "default"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.resource, func(t *testing.T) {
+			r, err := lookupResourceType(tt.resource)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res := &storeResources{svc: svc, r: r}
+			var out bytes.Buffer
+			p := &printer{format: outputJSON, out: &out}
+
+			if err := res.create(ctx, []byte(tt.create), p); err != nil {
+				t.Fatalf("create() error = %v", err)
+			}
+			var created document
+			if err := json.Unmarshal(out.Bytes(), &created); err != nil {
+				t.Fatal(err)
+			}
+			id := documentID(created)
+			if id == "" || id == "0" {
+				t.Fatalf("create() = %v, want an ID set by the store", created)
+			}
+
+			out.Reset()
+			if err := res.update(ctx, id, []byte(tt.update), p); err != nil {
+				t.Fatalf("update() error = %v", err)
+			}
+			out.Reset()
+			if err := res.get(ctx, id, p); err != nil {
+				t.Fatalf("get() error = %v", err)
+			}
+			var got document
+			if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if documentID(got) != id || !tt.want(got) {
+				t.Errorf("get() = %v after update", got)
+			}
+
+			if err := res.remove(ctx, id); err != nil {
+				t.Fatalf("remove() error = %v", err)
+			}
+			if err := res.get(ctx, id, p); err == nil {
+				t.Errorf("get() of a removed resource expected an error")
+			}
+		})
+	}
+}
+
+func Test_printer(t *testing.T) {
+	org := &cloudhub.Organization{ID: "1", Name: "acme", DefaultRole: "viewer"}
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: outputJSON, want: "{\n  \"id\": \"1\",\n  \"name\": \"acme\",\n  \"defaultRole\": \"viewer\""},
+		{format: outputYAML, want: "id: \"1\"\nname: acme\ndefaultRole: viewer\n"},
+		{format: outputTable, want: "table"},
+		{format: "", want: "table"},
+		{format: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		p := &printer{format: tt.format, out: &out}
+		err := p.print(org, func(w io.Writer) { out.WriteString("table") })
+		if (err != nil) != tt.wantErr {
+			t.Errorf("print() of %q error = %v, wantErr %v", tt.format, err, tt.wantErr)
+			continue
+		}
+		if !strings.HasPrefix(out.String(), tt.want) {
+			t.Errorf("print() of %q = %q, want %q", tt.format, out.String(), tt.want)
+		}
+	}
+}
+
+func Test_decodeDocument(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    cloudhub.Organization
+		wantErr bool
+	}{
+		{name: "json", data: `{"name": "acme", "defaultRole": "viewer"}`, want: cloudhub.Organization{Name: "acme", DefaultRole: "viewer"}},
+		{name: "yaml", data: "name: acme\ndefaultRole: viewer\n", want: cloudhub.Organization{Name: "acme", DefaultRole: "viewer"}},
+		{name: "empty", data: "", wantErr: true},
+		{name: "invalid yaml", data: "name: [acme", wantErr: true},
+		{name: "wrong type", data: "name: [acme]", wantErr: true},
+	}
+	for _, tt := range tests {
+		var got cloudhub.Organization
+		err := decodeDocument([]byte(tt.data), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("decodeDocument() of %s error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && (got.Name != tt.want.Name || got.DefaultRole != tt.want.DefaultRole) {
+			t.Errorf("decodeDocument() of %s = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
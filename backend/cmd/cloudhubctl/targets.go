@@ -0,0 +1,396 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kapacitor"
+	"github.com/snetsystems/cloudhub/backend/kv"
+)
+
+// target is the live configuration a manifest is planned against and applied to.
+// The parent is the live document of the resource holding the resources of the kind,
+// nil for organizations.
+type target interface {
+	list(ctx context.Context, k *kind, parent document) ([]document, error)
+	create(ctx context.Context, k *kind, parent, spec document) (document, error)
+	update(ctx context.Context, k *kind, parent, live, spec document) error
+	remove(ctx context.Context, k *kind, parent, live document) error
+}
+
+// documentID returns the ID of a live document
+func documentID(doc document) string {
+	if v, ok := doc["id"]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// storeTarget applies manifests with the kv services; alert rules are applied to the
+// kapacitor of their server.
+type storeTarget struct {
+	svc *kv.Service
+}
+
+// parentFields are the fields of the stored resources holding the ID of their parent
+var parentFields = map[*kind]string{
+	mappingKind:   "organizationId",
+	sourceKind:    "organization",
+	serverKind:    "srcId",
+	dashboardKind: "organization",
+	deviceKind:    "organization",
+}
+
+func (t *storeTarget) list(ctx context.Context, k *kind, parent document) ([]document, error) {
+	switch k {
+	case topologyKind:
+		orgID := documentID(parent)
+		topology, err := t.svc.TopologiesStore().Get(ctx, cloudhub.TopologyQuery{Organization: &orgID})
+		if err == cloudhub.ErrTopologyNotFound {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		return []document{topologyDocument(topology)}, nil
+	case ruleKind:
+		tasks, err := kapacitorClient(parent).All(ctx)
+		if err != nil {
+			return nil, err
+		}
+		docs := []document{}
+		for _, task := range tasks {
+			doc, err := toDocument(task.Rule)
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, doc)
+		}
+		return docs, nil
+	}
+
+	docs, err := resourceTypes[k.key].documents(ctx, t.svc)
+	if err != nil || parent == nil {
+		return docs, err
+	}
+	children := []document{}
+	for _, doc := range docs {
+		if fmt.Sprint(doc[parentFields[k]]) == documentID(parent) {
+			children = append(children, doc)
+		}
+	}
+	return children, nil
+}
+
+func (t *storeTarget) create(ctx context.Context, k *kind, parent, spec document) (document, error) {
+	switch k {
+	case topologyKind:
+		topology := &cloudhub.Topology{Organization: documentID(parent)}
+		if err := fromTopologyDocument(spec, topology); err != nil {
+			return nil, err
+		}
+		topology, err := t.svc.TopologiesStore().Add(ctx, topology)
+		if err != nil {
+			return nil, err
+		}
+		return topologyDocument(topology), nil
+	case ruleKind:
+		var rule cloudhub.AlertRule
+		if err := fromDocument(spec, &rule); err != nil {
+			return nil, err
+		}
+		task, err := kapacitorClient(parent).Create(ctx, rule)
+		if err != nil {
+			return nil, err
+		}
+		return toDocument(task.Rule)
+	}
+
+	doc := document{}
+	for f, v := range spec {
+		doc[f] = v
+	}
+	if parent != nil {
+		doc[parentFields[k]] = documentID(parent)
+	}
+	if k == serverKind {
+		doc["organization"] = parent["organization"]
+	}
+	return resourceTypes[k.key].addDocument(ctx, t.svc, doc)
+}
+
+func (t *storeTarget) update(ctx context.Context, k *kind, parent, live, spec document) error {
+	switch k {
+	case topologyKind:
+		orgID := documentID(parent)
+		topology, err := t.svc.TopologiesStore().Get(ctx, cloudhub.TopologyQuery{Organization: &orgID})
+		if err != nil {
+			return err
+		}
+		if err := fromTopologyDocument(spec, topology); err != nil {
+			return err
+		}
+		return t.svc.TopologiesStore().Update(ctx, topology)
+	case ruleKind:
+		var rule cloudhub.AlertRule
+		if err := fromDocument(live, &rule); err != nil {
+			return err
+		}
+		if err := fromDocument(spec, &rule); err != nil {
+			return err
+		}
+		c := kapacitorClient(parent)
+		_, err := c.Update(ctx, c.Href(rule.ID), rule)
+		return err
+	}
+	return resourceTypes[k.key].updateDocument(ctx, t.svc, documentID(live), spec)
+}
+
+func (t *storeTarget) remove(ctx context.Context, k *kind, parent, live document) error {
+	switch k {
+	case topologyKind:
+		orgID := documentID(parent)
+		topology, err := t.svc.TopologiesStore().Get(ctx, cloudhub.TopologyQuery{Organization: &orgID})
+		if err != nil {
+			return err
+		}
+		return t.svc.TopologiesStore().Delete(ctx, topology)
+	case ruleKind:
+		c := kapacitorClient(parent)
+		return c.Delete(ctx, c.Href(documentID(live)))
+	}
+	return resourceTypes[k.key].remove(ctx, t.svc, documentID(live))
+}
+
+// kapacitorClient returns the client of the kapacitor of the server document
+func kapacitorClient(server document) *kapacitor.Client {
+	var srv cloudhub.Server
+	fromDocument(server, &srv)
+	return kapacitor.NewClient(srv.URL, srv.Username, srv.Password, srv.InsecureSkipVerify)
+}
+
+// topologyDocument returns the document of a topology as the API shows it; the JSON of
+// the stored topology quotes the diagram
+func topologyDocument(t *cloudhub.Topology) document {
+	doc := document{
+		"id":           t.ID,
+		"organization": t.Organization,
+		"diagram":      t.Diagram,
+	}
+	if len(t.Preferences) > 0 {
+		preferences := make([]interface{}, len(t.Preferences))
+		for i, p := range t.Preferences {
+			preferences[i] = p
+		}
+		doc["preferences"] = preferences
+	}
+	if options, err := toDocument(t.TopologyOptions); err == nil {
+		doc["topologyOptions"] = options
+	}
+	return doc
+}
+
+// fromTopologyDocument sets the fields of the topology document on the topology
+func fromTopologyDocument(doc document, t *cloudhub.Topology) error {
+	var fields struct {
+		Diagram         *string                   `json:"diagram"`
+		Preferences     *[]string                 `json:"preferences"`
+		TopologyOptions *cloudhub.TopologyOptions `json:"topologyOptions"`
+	}
+	if err := fromDocument(doc, &fields); err != nil {
+		return err
+	}
+	if fields.Diagram != nil {
+		t.Diagram = *fields.Diagram
+	}
+	if fields.Preferences != nil {
+		t.Preferences = *fields.Preferences
+	}
+	if fields.TopologyOptions != nil {
+		t.TopologyOptions = *fields.TopologyOptions
+	}
+	return nil
+}
+
+// apiTarget applies manifests through the API of a server. Resources of organizations
+// are managed in the organization, which is switched to as needed.
+type apiTarget struct {
+	client *apiClient
+	org    string // current organization of the session
+}
+
+func (t *apiTarget) list(ctx context.Context, k *kind, parent document) ([]document, error) {
+	var raw []json.RawMessage
+	var err error
+	switch k {
+	case organizationKind:
+		raw, err = collection(ctx, t.client, "/cloudhub/v1/organizations", "organizations", "id")
+	case serverKind:
+		raw, err = collection(ctx, t.client, link(parent, "kapacitors"), "kapacitors", "id")
+	case ruleKind:
+		raw, err = collection(ctx, t.client, link(parent, "rules"), "rules", "id")
+	default:
+		if err := t.switchOrganization(ctx, k, parent); err != nil {
+			return nil, err
+		}
+		r := remoteResourceTypes[k.key]
+		if k == topologyKind {
+			r = remoteResourceTypes["topologies"]
+		}
+		raw, err = collection(ctx, t.client, r.path, r.key, "id")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []document{}
+	for _, item := range raw {
+		var doc document
+		if err := json.Unmarshal(item, &doc); err != nil {
+			return nil, err
+		}
+		// mappings and devices are listed for all organizations
+		if (k == mappingKind || k == deviceKind) && fmt.Sprint(doc[parentFields[k]]) != documentID(parent) {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (t *apiTarget) create(ctx context.Context, k *kind, parent, spec document) (document, error) {
+	var path string
+	switch k {
+	case organizationKind:
+		path = "/cloudhub/v1/organizations"
+	case serverKind:
+		path = link(parent, "kapacitors")
+	case ruleKind:
+		path = link(parent, "rules")
+	default:
+		if err := t.switchOrganization(ctx, k, parent); err != nil {
+			return nil, err
+		}
+		doc := document{}
+		for f, v := range spec {
+			doc[f] = v
+		}
+		if f, ok := parentFields[k]; ok {
+			doc[f] = documentID(parent)
+		}
+		spec = doc
+
+		switch k {
+		case topologyKind:
+			path, spec = remoteResourceTypes["topologies"].path, topologyRequest(spec)
+		case deviceKind:
+			raw, err := createDevice(ctx, t.client, spec)
+			if err != nil {
+				return nil, err
+			}
+			return rawDocument(raw)
+		default:
+			path = remoteResourceTypes[k.key].path
+		}
+	}
+
+	var raw json.RawMessage
+	if err := t.client.do(ctx, http.MethodPost, path, spec, &raw); err != nil {
+		return nil, err
+	}
+	return rawDocument(raw)
+}
+
+func (t *apiTarget) update(ctx context.Context, k *kind, parent, live, spec document) error {
+	if err := t.switchOrganization(ctx, k, parent); err != nil {
+		return err
+	}
+
+	method, body := http.MethodPatch, spec
+	// mappings, rules and topologies are replaced rather than patched
+	if k == mappingKind || k == ruleKind || k == topologyKind {
+		body = document{}
+		for f, v := range live {
+			body[f] = v
+		}
+		for f, v := range spec {
+			body[f] = v
+		}
+		delete(body, "links")
+	}
+	switch k {
+	case mappingKind, ruleKind:
+		method = http.MethodPut
+	case topologyKind:
+		body = topologyRequest(body)
+	}
+
+	path := link(live, "self")
+	if path == "" {
+		path = remoteResourceTypes[k.key].path + "/" + documentID(live)
+	}
+	return t.client.do(ctx, method, path, body, nil)
+}
+
+func (t *apiTarget) remove(ctx context.Context, k *kind, parent, live document) error {
+	if err := t.switchOrganization(ctx, k, parent); err != nil {
+		return err
+	}
+	if k == deviceKind {
+		return removeDevice(ctx, t.client, documentID(live))
+	}
+
+	path := link(live, "self")
+	if path == "" {
+		path = remoteResourceTypes[k.key].path + "/" + documentID(live)
+	}
+	return t.client.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+// switchOrganization makes the organization of the resources the current organization
+// of the session, as the API lists and creates resources in the current organization
+func (t *apiTarget) switchOrganization(ctx context.Context, k *kind, parent document) error {
+	if !organizationKind.holds(k) {
+		return nil
+	}
+	orgID := documentID(parent)
+	if orgID == t.org {
+		return nil
+	}
+	req := map[string]string{"organization": orgID}
+	if err := t.client.do(ctx, http.MethodPut, "/cloudhub/v1/me", req, nil); err != nil {
+		return fmt.Errorf("unable to switch to organization %s: %s", orgID, err)
+	}
+	t.org = orgID
+	return nil
+}
+
+// topologyRequest returns the request of the API creating or updating the topology
+// document, which holds the diagram as cells
+func topologyRequest(doc document) document {
+	return document{
+		"cells":           doc["diagram"],
+		"preferences":     doc["preferences"],
+		"topologyOptions": doc["topologyOptions"],
+	}
+}
+
+// link returns a link of the live document
+func link(doc document, name string) string {
+	links, _ := doc["links"].(map[string]interface{})
+	s, _ := links[name].(string)
+	return s
+}
+
+func rawDocument(raw json.RawMessage) (document, error) {
+	doc := document{}
+	if len(raw) == 0 {
+		return doc, nil
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -81,6 +81,11 @@ func WriteColumnEncodingHeaders(w io.Writer) {
 	fmt.Fprintln(w, "Type\tValue\tName")
 }
 
+// WriteIntegrityIssueHeaders check headers
+func WriteIntegrityIssueHeaders(w io.Writer) {
+	fmt.Fprintln(w, "Severity\tResource\tID\tField\tReference\tRepair\tMessage")
+}
+
 // WriteHeaders store headers
 func WriteHeaders(w io.Writer, anything interface{}) {
 	target := reflect.ValueOf(anything)
@@ -198,4 +203,59 @@ func WriteColumnEncoding(w io.Writer, organizationConfig *cloudhub.OrganizationC
 
 		fmt.Fprintf(w, "%s\t%d\t%s\n", logViewerColumn.Name, logViewerColumn.Position, strings.Join(encodings, ","))	
 	}
-}
\ No newline at end of file
+}
+
+// WriteIntegrityIssue check IntegrityIssue
+func WriteIntegrityIssue(w io.Writer, issue *cloudhub.IntegrityIssue) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", issue.Severity, issue.ResourceType, issue.ResourceID, issue.Field, issue.Reference, issue.Repair, issue.Message)
+}
+
+// WriteSourceHeaders stores-info headers
+func WriteSourceHeaders(w io.Writer) {
+	fmt.Fprintln(w, "ID\tName\tType\tURL\tTelegraf\tDefaultRP\tDefault\tOrganization")
+}
+
+// WriteSource stores-info Sources
+func WriteSource(w io.Writer, source *cloudhub.Source) {
+	fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%t\t%s\n", source.ID, source.Name, source.Type, source.URL, source.Telegraf, source.DefaultRP, source.Default, source.Organization)
+}
+
+// WriteTopologyHeaders stores-info headers
+func WriteTopologyHeaders(w io.Writer) {
+	fmt.Fprintln(w, "ID\tOrganization\tVersion\tDiagram")
+}
+
+// WriteTopology stores-info Topologies
+func WriteTopology(w io.Writer, topology *cloudhub.Topology) {
+	fmt.Fprintf(w, "%s\t%s\t%d\t%d bytes\n", topology.ID, topology.Organization, topology.Version, len(topology.Diagram))
+}
+
+// WriteCSPHeaders stores-info headers
+func WriteCSPHeaders(w io.Writer) {
+	fmt.Fprintln(w, "ID\tProvider\tNameSpace\tAccessKey\tOrganization\tMinion")
+}
+
+// WriteCSP stores-info CSP
+func WriteCSP(w io.Writer, csp *cloudhub.CSP) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", csp.ID, csp.Provider, csp.NameSpace, csp.AccessKey, csp.Organization, csp.Minion)
+}
+
+// WriteNetworkDeviceHeaders stores-info headers
+func WriteNetworkDeviceHeaders(w io.Writer) {
+	fmt.Fprintln(w, "ID\tOrganization\tDeviceIP\tHostname\tDeviceType\tDeviceVendor\tDeviceOS\tLearningState")
+}
+
+// WriteNetworkDevice stores-info NetworkDevices
+func WriteNetworkDevice(w io.Writer, device *cloudhub.NetworkDevice) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", device.ID, device.Organization, device.DeviceIP, device.Hostname, device.DeviceType, device.DeviceVendor, device.DeviceOS, device.LearningState)
+}
+
+// WriteNetworkDeviceOrgHeaders stores-info headers
+func WriteNetworkDeviceOrgHeaders(w io.Writer) {
+	fmt.Fprintln(w, "Organization\tLoadModule\tMLFunction\tDataDuration\tCollectorServer\tLearnedDevices\tCollectedDevices\tLearningCron")
+}
+
+// WriteNetworkDeviceOrg stores-info NetworkDeviceOrgs
+func WriteNetworkDeviceOrg(w io.Writer, org *cloudhub.NetworkDeviceOrg) {
+	fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\n", org.ID, org.LoadModule, org.MLFunction, org.DataDuration, org.CollectorServer, strings.Join(org.LearnedDevicesIDs, ","), strings.Join(org.CollectedDevicesIDs, ","), org.LearningCron)
+}
@@ -160,6 +160,15 @@ func (c *UserStore) All(ctx context.Context) ([]cloudhub.User, error) {
 	return res, nil
 }
 
+// Page returns the page of the users in influx q selects, the ID of a user being its name
+func (c *UserStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.User]) ([]cloudhub.User, error) {
+	users, err := c.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cloudhub.PageOf(users, q, func(u cloudhub.User) string { return u.Name }), nil
+}
+
 // ToEnterprise converts cloudhub permission shape to enterprise
 func ToEnterprise(perms cloudhub.Permissions) Permissions {
 	res := Permissions{}
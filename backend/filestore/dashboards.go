@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"strconv"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 )
@@ -56,6 +57,15 @@ func (d *Dashboards) All(ctx context.Context) ([]cloudhub.Dashboard, error) {
 	return dashboards, nil
 }
 
+// Page returns the page of the dashboards of the directory q selects, in the order of their files
+func (d *Dashboards) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error) {
+	dashboards, err := d.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cloudhub.PageOf(dashboards, q, func(b cloudhub.Dashboard) string { return strconv.Itoa(int(b.ID)) }), nil
+}
+
 // Get returns a dashboard file from the dashboard directory
 func (d *Dashboards) Get(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
 	board, file, err := d.idToFile(id)
@@ -70,6 +70,21 @@ func (a *Protoboards) All(ctx context.Context) ([]cloudhub.Protoboard, error) {
 	return protoboards, nil
 }
 
+// Add is not supported, protoboards of the directory are deployed as files
+func (a *Protoboards) Add(ctx context.Context, protoboard cloudhub.Protoboard) (cloudhub.Protoboard, error) {
+	return cloudhub.Protoboard{}, cloudhub.ErrProtoboardReadOnly
+}
+
+// Delete is not supported, protoboards of the directory are deployed as files
+func (a *Protoboards) Delete(ctx context.Context, protoboard cloudhub.Protoboard) error {
+	return cloudhub.ErrProtoboardReadOnly
+}
+
+// Update is not supported, protoboards of the directory are deployed as files
+func (a *Protoboards) Update(ctx context.Context, protoboard cloudhub.Protoboard) error {
+	return cloudhub.ErrProtoboardReadOnly
+}
+
 // Get returns a protoboard file from the protoboard directory
 func (a *Protoboards) Get(ctx context.Context, ID string) (cloudhub.Protoboard, error) {
 	l, file, err := a.idToFile(ID)
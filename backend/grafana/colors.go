@@ -0,0 +1,210 @@
+package grafana
+
+import (
+	"strconv"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// paletteColor is a color of the palette of thresholds of CloudHub
+type paletteColor struct {
+	name    string
+	hex     string
+	r, g, b int
+}
+
+func newPaletteColor(name, hex string) paletteColor {
+	r, g, b, _ := parseHex(hex)
+	return paletteColor{name: name, hex: hex, r: r, g: g, b: b}
+}
+
+// palette are the colors of thresholds of the UI
+var palette = []paletteColor{
+	newPaletteColor("ruby", "#BF3D5E"),
+	newPaletteColor("fire", "#DC4E58"),
+	newPaletteColor("curacao", "#F95F53"),
+	newPaletteColor("tiger", "#F48D38"),
+	newPaletteColor("pineapple", "#FFB94A"),
+	newPaletteColor("thunder", "#FFD255"),
+	newPaletteColor("honeydew", "#7CE490"),
+	newPaletteColor("rainforest", "#4ED8A0"),
+	newPaletteColor("viridian", "#32B08C"),
+	newPaletteColor("ocean", "#4591ED"),
+	newPaletteColor("pool", "#22ADF6"),
+	newPaletteColor("laser", "#00C9FF"),
+	newPaletteColor("planet", "#513CC6"),
+	newPaletteColor("star", "#7A65F2"),
+	newPaletteColor("comet", "#9394FF"),
+	newPaletteColor("pepper", "#383846"),
+	newPaletteColor("graphite", "#545667"),
+	newPaletteColor("white", "#ffffff"),
+	newPaletteColor("castle", "#292933"),
+}
+
+// namedColors are the colors of the palette of Grafana
+var namedColors = map[string]string{
+	"green":       "#73BF69",
+	"red":         "#F2495C",
+	"yellow":      "#FADE2A",
+	"orange":      "#FF9830",
+	"blue":        "#5794F2",
+	"purple":      "#B877D9",
+	"white":       "#FFFFFF",
+	"black":       "#000000",
+	"transparent": "#FFFFFF",
+	"text":        "#FFFFFF",
+}
+
+// baseValue is the value of the base color of single stats and tables
+const baseValue = "-999999999999999999"
+
+// colors returns the colors of the cell from the thresholds of the panel
+func (c *converter) colors(p Panel, cellType string) []cloudhub.CellColor {
+	type step struct {
+		color string
+		value *float64
+	}
+	steps := []step{}
+	for _, s := range p.FieldConfig.Defaults.Thresholds.Steps {
+		steps = append(steps, step{s.Color, s.Value})
+	}
+	// singlestat panels of Grafana 6 have a color more than thresholds, which is the base color
+	if len(steps) == 0 && len(p.Colors) > 0 {
+		steps = append(steps, step{color: p.Colors[0]})
+		for i, t := range strings.Split(p.Thresholds, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+			if err != nil || i+1 >= len(p.Colors) {
+				break
+			}
+			steps = append(steps, step{p.Colors[i+1], &v})
+		}
+	}
+	if len(steps) == 0 {
+		return []cloudhub.CellColor{}
+	}
+	if p.FieldConfig.Defaults.Thresholds.Mode == "percentage" {
+		c.warn(p.Title, "thresholds in percentage are converted as absolute values")
+	}
+
+	colors := []cloudhub.CellColor{}
+	color := func(id, colorType, value, grafana string) cloudhub.CellColor {
+		pc, ok := nearestColor(grafana)
+		if !ok {
+			c.warn(p.Title, "color %s is not converted", grafana)
+		}
+		return cloudhub.CellColor{ID: id, Type: colorType, Hex: pc.hex, Name: pc.name, Value: value}
+	}
+	format := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	}
+
+	switch cellType {
+	case "single-stat", "line-plus-single-stat", "table":
+		for i, s := range steps {
+			if s.value == nil {
+				colors = append(colors, color("base", "text", baseValue, s.color))
+				continue
+			}
+			colors = append(colors, color(strconv.Itoa(i), "text", format(s.value), s.color))
+		}
+	case "gauge":
+		// gauges range from a min color to a max color with thresholds in between
+		min, max := "0", "100"
+		if v := p.FieldConfig.Defaults.Min; v != nil {
+			min = format(v)
+		}
+		if v := p.FieldConfig.Defaults.Max; v != nil {
+			max = format(v)
+		}
+		colors = append(colors, color("0", "min", min, steps[0].color))
+		for i, s := range steps[1:] {
+			colors = append(colors, color(strconv.Itoa(i+1), "threshold", format(s.value), s.color))
+		}
+		colors = append(colors, color(strconv.Itoa(len(steps)), "max", max, steps[len(steps)-1].color))
+	default:
+		// graphs of Grafana show thresholds as lines or areas, which CloudHub does not
+		if len(steps) > 1 {
+			c.warn(p.Title, "thresholds of graphs are not converted")
+		}
+	}
+	return colors
+}
+
+// nearestColor returns the color of the palette nearest to the color of Grafana,
+// which is a name, a hex or an rgb(a) color. It returns false for unknown colors.
+func nearestColor(color string) (paletteColor, bool) {
+	color = strings.ToLower(strings.TrimSpace(color))
+	for _, prefix := range []string{"super-light-", "light-", "semi-dark-", "dark-"} {
+		color = strings.TrimPrefix(color, prefix)
+	}
+	if hex, ok := namedColors[color]; ok {
+		color = hex
+	}
+
+	r, g, b, ok := parseHex(color)
+	if !ok {
+		r, g, b, ok = parseRGB(color)
+	}
+	if !ok {
+		// unknown colors are shown in graphite
+		return palette[16], false
+	}
+
+	nearest, distance := palette[0], -1
+	for _, pc := range palette {
+		d := (pc.r-r)*(pc.r-r) + (pc.g-g)*(pc.g-g) + (pc.b-b)*(pc.b-b)
+		if distance < 0 || d < distance {
+			nearest, distance = pc, d
+		}
+	}
+	return nearest, true
+}
+
+// parseHex parses #rrggbb and #rgb colors
+func parseHex(s string) (int, int, int, bool) {
+	if !strings.HasPrefix(s, "#") {
+		return 0, 0, 0, false
+	}
+	s = s[1:]
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return 0, 0, 0, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	return int(v >> 16), int(v >> 8 & 0xff), int(v & 0xff), true
+}
+
+// parseRGB parses rgb(r, g, b) and rgba(r, g, b, a) colors
+func parseRGB(s string) (int, int, int, bool) {
+	var args string
+	switch {
+	case strings.HasPrefix(s, "rgba(") && strings.HasSuffix(s, ")"):
+		args = s[len("rgba(") : len(s)-1]
+	case strings.HasPrefix(s, "rgb(") && strings.HasSuffix(s, ")"):
+		args = s[len("rgb(") : len(s)-1]
+	default:
+		return 0, 0, 0, false
+	}
+	parts := strings.Split(args, ",")
+	if len(parts) < 3 {
+		return 0, 0, 0, false
+	}
+	rgb := [3]int{}
+	for i := range rgb {
+		v, err := strconv.Atoi(strings.TrimSpace(parts[i]))
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		rgb[i] = v
+	}
+	return rgb[0], rgb[1], rgb[2], true
+}
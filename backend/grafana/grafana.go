@@ -0,0 +1,380 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// Dashboard is a Grafana dashboard as exported by Grafana (schema 5 and later)
+type Dashboard struct {
+	Title      string            `json:"title"`
+	Panels     []Panel           `json:"panels"`
+	Rows       []json.RawMessage `json:"rows"` // Rows hold the panels of dashboards before schema 5
+	Templating struct {
+		List []Variable `json:"list"`
+	} `json:"templating"`
+}
+
+// Panel is a panel of a Grafana dashboard
+type Panel struct {
+	Type        string          `json:"type"`
+	Title       string          `json:"title"`
+	GridPos     GridPos         `json:"gridPos"`
+	Datasource  json.RawMessage `json:"datasource"` // Datasource is the name of the datasource, or a reference to it
+	Targets     []Target        `json:"targets"`
+	FieldConfig FieldConfig     `json:"fieldConfig"`
+	Options     PanelOptions    `json:"options"`
+	Panels      []Panel         `json:"panels"` // Panels are the panels of a collapsed row
+
+	// The fields of panels before Grafana 7
+	Content     string   `json:"content"`
+	Bars        bool     `json:"bars"`
+	Stack       bool     `json:"stack"`
+	SteppedLine bool     `json:"steppedLine"`
+	Thresholds  string   `json:"thresholds"` // Thresholds of singlestat panels, e.g. "80,90"
+	Colors      []string `json:"colors"`     // Colors of the thresholds of singlestat panels
+	Format      string   `json:"format"`
+	Decimals    *int32   `json:"decimals"`
+	Sparkline   struct {
+		Show bool `json:"show"`
+	} `json:"sparkline"`
+	Yaxes []struct {
+		Label   string          `json:"label"`
+		Format  string          `json:"format"`
+		LogBase int             `json:"logBase"`
+		Min     json.RawMessage `json:"min"`
+		Max     json.RawMessage `json:"max"`
+	} `json:"yaxes"`
+	Legend struct {
+		Show      bool `json:"show"`
+		RightSide bool `json:"rightSide"`
+	} `json:"legend"`
+}
+
+// GridPos is the geometry of a panel in the grid of 24 columns of Grafana
+type GridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+// FieldConfig are the display options of the values of a panel
+type FieldConfig struct {
+	Defaults struct {
+		Unit       string   `json:"unit"`
+		Decimals   *int32   `json:"decimals"`
+		Min        *float64 `json:"min"`
+		Max        *float64 `json:"max"`
+		Thresholds struct {
+			Mode  string `json:"mode"`
+			Steps []struct {
+				Color string   `json:"color"`
+				Value *float64 `json:"value"` // Value is nil for the base step
+			} `json:"steps"`
+		} `json:"thresholds"`
+		Custom struct {
+			DrawStyle         string `json:"drawStyle"`
+			LineInterpolation string `json:"lineInterpolation"`
+			AxisLabel         string `json:"axisLabel"`
+			Stacking          struct {
+				Mode string `json:"mode"`
+			} `json:"stacking"`
+			ScaleDistribution struct {
+				Type string `json:"type"`
+			} `json:"scaleDistribution"`
+		} `json:"custom"`
+	} `json:"defaults"`
+}
+
+// PanelOptions are the options of the panels of Grafana 7 and later
+type PanelOptions struct {
+	Content string `json:"content"`
+	Legend  struct {
+		DisplayMode string `json:"displayMode"`
+		Placement   string `json:"placement"`
+		ShowLegend  *bool  `json:"showLegend"`
+	} `json:"legend"`
+	GraphMode string `json:"graphMode"` // GraphMode of stat panels shows a sparkline if "area"
+}
+
+// Options tell how the converted dashboard refers to the sources of CloudHub
+type Options struct {
+	// Sources are the links of the sources of the queries of the datasources of
+	// Grafana, by name or UID of datasource
+	Sources map[string]string
+	// Source is the link of the source of the queries of other datasources; queries
+	// without source use the default source
+	Source string
+	// Databases are the databases of the queries and of the template variables of the
+	// datasources of Grafana, by name or UID of datasource
+	Databases map[string]string
+	// Database is the database of the queries and of the template variables listing
+	// tag values, fields or measurements without database of other datasources.
+	// Defaults to telegraf.
+	Database string
+}
+
+// Warning tells a part of a Grafana dashboard which could not be converted, or only in part
+type Warning struct {
+	Panel    string `json:"panel,omitempty"`    // Panel is the title of the panel
+	Variable string `json:"variable,omitempty"` // Variable is the name of the templating variable
+	Message  string `json:"message"`
+}
+
+const (
+	// gridColumns is the number of columns of the grid of CloudHub dashboards
+	gridColumns = 96
+	// columnScale scales the 24 columns of Grafana to the columns of CloudHub
+	columnScale = gridColumns / 24
+	// rowScale scales rows of Grafana, 30px with a margin of 8px, to the rows of CloudHub,
+	// 10.6px with a margin of 4px
+	rowScale = 38 / 14.6
+)
+
+// Convert converts the Grafana dashboard to a CloudHub dashboard. Parts which cannot
+// be converted are left out and reported by the warnings.
+func Convert(data []byte, opts Options) (cloudhub.Dashboard, []Warning, error) {
+	var g Dashboard
+	if err := json.Unmarshal(data, &g); err != nil {
+		return cloudhub.Dashboard{}, nil, fmt.Errorf("invalid Grafana dashboard: %s", err)
+	}
+	// dashboards exported for sharing hold the dashboard next to its meta data
+	if g.Title == "" && len(g.Panels) == 0 {
+		var wrapped struct {
+			Dashboard json.RawMessage `json:"dashboard"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err == nil && len(wrapped.Dashboard) > 0 {
+			return Convert(wrapped.Dashboard, opts)
+		}
+	}
+	if opts.Database == "" {
+		opts.Database = "telegraf"
+	}
+
+	c := &converter{
+		opts:      opts,
+		variables: map[string]string{},
+		intervals: map[string]bool{},
+		warnings:  []Warning{},
+	}
+	d := cloudhub.Dashboard{
+		Name:      g.Title,
+		Cells:     []cloudhub.DashboardCell{},
+		Templates: c.templates(g.Templating.List),
+	}
+	if len(g.Rows) > 0 {
+		c.warn("", "rows of dashboards before Grafana 5 are not converted; save the dashboard with a recent Grafana first")
+	}
+	for _, p := range g.Panels {
+		d.Cells = append(d.Cells, c.panel(p)...)
+	}
+	return d, c.warnings, nil
+}
+
+type converter struct {
+	opts Options
+	// variables are the quotes CloudHub puts around the values of the templating
+	// variables by name, and intervals the names of the interval variables
+	variables map[string]string
+	intervals map[string]bool
+	warnings  []Warning
+}
+
+func (c *converter) warn(panel, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, Warning{Panel: panel, Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *converter) warnVariable(variable, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, Warning{Variable: variable, Message: fmt.Sprintf(format, args...)})
+}
+
+// panel returns the cells of the panel; rows return the cells of their collapsed panels
+func (c *converter) panel(p Panel) []cloudhub.DashboardCell {
+	cellType, ok := c.cellType(p)
+	if !ok {
+		return nil
+	}
+	if p.Type == "row" {
+		cells := []cloudhub.DashboardCell{}
+		for _, child := range p.Panels {
+			cells = append(cells, c.panel(child)...)
+		}
+		return cells
+	}
+
+	cell := cloudhub.DashboardCell{
+		Name:           p.Title,
+		Type:           cellType,
+		Queries:        []cloudhub.DashboardQuery{},
+		Axes:           map[string]cloudhub.Axis{},
+		CellColors:     []cloudhub.CellColor{},
+		FieldOptions:   []cloudhub.RenamableField{},
+		NoteVisibility: "default",
+	}
+	cell.X, cell.W = scale(p.GridPos.X, p.GridPos.W, columnScale)
+	cell.Y, cell.H = scale(p.GridPos.Y, p.GridPos.H, rowScale)
+
+	if cellType == "note" {
+		cell.Note = p.Options.Content
+		if cell.Note == "" {
+			cell.Note = p.Content
+		}
+		return []cloudhub.DashboardCell{cell}
+	}
+
+	for _, t := range p.Targets {
+		if q, ok := c.query(p, t); ok {
+			cell.Queries = append(cell.Queries, q)
+		}
+	}
+	cell.Axes["y"] = c.axis(p)
+	cell.CellColors = c.colors(p, cellType)
+	cell.Legend = legend(p)
+	if d := p.FieldConfig.Defaults.Decimals; d != nil {
+		cell.DecimalPlaces = cloudhub.DecimalPlaces{IsEnforced: true, Digits: *d}
+	} else if p.Decimals != nil {
+		cell.DecimalPlaces = cloudhub.DecimalPlaces{IsEnforced: true, Digits: *p.Decimals}
+	}
+	return []cloudhub.DashboardCell{cell}
+}
+
+// cellType returns the type of the cell of the panel, and false for panels which
+// are not converted
+func (c *converter) cellType(p Panel) (string, bool) {
+	custom := p.FieldConfig.Defaults.Custom
+	switch p.Type {
+	case "row":
+		return "", true
+	case "graph":
+		switch {
+		case p.Bars:
+			return "bar", true
+		case p.Stack:
+			return "line-stacked", true
+		case p.SteppedLine:
+			return "line-stepplot", true
+		}
+		return "line", true
+	case "timeseries":
+		switch {
+		case custom.DrawStyle == "bars":
+			return "bar", true
+		case custom.Stacking.Mode != "" && custom.Stacking.Mode != "none":
+			return "line-stacked", true
+		case strings.HasPrefix(custom.LineInterpolation, "step"):
+			return "line-stepplot", true
+		}
+		return "line", true
+	case "stat", "singlestat":
+		if p.Sparkline.Show || p.Options.GraphMode == "area" {
+			return "line-plus-single-stat", true
+		}
+		return "single-stat", true
+	case "gauge":
+		return "gauge", true
+	case "table", "table-old":
+		return "table", true
+	case "text":
+		return "note", true
+	case "bargauge", "barchart":
+		c.warn(p.Title, "%s panel is converted to a bar graph", p.Type)
+		return "bar", true
+	}
+	c.warn(p.Title, "%s panels are not supported", p.Type)
+	return "", false
+}
+
+// scale returns the position and size of the geometry of Grafana in the grid of
+// CloudHub; edges are rounded so that adjacent panels stay adjacent
+func scale(pos, size int, factor float64) (int32, int32) {
+	start := int32(math.Round(float64(pos) * factor))
+	end := int32(math.Round(float64(pos+size) * factor))
+	if end <= start {
+		end = start + 1
+	}
+	return start, end - start
+}
+
+// axis returns the y axis of the panel
+func (c *converter) axis(p Panel) cloudhub.Axis {
+	axis := cloudhub.Axis{Bounds: []string{"", ""}}
+	defaults := p.FieldConfig.Defaults
+	if defaults.Min != nil {
+		axis.Bounds[0] = strconv.FormatFloat(*defaults.Min, 'f', -1, 64)
+	}
+	if defaults.Max != nil {
+		axis.Bounds[1] = strconv.FormatFloat(*defaults.Max, 'f', -1, 64)
+	}
+	axis.Label = defaults.Custom.AxisLabel
+	if defaults.Custom.ScaleDistribution.Type == "log" {
+		axis.Scale = "log"
+	}
+
+	unit := defaults.Unit
+	if len(p.Yaxes) > 0 {
+		y := p.Yaxes[0]
+		axis.Label = y.Label
+		if y.LogBase > 1 {
+			axis.Scale = "log"
+		}
+		axis.Bounds = []string{bound(y.Min), bound(y.Max)}
+		if unit == "" {
+			unit = y.Format
+		}
+	}
+	if unit == "" {
+		unit = p.Format
+	}
+
+	switch unit {
+	case "", "short", "none":
+	case "percent":
+		axis.Suffix = "%"
+	case "bytes", "bits", "kbytes", "mbytes", "gbytes":
+		axis.Base = "2"
+	case "decbytes", "decbits":
+		axis.Base = "10"
+	default:
+		c.warn(p.Title, "unit %s is not converted", unit)
+	}
+	return axis
+}
+
+// bound returns a bound of the y axis of a graph, which is a number or a string
+func bound(raw json.RawMessage) string {
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil || v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// legend returns the legend of the cell; legends shown as a list or table become
+// static legends, CloudHub shows the values on hover otherwise
+func legend(p Panel) cloudhub.Legend {
+	l := p.Options.Legend
+	if l.DisplayMode == "hidden" || (l.ShowLegend != nil && !*l.ShowLegend) {
+		return cloudhub.Legend{}
+	}
+	if l.DisplayMode != "" {
+		orientation := "bottom"
+		if l.Placement == "right" {
+			orientation = "right"
+		}
+		return cloudhub.Legend{Type: "static", Orientation: orientation}
+	}
+	if p.Legend.Show && p.Type == "graph" {
+		orientation := "bottom"
+		if p.Legend.RightSide {
+			orientation = "right"
+		}
+		return cloudhub.Legend{Type: "static", Orientation: orientation}
+	}
+	return cloudhub.Legend{}
+}
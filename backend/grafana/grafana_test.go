@@ -0,0 +1,181 @@
+package grafana
+
+import (
+	"reflect"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+const dashboard = `{
+  "dashboard": {
+    "title": "Hosts",
+    "panels": [
+      {
+        "type": "timeseries",
+        "title": "CPU",
+        "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+        "datasource": {"type": "influxdb", "uid": "abc"},
+        "fieldConfig": {"defaults": {"unit": "percent", "min": 0, "max": 100, "custom": {"stacking": {"mode": "normal"}}}},
+        "options": {"legend": {"displayMode": "list", "placement": "right"}},
+        "targets": [
+          {
+            "refId": "A",
+            "measurement": "cpu",
+            "policy": "default",
+            "select": [[{"type": "field", "params": ["usage_user"]}, {"type": "mean", "params": []}]],
+            "tags": [{"key": "host", "operator": "=~", "value": "/^$host$/"}],
+            "groupBy": [{"type": "time", "params": ["$__interval"]}, {"type": "fill", "params": ["null"]}]
+          },
+          {"refId": "B", "rawQuery": true, "query": "SELECT last(\"used\") FROM \"mem\" WHERE \"host\" = '$host' AND $timeFilter"},
+          {"refId": "C", "hide": true, "measurement": "disk"},
+          {"refId": "D", "datasource": {"type": "prometheus", "uid": "p"}, "expr": "up"}
+        ]
+      },
+      {
+        "type": "row",
+        "title": "Stats",
+        "collapsed": true,
+        "panels": [
+          {
+            "type": "gauge",
+            "title": "Load",
+            "gridPos": {"x": 12, "y": 8, "w": 6, "h": 4},
+            "datasource": "influx",
+            "fieldConfig": {"defaults": {"thresholds": {"mode": "absolute", "steps": [
+              {"color": "green", "value": null},
+              {"color": "red", "value": 4}
+            ]}}},
+            "targets": [{"refId": "A", "rawQuery": true, "query": "SELECT last(\"load1\") FROM \"system\" WHERE $timeFilter GROUP BY time($inter)"}]
+          }
+        ]
+      },
+      {"type": "piechart", "title": "Pie"}
+    ],
+    "templating": {
+      "list": [
+        {"type": "query", "name": "host", "query": "SHOW TAG VALUES FROM \"system\" WITH KEY = \"host\"", "current": {"value": ["server01"]}, "multi": true},
+        {"type": "custom", "name": "env", "query": "prod,dev", "current": {"value": "dev"}},
+        {"type": "interval", "name": "inter", "query": "1m,5m"},
+        {"type": "adhoc", "name": "filters"}
+      ]
+    }
+  }
+}`
+
+func TestConvert(t *testing.T) {
+	d, warnings, err := Convert([]byte(dashboard), Options{
+		Sources:   map[string]string{"abc": "/cloudhub/v1/sources/1"},
+		Databases: map[string]string{"abc": "tenant"},
+		Source:    "/cloudhub/v1/sources/2",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Name != "Hosts" || len(d.Cells) != 2 {
+		t.Fatalf("Convert() unexpected dashboard %#v", d)
+	}
+
+	cpu := d.Cells[0]
+	if cpu.Type != "line-stacked" || cpu.X != 0 || cpu.Y != 0 || cpu.W != 48 || cpu.H != 21 {
+		t.Errorf("Convert() unexpected cell %s %d %d %d %d", cpu.Type, cpu.X, cpu.Y, cpu.W, cpu.H)
+	}
+	if y := cpu.Axes["y"]; y.Suffix != "%" || !reflect.DeepEqual(y.Bounds, []string{"0", "100"}) {
+		t.Errorf("Convert() unexpected axis %#v", y)
+	}
+	if cpu.Legend != (cloudhub.Legend{Type: "static", Orientation: "right"}) {
+		t.Errorf("Convert() unexpected legend %#v", cpu.Legend)
+	}
+	queries := []string{
+		`SELECT mean("usage_user") FROM "tenant".."cpu" WHERE ("host" =~ /^:host:$/) AND time > :dashboardTime: AND time < :upperDashboardTime: GROUP BY time(:interval:) fill(null)`,
+		`SELECT last("used") FROM "tenant".."mem" WHERE "host" = :host: AND time > :dashboardTime: AND time < :upperDashboardTime:`,
+	}
+	if len(cpu.Queries) != len(queries) {
+		t.Fatalf("Convert() queries = %#v", cpu.Queries)
+	}
+	for i, q := range cpu.Queries {
+		if q.Command != queries[i] || q.Source != "/cloudhub/v1/sources/1" || q.Type != "influxql" {
+			t.Errorf("Convert() query %d = %s %s, want %s", i, q.Source, q.Command, queries[i])
+		}
+	}
+	if qc := cpu.Queries[1].QueryConfig; qc.RawText == nil {
+		t.Errorf("Convert() unexpected query config of a raw query %#v", qc)
+	}
+
+	load := d.Cells[1]
+	if load.Type != "gauge" || load.X != 48 || load.W != 24 || load.Queries[0].Source != "/cloudhub/v1/sources/2" {
+		t.Errorf("Convert() unexpected cell %#v", load)
+	}
+	if got := load.Queries[0].Command; got != `SELECT last("load1") FROM "telegraf".."system" WHERE time > :dashboardTime: AND time < :upperDashboardTime: GROUP BY time(:interval:)` {
+		t.Errorf("Convert() query = %s", got)
+	}
+	if qc := load.Queries[0].QueryConfig; qc.RawText != nil || qc.Measurement != "system" || qc.Database != "telegraf" {
+		t.Errorf("Convert() unexpected query config %#v", qc)
+	}
+	colors := []cloudhub.CellColor{
+		{ID: "0", Type: "min", Hex: "#7CE490", Name: "honeydew", Value: "0"},
+		{ID: "1", Type: "threshold", Hex: "#DC4E58", Name: "fire", Value: "4"},
+		{ID: "2", Type: "max", Hex: "#DC4E58", Name: "fire", Value: "100"},
+	}
+	if !reflect.DeepEqual(load.CellColors, colors) {
+		t.Errorf("Convert() colors = %#v, want %#v", load.CellColors, colors)
+	}
+
+	if len(d.Templates) != 2 {
+		t.Fatalf("Convert() templates = %#v", d.Templates)
+	}
+	host := d.Templates[0]
+	if host.Var != ":host:" || host.Type != "tagValues" || host.ID == "" {
+		t.Errorf("Convert() unexpected template %#v", host)
+	}
+	wantQuery := cloudhub.TemplateQuery{
+		Command:     "SHOW TAG VALUES ON :database: FROM :measurement: WITH KEY=:tagKey:",
+		DB:          "telegraf",
+		Measurement: "system",
+		TagKey:      "host",
+	}
+	if host.Query == nil || *host.Query != wantQuery {
+		t.Errorf("Convert() template query = %#v, want %#v", host.Query, wantQuery)
+	}
+	if want := []cloudhub.TemplateValue{{Value: "server01", Type: "tagValue", Selected: true}}; !reflect.DeepEqual(host.Values, want) {
+		t.Errorf("Convert() template values = %#v, want %#v", host.Values, want)
+	}
+	env := d.Templates[1]
+	want := []cloudhub.TemplateValue{{Value: "prod", Type: "csv"}, {Value: "dev", Type: "csv", Selected: true}}
+	if env.Type != "csv" || !reflect.DeepEqual(env.Values, want) {
+		t.Errorf("Convert() template = %#v, want values %#v", env, want)
+	}
+
+	wantWarnings := []Warning{
+		{Variable: "host", Message: "variables select a single value"},
+		{Variable: "inter", Message: "interval variables are replaced by the interval of the dashboard"},
+		{Variable: "filters", Message: "adhoc variables are not supported"},
+		{Panel: "CPU", Message: "hidden query C is not converted"},
+		{Panel: "CPU", Message: "query D is not an InfluxDB query"},
+		{Panel: "Pie", Message: "piechart panels are not supported"},
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("Convert() warnings = %#v, want %#v", warnings, wantWarnings)
+	}
+}
+
+func TestNearestColor(t *testing.T) {
+	tests := []struct {
+		color string
+		want  string
+		ok    bool
+	}{
+		{"green", "honeydew", true},
+		{"dark-red", "fire", true},
+		{"#F2495C", "fire", true},
+		{"rgba(50, 172, 45, 0.97)", "viridian", true},
+		{"#fff", "white", true},
+		{"chartreuse", "graphite", false},
+	}
+	for _, tt := range tests {
+		got, ok := nearestColor(tt.color)
+		if got.name != tt.want || ok != tt.ok {
+			t.Errorf("nearestColor(%q) = %s, %v, want %s, %v", tt.color, got.name, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/influx"
+)
+
+// Target is a query of a panel
+type Target struct {
+	RefID      string          `json:"refId"`
+	Hide       bool            `json:"hide"`
+	Datasource json.RawMessage `json:"datasource"` // Datasource overrides the datasource of the panel
+	Expr       string          `json:"expr"`       // Expr is the query of Prometheus datasources
+
+	// Query is the InfluxQL query written by hand if RawQuery is set, or the Flux query
+	Query    string `json:"query"`
+	RawQuery bool   `json:"rawQuery"`
+
+	// The parts of queries built with the query editor of Grafana
+	Measurement string        `json:"measurement"`
+	Policy      string        `json:"policy"`
+	Select      [][]QueryPart `json:"select"`
+	GroupBy     []QueryPart   `json:"groupBy"`
+	Tags        []QueryTag    `json:"tags"`
+	Alias       string        `json:"alias"`
+}
+
+// QueryPart is a part of a query built with the query editor, e.g. a field, a
+// function or a group by time
+type QueryPart struct {
+	Type   string        `json:"type"`
+	Params []interface{} `json:"params"`
+}
+
+// QueryTag is a condition on a tag of a query built with the query editor
+type QueryTag struct {
+	Key       string `json:"key"`
+	Operator  string `json:"operator"`
+	Value     string `json:"value"`
+	Condition string `json:"condition"` // Condition is AND or OR, joining the previous condition
+}
+
+// timeFilter is the time range of the dashboard
+const timeFilter = "time > :dashboardTime: AND time < :upperDashboardTime:"
+
+// query returns the query of the target, and false for targets which are not converted
+func (c *converter) query(p Panel, t Target) (cloudhub.DashboardQuery, bool) {
+	if t.Hide {
+		c.warn(p.Title, "hidden query %s is not converted", t.RefID)
+		return cloudhub.DashboardQuery{}, false
+	}
+	ds := t.Datasource
+	if isNull(ds) {
+		ds = p.Datasource
+	}
+	name, dsType := datasource(ds)
+	if (dsType != "" && dsType != "influxdb") || (t.Expr != "" && t.Query == "" && t.Measurement == "") {
+		c.warn(p.Title, "query %s is not an InfluxDB query", t.RefID)
+		return cloudhub.DashboardQuery{}, false
+	}
+
+	q := cloudhub.DashboardQuery{
+		Source: c.opts.Source,
+		Type:   "influxql",
+	}
+	if src, ok := c.opts.Sources[name]; ok {
+		q.Source = src
+	}
+
+	switch {
+	case strings.Contains(t.Query, "from(bucket"):
+		// the flux of Grafana and CloudHub share the time range variables
+		q.Type = "flux"
+		q.Command = t.Query
+		return q, true
+	case t.RawQuery:
+		q.Command = t.Query
+	default:
+		q.Command = builderQuery(t)
+	}
+	if strings.TrimSpace(q.Command) == "" {
+		c.warn(p.Title, "query %s is empty", t.RefID)
+		return cloudhub.DashboardQuery{}, false
+	}
+	if t.Alias != "" {
+		c.warn(p.Title, "alias %s of query %s is not converted", t.Alias, t.RefID)
+	}
+
+	q.Command = qualifyMeasurements(c.replaceVariables(p.Title, q.Command), c.database(name))
+	q.QueryConfig = queryConfig(q.Command)
+	return q, true
+}
+
+// database returns the database of the queries of the datasource
+func (c *converter) database(name string) string {
+	if db := c.opts.Databases[name]; db != "" {
+		return db
+	}
+	return c.opts.Database
+}
+
+// queryConfig returns the query config of the query; queries the query builder
+// cannot show are raw queries
+func queryConfig(query string) cloudhub.QueryConfig {
+	qc, err := influx.Convert(query)
+	if err == nil {
+		return qc
+	}
+	return cloudhub.QueryConfig{
+		RawText: &query,
+		Fields:  []cloudhub.Field{},
+		GroupBy: cloudhub.GroupBy{
+			Tags: []string{},
+		},
+		Tags: make(map[string][]string, 0),
+	}
+}
+
+// measurementPattern matches the measurements of FROM clauses: measurements, regexes
+// and template variables, prefixed by their retention policy and database
+var measurementPattern = regexp.MustCompile(`(?i)(\bFROM\s+)((?:(?:` + identifier + `)?\.){0,2}(?:` + identifier + `|/(?:[^/\\]|\\.)*/))`)
+
+// identifierPattern matches the identifiers and the dots of a measurement
+var identifierPattern = regexp.MustCompile(identifier + `|/(?:[^/\\]|\\.)*/|\.`)
+
+const identifier = `"(?:[^"\\]|\\.)*"|\w+|:\w+:`
+
+// qualifyMeasurements prefixes the measurements of the query with the database.
+// Queries of Grafana run on the database of their datasource, and queries of
+// CloudHub name their database.
+func qualifyMeasurements(query, db string) string {
+	return measurementPattern.ReplaceAllStringFunc(query, func(match string) string {
+		m := measurementPattern.FindStringSubmatch(match)
+		dots := 0
+		for _, token := range identifierPattern.FindAllString(m[2], -1) {
+			if token == "." {
+				dots++
+			}
+		}
+		switch dots {
+		case 0:
+			return m[1] + quoteIdent(db) + ".." + m[2]
+		case 1:
+			return m[1] + quoteIdent(db) + "." + m[2]
+		}
+		return match
+	})
+}
+
+// datasource returns the name, or UID, and the type of the datasource, which is
+// a name or a reference {type, uid} in recent versions of Grafana
+func datasource(raw json.RawMessage) (string, string) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return name, ""
+	}
+	var ref struct {
+		Type string `json:"type"`
+		UID  string `json:"uid"`
+	}
+	if err := json.Unmarshal(raw, &ref); err != nil {
+		return "", ""
+	}
+	return ref.UID, ref.Type
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+// variablePattern matches the variables of Grafana: $name, ${name}, ${name:format}
+// and [[name]]
+var variablePattern = regexp.MustCompile(`\$\{(\w+)(?::[^}]*)?\}|\[\[(\w+)(?::[^\]]*)?\]\]|\$(\w+)`)
+
+// replaceVariables replaces the variables and macros of Grafana in the query with
+// the template variables of CloudHub. CloudHub quotes the values of variables out
+// of regexes, so the quotes around the variables are removed.
+func (c *converter) replaceVariables(panel, query string) string {
+	query = variablePattern.ReplaceAllStringFunc(query, func(match string) string {
+		m := variablePattern.FindStringSubmatch(match)
+		name := m[1] + m[2] + m[3]
+		if _, ok := c.variables[name]; ok {
+			return ":" + name + ":"
+		}
+		switch {
+		case c.intervals[name], name == "interval", name == "__interval":
+			return ":interval:"
+		case name == "timeFilter", name == "__timeFilter":
+			return timeFilter
+		case strings.HasPrefix(name, "__"):
+			c.warn(panel, "macro %s is not converted", match)
+		default:
+			c.warn(panel, "unknown variable %s is not converted", match)
+		}
+		return match
+	})
+	for name, quote := range c.variables {
+		if quote != "" {
+			query = strings.Replace(query, quote+":"+name+":"+quote, ":"+name+":", -1)
+		}
+	}
+	return query
+}
+
+// builderQuery returns the InfluxQL query of a query built with the query editor of Grafana
+func builderQuery(t Target) string {
+	if t.Measurement == "" {
+		return ""
+	}
+
+	fields := []string{}
+	for _, parts := range t.Select {
+		expr, alias := "", ""
+		for _, part := range parts {
+			params := partParams(part)
+			switch part.Type {
+			case "field":
+				if len(params) > 0 {
+					expr = quoteIdent(params[0])
+				}
+			case "alias":
+				if len(params) > 0 {
+					alias = params[0]
+				}
+			case "math":
+				if len(params) > 0 {
+					expr = expr + " " + strings.TrimSpace(params[0])
+				}
+			default:
+				expr = part.Type + "(" + strings.Join(append([]string{expr}, params...), ", ") + ")"
+			}
+		}
+		if alias != "" {
+			expr += " AS " + quoteIdent(alias)
+		}
+		if expr != "" {
+			fields = append(fields, expr)
+		}
+	}
+	if len(fields) == 0 {
+		fields = append(fields, "*")
+	}
+
+	from := t.Measurement
+	if !strings.HasPrefix(from, "/") {
+		from = quoteIdent(from)
+	}
+	if t.Policy != "" && t.Policy != "default" {
+		from = quoteIdent(t.Policy) + "." + from
+	}
+
+	query := "SELECT " + strings.Join(fields, ", ") + " FROM " + from + " WHERE "
+	if len(t.Tags) > 0 {
+		conditions := ""
+		for i, tag := range t.Tags {
+			if i > 0 {
+				condition := tag.Condition
+				if condition == "" {
+					condition = "AND"
+				}
+				conditions += " " + condition + " "
+			}
+			conditions += tagCondition(tag)
+		}
+		query += "(" + conditions + ") AND "
+	}
+	query += "$timeFilter"
+
+	groupBy, fill := []string{}, ""
+	for _, part := range t.GroupBy {
+		params := partParams(part)
+		if len(params) == 0 {
+			continue
+		}
+		switch part.Type {
+		case "time":
+			// auto is the interval of Grafana 4
+			if params[0] == "auto" {
+				params[0] = "$__interval"
+			}
+			groupBy = append(groupBy, "time("+params[0]+")")
+		case "tag":
+			groupBy = append(groupBy, quoteIdent(params[0]))
+		case "fill":
+			fill = " fill(" + params[0] + ")"
+		}
+	}
+	if len(groupBy) > 0 {
+		query += " GROUP BY " + strings.Join(groupBy, ", ")
+	}
+	return query + fill
+}
+
+// tagCondition returns the condition of the tag as written by Grafana: regexes are
+// not quoted and the operators = and != of regexes match them
+func tagCondition(tag QueryTag) string {
+	op, value := tag.Operator, tag.Value
+	regex := strings.HasPrefix(value, "/") && strings.HasSuffix(value, "/") && len(value) > 1
+	if op == "" {
+		op = "="
+		if regex {
+			op = "=~"
+		}
+	}
+	switch {
+	case op == "=~" || op == "!~":
+	case op == "<" || op == ">":
+	default:
+		value = "'" + strings.Replace(value, "'", `\'`, -1) + "'"
+	}
+	return quoteIdent(tag.Key) + " " + op + " " + value
+}
+
+func partParams(part QueryPart) []string {
+	params := []string{}
+	for _, p := range part.Params {
+		params = append(params, fmt.Sprint(p))
+	}
+	return params
+}
+
+func quoteIdent(s string) string {
+	if s == "*" {
+		return s
+	}
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
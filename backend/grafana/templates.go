@@ -0,0 +1,244 @@
+package grafana
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/id"
+)
+
+// Variable is a templating variable of a Grafana dashboard
+type Variable struct {
+	Type       string          `json:"type"`
+	Name       string          `json:"name"`
+	Label      string          `json:"label"`
+	Query      json.RawMessage `json:"query"` // Query is a string, or {query} in recent versions of Grafana
+	Datasource json.RawMessage `json:"datasource"`
+	Regex      string          `json:"regex"`
+	Multi      bool            `json:"multi"`
+	IncludeAll bool            `json:"includeAll"`
+	Current    struct {
+		Value json.RawMessage `json:"value"` // Value is a string, or a list of strings for multi-value variables
+	} `json:"current"`
+}
+
+// templateValueTypes are the types of the values of the types of templates, and
+// quotes are the quotes CloudHub puts around their values out of regexes
+var (
+	templateValueTypes = map[string]string{
+		"databases":    "database",
+		"measurements": "measurement",
+		"fieldKeys":    "fieldKey",
+		"tagKeys":      "tagKey",
+		"tagValues":    "tagValue",
+		"influxql":     "influxql",
+		"csv":          "csv",
+		"map":          "map",
+		"constant":     "constant",
+		"text":         "constant",
+	}
+	quotes = map[string]string{
+		"databases":    `"`,
+		"measurements": `"`,
+		"fieldKeys":    `"`,
+		"tagKeys":      `"`,
+		"tagValues":    `'`,
+	}
+)
+
+// metaQueries are the meta queries of Grafana which are templates of CloudHub, with
+// the groups of the database, measurement and tag key of the query
+var metaQueries = []struct {
+	pattern                 *regexp.Regexp
+	templateType            string
+	db, measurement, tagKey int
+}{
+	{regexp.MustCompile(`(?i)^SHOW\s+DATABASES$`), "databases", 0, 0, 0},
+	{regexp.MustCompile(`(?i)^SHOW\s+MEASUREMENTS(?:\s+ON\s+(\S+))?$`), "measurements", 1, 0, 0},
+	{regexp.MustCompile(`(?i)^SHOW\s+FIELD\s+KEYS(?:\s+ON\s+(\S+))?\s+FROM\s+(\S+)$`), "fieldKeys", 1, 2, 0},
+	{regexp.MustCompile(`(?i)^SHOW\s+TAG\s+KEYS(?:\s+ON\s+(\S+))?\s+FROM\s+(\S+)$`), "tagKeys", 1, 2, 0},
+	{regexp.MustCompile(`(?i)^SHOW\s+TAG\s+VALUES(?:\s+ON\s+(\S+))?\s+FROM\s+(\S+)\s+WITH\s+KEY\s*=\s*(\S+)$`), "tagValues", 1, 2, 3},
+}
+
+// templateQueries are the queries of the templates of CloudHub listing values
+var templateQueries = map[string]string{
+	"databases":    "SHOW DATABASES",
+	"measurements": "SHOW MEASUREMENTS ON :database:",
+	"fieldKeys":    "SHOW FIELD KEYS ON :database: FROM :measurement:",
+	"tagKeys":      "SHOW TAG KEYS ON :database: FROM :measurement:",
+	"tagValues":    "SHOW TAG VALUES ON :database: FROM :measurement: WITH KEY=:tagKey:",
+}
+
+// templates returns the templates of the templating variables. The names of the
+// variables are known first, as variables refer to other variables.
+func (c *converter) templates(list []Variable) []cloudhub.Template {
+	types := map[string]string{}
+	for _, v := range list {
+		switch v.Type {
+		case "interval":
+			c.intervals[v.Name] = true
+		case "query", "custom", "constant", "textbox":
+			types[v.Name] = c.templateType(v)
+			c.variables[v.Name] = quotes[types[v.Name]]
+		}
+	}
+
+	templates := []cloudhub.Template{}
+	for _, v := range list {
+		switch v.Type {
+		case "interval":
+			c.warnVariable(v.Name, "interval variables are replaced by the interval of the dashboard")
+			continue
+		case "query", "custom", "constant", "textbox":
+		default:
+			c.warnVariable(v.Name, "%s variables are not supported", v.Type)
+			continue
+		}
+		if v.Multi || v.IncludeAll {
+			c.warnVariable(v.Name, "variables select a single value")
+		}
+		if v.Regex != "" {
+			c.warnVariable(v.Name, "regex %s is not applied", v.Regex)
+		}
+
+		tid, err := (&id.UUID{}).Generate()
+		if err != nil {
+			c.warnVariable(v.Name, "unable to create the ID of the template: %s", err)
+			continue
+		}
+		label := v.Label
+		if label == "" {
+			label = v.Name
+		}
+		t := cloudhub.Template{
+			TemplateVar: cloudhub.TemplateVar{
+				Var:    ":" + v.Name + ":",
+				Values: []cloudhub.TemplateValue{},
+			},
+			ID:    cloudhub.TemplateID(tid),
+			Type:  types[v.Name],
+			Label: label,
+		}
+		c.templateValues(v, &t)
+		templates = append(templates, t)
+	}
+	return templates
+}
+
+// templateType returns the type of the template of the variable
+func (c *converter) templateType(v Variable) string {
+	switch v.Type {
+	case "custom":
+		if strings.Contains(variableQuery(v), " : ") {
+			return "map"
+		}
+		return "csv"
+	case "constant":
+		return "constant"
+	case "textbox":
+		return "text"
+	}
+	query := strings.TrimSpace(variableQuery(v))
+	for _, mq := range metaQueries {
+		if m := mq.pattern.FindStringSubmatch(query); m != nil && !strings.Contains(query, "$") {
+			return mq.templateType
+		}
+	}
+	return "influxql"
+}
+
+// templateValues sets the query and the selected value of the template
+func (c *converter) templateValues(v Variable, t *cloudhub.Template) {
+	valueType := templateValueTypes[t.Type]
+	query := strings.TrimSpace(variableQuery(v))
+	selected := currentValue(v)
+
+	switch t.Type {
+	case "csv", "map":
+		for _, option := range strings.Split(query, ",") {
+			option = strings.TrimSpace(option)
+			if option == "" {
+				continue
+			}
+			value := cloudhub.TemplateValue{Value: option, Type: valueType}
+			if t.Type == "map" {
+				parts := strings.SplitN(option, " : ", 2)
+				value.Key = strings.TrimSpace(parts[0])
+				value.Value = value.Key
+				if len(parts) == 2 {
+					value.Value = strings.TrimSpace(parts[1])
+				}
+			}
+			value.Selected = value.Value == selected || value.Key == selected
+			t.Values = append(t.Values, value)
+		}
+		return
+	case "constant", "text":
+		if selected == "" {
+			selected = query
+		}
+		t.Values = append(t.Values, cloudhub.TemplateValue{Value: selected, Type: valueType, Selected: true})
+		return
+	case "influxql":
+		c.warnVariable(v.Name, "the query is a meta query, whose values are not quoted")
+		t.Query = &cloudhub.TemplateQuery{Command: c.replaceVariables("", query)}
+	default:
+		name, _ := datasource(v.Datasource)
+		t.Query = &cloudhub.TemplateQuery{Command: templateQueries[t.Type], DB: c.database(name)}
+		for _, mq := range metaQueries {
+			m := mq.pattern.FindStringSubmatch(query)
+			if mq.templateType != t.Type || m == nil {
+				continue
+			}
+			if mq.db > 0 && m[mq.db] != "" {
+				t.Query.DB = unquote(m[mq.db])
+			}
+			if mq.measurement > 0 {
+				t.Query.Measurement = unquote(m[mq.measurement])
+			}
+			if mq.tagKey > 0 {
+				t.Query.TagKey = unquote(m[mq.tagKey])
+			}
+		}
+	}
+	if selected != "" {
+		t.Values = append(t.Values, cloudhub.TemplateValue{Value: selected, Type: valueType, Selected: true})
+	}
+}
+
+// variableQuery returns the query of the variable, which is the list of values of
+// custom variables and the value of constants
+func variableQuery(v Variable) string {
+	var query string
+	if err := json.Unmarshal(v.Query, &query); err == nil {
+		return query
+	}
+	var q struct {
+		Query string `json:"query"`
+	}
+	json.Unmarshal(v.Query, &q)
+	return q.Query
+}
+
+// currentValue returns the current value of the variable, the first one of multi-value
+// variables; all values are not selected
+func currentValue(v Variable) string {
+	var value string
+	if err := json.Unmarshal(v.Current.Value, &value); err != nil {
+		var values []string
+		if err := json.Unmarshal(v.Current.Value, &values); err != nil || len(values) == 0 {
+			return ""
+		}
+		value = values[0]
+	}
+	if value == "$__all" {
+		return ""
+	}
+	return value
+}
+
+func unquote(s string) string {
+	return strings.Trim(s, `"'`)
+}
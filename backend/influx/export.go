@@ -0,0 +1,296 @@
+package influx
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// Formats of the exports of query results
+const (
+	ExportCSV          = "csv"    // ExportCSV writes a row per point with the name and the tags of its series, like the influx CLI
+	ExportNDJSON       = "ndjson" // ExportNDJSON writes a JSON object per point
+	ExportLineProtocol = "lp"     // ExportLineProtocol writes the points in line protocol, to be written back to InfluxDB
+)
+
+// Time formats of the exports of query results
+const (
+	TimeRFC3339 = "rfc3339" // TimeRFC3339 formats times as RFC3339 with nanoseconds in the location of the export
+)
+
+var epochPrecisions = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+// ExportOptions are the format of an export
+type ExportOptions struct {
+	Format     string         // Format is csv, ndjson or lp
+	TimeFormat string         // TimeFormat is rfc3339 or the precision of epoch times, ns, us, ms or s; line protocol is always in ns
+	Location   *time.Location // Location is the timezone of RFC3339 times, UTC if nil
+}
+
+// Exporter writes the series of query results in an export format, one point
+// at a time. Buffered points are written by Flush.
+type Exporter struct {
+	opts   ExportOptions
+	w      *bufio.Writer
+	csv    *csv.Writer
+	header []string
+}
+
+// NewExporter returns an exporter writing to w in the format of opts
+func NewExporter(w io.Writer, opts ExportOptions) (*Exporter, error) {
+	switch opts.Format {
+	case ExportCSV, ExportNDJSON, ExportLineProtocol:
+	default:
+		return nil, fmt.Errorf("invalid export format %q: use csv, ndjson or lp", opts.Format)
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = TimeRFC3339
+	}
+	if _, ok := epochPrecisions[opts.TimeFormat]; !ok && opts.TimeFormat != TimeRFC3339 {
+		return nil, fmt.Errorf("invalid time format %q: use rfc3339, ns, us, ms or s", opts.TimeFormat)
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	e := &Exporter{
+		opts: opts,
+		w:    bufio.NewWriter(w),
+	}
+	if opts.Format == ExportCSV {
+		e.csv = csv.NewWriter(e.w)
+	}
+	return e, nil
+}
+
+// Write writes the points of a series
+func (e *Exporter) Write(s *Series) error {
+	switch e.opts.Format {
+	case ExportCSV:
+		return e.writeCSV(s)
+	case ExportNDJSON:
+		return e.writeNDJSON(s)
+	default:
+		return e.writeLineProtocol(s)
+	}
+}
+
+// Flush writes the points buffered
+func (e *Exporter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return e.w.Flush()
+}
+
+// writeCSV writes the points as rows of name, tags and columns, with a header
+// whenever the columns change
+func (e *Exporter) writeCSV(s *Series) error {
+	header := append([]string{"name", "tags"}, s.Columns...)
+	if !equalStrings(header, e.header) {
+		if err := e.csv.Write(header); err != nil {
+			return err
+		}
+		e.header = header
+	}
+
+	tags := joinTags(s.Tags)
+	record := make([]string, len(header))
+	for _, row := range s.Values {
+		record[0], record[1] = s.Name, tags
+		for i := range s.Columns {
+			record[i+2] = ""
+			if i < len(row) {
+				record[i+2] = e.formatCSV(row[i])
+			}
+		}
+		if err := e.csv.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) formatCSV(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return e.formatTime(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeNDJSON writes a JSON object per point with the name, the tags and the
+// columns of its series, in order
+func (e *Exporter) writeNDJSON(s *Series) error {
+	var prefix []byte
+	if s.Name != "" {
+		name, _ := json.Marshal(s.Name)
+		prefix = append(append([]byte(`"name":`), name...), ',')
+	}
+	if len(s.Tags) > 0 {
+		tags, err := json.Marshal(s.Tags)
+		if err != nil {
+			return err
+		}
+		prefix = append(append(append(prefix, `"tags":`...), tags...), ',')
+	}
+	keys := make([][]byte, len(s.Columns))
+	for i, col := range s.Columns {
+		key, _ := json.Marshal(col)
+		keys[i] = append(key, ':')
+	}
+
+	for _, row := range s.Values {
+		e.w.WriteByte('{')
+		e.w.Write(prefix)
+		for i := range s.Columns {
+			if i > 0 {
+				e.w.WriteByte(',')
+			}
+			e.w.Write(keys[i])
+			var v interface{}
+			if i < len(row) {
+				v = row[i]
+			}
+			if t, ok := v.(time.Time); ok {
+				v = e.jsonTime(t)
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			e.w.Write(b)
+		}
+		if _, err := e.w.WriteString("}\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLineProtocol writes the points with the tags of their series and their
+// other columns as fields, or the _field and _value columns of Flux tables.
+// InfluxQL only returns the tags of GROUP BY clauses as tags, others as fields.
+func (e *Exporter) writeLineProtocol(s *Series) error {
+	timeCol, measurementCol, fieldCol, valueCol := -1, -1, -1, -1
+	for i, col := range s.Columns {
+		switch col {
+		case "time", "_time":
+			timeCol = i
+		case "_measurement":
+			measurementCol = i
+		case "_field":
+			fieldCol = i
+		case "_value":
+			valueCol = i
+		}
+	}
+
+	for _, row := range s.Values {
+		point := cloudhub.Point{
+			Measurement: s.Name,
+			Tags:        s.Tags,
+			Fields:      map[string]interface{}{},
+		}
+		for i, v := range row {
+			if i >= len(s.Columns) || v == nil {
+				continue
+			}
+			col := s.Columns[i]
+			switch {
+			case i == timeCol:
+				if t, ok := v.(time.Time); ok {
+					point.Time = t.UnixNano()
+				}
+			case i == measurementCol:
+				point.Measurement = fmt.Sprint(v)
+			case fieldCol >= 0 && valueCol >= 0:
+				if i == valueCol && fieldCol < len(row) {
+					point.Fields[fmt.Sprint(row[fieldCol])] = lineProtocolValue(v)
+				}
+			case s.Tags[col] != "" || fluxColumns[col]:
+			default:
+				point.Fields[col] = lineProtocolValue(v)
+			}
+		}
+		if len(point.Fields) == 0 {
+			continue
+		}
+		line, err := toLineProtocol(&point)
+		if err != nil {
+			return err
+		}
+		e.w.WriteString(line)
+		if err := e.w.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineProtocolValue returns floats as numbers, written with their full precision
+func lineProtocolValue(v interface{}) interface{} {
+	if f, ok := v.(float64); ok {
+		return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return v
+}
+
+func (e *Exporter) formatTime(t time.Time) string {
+	if precision, ok := epochPrecisions[e.opts.TimeFormat]; ok {
+		return strconv.FormatInt(t.UnixNano()/int64(precision), 10)
+	}
+	return t.In(e.opts.Location).Format(time.RFC3339Nano)
+}
+
+func (e *Exporter) jsonTime(t time.Time) interface{} {
+	if precision, ok := epochPrecisions[e.opts.TimeFormat]; ok {
+		return t.UnixNano() / int64(precision)
+	}
+	return t.In(e.opts.Location).Format(time.RFC3339Nano)
+}
+
+// joinTags returns the tags as sorted key=value pairs separated by commas
+func joinTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for k, v := range tags {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package influx
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestExporter(t *testing.T) {
+	seoul := time.FixedZone("KST", 9*60*60)
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	influxQL := []*Series{
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "web01", "cpu": "cpu0"},
+			Columns: []string{"time", "usage", "state"},
+			Values: [][]interface{}{
+				{t0, json.Number("1.5"), "idle"},
+				{t0.Add(time.Second), json.Number("2"), nil},
+			},
+		},
+		{
+			Name:    "mem",
+			Columns: []string{"time", "used"},
+			Values: [][]interface{}{
+				{t0, json.Number("10")},
+			},
+		},
+	}
+	flux := []*Series{
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "web 01"},
+			Columns: []string{"_time", "_value", "_field", "_measurement", "host"},
+			Values: [][]interface{}{
+				{t0, 0.1, "usage", "cpu", "web 01"},
+				{t0, int64(3), "count", "cpu", "web 01"},
+			},
+		},
+	}
+	tests := []struct {
+		name    string
+		opts    ExportOptions
+		series  []*Series
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "csv with RFC3339 times in a timezone",
+			opts:   ExportOptions{Format: ExportCSV, Location: seoul},
+			series: influxQL,
+			want: `name,tags,time,usage,state
+cpu,"cpu=cpu0,host=web01",2020-01-01T09:00:00+09:00,1.5,idle
+cpu,"cpu=cpu0,host=web01",2020-01-01T09:00:01+09:00,2,
+name,tags,time,used
+mem,,2020-01-01T09:00:00+09:00,10
+`,
+		},
+		{
+			name:   "ndjson with epoch times",
+			opts:   ExportOptions{Format: ExportNDJSON, TimeFormat: "ms"},
+			series: influxQL,
+			want: `{"name":"cpu","tags":{"cpu":"cpu0","host":"web01"},"time":1577836800000,"usage":1.5,"state":"idle"}
+{"name":"cpu","tags":{"cpu":"cpu0","host":"web01"},"time":1577836801000,"usage":2,"state":null}
+{"name":"mem","time":1577836800000,"used":10}
+`,
+		},
+		{
+			name:   "line protocol of InfluxQL",
+			opts:   ExportOptions{Format: ExportLineProtocol},
+			series: influxQL,
+			want: `cpu,cpu=cpu0,host=web01 state="idle",usage=1.5 1577836800000000000
+cpu,cpu=cpu0,host=web01 usage=2 1577836801000000000
+mem used=10 1577836800000000000
+`,
+		},
+		{
+			name:   "line protocol of Flux",
+			opts:   ExportOptions{Format: ExportLineProtocol},
+			series: flux,
+			want: `cpu,host=web\ 01 usage=0.1 1577836800000000000
+cpu,host=web\ 01 count=3i 1577836800000000000
+`,
+		},
+		{
+			name:    "invalid format",
+			opts:    ExportOptions{Format: "xml"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid time format",
+			opts:    ExportOptions{Format: ExportCSV, TimeFormat: "unix"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			e, err := NewExporter(&buf, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewExporter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for _, s := range tt.series {
+				if err := e.Write(s); err != nil {
+					t.Fatalf("Exporter.Write() error = %v", err)
+				}
+			}
+			if err := e.Flush(); err != nil {
+				t.Fatalf("Exporter.Flush() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("Exporter =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -94,7 +94,7 @@ func (r *responseType) Error() string {
 	return r.V2Err
 }
 
-func (c *Client) query(u *url.URL, q cloudhub.Query) (cloudhub.Response, error) {
+func (c *Client) query(ctx context.Context, u *url.URL, q cloudhub.Query) (cloudhub.Response, error) {
 	u.Path = "query"
 	req, err := http.NewRequest("POST", u.String(), nil)
 	if err != nil {
@@ -129,7 +129,8 @@ func (c *Client) query(u *url.URL, q cloudhub.Query) (cloudhub.Response, error)
 
 	hc := &http.Client{}
 	hc.Transport = SharedTransport(c.InsecureSkipVerify)
-	resp, err := hc.Do(req)
+	// InfluxDB kills the query when the request is canceled
+	resp, err := hc.Do(req.WithContext(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -178,9 +179,9 @@ type result struct {
 // include both the database and retention policy. In-flight requests can be
 // cancelled using the provided context.
 func (c *Client) Query(ctx context.Context, q cloudhub.Query) (cloudhub.Response, error) {
-	resps := make(chan (result))
+	resps := make(chan (result), 1)
 	go func() {
-		resp, err := c.query(c.URL, q)
+		resp, err := c.query(ctx, c.URL, q)
 		resps <- result{resp, err}
 	}()
 
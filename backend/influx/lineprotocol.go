@@ -1,6 +1,7 @@
 package influx
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -60,6 +61,8 @@ func toLineProtocol(point *cloudhub.Point) (string, error) {
 			format = fmt.Sprintf("%s=%du", escapeKeys.Replace(field), v)
 		case float64, float32:
 			format = fmt.Sprintf("%s=%f", escapeKeys.Replace(field), v)
+		case json.Number:
+			format = fmt.Sprintf("%s=%s", escapeKeys.Replace(field), v)
 		case string:
 			format = fmt.Sprintf(`%s="%s"`, escapeKeys.Replace(field), escapeFieldStrings.Replace(v))
 		case bool:
@@ -0,0 +1,214 @@
+package influx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	"github.com/snetsystems/cloudhub/backend/querycache"
+)
+
+// QueryPolicy restricts the InfluxQL queries sent to InfluxDB, so that a single
+// query cannot exhaust its resources
+type QueryPolicy struct {
+	ReadOnly     bool          // ReadOnly rejects the statements writing data or changing the databases, but SHOW statements
+	DefaultRange time.Duration // DefaultRange bounds the SELECT statements without time condition, unlimited if zero
+	MaxRange     time.Duration // MaxRange is the longest time range of SELECT statements, unlimited if zero
+	MaxPoints    int           // MaxPoints caps the LIMIT of the SELECT statements of raw points, unlimited if zero
+	MaxSeries    int           // MaxSeries caps the SLIMIT of the SELECT statements grouped by tags, unlimited if zero
+	Timeout      time.Duration // Timeout bounds the time to run a query, unlimited if zero
+}
+
+// PolicyError is a query rejected by a policy, with the way to comply with it
+type PolicyError struct {
+	Forbidden bool   // Forbidden is true if the statement is not allowed at all
+	Message   string // Message explains how to change the query
+}
+
+func (e *PolicyError) Error() string {
+	return e.Message
+}
+
+// Apply returns the command with its statements rewritten to comply with the
+// policy, and whether it was rewritten, or an error when a statement cannot
+// comply. Commands which InfluxQL does not parse, e.g. subqueries or queries
+// with template variables, cannot be rewritten and are only bounded by the
+// Timeout, but read-only policies still reject the ones that are not SELECT
+// or SHOW statements.
+func (p *QueryPolicy) Apply(command string, now time.Time) (string, bool, error) {
+	if !p.restrictive() {
+		return command, false, nil
+	}
+	q, err := influxql.ParseQuery(command)
+	if err != nil {
+		if p.ReadOnly && !showOnly(command) && !querycache.Cacheable(querycache.Normalize(command)) {
+			return "", false, &PolicyError{
+				Forbidden: true,
+				Message:   "Your role may only run SELECT and SHOW statements reading data",
+			}
+		}
+		return command, false, nil
+	}
+
+	rewritten := false
+	for _, stmt := range q.Statements {
+		if err := p.checkPrivileges(stmt); err != nil {
+			return "", false, err
+		}
+		sel, ok := stmt.(*influxql.SelectStatement)
+		if !ok {
+			continue
+		}
+		changed, err := p.applySelect(sel, now)
+		if err != nil {
+			return "", false, err
+		}
+		rewritten = rewritten || changed
+	}
+	if !rewritten {
+		return command, false, nil
+	}
+	return q.String(), true, nil
+}
+
+// restrictive returns true if the policy restricts the statements, not only
+// the time to run them
+func (p *QueryPolicy) restrictive() bool {
+	return p.ReadOnly || p.DefaultRange > 0 || p.MaxRange > 0 || p.MaxPoints > 0 || p.MaxSeries > 0
+}
+
+// showOnly returns true if every statement of the command is a SHOW statement
+func showOnly(command string) bool {
+	scanner := influxql.NewScanner(strings.NewReader(command))
+	start, found := true, false
+	for {
+		tok, _, _ := scanner.Scan()
+		switch tok {
+		case influxql.EOF:
+			return found
+		case influxql.WS:
+			continue
+		case influxql.SEMICOLON:
+			start = true
+			continue
+		}
+		if start {
+			if tok != influxql.SHOW {
+				return false
+			}
+			start, found = false, true
+		}
+	}
+}
+
+// checkPrivileges rejects the statements of read-only policies which need more
+// than reading data. SHOW statements are allowed, though InfluxDB requires admin
+// privileges for some of them, e.g. SHOW DATABASES.
+func (p *QueryPolicy) checkPrivileges(stmt influxql.Statement) error {
+	if !p.ReadOnly || strings.HasPrefix(stmt.String(), "SHOW ") {
+		return nil
+	}
+	privileges, err := stmt.RequiredPrivileges()
+	if err != nil {
+		return err
+	}
+	for _, priv := range privileges {
+		if priv.Admin || priv.Privilege != influxql.ReadPrivilege {
+			return &PolicyError{
+				Forbidden: true,
+				Message:   fmt.Sprintf("%s statements are not allowed to your role, which may only run SELECT and SHOW statements reading data", statementName(stmt)),
+			}
+		}
+	}
+	return nil
+}
+
+// applySelect bounds the time range, the points and the series of a SELECT
+// statement and returns whether it was changed
+func (p *QueryPolicy) applySelect(sel *influxql.SelectStatement, now time.Time) (bool, error) {
+	changed := false
+	var cond influxql.Expr
+	if sel.Condition != nil {
+		cond = influxql.Reduce(sel.Condition, &influxql.NowValuer{Now: now})
+	}
+	min, max, err := influxql.TimeRange(cond)
+	if err != nil {
+		return false, &PolicyError{Message: fmt.Sprintf("Invalid time condition: %v", err)}
+	}
+
+	bounded := p.DefaultRange > 0 || p.MaxRange > 0
+	switch {
+	case min.IsZero() && !bounded:
+	case min.IsZero() && !max.IsZero():
+		return false, &PolicyError{Message: "SELECT statements must have a lower time bound, e.g. WHERE time > now() - 1h"}
+	case min.IsZero() && p.DefaultRange == 0:
+		return false, &PolicyError{Message: "SELECT statements must be bounded in time, e.g. WHERE time > now() - 1h"}
+	case min.IsZero():
+		bound := &influxql.BinaryExpr{
+			Op:  influxql.GT,
+			LHS: &influxql.VarRef{Val: "time"},
+			RHS: &influxql.BinaryExpr{
+				Op:  influxql.SUB,
+				LHS: &influxql.Call{Name: "now"},
+				RHS: &influxql.DurationLiteral{Val: p.DefaultRange},
+			},
+		}
+		if sel.Condition == nil {
+			sel.Condition = bound
+		} else {
+			sel.Condition = &influxql.BinaryExpr{
+				Op:  influxql.AND,
+				LHS: &influxql.ParenExpr{Expr: sel.Condition},
+				RHS: bound,
+			}
+		}
+		changed = true
+	default:
+		if max.IsZero() {
+			max = now
+		}
+		if p.MaxRange > 0 && max.Sub(min) > p.MaxRange {
+			return false, &PolicyError{
+				Message: fmt.Sprintf("The time range of the query, %s, exceeds the %s allowed to your role. Narrow it, e.g. WHERE time > now() - %s",
+					influxql.FormatDuration(max.Sub(min).Round(time.Second)), influxql.FormatDuration(p.MaxRange), influxql.FormatDuration(p.MaxRange)),
+			}
+		}
+	}
+
+	if p.MaxPoints > 0 && len(sel.FunctionCalls()) == 0 && (sel.Limit == 0 || sel.Limit > p.MaxPoints) {
+		sel.Limit = p.MaxPoints
+		changed = true
+	}
+	if p.MaxSeries > 0 && groupsByTags(sel) && (sel.SLimit == 0 || sel.SLimit > p.MaxSeries) {
+		sel.SLimit = p.MaxSeries
+		changed = true
+	}
+	return changed, nil
+}
+
+// groupsByTags returns true if the SELECT statement returns a series per tag value
+func groupsByTags(sel *influxql.SelectStatement) bool {
+	for _, d := range sel.Dimensions {
+		if call, ok := d.Expr.(*influxql.Call); ok && call.Name == "time" {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// statementName returns the keywords of the statement, e.g. DROP MEASUREMENT
+func statementName(stmt influxql.Statement) string {
+	if sel, ok := stmt.(*influxql.SelectStatement); ok && sel.Target != nil {
+		return "SELECT INTO"
+	}
+	var keywords []string
+	for _, word := range strings.Fields(stmt.String()) {
+		if word != strings.ToUpper(word) || strings.ContainsAny(word, `"'*/()`) || word == "ON" || word == "FROM" {
+			break
+		}
+		keywords = append(keywords, word)
+	}
+	return strings.Join(keywords, " ")
+}
@@ -0,0 +1,194 @@
+package influx
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryPolicy_Apply(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	viewer := QueryPolicy{
+		ReadOnly:     true,
+		DefaultRange: time.Hour,
+		MaxRange:     24 * time.Hour,
+		MaxPoints:    100,
+		MaxSeries:    10,
+	}
+	tests := []struct {
+		name          string
+		policy        QueryPolicy
+		command       string
+		want          string
+		wantRewritten bool
+		wantErr       string
+		wantForbidden bool
+	}{
+		{
+			name:    "bounded aggregate is unchanged",
+			policy:  viewer,
+			command: `SELECT mean("usage_user") FROM "telegraf"."autogen"."cpu" WHERE time > now() - 1h GROUP BY time(1m)`,
+			want:    `SELECT mean("usage_user") FROM "telegraf"."autogen"."cpu" WHERE time > now() - 1h GROUP BY time(1m)`,
+		},
+		{
+			name:          "unbounded time range gets the default range",
+			policy:        viewer,
+			command:       `SELECT max(usage_user) FROM cpu WHERE host = 'web01'`,
+			want:          `SELECT max(usage_user) FROM cpu WHERE (host = 'web01') AND time > now() - 1h`,
+			wantRewritten: true,
+		},
+		{
+			name:    "unparsed unbounded time range grouped by time is only bounded by the timeout",
+			policy:  viewer,
+			command: `SELECT mean(usage_user) FROM cpu WHERE host = 'web01' GROUP BY time(1m)`,
+			want:    `SELECT mean(usage_user) FROM cpu WHERE host = 'web01' GROUP BY time(1m)`,
+		},
+		{
+			name:    "unbounded time range without default range",
+			policy:  QueryPolicy{MaxRange: 24 * time.Hour},
+			command: `SELECT mean(usage_user) FROM cpu`,
+			wantErr: "SELECT statements must be bounded in time, e.g. WHERE time > now() - 1h",
+		},
+		{
+			name:    "unrestrictive policy leaves the time range unbounded",
+			policy:  QueryPolicy{Timeout: time.Minute},
+			command: `SELECT * FROM cpu`,
+			want:    `SELECT * FROM cpu`,
+		},
+		{
+			name:    "unparsed subquery is only bounded by the timeout",
+			policy:  viewer,
+			command: `SELECT * FROM (SELECT * FROM cpu)`,
+			want:    `SELECT * FROM (SELECT * FROM cpu)`,
+		},
+		{
+			name:    "subquery of the disk usage of hosts",
+			policy:  viewer,
+			command: `SELECT mean("used_percent") AS "memUsed" FROM "telegraf"."autogen"."mem" WHERE time > now() - 10m GROUP BY host; SELECT max("diskUsed") AS "diskUsed", "path" AS "diskPath" FROM (SELECT last("used_percent") AS "diskUsed" FROM "telegraf"."autogen"."disk" WHERE time > now() - 10m GROUP BY host, path) GROUP BY host`,
+			want:    `SELECT mean("used_percent") AS "memUsed" FROM "telegraf"."autogen"."mem" WHERE time > now() - 10m GROUP BY host; SELECT max("diskUsed") AS "diskUsed", "path" AS "diskPath" FROM (SELECT last("used_percent") AS "diskUsed" FROM "telegraf"."autogen"."disk" WHERE time > now() - 10m GROUP BY host, path) GROUP BY host`,
+		},
+		{
+			name:    "unreplaced template variables",
+			policy:  viewer,
+			command: `SELECT mean("usage_user") FROM ":db:".":rp:"."cpu" WHERE time > :dashboardTime: GROUP BY time(:interval:)`,
+			want:    `SELECT mean("usage_user") FROM ":db:".":rp:"."cpu" WHERE time > :dashboardTime: GROUP BY time(:interval:)`,
+		},
+		{
+			name:          "read-only policy rejects unparsed SELECT INTO",
+			policy:        viewer,
+			command:       `SELECT * INTO cpu_copy FROM (SELECT * FROM cpu)`,
+			wantErr:       "Your role may only run SELECT and SHOW statements reading data",
+			wantForbidden: true,
+		},
+		{
+			name:    "unparsed subquery of an unrestrictive policy",
+			policy:  QueryPolicy{Timeout: time.Minute},
+			command: `SELECT * FROM (SELECT * FROM cpu)`,
+			want:    `SELECT * FROM (SELECT * FROM cpu)`,
+		},
+		{
+			name:    "upper time bound only",
+			policy:  viewer,
+			command: `SELECT mean(usage_user) FROM cpu WHERE time < now() - 1h`,
+			wantErr: "SELECT statements must have a lower time bound, e.g. WHERE time > now() - 1h",
+		},
+		{
+			name:    "time range too long",
+			policy:  viewer,
+			command: `SELECT mean(usage_user) FROM cpu WHERE time > now() - 7d GROUP BY time(1h)`,
+			wantErr: "The time range of the query, 1w, exceeds the 1d allowed to your role. Narrow it, e.g. WHERE time > now() - 1d",
+		},
+		{
+			name:    "absolute time range within the range",
+			policy:  viewer,
+			command: `SELECT mean(usage_user) FROM cpu WHERE time >= '2019-12-31T12:00:00Z' AND time < '2019-12-31T18:00:00Z' GROUP BY time(1h)`,
+			want:    `SELECT mean(usage_user) FROM cpu WHERE time >= '2019-12-31T12:00:00Z' AND time < '2019-12-31T18:00:00Z' GROUP BY time(1h)`,
+		},
+		{
+			name:          "raw points are limited",
+			policy:        viewer,
+			command:       `SELECT usage_user FROM cpu WHERE time > now() - 1h`,
+			want:          `SELECT usage_user FROM cpu WHERE time > now() - 1h LIMIT 100`,
+			wantRewritten: true,
+		},
+		{
+			name:          "limit above the maximum is capped",
+			policy:        viewer,
+			command:       `SELECT usage_user FROM cpu WHERE time > now() - 1h LIMIT 1000`,
+			want:          `SELECT usage_user FROM cpu WHERE time > now() - 1h LIMIT 100`,
+			wantRewritten: true,
+		},
+		{
+			name:    "limit below the maximum is kept",
+			policy:  viewer,
+			command: `SELECT usage_user FROM cpu WHERE time > now() - 1h LIMIT 10`,
+			want:    `SELECT usage_user FROM cpu WHERE time > now() - 1h LIMIT 10`,
+		},
+		{
+			name:          "series grouped by tags are limited",
+			policy:        viewer,
+			command:       `SELECT mean(usage_user) FROM cpu WHERE time > now() - 1h GROUP BY time(1m), host`,
+			want:          `SELECT mean(usage_user) FROM cpu WHERE time > now() - 1h GROUP BY time(1m), host SLIMIT 10`,
+			wantRewritten: true,
+		},
+		{
+			name:          "read-only policy rejects SELECT INTO",
+			policy:        viewer,
+			command:       `SELECT mean(usage_user) INTO cpu_1h FROM cpu WHERE time > now() - 1h GROUP BY time(1h)`,
+			wantErr:       "SELECT INTO statements are not allowed to your role, which may only run SELECT and SHOW statements reading data",
+			wantForbidden: true,
+		},
+		{
+			name:          "read-only policy rejects DDL",
+			policy:        viewer,
+			command:       `SHOW MEASUREMENTS; DROP MEASUREMENT cpu`,
+			wantErr:       "DROP MEASUREMENT statements are not allowed to your role, which may only run SELECT and SHOW statements reading data",
+			wantForbidden: true,
+		},
+		{
+			name:    "read-only policy allows SHOW statements requiring admin",
+			policy:  viewer,
+			command: `SHOW DATABASES`,
+			want:    `SHOW DATABASES`,
+		},
+		{
+			name:    "writable policy allows DDL",
+			policy:  QueryPolicy{},
+			command: `DROP MEASUREMENT cpu`,
+			want:    `DROP MEASUREMENT cpu`,
+		},
+		{
+			name:          "read-only policy rejects unparsed statements",
+			policy:        viewer,
+			command:       `DROP SHARD GROUPS ALL`,
+			wantErr:       "Your role may only run SELECT and SHOW statements reading data",
+			wantForbidden: true,
+		},
+		{
+			name:    "unparsed SHOW statements are unchecked",
+			policy:  viewer,
+			command: `SHOW MEASUREMENT CARDINALITY`,
+			want:    `SHOW MEASUREMENT CARDINALITY`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rewritten, err := tt.policy.Apply(tt.command, now)
+			if tt.wantErr != "" {
+				perr, ok := err.(*PolicyError)
+				if !ok {
+					t.Fatalf("QueryPolicy.Apply() error = %v, want %q", err, tt.wantErr)
+				}
+				if perr.Message != tt.wantErr || perr.Forbidden != tt.wantForbidden {
+					t.Errorf("QueryPolicy.Apply() error = %q forbidden %v, want %q forbidden %v", perr.Message, perr.Forbidden, tt.wantErr, tt.wantForbidden)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("QueryPolicy.Apply() error = %v", err)
+			}
+			if got != tt.want || rewritten != tt.wantRewritten {
+				t.Errorf("QueryPolicy.Apply() = %q, %v, want %q, %v", got, rewritten, tt.want, tt.wantRewritten)
+			}
+		})
+	}
+}
@@ -0,0 +1,349 @@
+package influx
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// DefaultChunkSize is the number of points of the chunks of streamed queries
+const DefaultChunkSize = 10000
+
+// Series are points of a series of the results of a streamed query. Times are
+// time.Time, numbers of InfluxQL json.Number and those of Flux typed by the
+// datatype of their column.
+type Series struct {
+	Statement int               `json:"-"`
+	Name      string            `json:"name"`
+	Tags      map[string]string `json:"tags"`
+	Columns   []string          `json:"columns"`
+	Values    [][]interface{}   `json:"values"`
+}
+
+// fluxColumns are the columns of Flux tables which are not tags of their series
+var fluxColumns = map[string]bool{
+	"result":       true,
+	"table":        true,
+	"_start":       true,
+	"_stop":        true,
+	"_time":        true,
+	"_measurement": true,
+	"_field":       true,
+	"_value":       true,
+}
+
+// QueryStream runs an InfluxQL query in chunks of chunkSize points and calls
+// fn with the series of each chunk, so that the results are never held in memory
+func (c *Client) QueryStream(ctx context.Context, q cloudhub.Query, chunkSize int, fn func(*Series) error) error {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	u := *c.URL
+	u.Path = "query"
+	params := u.Query()
+	params.Set("q", q.Command)
+	params.Set("db", q.DB)
+	params.Set("rp", q.RP)
+	params.Set("epoch", "ns")
+	params.Set("chunked", "true")
+	params.Set("chunk_size", strconv.Itoa(chunkSize))
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest("POST", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.stream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if resp.StatusCode != http.StatusOK {
+		var response responseType
+		_ = dec.Decode(&response)
+		return fmt.Errorf("received status code %d from server: err: %s", resp.StatusCode, response.Error())
+	}
+	for {
+		var chunk struct {
+			Results []struct {
+				StatementID int      `json:"statement_id"`
+				Series      []Series `json:"series"`
+				Err         string   `json:"error"`
+			} `json:"results"`
+			Err string `json:"error"`
+		}
+		if err := dec.Decode(&chunk); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if chunk.Err != "" {
+			return errors.New(chunk.Err)
+		}
+		for _, res := range chunk.Results {
+			if res.Err != "" {
+				return errors.New(res.Err)
+			}
+			for i := range res.Series {
+				s := &res.Series[i]
+				s.Statement = res.StatementID
+				if err := epochTimes(s); err != nil {
+					return err
+				}
+				if err := fn(s); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// FluxStream runs a Flux query of the organization and calls fn with the rows
+// of its tables, in series of at most chunkSize rows
+func (c *Client) FluxStream(ctx context.Context, org, query string, chunkSize int, fn func(*Series) error) error {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	u := *c.URL
+	u.Path = "api/v2/query"
+	params := u.Query()
+	params.Set("org", org)
+	u.RawQuery = params.Encode()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query": query,
+		"type":  "flux",
+		"dialect": map[string]interface{}{
+			"annotations": []string{"datatype", "group", "default"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.stream(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var response responseType
+		_ = json.NewDecoder(resp.Body).Decode(&response)
+		return fmt.Errorf("received status code %d from server: err: %s", resp.StatusCode, response.Error())
+	}
+	return decodeFlux(resp.Body, chunkSize, fn)
+}
+
+// stream sends the request of a streamed query, canceled with ctx
+func (c *Client) stream(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if c.Authorizer != nil {
+		if err := c.Authorizer.Set(req); err != nil {
+			return nil, err
+		}
+	}
+	hc := &http.Client{
+		Transport: SharedTransport(c.InsecureSkipVerify),
+	}
+	return hc.Do(req.WithContext(ctx))
+}
+
+// epochTimes changes the epoch nanoseconds of the time column of the series to times
+func epochTimes(s *Series) error {
+	for i, col := range s.Columns {
+		if col != "time" {
+			continue
+		}
+		for _, row := range s.Values {
+			if i >= len(row) {
+				continue
+			}
+			n, ok := row[i].(json.Number)
+			if !ok {
+				continue
+			}
+			ns, err := n.Int64()
+			if err != nil {
+				return fmt.Errorf("invalid time %s: %v", n, err)
+			}
+			row[i] = time.Unix(0, ns).UTC()
+		}
+	}
+	return nil
+}
+
+// fluxTable is the schema of the Flux tables being decoded
+type fluxTable struct {
+	datatypes []string
+	groups    []string
+	defaults  []string
+	header    []string
+}
+
+// decodeFlux decodes the tables of annotated CSV, the response of Flux queries
+func decodeFlux(r io.Reader, chunkSize int, fn func(*Series) error) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+
+	var table fluxTable
+	var series *Series
+	tableID := ""
+	flush := func() error {
+		if series == nil || len(series.Values) == 0 {
+			return nil
+		}
+		err := fn(series)
+		series.Values = make([][]interface{}, 0, chunkSize)
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return flush()
+		} else if err != nil {
+			return err
+		}
+		if len(record) == 0 {
+			continue
+		}
+		switch record[0] {
+		case "#datatype":
+			if err := flush(); err != nil {
+				return err
+			}
+			table = fluxTable{datatypes: copyRecord(record)}
+			series = nil
+			continue
+		case "#group":
+			table.groups = copyRecord(record)
+			continue
+		case "#default":
+			table.defaults = copyRecord(record)
+			continue
+		}
+		if table.header == nil {
+			table.header = copyRecord(record)
+			continue
+		}
+		if len(table.header) > 1 && table.header[1] == "error" {
+			if len(record) > 1 && record[1] != "" {
+				return errors.New(record[1])
+			}
+			continue
+		}
+
+		id := table.value(record, "table")
+		if series == nil || id != tableID {
+			if err := flush(); err != nil {
+				return err
+			}
+			series = table.series(record, chunkSize)
+			tableID = id
+		}
+		row := make([]interface{}, 0, len(series.Columns))
+		for i, col := range table.header {
+			if i == 0 || col == "result" || col == "table" {
+				continue
+			}
+			v, err := table.parse(i, record)
+			if err != nil {
+				return fmt.Errorf("invalid value of column %s: %v", col, err)
+			}
+			row = append(row, v)
+		}
+		series.Values = append(series.Values, row)
+		if len(series.Values) >= chunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func copyRecord(record []string) []string {
+	return append([]string(nil), record...)
+}
+
+// value returns the value of the column of the record, or its default
+func (t *fluxTable) value(record []string, column string) string {
+	for i, col := range t.header {
+		if col == column {
+			return t.raw(i, record)
+		}
+	}
+	return ""
+}
+
+func (t *fluxTable) raw(i int, record []string) string {
+	if i < len(record) && record[i] != "" {
+		return record[i]
+	}
+	if i < len(t.defaults) {
+		return t.defaults[i]
+	}
+	return ""
+}
+
+// series returns the series of the table of the record: its measurement and
+// its group key columns as tags
+func (t *fluxTable) series(record []string, chunkSize int) *Series {
+	s := &Series{
+		Name:   t.value(record, "_measurement"),
+		Tags:   map[string]string{},
+		Values: make([][]interface{}, 0, chunkSize),
+	}
+	for i, col := range t.header {
+		if i == 0 || col == "result" || col == "table" {
+			continue
+		}
+		s.Columns = append(s.Columns, col)
+		if !fluxColumns[col] && i < len(t.groups) && t.groups[i] == "true" {
+			s.Tags[col] = t.raw(i, record)
+		}
+	}
+	return s
+}
+
+// parse returns the value of column i of the record typed by its datatype
+func (t *fluxTable) parse(i int, record []string) (interface{}, error) {
+	v := t.raw(i, record)
+	datatype := ""
+	if i < len(t.datatypes) {
+		datatype = t.datatypes[i]
+	}
+	if v == "" && datatype != "string" {
+		return nil, nil
+	}
+	switch {
+	case datatype == "long":
+		return strconv.ParseInt(v, 10, 64)
+	case datatype == "unsignedLong":
+		return strconv.ParseUint(v, 10, 64)
+	case datatype == "double":
+		return strconv.ParseFloat(v, 64)
+	case datatype == "boolean":
+		return strconv.ParseBool(v)
+	case strings.HasPrefix(datatype, "dateTime"):
+		return time.Parse(time.RFC3339Nano, v)
+	}
+	return v, nil
+}
@@ -0,0 +1,149 @@
+package influx_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/influx"
+	"github.com/snetsystems/cloudhub/backend/log"
+)
+
+func TestClient_QueryStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		if r.URL.Path != "/query" || params.Get("chunked") != "true" || params.Get("chunk_size") != "2" || params.Get("epoch") != "ns" || params.Get("db") != "telegraf" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"web01"},"columns":["time","usage"],"values":[[1000000000,1.5],[2000000000,2]]}],"partial":true}]}
+{"results":[{"statement_id":0,"series":[{"name":"cpu","tags":{"host":"web01"},"columns":["time","usage"],"values":[[3000000000,3]]}]}]}
+`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL, log.New(log.DebugLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []influx.Series
+	err = client.QueryStream(context.Background(), cloudhub.Query{Command: "SELECT usage FROM cpu", DB: "telegraf"}, 2, func(s *influx.Series) error {
+		got = append(got, *s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("QueryStream() error = %v", err)
+	}
+	want := []influx.Series{
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "web01"},
+			Columns: []string{"time", "usage"},
+			Values: [][]interface{}{
+				{time.Unix(1, 0).UTC(), json.Number("1.5")},
+				{time.Unix(2, 0).UTC(), json.Number("2")},
+			},
+		},
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "web01"},
+			Columns: []string{"time", "usage"},
+			Values: [][]interface{}{
+				{time.Unix(3, 0).UTC(), json.Number("3")},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("QueryStream() = %v, want %v", got, want)
+	}
+}
+
+func TestClient_QueryStream_Error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results":[{"statement_id":0,"error":"database not found: telegraf"}]}
+`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL, log.New(log.DebugLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.QueryStream(context.Background(), cloudhub.Query{Command: "SELECT usage FROM cpu"}, 0, func(s *influx.Series) error {
+		return nil
+	})
+	if err == nil || err.Error() != "database not found: telegraf" {
+		t.Errorf("QueryStream() error = %v, want database not found: telegraf", err)
+	}
+}
+
+func TestClient_FluxStream(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/query" || r.URL.Query().Get("org") != "snet" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(`#datatype,string,long,dateTime:RFC3339,double,string,string,string
+#group,false,false,false,false,true,true,true
+#default,_result,,,,,,
+,result,table,_time,_value,_field,_measurement,host
+,,0,2020-01-01T00:00:00Z,1.5,usage,cpu,web01
+,,0,2020-01-01T00:00:10Z,2,usage,cpu,web01
+,,1,2020-01-01T00:00:00Z,3,usage,cpu,web02
+
+#datatype,string,long,dateTime:RFC3339,long,string,string
+#group,false,false,false,false,true,true
+#default,_result,,,,,
+,result,table,_time,_value,_field,_measurement
+,,2,2020-01-01T00:00:00Z,7,count,mem
+`))
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL, log.New(log.DebugLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []influx.Series
+	err = client.FluxStream(context.Background(), "snet", `from(bucket: "telegraf")`, 1000, func(s *influx.Series) error {
+		got = append(got, *s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("FluxStream() error = %v", err)
+	}
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := []influx.Series{
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "web01"},
+			Columns: []string{"_time", "_value", "_field", "_measurement", "host"},
+			Values: [][]interface{}{
+				{t0, 1.5, "usage", "cpu", "web01"},
+				{t0.Add(10 * time.Second), 2.0, "usage", "cpu", "web01"},
+			},
+		},
+		{
+			Name:    "cpu",
+			Tags:    map[string]string{"host": "web02"},
+			Columns: []string{"_time", "_value", "_field", "_measurement", "host"},
+			Values: [][]interface{}{
+				{t0, 3.0, "usage", "cpu", "web02"},
+			},
+		},
+		{
+			Name:    "mem",
+			Tags:    map[string]string{},
+			Columns: []string{"_time", "_value", "_field", "_measurement"},
+			Values: [][]interface{}{
+				{t0, int64(7), "count", "mem"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FluxStream() =\n%v, want\n%v", got, want)
+	}
+}
@@ -126,6 +126,15 @@ func (c *Client) All(ctx context.Context) ([]cloudhub.User, error) {
 	return users, nil
 }
 
+// Page returns the page of the users in influx q selects, the ID of a user being its name
+func (c *Client) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.User]) ([]cloudhub.User, error) {
+	users, err := c.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cloudhub.PageOf(users, q, func(u cloudhub.User) string { return u.Name }), nil
+}
+
 // Num is the number of users in DB
 func (c *Client) Num(ctx context.Context) (int, error) {
 	all, err := c.All(ctx)
@@ -1,6 +1,7 @@
 package bolt
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -36,20 +37,22 @@ var (
 
 // client is a client for the boltDB data store.
 type client struct {
-	buildInfo  cloudhub.BuildInfo
-	buildStore *buildStore
-	db         *bolt.DB
-	isNew      bool
-	logger     cloudhub.Logger
-	path       string
+	buildInfo   cloudhub.BuildInfo
+	buildStore  *buildStore
+	coordinator *kv.LocalCoordinator
+	db          *bolt.DB
+	isNew       bool
+	logger      cloudhub.Logger
+	path        string
 }
 
 // NewClient initializes bolt client implementing the kv.Store interface.
 func NewClient(ctx context.Context, opts ...Option) (*client, error) {
 	c := &client{
-		buildInfo: defaultBuildInfo,
-		path:      defaultBoltPath,
-		logger:    mocks.NewLogger(),
+		buildInfo:   defaultBuildInfo,
+		coordinator: kv.NewLocalCoordinator(),
+		path:        defaultBoltPath,
+		logger:      mocks.NewLogger(),
 	}
 
 	for i := range opts {
@@ -210,6 +213,36 @@ func (b *Bucket) ForEach(fn func(k, v []byte) error) error {
 	return b.bucket.ForEach(fn)
 }
 
+// ForEachPrefix executes a function for each key/value pair in a bucket
+// whose key starts with prefix, in key order.
+func (b *Bucket) ForEachPrefix(prefix []byte, fn func(k, v []byte) error) error {
+	c := b.bucket.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForEachAfter executes a function for each key/value pair in a bucket
+// whose key sorts after key, in key order, from the first pair when key is nil.
+func (b *Bucket) ForEachAfter(key []byte, fn func(k, v []byte) error) error {
+	c := b.bucket.Cursor()
+	k, v := c.First()
+	if key != nil {
+		if k, v = c.Seek(key); bytes.Equal(k, key) {
+			k, v = c.Next()
+		}
+	}
+	for ; k != nil; k, v = c.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // initialize creates Buckets that are missing
 func (c *client) initialize(ctx context.Context) error {
 	if err := c.db.Update(func(tx *bolt.Tx) error {
@@ -0,0 +1,26 @@
+package bolt
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// Ensure client implements cloudhub.Coordinator. A bolt file is opened by a
+// single CloudHub instance, so the work is only coordinated within this process.
+var _ cloudhub.Coordinator = (*client)(nil)
+
+// Lock blocks until the named lock is held or ctx is done.
+func (c *client) Lock(ctx context.Context, name string) (func(), error) {
+	return c.coordinator.Lock(ctx, name)
+}
+
+// TryLock acquires the named lock unless it is already held.
+func (c *client) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	return c.coordinator.TryLock(ctx, name)
+}
+
+// Campaign elects this instance right away.
+func (c *client) Campaign(ctx context.Context, name string) (context.Context, func(), error) {
+	return c.coordinator.Campaign(ctx, name)
+}
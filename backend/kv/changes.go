@@ -0,0 +1,152 @@
+package kv
+
+import (
+	"context"
+	"sync"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// Ensure changeFeed implements cloudhub.ChangeFeed.
+var _ cloudhub.ChangeFeed = &changeFeed{}
+
+// changedResources names the resources of the buckets whose changes are published.
+// Index buckets and buckets of internal records are not published.
+var changedResources = map[string]string{
+	string(dashboardsBucket):    "dashboards",
+	string(organizationsBucket): "organizations",
+	string(serversBucket):       "servers",
+	string(sourcesBucket):       "sources",
+	string(usersBucket):         "users",
+	string(topologyBucket):      "topologies",
+	string(protoboardsBucket):   "protoboards",
+	string(orgTemplatesBucket):  "orgTemplates",
+	string(reportsBucket):       "reports",
+	string(snapshotsBucket):     "snapshots",
+	string(foldersBucket):       "folders",
+}
+
+// changeFeed publishes the changes committed through a changeStore
+type changeFeed struct {
+	mu          sync.RWMutex
+	next        int
+	subscribers map[int]func(cloudhub.Change)
+}
+
+// Subscribe calls fn with each change committed until unsubscribe is called.
+func (f *changeFeed) Subscribe(fn func(cloudhub.Change)) func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subscribers == nil {
+		f.subscribers = map[int]func(cloudhub.Change){}
+	}
+	id := f.next
+	f.next++
+	f.subscribers[id] = fn
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			delete(f.subscribers, id)
+		})
+	}
+}
+
+func (f *changeFeed) publish(changes []cloudhub.Change) {
+	if len(changes) == 0 {
+		return
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, c := range changes {
+		for _, fn := range f.subscribers {
+			fn(c)
+		}
+	}
+}
+
+// changeStore is a Store recording the changes of its update transactions and
+// publishing them to its feed once they are committed
+type changeStore struct {
+	Store
+	feed *changeFeed
+}
+
+// Update records the changes made by fn and publishes them if the transaction commits.
+func (s *changeStore) Update(ctx context.Context, fn func(Tx) error) error {
+	var tx *changeTx
+	err := s.Store.Update(ctx, func(inner Tx) error {
+		// stores may retry a transaction, only the last attempt commits
+		tx = &changeTx{Tx: inner, seen: map[cloudhub.Change]bool{}}
+		return fn(tx)
+	})
+	if err == nil && tx != nil {
+		s.feed.publish(tx.changes)
+	}
+	return err
+}
+
+// changeTx is a Tx recording the changes made to its buckets
+type changeTx struct {
+	Tx
+	changes []cloudhub.Change
+	seen    map[cloudhub.Change]bool
+}
+
+func (tx *changeTx) record(resource string, key []byte, deleted bool) {
+	c := cloudhub.Change{Resource: resource, ID: string(key), Deleted: deleted}
+	if tx.seen[c] {
+		return
+	}
+	tx.seen[c] = true
+	tx.changes = append(tx.changes, c)
+}
+
+func (tx *changeTx) wrap(name []byte, b Bucket) Bucket {
+	resource, ok := changedResources[string(name)]
+	if !ok || b == nil {
+		return b
+	}
+	return &changeBucket{Bucket: b, tx: tx, resource: resource}
+}
+
+// Bucket returns the bucket, recording the changes made to it.
+func (tx *changeTx) Bucket(b []byte) Bucket {
+	return tx.wrap(b, tx.Tx.Bucket(b))
+}
+
+// CreateBucketIfNotExists returns the bucket, recording the changes made to it.
+func (tx *changeTx) CreateBucketIfNotExists(b []byte) (Bucket, error) {
+	bucket, err := tx.Tx.CreateBucketIfNotExists(b)
+	if err != nil {
+		return nil, err
+	}
+	return tx.wrap(b, bucket), nil
+}
+
+// changeBucket is a Bucket recording the keys put and deleted
+type changeBucket struct {
+	Bucket
+	tx       *changeTx
+	resource string
+}
+
+// Put records the key as changed.
+func (b *changeBucket) Put(key, value []byte) error {
+	if err := b.Bucket.Put(key, value); err != nil {
+		return err
+	}
+	b.tx.record(b.resource, key, false)
+	return nil
+}
+
+// Delete records the key as deleted.
+func (b *changeBucket) Delete(key []byte) error {
+	if err := b.Bucket.Delete(key); err != nil {
+		return err
+	}
+	b.tx.record(b.resource, key, true)
+	return nil
+}
@@ -0,0 +1,61 @@
+package kv_test
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestChangeFeed(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	var changes []cloudhub.Change
+	unsubscribe := client.ChangeFeed().Subscribe(func(c cloudhub.Change) {
+		changes = append(changes, c)
+	})
+
+	s := client.DashboardsStore()
+	d, err := s.Add(ctx, cloudhub.Dashboard{Name: "Servers", Organization: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := strconv.Itoa(int(d.ID))
+	d.Name = "Hosts"
+	if err := s.Update(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if d, err = s.Get(ctx, d.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(ctx, cloudhub.Dashboard{ID: 1000}); err == nil {
+		t.Fatal("Update() of an unknown dashboard expected to fail")
+	}
+	if err := s.Delete(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []cloudhub.Change{
+		{Resource: "dashboards", ID: id},
+		{Resource: "dashboards", ID: id},
+		{Resource: "dashboards", ID: id, Deleted: true},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("ChangeFeed() published %v, want %v", changes, want)
+	}
+
+	unsubscribe()
+	if _, err := s.Add(ctx, cloudhub.Dashboard{Name: "Other"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != len(want) {
+		t.Errorf("ChangeFeed() published changes after unsubscribe: %v", changes[len(want):])
+	}
+}
@@ -0,0 +1,77 @@
+package kv
+
+import (
+	"context"
+	"sync"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// Ensure LocalCoordinator implements cloudhub.Coordinator.
+var _ cloudhub.Coordinator = &LocalCoordinator{}
+
+// LocalCoordinator is the cloudhub.Coordinator of a store used by a single
+// CloudHub instance, such as a bolt file which only one process can open.
+// Its locks only exclude the work within this process and the instance is
+// elected the leader of every election right away.
+type LocalCoordinator struct {
+	mu    sync.Mutex
+	locks map[string]chan struct{}
+}
+
+// NewLocalCoordinator returns a LocalCoordinator without any lock held.
+func NewLocalCoordinator() *LocalCoordinator {
+	return &LocalCoordinator{
+		locks: map[string]chan struct{}{},
+	}
+}
+
+// lock returns the channel holding a value while the named lock is held
+func (c *LocalCoordinator) lock(name string) chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.locks[name]
+	if !ok {
+		l = make(chan struct{}, 1)
+		c.locks[name] = l
+	}
+	return l
+}
+
+func unlocker(l chan struct{}) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-l })
+	}
+}
+
+// Lock blocks until the named lock is held or ctx is done.
+func (c *LocalCoordinator) Lock(ctx context.Context, name string) (func(), error) {
+	l := c.lock(name)
+	select {
+	case l <- struct{}{}:
+		return unlocker(l), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// TryLock acquires the named lock unless it is already held.
+func (c *LocalCoordinator) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l := c.lock(name)
+	select {
+	case l <- struct{}{}:
+		return unlocker(l), true, nil
+	default:
+		return nil, false, nil
+	}
+}
+
+// Campaign elects this instance right away as there is no other instance.
+func (c *LocalCoordinator) Campaign(ctx context.Context, name string) (context.Context, func(), error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	leaderCtx, cancel := context.WithCancel(ctx)
+	return leaderCtx, cancel, nil
+}
@@ -0,0 +1,53 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/snetsystems/cloudhub/backend/kv"
+)
+
+func TestLocalCoordinator(t *testing.T) {
+	c := kv.NewLocalCoordinator()
+	ctx := context.Background()
+
+	unlock, err := c.Lock(ctx, "collectors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := c.TryLock(ctx, "collectors"); ok {
+		t.Error("TryLock() acquired a held lock")
+	}
+	if unlockOther, ok, _ := c.TryLock(ctx, "jobs/1"); !ok {
+		t.Error("TryLock() of another lock failed")
+	} else {
+		unlockOther()
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Lock(timeout, "collectors"); err == nil {
+		t.Error("Lock() of a held lock expected to wait until the context is done")
+	}
+
+	unlock()
+	unlock()
+	unlock, ok, _ := c.TryLock(ctx, "collectors")
+	if !ok {
+		t.Fatal("TryLock() failed after the lock was released")
+	}
+	unlock()
+
+	leaderCtx, resign, err := c.Campaign(ctx, "trash-purge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaderCtx.Err() != nil {
+		t.Fatal("Campaign() returned a done leader context")
+	}
+	resign()
+	if leaderCtx.Err() == nil {
+		t.Error("resign() expected to end the leadership")
+	}
+}
@@ -70,15 +70,23 @@ func (s *cspStore) get(ctx context.Context, id string) (*cloudhub.CSP, error) {
 // Delete the CSP from cspStore
 func (s *cspStore) Delete(ctx context.Context, csp *cloudhub.CSP) error {
 	if err := s.client.kv.Update(ctx, func(tx Tx) error {
-		_, err := s.get(ctx, csp.ID)
+		prev, err := s.get(ctx, csp.ID)
 		if err != nil {
 			return cloudhub.ErrCSPNotFound
 		}
 
-		if err := tx.Bucket(cspBucket).Delete([]byte(csp.ID)); err != nil {
+		b := tx.Bucket(cspBucket)
+		v, err := b.Get([]byte(csp.ID))
+		if v == nil || err != nil {
+			return cloudhub.ErrCSPNotFound
+		}
+		if err := b.Delete([]byte(csp.ID)); err != nil {
 			return err
 		}
-		return nil
+
+		trash := &trashStore{client: s.client}
+		name := prev.Provider + "/" + prev.NameSpace
+		return trash.trash(ctx, tx, cloudhub.TrashCSP, csp.ID, prev.Organization, name, v)
 	}); err != nil {
 		return err
 	}
@@ -122,6 +130,31 @@ func (s *cspStore) All(ctx context.Context) ([]cloudhub.CSP, error) {
 	return csps, nil
 }
 
+// Page returns the CSP q selects in the order of their keys
+func (s *cspStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.CSP]) ([]cloudhub.CSP, error) {
+	var after []byte
+	if q.After != "" {
+		after = []byte(q.After)
+	}
+
+	csps := []cloudhub.CSP{}
+	if err := s.client.page(ctx, cspBucket, after, q.Limit, func(v []byte) (bool, error) {
+		var csp cloudhub.CSP
+		if err := internal.UnmarshalCSP(v, &csp); err != nil {
+			return false, err
+		}
+		if q.Match != nil && !q.Match(csp) {
+			return false, nil
+		}
+		csps = append(csps, csp)
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return csps, nil
+}
+
 func (s *cspStore) each(ctx context.Context, fn func(*cloudhub.CSP)) error {
 	return s.client.kv.View(ctx, func(tx Tx) error {
 		return tx.Bucket(cspBucket).ForEach(func(k, v []byte) error {
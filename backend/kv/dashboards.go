@@ -39,6 +39,31 @@ func (d *dashboardsStore) All(ctx context.Context) ([]cloudhub.Dashboard, error)
 	return srcs, nil
 }
 
+// Page returns the dashboards q selects in the order of their keys
+func (d *dashboardsStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error) {
+	var after []byte
+	if q.After != "" {
+		after = []byte(q.After)
+	}
+
+	srcs := []cloudhub.Dashboard{}
+	if err := d.client.page(ctx, dashboardsBucket, after, q.Limit, func(v []byte) (bool, error) {
+		var src cloudhub.Dashboard
+		if err := internal.UnmarshalDashboard(v, &src); err != nil {
+			return false, err
+		}
+		if q.Match != nil && !q.Match(src) {
+			return false, nil
+		}
+		srcs = append(srcs, src)
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return srcs, nil
+}
+
 // Add creates a new Dashboard in the dashboardsStore
 func (d *dashboardsStore) Add(ctx context.Context, src cloudhub.Dashboard) (cloudhub.Dashboard, error) {
 	if err := d.client.kv.Update(ctx, func(tx Tx) error {
@@ -49,6 +74,7 @@ func (d *dashboardsStore) Add(ctx context.Context, src cloudhub.Dashboard) (clou
 		}
 
 		src.ID = cloudhub.DashboardID(id)
+		src.Version = 1
 		// TODO: use FormatInt
 		strID := strconv.FormatUint(id, 10)
 		for i, cell := range src.Cells {
@@ -92,19 +118,49 @@ func (d *dashboardsStore) Get(ctx context.Context, id cloudhub.DashboardID) (clo
 // Delete the dashboard from dashboardsStore
 func (d *dashboardsStore) Delete(ctx context.Context, dash cloudhub.Dashboard) error {
 	return d.client.kv.Update(ctx, func(tx Tx) error {
-		return tx.Bucket(dashboardsBucket).Delete([]byte(strconv.Itoa(int(dash.ID))))
+		b := tx.Bucket(dashboardsBucket)
+		strID := strconv.Itoa(int(dash.ID))
+		v, err := b.Get([]byte(strID))
+		if v == nil || err != nil {
+			// nothing to move to the trash if the dashboard does not exist
+			return b.Delete([]byte(strID))
+		}
+
+		var prev cloudhub.Dashboard
+		if err := internal.UnmarshalDashboard(v, &prev); err != nil {
+			return err
+		}
+		if err := checkVersion(dash.Version, prev.Version); err != nil {
+			return err
+		}
+		if err := b.Delete([]byte(strID)); err != nil {
+			return err
+		}
+
+		trash := &trashStore{client: d.client}
+		return trash.trash(ctx, tx, cloudhub.TrashDashboard, strID, prev.Organization, prev.Name, v)
 	})
 }
 
 // Update the dashboard in dashboardsStore
 func (d *dashboardsStore) Update(ctx context.Context, dash cloudhub.Dashboard) error {
+	expected := dash.Version
 	if err := d.client.kv.Update(ctx, func(tx Tx) error {
 		// Get an existing dashboard with the same ID.
 		b := tx.Bucket(dashboardsBucket)
 		strID := strconv.Itoa(int(dash.ID))
-		if v, err := b.Get([]byte(strID)); v == nil || err != nil {
+		v, err := b.Get([]byte(strID))
+		if v == nil || err != nil {
 			return cloudhub.ErrDashboardNotFound
 		}
+		var prev cloudhub.Dashboard
+		if err := internal.UnmarshalDashboard(v, &prev); err != nil {
+			return err
+		}
+		if err := checkVersion(expected, prev.Version); err != nil {
+			return err
+		}
+		dash.Version = prev.Version + 1
 
 		for i, cell := range dash.Cells {
 			if cell.ID != "" {
@@ -122,7 +178,9 @@ func (d *dashboardsStore) Update(ctx context.Context, dash cloudhub.Dashboard) e
 		} else if err := b.Put([]byte(strID), v); err != nil {
 			return err
 		}
-		return nil
+
+		revs := &revisionsStore{client: d.client}
+		return revs.revise(ctx, tx, cloudhub.RevisionDashboard, strID, dash.Organization, prev, dash)
 	}); err != nil {
 		return err
 	}
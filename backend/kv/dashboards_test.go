@@ -2,6 +2,8 @@ package kv_test
 
 import (
 	"context"
+	"reflect"
+	"strconv"
 	"testing"
 
 	gocmp "github.com/google/go-cmp/cmp"
@@ -73,6 +75,7 @@ func TestDashboardsStore_Add(t *testing.T) {
 				},
 				Templates: []cloudhub.Template{},
 				Name:      "best name",
+				Version:   1,
 			},
 		},
 	}
@@ -159,6 +162,7 @@ func TestDashboardStore_All(t *testing.T) {
 					},
 					Templates: []cloudhub.Template{},
 					Name:      "best name",
+					Version:   1,
 				},
 			},
 		},
@@ -375,6 +379,7 @@ func TestDashboardStore_Get(t *testing.T) {
 					},
 					Templates: []cloudhub.Template{},
 					Name:      "best name1",
+					Version:   1,
 				},
 				err: nil,
 			},
@@ -509,6 +514,7 @@ func TestDashboardStore_Update(t *testing.T) {
 					},
 					Templates: []cloudhub.Template{},
 					Name:      "best name2",
+					Version:   2,
 				},
 
 				err: nil,
@@ -535,6 +541,7 @@ func TestDashboardStore_Update(t *testing.T) {
 			id := d.ID
 			d = tt.wants.dashboard
 			d.ID = id
+			d.Version = 0
 
 			err = s.Update(ctx, d)
 			if (err != nil) != (tt.wants.err != nil) {
@@ -550,3 +557,100 @@ func TestDashboardStore_Update(t *testing.T) {
 		})
 	}
 }
+
+func TestDashboardStore_UpdateVersion(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	s := client.DashboardsStore()
+	ctx := context.Background()
+
+	d, err := s.Add(ctx, cloudhub.Dashboard{Name: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version != 1 {
+		t.Fatalf("expected version 1 after add, got %d", d.Version)
+	}
+
+	a, _ := s.Get(ctx, d.ID)
+	if err := s.Update(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(ctx, d); err != cloudhub.ErrVersionConflict {
+		t.Fatalf("expected ErrVersionConflict for a stale update of a new dashboard, got %v", err)
+	}
+	a, _ = s.Get(ctx, d.ID)
+	if a.Version != 2 {
+		t.Fatalf("expected version 2 after update, got %d", a.Version)
+	}
+
+	a.Name = "second"
+	if err := s.Update(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := a
+	stale.Name = "stale"
+	if err := s.Update(ctx, stale); err != cloudhub.ErrVersionConflict {
+		t.Fatalf("expected ErrVersionConflict for a stale update, got %v", err)
+	}
+	if err := s.Delete(ctx, stale); err != cloudhub.ErrVersionConflict {
+		t.Fatalf("expected ErrVersionConflict for a stale delete, got %v", err)
+	}
+
+	got, _ := s.Get(ctx, d.ID)
+	if got.Name != "second" || got.Version != 3 {
+		t.Errorf("expected second at version 3, got %q at version %d", got.Name, got.Version)
+	}
+}
+
+func TestDashboardStore_Page(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	s := client.DashboardsStore()
+	ctx := context.Background()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := s.Add(ctx, cloudhub.Dashboard{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(ds []cloudhub.Dashboard) []string {
+		res := []string{}
+		for _, d := range ds {
+			res = append(res, d.Name)
+		}
+		return res
+	}
+	notC := func(d cloudhub.Dashboard) bool { return d.Name != "c" }
+
+	first, err := s.Page(ctx, cloudhub.PageQuery[cloudhub.Dashboard]{Limit: 2, Match: notC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(first); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("first page = %v, want a, b", got)
+	}
+	second, err := s.Page(ctx, cloudhub.PageQuery[cloudhub.Dashboard]{Limit: 2, Match: notC, After: strconv.Itoa(int(first[1].ID))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(second); !reflect.DeepEqual(got, []string{"d", "e"}) {
+		t.Errorf("second page = %v, want d, e", got)
+	}
+	rest, err := s.Page(ctx, cloudhub.PageQuery[cloudhub.Dashboard]{After: strconv.Itoa(int(second[0].ID))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(rest); !reflect.DeepEqual(got, []string{"e"}) {
+		t.Errorf("page without limit = %v, want e", got)
+	}
+}
@@ -29,6 +29,9 @@ const (
 	DefaultCacheTimeout = 2 * time.Second
 	// DefaultEndpoint is the default etcd endpoint.
 	DefaultEndpoint = "localhost:2379"
+
+	// batchSize is the number of entries read by each request of ForEachAfter.
+	batchSize = 100
 )
 
 var (
@@ -334,7 +337,13 @@ type Pair struct {
 
 // ForEach loops over all bucket entries and applies fn to them.
 func (b *Bucket) ForEach(fn func(k, v []byte) error) error {
-	pairs, err := b.getAll(string(b.prefix))
+	return b.ForEachPrefix(nil, fn)
+}
+
+// ForEachPrefix loops over the bucket entries whose key starts with prefix
+// and applies fn to them.
+func (b *Bucket) ForEachPrefix(prefix []byte, fn func(k, v []byte) error) error {
+	pairs, err := b.getAll(string(b.prefix), prefix)
 	if err != nil {
 		return err
 	}
@@ -349,12 +358,44 @@ func (b *Bucket) ForEach(fn func(k, v []byte) error) error {
 	return nil
 }
 
+// ForEachAfter loops over the bucket entries whose key sorts after key and
+// applies fn to them. Entries are read in batches, so that the entries after
+// the ones fn stops at are not read.
+func (b *Bucket) ForEachAfter(key []byte, fn func(k, v []byte) error) error {
+	prefix := string(b.prefix) + "/"
+	from := prefix
+	if key != nil {
+		from = prefix + string(key) + "\x00"
+	}
+
+	kvOpts := []clientv3.OpOption{
+		clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+		clientv3.WithLimit(batchSize),
+	}
+	for {
+		r, err := b.tx.client.db.Get(context.TODO(), from, kvOpts...)
+		if err != nil {
+			return err
+		}
+		for _, k := range r.Kvs {
+			if err := fn(bytes.TrimPrefix(k.Key, []byte(prefix)), k.Value); err != nil {
+				return err
+			}
+		}
+		if !r.More || len(r.Kvs) == 0 {
+			return nil
+		}
+		from = string(r.Kvs[len(r.Kvs)-1].Key) + "\x00"
+	}
+}
+
 // NextSequence generates a universally unique uint64.
 func (b *Bucket) NextSequence() (uint64, error) {
 	return generator.Next(), nil
 }
 
-func (b *Bucket) getAll(prefix string) ([]Pair, error) {
+func (b *Bucket) getAll(prefix string, keyPrefix []byte) ([]Pair, error) {
 	var startKey = prefix + "/"
 
 	kvOpts := []clientv3.OpOption{
@@ -362,7 +403,7 @@ func (b *Bucket) getAll(prefix string) ([]Pair, error) {
 		clientv3.WithPrefix(),
 	}
 
-	r, err := b.tx.client.db.Get(context.TODO(), startKey, kvOpts...)
+	r, err := b.tx.client.db.Get(context.TODO(), startKey+string(keyPrefix), kvOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,130 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"go.etcd.io/etcd/clientv3"
+	"go.etcd.io/etcd/clientv3/concurrency"
+)
+
+const (
+	lockPrefix     = "/cloudhub/locks/"
+	electionPrefix = "/cloudhub/elections/"
+	// sessionTTL is the number of seconds after which the locks and the
+	// leadership of an instance that stopped are released.
+	sessionTTL = 15
+)
+
+// Ensure client implements cloudhub.Coordinator.
+var _ cloudhub.Coordinator = (*client)(nil)
+
+// instanceName identifies this process as the candidate of the elections
+var instanceName = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}()
+
+// session returns a new session whose lease is kept alive until it is closed.
+// Each lock and leadership has its own session so that releasing it revokes
+// the lease and removes every key it holds.
+func (c *client) session() (*concurrency.Session, error) {
+	return concurrency.NewSession(c.db, concurrency.WithTTL(sessionTTL))
+}
+
+func (c *client) closer(s *concurrency.Session) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if err := s.Close(); err != nil {
+				c.logger.
+					WithField("component", "etcd").
+					Error("Unable to release lease: ", err)
+			}
+		})
+	}
+}
+
+// Lock blocks until the named lock is held or ctx is done. The lock is
+// released by the returned function or once this instance stops.
+func (c *client) Lock(ctx context.Context, name string) (func(), error) {
+	s, err := c.session()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := concurrency.NewMutex(s, lockPrefix+name).Lock(ctx); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return c.closer(s), nil
+}
+
+// TryLock acquires the named lock unless another instance holds or waits for it.
+// It takes the lock like concurrency.Mutex so that it excludes the holders of Lock.
+func (c *client) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	s, err := c.session()
+	if err != nil {
+		return nil, false, err
+	}
+
+	pfx := lockPrefix + name + "/"
+	key := fmt.Sprintf("%s%x", pfx, s.Lease())
+	resp, err := c.db.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(
+			clientv3.OpPut(key, "", clientv3.WithLease(s.Lease())),
+			clientv3.OpGet(pfx, clientv3.WithFirstCreate()...),
+		).
+		Commit()
+	if err != nil {
+		s.Close()
+		return nil, false, err
+	}
+
+	// the oldest key of the prefix holds the lock
+	owner := resp.Responses[1].GetResponseRange().Kvs
+	if len(owner) > 0 && owner[0].CreateRevision != resp.Header.Revision {
+		s.Close()
+		return nil, false, nil
+	}
+	return c.closer(s), true, nil
+}
+
+// Campaign blocks until this instance is elected the leader of the named
+// election or ctx is done. The leadership is lost once the lease of this
+// instance expires, for example when it cannot reach etcd.
+func (c *client) Campaign(ctx context.Context, name string) (context.Context, func(), error) {
+	s, err := c.session()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e := concurrency.NewElection(s, electionPrefix+name)
+	if err := e.Campaign(ctx, instanceName); err != nil {
+		s.Close()
+		return nil, nil, err
+	}
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-s.Done():
+		case <-leaderCtx.Done():
+		}
+		cancel()
+	}()
+
+	release := c.closer(s)
+	resign := func() {
+		cancel()
+		rctx, rcancel := context.WithTimeout(context.Background(), c.requestTimeout)
+		defer rcancel()
+		e.Resign(rctx)
+		release()
+	}
+	return leaderCtx, resign, nil
+}
@@ -0,0 +1,127 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure foldersStore implements cloudhub.FoldersStore.
+var _ cloudhub.FoldersStore = &foldersStore{}
+
+// foldersStore is the kv implementation of storing folders
+type foldersStore struct {
+	client *Service
+}
+
+// folderKey returns the key of a folder. The sequence is zero padded so that
+// the folders are iterated oldest first.
+func folderKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%020d", seq))
+}
+
+// All returns all folders in the store, oldest first.
+func (s *foldersStore) All(ctx context.Context) ([]cloudhub.Folder, error) {
+	folders := []cloudhub.Folder{}
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(foldersBucket).ForEach(func(k, v []byte) error {
+			var folder cloudhub.Folder
+			if err := internal.UnmarshalFolder(v, &folder); err != nil {
+				return err
+			}
+			folders = append(folders, folder)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// Add creates a new folder in the store.
+func (s *foldersStore) Add(ctx context.Context, folder *cloudhub.Folder) (*cloudhub.Folder, error) {
+	if err := s.client.kv.Update(ctx, func(tx Tx) error {
+		b := tx.Bucket(foldersBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		folder.ID = strconv.FormatUint(seq, 10)
+
+		v, err := internal.MarshalFolder(folder)
+		if err != nil {
+			return err
+		}
+		return b.Put(folderKey(seq), v)
+	}); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// Get returns a folder if the id exists.
+func (s *foldersStore) Get(ctx context.Context, id string) (*cloudhub.Folder, error) {
+	var folder *cloudhub.Folder
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		var err error
+		folder, _, err = s.get(tx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// Update replaces the folder in the store.
+func (s *foldersStore) Update(ctx context.Context, folder *cloudhub.Folder) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		_, key, err := s.get(tx, folder.ID)
+		if err != nil {
+			return err
+		}
+
+		v, err := internal.MarshalFolder(folder)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(foldersBucket).Put(key, v)
+	})
+}
+
+// Delete removes the folder from the store.
+func (s *foldersStore) Delete(ctx context.Context, folder *cloudhub.Folder) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		_, key, err := s.get(tx, folder.ID)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(foldersBucket).Delete(key)
+	})
+}
+
+// get returns the folder with the given id along with its key
+func (s *foldersStore) get(tx Tx, id string) (*cloudhub.Folder, []byte, error) {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, nil, cloudhub.ErrFolderNotFound
+	}
+
+	key := folderKey(seq)
+	v, err := tx.Bucket(foldersBucket).Get(key)
+	if v == nil || err != nil {
+		return nil, nil, cloudhub.ErrFolderNotFound
+	}
+
+	var folder cloudhub.Folder
+	if err := internal.UnmarshalFolder(v, &folder); err != nil {
+		return nil, nil, err
+	}
+	return &folder, key, nil
+}
@@ -0,0 +1,62 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestFoldersStore(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	s := client.FoldersStore()
+
+	parent, err := s.Add(ctx, &cloudhub.Folder{
+		Name:         "Network",
+		Organization: "1",
+		Permissions:  cloudhub.FolderPermissions{View: "viewer", Edit: "admin"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := s.Add(ctx, &cloudhub.Folder{Name: "Switches", Parent: parent.ID, Organization: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parent.ID == "" || child.ID == parent.ID {
+		t.Fatalf("expected Add to set distinct IDs, got %q and %q", parent.ID, child.ID)
+	}
+
+	child.Permissions.Edit = "editor"
+	if err := s.Update(ctx, child); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.Get(ctx, child.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Parent != parent.ID || got.Permissions.Edit != "editor" || got.Organization != "1" {
+		t.Errorf("unexpected folder %#v", got)
+	}
+
+	folders, err := s.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(folders) != 2 || folders[0].ID != parent.ID || folders[0].Permissions.View != "viewer" {
+		t.Fatalf("expected the 2 folders oldest first, got %#v", folders)
+	}
+
+	if err := s.Delete(ctx, child); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, child.ID); err != cloudhub.ErrFolderNotFound {
+		t.Errorf("expected ErrFolderNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package kv
+
+import (
+	"context"
+	"net/url"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// index is a secondary index of the records of a bucket by the value of one
+// of their fields. Its keys are <escaped value>/<record id> so that the IDs of
+// the records holding a value are found with a prefix scan of the index.
+type index struct {
+	bucket []byte
+	value  func(v []byte) (string, error)
+}
+
+var (
+	networkDeviceOrgIndex = &index{
+		bucket: networkDeviceOrgIndexBucket,
+		value: func(v []byte) (string, error) {
+			var device cloudhub.NetworkDevice
+			err := internal.UnmarshalNetworkDevice(v, &device)
+			return device.Organization, err
+		},
+	}
+	networkDeviceIPIndex = &index{
+		bucket: networkDeviceIPIndexBucket,
+		value: func(v []byte) (string, error) {
+			var device cloudhub.NetworkDevice
+			err := internal.UnmarshalNetworkDevice(v, &device)
+			return device.DeviceIP, err
+		},
+	}
+	topologyOrgIndex = &index{
+		bucket: topologyOrgIndexBucket,
+		value: func(v []byte) (string, error) {
+			var tp cloudhub.Topology
+			err := internal.UnmarshalTopology(v, &tp)
+			return tp.Organization, err
+		},
+	}
+	snapshotTokenIndex = &index{
+		bucket: snapshotTokenIndexBucket,
+		value: func(v []byte) (string, error) {
+			var snapshot cloudhub.Snapshot
+			err := internal.UnmarshalSnapshot(v, &snapshot)
+			return snapshot.TokenHash, err
+		},
+	}
+)
+
+// indexes are the secondary indexes maintained for the records of a bucket
+var indexes = map[string][]*index{
+	string(networkDeviceBucket): {networkDeviceOrgIndex, networkDeviceIPIndex},
+	string(topologyBucket):      {topologyOrgIndex},
+	string(snapshotsBucket):     {snapshotTokenIndex},
+}
+
+func (i *index) prefix(value string) []byte {
+	return []byte(url.PathEscape(value) + "/")
+}
+
+func (i *index) key(value, id string) []byte {
+	return append(i.prefix(value), id...)
+}
+
+// ids returns the IDs of the records whose indexed field is value.
+func (i *index) ids(tx Tx, value string) ([]string, error) {
+	prefix := i.prefix(value)
+	ids := []string{}
+	err := tx.Bucket(i.bucket).ForEachPrefix(prefix, func(k, v []byte) error {
+		ids = append(ids, string(k[len(prefix):]))
+		return nil
+	})
+	return ids, err
+}
+
+// reindex updates the indexes of bucket for the record id changing from prev
+// to next. prev is nil for a new record and next is nil for a removed one.
+func reindex(tx Tx, bucket []byte, id string, prev, next []byte) error {
+	for _, i := range indexes[string(bucket)] {
+		var old, cur string
+		var err error
+		if prev != nil {
+			if old, err = i.value(prev); err != nil {
+				return err
+			}
+		}
+		if next != nil {
+			if cur, err = i.value(next); err != nil {
+				return err
+			}
+		}
+		if prev != nil && next != nil && old == cur {
+			continue
+		}
+
+		b := tx.Bucket(i.bucket)
+		if prev != nil && old != "" {
+			if err := b.Delete(i.key(old, id)); err != nil {
+				return err
+			}
+		}
+		if next != nil && cur != "" {
+			if err := b.Put(i.key(cur, id), []byte(id)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// buildIndexes builds the indexes that were not built yet from the records of
+// their buckets, so that they also cover records stored before they existed.
+// A built index is marked in the migrations bucket and then maintained by the
+// stores with reindex.
+func (s *Service) buildIndexes(ctx context.Context) error {
+	for bucket, idxs := range indexes {
+		for _, i := range idxs {
+			if err := s.buildIndex(ctx, []byte(bucket), i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Service) buildIndex(ctx context.Context, bucket []byte, i *index) error {
+	var built bool
+	var stale [][]byte
+	var missing [][2][]byte // keys and IDs of the entries to write
+	if err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		if built, err = tx.Bucket(migrationsBucket).Exists(i.bucket); err != nil || built {
+			return err
+		}
+
+		want := map[string]string{}
+		if err := tx.Bucket(bucket).ForEach(func(k, v []byte) error {
+			value, err := i.value(v)
+			if err != nil || value == "" {
+				// records that cannot be read are skipped like in the stores
+				return nil
+			}
+			want[string(i.key(value, string(k)))] = string(k)
+			return nil
+		}); err != nil {
+			return err
+		}
+		// entries of an interrupted build are kept, those of removed records dropped
+		if err := tx.Bucket(i.bucket).ForEach(func(k, v []byte) error {
+			if _, ok := want[string(k)]; ok {
+				delete(want, string(k))
+			} else {
+				stale = append(stale, append([]byte{}, k...))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		for k, id := range want {
+			missing = append(missing, [2][]byte{[]byte(k), []byte(id)})
+		}
+		return nil
+	}); err != nil || built {
+		return err
+	}
+
+	if err := s.deleteKeys(ctx, i.bucket, stale); err != nil {
+		return err
+	}
+	for len(missing) > 0 {
+		n := len(missing)
+		if n > batchSize {
+			n = batchSize
+		}
+		if err := s.kv.Update(ctx, func(tx Tx) error {
+			b := tx.Bucket(i.bucket)
+			for _, e := range missing[:n] {
+				if err := b.Put(e[0], e[1]); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		missing = missing[n:]
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return tx.Bucket(migrationsBucket).Put(i.bucket, []byte("built"))
+	})
+}
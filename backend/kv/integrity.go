@@ -0,0 +1,556 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure integrityChecker implements cloudhub.IntegrityChecker.
+var _ cloudhub.IntegrityChecker = &integrityChecker{}
+
+// Resource types reported in integrity issues
+const (
+	integrityUser               = "user"
+	integritySource             = "source"
+	integrityServer             = "server"
+	integrityMapping            = "mapping"
+	integrityOrganizationConfig = "organization_config"
+	integrityDashboard          = "dashboard"
+	integrityTopology           = "topology"
+	integrityCSP                = "csp"
+	integrityVsphere            = "vsphere"
+	integrityNetworkDevice      = "network_device"
+	integrityNetworkDeviceOrg   = "network_device_org"
+	integrityMLNxRst            = "ml_nx_rst"
+)
+
+// integrityChecker is the bolt and etcd implementation of checking
+// the references between the resources of all stores.
+type integrityChecker struct {
+	client *Service
+}
+
+// integrityScan collects the issues found in one transaction together with
+// the changes that repair them. A fix may be nil when an earlier fix of the
+// same resource already repairs the issue.
+type integrityScan struct {
+	ctx    context.Context
+	trash  *trashStore
+	issues []cloudhub.IntegrityIssue
+	fixes  []func(Tx) error
+
+	orgs      map[string]bool
+	srcs      map[string]cloudhub.Source
+	deviceIDs map[string]bool
+	deviceIPs map[string]bool
+}
+
+// Check reports the dangling references and orphaned resources of all stores.
+func (s *integrityChecker) Check(ctx context.Context) ([]cloudhub.IntegrityIssue, error) {
+	var issues []cloudhub.IntegrityIssue
+	err := s.client.kv.View(ctx, func(tx Tx) error {
+		scan, err := s.scan(ctx, tx)
+		if err != nil {
+			return err
+		}
+		issues = scan.issues
+		return nil
+	})
+	return issues, err
+}
+
+// Repair removes or re-links the dangling references and orphaned resources
+// of all stores in a single transaction.
+func (s *integrityChecker) Repair(ctx context.Context) ([]cloudhub.IntegrityIssue, error) {
+	var issues []cloudhub.IntegrityIssue
+	err := s.client.kv.Update(ctx, func(tx Tx) error {
+		scan, err := s.scan(ctx, tx)
+		if err != nil {
+			return err
+		}
+		for _, fix := range scan.fixes {
+			if fix == nil {
+				continue
+			}
+			if err := fix(tx); err != nil {
+				return err
+			}
+		}
+		issues = scan.issues
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range issues {
+		issues[i].Repaired = true
+	}
+	return issues, nil
+}
+
+func (s *integrityChecker) scan(ctx context.Context, tx Tx) (*integrityScan, error) {
+	scan := &integrityScan{
+		ctx:       ctx,
+		trash:     &trashStore{client: s.client},
+		orgs:      map[string]bool{},
+		srcs:      map[string]cloudhub.Source{},
+		deviceIDs: map[string]bool{},
+		deviceIPs: map[string]bool{},
+	}
+
+	if err := scan.index(tx); err != nil {
+		return nil, err
+	}
+
+	checks := []func(Tx) error{
+		scan.users,
+		scan.sources,
+		scan.servers,
+		scan.mappings,
+		scan.organizationConfigs,
+		scan.dashboards,
+		scan.topologies,
+		scan.csps,
+		scan.vspheres,
+		scan.networkDevices,
+		scan.networkDeviceOrgs,
+		scan.mlNxRsts,
+	}
+	for _, check := range checks {
+		if err := check(tx); err != nil {
+			return nil, err
+		}
+	}
+	return scan, nil
+}
+
+// index loads the IDs that other resources refer to
+func (c *integrityScan) index(tx Tx) error {
+	if err := tx.Bucket(organizationsBucket).ForEach(func(k, v []byte) error {
+		var org cloudhub.Organization
+		if err := internal.UnmarshalOrganization(v, &org); err != nil {
+			return err
+		}
+		c.orgs[org.ID] = true
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(sourcesBucket).ForEach(func(k, v []byte) error {
+		var src cloudhub.Source
+		if err := internal.UnmarshalSource(v, &src); err != nil {
+			return err
+		}
+		c.srcs[strconv.Itoa(src.ID)] = src
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	return tx.Bucket(networkDeviceBucket).ForEach(func(k, v []byte) error {
+		var device cloudhub.NetworkDevice
+		if err := internal.UnmarshalNetworkDevice(v, &device); err != nil {
+			return err
+		}
+		c.deviceIDs[device.ID] = true
+		c.deviceIPs[device.DeviceIP] = true
+		return nil
+	})
+}
+
+func (c *integrityScan) report(issue cloudhub.IntegrityIssue, fix func(Tx) error) {
+	c.issues = append(c.issues, issue)
+	c.fixes = append(c.fixes, fix)
+}
+
+// orphan reports a resource whose organization does not exist.
+// The fix removes the resource under key from bucket.
+func (c *integrityScan) orphan(resourceType, id, org string, bucket, key []byte) {
+	c.report(cloudhub.IntegrityIssue{
+		Severity:     cloudhub.IntegritySeverityWarning,
+		ResourceType: resourceType,
+		ResourceID:   id,
+		Field:        "organization",
+		Reference:    org,
+		Organization: org,
+		Message:      fmt.Sprintf("%s %s belongs to organization %s which does not exist", resourceType, id, org),
+		Repair:       "remove",
+	}, func(tx Tx) error {
+		return tx.Bucket(bucket).Delete(key)
+	})
+}
+
+// trashOrphan is orphan for the resources that are moved to the trash on delete
+func (c *integrityScan) trashOrphan(resourceType, trashType, id, org, name string, bucket, key, v []byte) {
+	c.report(cloudhub.IntegrityIssue{
+		Severity:     cloudhub.IntegritySeverityWarning,
+		ResourceType: resourceType,
+		ResourceID:   id,
+		Field:        "organization",
+		Reference:    org,
+		Organization: org,
+		Message:      fmt.Sprintf("%s %s belongs to organization %s which does not exist", resourceType, id, org),
+		Repair:       "move to trash",
+	}, func(tx Tx) error {
+		if err := tx.Bucket(bucket).Delete(key); err != nil {
+			return err
+		}
+		if err := reindex(tx, bucket, string(key), v, nil); err != nil {
+			return err
+		}
+		return c.trash.trash(c.ctx, tx, trashType, id, org, name, v)
+	})
+}
+
+// defaultSource returns the source that the resources of org referring to a
+// missing source are re-linked to: the default source, otherwise the first one.
+func (c *integrityScan) defaultSource(org string) (cloudhub.Source, bool) {
+	var ids []int
+	for _, src := range c.srcs {
+		if src.Organization != org {
+			continue
+		}
+		if src.Default {
+			return src, true
+		}
+		ids = append(ids, src.ID)
+	}
+	if len(ids) == 0 {
+		return cloudhub.Source{}, false
+	}
+	sort.Ints(ids)
+	return c.srcs[strconv.Itoa(ids[0])], true
+}
+
+func (c *integrityScan) users(tx Tx) error {
+	return tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+		var user cloudhub.User
+		if err := internal.UnmarshalUser(v, &user); err != nil {
+			return err
+		}
+
+		roles := []cloudhub.Role{}
+		for _, role := range user.Roles {
+			if c.orgs[role.Organization] {
+				roles = append(roles, role)
+			}
+		}
+		if len(roles) == len(user.Roles) {
+			return nil
+		}
+
+		key := u64tob(user.ID)
+		fixed := user
+		fixed.Roles = roles
+		fix := func(tx Tx) error {
+			v, err := internal.MarshalUser(&fixed)
+			if err != nil {
+				return err
+			}
+			return tx.Bucket(usersBucket).Put(key, v)
+		}
+
+		for _, role := range user.Roles {
+			if c.orgs[role.Organization] {
+				continue
+			}
+			c.report(cloudhub.IntegrityIssue{
+				Severity:     cloudhub.IntegritySeverityError,
+				ResourceType: integrityUser,
+				ResourceID:   strconv.FormatUint(user.ID, 10),
+				Field:        "roles.organization",
+				Reference:    role.Organization,
+				Message:      fmt.Sprintf("user %s has a %s role in organization %s which does not exist", user.Name, role.Name, role.Organization),
+				Repair:       "remove role",
+			}, fix)
+			fix = nil
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) sources(tx Tx) error {
+	return tx.Bucket(sourcesBucket).ForEach(func(k, v []byte) error {
+		var src cloudhub.Source
+		if err := internal.UnmarshalSource(v, &src); err != nil {
+			return err
+		}
+		if !c.orgs[src.Organization] {
+			c.orphan(integritySource, strconv.Itoa(src.ID), src.Organization, sourcesBucket, itob(src.ID))
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) servers(tx Tx) error {
+	return tx.Bucket(serversBucket).ForEach(func(k, v []byte) error {
+		var server cloudhub.Server
+		if err := internal.UnmarshalServer(v, &server); err != nil {
+			return err
+		}
+
+		id := strconv.Itoa(server.ID)
+		key := itob(server.ID)
+		if !c.orgs[server.Organization] {
+			c.orphan(integrityServer, id, server.Organization, serversBucket, key)
+			return nil
+		}
+		srcID := strconv.Itoa(server.SrcID)
+		if _, ok := c.srcs[srcID]; ok {
+			return nil
+		}
+
+		issue := cloudhub.IntegrityIssue{
+			Severity:     cloudhub.IntegritySeverityError,
+			ResourceType: integrityServer,
+			ResourceID:   id,
+			Field:        "srcId",
+			Reference:    srcID,
+			Organization: server.Organization,
+			Message:      fmt.Sprintf("server %s refers to source %s which does not exist", server.Name, srcID),
+		}
+		src, ok := c.defaultSource(server.Organization)
+		if !ok {
+			issue.Repair = "remove"
+			c.report(issue, func(tx Tx) error {
+				return tx.Bucket(serversBucket).Delete(key)
+			})
+			return nil
+		}
+
+		issue.Repair = fmt.Sprintf("re-link to source %d", src.ID)
+		server.SrcID = src.ID
+		c.report(issue, func(tx Tx) error {
+			v, err := internal.MarshalServer(server)
+			if err != nil {
+				return err
+			}
+			return tx.Bucket(serversBucket).Put(key, v)
+		})
+		return nil
+	})
+}
+
+func (c *integrityScan) mappings(tx Tx) error {
+	return tx.Bucket(mappingsBucket).ForEach(func(k, v []byte) error {
+		var mapping cloudhub.Mapping
+		if err := internal.UnmarshalMapping(v, &mapping); err != nil {
+			return err
+		}
+		if !c.orgs[mapping.Organization] {
+			c.orphan(integrityMapping, mapping.ID, mapping.Organization, mappingsBucket, []byte(mapping.ID))
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) organizationConfigs(tx Tx) error {
+	return tx.Bucket(organizationConfigBucket).ForEach(func(k, v []byte) error {
+		var cfg cloudhub.OrganizationConfig
+		if err := internal.UnmarshalOrganizationConfig(v, &cfg); err != nil {
+			return err
+		}
+		if !c.orgs[cfg.OrganizationID] {
+			c.orphan(integrityOrganizationConfig, cfg.OrganizationID, cfg.OrganizationID, organizationConfigBucket, []byte(cfg.OrganizationID))
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) dashboards(tx Tx) error {
+	return tx.Bucket(dashboardsBucket).ForEach(func(k, v []byte) error {
+		var dash cloudhub.Dashboard
+		if err := internal.UnmarshalDashboard(v, &dash); err != nil {
+			return err
+		}
+		if !c.orgs[dash.Organization] {
+			id := strconv.Itoa(int(dash.ID))
+			c.trashOrphan(integrityDashboard, cloudhub.TrashDashboard, id, dash.Organization, dash.Name, dashboardsBucket, []byte(id), v)
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) topologies(tx Tx) error {
+	return tx.Bucket(topologyBucket).ForEach(func(k, v []byte) error {
+		var tp cloudhub.Topology
+		if err := internal.UnmarshalTopology(v, &tp); err != nil {
+			return err
+		}
+		if !c.orgs[tp.Organization] {
+			c.trashOrphan(integrityTopology, cloudhub.TrashTopology, tp.ID, tp.Organization, "", topologyBucket, []byte(tp.ID), v)
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) csps(tx Tx) error {
+	return tx.Bucket(cspBucket).ForEach(func(k, v []byte) error {
+		var csp cloudhub.CSP
+		if err := internal.UnmarshalCSP(v, &csp); err != nil {
+			return err
+		}
+		if !c.orgs[csp.Organization] {
+			name := csp.Provider + "/" + csp.NameSpace
+			c.trashOrphan(integrityCSP, cloudhub.TrashCSP, csp.ID, csp.Organization, name, cspBucket, []byte(csp.ID), v)
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) vspheres(tx Tx) error {
+	return tx.Bucket(vSpheresBucket).ForEach(func(k, v []byte) error {
+		var vs cloudhub.Vsphere
+		if err := internal.UnmarshalVsphere(v, &vs); err != nil {
+			return err
+		}
+
+		key := []byte(vs.ID)
+		if !c.orgs[vs.Organization] {
+			c.orphan(integrityVsphere, vs.ID, vs.Organization, vSpheresBucket, key)
+			return nil
+		}
+		if _, ok := c.srcs[vs.DataSource]; ok {
+			return nil
+		}
+
+		issue := cloudhub.IntegrityIssue{
+			Severity:     cloudhub.IntegritySeverityError,
+			ResourceType: integrityVsphere,
+			ResourceID:   vs.ID,
+			Field:        "datasource",
+			Reference:    vs.DataSource,
+			Organization: vs.Organization,
+			Message:      fmt.Sprintf("vsphere %s refers to source %s which does not exist", vs.Host, vs.DataSource),
+		}
+		src, ok := c.defaultSource(vs.Organization)
+		if !ok {
+			issue.Repair = "remove"
+			c.report(issue, func(tx Tx) error {
+				return tx.Bucket(vSpheresBucket).Delete(key)
+			})
+			return nil
+		}
+
+		issue.Repair = fmt.Sprintf("re-link to source %d", src.ID)
+		vs.DataSource = strconv.Itoa(src.ID)
+		c.report(issue, func(tx Tx) error {
+			v, err := internal.MarshalVsphere(vs)
+			if err != nil {
+				return err
+			}
+			return tx.Bucket(vSpheresBucket).Put(key, v)
+		})
+		return nil
+	})
+}
+
+func (c *integrityScan) networkDevices(tx Tx) error {
+	return tx.Bucket(networkDeviceBucket).ForEach(func(k, v []byte) error {
+		var device cloudhub.NetworkDevice
+		if err := internal.UnmarshalNetworkDevice(v, &device); err != nil {
+			return err
+		}
+		if !c.orgs[device.Organization] {
+			c.trashOrphan(integrityNetworkDevice, cloudhub.TrashNetworkDevice, device.ID, device.Organization, device.DeviceIP, networkDeviceBucket, []byte(device.ID), v)
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) networkDeviceOrgs(tx Tx) error {
+	return tx.Bucket(networkDeviceOrgBucket).ForEach(func(k, v []byte) error {
+		var org cloudhub.NetworkDeviceOrg
+		if err := internal.UnmarshalNetworkDeviceOrg(v, &org); err != nil {
+			return err
+		}
+
+		key := []byte(org.ID)
+		if !c.orgs[org.ID] {
+			c.orphan(integrityNetworkDeviceOrg, org.ID, org.ID, networkDeviceOrgBucket, key)
+			return nil
+		}
+
+		fixed := org
+		fixed.CollectedDevicesIDs = c.existingDevices(org.CollectedDevicesIDs)
+		fixed.LearnedDevicesIDs = c.existingDevices(org.LearnedDevicesIDs)
+		fixed.Version = org.Version + 1
+		fix := func(tx Tx) error {
+			v, err := internal.MarshalNetworkDeviceOrg(&fixed)
+			if err != nil {
+				return err
+			}
+			return tx.Bucket(networkDeviceOrgBucket).Put(key, v)
+		}
+
+		fields := []struct {
+			name string
+			ids  []string
+		}{
+			{"collected_devices_ids", org.CollectedDevicesIDs},
+			{"learned_devices_ids", org.LearnedDevicesIDs},
+		}
+		for _, field := range fields {
+			for _, id := range field.ids {
+				if c.deviceIDs[id] {
+					continue
+				}
+				c.report(cloudhub.IntegrityIssue{
+					Severity:     cloudhub.IntegritySeverityError,
+					ResourceType: integrityNetworkDeviceOrg,
+					ResourceID:   org.ID,
+					Field:        field.name,
+					Reference:    id,
+					Organization: org.ID,
+					Message:      fmt.Sprintf("network device org %s refers to device %s which does not exist", org.ID, id),
+					Repair:       "remove reference",
+				}, fix)
+				fix = nil
+			}
+		}
+		return nil
+	})
+}
+
+func (c *integrityScan) existingDevices(ids []string) []string {
+	existing := []string{}
+	for _, id := range ids {
+		if c.deviceIDs[id] {
+			existing = append(existing, id)
+		}
+	}
+	return existing
+}
+
+func (c *integrityScan) mlNxRsts(tx Tx) error {
+	return tx.Bucket(mlNxRstBucket).ForEach(func(k, v []byte) error {
+		var rst cloudhub.MLNxRst
+		if err := internal.UnmarshalMLNxRst(v, &rst); err != nil {
+			return err
+		}
+		if c.deviceIPs[rst.Device] || c.deviceIDs[rst.Device] {
+			return nil
+		}
+
+		key := []byte(rst.Device)
+		c.report(cloudhub.IntegrityIssue{
+			Severity:     cloudhub.IntegritySeverityError,
+			ResourceType: integrityMLNxRst,
+			ResourceID:   rst.Device,
+			Field:        "device_ip",
+			Reference:    rst.Device,
+			Message:      fmt.Sprintf("ML result refers to device %s which does not exist", rst.Device),
+			Repair:       "remove",
+		}, func(tx Tx) error {
+			return tx.Bucket(mlNxRstBucket).Delete(key)
+		})
+		return nil
+	})
+}
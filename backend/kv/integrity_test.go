@@ -0,0 +1,113 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestIntegrityChecker(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+
+	src, err := client.SourcesStore().Add(ctx, cloudhub.Source{Name: "influx", Organization: "default", Default: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := client.UsersStore().Add(ctx, &cloudhub.User{
+		Name: "admin",
+		Roles: []cloudhub.Role{
+			{Name: "admin", Organization: "default"},
+			{Name: "viewer", Organization: "gone"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, err := client.ServersStore().Add(ctx, cloudhub.Server{Name: "kapa", SrcID: src.ID + 100, Organization: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := client.DashboardsStore().Add(ctx, cloudhub.Dashboard{Name: "cpu", Organization: "gone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	device, err := client.NetworkDeviceStore().Add(ctx, &cloudhub.NetworkDevice{DeviceIP: "10.0.0.1", Organization: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NetworkDeviceOrgStore().Add(ctx, &cloudhub.NetworkDeviceOrg{
+		ID:                  "default",
+		CollectedDevicesIDs: []string{device.ID, "404"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.MLNxRstStore().Add(ctx, &cloudhub.MLNxRst{Device: "10.0.0.2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	checker := client.IntegrityChecker()
+	issues, err := checker.Check(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"user":               cloudhub.IntegritySeverityError,
+		"server":             cloudhub.IntegritySeverityError,
+		"dashboard":          cloudhub.IntegritySeverityWarning,
+		"network_device_org": cloudhub.IntegritySeverityError,
+		"ml_nx_rst":          cloudhub.IntegritySeverityError,
+	}
+	if len(issues) != len(want) {
+		t.Fatalf("Check() returned %d issues, want %d: %#v", len(issues), len(want), issues)
+	}
+	for _, issue := range issues {
+		if want[issue.ResourceType] != issue.Severity {
+			t.Errorf("Check() issue %#v, want severity %q", issue, want[issue.ResourceType])
+		}
+		if issue.Repaired {
+			t.Errorf("Check() must not repair %#v", issue)
+		}
+	}
+
+	if _, err := checker.Repair(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if issues, err := checker.Check(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(issues) != 0 {
+		t.Fatalf("Check() after Repair() returned %#v", issues)
+	}
+
+	if u, err := client.UsersStore().Get(ctx, cloudhub.UserQuery{ID: &user.ID}); err != nil {
+		t.Fatal(err)
+	} else if len(u.Roles) != 1 || u.Roles[0].Organization != "default" {
+		t.Errorf("expected the dangling role to be removed, got %#v", u.Roles)
+	}
+	if s, err := client.ServersStore().Get(ctx, srv.ID); err != nil {
+		t.Fatal(err)
+	} else if s.SrcID != src.ID {
+		t.Errorf("expected server to be re-linked to source %d, got %d", src.ID, s.SrcID)
+	}
+	if _, err := client.DashboardsStore().Get(ctx, d.ID); err != cloudhub.ErrDashboardNotFound {
+		t.Errorf("expected orphaned dashboard to be removed, got %v", err)
+	}
+	if items, err := client.TrashStore().All(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(items) != 1 || items[0].ResourceType != cloudhub.TrashDashboard {
+		t.Errorf("expected orphaned dashboard in the trash, got %#v", items)
+	}
+	id := "default"
+	if org, err := client.NetworkDeviceOrgStore().Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &id}); err != nil {
+		t.Fatal(err)
+	} else if len(org.CollectedDevicesIDs) != 1 || org.CollectedDevicesIDs[0] != device.ID {
+		t.Errorf("expected dangling device ID to be removed, got %v", org.CollectedDevicesIDs)
+	}
+}
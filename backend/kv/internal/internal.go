@@ -3,6 +3,8 @@ package internal
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"time"
 
 	"github.com/gogo/protobuf/proto"
 	cloudhub "github.com/snetsystems/cloudhub/backend"
@@ -449,6 +451,10 @@ func MarshalDashboard(d cloudhub.Dashboard) ([]byte, error) {
 		Templates:    templates,
 		Name:         d.Name,
 		Organization: d.Organization,
+		Version:      int64(d.Version),
+		Folder:       d.Folder,
+		Tags:         d.Tags,
+		Protoboard:   d.Protoboard,
 	})
 }
 
@@ -658,6 +664,10 @@ func UnmarshalDashboard(data []byte, d *cloudhub.Dashboard) error {
 	d.Templates = templates
 	d.Name = pb.Name
 	d.Organization = pb.Organization
+	d.Version = uint64(pb.Version)
+	d.Folder = pb.Folder
+	d.Tags = pb.Tags
+	d.Protoboard = pb.Protoboard
 	return nil
 }
 
@@ -801,11 +811,44 @@ func UnmarshalRolePB(data []byte, r *Role) error {
 // MarshalOrganization encodes a organization to binary protobuf format.
 func MarshalOrganization(o *cloudhub.Organization) ([]byte, error) {
 
-	return MarshalOrganizationPB(&Organization{
+	pb := &Organization{
 		ID:          o.ID,
 		Name:        o.Name,
 		DefaultRole: o.DefaultRole,
-	})
+		Quotas:      marshalOrganizationQuotas(o.Quotas),
+	}
+
+	return MarshalOrganizationPB(pb)
+}
+
+func marshalOrganizationQuotas(q *cloudhub.OrganizationQuotas) *OrganizationQuotas {
+	if q == nil {
+		return nil
+	}
+	return &OrganizationQuotas{
+		Dashboards:        int64(q.Dashboards),
+		CellsPerDashboard: int64(q.CellsPerDashboard),
+		Sources:           int64(q.Sources),
+		NetworkDevices:    int64(q.NetworkDevices),
+		LearnedDevices:    int64(q.LearnedDevices),
+		Topologies:        int64(q.Topologies),
+		TerminalSessions:  int64(q.TerminalSessions),
+	}
+}
+
+func unmarshalOrganizationQuotas(q *OrganizationQuotas) *cloudhub.OrganizationQuotas {
+	if q == nil {
+		return nil
+	}
+	return &cloudhub.OrganizationQuotas{
+		Dashboards:        int(q.Dashboards),
+		CellsPerDashboard: int(q.CellsPerDashboard),
+		Sources:           int(q.Sources),
+		NetworkDevices:    int(q.NetworkDevices),
+		LearnedDevices:    int(q.LearnedDevices),
+		Topologies:        int(q.Topologies),
+		TerminalSessions:  int(q.TerminalSessions),
+	}
 }
 
 // MarshalOrganizationPB encodes a organization to binary protobuf format.
@@ -822,6 +865,7 @@ func UnmarshalOrganization(data []byte, o *cloudhub.Organization) error {
 	o.ID = pb.ID
 	o.Name = pb.Name
 	o.DefaultRole = pb.DefaultRole
+	o.Quotas = unmarshalOrganizationQuotas(pb.Quotas)
 
 	return nil
 }
@@ -1060,6 +1104,7 @@ func MarshalTopology(t *cloudhub.Topology) ([]byte, error) {
 			IpmiVisible:       t.TopologyOptions.IPMIVisible,
 			LinkVisible:       t.TopologyOptions.LinkVisible,
 		},
+		Version: int64(t.Version),
 	})
 }
 
@@ -1074,6 +1119,7 @@ func UnmarshalTopology(data []byte, t *cloudhub.Topology) error {
 	t.Organization = pb.Organization
 	t.Diagram = pb.Diagram
 	t.Preferences = pb.Preferences
+	t.Version = uint64(pb.Version)
 
 	if pb.TopologyOptions != nil {
 		t.TopologyOptions = cloudhub.TopologyOptions{
@@ -1239,6 +1285,7 @@ func MarshalNetworkDeviceOrg(t *cloudhub.NetworkDeviceOrg) ([]byte, error) {
 		},
 		LearningCron: t.LearningCron,
 		ProcCnt:      int32(t.ProcCnt),
+		Version:      int64(t.Version),
 	})
 }
 
@@ -1269,6 +1316,7 @@ func UnmarshalNetworkDeviceOrg(data []byte, t *cloudhub.NetworkDeviceOrg) error
 	}
 	t.LearningCron = pb.LearningCron
 	t.ProcCnt = int(pb.ProcCnt)
+	t.Version = uint64(pb.Version)
 	return nil
 }
 
@@ -1368,3 +1416,524 @@ func UnmarshalDLNxRstStg(data []byte, t *cloudhub.DLNxRstStg) error {
 
 	return nil
 }
+
+// MarshalRevision encodes a Revision struct to binary protobuf format.
+func MarshalRevision(r *cloudhub.Revision) ([]byte, error) {
+	return proto.Marshal(&Revision{
+		ID:           r.ID,
+		ResourceType: r.ResourceType,
+		ResourceID:   r.ResourceID,
+		Organization: r.Organization,
+		Author:       r.Author,
+		Message:      r.Message,
+		CreatedAt:    r.CreatedAt.UnixNano(),
+		Content:      r.Content,
+	})
+}
+
+// UnmarshalRevision decodes a Revision from binary protobuf data.
+func UnmarshalRevision(data []byte, r *cloudhub.Revision) error {
+	var pb Revision
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	r.ID = pb.ID
+	r.ResourceType = pb.ResourceType
+	r.ResourceID = pb.ResourceID
+	r.Organization = pb.Organization
+	r.Author = pb.Author
+	r.Message = pb.Message
+	r.CreatedAt = time.Unix(0, pb.CreatedAt).UTC()
+	r.Content = pb.Content
+
+	return nil
+}
+
+// MarshalTrashItem encodes a TrashItem to binary protobuf format.
+func MarshalTrashItem(t *cloudhub.TrashItem) ([]byte, error) {
+	return proto.Marshal(&TrashItem{
+		ID:           t.ID,
+		ResourceType: t.ResourceType,
+		ResourceID:   t.ResourceID,
+		Organization: t.Organization,
+		Name:         t.Name,
+		DeletedBy:    t.DeletedBy,
+		DeletedAt:    t.DeletedAt.UnixNano(),
+		Content:      t.Content,
+	})
+}
+
+// UnmarshalTrashItem decodes a TrashItem from binary protobuf data.
+func UnmarshalTrashItem(data []byte, t *cloudhub.TrashItem) error {
+	var pb TrashItem
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	t.ID = pb.ID
+	t.ResourceType = pb.ResourceType
+	t.ResourceID = pb.ResourceID
+	t.Organization = pb.Organization
+	t.Name = pb.Name
+	t.DeletedBy = pb.DeletedBy
+	t.DeletedAt = time.Unix(0, pb.DeletedAt).UTC()
+	t.Content = pb.Content
+
+	return nil
+}
+
+// MarshalJob encodes a job to binary protobuf format.
+func MarshalJob(j *cloudhub.Job) ([]byte, error) {
+	steps := make([]*JobStep, len(j.Steps))
+	for i, step := range j.Steps {
+		steps[i] = &JobStep{
+			ID:          int64(step.ID),
+			Kind:        step.Kind,
+			Resource:    step.Resource,
+			Target:      step.Target,
+			Description: step.Description,
+			Status:      step.Status,
+			Error:       step.Error,
+			Attempts:    int64(step.Attempts),
+		}
+	}
+
+	return proto.Marshal(&Job{
+		ID:           j.ID,
+		Type:         j.Type,
+		Organization: j.Organization,
+		Name:         j.Name,
+		Status:       j.Status,
+		CreatedBy:    j.CreatedBy,
+		CreatedAt:    j.CreatedAt.UnixNano(),
+		UpdatedAt:    j.UpdatedAt.UnixNano(),
+		Steps:        steps,
+	})
+}
+
+// UnmarshalJob decodes a job from binary protobuf data.
+func UnmarshalJob(data []byte, j *cloudhub.Job) error {
+	var pb Job
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	j.ID = pb.ID
+	j.Type = pb.Type
+	j.Organization = pb.Organization
+	j.Name = pb.Name
+	j.Status = pb.Status
+	j.CreatedBy = pb.CreatedBy
+	j.CreatedAt = time.Unix(0, pb.CreatedAt).UTC()
+	j.UpdatedAt = time.Unix(0, pb.UpdatedAt).UTC()
+	j.Steps = make([]cloudhub.JobStep, len(pb.Steps))
+	for i, step := range pb.Steps {
+		j.Steps[i] = cloudhub.JobStep{
+			ID:          int(step.ID),
+			Kind:        step.Kind,
+			Resource:    step.Resource,
+			Target:      step.Target,
+			Description: step.Description,
+			Status:      step.Status,
+			Error:       step.Error,
+			Attempts:    int(step.Attempts),
+		}
+	}
+
+	return nil
+}
+
+// MarshalOrgTemplate encodes an organization template to binary protobuf format.
+// The resources of the template are encoded like in their own buckets.
+func MarshalOrgTemplate(t *cloudhub.OrgTemplate) ([]byte, error) {
+	pb := &OrgTemplate{
+		ID:           t.ID,
+		Name:         t.Name,
+		Organization: t.Organization,
+		DefaultRole:  t.DefaultRole,
+		Quotas:       marshalOrganizationQuotas(t.Quotas),
+		CreatedBy:    t.CreatedBy,
+		CreatedAt:    t.CreatedAt.UnixNano(),
+	}
+
+	for _, src := range t.Sources {
+		v, err := MarshalSource(src)
+		if err != nil {
+			return nil, err
+		}
+		pb.Sources = append(pb.Sources, v)
+	}
+	for _, d := range t.Dashboards {
+		v, err := MarshalDashboard(d)
+		if err != nil {
+			return nil, err
+		}
+		pb.Dashboards = append(pb.Dashboards, v)
+	}
+	for i := range t.Topologies {
+		v, err := MarshalTopology(&t.Topologies[i])
+		if err != nil {
+			return nil, err
+		}
+		pb.Topologies = append(pb.Topologies, v)
+	}
+
+	v, err := MarshalOrganizationConfig(&cloudhub.OrganizationConfig{
+		OrganizationID: t.Organization,
+		LogViewer:      t.LogViewer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pb.LogViewer = v
+
+	if t.NetworkDeviceOrg != nil {
+		v, err := MarshalNetworkDeviceOrg(t.NetworkDeviceOrg)
+		if err != nil {
+			return nil, err
+		}
+		pb.NetworkDeviceOrg = v
+	}
+
+	return proto.Marshal(pb)
+}
+
+// UnmarshalOrgTemplate decodes an organization template from binary protobuf data.
+func UnmarshalOrgTemplate(data []byte, t *cloudhub.OrgTemplate) error {
+	var pb OrgTemplate
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	t.ID = pb.ID
+	t.Name = pb.Name
+	t.Organization = pb.Organization
+	t.DefaultRole = pb.DefaultRole
+	t.Quotas = unmarshalOrganizationQuotas(pb.Quotas)
+	t.CreatedBy = pb.CreatedBy
+	t.CreatedAt = time.Unix(0, pb.CreatedAt).UTC()
+
+	t.Sources = make([]cloudhub.Source, len(pb.Sources))
+	for i, v := range pb.Sources {
+		if err := UnmarshalSource(v, &t.Sources[i]); err != nil {
+			return err
+		}
+	}
+	t.Dashboards = make([]cloudhub.Dashboard, len(pb.Dashboards))
+	for i, v := range pb.Dashboards {
+		if err := UnmarshalDashboard(v, &t.Dashboards[i]); err != nil {
+			return err
+		}
+	}
+	t.Topologies = make([]cloudhub.Topology, len(pb.Topologies))
+	for i, v := range pb.Topologies {
+		if err := UnmarshalTopology(v, &t.Topologies[i]); err != nil {
+			return err
+		}
+	}
+
+	var config cloudhub.OrganizationConfig
+	if err := UnmarshalOrganizationConfig(pb.LogViewer, &config); err != nil {
+		return err
+	}
+	t.LogViewer = config.LogViewer
+
+	t.NetworkDeviceOrg = nil
+	if len(pb.NetworkDeviceOrg) > 0 {
+		var deviceOrg cloudhub.NetworkDeviceOrg
+		if err := UnmarshalNetworkDeviceOrg(pb.NetworkDeviceOrg, &deviceOrg); err != nil {
+			return err
+		}
+		t.NetworkDeviceOrg = &deviceOrg
+	}
+
+	return nil
+}
+
+// MarshalProtoboard encodes a protoboard to binary protobuf format.
+// The cells and templates of the protoboard are encoded as JSON.
+func MarshalProtoboard(p *cloudhub.Protoboard) ([]byte, error) {
+	data, err := json.Marshal(p.Data)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&Protoboard{
+		ID:               p.ID,
+		Name:             p.Meta.Name,
+		Icon:             p.Meta.Icon,
+		Version:          p.Meta.Version,
+		Measurements:     p.Meta.Measurements,
+		DashboardVersion: p.Meta.DashboardVersion,
+		Description:      p.Meta.Description,
+		Author:           p.Meta.Author,
+		License:          p.Meta.License,
+		URL:              p.Meta.URL,
+		DataJSON:         string(data),
+	})
+}
+
+// UnmarshalProtoboard decodes a protoboard from binary protobuf data.
+func UnmarshalProtoboard(data []byte, p *cloudhub.Protoboard) error {
+	var pb Protoboard
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	p.ID = pb.ID
+	p.Meta = cloudhub.ProtoboardMeta{
+		Name:             pb.Name,
+		Icon:             pb.Icon,
+		Version:          pb.Version,
+		Measurements:     pb.Measurements,
+		DashboardVersion: pb.DashboardVersion,
+		Description:      pb.Description,
+		Author:           pb.Author,
+		License:          pb.License,
+		URL:              pb.URL,
+	}
+	if p.Meta.Measurements == nil {
+		p.Meta.Measurements = []string{}
+	}
+	p.Data = cloudhub.ProtoboardData{}
+	return json.Unmarshal([]byte(pb.DataJSON), &p.Data)
+}
+
+// MarshalReport encodes a report to binary protobuf format.
+func MarshalReport(r *cloudhub.Report) ([]byte, error) {
+	vars := make([]*ReportVariable, 0, len(r.Variables))
+	for v, value := range r.Variables {
+		vars = append(vars, &ReportVariable{Var: v, Value: value})
+	}
+	runs := make([]*ReportRun, len(r.Runs))
+	for i, run := range r.Runs {
+		runs[i] = &ReportRun{
+			Time:    run.Time.UnixNano(),
+			Trigger: run.Trigger,
+			Status:  run.Status,
+			Error:   run.Error,
+		}
+	}
+	var next int64
+	if !r.NextRun.IsZero() {
+		next = r.NextRun.UnixNano()
+	}
+
+	return proto.Marshal(&Report{
+		ID:           r.ID,
+		Name:         r.Name,
+		Organization: r.Organization,
+		Dashboard:    int64(r.Dashboard),
+		Variables:    vars,
+		TimeRange:    r.TimeRange,
+		Schedule:     r.Schedule,
+		TimeZone:     r.TimeZone,
+		Format:       r.Format,
+		Recipients:   r.Recipients,
+		Webhook:      r.Webhook,
+		CreatedBy:    r.CreatedBy,
+		CreatedAt:    r.CreatedAt.UnixNano(),
+		NextRun:      next,
+		Runs:         runs,
+	})
+}
+
+// UnmarshalReport decodes a report from binary protobuf data.
+func UnmarshalReport(data []byte, r *cloudhub.Report) error {
+	var pb Report
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	r.ID = pb.ID
+	r.Name = pb.Name
+	r.Organization = pb.Organization
+	r.Dashboard = cloudhub.DashboardID(pb.Dashboard)
+	r.Variables = make(map[string]string, len(pb.Variables))
+	for _, v := range pb.Variables {
+		r.Variables[v.Var] = v.Value
+	}
+	r.TimeRange = pb.TimeRange
+	r.Schedule = pb.Schedule
+	r.TimeZone = pb.TimeZone
+	r.Format = pb.Format
+	r.Recipients = pb.Recipients
+	if r.Recipients == nil {
+		r.Recipients = []string{}
+	}
+	r.Webhook = pb.Webhook
+	r.CreatedBy = pb.CreatedBy
+	r.CreatedAt = time.Unix(0, pb.CreatedAt).UTC()
+	r.NextRun = time.Time{}
+	if pb.NextRun != 0 {
+		r.NextRun = time.Unix(0, pb.NextRun).UTC()
+	}
+	r.Runs = make([]cloudhub.ReportRun, len(pb.Runs))
+	for i, run := range pb.Runs {
+		r.Runs[i] = cloudhub.ReportRun{
+			Time:    time.Unix(0, run.Time).UTC(),
+			Trigger: run.Trigger,
+			Status:  run.Status,
+			Error:   run.Error,
+		}
+	}
+
+	return nil
+}
+
+// MarshalSnapshot encodes a snapshot to binary protobuf format.
+// The dashboard of the snapshot is encoded like in its own bucket.
+func MarshalSnapshot(s *cloudhub.Snapshot) ([]byte, error) {
+	dashboard, err := MarshalDashboard(s.Dashboard)
+	if err != nil {
+		return nil, err
+	}
+	cells := make([]*SnapshotCell, len(s.Cells))
+	for i, c := range s.Cells {
+		results := make([][]byte, len(c.Results))
+		for j, r := range c.Results {
+			results[j] = r
+		}
+		cells[i] = &SnapshotCell{
+			CellID:  c.CellID,
+			Results: results,
+			Error:   c.Error,
+		}
+	}
+
+	return proto.Marshal(&Snapshot{
+		ID:           s.ID,
+		Name:         s.Name,
+		Organization: s.Organization,
+		Dashboard:    dashboard,
+		Lower:        s.Lower.UnixNano(),
+		Upper:        s.Upper.UnixNano(),
+		Cells:        cells,
+		TokenHash:    s.TokenHash,
+		CreatedBy:    s.CreatedBy,
+		CreatedAt:    s.CreatedAt.UnixNano(),
+		ExpiresAt:    s.ExpiresAt.UnixNano(),
+	})
+}
+
+// UnmarshalSnapshot decodes a snapshot from binary protobuf data.
+func UnmarshalSnapshot(data []byte, s *cloudhub.Snapshot) error {
+	var pb Snapshot
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	s.ID = pb.ID
+	s.Name = pb.Name
+	s.Organization = pb.Organization
+	if err := UnmarshalDashboard(pb.Dashboard, &s.Dashboard); err != nil {
+		return err
+	}
+	s.Lower = time.Unix(0, pb.Lower).UTC()
+	s.Upper = time.Unix(0, pb.Upper).UTC()
+	s.Cells = make([]cloudhub.SnapshotCell, len(pb.Cells))
+	for i, c := range pb.Cells {
+		results := make([]json.RawMessage, len(c.Results))
+		for j, r := range c.Results {
+			results[j] = r
+		}
+		s.Cells[i] = cloudhub.SnapshotCell{
+			CellID:  c.CellID,
+			Results: results,
+			Error:   c.Error,
+		}
+	}
+	s.TokenHash = pb.TokenHash
+	s.CreatedBy = pb.CreatedBy
+	s.CreatedAt = time.Unix(0, pb.CreatedAt).UTC()
+	s.ExpiresAt = time.Unix(0, pb.ExpiresAt).UTC()
+
+	return nil
+}
+
+// snapshotCellsField is the number of the field of the results of the cells of snapshots
+const snapshotCellsField = 7
+
+// UnmarshalSnapshotMetadata decodes a snapshot from binary protobuf data without
+// the results of its cells, which are skipped undecoded.
+func UnmarshalSnapshotMetadata(data []byte, s *cloudhub.Snapshot) error {
+	meta, err := withoutField(data, snapshotCellsField)
+	if err != nil {
+		return err
+	}
+	if err := UnmarshalSnapshot(meta, s); err != nil {
+		return err
+	}
+	s.Cells = nil
+	return nil
+}
+
+// withoutField returns binary protobuf data without the values of a field
+func withoutField(data []byte, field uint64) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); {
+		key, n := proto.DecodeVarint(data[i:])
+		if n == 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		end := i + n
+		switch key & 7 {
+		case proto.WireVarint:
+			if _, n = proto.DecodeVarint(data[end:]); n == 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			end += n
+		case proto.WireFixed64:
+			end += 8
+		case proto.WireBytes:
+			l, n := proto.DecodeVarint(data[end:])
+			if n == 0 || l > uint64(len(data)) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			end += n + int(l)
+		case proto.WireFixed32:
+			end += 4
+		default:
+			return nil, fmt.Errorf("unexpected wire type %d", key&7)
+		}
+		if end > len(data) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if key>>3 != field {
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out, nil
+}
+
+// MarshalFolder encodes a folder to binary protobuf format.
+func MarshalFolder(f *cloudhub.Folder) ([]byte, error) {
+	return proto.Marshal(&Folder{
+		ID:           f.ID,
+		Name:         f.Name,
+		Parent:       f.Parent,
+		Organization: f.Organization,
+		ViewRole:     f.Permissions.View,
+		EditRole:     f.Permissions.Edit,
+	})
+}
+
+// UnmarshalFolder decodes a folder from binary protobuf data.
+func UnmarshalFolder(data []byte, f *cloudhub.Folder) error {
+	var pb Folder
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return err
+	}
+
+	f.ID = pb.ID
+	f.Name = pb.Name
+	f.Parent = pb.Parent
+	f.Organization = pb.Organization
+	f.Permissions = cloudhub.FolderPermissions{
+		View: pb.ViewRole,
+		Edit: pb.EditRole,
+	}
+
+	return nil
+}
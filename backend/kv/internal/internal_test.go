@@ -3,6 +3,7 @@ package internal_test
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	gocmp "github.com/google/go-cmp/cmp"
 	cloudhub "github.com/snetsystems/cloudhub/backend"
@@ -204,8 +205,9 @@ func Test_MarshalDashboard(t *testing.T) {
 				TimeFormat:   "",
 			},
 		},
-		Templates: []cloudhub.Template{},
-		Name:      "Dashboard",
+		Templates:  []cloudhub.Template{},
+		Name:       "Dashboard",
+		Protoboard: "system",
 	}
 
 	var actual cloudhub.Dashboard
@@ -701,3 +703,109 @@ func TestMarshalDLNxRstStg(t *testing.T) {
 		t.Fatalf("Mismatch in original and copied DLNxRstStg struct: got %#v, want %#v", vv, v)
 	}
 }
+
+func TestMarshalRevision(t *testing.T) {
+	v := cloudhub.Revision{
+		ID:           "3",
+		ResourceType: cloudhub.RevisionDashboard,
+		ResourceID:   "12",
+		Organization: "8373476",
+		Author:       "admin",
+		Message:      "rename cpu cell",
+		CreatedAt:    time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		Content:      []byte(`{"id":12,"name":"cpu"}`),
+	}
+
+	var vv cloudhub.Revision
+	if buf, err := internal.MarshalRevision(&v); err != nil {
+		t.Fatal(err)
+	} else if err := internal.UnmarshalRevision(buf, &vv); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(v, vv) {
+		t.Fatalf("source protobuf copy error: got %#v, expected %#v", vv, v)
+	}
+}
+
+func TestMarshalTrashItem(t *testing.T) {
+	v := cloudhub.TrashItem{
+		ID:           "5",
+		ResourceType: cloudhub.TrashCSP,
+		ResourceID:   "42",
+		Organization: "8373476",
+		Name:         "aws/ap-northeast-2",
+		DeletedBy:    "editor",
+		DeletedAt:    time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC),
+		Content:      []byte(`{"id":"42"}`),
+	}
+
+	var vv cloudhub.TrashItem
+	if buf, err := internal.MarshalTrashItem(&v); err != nil {
+		t.Fatal(err)
+	} else if err := internal.UnmarshalTrashItem(buf, &vv); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(v, vv) {
+		t.Fatalf("source protobuf copy error: got %#v, expected %#v", vv, v)
+	}
+}
+
+func TestMarshalJob(t *testing.T) {
+	v := cloudhub.Job{
+		ID:           "3",
+		Type:         cloudhub.JobOrganizationDeletion,
+		Organization: "8373476",
+		Name:         "snet",
+		Status:       cloudhub.JobFailed,
+		CreatedBy:    "admin",
+		CreatedAt:    time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC),
+		UpdatedAt:    time.Date(2024, 6, 3, 9, 1, 0, 0, time.UTC),
+		Steps: []cloudhub.JobStep{
+			{
+				ID:          0,
+				Kind:        "kapacitor",
+				Resource:    "task",
+				Target:      "learn-8373476",
+				Description: "Delete Kapacitor task learn-8373476",
+				Status:      cloudhub.JobFailed,
+				Error:       "connection refused",
+				Attempts:    2,
+			},
+			{
+				ID:       1,
+				Kind:     "store",
+				Resource: "topology",
+				Status:   cloudhub.JobPending,
+			},
+		},
+	}
+
+	var vv cloudhub.Job
+	if buf, err := internal.MarshalJob(&v); err != nil {
+		t.Fatal(err)
+	} else if err := internal.UnmarshalJob(buf, &vv); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(v, vv) {
+		t.Fatalf("source protobuf copy error: got %#v, expected %#v", vv, v)
+	}
+}
+
+func TestMarshalOrganization(t *testing.T) {
+	v := cloudhub.Organization{
+		ID:          "8373476",
+		Name:        "snet",
+		DefaultRole: "viewer",
+		Quotas: &cloudhub.OrganizationQuotas{
+			Dashboards:       100,
+			NetworkDevices:   500,
+			TerminalSessions: 4,
+		},
+	}
+
+	var vv cloudhub.Organization
+	if buf, err := internal.MarshalOrganization(&v); err != nil {
+		t.Fatal(err)
+	} else if err := internal.UnmarshalOrganization(buf, &vv); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(v, vv) {
+		t.Fatalf("source protobuf copy error: got %#v, expected %#v", vv, v)
+	}
+}
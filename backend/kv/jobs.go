@@ -0,0 +1,132 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure jobsStore implements cloudhub.JobsStore.
+var _ cloudhub.JobsStore = &jobsStore{}
+
+// jobsStore is the kv implementation of storing tracked jobs
+type jobsStore struct {
+	client *Service
+}
+
+// jobKey returns the key of a job. The sequence is zero padded so that
+// the jobs are iterated oldest first.
+func jobKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%020d", seq))
+}
+
+// All returns all jobs in the store, oldest first.
+func (s *jobsStore) All(ctx context.Context) ([]cloudhub.Job, error) {
+	jobs := []cloudhub.Job{}
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(k, v []byte) error {
+			var job cloudhub.Job
+			if err := internal.UnmarshalJob(v, &job); err != nil {
+				return err
+			}
+			jobs = append(jobs, job)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// Add creates a new job in the store.
+func (s *jobsStore) Add(ctx context.Context, job *cloudhub.Job) (*cloudhub.Job, error) {
+	if err := s.client.kv.Update(ctx, func(tx Tx) error {
+		b := tx.Bucket(jobsBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		job.ID = strconv.FormatUint(seq, 10)
+		now := time.Now().UTC()
+		job.CreatedAt = now
+		job.UpdatedAt = now
+
+		v, err := internal.MarshalJob(job)
+		if err != nil {
+			return err
+		}
+		return b.Put(jobKey(seq), v)
+	}); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Get returns a job if the id exists.
+func (s *jobsStore) Get(ctx context.Context, id string) (*cloudhub.Job, error) {
+	var job *cloudhub.Job
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		var err error
+		job, _, err = s.get(tx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// Update replaces the job in the store.
+func (s *jobsStore) Update(ctx context.Context, job *cloudhub.Job) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		_, key, err := s.get(tx, job.ID)
+		if err != nil {
+			return err
+		}
+
+		job.UpdatedAt = time.Now().UTC()
+		v, err := internal.MarshalJob(job)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(jobsBucket).Put(key, v)
+	})
+}
+
+// Delete removes the job from the store.
+func (s *jobsStore) Delete(ctx context.Context, job *cloudhub.Job) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		_, key, err := s.get(tx, job.ID)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(jobsBucket).Delete(key)
+	})
+}
+
+// get returns the job with the given id along with its key
+func (s *jobsStore) get(tx Tx, id string) (*cloudhub.Job, []byte, error) {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, nil, cloudhub.ErrJobNotFound
+	}
+
+	key := jobKey(seq)
+	v, err := tx.Bucket(jobsBucket).Get(key)
+	if v == nil || err != nil {
+		return nil, nil, cloudhub.ErrJobNotFound
+	}
+
+	var job cloudhub.Job
+	if err := internal.UnmarshalJob(v, &job); err != nil {
+		return nil, nil, err
+	}
+	return &job, key, nil
+}
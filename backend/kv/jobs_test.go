@@ -0,0 +1,67 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestJobsStore(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	s := client.JobsStore()
+
+	job, err := s.Add(ctx, &cloudhub.Job{
+		Type:         cloudhub.JobOrganizationDeletion,
+		Organization: "1",
+		Status:       cloudhub.JobPending,
+		Steps: []cloudhub.JobStep{
+			{ID: 0, Kind: "store", Resource: "topology", Status: cloudhub.JobPending},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.ID == "" || job.CreatedAt.IsZero() {
+		t.Fatalf("expected Add to set the ID and creation time, got %#v", job)
+	}
+
+	job.Status = cloudhub.JobSucceeded
+	job.Steps[0].Status = cloudhub.JobSucceeded
+	job.Steps[0].Attempts = 1
+	if err := s.Update(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get(ctx, job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != cloudhub.JobSucceeded || got.Steps[0].Attempts != 1 {
+		t.Errorf("unexpected job %#v", got)
+	}
+
+	if _, err := s.Add(ctx, &cloudhub.Job{Type: cloudhub.JobOrganizationDeletion}); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := s.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != job.ID {
+		t.Fatalf("expected 2 jobs oldest first, got %#v", jobs)
+	}
+
+	if err := s.Delete(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, job.ID); err != cloudhub.ErrJobNotFound {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package kv
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 	"github.com/snetsystems/cloudhub/backend/id"
@@ -29,6 +30,20 @@ var (
 	mlNxRstBucket            = []byte("MLNxRst")
 	dlNxRstBucket            = []byte("DLNxRst")
 	dLNxRstStgBucket         = []byte("DLNxRstStg")
+	revisionsBucket          = []byte("RevisionsV1")
+	trashBucket              = []byte("TrashV1")
+	jobsBucket               = []byte("JobsV1")
+	orgTemplatesBucket       = []byte("OrgTemplatesV1")
+	protoboardsBucket        = []byte("ProtoboardsV1")
+	reportsBucket            = []byte("ReportsV1")
+	snapshotsBucket          = []byte("SnapshotsV1")
+	foldersBucket            = []byte("FoldersV1")
+	migrationsBucket         = []byte("MigrationsV1")
+
+	networkDeviceOrgIndexBucket = []byte("NetworkDeviceByOrgV1")
+	networkDeviceIPIndexBucket  = []byte("NetworkDeviceByIPV1")
+	topologyOrgIndexBucket      = []byte("TopologiesByOrgV1")
+	snapshotTokenIndexBucket    = []byte("SnapshotsByTokenV1")
 )
 
 // Store is an interface for a generic key value store. It is modeled after
@@ -69,14 +84,22 @@ type Bucket interface {
 	// the error is returned to the caller. The provided function must not modify
 	// the bucket; this will result in undefined behavior.
 	ForEach(fn func(k, v []byte) error) error
+	// ForEachPrefix executes a function for each key/value pair in a bucket
+	// whose key starts with prefix, in key order.
+	ForEachPrefix(prefix []byte, fn func(k, v []byte) error) error
+	// ForEachAfter executes a function for each key/value pair in a bucket
+	// whose key sorts after key, in key order, from the first pair when key is nil.
+	ForEachAfter(key []byte, fn func(k, v []byte) error) error
 	// Exists returns a key within this bucket. Errors if key does not exist.
 	Exists(key []byte) (bool, error)
 }
 
 // Service is the struct that cloudhub services are implemented on.
 type Service struct {
-	kv  Store
-	log cloudhub.Logger
+	kv           Store
+	log          cloudhub.Logger
+	maxRevisions int
+	changes      *changeFeed
 }
 
 // Option to change behavior of Open()
@@ -90,11 +113,23 @@ func WithLogger(logger cloudhub.Logger) Option {
 	}
 }
 
+// WithMaxRevisions sets the number of revisions kept for each revisioned resource.
+// A value of zero or less keeps every revision.
+func WithMaxRevisions(max int) Option {
+	return func(s *Service) error {
+		s.maxRevisions = max
+		return nil
+	}
+}
+
 // NewService returns an instance of a Service.
 func NewService(ctx context.Context, kv Store, opts ...Option) (*Service, error) {
+	changes := &changeFeed{}
 	s := &Service{
-		log: mocks.NewLogger(),
-		kv:  kv,
+		log:          mocks.NewLogger(),
+		kv:           &changeStore{Store: kv, feed: changes},
+		maxRevisions: DefaultMaxRevisions,
+		changes:      changes,
 	}
 
 	for i := range opts {
@@ -108,6 +143,12 @@ func NewService(ctx context.Context, kv Store, opts ...Option) (*Service, error)
 	}); err != nil {
 		return nil, err
 	}
+	if err := s.buildIndexes(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.backfillVersions(ctx); err != nil {
+		return nil, err
+	}
 
 	return s, s.OrganizationsStore().CreateDefault(ctx)
 }
@@ -136,6 +177,19 @@ func (s *Service) initialize(ctx context.Context, tx Tx) error {
 		mlNxRstBucket,
 		dlNxRstBucket,
 		dLNxRstStgBucket,
+		revisionsBucket,
+		trashBucket,
+		jobsBucket,
+		orgTemplatesBucket,
+		protoboardsBucket,
+		reportsBucket,
+		snapshotsBucket,
+		foldersBucket,
+		migrationsBucket,
+		networkDeviceOrgIndexBucket,
+		networkDeviceIPIndexBucket,
+		topologyOrgIndexBucket,
+		snapshotTokenIndexBucket,
 	}
 
 	for i := range buckets {
@@ -154,6 +208,69 @@ func itob(v int) []byte {
 	return b
 }
 
+// errPageFull stops the scan of a bucket once a page is full
+var errPageFull = errors.New("page is full")
+
+// page scans the records of bucket in key order from the key after the after
+// key, passing them to add until it added limit of them, or every record when
+// limit is zero.
+func (s *Service) page(ctx context.Context, bucket, after []byte, limit int, add func(v []byte) (bool, error)) error {
+	added := 0
+	err := s.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(bucket).ForEachAfter(after, func(k, v []byte) error {
+			ok, err := add(v)
+			if err != nil || !ok {
+				return err
+			}
+			if added++; limit > 0 && added == limit {
+				return errPageFull
+			}
+			return nil
+		})
+	})
+	if err == errPageFull {
+		return nil
+	}
+	return err
+}
+
+// batchSize is the number of keys written by each transaction of the writes
+// split into batches, below the operations allowed in a transaction of etcd.
+const batchSize = 100
+
+// deleteKeys removes keys from bucket in transactions of batchSize keys
+func (s *Service) deleteKeys(ctx context.Context, bucket []byte, keys [][]byte) error {
+	for len(keys) > 0 {
+		n := len(keys)
+		if n > batchSize {
+			n = batchSize
+		}
+		if err := s.kv.Update(ctx, func(tx Tx) error {
+			b := tx.Bucket(bucket)
+			for _, k := range keys[:n] {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// checkVersion compares the version a caller read with the stored version of a resource.
+// Stored resources start at version 1, or were backfilled to it, so an expected version
+// of zero, set by callers that did not read the resource, skips the check.
+func checkVersion(expected, stored uint64) error {
+	if expected != 0 && expected != stored {
+		return cloudhub.ErrVersionConflict
+	}
+	return nil
+}
+
 // u64tob returns an 8-byte big endian representation of v.
 func u64tob(v uint64) []byte {
 	b := make([]byte, 8)
@@ -240,3 +357,53 @@ func (s *Service) DLNxRstStore() cloudhub.DLNxRstStore {
 func (s *Service) DLNxRstStgStore() cloudhub.DLNxRstStgStore {
 	return &DLNxRstStgStore{client: s}
 }
+
+// RevisionsStore returns a cloudhub.RevisionsStore.
+func (s *Service) RevisionsStore() cloudhub.RevisionsStore {
+	return &revisionsStore{client: s}
+}
+
+// TrashStore returns a cloudhub.TrashStore.
+func (s *Service) TrashStore() cloudhub.TrashStore {
+	return &trashStore{client: s}
+}
+
+// IntegrityChecker returns a cloudhub.IntegrityChecker.
+func (s *Service) IntegrityChecker() cloudhub.IntegrityChecker {
+	return &integrityChecker{client: s}
+}
+
+// JobsStore returns a cloudhub.JobsStore.
+func (s *Service) JobsStore() cloudhub.JobsStore {
+	return &jobsStore{client: s}
+}
+
+// OrgTemplatesStore returns a cloudhub.OrgTemplatesStore.
+func (s *Service) OrgTemplatesStore() cloudhub.OrgTemplatesStore {
+	return &orgTemplatesStore{client: s}
+}
+
+// ProtoboardsStore returns a cloudhub.ProtoboardsStore.
+func (s *Service) ProtoboardsStore() cloudhub.ProtoboardsStore {
+	return &protoboardsStore{client: s, IDs: &id.UUID{}}
+}
+
+// ReportsStore returns a cloudhub.ReportsStore.
+func (s *Service) ReportsStore() cloudhub.ReportsStore {
+	return &reportsStore{client: s}
+}
+
+// SnapshotsStore returns a cloudhub.SnapshotsStore.
+func (s *Service) SnapshotsStore() cloudhub.SnapshotsStore {
+	return &snapshotsStore{client: s}
+}
+
+// FoldersStore returns a cloudhub.FoldersStore.
+func (s *Service) FoldersStore() cloudhub.FoldersStore {
+	return &foldersStore{client: s}
+}
+
+// ChangeFeed returns a cloudhub.ChangeFeed of the changes committed by this service.
+func (s *Service) ChangeFeed() cloudhub.ChangeFeed {
+	return s.changes
+}
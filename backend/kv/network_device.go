@@ -49,13 +49,79 @@ func (s *NetworkDeviceStore) each(ctx context.Context, fn func(*cloudhub.Network
 	})
 }
 
-// Get returns a Device if the id exists.
+// Get returns a Device if the id or the device IP exists.
 func (s *NetworkDeviceStore) Get(ctx context.Context, q cloudhub.NetworkDeviceQuery) (*cloudhub.NetworkDevice, error) {
 	if q.ID != nil {
 		return s.get(ctx, *q.ID)
 	}
 
-	return nil, fmt.Errorf("must specify either ID in DeviceQuery")
+	if q.DeviceIP != nil {
+		var ids []string
+		if err := s.client.kv.View(ctx, func(tx Tx) error {
+			var err error
+			ids, err = networkDeviceIPIndex.ids(tx, *q.DeviceIP)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, cloudhub.ErrDeviceNotFound
+		}
+		return s.get(ctx, ids[0])
+	}
+
+	return nil, fmt.Errorf("must specify either ID or DeviceIP in DeviceQuery")
+}
+
+// Find returns the Devices matching every field set in q.
+// Devices are looked up by the device IP and organization indexes
+// instead of scanning all Devices.
+func (s *NetworkDeviceStore) Find(ctx context.Context, q cloudhub.NetworkDeviceQuery) ([]cloudhub.NetworkDevice, error) {
+	if q.ID == nil && q.DeviceIP == nil && q.Organization == nil {
+		return s.All(ctx)
+	}
+
+	devices := []cloudhub.NetworkDevice{}
+	err := s.client.kv.View(ctx, func(tx Tx) error {
+		var ids []string
+		var err error
+		switch {
+		case q.ID != nil:
+			ids = []string{*q.ID}
+		case q.DeviceIP != nil:
+			ids, err = networkDeviceIPIndex.ids(tx, *q.DeviceIP)
+		default:
+			ids, err = networkDeviceOrgIndex.ids(tx, *q.Organization)
+		}
+		if err != nil {
+			return err
+		}
+
+		b := tx.Bucket(networkDeviceBucket)
+		for _, id := range ids {
+			v, err := b.Get([]byte(id))
+			if v == nil || err != nil {
+				continue
+			}
+			var device cloudhub.NetworkDevice
+			if err := internal.UnmarshalNetworkDevice(v, &device); err != nil {
+				continue
+			}
+			if q.DeviceIP != nil && device.DeviceIP != *q.DeviceIP {
+				continue
+			}
+			if q.Organization != nil && device.Organization != *q.Organization {
+				continue
+			}
+			devices = append(devices, device)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return devices, nil
 }
 
 // Add creates a new Device in the deviceStore.
@@ -70,12 +136,14 @@ func (s *NetworkDeviceStore) Add(ctx context.Context, device *cloudhub.NetworkDe
 		strID := strconv.FormatUint(seq, 10)
 		device.ID = strID
 
-		if v, err := internal.MarshalNetworkDevice(device); err != nil {
+		v, err := internal.MarshalNetworkDevice(device)
+		if err != nil {
 			return err
-		} else if err := b.Put([]byte(strID), v); err != nil {
+		}
+		if err := b.Put([]byte(strID), v); err != nil {
 			return err
 		}
-		return nil
+		return reindex(tx, networkDeviceBucket, strID, nil, v)
 	}); err != nil {
 		return nil, err
 	}
@@ -87,15 +155,25 @@ func (s *NetworkDeviceStore) Add(ctx context.Context, device *cloudhub.NetworkDe
 func (s *NetworkDeviceStore) Delete(ctx context.Context, device *cloudhub.NetworkDevice) error {
 	if err := s.client.kv.Update(ctx, func(tx Tx) error {
 
-		_, err := s.get(ctx, device.ID)
+		prev, err := s.get(ctx, device.ID)
 		if err != nil {
 			return cloudhub.ErrDeviceNotFound
 		}
 
-		if err := tx.Bucket(networkDeviceBucket).Delete([]byte(device.ID)); err != nil {
+		b := tx.Bucket(networkDeviceBucket)
+		v, err := b.Get([]byte(device.ID))
+		if v == nil || err != nil {
+			return cloudhub.ErrDeviceNotFound
+		}
+		if err := b.Delete([]byte(device.ID)); err != nil {
 			return err
 		}
-		return nil
+		if err := reindex(tx, networkDeviceBucket, device.ID, v, nil); err != nil {
+			return err
+		}
+
+		trash := &trashStore{client: s.client}
+		return trash.trash(ctx, tx, cloudhub.TrashNetworkDevice, device.ID, prev.Organization, prev.DeviceIP, v)
 	}); err != nil {
 		return err
 	}
@@ -107,17 +185,20 @@ func (s *NetworkDeviceStore) Delete(ctx context.Context, device *cloudhub.Networ
 func (s *NetworkDeviceStore) Update(ctx context.Context, device *cloudhub.NetworkDevice) error {
 	if err := s.client.kv.Update(ctx, func(tx Tx) error {
 		// Get an existing Device with the same ID.
-		_, err := s.get(ctx, device.ID)
-		if err != nil {
+		b := tx.Bucket(networkDeviceBucket)
+		prev, err := b.Get([]byte(device.ID))
+		if prev == nil || err != nil {
 			return cloudhub.ErrDeviceNotFound
 		}
 
-		if v, err := internal.MarshalNetworkDevice(device); err != nil {
+		v, err := internal.MarshalNetworkDevice(device)
+		if err != nil {
 			return err
-		} else if err := tx.Bucket(networkDeviceBucket).Put([]byte(device.ID), v); err != nil {
+		}
+		if err := b.Put([]byte(device.ID), v); err != nil {
 			return err
 		}
-		return nil
+		return reindex(tx, networkDeviceBucket, device.ID, prev, v)
 	}); err != nil {
 		return err
 	}
@@ -159,9 +240,16 @@ func (s *NetworkDeviceStore) DeleteAll(ctx context.Context) error {
 	err = s.client.kv.Update(ctx, func(tx Tx) error {
 		bucket := tx.Bucket(networkDeviceBucket)
 		for _, k := range keys {
+			v, err := bucket.Get(k)
+			if v == nil || err != nil {
+				continue
+			}
 			if err := bucket.Delete(k); err != nil {
 				return fmt.Errorf("failed to delete key %s: %v", k, err)
 			}
+			if err := reindex(tx, networkDeviceBucket, string(k), v, nil); err != nil {
+				return err
+			}
 		}
 		return nil
 	})
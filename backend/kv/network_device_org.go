@@ -20,6 +20,7 @@ type NetworkDeviceOrgStore struct {
 func (s *NetworkDeviceOrgStore) Add(ctx context.Context, org *cloudhub.NetworkDeviceOrg) (*cloudhub.NetworkDeviceOrg, error) {
 	if err := s.client.kv.Update(ctx, func(tx Tx) error {
 		b := tx.Bucket(networkDeviceOrgBucket)
+		org.Version = 1
 
 		if v, err := internal.MarshalNetworkDeviceOrg(org); err != nil {
 			return err
@@ -61,16 +62,33 @@ func (s *NetworkDeviceOrgStore) get(ctx context.Context, id string) (*cloudhub.N
 	return &org, nil
 }
 
+// getTx reads a Network Device Org within an existing transaction.
+func (s *NetworkDeviceOrgStore) getTx(tx Tx, id string) (*cloudhub.NetworkDeviceOrg, error) {
+	v, err := tx.Bucket(networkDeviceOrgBucket).Get([]byte(id))
+	if v == nil || err != nil {
+		return nil, cloudhub.ErrDeviceOrgNotFound
+	}
+
+	var org cloudhub.NetworkDeviceOrg
+	if err := internal.UnmarshalNetworkDeviceOrg(v, &org); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
 // Delete removes the Device from the deviceStore.
 func (s *NetworkDeviceOrgStore) Delete(ctx context.Context, org *cloudhub.NetworkDeviceOrg) error {
 	if err := s.client.kv.Update(ctx, func(tx Tx) error {
-
-		_, err := s.get(ctx, org.ID)
+		b := tx.Bucket(networkDeviceOrgBucket)
+		prev, err := s.getTx(tx, org.ID)
 		if err != nil {
-			return cloudhub.ErrDeviceOrgNotFound
+			return err
+		}
+		if err := checkVersion(org.Version, prev.Version); err != nil {
+			return err
 		}
 
-		if err := tx.Bucket(networkDeviceOrgBucket).Delete([]byte(org.ID)); err != nil {
+		if err := b.Delete([]byte(org.ID)); err != nil {
 			return err
 		}
 		return nil
@@ -83,13 +101,17 @@ func (s *NetworkDeviceOrgStore) Delete(ctx context.Context, org *cloudhub.Networ
 
 // Update modifies an existing Device in the deviceStore.
 func (s *NetworkDeviceOrgStore) Update(ctx context.Context, org *cloudhub.NetworkDeviceOrg) error {
+	expected := org.Version
 	if err := s.client.kv.Update(ctx, func(tx Tx) error {
-
 		// Get an existing Device with the same ID.
-		_, err := s.get(ctx, org.ID)
+		prev, err := s.getTx(tx, org.ID)
 		if err != nil {
-			return cloudhub.ErrDeviceOrgNotFound
+			return err
+		}
+		if err := checkVersion(expected, prev.Version); err != nil {
+			return err
 		}
+		org.Version = prev.Version + 1
 
 		if v, err := internal.MarshalNetworkDeviceOrg(org); err != nil {
 			return err
@@ -98,6 +120,7 @@ func (s *NetworkDeviceOrgStore) Update(ctx context.Context, org *cloudhub.Networ
 		}
 		return nil
 	}); err != nil {
+		org.Version = expected
 		return err
 	}
 
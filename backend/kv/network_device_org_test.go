@@ -49,6 +49,7 @@ func TestNetworkDeviceOrgStore(t *testing.T) {
 		if _, err = s.Add(ctx, &org); err != nil {
 			t.Fatal(err)
 		}
+		orgs[i].Version = org.Version
 		// Check if the org in the store is the same as the original.
 		if actual, err := s.Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &org.ID}); err != nil {
 			t.Fatal(err)
@@ -3,10 +3,14 @@ package kv_test
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"reflect"
 	"testing"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv"
+	"github.com/snetsystems/cloudhub/backend/kv/bolt"
 )
 
 // Ensure a NetworkDeviceStore can store, retrieve, update, and delete Device.
@@ -123,3 +127,147 @@ func TestNetworkDeviceStore(t *testing.T) {
 		t.Fatalf("Device delete error: got %v, expected %v", err, cloudhub.ErrDeviceNotFound)
 	}
 }
+
+// Ensure a NetworkDeviceStore keeps its organization and device IP indexes
+// in step with the stored Devices.
+func TestNetworkDeviceStore_Find(t *testing.T) {
+	c, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx := context.Background()
+	s := c.NetworkDeviceStore()
+
+	a, err := s.Add(ctx, &cloudhub.NetworkDevice{Organization: "default", DeviceIP: "10.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := s.Add(ctx, &cloudhub.NetworkDevice{Organization: "default", DeviceIP: "10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Add(ctx, &cloudhub.NetworkDevice{Organization: "1", DeviceIP: "10.0.0.3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	org := "default"
+	if devices, err := s.Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &org}); err != nil {
+		t.Fatal(err)
+	} else if len(devices) != 2 {
+		t.Fatalf("Find() by organization returned %d devices, want 2", len(devices))
+	}
+
+	ip := "10.0.0.2"
+	if device, err := s.Get(ctx, cloudhub.NetworkDeviceQuery{DeviceIP: &ip}); err != nil {
+		t.Fatal(err)
+	} else if device.ID != b.ID {
+		t.Errorf("Get() by device IP returned device %s, want %s", device.ID, b.ID)
+	}
+
+	// moving a device to another organization and IP updates both indexes
+	b.Organization = "1"
+	b.DeviceIP = "10.0.0.4"
+	if err := s.Update(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, cloudhub.NetworkDeviceQuery{DeviceIP: &ip}); err != cloudhub.ErrDeviceNotFound {
+		t.Errorf("Get() by the previous device IP error = %v, want %v", err, cloudhub.ErrDeviceNotFound)
+	}
+	if devices, err := s.Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &org}); err != nil {
+		t.Fatal(err)
+	} else if len(devices) != 1 || devices[0].ID != a.ID {
+		t.Errorf("Find() by organization after Update() = %v, want device %s", devices, a.ID)
+	}
+
+	// deleting and restoring a device from the trash updates the indexes
+	if err := s.Delete(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if devices, err := s.Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &org}); err != nil {
+		t.Fatal(err)
+	} else if len(devices) != 0 {
+		t.Errorf("Find() by organization after Delete() = %v, want none", devices)
+	}
+	items, err := c.TrashStore().All(ctx)
+	if err != nil || len(items) != 1 {
+		t.Fatalf("expected the deleted device in the trash, got %v, %v", items, err)
+	}
+	if err := c.TrashStore().Restore(ctx, &items[0]); err != nil {
+		t.Fatal(err)
+	}
+	ip = "10.0.0.1"
+	if devices, err := s.Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &org, DeviceIP: &ip}); err != nil {
+		t.Fatal(err)
+	} else if len(devices) != 1 || devices[0].ID != a.ID {
+		t.Errorf("Find() after Restore() = %v, want device %s", devices, a.ID)
+	}
+}
+
+// Ensure the indexes of the NetworkDeviceStore are built once for the Devices
+// stored before them, in batches.
+func TestNetworkDeviceStore_BuildIndexes(t *testing.T) {
+	f, err := ioutil.TempFile("", "cloudhub-bolt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	ctx := context.Background()
+	b, err := bolt.NewClient(ctx, bolt.WithPath(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	c, err := kv.NewService(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// more devices than the entries written by a transaction
+	for i := 0; i < 250; i++ {
+		if _, err := c.NetworkDeviceStore().Add(ctx, &cloudhub.NetworkDevice{Organization: "default", DeviceIP: fmt.Sprintf("10.0.%d.%d", i/100, i%100)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the devices were stored before the organization index and its build
+	if err := b.Update(ctx, func(tx kv.Tx) error {
+		if err := tx.Bucket([]byte("MigrationsV1")).Delete([]byte("NetworkDeviceByOrgV1")); err != nil {
+			return err
+		}
+		idx := tx.Bucket([]byte("NetworkDeviceByOrgV1"))
+		var keys [][]byte
+		if err := idx.ForEach(func(k, v []byte) error {
+			keys = append(keys, append([]byte{}, k...))
+			return nil
+		}); err != nil {
+			return err
+		}
+		for _, k := range keys {
+			if err := idx.Delete(k); err != nil {
+				return err
+			}
+		}
+		return idx.Put([]byte("removed/1000"), []byte("1000"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	org := "default"
+	if c, err = kv.NewService(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+	if devices, err := c.NetworkDeviceStore().Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &org}); err != nil {
+		t.Fatal(err)
+	} else if len(devices) != 250 {
+		t.Errorf("Find() by organization returned %d devices, want 250", len(devices))
+	}
+	removed := "removed"
+	if devices, err := c.NetworkDeviceStore().Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &removed}); err != nil {
+		t.Fatal(err)
+	} else if len(devices) != 0 {
+		t.Errorf("Find() by a stale index entry returned %v, want none", devices)
+	}
+}
@@ -0,0 +1,113 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure orgTemplatesStore implements cloudhub.OrgTemplatesStore.
+var _ cloudhub.OrgTemplatesStore = &orgTemplatesStore{}
+
+// orgTemplatesStore is the kv implementation of storing organization templates
+type orgTemplatesStore struct {
+	client *Service
+}
+
+// orgTemplateKey returns the key of a template. The sequence is zero padded so that
+// the templates are iterated oldest first.
+func orgTemplateKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%020d", seq))
+}
+
+// All returns all templates in the store, oldest first.
+func (s *orgTemplatesStore) All(ctx context.Context) ([]cloudhub.OrgTemplate, error) {
+	templates := []cloudhub.OrgTemplate{}
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(orgTemplatesBucket).ForEach(func(k, v []byte) error {
+			var t cloudhub.OrgTemplate
+			if err := internal.UnmarshalOrgTemplate(v, &t); err != nil {
+				return err
+			}
+			templates = append(templates, t)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return templates, nil
+}
+
+// Add creates a new template in the store.
+func (s *orgTemplatesStore) Add(ctx context.Context, t *cloudhub.OrgTemplate) (*cloudhub.OrgTemplate, error) {
+	if err := s.client.kv.Update(ctx, func(tx Tx) error {
+		b := tx.Bucket(orgTemplatesBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		t.ID = strconv.FormatUint(seq, 10)
+		t.CreatedAt = time.Now().UTC()
+
+		v, err := internal.MarshalOrgTemplate(t)
+		if err != nil {
+			return err
+		}
+		return b.Put(orgTemplateKey(seq), v)
+	}); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Get returns a template if the id exists.
+func (s *orgTemplatesStore) Get(ctx context.Context, id string) (*cloudhub.OrgTemplate, error) {
+	var t *cloudhub.OrgTemplate
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		var err error
+		t, _, err = s.get(tx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Delete removes the template from the store.
+func (s *orgTemplatesStore) Delete(ctx context.Context, t *cloudhub.OrgTemplate) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		_, key, err := s.get(tx, t.ID)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(orgTemplatesBucket).Delete(key)
+	})
+}
+
+// get returns the template with the given id along with its key
+func (s *orgTemplatesStore) get(tx Tx, id string) (*cloudhub.OrgTemplate, []byte, error) {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, nil, cloudhub.ErrOrgTemplateNotFound
+	}
+
+	key := orgTemplateKey(seq)
+	v, err := tx.Bucket(orgTemplatesBucket).Get(key)
+	if v == nil || err != nil {
+		return nil, nil, cloudhub.ErrOrgTemplateNotFound
+	}
+
+	var t cloudhub.OrgTemplate
+	if err := internal.UnmarshalOrgTemplate(v, &t); err != nil {
+		return nil, nil, err
+	}
+	return &t, key, nil
+}
@@ -0,0 +1,74 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestOrgTemplatesStore(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	s := client.OrgTemplatesStore()
+
+	tmpl, err := s.Add(ctx, &cloudhub.OrgTemplate{
+		Name:         "customer",
+		Organization: "1",
+		DefaultRole:  "viewer",
+		Quotas:       &cloudhub.OrganizationQuotas{Dashboards: 3},
+		Sources: []cloudhub.Source{
+			{ID: 5, Name: "influx", URL: "http://localhost:8086", Telegraf: "telegraf", Organization: "1"},
+		},
+		Dashboards: []cloudhub.Dashboard{
+			{ID: 2, Name: "hosts", Organization: "1", Cells: []cloudhub.DashboardCell{}, Templates: []cloudhub.Template{}},
+		},
+		Topologies: []cloudhub.Topology{
+			{ID: "3", Organization: "1", Diagram: "<mxGraphModel/>"},
+		},
+		NetworkDeviceOrg: &cloudhub.NetworkDeviceOrg{ID: "1", LoadModule: "learn.ch_nx_load", DataDuration: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tmpl.ID == "" || tmpl.CreatedAt.IsZero() {
+		t.Fatalf("expected Add to set the ID and creation time, got %#v", tmpl)
+	}
+
+	got, err := s.Get(ctx, tmpl.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "customer" || got.Quotas == nil || got.Quotas.Dashboards != 3 {
+		t.Errorf("unexpected template %#v", got)
+	}
+	if len(got.Sources) != 1 || got.Sources[0].ID != 5 || got.Sources[0].Telegraf != "telegraf" {
+		t.Errorf("unexpected template sources %#v", got.Sources)
+	}
+	if len(got.Dashboards) != 1 || got.Dashboards[0].ID != 2 || len(got.Topologies) != 1 || got.Topologies[0].ID != "3" {
+		t.Errorf("unexpected template dashboards %#v or topologies %#v", got.Dashboards, got.Topologies)
+	}
+	if got.NetworkDeviceOrg == nil || got.NetworkDeviceOrg.DataDuration != 3 {
+		t.Errorf("unexpected template network device settings %#v", got.NetworkDeviceOrg)
+	}
+
+	templates, err := s.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) != 1 {
+		t.Fatalf("expected 1 template, got %#v", templates)
+	}
+
+	if err := s.Delete(ctx, tmpl); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, tmpl.ID); err != cloudhub.ErrOrgTemplateNotFound {
+		t.Errorf("expected ErrOrgTemplateNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package kv
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure protoboardsStore implements cloudhub.ProtoboardsStore.
+var _ cloudhub.ProtoboardsStore = &protoboardsStore{}
+
+// protoboardsStore is the kv implementation of storing the protoboards created by users
+type protoboardsStore struct {
+	client *Service
+	IDs    cloudhub.ID
+}
+
+// All returns all protoboards in the store.
+func (s *protoboardsStore) All(ctx context.Context) ([]cloudhub.Protoboard, error) {
+	protoboards := []cloudhub.Protoboard{}
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(protoboardsBucket).ForEach(func(k, v []byte) error {
+			var p cloudhub.Protoboard
+			if err := internal.UnmarshalProtoboard(v, &p); err != nil {
+				return err
+			}
+			protoboards = append(protoboards, p)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return protoboards, nil
+}
+
+// Add creates a new protoboard in the store with a new ID.
+func (s *protoboardsStore) Add(ctx context.Context, p cloudhub.Protoboard) (cloudhub.Protoboard, error) {
+	id, err := s.IDs.Generate()
+	if err != nil {
+		return cloudhub.Protoboard{}, err
+	}
+	p.ID = id
+
+	if err := s.client.kv.Update(ctx, func(tx Tx) error {
+		v, err := internal.MarshalProtoboard(&p)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(protoboardsBucket).Put([]byte(p.ID), v)
+	}); err != nil {
+		return cloudhub.Protoboard{}, err
+	}
+
+	return p, nil
+}
+
+// Get returns a protoboard if the id exists.
+func (s *protoboardsStore) Get(ctx context.Context, id string) (cloudhub.Protoboard, error) {
+	var p cloudhub.Protoboard
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		v, err := tx.Bucket(protoboardsBucket).Get([]byte(id))
+		if v == nil || err != nil {
+			return cloudhub.ErrProtoboardNotFound
+		}
+		return internal.UnmarshalProtoboard(v, &p)
+	}); err != nil {
+		return cloudhub.Protoboard{}, err
+	}
+
+	return p, nil
+}
+
+// Delete removes the protoboard from the store.
+func (s *protoboardsStore) Delete(ctx context.Context, p cloudhub.Protoboard) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		b := tx.Bucket(protoboardsBucket)
+		if v, err := b.Get([]byte(p.ID)); v == nil || err != nil {
+			return cloudhub.ErrProtoboardNotFound
+		}
+		return b.Delete([]byte(p.ID))
+	})
+}
+
+// Update replaces the protoboard in the store.
+func (s *protoboardsStore) Update(ctx context.Context, p cloudhub.Protoboard) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		b := tx.Bucket(protoboardsBucket)
+		if v, err := b.Get([]byte(p.ID)); v == nil || err != nil {
+			return cloudhub.ErrProtoboardNotFound
+		}
+		v, err := internal.MarshalProtoboard(&p)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(p.ID), v)
+	})
+}
@@ -0,0 +1,88 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestProtoboardsStore(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	s := client.ProtoboardsStore()
+
+	pb, err := s.Add(ctx, cloudhub.Protoboard{
+		Meta: cloudhub.ProtoboardMeta{
+			Name:         "nginx",
+			Version:      "1.0",
+			Measurements: []string{"nginx"},
+		},
+		Data: cloudhub.ProtoboardData{
+			Cells: []cloudhub.ProtoboardCell{
+				{
+					Name: "Requests",
+					W:    24,
+					H:    12,
+					Queries: []cloudhub.DashboardQuery{
+						{Command: `SELECT mean("requests") FROM ":db:".":rp:"."nginx" WHERE "host" = :host:`, Type: "influxql"},
+					},
+				},
+			},
+			Templates: []cloudhub.Template{
+				{TemplateVar: cloudhub.TemplateVar{Var: ":host:"}, Type: "tagValues"},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pb.ID == "" {
+		t.Fatalf("expected Add to set the ID, got %#v", pb)
+	}
+
+	got, err := s.Get(ctx, pb.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Meta.Name != "nginx" || got.Meta.Version != "1.0" || len(got.Meta.Measurements) != 1 {
+		t.Errorf("unexpected protoboard %#v", got.Meta)
+	}
+	if len(got.Data.Cells) != 1 || got.Data.Cells[0].Queries[0].Command != pb.Data.Cells[0].Queries[0].Command {
+		t.Errorf("unexpected protoboard cells %#v", got.Data.Cells)
+	}
+	if len(got.Data.Templates) != 1 || got.Data.Templates[0].Var != ":host:" {
+		t.Errorf("unexpected protoboard templates %#v", got.Data.Templates)
+	}
+
+	got.Meta.Version = "1.1"
+	if err := s.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if got, err = s.Get(ctx, pb.ID); err != nil || got.Meta.Version != "1.1" {
+		t.Errorf("expected version 1.1, got %#v, %v", got.Meta, err)
+	}
+
+	protoboards, err := s.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(protoboards) != 1 {
+		t.Fatalf("expected 1 protoboard, got %#v", protoboards)
+	}
+
+	if err := s.Delete(ctx, pb); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, pb.ID); err != cloudhub.ErrProtoboardNotFound {
+		t.Errorf("expected ErrProtoboardNotFound, got %v", err)
+	}
+	if err := s.Update(ctx, pb); err != cloudhub.ErrProtoboardNotFound {
+		t.Errorf("expected ErrProtoboardNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure reportsStore implements cloudhub.ReportsStore.
+var _ cloudhub.ReportsStore = &reportsStore{}
+
+// reportsStore is the kv implementation of storing reports
+type reportsStore struct {
+	client *Service
+}
+
+// reportKey returns the key of a report. The sequence is zero padded so that
+// the reports are iterated oldest first.
+func reportKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%020d", seq))
+}
+
+// All returns all reports in the store, oldest first.
+func (s *reportsStore) All(ctx context.Context) ([]cloudhub.Report, error) {
+	reports := []cloudhub.Report{}
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(reportsBucket).ForEach(func(k, v []byte) error {
+			var report cloudhub.Report
+			if err := internal.UnmarshalReport(v, &report); err != nil {
+				return err
+			}
+			reports = append(reports, report)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// Add creates a new report in the store.
+func (s *reportsStore) Add(ctx context.Context, report *cloudhub.Report) (*cloudhub.Report, error) {
+	if err := s.client.kv.Update(ctx, func(tx Tx) error {
+		b := tx.Bucket(reportsBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		report.ID = strconv.FormatUint(seq, 10)
+		report.CreatedAt = time.Now().UTC()
+
+		v, err := internal.MarshalReport(report)
+		if err != nil {
+			return err
+		}
+		return b.Put(reportKey(seq), v)
+	}); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Get returns a report if the id exists.
+func (s *reportsStore) Get(ctx context.Context, id string) (*cloudhub.Report, error) {
+	var report *cloudhub.Report
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		var err error
+		report, _, err = s.get(tx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+// Update replaces the report in the store.
+func (s *reportsStore) Update(ctx context.Context, report *cloudhub.Report) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		_, key, err := s.get(tx, report.ID)
+		if err != nil {
+			return err
+		}
+
+		v, err := internal.MarshalReport(report)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(reportsBucket).Put(key, v)
+	})
+}
+
+// Delete removes the report from the store.
+func (s *reportsStore) Delete(ctx context.Context, report *cloudhub.Report) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		_, key, err := s.get(tx, report.ID)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(reportsBucket).Delete(key)
+	})
+}
+
+// get returns the report with the given id along with its key
+func (s *reportsStore) get(tx Tx, id string) (*cloudhub.Report, []byte, error) {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, nil, cloudhub.ErrReportNotFound
+	}
+
+	key := reportKey(seq)
+	v, err := tx.Bucket(reportsBucket).Get(key)
+	if v == nil || err != nil {
+		return nil, nil, cloudhub.ErrReportNotFound
+	}
+
+	var report cloudhub.Report
+	if err := internal.UnmarshalReport(v, &report); err != nil {
+		return nil, nil, err
+	}
+	return &report, key, nil
+}
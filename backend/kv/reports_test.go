@@ -0,0 +1,68 @@
+package kv_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestReportsStore(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	s := client.ReportsStore()
+
+	next := time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+	report, err := s.Add(ctx, &cloudhub.Report{
+		Name:         "Weekly CPU",
+		Organization: "1",
+		Dashboard:    2,
+		Variables:    map[string]string{":host:": "web01"},
+		TimeRange:    "168h",
+		Schedule:     "0 8 * * MON",
+		TimeZone:     "UTC",
+		Format:       cloudhub.ReportPDF,
+		Recipients:   []string{"ops@example.com"},
+		NextRun:      next,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.ID == "" || report.CreatedAt.IsZero() {
+		t.Fatalf("expected Add to set the ID and creation time, got %#v", report)
+	}
+
+	report.Runs = append(report.Runs, cloudhub.ReportRun{Time: next, Trigger: cloudhub.ReportTriggerSchedule, Status: cloudhub.JobFailed, Error: "no recipient"})
+	if err := s.Update(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get(ctx, report.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Variables[":host:"] != "web01" || !got.NextRun.Equal(next) || len(got.Runs) != 1 || got.Runs[0].Error != "no recipient" {
+		t.Errorf("unexpected report %#v", got)
+	}
+
+	reports, err := s.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %#v", reports)
+	}
+
+	if err := s.Delete(ctx, report); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, report.ID); err != cloudhub.ErrReportNotFound {
+		t.Errorf("expected ErrReportNotFound, got %v", err)
+	}
+}
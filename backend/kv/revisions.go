@@ -0,0 +1,200 @@
+package kv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// DefaultMaxRevisions is the default number of revisions kept for each resource.
+const DefaultMaxRevisions = 50
+
+// Ensure revisionsStore implements cloudhub.RevisionsStore.
+var _ cloudhub.RevisionsStore = &revisionsStore{}
+
+// revisionsStore is the kv implementation of storing revisions
+type revisionsStore struct {
+	client *Service
+}
+
+// revisionPrefix returns the key prefix under which all revisions of a resource are stored.
+func revisionPrefix(resourceType, resourceID string) []byte {
+	return []byte(fmt.Sprintf("%s/%s/", resourceType, resourceID))
+}
+
+// revisionKey returns the key of a revision. The sequence is zero padded so that
+// revisions of a resource are iterated oldest first.
+func revisionKey(resourceType, resourceID string, seq uint64) []byte {
+	return []byte(fmt.Sprintf("%s/%s/%020d", resourceType, resourceID, seq))
+}
+
+// All returns the revisions of a resource, oldest first.
+func (s *revisionsStore) All(ctx context.Context, q cloudhub.RevisionQuery) ([]cloudhub.Revision, error) {
+	var revs []cloudhub.Revision
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		var err error
+		revs, err = s.all(tx, q)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return revs, nil
+}
+
+// Add stores a new revision of a resource.
+func (s *revisionsStore) Add(ctx context.Context, r *cloudhub.Revision) (*cloudhub.Revision, error) {
+	if err := s.client.kv.Update(ctx, func(tx Tx) error {
+		return s.add(tx, r)
+	}); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Get returns a revision of a resource if the id exists.
+func (s *revisionsStore) Get(ctx context.Context, q cloudhub.RevisionQuery, id string) (*cloudhub.Revision, error) {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, cloudhub.ErrRevisionNotFound
+	}
+
+	var r cloudhub.Revision
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		v, err := tx.Bucket(revisionsBucket).Get(revisionKey(q.ResourceType, q.ResourceID, seq))
+		if v == nil || err != nil {
+			return cloudhub.ErrRevisionNotFound
+		}
+		return internal.UnmarshalRevision(v, &r)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+func (s *revisionsStore) all(tx Tx, q cloudhub.RevisionQuery) ([]cloudhub.Revision, error) {
+	prefix := revisionPrefix(q.ResourceType, q.ResourceID)
+	revs := []cloudhub.Revision{}
+	err := tx.Bucket(revisionsBucket).ForEachPrefix(prefix, func(k, v []byte) error {
+		var r cloudhub.Revision
+		if err := internal.UnmarshalRevision(v, &r); err != nil {
+			return err
+		}
+		revs = append(revs, r)
+		return nil
+	})
+	return revs, err
+}
+
+// keys returns the keys of the revisions of a resource, oldest first.
+func (s *revisionsStore) keys(tx Tx, resourceType, resourceID string) ([][]byte, error) {
+	var keys [][]byte
+	err := tx.Bucket(revisionsBucket).ForEachPrefix(revisionPrefix(resourceType, resourceID), func(k, v []byte) error {
+		keys = append(keys, append([]byte{}, k...))
+		return nil
+	})
+	return keys, err
+}
+
+// DeleteAll removes every revision of a resource within an existing transaction.
+func (s *revisionsStore) DeleteAll(tx Tx, resourceType, resourceID string) error {
+	keys, err := s.keys(tx, resourceType, resourceID)
+	if err != nil {
+		return err
+	}
+	b := tx.Bucket(revisionsBucket)
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// add puts a revision within an existing transaction and removes the oldest
+// revisions of the resource that exceed the configured maximum.
+func (s *revisionsStore) add(tx Tx, r *cloudhub.Revision) error {
+	b := tx.Bucket(revisionsBucket)
+
+	existing, err := s.keys(tx, r.ResourceType, r.ResourceID)
+	if err != nil {
+		return err
+	}
+
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	r.ID = strconv.FormatUint(seq, 10)
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now().UTC()
+	}
+
+	v, err := internal.MarshalRevision(r)
+	if err != nil {
+		return err
+	}
+	if err := b.Put(revisionKey(r.ResourceType, r.ResourceID, seq), v); err != nil {
+		return err
+	}
+
+	max := s.client.maxRevisions
+	if max <= 0 {
+		return nil
+	}
+	for i := 0; i < len(existing)+1-max; i++ {
+		if err := b.Delete(existing[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revise records the new state of an updated resource within the update transaction.
+// When the resource has no history yet, its previous state is stored first so that
+// the state before the first tracked update can be restored.
+func (s *revisionsStore) revise(ctx context.Context, tx Tx, resourceType, resourceID, org string, prev, next interface{}) error {
+	hist, err := s.keys(tx, resourceType, resourceID)
+	if err != nil {
+		return err
+	}
+
+	if len(hist) == 0 && prev != nil {
+		content, err := json.Marshal(prev)
+		if err != nil {
+			return err
+		}
+		if err := s.add(tx, &cloudhub.Revision{
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			Organization: org,
+			Message:      "Initial revision",
+			Content:      content,
+		}); err != nil {
+			return err
+		}
+	}
+
+	content, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+
+	info, _ := ctx.Value(cloudhub.RevisionContextKey).(cloudhub.RevisionInfo)
+	return s.add(tx, &cloudhub.Revision{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Organization: org,
+		Author:       info.Author,
+		Message:      info.Message,
+		Content:      content,
+	})
+}
@@ -0,0 +1,173 @@
+package kv_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv"
+	"github.com/snetsystems/cloudhub/backend/kv/bolt"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestRevisionsStore_DashboardUpdate(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	s := client.DashboardsStore()
+	revs := client.RevisionsStore()
+
+	d, err := s.Add(ctx, cloudhub.Dashboard{Name: "first", Organization: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q := cloudhub.RevisionQuery{ResourceType: cloudhub.RevisionDashboard, ResourceID: "1"}
+	if all, err := revs.All(ctx, q); err != nil {
+		t.Fatal(err)
+	} else if len(all) != 0 {
+		t.Fatalf("expected no revisions before the first update, got %d", len(all))
+	}
+
+	ctx = context.WithValue(ctx, cloudhub.RevisionContextKey, cloudhub.RevisionInfo{
+		Author:  "admin",
+		Message: "rename",
+	})
+	d.Name = "second"
+	if err := s.Update(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if d, err = s.Get(ctx, d.ID); err != nil {
+		t.Fatal(err)
+	}
+	d.Name = "third"
+	if err := s.Update(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := revs.All(ctx, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(all))
+	}
+
+	names := []string{"first", "second", "third"}
+	for i, r := range all {
+		var got cloudhub.Dashboard
+		if err := json.Unmarshal(r.Content, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != names[i] {
+			t.Errorf("revision %d: expected name %q, got %q", i, names[i], got.Name)
+		}
+		if r.Organization != "default" {
+			t.Errorf("revision %d: expected organization default, got %q", i, r.Organization)
+		}
+	}
+	if all[0].Author != "" || all[0].Message != "Initial revision" {
+		t.Errorf("unexpected initial revision %#v", all[0])
+	}
+	if all[2].Author != "admin" || all[2].Message != "rename" {
+		t.Errorf("unexpected revision info %#v", all[2])
+	}
+
+	r, err := revs.Get(ctx, q, all[1].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ID != all[1].ID {
+		t.Errorf("expected revision %s, got %s", all[1].ID, r.ID)
+	}
+
+	if _, err := revs.Get(ctx, cloudhub.RevisionQuery{ResourceType: cloudhub.RevisionTopology, ResourceID: "1"}, all[1].ID); err != cloudhub.ErrRevisionNotFound {
+		t.Errorf("expected ErrRevisionNotFound for another resource, got %v", err)
+	}
+}
+
+func TestRevisionsStore_MaxRevisions(t *testing.T) {
+	f, err := ioutil.TempFile("", "cloudhub-bolt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	ctx := context.Background()
+	b, err := bolt.NewClient(ctx, bolt.WithPath(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := kv.NewService(ctx, b, kv.WithLogger(mocks.NewLogger()), kv.WithMaxRevisions(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	s := client.TopologiesStore()
+	tp, err := s.Add(ctx, &cloudhub.Topology{Organization: "default", Diagram: "<mxGraphModel/>"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, diagram := range []string{"a", "b", "c"} {
+		tp.Diagram = diagram
+		if err := s.Update(ctx, tp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := client.RevisionsStore().All(ctx, cloudhub.RevisionQuery{
+		ResourceType: cloudhub.RevisionTopology,
+		ResourceID:   tp.ID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 revisions, got %d", len(all))
+	}
+
+	var last cloudhub.Topology
+	if err := json.Unmarshal(all[1].Content, &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Diagram != "c" {
+		t.Errorf("expected latest revision to hold diagram c, got %q", last.Diagram)
+	}
+}
+
+func TestRevisionsStore_AllOfResource(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	s := client.RevisionsStore()
+	for _, id := range []string{"1", "10", "1", "2"} {
+		if _, err := s.Add(ctx, &cloudhub.Revision{ResourceType: cloudhub.RevisionDashboard, ResourceID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	all, err := s.All(ctx, cloudhub.RevisionQuery{ResourceType: cloudhub.RevisionDashboard, ResourceID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("expected 2 revisions of dashboard 1, got %#v", all)
+	}
+	for _, r := range all {
+		if r.ResourceID != "1" {
+			t.Errorf("expected only revisions of dashboard 1, got %#v", r)
+		}
+	}
+}
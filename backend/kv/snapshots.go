@@ -0,0 +1,151 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure snapshotsStore implements cloudhub.SnapshotsStore.
+var _ cloudhub.SnapshotsStore = &snapshotsStore{}
+
+// snapshotsStore is the kv implementation of storing dashboard snapshots
+type snapshotsStore struct {
+	client *Service
+}
+
+// snapshotKey returns the key of a snapshot. The sequence is zero padded so that
+// the snapshots are iterated oldest first.
+func snapshotKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%020d", seq))
+}
+
+// All returns all snapshots in the store, oldest first, without the results
+// of their cells.
+func (s *snapshotsStore) All(ctx context.Context) ([]cloudhub.Snapshot, error) {
+	snapshots := []cloudhub.Snapshot{}
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(snapshotsBucket).ForEach(func(k, v []byte) error {
+			var snapshot cloudhub.Snapshot
+			if err := internal.UnmarshalSnapshotMetadata(v, &snapshot); err != nil {
+				return err
+			}
+			snapshots = append(snapshots, snapshot)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// Add creates a new snapshot in the store.
+func (s *snapshotsStore) Add(ctx context.Context, snapshot *cloudhub.Snapshot) (*cloudhub.Snapshot, error) {
+	if err := s.client.kv.Update(ctx, func(tx Tx) error {
+		b := tx.Bucket(snapshotsBucket)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		snapshot.ID = strconv.FormatUint(seq, 10)
+		snapshot.CreatedAt = time.Now().UTC()
+
+		v, err := internal.MarshalSnapshot(snapshot)
+		if err != nil {
+			return err
+		}
+		key := snapshotKey(seq)
+		if err := b.Put(key, v); err != nil {
+			return err
+		}
+		return reindex(tx, snapshotsBucket, string(key), nil, v)
+	}); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// Get returns a snapshot if the id exists.
+func (s *snapshotsStore) Get(ctx context.Context, id string) (*cloudhub.Snapshot, error) {
+	var snapshot *cloudhub.Snapshot
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		var err error
+		snapshot, _, err = s.get(tx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// Delete removes the snapshot from the store.
+func (s *snapshotsStore) Delete(ctx context.Context, snapshot *cloudhub.Snapshot) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		b := tx.Bucket(snapshotsBucket)
+		_, key, err := s.get(tx, snapshot.ID)
+		if err != nil {
+			return err
+		}
+		v, err := b.Get(key)
+		if err != nil {
+			return err
+		}
+		if err := b.Delete(key); err != nil {
+			return err
+		}
+		return reindex(tx, snapshotsBucket, string(key), v, nil)
+	})
+}
+
+// GetByTokenHash returns the snapshot of a share link by the hash of its token,
+// looked up in the token index.
+func (s *snapshotsStore) GetByTokenHash(ctx context.Context, hash string) (*cloudhub.Snapshot, error) {
+	var snapshot *cloudhub.Snapshot
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		keys, err := snapshotTokenIndex.ids(tx, hash)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return cloudhub.ErrSnapshotNotFound
+		}
+		v, err := tx.Bucket(snapshotsBucket).Get([]byte(keys[0]))
+		if v == nil || err != nil {
+			return cloudhub.ErrSnapshotNotFound
+		}
+		snapshot = &cloudhub.Snapshot{}
+		return internal.UnmarshalSnapshot(v, snapshot)
+	}); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// get returns the snapshot with the given id along with its key
+func (s *snapshotsStore) get(tx Tx, id string) (*cloudhub.Snapshot, []byte, error) {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, nil, cloudhub.ErrSnapshotNotFound
+	}
+
+	key := snapshotKey(seq)
+	v, err := tx.Bucket(snapshotsBucket).Get(key)
+	if v == nil || err != nil {
+		return nil, nil, cloudhub.ErrSnapshotNotFound
+	}
+
+	var snapshot cloudhub.Snapshot
+	if err := internal.UnmarshalSnapshot(v, &snapshot); err != nil {
+		return nil, nil, err
+	}
+	return &snapshot, key, nil
+}
@@ -0,0 +1,84 @@
+package kv_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestSnapshotsStore(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	s := client.SnapshotsStore()
+
+	lower := time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)
+	snapshot, err := s.Add(ctx, &cloudhub.Snapshot{
+		Name:         "Outage",
+		Organization: "1",
+		Dashboard: cloudhub.Dashboard{
+			Name:  "Servers",
+			Cells: []cloudhub.DashboardCell{{ID: "c1", Type: "line", Queries: []cloudhub.DashboardQuery{{Command: "SELECT 1"}}}},
+		},
+		Lower:     lower,
+		Upper:     lower.Add(time.Hour),
+		Cells:     []cloudhub.SnapshotCell{{CellID: "c1", Results: []json.RawMessage{json.RawMessage(`[{"series":[]}]`)}}},
+		TokenHash: "abc",
+		ExpiresAt: lower.Add(24 * time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.ID == "" || snapshot.CreatedAt.IsZero() {
+		t.Fatalf("expected Add to set the ID and creation time, got %#v", snapshot)
+	}
+
+	got, err := s.Get(ctx, snapshot.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Dashboard.Name != "Servers" || got.Dashboard.Cells[0].Queries[0].Command != "SELECT 1" ||
+		string(got.Cells[0].Results[0]) != `[{"series":[]}]` || got.TokenHash != "abc" || !got.Upper.Equal(lower.Add(time.Hour)) {
+		t.Errorf("unexpected snapshot %#v", got)
+	}
+
+	snapshots, err := s.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot, got %#v", snapshots)
+	}
+	if snapshots[0].Cells != nil || snapshots[0].Dashboard.Name != "Servers" || snapshots[0].TokenHash != "abc" ||
+		!snapshots[0].ExpiresAt.Equal(lower.Add(24*time.Hour)) {
+		t.Errorf("expected All to list the snapshot without its results, got %#v", snapshots[0])
+	}
+
+	byToken, err := s.GetByTokenHash(ctx, "abc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byToken.ID != snapshot.ID {
+		t.Errorf("expected snapshot %s of the token hash, got %s", snapshot.ID, byToken.ID)
+	}
+	if _, err := s.GetByTokenHash(ctx, "abcd"); err != cloudhub.ErrSnapshotNotFound {
+		t.Errorf("expected ErrSnapshotNotFound of another token hash, got %v", err)
+	}
+
+	if err := s.Delete(ctx, snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, snapshot.ID); err != cloudhub.ErrSnapshotNotFound {
+		t.Errorf("expected ErrSnapshotNotFound, got %v", err)
+	}
+	if _, err := s.GetByTokenHash(ctx, "abc"); err != cloudhub.ErrSnapshotNotFound {
+		t.Errorf("expected ErrSnapshotNotFound of the token hash of a deleted snapshot, got %v", err)
+	}
+}
@@ -48,13 +48,16 @@ func (s *topologiesStore) Add(ctx context.Context, tp *cloudhub.Topology) (*clou
 			return err
 		}
 		tp.ID = strconv.FormatUint(seq, 10)
+		tp.Version = 1
 
-		if v, err := internal.MarshalTopology(tp); err != nil {
+		v, err := internal.MarshalTopology(tp)
+		if err != nil {
 			return err
-		} else if err := b.Put([]byte(tp.ID), v); err != nil {
+		}
+		if err := b.Put([]byte(tp.ID), v); err != nil {
 			return err
 		}
-		return nil
+		return reindex(tx, topologyBucket, tp.ID, nil, v)
 	}); err != nil {
 		return nil, err
 	}
@@ -69,25 +72,20 @@ func (s *topologiesStore) Get(ctx context.Context, q cloudhub.TopologyQuery) (*c
 	}
 
 	if q.Organization != nil {
-		var tp *cloudhub.Topology
-		err := s.each(ctx, func(t *cloudhub.Topology) {
-			if tp != nil {
-				return
-			}
-			if t.Organization == *q.Organization {
-				tp = t
-			}
-		})
-
-		if err != nil {
+		var ids []string
+		if err := s.client.kv.View(ctx, func(tx Tx) error {
+			var err error
+			ids, err = topologyOrgIndex.ids(tx, *q.Organization)
+			return err
+		}); err != nil {
 			return nil, err
 		}
 
-		if tp == nil {
+		if len(ids) == 0 {
 			return nil, cloudhub.ErrTopologyNotFound
 		}
 
-		return tp, nil
+		return s.get(ctx, ids[0])
 	}
 
 	return nil, fmt.Errorf("must specify either Organization in TopologyQuery")
@@ -96,15 +94,27 @@ func (s *topologiesStore) Get(ctx context.Context, q cloudhub.TopologyQuery) (*c
 // Delete the topology from topologiesStore
 func (s *topologiesStore) Delete(ctx context.Context, tp *cloudhub.Topology) error {
 	if err := s.client.kv.Update(ctx, func(tx Tx) error {
-		_, err := s.get(ctx, tp.ID)
-		if err != nil {
+		b := tx.Bucket(topologyBucket)
+		v, err := b.Get([]byte(tp.ID))
+		if v == nil || err != nil {
 			return cloudhub.ErrTopologyNotFound
 		}
-
-		if err := tx.Bucket(topologyBucket).Delete([]byte(tp.ID)); err != nil {
+		var prev cloudhub.Topology
+		if err := internal.UnmarshalTopology(v, &prev); err != nil {
 			return err
 		}
-		return nil
+		if err := checkVersion(tp.Version, prev.Version); err != nil {
+			return err
+		}
+		if err := b.Delete([]byte(tp.ID)); err != nil {
+			return err
+		}
+		if err := reindex(tx, topologyBucket, tp.ID, v, nil); err != nil {
+			return err
+		}
+
+		trash := &trashStore{client: s.client}
+		return trash.trash(ctx, tx, cloudhub.TrashTopology, tp.ID, prev.Organization, "", v)
 	}); err != nil {
 		return err
 	}
@@ -114,20 +124,38 @@ func (s *topologiesStore) Delete(ctx context.Context, tp *cloudhub.Topology) err
 
 // Update the topology in topologiesStore
 func (s *topologiesStore) Update(ctx context.Context, tp *cloudhub.Topology) error {
+	expected := tp.Version
 	if err := s.client.kv.Update(ctx, func(tx Tx) error {
 		// Get an existing topology with the same ID.
-		_, err := s.get(ctx, tp.ID)
-		if err != nil {
+		b := tx.Bucket(topologyBucket)
+		v, err := b.Get([]byte(tp.ID))
+		if v == nil || err != nil {
 			return cloudhub.ErrTopologyNotFound
 		}
+		prev := &cloudhub.Topology{}
+		if err := internal.UnmarshalTopology(v, prev); err != nil {
+			return err
+		}
+		if err := checkVersion(expected, prev.Version); err != nil {
+			return err
+		}
+		tp.Version = prev.Version + 1
 
-		if v, err := internal.MarshalTopology(tp); err != nil {
+		next, err := internal.MarshalTopology(tp)
+		if err != nil {
 			return err
-		} else if err := tx.Bucket(topologyBucket).Put([]byte(tp.ID), v); err != nil {
+		}
+		if err := b.Put([]byte(tp.ID), next); err != nil {
 			return err
 		}
-		return nil
+		if err := reindex(tx, topologyBucket, tp.ID, v, next); err != nil {
+			return err
+		}
+
+		revs := &revisionsStore{client: s.client}
+		return revs.revise(ctx, tx, cloudhub.RevisionTopology, tp.ID, tp.Organization, prev, tp)
 	}); err != nil {
+		tp.Version = expected
 		return err
 	}
 
@@ -151,16 +179,3 @@ func (s *topologiesStore) get(ctx context.Context, id string) (*cloudhub.Topolog
 
 	return &tp, nil
 }
-
-func (s *topologiesStore) each(ctx context.Context, fn func(*cloudhub.Topology)) error {
-	return s.client.kv.View(ctx, func(tx Tx) error {
-		return tx.Bucket(topologyBucket).ForEach(func(k, v []byte) error {
-			var tp cloudhub.Topology
-			if err := internal.UnmarshalTopology(v, &tp); err != nil {
-				return err
-			}
-			fn(&tp)
-			return nil
-		})
-	})
-}
\ No newline at end of file
@@ -54,6 +54,7 @@ func TestTopologiesStore(t *testing.T) {
 			t.Fatal(err)
 		}
 		tss[i].ID = rtnTs.ID
+		tss[i].Version = rtnTs.Version
 
 		// Confirm first ts in the store is the same as the original.
 		if actual, err := s.Get(ctx, cloudhub.TopologyQuery{ID: &rtnTs.ID}); err != nil {
@@ -0,0 +1,236 @@
+package kv
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure trashStore implements cloudhub.TrashStore.
+var _ cloudhub.TrashStore = &trashStore{}
+
+// trashBuckets maps the kind of a trashable resource to the bucket it is stored in
+var trashBuckets = map[string][]byte{
+	cloudhub.TrashDashboard:     dashboardsBucket,
+	cloudhub.TrashTopology:      topologyBucket,
+	cloudhub.TrashNetworkDevice: networkDeviceBucket,
+	cloudhub.TrashCSP:           cspBucket,
+}
+
+// trashRevisions maps the kind of a trashable resource to the kind of its revisions, if it has any
+var trashRevisions = map[string]string{
+	cloudhub.TrashDashboard: cloudhub.RevisionDashboard,
+	cloudhub.TrashTopology:  cloudhub.RevisionTopology,
+}
+
+// trashStore is the kv implementation of storing deleted resources
+type trashStore struct {
+	client *Service
+}
+
+// trashKey returns the key of a trash item. The sequence is zero padded so that
+// the items are iterated oldest first.
+func trashKey(seq uint64) []byte {
+	return []byte(fmt.Sprintf("%020d", seq))
+}
+
+// All returns all items in the trash, oldest first.
+func (s *trashStore) All(ctx context.Context) ([]cloudhub.TrashItem, error) {
+	items := []cloudhub.TrashItem{}
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(trashBucket).ForEach(func(k, v []byte) error {
+			var item cloudhub.TrashItem
+			if err := unmarshalTrashItem(v, &item); err != nil {
+				return err
+			}
+			items = append(items, item)
+			return nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Get returns a trash item if the id exists.
+func (s *trashStore) Get(ctx context.Context, id string) (*cloudhub.TrashItem, error) {
+	var item *cloudhub.TrashItem
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		var err error
+		item, _, err = s.get(tx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// Restore puts the deleted resource back under its original ID and removes the item from the trash.
+// References to the resource removed by other stores on delete are not restored.
+func (s *trashStore) Restore(ctx context.Context, item *cloudhub.TrashItem) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		stored, key, err := s.get(tx, item.ID)
+		if err != nil {
+			return err
+		}
+
+		bucket, ok := trashBuckets[stored.ResourceType]
+		if !ok {
+			return fmt.Errorf("unknown trash resource type %q", stored.ResourceType)
+		}
+		b := tx.Bucket(bucket)
+		if v, err := b.Get([]byte(stored.ResourceID)); err == nil && v != nil {
+			return cloudhub.ErrTrashRestoreConflict
+		}
+		if err := b.Put([]byte(stored.ResourceID), stored.Content); err != nil {
+			return err
+		}
+		if err := reindex(tx, bucket, stored.ResourceID, nil, stored.Content); err != nil {
+			return err
+		}
+
+		return tx.Bucket(trashBucket).Delete(key)
+	})
+}
+
+// Delete permanently removes an item from the trash along with the revisions of its resource.
+func (s *trashStore) Delete(ctx context.Context, item *cloudhub.TrashItem) error {
+	return s.client.kv.Update(ctx, func(tx Tx) error {
+		stored, key, err := s.get(tx, item.ID)
+		if err != nil {
+			return err
+		}
+		if err := s.deleteRevisions(tx, stored); err != nil {
+			return err
+		}
+		return tx.Bucket(trashBucket).Delete(key)
+	})
+}
+
+// Purge permanently removes the items deleted before the given time, in
+// batches so that a large trash does not exceed the transactions of etcd.
+// The revisions of their resources are removed first, one resource per
+// transaction, so that a failed purge leaves the items to purge again.
+func (s *trashStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	var expired [][]byte
+	var items []cloudhub.TrashItem
+	if err := s.client.kv.View(ctx, func(tx Tx) error {
+		return tx.Bucket(trashBucket).ForEach(func(k, v []byte) error {
+			var item cloudhub.TrashItem
+			if err := internal.UnmarshalTrashItem(v, &item); err != nil {
+				return err
+			}
+			if item.DeletedAt.Before(before) {
+				expired = append(expired, append([]byte{}, k...))
+				items = append(items, item)
+			}
+			return nil
+		})
+	}); err != nil {
+		return 0, err
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	for i := range items {
+		if _, ok := trashRevisions[items[i].ResourceType]; !ok {
+			continue
+		}
+		if err := s.client.kv.Update(ctx, func(tx Tx) error {
+			return s.deleteRevisions(tx, &items[i])
+		}); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.client.deleteKeys(ctx, trashBucket, expired); err != nil {
+		return 0, err
+	}
+
+	return len(expired), nil
+}
+
+// deleteRevisions removes the revisions of the resource of a trash item.
+// A resource restored under the same ID since keeps them.
+func (s *trashStore) deleteRevisions(tx Tx, item *cloudhub.TrashItem) error {
+	resourceType, ok := trashRevisions[item.ResourceType]
+	if !ok {
+		return nil
+	}
+	if bucket := trashBuckets[item.ResourceType]; bucket != nil {
+		if v, err := tx.Bucket(bucket).Get([]byte(item.ResourceID)); err == nil && v != nil {
+			return nil
+		}
+	}
+	revs := &revisionsStore{client: s.client}
+	return revs.DeleteAll(tx, resourceType, item.ResourceID)
+}
+
+// get returns the trash item with the given id along with its key
+func (s *trashStore) get(tx Tx, id string) (*cloudhub.TrashItem, []byte, error) {
+	seq, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, nil, cloudhub.ErrTrashItemNotFound
+	}
+
+	key := trashKey(seq)
+	v, err := tx.Bucket(trashBucket).Get(key)
+	if v == nil || err != nil {
+		return nil, nil, cloudhub.ErrTrashItemNotFound
+	}
+
+	var item cloudhub.TrashItem
+	if err := unmarshalTrashItem(v, &item); err != nil {
+		return nil, nil, err
+	}
+	return &item, key, nil
+}
+
+// unmarshalTrashItem decodes a trash item with the folder of a deleted dashboard
+func unmarshalTrashItem(v []byte, item *cloudhub.TrashItem) error {
+	if err := internal.UnmarshalTrashItem(v, item); err != nil {
+		return err
+	}
+	if item.ResourceType == cloudhub.TrashDashboard {
+		var d cloudhub.Dashboard
+		if err := internal.UnmarshalDashboard(item.Content, &d); err != nil {
+			return err
+		}
+		item.Folder = d.Folder
+	}
+	return nil
+}
+
+// trash moves the stored value of a deleted resource to the trash within the delete transaction.
+// The deleter is read from the cloudhub.TrashContextKey of ctx.
+func (s *trashStore) trash(ctx context.Context, tx Tx, resourceType, resourceID, org, name string, v []byte) error {
+	b := tx.Bucket(trashBucket)
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	deleter, _ := ctx.Value(cloudhub.TrashContextKey).(string)
+	item := &cloudhub.TrashItem{
+		ID:           strconv.FormatUint(seq, 10),
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Organization: org,
+		Name:         name,
+		DeletedBy:    deleter,
+		DeletedAt:    time.Now().UTC(),
+		Content:      append([]byte{}, v...),
+	}
+
+	data, err := internal.MarshalTrashItem(item)
+	if err != nil {
+		return err
+	}
+	return b.Put(trashKey(seq), data)
+}
@@ -0,0 +1,187 @@
+package kv_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func TestTrashStore_DeleteRestore(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.WithValue(context.Background(), cloudhub.TrashContextKey, "editor")
+	dashboards := client.DashboardsStore()
+	csps := client.CSPStore()
+	trash := client.TrashStore()
+
+	d, err := dashboards.Add(ctx, cloudhub.Dashboard{Name: "cpu", Organization: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csp, err := csps.Add(ctx, &cloudhub.CSP{Provider: "aws", NameSpace: "seoul", Organization: "default"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dashboards.Delete(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if err := csps.Delete(ctx, csp); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dashboards.Get(ctx, d.ID); err != cloudhub.ErrDashboardNotFound {
+		t.Fatalf("expected deleted dashboard to be gone, got %v", err)
+	}
+
+	items, err := trash.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 trash items, got %d", len(items))
+	}
+	if items[0].ResourceType != cloudhub.TrashDashboard || items[0].Name != "cpu" || items[0].DeletedBy != "editor" || items[0].Organization != "default" {
+		t.Errorf("unexpected dashboard trash item %#v", items[0])
+	}
+	if items[1].ResourceType != cloudhub.TrashCSP || items[1].Name != "aws/seoul" || items[1].ResourceID != csp.ID {
+		t.Errorf("unexpected csp trash item %#v", items[1])
+	}
+
+	if err := trash.Restore(ctx, &items[0]); err != nil {
+		t.Fatal(err)
+	}
+	got, err := dashboards.Get(ctx, d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "cpu" {
+		t.Errorf("expected restored dashboard cpu, got %q", got.Name)
+	}
+	if _, err := trash.Get(ctx, items[0].ID); err != cloudhub.ErrTrashItemNotFound {
+		t.Errorf("expected restored item to leave the trash, got %v", err)
+	}
+
+	if err := trash.Delete(ctx, &items[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := trash.Restore(ctx, &items[1]); err != cloudhub.ErrTrashItemNotFound {
+		t.Errorf("expected ErrTrashItemNotFound for a deleted item, got %v", err)
+	}
+}
+
+func TestTrashStore_Purge(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	topologies := client.TopologiesStore()
+	trash := client.TrashStore()
+
+	// more items than a transaction deletes
+	const count = 250
+	for i := 0; i < count; i++ {
+		tp, err := topologies.Add(ctx, &cloudhub.Topology{Organization: "default"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := topologies.Delete(ctx, tp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := trash.Purge(ctx, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	} else if n != 0 {
+		t.Errorf("expected no items older than an hour, purged %d", n)
+	}
+
+	if n, err := trash.Purge(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	} else if n != count {
+		t.Errorf("expected %d purged items, got %d", count, n)
+	}
+
+	items, err := trash.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 0 {
+		t.Errorf("expected empty trash, got %d items", len(items))
+	}
+}
+
+func TestTrashStore_DeleteRevisions(t *testing.T) {
+	client, err := NewTestClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	dashboards := client.DashboardsStore()
+	revs := client.RevisionsStore()
+	trash := client.TrashStore()
+
+	// two dashboards with revisions, one deleted from the trash, the other purged
+	var queries []cloudhub.RevisionQuery
+	for _, name := range []string{"cpu", "mem"} {
+		d, err := dashboards.Add(ctx, cloudhub.Dashboard{Name: name, Organization: "default"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Name = name + " usage"
+		if err := dashboards.Update(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+		if d, err = dashboards.Get(ctx, d.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := dashboards.Delete(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+		q := cloudhub.RevisionQuery{ResourceType: cloudhub.RevisionDashboard, ResourceID: strconv.Itoa(int(d.ID))}
+		if all, err := revs.All(ctx, q); err != nil {
+			t.Fatal(err)
+		} else if len(all) != 2 {
+			t.Fatalf("expected the revisions of trashed dashboard %s to be kept, got %d", name, len(all))
+		}
+		queries = append(queries, q)
+	}
+
+	items, err := trash.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := trash.Delete(ctx, &items[0]); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := revs.All(ctx, queries[0]); err != nil {
+		t.Fatal(err)
+	} else if len(all) != 0 {
+		t.Errorf("expected Delete to remove the revisions of the dashboard, got %d", len(all))
+	}
+	if all, err := revs.All(ctx, queries[1]); err != nil {
+		t.Fatal(err)
+	} else if len(all) != 2 {
+		t.Errorf("expected Delete to keep the revisions of other dashboards, got %d", len(all))
+	}
+
+	if _, err := trash.Purge(ctx, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := revs.All(ctx, queries[1]); err != nil {
+		t.Fatal(err)
+	} else if len(all) != 0 {
+		t.Errorf("expected Purge to remove the revisions of the dashboard, got %d", len(all))
+	}
+}
@@ -3,6 +3,7 @@ package kv
 import (
 	"context"
 	"fmt"
+	"strconv"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 	"github.com/snetsystems/cloudhub/backend/kv/internal"
@@ -212,3 +213,32 @@ func (s *usersStore) All(ctx context.Context) ([]cloudhub.User, error) {
 
 	return users, nil
 }
+
+// Page returns the users q selects in the order of their IDs
+func (s *usersStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.User]) ([]cloudhub.User, error) {
+	var after []byte
+	if q.After != "" {
+		id, err := strconv.ParseUint(q.After, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %q", q.After)
+		}
+		after = u64tob(id)
+	}
+
+	users := []cloudhub.User{}
+	if err := s.client.page(ctx, usersBucket, after, q.Limit, func(v []byte) (bool, error) {
+		var user cloudhub.User
+		if err := internal.UnmarshalUser(v, &user); err != nil {
+			return false, err
+		}
+		if q.Match != nil && !q.Match(user) {
+			return false, nil
+		}
+		users = append(users, user)
+		return true, nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
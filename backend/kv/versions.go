@@ -0,0 +1,108 @@
+package kv
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// versionsMigration marks in the migrations bucket that the versioned
+// resources stored before they were versioned were backfilled
+var versionsMigration = []byte("VersionsV1")
+
+// versioned maps the buckets of the versioned resources to a function
+// returning a record at version 1, or nil if it is versioned already
+var versioned = map[string]func(v []byte) ([]byte, error){
+	string(dashboardsBucket): func(v []byte) ([]byte, error) {
+		var d cloudhub.Dashboard
+		if err := internal.UnmarshalDashboard(v, &d); err != nil || d.Version != 0 {
+			return nil, nil
+		}
+		d.Version = 1
+		return internal.MarshalDashboard(d)
+	},
+	string(topologyBucket): func(v []byte) ([]byte, error) {
+		var tp cloudhub.Topology
+		if err := internal.UnmarshalTopology(v, &tp); err != nil || tp.Version != 0 {
+			return nil, nil
+		}
+		tp.Version = 1
+		return internal.MarshalTopology(&tp)
+	},
+	string(networkDeviceOrgBucket): func(v []byte) ([]byte, error) {
+		var org cloudhub.NetworkDeviceOrg
+		if err := internal.UnmarshalNetworkDeviceOrg(v, &org); err != nil || org.Version != 0 {
+			return nil, nil
+		}
+		org.Version = 1
+		return internal.MarshalNetworkDeviceOrg(&org)
+	},
+}
+
+// backfillVersions puts the versioned resources stored before they were
+// versioned at version 1, once, so that every update of them is checked
+// against the version its caller read. Stores only skip the check of the
+// callers passing version 0, which did not read the resource.
+func (s *Service) backfillVersions(ctx context.Context) error {
+	var done bool
+	keys := map[string][][]byte{} // keys of the records to backfill, by bucket
+	if err := s.kv.View(ctx, func(tx Tx) error {
+		var err error
+		if done, err = tx.Bucket(migrationsBucket).Exists(versionsMigration); err != nil || done {
+			return err
+		}
+		for bucket, backfill := range versioned {
+			if err := tx.Bucket([]byte(bucket)).ForEach(func(k, v []byte) error {
+				if next, err := backfill(v); err != nil || next == nil {
+					return err
+				}
+				keys[bucket] = append(keys[bucket], append([]byte{}, k...))
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil || done {
+		return err
+	}
+
+	for bucket, ks := range keys {
+		backfill := versioned[bucket]
+		for len(ks) > 0 {
+			n := len(ks)
+			if n > batchSize {
+				n = batchSize
+			}
+			if err := s.kv.Update(ctx, func(tx Tx) error {
+				b := tx.Bucket([]byte(bucket))
+				for _, k := range ks[:n] {
+					// records updated or removed since they were read are left as they are
+					v, err := b.Get(k)
+					if err != nil || v == nil {
+						continue
+					}
+					next, err := backfill(v)
+					if err != nil {
+						return err
+					}
+					if next == nil {
+						continue
+					}
+					if err := b.Put(k, next); err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				return err
+			}
+			ks = ks[n:]
+		}
+	}
+
+	return s.kv.Update(ctx, func(tx Tx) error {
+		return tx.Bucket(migrationsBucket).Put(versionsMigration, []byte("built"))
+	})
+}
@@ -0,0 +1,70 @@
+package kv_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv"
+	"github.com/snetsystems/cloudhub/backend/kv/bolt"
+	"github.com/snetsystems/cloudhub/backend/kv/internal"
+)
+
+// Ensure the versioned resources stored before they were versioned are
+// backfilled at version 1, so that stale updates of them are rejected.
+func TestService_BackfillVersions(t *testing.T) {
+	f, err := ioutil.TempFile("", "cloudhub-bolt-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	ctx := context.Background()
+	b, err := bolt.NewClient(ctx, bolt.WithPath(f.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if _, err := kv.NewService(ctx, b); err != nil {
+		t.Fatal(err)
+	}
+
+	// the settings were stored before the versions and their backfill
+	if err := b.Update(ctx, func(tx kv.Tx) error {
+		if err := tx.Bucket([]byte("MigrationsV1")).Delete([]byte("VersionsV1")); err != nil {
+			return err
+		}
+		v, err := internal.MarshalNetworkDeviceOrg(&cloudhub.NetworkDeviceOrg{ID: "1", LoadModule: "learn"})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("NetworkDeviceOrg")).Put([]byte("1"), v)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := kv.NewService(ctx, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := c.NetworkDeviceOrgStore()
+	id := "1"
+	org, err := s.Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org.Version != 1 || org.LoadModule != "learn" {
+		t.Fatalf("Get() of unversioned settings = %#v, want version 1", org)
+	}
+
+	stale := *org
+	if err := s.Update(ctx, org); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(ctx, &stale); err != cloudhub.ErrVersionConflict {
+		t.Errorf("Update() of stale settings error = %v, want ErrVersionConflict", err)
+	}
+}
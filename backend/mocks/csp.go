@@ -11,6 +11,7 @@ var _ cloudhub.CSPStore = &CSPStore{}
 // CSPStore mock allows all functions to be set for testing
 type CSPStore struct {
 	AllF    func(context.Context) ([]cloudhub.CSP, error)
+	PageF   func(context.Context, cloudhub.PageQuery[cloudhub.CSP]) ([]cloudhub.CSP, error)
 	AddF    func(context.Context, *cloudhub.CSP) (*cloudhub.CSP, error)
 	DeleteF func(context.Context, *cloudhub.CSP) error
 	GetF    func(ctx context.Context, q cloudhub.CSPQuery) (*cloudhub.CSP, error)
@@ -22,6 +23,18 @@ func (s *CSPStore) All(ctx context.Context) ([]cloudhub.CSP, error) {
 	return s.AllF(ctx)
 }
 
+// Page pages the CSP of AllF unless PageF is set
+func (s *CSPStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.CSP]) ([]cloudhub.CSP, error) {
+	if s.PageF != nil {
+		return s.PageF(ctx, q)
+	}
+	all, err := s.AllF(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cloudhub.PageOf(all, q, func(c cloudhub.CSP) string { return c.ID }), nil
+}
+
 // Add ...
 func (s *CSPStore) Add(ctx context.Context, csp *cloudhub.CSP) (*cloudhub.CSP, error) {
 	return s.AddF(ctx, csp)
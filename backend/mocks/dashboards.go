@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"strconv"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 )
@@ -12,6 +13,7 @@ var _ cloudhub.DashboardsStore = &DashboardsStore{}
 type DashboardsStore struct {
 	AddF    func(ctx context.Context, newDashboard cloudhub.Dashboard) (cloudhub.Dashboard, error)
 	AllF    func(ctx context.Context) ([]cloudhub.Dashboard, error)
+	PageF   func(ctx context.Context, q cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error)
 	DeleteF func(ctx context.Context, target cloudhub.Dashboard) error
 	GetF    func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error)
 	UpdateF func(ctx context.Context, target cloudhub.Dashboard) error
@@ -27,6 +29,18 @@ func (d *DashboardsStore) All(ctx context.Context) ([]cloudhub.Dashboard, error)
 	return d.AllF(ctx)
 }
 
+// Page pages the dashboards of AllF unless PageF is set
+func (d *DashboardsStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error) {
+	if d.PageF != nil {
+		return d.PageF(ctx, q)
+	}
+	all, err := d.AllF(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cloudhub.PageOf(all, q, func(d cloudhub.Dashboard) string { return strconv.Itoa(int(d.ID)) }), nil
+}
+
 // Delete ...
 func (d *DashboardsStore) Delete(ctx context.Context, target cloudhub.Dashboard) error {
 	return d.DeleteF(ctx, target)
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+var _ cloudhub.FoldersStore = &FoldersStore{}
+
+// FoldersStore mock allows all functions to be set for testing
+type FoldersStore struct {
+	AllF    func(context.Context) ([]cloudhub.Folder, error)
+	AddF    func(context.Context, *cloudhub.Folder) (*cloudhub.Folder, error)
+	GetF    func(context.Context, string) (*cloudhub.Folder, error)
+	UpdateF func(context.Context, *cloudhub.Folder) error
+	DeleteF func(context.Context, *cloudhub.Folder) error
+}
+
+// All ...
+func (s *FoldersStore) All(ctx context.Context) ([]cloudhub.Folder, error) {
+	return s.AllF(ctx)
+}
+
+// Add ...
+func (s *FoldersStore) Add(ctx context.Context, folder *cloudhub.Folder) (*cloudhub.Folder, error) {
+	return s.AddF(ctx, folder)
+}
+
+// Get ...
+func (s *FoldersStore) Get(ctx context.Context, id string) (*cloudhub.Folder, error) {
+	return s.GetF(ctx, id)
+}
+
+// Update ...
+func (s *FoldersStore) Update(ctx context.Context, folder *cloudhub.Folder) error {
+	return s.UpdateF(ctx, folder)
+}
+
+// Delete ...
+func (s *FoldersStore) Delete(ctx context.Context, folder *cloudhub.Folder) error {
+	return s.DeleteF(ctx, folder)
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+var _ cloudhub.IntegrityChecker = &IntegrityChecker{}
+
+// IntegrityChecker mock allows all functions to be set for testing
+type IntegrityChecker struct {
+	CheckF  func(context.Context) ([]cloudhub.IntegrityIssue, error)
+	RepairF func(context.Context) ([]cloudhub.IntegrityIssue, error)
+}
+
+// Check ...
+func (s *IntegrityChecker) Check(ctx context.Context) ([]cloudhub.IntegrityIssue, error) {
+	return s.CheckF(ctx)
+}
+
+// Repair ...
+func (s *IntegrityChecker) Repair(ctx context.Context) ([]cloudhub.IntegrityIssue, error) {
+	return s.RepairF(ctx)
+}
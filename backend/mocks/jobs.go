@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+var _ cloudhub.JobsStore = &JobsStore{}
+
+// JobsStore mock allows all functions to be set for testing
+type JobsStore struct {
+	AllF    func(context.Context) ([]cloudhub.Job, error)
+	AddF    func(context.Context, *cloudhub.Job) (*cloudhub.Job, error)
+	GetF    func(context.Context, string) (*cloudhub.Job, error)
+	UpdateF func(context.Context, *cloudhub.Job) error
+	DeleteF func(context.Context, *cloudhub.Job) error
+}
+
+// All ...
+func (s *JobsStore) All(ctx context.Context) ([]cloudhub.Job, error) {
+	return s.AllF(ctx)
+}
+
+// Add ...
+func (s *JobsStore) Add(ctx context.Context, job *cloudhub.Job) (*cloudhub.Job, error) {
+	return s.AddF(ctx, job)
+}
+
+// Get ...
+func (s *JobsStore) Get(ctx context.Context, id string) (*cloudhub.Job, error) {
+	return s.GetF(ctx, id)
+}
+
+// Update ...
+func (s *JobsStore) Update(ctx context.Context, job *cloudhub.Job) error {
+	return s.UpdateF(ctx, job)
+}
+
+// Delete ...
+func (s *JobsStore) Delete(ctx context.Context, job *cloudhub.Job) error {
+	return s.DeleteF(ctx, job)
+}
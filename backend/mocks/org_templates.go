@@ -0,0 +1,37 @@
+package mocks
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+var _ cloudhub.OrgTemplatesStore = &OrgTemplatesStore{}
+
+// OrgTemplatesStore mock allows all functions to be set for testing
+type OrgTemplatesStore struct {
+	AllF    func(context.Context) ([]cloudhub.OrgTemplate, error)
+	AddF    func(context.Context, *cloudhub.OrgTemplate) (*cloudhub.OrgTemplate, error)
+	GetF    func(context.Context, string) (*cloudhub.OrgTemplate, error)
+	DeleteF func(context.Context, *cloudhub.OrgTemplate) error
+}
+
+// All ...
+func (s *OrgTemplatesStore) All(ctx context.Context) ([]cloudhub.OrgTemplate, error) {
+	return s.AllF(ctx)
+}
+
+// Add ...
+func (s *OrgTemplatesStore) Add(ctx context.Context, t *cloudhub.OrgTemplate) (*cloudhub.OrgTemplate, error) {
+	return s.AddF(ctx, t)
+}
+
+// Get ...
+func (s *OrgTemplatesStore) Get(ctx context.Context, id string) (*cloudhub.OrgTemplate, error) {
+	return s.GetF(ctx, id)
+}
+
+// Delete ...
+func (s *OrgTemplatesStore) Delete(ctx context.Context, t *cloudhub.OrgTemplate) error {
+	return s.DeleteF(ctx, t)
+}
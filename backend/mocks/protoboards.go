@@ -10,8 +10,11 @@ var _ cloudhub.ProtoboardsStore = &ProtoboardsStore{}
 
 // ProtoboardsStore ...
 type ProtoboardsStore struct {
-	AllF func(ctx context.Context) ([]cloudhub.Protoboard, error)
-	GetF func(ctx context.Context, id string) (cloudhub.Protoboard, error)
+	AllF    func(ctx context.Context) ([]cloudhub.Protoboard, error)
+	AddF    func(ctx context.Context, protoboard cloudhub.Protoboard) (cloudhub.Protoboard, error)
+	DeleteF func(ctx context.Context, protoboard cloudhub.Protoboard) error
+	GetF    func(ctx context.Context, id string) (cloudhub.Protoboard, error)
+	UpdateF func(ctx context.Context, protoboard cloudhub.Protoboard) error
 }
 
 // All ...
@@ -19,7 +22,22 @@ func (s *ProtoboardsStore) All(ctx context.Context) ([]cloudhub.Protoboard, erro
 	return s.AllF(ctx)
 }
 
+// Add ...
+func (s *ProtoboardsStore) Add(ctx context.Context, protoboard cloudhub.Protoboard) (cloudhub.Protoboard, error) {
+	return s.AddF(ctx, protoboard)
+}
+
+// Delete ...
+func (s *ProtoboardsStore) Delete(ctx context.Context, protoboard cloudhub.Protoboard) error {
+	return s.DeleteF(ctx, protoboard)
+}
+
 // Get ...
 func (s *ProtoboardsStore) Get(ctx context.Context, id string) (cloudhub.Protoboard, error) {
 	return s.GetF(ctx, id)
 }
+
+// Update ...
+func (s *ProtoboardsStore) Update(ctx context.Context, protoboard cloudhub.Protoboard) error {
+	return s.UpdateF(ctx, protoboard)
+}
@@ -0,0 +1,43 @@
+package mocks
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+var _ cloudhub.ReportsStore = &ReportsStore{}
+
+// ReportsStore mock allows all functions to be set for testing
+type ReportsStore struct {
+	AllF    func(context.Context) ([]cloudhub.Report, error)
+	AddF    func(context.Context, *cloudhub.Report) (*cloudhub.Report, error)
+	GetF    func(context.Context, string) (*cloudhub.Report, error)
+	UpdateF func(context.Context, *cloudhub.Report) error
+	DeleteF func(context.Context, *cloudhub.Report) error
+}
+
+// All ...
+func (s *ReportsStore) All(ctx context.Context) ([]cloudhub.Report, error) {
+	return s.AllF(ctx)
+}
+
+// Add ...
+func (s *ReportsStore) Add(ctx context.Context, report *cloudhub.Report) (*cloudhub.Report, error) {
+	return s.AddF(ctx, report)
+}
+
+// Get ...
+func (s *ReportsStore) Get(ctx context.Context, id string) (*cloudhub.Report, error) {
+	return s.GetF(ctx, id)
+}
+
+// Update ...
+func (s *ReportsStore) Update(ctx context.Context, report *cloudhub.Report) error {
+	return s.UpdateF(ctx, report)
+}
+
+// Delete ...
+func (s *ReportsStore) Delete(ctx context.Context, report *cloudhub.Report) error {
+	return s.DeleteF(ctx, report)
+}
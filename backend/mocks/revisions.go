@@ -0,0 +1,31 @@
+package mocks
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+var _ cloudhub.RevisionsStore = &RevisionsStore{}
+
+// RevisionsStore mock allows all functions to be set for testing
+type RevisionsStore struct {
+	AllF func(context.Context, cloudhub.RevisionQuery) ([]cloudhub.Revision, error)
+	AddF func(context.Context, *cloudhub.Revision) (*cloudhub.Revision, error)
+	GetF func(context.Context, cloudhub.RevisionQuery, string) (*cloudhub.Revision, error)
+}
+
+// All ...
+func (s *RevisionsStore) All(ctx context.Context, q cloudhub.RevisionQuery) ([]cloudhub.Revision, error) {
+	return s.AllF(ctx, q)
+}
+
+// Add ...
+func (s *RevisionsStore) Add(ctx context.Context, r *cloudhub.Revision) (*cloudhub.Revision, error) {
+	return s.AddF(ctx, r)
+}
+
+// Get ...
+func (s *RevisionsStore) Get(ctx context.Context, q cloudhub.RevisionQuery, id string) (*cloudhub.Revision, error) {
+	return s.GetF(ctx, q, id)
+}
@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+var _ cloudhub.SnapshotsStore = &SnapshotsStore{}
+
+// SnapshotsStore mock allows all functions to be set for testing
+type SnapshotsStore struct {
+	AllF    func(context.Context) ([]cloudhub.Snapshot, error)
+	AddF    func(context.Context, *cloudhub.Snapshot) (*cloudhub.Snapshot, error)
+	GetF    func(context.Context, string) (*cloudhub.Snapshot, error)
+	DeleteF func(context.Context, *cloudhub.Snapshot) error
+
+	GetByTokenHashF func(context.Context, string) (*cloudhub.Snapshot, error)
+}
+
+// All ...
+func (s *SnapshotsStore) All(ctx context.Context) ([]cloudhub.Snapshot, error) {
+	return s.AllF(ctx)
+}
+
+// Add ...
+func (s *SnapshotsStore) Add(ctx context.Context, snapshot *cloudhub.Snapshot) (*cloudhub.Snapshot, error) {
+	return s.AddF(ctx, snapshot)
+}
+
+// Get ...
+func (s *SnapshotsStore) Get(ctx context.Context, id string) (*cloudhub.Snapshot, error) {
+	return s.GetF(ctx, id)
+}
+
+// GetByTokenHash ...
+func (s *SnapshotsStore) GetByTokenHash(ctx context.Context, hash string) (*cloudhub.Snapshot, error) {
+	return s.GetByTokenHashF(ctx, hash)
+}
+
+// Delete ...
+func (s *SnapshotsStore) Delete(ctx context.Context, snapshot *cloudhub.Snapshot) error {
+	return s.DeleteF(ctx, snapshot)
+}
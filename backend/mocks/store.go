@@ -26,6 +26,14 @@ type Store struct {
 	MLNxRstStore            cloudhub.MLNxRstStore
 	DLNxRstStore            cloudhub.DLNxRstStore
 	DLNxRstStgStore         cloudhub.DLNxRstStgStore
+	RevisionsStore          cloudhub.RevisionsStore
+	TrashStore              cloudhub.TrashStore
+	IntegrityChecker        cloudhub.IntegrityChecker
+	JobsStore               cloudhub.JobsStore
+	OrgTemplatesStore       cloudhub.OrgTemplatesStore
+	ReportsStore            cloudhub.ReportsStore
+	SnapshotsStore          cloudhub.SnapshotsStore
+	FoldersStore            cloudhub.FoldersStore
 }
 
 // Sources ...
@@ -117,3 +125,43 @@ func (s *Store) DLNxRst(ctx context.Context) cloudhub.DLNxRstStore {
 func (s *Store) DLNxRstStg(ctx context.Context) cloudhub.DLNxRstStgStore {
 	return s.DLNxRstStgStore
 }
+
+// Revisions ...
+func (s *Store) Revisions(ctx context.Context) cloudhub.RevisionsStore {
+	return s.RevisionsStore
+}
+
+// Trash ...
+func (s *Store) Trash(ctx context.Context) cloudhub.TrashStore {
+	return s.TrashStore
+}
+
+// Integrity ...
+func (s *Store) Integrity(ctx context.Context) cloudhub.IntegrityChecker {
+	return s.IntegrityChecker
+}
+
+// Jobs ...
+func (s *Store) Jobs(ctx context.Context) cloudhub.JobsStore {
+	return s.JobsStore
+}
+
+// OrgTemplates ...
+func (s *Store) OrgTemplates(ctx context.Context) cloudhub.OrgTemplatesStore {
+	return s.OrgTemplatesStore
+}
+
+// Reports ...
+func (s *Store) Reports(ctx context.Context) cloudhub.ReportsStore {
+	return s.ReportsStore
+}
+
+// Snapshots ...
+func (s *Store) Snapshots(ctx context.Context) cloudhub.SnapshotsStore {
+	return s.SnapshotsStore
+}
+
+// Folders ...
+func (s *Store) Folders(ctx context.Context) cloudhub.FoldersStore {
+	return s.FoldersStore
+}
@@ -0,0 +1,44 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+var _ cloudhub.TrashStore = &TrashStore{}
+
+// TrashStore mock allows all functions to be set for testing
+type TrashStore struct {
+	AllF     func(context.Context) ([]cloudhub.TrashItem, error)
+	GetF     func(context.Context, string) (*cloudhub.TrashItem, error)
+	RestoreF func(context.Context, *cloudhub.TrashItem) error
+	DeleteF  func(context.Context, *cloudhub.TrashItem) error
+	PurgeF   func(context.Context, time.Time) (int, error)
+}
+
+// All ...
+func (s *TrashStore) All(ctx context.Context) ([]cloudhub.TrashItem, error) {
+	return s.AllF(ctx)
+}
+
+// Get ...
+func (s *TrashStore) Get(ctx context.Context, id string) (*cloudhub.TrashItem, error) {
+	return s.GetF(ctx, id)
+}
+
+// Restore ...
+func (s *TrashStore) Restore(ctx context.Context, t *cloudhub.TrashItem) error {
+	return s.RestoreF(ctx, t)
+}
+
+// Delete ...
+func (s *TrashStore) Delete(ctx context.Context, t *cloudhub.TrashItem) error {
+	return s.DeleteF(ctx, t)
+}
+
+// Purge ...
+func (s *TrashStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	return s.PurgeF(ctx, before)
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"strconv"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 )
@@ -11,6 +12,7 @@ var _ cloudhub.UsersStore = &UsersStore{}
 // UsersStore mock allows all functions to be set for testing
 type UsersStore struct {
 	AllF    func(context.Context) ([]cloudhub.User, error)
+	PageF   func(context.Context, cloudhub.PageQuery[cloudhub.User]) ([]cloudhub.User, error)
 	AddF    func(context.Context, *cloudhub.User) (*cloudhub.User, error)
 	DeleteF func(context.Context, *cloudhub.User) error
 	GetF    func(ctx context.Context, q cloudhub.UserQuery) (*cloudhub.User, error)
@@ -23,6 +25,18 @@ func (s *UsersStore) All(ctx context.Context) ([]cloudhub.User, error) {
 	return s.AllF(ctx)
 }
 
+// Page pages the users of AllF unless PageF is set
+func (s *UsersStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.User]) ([]cloudhub.User, error) {
+	if s.PageF != nil {
+		return s.PageF(ctx, q)
+	}
+	all, err := s.AllF(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return cloudhub.PageOf(all, q, func(u cloudhub.User) string { return strconv.FormatUint(u.ID, 10) }), nil
+}
+
 // Num returns the number of users in the UsersStore
 func (s *UsersStore) Num(ctx context.Context) (int, error) {
 	return s.NumF(ctx)
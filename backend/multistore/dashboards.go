@@ -2,6 +2,7 @@ package multistore
 
 import (
 	"context"
+	"strconv"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 )
@@ -46,6 +47,60 @@ func (multi *DashboardsStore) All(ctx context.Context) ([]cloudhub.Dashboard, er
 	return all, nil
 }
 
+// Page pages the Dashboards of the contained Stores one Store after the other,
+// skipping the Dashboards whose ID is held by a previous Store as All does.
+// The page following a Dashboard continues in the first Store holding it.
+func (multi *DashboardsStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error) {
+	start := 0
+	if id, err := strconv.Atoi(q.After); err == nil {
+		for i, store := range multi.Stores {
+			if _, err := store.Get(ctx, cloudhub.DashboardID(id)); err == nil {
+				start = i
+				break
+			}
+		}
+	}
+
+	page := []cloudhub.Dashboard{}
+	ok := false
+	var err error
+	for i := start; i < len(multi.Stores); i++ {
+		previous := multi.Stores[:i]
+		sq := cloudhub.PageQuery[cloudhub.Dashboard]{
+			Match: func(d cloudhub.Dashboard) bool {
+				for _, store := range previous {
+					if _, err := store.Get(ctx, d.ID); err == nil {
+						return false
+					}
+				}
+				return q.Match == nil || q.Match(d)
+			},
+		}
+		if i == start {
+			sq.After = q.After
+		}
+		if q.Limit > 0 {
+			sq.Limit = q.Limit - len(page)
+		}
+		var boards []cloudhub.Dashboard
+		boards, err = multi.Stores[i].Page(ctx, sq)
+		if err != nil {
+			// If this Store is unable to return a page of dashboards, skip to the
+			// next Store.
+			continue
+		}
+		ok = true // We've received a response from at least one Store
+		page = append(page, boards...)
+		if q.Limit > 0 && len(page) >= q.Limit {
+			break
+		}
+	}
+	if !ok {
+		return nil, err
+	}
+	return page, nil
+}
+
 // Add the dashboard to the first responsive Store
 func (multi *DashboardsStore) Add(ctx context.Context, dashboard cloudhub.Dashboard) (cloudhub.Dashboard, error) {
 	var err error
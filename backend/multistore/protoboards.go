@@ -42,6 +42,43 @@ func (s *Protoboards) All(ctx context.Context) ([]cloudhub.Protoboard, error) {
 	return all, nil
 }
 
+// Add the protoboard to the first Store which accepts it
+func (s *Protoboards) Add(ctx context.Context, protoboard cloudhub.Protoboard) (cloudhub.Protoboard, error) {
+	var err error
+	for _, store := range s.Stores {
+		var p cloudhub.Protoboard
+		p, err = store.Add(ctx, protoboard)
+		if err == nil {
+			return p, nil
+		}
+	}
+	return cloudhub.Protoboard{}, err
+}
+
+// Delete delegates to all Stores, returns success if one Store is successful
+func (s *Protoboards) Delete(ctx context.Context, protoboard cloudhub.Protoboard) error {
+	var err error
+	for _, store := range s.Stores {
+		err = store.Delete(ctx, protoboard)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// Update the first Store holding the protoboard
+func (s *Protoboards) Update(ctx context.Context, protoboard cloudhub.Protoboard) error {
+	var err error
+	for _, store := range s.Stores {
+		err = store.Update(ctx, protoboard)
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
 // Get retrieves protoboard if `ID` exists.  Searches through each store sequentially until success.
 func (s *Protoboards) Get(ctx context.Context, ID string) (cloudhub.Protoboard, error) {
 	var err error
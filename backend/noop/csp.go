@@ -18,6 +18,11 @@ func (s *CSPStore) All(context.Context) ([]cloudhub.CSP, error) {
 	return nil, fmt.Errorf("no CSP found")
 }
 
+// Page ...
+func (s *CSPStore) Page(context.Context, cloudhub.PageQuery[cloudhub.CSP]) ([]cloudhub.CSP, error) {
+	return nil, fmt.Errorf("no CSP found")
+}
+
 // Add ...
 func (s *CSPStore) Add(context.Context, *cloudhub.CSP) (*cloudhub.CSP, error) {
 	return nil, fmt.Errorf("failed to add CSP")
@@ -18,6 +18,11 @@ func (s *DashboardsStore) All(context.Context) ([]cloudhub.Dashboard, error) {
 	return nil, fmt.Errorf("no dashboards found")
 }
 
+// Page ...
+func (s *DashboardsStore) Page(context.Context, cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error) {
+	return nil, fmt.Errorf("no dashboards found")
+}
+
 // Add ...
 func (s *DashboardsStore) Add(context.Context, cloudhub.Dashboard) (cloudhub.Dashboard, error) {
 	return cloudhub.Dashboard{}, fmt.Errorf("failed to add dashboard")
@@ -0,0 +1,39 @@
+package noop
+
+import (
+	"context"
+	"fmt"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure FoldersStore implements cloudhub.FoldersStore
+var _ cloudhub.FoldersStore = &FoldersStore{}
+
+// FoldersStore ...
+type FoldersStore struct{}
+
+// All ...
+func (s *FoldersStore) All(context.Context) ([]cloudhub.Folder, error) {
+	return nil, fmt.Errorf("no folders found")
+}
+
+// Add ...
+func (s *FoldersStore) Add(context.Context, *cloudhub.Folder) (*cloudhub.Folder, error) {
+	return nil, fmt.Errorf("failed to add folder")
+}
+
+// Get ...
+func (s *FoldersStore) Get(context.Context, string) (*cloudhub.Folder, error) {
+	return nil, cloudhub.ErrFolderNotFound
+}
+
+// Update ...
+func (s *FoldersStore) Update(context.Context, *cloudhub.Folder) error {
+	return fmt.Errorf("failed to update folder")
+}
+
+// Delete ...
+func (s *FoldersStore) Delete(context.Context, *cloudhub.Folder) error {
+	return fmt.Errorf("failed to delete folder")
+}
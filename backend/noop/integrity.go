@@ -0,0 +1,24 @@
+package noop
+
+import (
+	"context"
+	"fmt"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure IntegrityChecker implements cloudhub.IntegrityChecker
+var _ cloudhub.IntegrityChecker = &IntegrityChecker{}
+
+// IntegrityChecker ...
+type IntegrityChecker struct{}
+
+// Check ...
+func (s *IntegrityChecker) Check(context.Context) ([]cloudhub.IntegrityIssue, error) {
+	return nil, fmt.Errorf("cannot check integrity")
+}
+
+// Repair ...
+func (s *IntegrityChecker) Repair(context.Context) ([]cloudhub.IntegrityIssue, error) {
+	return nil, fmt.Errorf("cannot repair integrity")
+}
@@ -0,0 +1,39 @@
+package noop
+
+import (
+	"context"
+	"fmt"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure JobsStore implements cloudhub.JobsStore
+var _ cloudhub.JobsStore = &JobsStore{}
+
+// JobsStore ...
+type JobsStore struct{}
+
+// All ...
+func (s *JobsStore) All(context.Context) ([]cloudhub.Job, error) {
+	return nil, fmt.Errorf("no jobs found")
+}
+
+// Add ...
+func (s *JobsStore) Add(context.Context, *cloudhub.Job) (*cloudhub.Job, error) {
+	return nil, fmt.Errorf("failed to add job")
+}
+
+// Get ...
+func (s *JobsStore) Get(context.Context, string) (*cloudhub.Job, error) {
+	return nil, cloudhub.ErrJobNotFound
+}
+
+// Update ...
+func (s *JobsStore) Update(context.Context, *cloudhub.Job) error {
+	return fmt.Errorf("failed to update job")
+}
+
+// Delete ...
+func (s *JobsStore) Delete(context.Context, *cloudhub.Job) error {
+	return fmt.Errorf("failed to delete job")
+}
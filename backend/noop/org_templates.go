@@ -0,0 +1,34 @@
+package noop
+
+import (
+	"context"
+	"fmt"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure OrgTemplatesStore implements cloudhub.OrgTemplatesStore
+var _ cloudhub.OrgTemplatesStore = &OrgTemplatesStore{}
+
+// OrgTemplatesStore ...
+type OrgTemplatesStore struct{}
+
+// All ...
+func (s *OrgTemplatesStore) All(context.Context) ([]cloudhub.OrgTemplate, error) {
+	return nil, fmt.Errorf("no organization templates found")
+}
+
+// Add ...
+func (s *OrgTemplatesStore) Add(context.Context, *cloudhub.OrgTemplate) (*cloudhub.OrgTemplate, error) {
+	return nil, fmt.Errorf("failed to add organization template")
+}
+
+// Get ...
+func (s *OrgTemplatesStore) Get(context.Context, string) (*cloudhub.OrgTemplate, error) {
+	return nil, cloudhub.ErrOrgTemplateNotFound
+}
+
+// Delete ...
+func (s *OrgTemplatesStore) Delete(context.Context, *cloudhub.OrgTemplate) error {
+	return fmt.Errorf("failed to delete organization template")
+}
@@ -0,0 +1,39 @@
+package noop
+
+import (
+	"context"
+	"fmt"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure ReportsStore implements cloudhub.ReportsStore
+var _ cloudhub.ReportsStore = &ReportsStore{}
+
+// ReportsStore ...
+type ReportsStore struct{}
+
+// All ...
+func (s *ReportsStore) All(context.Context) ([]cloudhub.Report, error) {
+	return nil, fmt.Errorf("no reports found")
+}
+
+// Add ...
+func (s *ReportsStore) Add(context.Context, *cloudhub.Report) (*cloudhub.Report, error) {
+	return nil, fmt.Errorf("failed to add report")
+}
+
+// Get ...
+func (s *ReportsStore) Get(context.Context, string) (*cloudhub.Report, error) {
+	return nil, cloudhub.ErrReportNotFound
+}
+
+// Update ...
+func (s *ReportsStore) Update(context.Context, *cloudhub.Report) error {
+	return fmt.Errorf("failed to update report")
+}
+
+// Delete ...
+func (s *ReportsStore) Delete(context.Context, *cloudhub.Report) error {
+	return fmt.Errorf("failed to delete report")
+}
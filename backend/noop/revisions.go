@@ -0,0 +1,29 @@
+package noop
+
+import (
+	"context"
+	"fmt"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure RevisionsStore implements cloudhub.RevisionsStore
+var _ cloudhub.RevisionsStore = &RevisionsStore{}
+
+// RevisionsStore ...
+type RevisionsStore struct{}
+
+// All ...
+func (s *RevisionsStore) All(context.Context, cloudhub.RevisionQuery) ([]cloudhub.Revision, error) {
+	return nil, fmt.Errorf("no revisions found")
+}
+
+// Add ...
+func (s *RevisionsStore) Add(context.Context, *cloudhub.Revision) (*cloudhub.Revision, error) {
+	return nil, fmt.Errorf("failed to add revision")
+}
+
+// Get ...
+func (s *RevisionsStore) Get(context.Context, cloudhub.RevisionQuery, string) (*cloudhub.Revision, error) {
+	return nil, cloudhub.ErrRevisionNotFound
+}
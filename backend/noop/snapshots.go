@@ -0,0 +1,39 @@
+package noop
+
+import (
+	"context"
+	"fmt"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure SnapshotsStore implements cloudhub.SnapshotsStore
+var _ cloudhub.SnapshotsStore = &SnapshotsStore{}
+
+// SnapshotsStore ...
+type SnapshotsStore struct{}
+
+// All ...
+func (s *SnapshotsStore) All(context.Context) ([]cloudhub.Snapshot, error) {
+	return nil, fmt.Errorf("no snapshots found")
+}
+
+// Add ...
+func (s *SnapshotsStore) Add(context.Context, *cloudhub.Snapshot) (*cloudhub.Snapshot, error) {
+	return nil, fmt.Errorf("failed to add snapshot")
+}
+
+// Get ...
+func (s *SnapshotsStore) Get(context.Context, string) (*cloudhub.Snapshot, error) {
+	return nil, cloudhub.ErrSnapshotNotFound
+}
+
+// GetByTokenHash ...
+func (s *SnapshotsStore) GetByTokenHash(context.Context, string) (*cloudhub.Snapshot, error) {
+	return nil, cloudhub.ErrSnapshotNotFound
+}
+
+// Delete ...
+func (s *SnapshotsStore) Delete(context.Context, *cloudhub.Snapshot) error {
+	return fmt.Errorf("failed to delete snapshot")
+}
@@ -0,0 +1,40 @@
+package noop
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure TrashStore implements cloudhub.TrashStore
+var _ cloudhub.TrashStore = &TrashStore{}
+
+// TrashStore ...
+type TrashStore struct{}
+
+// All ...
+func (s *TrashStore) All(context.Context) ([]cloudhub.TrashItem, error) {
+	return nil, fmt.Errorf("no trash items found")
+}
+
+// Get ...
+func (s *TrashStore) Get(context.Context, string) (*cloudhub.TrashItem, error) {
+	return nil, cloudhub.ErrTrashItemNotFound
+}
+
+// Restore ...
+func (s *TrashStore) Restore(context.Context, *cloudhub.TrashItem) error {
+	return fmt.Errorf("failed to restore trash item")
+}
+
+// Delete ...
+func (s *TrashStore) Delete(context.Context, *cloudhub.TrashItem) error {
+	return fmt.Errorf("failed to delete trash item")
+}
+
+// Purge ...
+func (s *TrashStore) Purge(context.Context, time.Time) (int, error) {
+	return 0, fmt.Errorf("failed to purge trash")
+}
@@ -18,6 +18,11 @@ func (s *UsersStore) All(context.Context) ([]cloudhub.User, error) {
 	return nil, fmt.Errorf("no users found")
 }
 
+// Page ...
+func (s *UsersStore) Page(context.Context, cloudhub.PageQuery[cloudhub.User]) ([]cloudhub.User, error) {
+	return nil, fmt.Errorf("no users found")
+}
+
 // Add ...
 func (s *UsersStore) Add(context.Context, *cloudhub.User) (*cloudhub.User, error) {
 	return nil, fmt.Errorf("failed to add user")
@@ -48,6 +48,21 @@ func (s *CSPStore) All(ctx context.Context) ([]cloudhub.CSP, error) {
 	return csps, nil
 }
 
+// Page retrieves a page of the CSPs of the underlying CSPStore that belong
+// to the organization.
+func (s *CSPStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.CSP]) ([]cloudhub.CSP, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := q.Match
+	q.Match = func(c cloudhub.CSP) bool {
+		return c.Organization == s.organization && (match == nil || match(c))
+	}
+	return s.store.Page(ctx, q)
+}
+
 // Get returns a CSP if the id exists and belongs to the organization that is set.
 func (s *CSPStore) Get(ctx context.Context, q cloudhub.CSPQuery) (*cloudhub.CSP, error) {
 	err := validOrganization(ctx)
@@ -50,6 +50,21 @@ func (s *DashboardsStore) All(ctx context.Context) ([]cloudhub.Dashboard, error)
 	return dashboards, nil
 }
 
+// Page retrieves a page of the dashboards of the underlying DashboardStore
+// that belong to the organization.
+func (s *DashboardsStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := q.Match
+	q.Match = func(d cloudhub.Dashboard) bool {
+		return d.Organization == s.organization && (match == nil || match(d))
+	}
+	return s.store.Page(ctx, q)
+}
+
 // Add creates a new Dashboard in the DashboardsStore with dashboard.Organization set to be the
 // organization from the dashboard store.
 func (s *DashboardsStore) Add(ctx context.Context, d cloudhub.Dashboard) (cloudhub.Dashboard, error) {
@@ -69,12 +84,13 @@ func (s *DashboardsStore) Delete(ctx context.Context, d cloudhub.Dashboard) erro
 		return err
 	}
 
-	d, err = s.store.Get(ctx, d.ID)
+	stored, err := s.store.Get(ctx, d.ID)
 	if err != nil {
 		return err
 	}
+	stored.Version = d.Version
 
-	return s.store.Delete(ctx, d)
+	return s.store.Delete(ctx, stored)
 }
 
 // Get returns a Dashboard if the id exists and belongs to the organization that is set.
@@ -0,0 +1,100 @@
+package organizations
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure that FoldersStore implements cloudhub.FoldersStore
+var _ cloudhub.FoldersStore = &FoldersStore{}
+
+// FoldersStore facade on a FoldersStore that filters folders
+// by organization.
+type FoldersStore struct {
+	store        cloudhub.FoldersStore
+	organization string
+}
+
+// NewFoldersStore creates a new FoldersStore from an existing
+// cloudhub.FoldersStore and an organization string
+func NewFoldersStore(s cloudhub.FoldersStore, org string) *FoldersStore {
+	return &FoldersStore{
+		store:        s,
+		organization: org,
+	}
+}
+
+// All retrieves all folders from the underlying FoldersStore and filters them
+// by organization.
+func (s *FoldersStore) All(ctx context.Context) ([]cloudhub.Folder, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := s.store.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	folders := fs[:0]
+	for _, f := range fs {
+		if f.Organization == s.organization {
+			folders = append(folders, f)
+		}
+	}
+
+	return folders, nil
+}
+
+// Add creates a new Folder in the FoldersStore with folder.Organization set to be the
+// organization from the folders store.
+func (s *FoldersStore) Add(ctx context.Context, f *cloudhub.Folder) (*cloudhub.Folder, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f.Organization = s.organization
+	return s.store.Add(ctx, f)
+}
+
+// Get returns a Folder if the id exists and belongs to the organization that is set.
+func (s *FoldersStore) Get(ctx context.Context, id string) (*cloudhub.Folder, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.Organization != s.organization {
+		return nil, cloudhub.ErrFolderNotFound
+	}
+
+	return f, nil
+}
+
+// Update the folder in FoldersStore if it belongs to the organization that is set.
+func (s *FoldersStore) Update(ctx context.Context, f *cloudhub.Folder) error {
+	if _, err := s.Get(ctx, f.ID); err != nil {
+		return err
+	}
+
+	f.Organization = s.organization
+	return s.store.Update(ctx, f)
+}
+
+// Delete the folder from FoldersStore if it belongs to the organization that is set.
+func (s *FoldersStore) Delete(ctx context.Context, f *cloudhub.Folder) error {
+	f, err := s.Get(ctx, f.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Delete(ctx, f)
+}
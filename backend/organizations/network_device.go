@@ -73,6 +73,21 @@ func (s *NetworkDeviceStore) Get(ctx context.Context, q cloudhub.NetworkDeviceQu
 	return t, nil
 }
 
+// Find returns the Devices of the underlying NetworkDeviceStore matching q.
+// Devices of other organizations are only returned to a super admin.
+func (s *NetworkDeviceStore) Find(ctx context.Context, q cloudhub.NetworkDeviceQuery) ([]cloudhub.NetworkDevice, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.isSuperAdmin {
+		q.Organization = &s.organization
+	}
+
+	return s.store.Find(ctx, q)
+}
+
 // Add creates a new Device in the NetworkDeviceStore with NetworkDevice.Organization set to be the
 // organization from the Device store.
 func (s *NetworkDeviceStore) Add(ctx context.Context, t *cloudhub.NetworkDevice) (*cloudhub.NetworkDevice, error) {
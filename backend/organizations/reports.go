@@ -0,0 +1,100 @@
+package organizations
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure that ReportsStore implements cloudhub.ReportsStore
+var _ cloudhub.ReportsStore = &ReportsStore{}
+
+// ReportsStore facade on a ReportsStore that filters reports
+// by organization.
+type ReportsStore struct {
+	store        cloudhub.ReportsStore
+	organization string
+}
+
+// NewReportsStore creates a new ReportsStore from an existing
+// cloudhub.ReportsStore and an organization string
+func NewReportsStore(s cloudhub.ReportsStore, org string) *ReportsStore {
+	return &ReportsStore{
+		store:        s,
+		organization: org,
+	}
+}
+
+// All retrieves all reports from the underlying ReportsStore and filters them
+// by organization.
+func (s *ReportsStore) All(ctx context.Context) ([]cloudhub.Report, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := s.store.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := rs[:0]
+	for _, r := range rs {
+		if r.Organization == s.organization {
+			reports = append(reports, r)
+		}
+	}
+
+	return reports, nil
+}
+
+// Add creates a new Report in the ReportsStore with report.Organization set to be the
+// organization from the reports store.
+func (s *ReportsStore) Add(ctx context.Context, r *cloudhub.Report) (*cloudhub.Report, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Organization = s.organization
+	return s.store.Add(ctx, r)
+}
+
+// Get returns a Report if the id exists and belongs to the organization that is set.
+func (s *ReportsStore) Get(ctx context.Context, id string) (*cloudhub.Report, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Organization != s.organization {
+		return nil, cloudhub.ErrReportNotFound
+	}
+
+	return r, nil
+}
+
+// Update the report in ReportsStore if it belongs to the organization that is set.
+func (s *ReportsStore) Update(ctx context.Context, r *cloudhub.Report) error {
+	if _, err := s.Get(ctx, r.ID); err != nil {
+		return err
+	}
+
+	r.Organization = s.organization
+	return s.store.Update(ctx, r)
+}
+
+// Delete the report from ReportsStore if it belongs to the organization that is set.
+func (s *ReportsStore) Delete(ctx context.Context, r *cloudhub.Report) error {
+	r, err := s.Get(ctx, r.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Delete(ctx, r)
+}
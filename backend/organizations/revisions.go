@@ -0,0 +1,80 @@
+package organizations
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure that RevisionsStore implements cloudhub.RevisionsStore
+var _ cloudhub.RevisionsStore = &RevisionsStore{}
+
+// RevisionsStore facade on a RevisionsStore that filters revisions
+// by organization.
+type RevisionsStore struct {
+	store        cloudhub.RevisionsStore
+	organization string
+}
+
+// NewRevisionsStore creates a new RevisionsStore from an existing
+// cloudhub.RevisionsStore and an organization string
+func NewRevisionsStore(s cloudhub.RevisionsStore, org string) *RevisionsStore {
+	return &RevisionsStore{
+		store:        s,
+		organization: org,
+	}
+}
+
+// All retrieves the revisions of a resource from the underlying RevisionsStore and filters them
+// by organization.
+func (s *RevisionsStore) All(ctx context.Context, q cloudhub.RevisionQuery) ([]cloudhub.Revision, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rs, err := s.store.All(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := rs[:0]
+	for _, r := range rs {
+		if r.Organization == s.organization {
+			revisions = append(revisions, r)
+		}
+	}
+
+	return revisions, nil
+}
+
+// Add creates a new Revision in the RevisionsStore with revision.Organization set to be the
+// organization from the revisions store.
+func (s *RevisionsStore) Add(ctx context.Context, r *cloudhub.Revision) (*cloudhub.Revision, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Organization = s.organization
+	return s.store.Add(ctx, r)
+}
+
+// Get returns a Revision if the id exists and belongs to the organization that is set.
+func (s *RevisionsStore) Get(ctx context.Context, q cloudhub.RevisionQuery, id string) (*cloudhub.Revision, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.store.Get(ctx, q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.Organization != s.organization {
+		return nil, cloudhub.ErrRevisionNotFound
+	}
+
+	return r, nil
+}
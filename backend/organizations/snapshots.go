@@ -0,0 +1,109 @@
+package organizations
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure that SnapshotsStore implements cloudhub.SnapshotsStore
+var _ cloudhub.SnapshotsStore = &SnapshotsStore{}
+
+// SnapshotsStore facade on a SnapshotsStore that filters snapshots
+// by organization.
+type SnapshotsStore struct {
+	store        cloudhub.SnapshotsStore
+	organization string
+}
+
+// NewSnapshotsStore creates a new SnapshotsStore from an existing
+// cloudhub.SnapshotsStore and an organization string
+func NewSnapshotsStore(s cloudhub.SnapshotsStore, org string) *SnapshotsStore {
+	return &SnapshotsStore{
+		store:        s,
+		organization: org,
+	}
+}
+
+// All retrieves all snapshots from the underlying SnapshotsStore and filters them
+// by organization.
+func (s *SnapshotsStore) All(ctx context.Context) ([]cloudhub.Snapshot, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snaps, err := s.store.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := snaps[:0]
+	for _, snap := range snaps {
+		if snap.Organization == s.organization {
+			snapshots = append(snapshots, snap)
+		}
+	}
+
+	return snapshots, nil
+}
+
+// Add creates a new Snapshot in the SnapshotsStore with snapshot.Organization set to be the
+// organization from the snapshots store.
+func (s *SnapshotsStore) Add(ctx context.Context, snap *cloudhub.Snapshot) (*cloudhub.Snapshot, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snap.Organization = s.organization
+	return s.store.Add(ctx, snap)
+}
+
+// GetByTokenHash returns the Snapshot of a token hash if it belongs to the organization that is set.
+func (s *SnapshotsStore) GetByTokenHash(ctx context.Context, hash string) (*cloudhub.Snapshot, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := s.store.GetByTokenHash(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if snap.Organization != s.organization {
+		return nil, cloudhub.ErrSnapshotNotFound
+	}
+
+	return snap, nil
+}
+
+// Get returns a Snapshot if the id exists and belongs to the organization that is set.
+func (s *SnapshotsStore) Get(ctx context.Context, id string) (*cloudhub.Snapshot, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snap, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if snap.Organization != s.organization {
+		return nil, cloudhub.ErrSnapshotNotFound
+	}
+
+	return snap, nil
+}
+
+// Delete the snapshot from SnapshotsStore if it belongs to the organization that is set.
+func (s *SnapshotsStore) Delete(ctx context.Context, snap *cloudhub.Snapshot) error {
+	snap, err := s.Get(ctx, snap.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Delete(ctx, snap)
+}
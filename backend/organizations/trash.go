@@ -0,0 +1,110 @@
+package organizations
+
+import (
+	"context"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure that TrashStore implements cloudhub.TrashStore
+var _ cloudhub.TrashStore = &TrashStore{}
+
+// TrashStore facade on a TrashStore that filters trash items
+// by organization.
+type TrashStore struct {
+	store        cloudhub.TrashStore
+	organization string
+}
+
+// NewTrashStore creates a new TrashStore from an existing
+// cloudhub.TrashStore and an organization string
+func NewTrashStore(s cloudhub.TrashStore, org string) *TrashStore {
+	return &TrashStore{
+		store:        s,
+		organization: org,
+	}
+}
+
+// All retrieves all trash items from the underlying TrashStore and filters them
+// by organization.
+func (s *TrashStore) All(ctx context.Context) ([]cloudhub.TrashItem, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := s.store.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items := ts[:0]
+	for _, t := range ts {
+		if t.Organization == s.organization {
+			items = append(items, t)
+		}
+	}
+
+	return items, nil
+}
+
+// Get returns a TrashItem if the id exists and belongs to the organization that is set.
+func (s *TrashStore) Get(ctx context.Context, id string) (*cloudhub.TrashItem, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t, err := s.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Organization != s.organization {
+		return nil, cloudhub.ErrTrashItemNotFound
+	}
+
+	return t, nil
+}
+
+// Restore restores the TrashItem if it belongs to the organization that is set.
+func (s *TrashStore) Restore(ctx context.Context, t *cloudhub.TrashItem) error {
+	item, err := s.Get(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Restore(ctx, item)
+}
+
+// Delete removes the TrashItem if it belongs to the organization that is set.
+func (s *TrashStore) Delete(ctx context.Context, t *cloudhub.TrashItem) error {
+	item, err := s.Get(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+
+	return s.store.Delete(ctx, item)
+}
+
+// Purge removes the expired trash items of the organization that is set.
+func (s *TrashStore) Purge(ctx context.Context, before time.Time) (int, error) {
+	items, err := s.All(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for i := range items {
+		if !items[i].DeletedAt.Before(before) {
+			continue
+		}
+		if err := s.store.Delete(ctx, &items[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
@@ -263,6 +263,41 @@ func (s *UsersStore) All(ctx context.Context) ([]cloudhub.User, error) {
 	return us, nil
 }
 
+// Page returns a page of the users that have a role in the organization
+// provided on the UsersStore, with their roles filtered as in All.
+func (s *UsersStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.User]) ([]cloudhub.User, error) {
+	err := validOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := q.Match
+	q.Match = func(usr cloudhub.User) bool {
+		usr.Roles = s.roles(usr)
+		return len(usr.Roles) != 0 && (match == nil || match(usr))
+	}
+	us, err := s.store.Page(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	for i := range us {
+		us[i].Roles = s.roles(us[i])
+	}
+
+	return us, nil
+}
+
+// roles returns the roles of a user in the organization provided on the UsersStore
+func (s *UsersStore) roles(usr cloudhub.User) []cloudhub.Role {
+	roles := []cloudhub.Role{}
+	for _, r := range usr.Roles {
+		if r.Organization == s.organization {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
 // Num returns the number of users in the UsersStore
 // This is unperformant, but should rarely be used.
 func (s *UsersStore) Num(ctx context.Context) (int, error) {
@@ -976,6 +976,41 @@ func TestUsersStore_All(t *testing.T) {
 	}
 }
 
+func TestUsersStore_Page(t *testing.T) {
+	users := []cloudhub.User{
+		{ID: 1, Name: "howdy", Roles: []cloudhub.Role{{Organization: "1337", Name: "admin"}, {Organization: "1338", Name: "viewer"}}},
+		{ID: 2, Name: "doody", Roles: []cloudhub.Role{{Organization: "1338", Name: "editor"}}},
+		{ID: 3, Name: "billietta", Roles: []cloudhub.Role{{Organization: "1337", Name: "viewer"}}},
+		{ID: 4, Name: "bill", Roles: []cloudhub.Role{{Organization: "1337", Name: "editor"}}},
+	}
+	store := &mocks.UsersStore{
+		AllF: func(ctx context.Context) ([]cloudhub.User, error) {
+			return users, nil
+		},
+	}
+	ctx := context.WithValue(context.Background(), organizations.ContextKey, "1337")
+	s := organizations.NewUsersStore(store, "1337")
+
+	// the users are matched with the roles of the organization only
+	got, err := s.Page(ctx, cloudhub.PageQuery[cloudhub.User]{
+		Limit: 2,
+		Match: func(u cloudhub.User) bool { return len(u.Roles) == 1 && u.Roles[0].Name != "viewer" },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []cloudhub.User{
+		{Name: "howdy", Roles: []cloudhub.Role{{Organization: "1337", Name: "admin"}}},
+		{Name: "bill", Roles: []cloudhub.Role{{Organization: "1337", Name: "editor"}}},
+	}
+	if diff := gocmp.Diff(got, want, userCloudHubOptions...); diff != "" {
+		t.Errorf("UsersStore.Page():\n-got/+want\ndiff %s", diff)
+	}
+	if len(users[0].Roles) != 2 {
+		t.Errorf("UsersStore.Page() changed the roles of the underlying users: %v", users[0].Roles)
+	}
+}
+
 func TestUsersStore_Num(t *testing.T) {
 	type fields struct {
 		UsersStore cloudhub.UsersStore
@@ -3,7 +3,6 @@ package protoboards
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 )
@@ -45,12 +44,12 @@ func (s *BinProtoboardsStore) All(ctx context.Context) ([]cloudhub.Protoboard, e
 
 // Add is not support by BinProtoboardsStore
 func (s *BinProtoboardsStore) Add(ctx context.Context, protoboard cloudhub.Protoboard) (cloudhub.Protoboard, error) {
-	return cloudhub.Protoboard{}, fmt.Errorf("Add to BinProtoboardsStore not supported")
+	return cloudhub.Protoboard{}, cloudhub.ErrProtoboardReadOnly
 }
 
 // Delete is not support by BinProtoboardsStore
 func (s *BinProtoboardsStore) Delete(ctx context.Context, protoboard cloudhub.Protoboard) error {
-	return fmt.Errorf("Delete to BinProtoboardsStore not supported")
+	return cloudhub.ErrProtoboardReadOnly
 }
 
 // Get retrieves protoboard if `ID` exists.
@@ -79,5 +78,5 @@ func (s *BinProtoboardsStore) Get(ctx context.Context, ID string) (cloudhub.Prot
 
 // Update not supported
 func (s *BinProtoboardsStore) Update(ctx context.Context, protoboard cloudhub.Protoboard) error {
-	return fmt.Errorf("Update to BinProtoboardsStore not supported")
+	return cloudhub.ErrProtoboardReadOnly
 }
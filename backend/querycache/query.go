@@ -0,0 +1,143 @@
+package querycache
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// groupByTime matches the interval of GROUP BY time(), once the quoted text of a
+// query is blanked
+var groupByTime = regexp.MustCompile(`(?i)\bGROUP\s+BY\b[^;]*?\btime\s*\(\s*(\d+)(ns|u|µ|ms|s|m|h|d|w)\b`)
+
+// into matches the INTO clause of a SELECT writing its results
+var into = regexp.MustCompile(`(?i)\bINTO\b`)
+
+var durationUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"u":  time.Microsecond,
+	"µ":  time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+}
+
+// Normalize returns the query with the spaces outside of quotes collapsed and
+// without trailing semicolons, so that identical queries formatted differently
+// share their results
+func Normalize(command string) string {
+	var b strings.Builder
+	var quote rune
+	space := false
+	for i, r := range command {
+		switch {
+		case quote != 0:
+			b.WriteRune(r)
+			if r == quote && !escaped(command, i) {
+				quote = 0
+			}
+			continue
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		if r == '\'' || r == '"' {
+			quote = r
+		}
+		b.WriteRune(r)
+	}
+	return strings.TrimRight(strings.TrimSpace(b.String()), "; ")
+}
+
+// Cacheable returns true if every statement of the query only reads data:
+// SELECT statements without INTO clause and SHOW statements
+func Cacheable(command string) bool {
+	found := false
+	for _, stmt := range strings.Split(blankQuotes(command), ";") {
+		stmt = strings.TrimSpace(stmt)
+		if stmt == "" {
+			continue
+		}
+		found = true
+		word := strings.ToUpper(strings.FieldsFunc(stmt, unicode.IsSpace)[0])
+		switch {
+		case word == "SHOW":
+		case word == "SELECT" && !into.MatchString(stmt):
+		default:
+			return false
+		}
+	}
+	return found
+}
+
+// GroupByInterval returns the smallest interval of the GROUP BY time() clauses
+// of the statements of the query, 0 if a statement does not group by time
+func GroupByInterval(command string) time.Duration {
+	var interval time.Duration
+	for _, stmt := range strings.Split(blankQuotes(command), ";") {
+		if strings.TrimSpace(stmt) == "" {
+			continue
+		}
+		m := groupByTime.FindStringSubmatch(stmt)
+		if m == nil {
+			return 0
+		}
+		n, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil || n <= 0 {
+			return 0
+		}
+		d := time.Duration(n) * durationUnits[m[2]]
+		if interval == 0 || d < interval {
+			interval = d
+		}
+	}
+	return interval
+}
+
+// Bucket returns the bounds of the interval aligned to the epoch holding now,
+// as are the intervals of GROUP BY time()
+func Bucket(now time.Time, interval time.Duration) (time.Time, time.Time) {
+	start := now.Truncate(interval)
+	return start, start.Add(interval)
+}
+
+// blankQuotes returns the command with the quoted text replaced by spaces
+func blankQuotes(command string) string {
+	var b strings.Builder
+	var quote rune
+	for i, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote && !escaped(command, i) {
+				quote = 0
+				b.WriteRune(r)
+			} else {
+				b.WriteByte(' ')
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// escaped returns true if the byte at i is preceded by an odd number of backslashes
+func escaped(s string, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
@@ -0,0 +1,61 @@
+package querycache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"SELECT  mean(\"usage\")\n FROM cpu ;", `SELECT mean("usage") FROM cpu`},
+		{`SELECT * FROM "my  cpu" WHERE host = 'web  01'`, `SELECT * FROM "my  cpu" WHERE host = 'web  01'`},
+		{`SELECT * FROM cpu WHERE path = 'it\'s  here'  ;;`, `SELECT * FROM cpu WHERE path = 'it\'s  here'`},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.command); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	tests := map[string]bool{
+		`SELECT mean(usage) FROM cpu`:                        true,
+		`select * from cpu; SHOW TAG VALUES WITH KEY = host`: true,
+		`SELECT * INTO backup FROM cpu`:                      false,
+		`SELECT * FROM cpu WHERE "into" = 'INTO'`:            true,
+		`SELECT * FROM cpu; DROP MEASUREMENT cpu`:            false,
+		`CREATE DATABASE telegraf`:                           false,
+		`DELETE FROM cpu WHERE time < now() - 1d`:            false,
+		` ; `: false,
+	}
+	for command, want := range tests {
+		if got := Cacheable(command); got != want {
+			t.Errorf("Cacheable(%q) = %v, want %v", command, got, want)
+		}
+	}
+}
+
+func TestGroupByInterval(t *testing.T) {
+	tests := map[string]time.Duration{
+		`SELECT mean(usage) FROM cpu WHERE time > now() - 1h GROUP BY time(1m), host`:             time.Minute,
+		`SELECT mean(usage) FROM cpu GROUP BY host, TIME(10s) FILL(null)`:                         10 * time.Second,
+		`SELECT mean(a) FROM cpu GROUP BY time(1h); SELECT mean(b) FROM mem GROUP BY time(500ms)`: 500 * time.Millisecond,
+		`SELECT mean(a) FROM cpu GROUP BY time(1h); SELECT b FROM mem`:                            0,
+		`SELECT last(usage) FROM cpu WHERE host = 'GROUP BY time(1m)'`:                            0,
+		`SELECT mean(usage) FROM cpu GROUP BY time(1d)`:                                           24 * time.Hour,
+	}
+	for command, want := range tests {
+		if got := GroupByInterval(command); got != want {
+			t.Errorf("GroupByInterval(%q) = %s, want %s", command, got, want)
+		}
+	}
+
+	start, end := Bucket(time.Date(2023, 11, 20, 8, 0, 42, 0, time.UTC), time.Minute)
+	if !start.Equal(time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2023, 11, 20, 8, 1, 0, 0, time.UTC)) {
+		t.Errorf("Bucket() = %s, %s", start, end)
+	}
+}
@@ -0,0 +1,183 @@
+// Package querycache is a memory-bounded cache of the results of InfluxQL queries
+// shared by the clients proxying identical queries, e.g. the browsers showing a
+// dashboard, which also coalesces the concurrent identical queries.
+package querycache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Status tells how a result was obtained
+type Status string
+
+// Statuses of the results of the cache
+const (
+	Miss      Status = "MISS"      // Miss results were queried for the request
+	Hit       Status = "HIT"       // Hit results were cached
+	Coalesced Status = "COALESCED" // Coalesced results were queried for a concurrent identical request
+)
+
+// Stats are the metrics of a cache
+type Stats struct {
+	Hits      uint64 `json:"hits"`      // Hits counts the results found in the cache
+	Misses    uint64 `json:"misses"`    // Misses counts the results queried
+	Coalesced uint64 `json:"coalesced"` // Coalesced counts the results shared by a concurrent query
+	Errors    uint64 `json:"errors"`    // Errors counts the queries failing, which are not cached
+	Evictions uint64 `json:"evictions"` // Evictions counts the results evicted before expiring to bound the memory
+	TooLarge  uint64 `json:"tooLarge"`  // TooLarge counts the results too large to be cached
+	Entries   int    `json:"entries"`   // Entries is the number of results cached
+	Bytes     int64  `json:"bytes"`     // Bytes is the size of the results cached
+	MaxBytes  int64  `json:"maxBytes"`  // MaxBytes is the maximum size of the results cached
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// call is a query in flight, whose result the identical requests wait for
+type call struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// Cache is a least recently used cache of query results, safe for concurrent use
+type Cache struct {
+	MaxBytes      int64            // MaxBytes bounds the size of the results cached
+	MaxEntryBytes int64            // MaxEntryBytes bounds the size of a result cached, MaxBytes/8 if zero
+	Now           func() time.Time // Now returns the current time
+
+	mu      sync.Mutex
+	lru     *list.List // lru lists the entries, most recently used first
+	entries map[string]*list.Element
+	calls   map[string]*call
+	bytes   int64
+	stats   Stats
+}
+
+// New returns a cache of results of at most maxBytes
+func New(maxBytes int64) *Cache {
+	return &Cache{
+		MaxBytes: maxBytes,
+		Now:      time.Now,
+	}
+}
+
+// Get returns the result of the key cached, or queries it with fetch and caches
+// it until expires. Concurrent calls for a key being fetched wait for its result.
+// Results are shared and must not be modified.
+func (c *Cache) Get(key string, expires time.Time, fetch func() ([]byte, error)) ([]byte, Status, error) {
+	c.mu.Lock()
+	c.init()
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*entry)
+		if c.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return e.value, Hit, nil
+		}
+		c.remove(el)
+	}
+	if cl, ok := c.calls[key]; ok {
+		c.stats.Coalesced++
+		c.mu.Unlock()
+		<-cl.done
+		return cl.value, Coalesced, cl.err
+	}
+	cl := &call{done: make(chan struct{})}
+	c.calls[key] = cl
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	cl.value, cl.err = fetch()
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	if cl.err != nil {
+		c.stats.Errors++
+	} else {
+		c.add(key, cl.value, expires)
+	}
+	c.mu.Unlock()
+	close(cl.done)
+
+	return cl.value, Miss, cl.err
+}
+
+// Stats returns the metrics of the cache
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	stats.Bytes = c.bytes
+	stats.MaxBytes = c.MaxBytes
+	return stats
+}
+
+// Flush removes every result cached
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lru = list.New()
+	c.entries = map[string]*list.Element{}
+	c.bytes = 0
+}
+
+func (c *Cache) init() {
+	if c.entries == nil {
+		c.lru = list.New()
+		c.entries = map[string]*list.Element{}
+		c.calls = map[string]*call{}
+	}
+}
+
+// add caches a result, evicting the expired and then the least recently used
+// results beyond the size of the cache
+func (c *Cache) add(key string, value []byte, expires time.Time) {
+	size := int64(len(key) + len(value))
+	maxEntry := c.MaxEntryBytes
+	if maxEntry <= 0 {
+		maxEntry = c.MaxBytes / 8
+	}
+	if size > maxEntry || size > c.MaxBytes {
+		c.stats.TooLarge++
+		return
+	}
+	now := c.Now()
+	if !now.Before(expires) {
+		return
+	}
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.lru.PushFront(&entry{key: key, value: value, expires: expires})
+	c.bytes += size
+
+	if c.bytes <= c.MaxBytes {
+		return
+	}
+	for el := c.lru.Back(); el != nil; {
+		prev := el.Prev()
+		if !now.Before(el.Value.(*entry).expires) {
+			c.remove(el)
+		}
+		el = prev
+	}
+	for c.bytes > c.MaxBytes {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*entry)
+	delete(c.entries, e.key)
+	c.bytes -= int64(len(e.key) + len(e.value))
+}
@@ -0,0 +1,137 @@
+package querycache
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCache_Get(t *testing.T) {
+	now := time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+	c := New(1000)
+	c.Now = func() time.Time { return now }
+
+	fetches := 0
+	fetch := func(value string) func() ([]byte, error) {
+		return func() ([]byte, error) {
+			fetches++
+			return []byte(value), nil
+		}
+	}
+
+	if v, status, err := c.Get("a", now.Add(10*time.Second), fetch("1")); err != nil || string(v) != "1" || status != Miss {
+		t.Fatalf("Get() = %s, %s, %v", v, status, err)
+	}
+	if v, status, _ := c.Get("a", now.Add(10*time.Second), fetch("2")); string(v) != "1" || status != Hit {
+		t.Errorf("Get() of a cached result = %s, %s", v, status)
+	}
+	now = now.Add(10 * time.Second)
+	if v, status, _ := c.Get("a", now.Add(10*time.Second), fetch("2")); string(v) != "2" || status != Miss {
+		t.Errorf("Get() of an expired result = %s, %s", v, status)
+	}
+
+	if _, _, err := c.Get("b", now.Add(time.Second), func() ([]byte, error) { return nil, errTest }); err != errTest {
+		t.Errorf("Get() error = %v", err)
+	}
+	if _, status, _ := c.Get("b", now.Add(time.Second), fetch("3")); status != Miss {
+		t.Errorf("Get() cached an error")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 4 || stats.Errors != 1 || stats.Entries != 2 || stats.Bytes != 4 || stats.MaxBytes != 1000 {
+		t.Errorf("Stats() = %#v", stats)
+	}
+	c.Flush()
+	if stats = c.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Errorf("Stats() after Flush() = %#v", stats)
+	}
+}
+
+type testError string
+
+func (e testError) Error() string { return string(e) }
+
+const errTest = testError("timeout")
+
+func TestCache_Evictions(t *testing.T) {
+	now := time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)
+	c := New(100)
+	c.Now = func() time.Time { return now }
+	value := func(n int) func() ([]byte, error) {
+		return func() ([]byte, error) { return []byte(strings.Repeat("x", n)), nil }
+	}
+
+	// entries are at most 1/8 of the cache
+	c.Get("big", now.Add(time.Minute), value(20))
+	if stats := c.Stats(); stats.Entries != 0 || stats.TooLarge != 1 {
+		t.Errorf("Get() cached a result too large: %#v", stats)
+	}
+
+	for _, k := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		c.Get(k, now.Add(time.Minute), value(10))
+	}
+	// a is the most recently used, b the least
+	c.Get("a", now.Add(time.Minute), value(10))
+	c.Get("i", now.Add(time.Minute), value(10))
+	c.Get("j", now.Add(time.Minute), value(10))
+
+	stats := c.Stats()
+	if stats.Bytes > 100 || stats.Evictions != 1 {
+		t.Errorf("Stats() = %#v", stats)
+	}
+	if _, status, _ := c.Get("a", now.Add(time.Minute), value(10)); status != Hit {
+		t.Errorf("Get() evicted a recently used result")
+	}
+	if _, status, _ := c.Get("b", now.Add(time.Minute), value(10)); status != Miss {
+		t.Errorf("Get() kept the least recently used result")
+	}
+}
+
+func TestCache_Coalescing(t *testing.T) {
+	c := New(1000)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	fetches := 0
+	fetch := func() ([]byte, error) {
+		fetches++
+		close(started)
+		<-release
+		return []byte("result"), nil
+	}
+
+	var wg sync.WaitGroup
+	statuses := make([]Status, 5)
+	values := make([]string, 5)
+	run := func(i int) {
+		defer wg.Done()
+		v, status, _ := c.Get("q", time.Now().Add(time.Minute), fetch)
+		values[i], statuses[i] = string(v), status
+	}
+	wg.Add(1)
+	go run(0)
+	<-started
+	for i := 1; i < 5; i++ {
+		wg.Add(1)
+		go run(i)
+	}
+	// waits for the requests to wait for the first one
+	for c.Stats().Coalesced != 4 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if fetches != 1 {
+		t.Errorf("Get() queried %d times, want 1", fetches)
+	}
+	for i := range values {
+		want := Coalesced
+		if i == 0 {
+			want = Miss
+		}
+		if values[i] != "result" || statuses[i] != want {
+			t.Errorf("Get() %d = %s, %s, want result, %s", i, values[i], statuses[i], want)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Chart is the image of a cell, a list of shapes written as SVG or drawn as PNG
+type Chart struct {
+	Width  int
+	Height int
+	shapes []shape
+}
+
+type point struct {
+	X, Y float64
+}
+
+// shape is a rect, a path or a text of a chart
+type shape interface {
+	svg(w io.Writer)
+	draw(c *canvas)
+}
+
+// rect is a filled rectangle
+type rect struct {
+	x, y, w, h float64
+	fill       string
+	opacity    float64
+}
+
+// path is a line through its points, or a polygon if it is filled
+type path struct {
+	points  []point
+	stroke  string
+	width   float64
+	fill    string
+	opacity float64 // opacity is the opacity of the fill
+}
+
+// text is a line of text; y is the top of the text and anchor is start, middle or end
+type text struct {
+	x, y   float64
+	s      string
+	size   float64
+	color  string
+	anchor string
+}
+
+func (c *Chart) rect(x, y, w, h float64, fill string) {
+	c.shapes = append(c.shapes, &rect{x: x, y: y, w: w, h: h, fill: fill, opacity: 1})
+}
+
+func (c *Chart) line(points []point, stroke string, width float64) {
+	c.shapes = append(c.shapes, &path{points: points, stroke: stroke, width: width})
+}
+
+func (c *Chart) polygon(points []point, fill string, opacity float64) {
+	c.shapes = append(c.shapes, &path{points: points, fill: fill, opacity: opacity})
+}
+
+func (c *Chart) text(x, y float64, s string, size float64, color, anchor string) {
+	c.shapes = append(c.shapes, &text{x: x, y: y, s: s, size: size, color: color, anchor: anchor})
+}
+
+// textWidth is the width of s, the font of the charts being monospace
+func textWidth(s string, size float64) float64 {
+	return float64(len([]rune(s))) * size * 0.6
+}
+
+// fitText shortens s to fit in width
+func fitText(s string, size, width float64) string {
+	runes := []rune(s)
+	n := int(width / (size * 0.6))
+	if len(runes) <= n {
+		return s
+	}
+	if n <= 1 {
+		return ""
+	}
+	return string(runes[:n-1]) + "~"
+}
+
+// SVG writes the chart as an SVG image
+func (c *Chart) SVG(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="monospace">`, c.Width, c.Height, c.Width, c.Height)
+	for _, s := range c.shapes {
+		s.svg(bw)
+	}
+	bw.WriteString("</svg>\n")
+	return bw.Flush()
+}
+
+func (r *rect) svg(w io.Writer) {
+	fmt.Fprintf(w, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`, num(r.x), num(r.y), num(r.w), num(r.h), r.fill)
+}
+
+func (p *path) svg(w io.Writer) {
+	points := make([]string, len(p.points))
+	for i, pt := range p.points {
+		points[i] = num(pt.X) + "," + num(pt.Y)
+	}
+	if p.fill != "" {
+		fmt.Fprintf(w, `<polygon points="%s" fill="%s" fill-opacity="%s"/>`, strings.Join(points, " "), p.fill, num(p.opacity))
+		return
+	}
+	fmt.Fprintf(w, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%s" stroke-linejoin="round"/>`, strings.Join(points, " "), p.stroke, num(p.width))
+}
+
+func (t *text) svg(w io.Writer) {
+	// the baseline of the text is below its top by the height of capitals
+	fmt.Fprintf(w, `<text x="%s" y="%s" font-size="%s" fill="%s" text-anchor="%s">%s</text>`, num(t.x), num(t.y+t.size*0.8), num(t.size), t.color, t.anchor, html.EscapeString(t.s))
+}
+
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10)/10, 'f', -1, 64)
+}
+
+// PNG writes the chart as a PNG image
+func (c *Chart) PNG(w io.Writer) error {
+	cv := &canvas{img: image.NewRGBA(image.Rect(0, 0, c.Width, c.Height))}
+	for _, s := range c.shapes {
+		s.draw(cv)
+	}
+	return png.Encode(w, cv.img)
+}
+
+// canvas draws the shapes of charts without antialiasing
+type canvas struct {
+	img *image.RGBA
+}
+
+func (c *canvas) blend(x, y int, col color.RGBA, alpha float64) {
+	if !(image.Point{x, y}.In(c.img.Rect)) {
+		return
+	}
+	if alpha >= 1 {
+		c.img.SetRGBA(x, y, col)
+		return
+	}
+	old := c.img.RGBAAt(x, y)
+	mix := func(a, b uint8) uint8 {
+		return uint8(float64(a)*(1-alpha) + float64(b)*alpha + 0.5)
+	}
+	c.img.SetRGBA(x, y, color.RGBA{mix(old.R, col.R), mix(old.G, col.G), mix(old.B, col.B), 255})
+}
+
+func (r *rect) draw(c *canvas) {
+	col := parseColor(r.fill)
+	for y := int(math.Round(r.y)); y < int(math.Round(r.y+r.h)); y++ {
+		for x := int(math.Round(r.x)); x < int(math.Round(r.x+r.w)); x++ {
+			c.blend(x, y, col, r.opacity)
+		}
+	}
+}
+
+func (p *path) draw(c *canvas) {
+	if p.fill != "" {
+		p.fillPolygon(c)
+		return
+	}
+	col := parseColor(p.stroke)
+	radius := p.width / 2
+	for i := 1; i < len(p.points); i++ {
+		a, b := p.points[i-1], p.points[i]
+		steps := int(math.Ceil(math.Hypot(b.X-a.X, b.Y-a.Y) * 2))
+		for s := 0; s <= steps; s++ {
+			t := 0.0
+			if steps > 0 {
+				t = float64(s) / float64(steps)
+			}
+			c.dot(a.X+(b.X-a.X)*t, a.Y+(b.Y-a.Y)*t, radius, col)
+		}
+	}
+}
+
+// dot fills the disk of radius r around x, y, or the pixel of x, y for thin lines
+func (c *canvas) dot(x, y, r float64, col color.RGBA) {
+	if r <= 0.75 {
+		c.blend(int(x), int(y), col, 1)
+		return
+	}
+	for dy := math.Floor(-r); dy <= r; dy++ {
+		for dx := math.Floor(-r); dx <= r; dx++ {
+			if dx*dx+dy*dy <= r*r {
+				c.blend(int(x+dx), int(y+dy), col, 1)
+			}
+		}
+	}
+}
+
+// fillPolygon fills the polygon scanline by scanline, with the even-odd rule
+func (p *path) fillPolygon(c *canvas) {
+	col := parseColor(p.fill)
+	n := len(p.points)
+	if n < 3 {
+		return
+	}
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, pt := range p.points {
+		minY, maxY = math.Min(minY, pt.Y), math.Max(maxY, pt.Y)
+	}
+	for y := int(math.Floor(minY)); y <= int(math.Ceil(maxY)); y++ {
+		sy := float64(y) + 0.5
+		xs := []float64{}
+		for i := 0; i < n; i++ {
+			a, b := p.points[i], p.points[(i+1)%n]
+			if (a.Y <= sy && b.Y > sy) || (b.Y <= sy && a.Y > sy) {
+				xs = append(xs, a.X+(sy-a.Y)*(b.X-a.X)/(b.Y-a.Y))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			for x := int(math.Round(xs[i])); x < int(math.Round(xs[i+1])); x++ {
+				c.blend(x, y, col, p.opacity)
+			}
+		}
+	}
+}
+
+func (t *text) draw(c *canvas) {
+	col := parseColor(t.color)
+	// glyphs are scaled to advance by the width of the characters of SVG images
+	scale := int(math.Max(1, math.Round(t.size*0.6/(glyphWidth+1))))
+	advance := (glyphWidth + 1) * scale
+	runes := []rune(t.s)
+	x := int(math.Round(t.x))
+	switch t.anchor {
+	case "middle":
+		x -= len(runes) * advance / 2
+	case "end":
+		x -= len(runes) * advance
+	}
+	// glyphs are centered on the height of the text
+	y := int(math.Round(t.y + (t.size-float64(glyphHeight*scale))/2))
+	for _, r := range runes {
+		g := glyph(r)
+		for row := 0; row < glyphHeight; row++ {
+			for column := 0; column < glyphWidth; column++ {
+				if g[row]&(1<<uint(glyphWidth-1-column)) == 0 {
+					continue
+				}
+				for sy := 0; sy < scale; sy++ {
+					for sx := 0; sx < scale; sx++ {
+						c.blend(x+column*scale+sx, y+row*scale+sy, col, 1)
+					}
+				}
+			}
+		}
+		x += advance
+	}
+}
+
+// parseColor returns the color of #RRGGBB and #RGB, or gray for other colors
+func parseColor(hex string) color.RGBA {
+	s := strings.TrimPrefix(hex, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if len(s) != 6 || err != nil {
+		return color.RGBA{0x99, 0x9d, 0xab, 0xff}
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}
+}
+
+// mixColors returns the color between a and b at t, from 0 to 1
+func mixColors(a, b string, t float64) string {
+	ca, cb := parseColor(a), parseColor(b)
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x)*(1-t) + float64(y)*t + 0.5)
+	}
+	return fmt.Sprintf("#%02X%02X%02X", mix(ca.R, cb.R), mix(ca.G, cb.G), mix(ca.B, cb.B))
+}
@@ -0,0 +1,137 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Series is a series of the results of an InfluxQL query
+type Series struct {
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags"`
+	Columns []string          `json:"columns"`
+	Values  [][]interface{}   `json:"values"`
+}
+
+// Results are the series of the results of a query
+type Results []Series
+
+// ParseResults returns the series of the results of InfluxQL queries, as returned
+// by InfluxDB with times in epoch milliseconds
+func ParseResults(data []byte) (Results, error) {
+	var results []struct {
+		Series []Series `json:"series"`
+		Error  string   `json:"error"`
+	}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	series := Results{}
+	for _, r := range results {
+		if r.Error != "" {
+			return nil, fmt.Errorf("%s", r.Error)
+		}
+		series = append(series, r.Series...)
+	}
+	return series, nil
+}
+
+// line is a field of a series, with its values in time order
+type line struct {
+	label  string
+	times  []int64
+	values []float64
+}
+
+// lines returns the lines of the fields of the series of the queries; values which
+// are not numbers are skipped
+func lines(results []Results) []line {
+	ls := []line{}
+	for _, series := range results {
+		for _, s := range series {
+			timeColumn := -1
+			for i, c := range s.Columns {
+				if c == "time" {
+					timeColumn = i
+				}
+			}
+			for i, c := range s.Columns {
+				if i == timeColumn {
+					continue
+				}
+				l := line{label: seriesLabel(s, c)}
+				for j, row := range s.Values {
+					if i >= len(row) {
+						continue
+					}
+					v, ok := row[i].(float64)
+					if !ok {
+						continue
+					}
+					t := int64(j)
+					if timeColumn >= 0 && timeColumn < len(row) {
+						if ms, ok := row[timeColumn].(float64); ok {
+							t = int64(ms)
+						}
+					}
+					l.times = append(l.times, t)
+					l.values = append(l.values, v)
+				}
+				if len(l.values) > 0 {
+					ls = append(ls, l)
+				}
+			}
+		}
+	}
+	return ls
+}
+
+// seriesLabel is the label of a field of a series, as in the legends of CloudHub,
+// e.g. cpu.usage_user[host=server01]
+func seriesLabel(s Series, column string) string {
+	label := column
+	if s.Name != "" {
+		label = s.Name + "." + column
+	}
+	if len(s.Tags) == 0 {
+		return label
+	}
+	tags := []string{}
+	for k, v := range s.Tags {
+		tags = append(tags, k+"="+v)
+	}
+	sort.Strings(tags)
+	return label + "[" + strings.Join(tags, ", ") + "]"
+}
+
+// last returns the latest value of the first line
+func last(ls []line) (float64, bool) {
+	if len(ls) == 0 {
+		return 0, false
+	}
+	l, latest := ls[0], 0
+	for i, t := range l.times {
+		if t >= l.times[latest] {
+			latest = i
+		}
+	}
+	return l.values[latest], true
+}
+
+// times returns the times of the lines, in order and without duplicates
+func times(ls []line) []int64 {
+	seen := map[int64]bool{}
+	ts := []int64{}
+	for _, l := range ls {
+		for _, t := range l.times {
+			if !seen[t] {
+				seen[t] = true
+				ts = append(ts, t)
+			}
+		}
+	}
+	sort.Slice(ts, func(i, j int) bool { return ts[i] < ts[j] })
+	return ts
+}
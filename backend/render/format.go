@@ -0,0 +1,137 @@
+package render
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// defaultTimeFormat is the time format of the tables of CloudHub
+const defaultTimeFormat = "MM/DD/YYYY HH:mm:ss"
+
+// momentTokens are the tokens of the time formats of moment.js, used by the cells
+// of CloudHub, and their layouts in Go; longer tokens go first
+var momentTokens = []struct {
+	token, layout string
+}{
+	{"YYYY", "2006"},
+	{"YY", "06"},
+	{"MMMM", "January"},
+	{"MMM", "Jan"},
+	{"MM", "01"},
+	{"M", "1"},
+	{"DD", "02"},
+	{"D", "2"},
+	{"dddd", "Monday"},
+	{"ddd", "Mon"},
+	{"HH", "15"},
+	{"hh", "03"},
+	{"h", "3"},
+	{"mm", "04"},
+	{"m", "4"},
+	{"ss", "05"},
+	{"s", "5"},
+	{"SSS", "000"},
+	{"A", "PM"},
+	{"a", "pm"},
+	{"ZZ", "-0700"},
+	{"Z", "-07:00"},
+}
+
+// timeLayout returns the Go layout of a moment.js time format
+func timeLayout(format string) string {
+	if format == "" {
+		format = defaultTimeFormat
+	}
+	var b strings.Builder
+	for i := 0; i < len(format); {
+		matched := false
+		for _, t := range momentTokens {
+			if strings.HasPrefix(format[i:], t.token) {
+				b.WriteString(t.layout)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			b.WriteByte(format[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// formatTime formats the time in epoch milliseconds with the moment.js format
+func formatTime(ms int64, format string, loc *time.Location) string {
+	return time.Unix(0, ms*int64(time.Millisecond)).In(loc).Format(timeLayout(format))
+}
+
+// unitPrefixes are the prefixes of the values of axes in base 10 and 2
+var unitPrefixes = []string{"", "k", "M", "G", "T", "P"}
+
+// formatValue formats v with the prefix, suffix and base of the axis, and the
+// decimal places of the cell
+func formatValue(v float64, axis cloudhub.Axis, dp cloudhub.DecimalPlaces) string {
+	unit := ""
+	step := 0.0
+	switch axis.Base {
+	case "10":
+		step = 1000
+	case "2":
+		step = 1024
+	}
+	if step > 0 {
+		for i := 1; i < len(unitPrefixes) && math.Abs(v) >= step; i++ {
+			v /= step
+			unit = unitPrefixes[i]
+		}
+		if axis.Base == "2" && unit != "" {
+			unit = strings.ToUpper(unit)
+		}
+	}
+	return axis.Prefix + formatNumber(v, dp) + unit + axis.Suffix
+}
+
+// formatNumber formats v with the enforced decimal places, or else with at most
+// two decimals
+func formatNumber(v float64, dp cloudhub.DecimalPlaces) string {
+	if dp.IsEnforced {
+		return strconv.FormatFloat(v, 'f', int(dp.Digits), 64)
+	}
+	return strconv.FormatFloat(math.Round(v*100)/100, 'f', -1, 64)
+}
+
+// niceTicks returns about n ticks of round values covering min to max
+func niceTicks(min, max float64, n int) []float64 {
+	if max <= min {
+		return []float64{min}
+	}
+	step := niceNumber((max - min) / float64(n-1))
+	ticks := []float64{}
+	for t := math.Ceil(min/step) * step; t <= max+step/1e6; t += step {
+		// avoid -0 and the rounding errors of the steps
+		ticks = append(ticks, math.Round(t/step)*step+0)
+	}
+	return ticks
+}
+
+// niceNumber returns a round number close to x: 1, 2 or 5 times a power of 10
+func niceNumber(x float64) float64 {
+	exp := math.Floor(math.Log10(x))
+	f := x / math.Pow(10, exp)
+	switch {
+	case f < 1.5:
+		f = 1
+	case f < 3:
+		f = 2
+	case f < 7:
+		f = 5
+	default:
+		f = 10
+	}
+	return f * math.Pow(10, exp)
+}
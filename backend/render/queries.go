@@ -0,0 +1,90 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// regexLiteral matches the regexes of the conditions of InfluxQL queries, where
+// template variables are replaced without quotes
+var regexLiteral = regexp.MustCompile(`(=~|!~)\s*/(?:[^/\\]|\\.)*/`)
+
+// Query returns the InfluxQL query of a cell for the time range, as CloudHub runs
+// it: the time range, the interval of points of a chart of the width and the
+// selected values of the templates of the dashboard replace the template variables.
+func Query(command string, templates []cloudhub.Template, lower, upper time.Time, width int) string {
+	vars := [][2]string{
+		{":dashboardTime:", quoteTime(lower)},
+		{":upperDashboardTime:", quoteTime(upper)},
+		{":interval:", interval(lower, upper, width)},
+	}
+	for _, t := range templates {
+		if v, ok := selectedValue(t); ok {
+			vars = append(vars, [2]string{t.Var, v.Value})
+		}
+	}
+	// longer variables go first, as variables may contain others, e.g. :host: and :hosts:
+	sort.SliceStable(vars, func(i, j int) bool { return len(vars[i][0]) > len(vars[j][0]) })
+
+	types := map[string]string{}
+	for _, t := range templates {
+		if v, ok := selectedValue(t); ok {
+			types[t.Var] = v.Type
+		}
+	}
+	command = regexLiteral.ReplaceAllStringFunc(command, func(regex string) string {
+		for _, v := range vars {
+			if types[v[0]] != "" {
+				regex = strings.Replace(regex, v[0], v[1], -1)
+			}
+		}
+		return regex
+	})
+	for _, v := range vars {
+		value := v[1]
+		switch types[v[0]] {
+		case "tagValue":
+			value = "'" + strings.Replace(value, "'", `\'`, -1) + "'"
+		case "fieldKey", "tagKey", "measurement", "database":
+			value = `"` + strings.Replace(value, `"`, `\"`, -1) + `"`
+		}
+		command = strings.Replace(command, v[0], value, -1)
+	}
+	return command
+}
+
+// selectedValue returns the selected value of the template, or its first value
+func selectedValue(t cloudhub.Template) (cloudhub.TemplateValue, bool) {
+	for _, v := range t.Values {
+		if v.Selected {
+			return v, true
+		}
+	}
+	if len(t.Values) > 0 {
+		return t.Values[0], true
+	}
+	return cloudhub.TemplateValue{}, false
+}
+
+func quoteTime(t time.Time) string {
+	return "'" + t.UTC().Format(time.RFC3339Nano) + "'"
+}
+
+// interval returns the interval of the points of a chart of the width over the
+// time range, about a point every 3 pixels as in the graphs of CloudHub
+func interval(lower, upper time.Time, width int) string {
+	points := int64(width / 3)
+	if points < 1 {
+		points = 1
+	}
+	ms := upper.Sub(lower).Milliseconds() / points
+	if ms < 1 {
+		ms = 1
+	}
+	return fmt.Sprintf("%dms", ms)
+}
@@ -0,0 +1,666 @@
+// Package render draws the cells of dashboards as SVG and PNG images out of the
+// browser, e.g. to attach graphs to alerts and chat messages.
+package render
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// Colors of the dark theme of CloudHub
+const (
+	backgroundColor = "#202028"
+	gridColor       = "#383846"
+	axisColor       = "#999DAB"
+	titleColor      = "#E7E8EB"
+	statColor       = "#00C9FF"
+)
+
+// palette are the colors of the series of graphs without scale colors
+var palette = []string{
+	"#31C0F6", "#A500A5", "#FF7E27", "#7CE490", "#F95F53",
+	"#FFD255", "#7A65F2", "#4ED8A0", "#DC4E58", "#BE2EE4",
+}
+
+const (
+	fontSize  = 11
+	titleSize = 13
+	padding   = 10
+)
+
+// Options are the size of the image and the time range of the queries
+type Options struct {
+	Width    int
+	Height   int
+	Lower    time.Time
+	Upper    time.Time
+	Location *time.Location // Location is the time zone of the times; defaults to UTC
+}
+
+// Supported reports whether cells of the type are rendered
+func Supported(cellType string) bool {
+	switch cellType {
+	case "line", "line-stacked", "line-stepplot", "line-plus-single-stat", "bar", "single-stat", "gauge", "table":
+		return true
+	}
+	return false
+}
+
+// Render draws the cell with the results of its queries
+func Render(cell cloudhub.DashboardCell, results []Results, opts Options) (*Chart, error) {
+	if !Supported(cell.Type) {
+		return nil, fmt.Errorf("cells of type %s are not rendered", cell.Type)
+	}
+	if opts.Width <= 0 || opts.Height <= 0 {
+		return nil, fmt.Errorf("invalid size %dx%d", opts.Width, opts.Height)
+	}
+	if opts.Location == nil {
+		opts.Location = time.UTC
+	}
+
+	r := &renderer{
+		cell:  cell,
+		opts:  opts,
+		chart: &Chart{Width: opts.Width, Height: opts.Height},
+		lines: lines(results),
+	}
+	r.chart.rect(0, 0, float64(opts.Width), float64(opts.Height), backgroundColor)
+	top := float64(padding)
+	if cell.Name != "" {
+		r.chart.text(padding, top, fitText(cell.Name, titleSize, float64(opts.Width-2*padding)), titleSize, titleColor, "start")
+		top += titleSize + padding
+	}
+	area := box{padding, top, float64(opts.Width - padding), float64(opts.Height - padding)}
+
+	switch cell.Type {
+	case "single-stat":
+		r.singleStat(area, true)
+	case "gauge":
+		r.gauge(area)
+	case "table":
+		r.table(results, area)
+	default:
+		if len(r.lines) == 0 {
+			r.noResults(area)
+			break
+		}
+		r.graph(area)
+		if cell.Type == "line-plus-single-stat" {
+			r.singleStat(area, false)
+		}
+	}
+	return r.chart, nil
+}
+
+// box is the area between x0, y0 and x1, y1
+type box struct {
+	x0, y0, x1, y1 float64
+}
+
+func (b box) width() float64  { return b.x1 - b.x0 }
+func (b box) height() float64 { return b.y1 - b.y0 }
+
+type renderer struct {
+	cell  cloudhub.DashboardCell
+	opts  Options
+	chart *Chart
+	lines []line
+}
+
+func (r *renderer) noResults(area box) {
+	r.chart.text((area.x0+area.x1)/2, (area.y0+area.y1)/2-fontSize, "No Results", fontSize*1.5, axisColor, "middle")
+}
+
+// colors returns the colors of the series: the scale colors of the cell, or else
+// the palette
+func (r *renderer) colors() []string {
+	colors := []string{}
+	for _, c := range r.cell.CellColors {
+		if c.Type == "scale" {
+			colors = append(colors, c.Hex)
+		}
+	}
+	if len(colors) == 0 {
+		colors = palette
+	}
+	return colors
+}
+
+// thresholds returns the colors of the cell of the types, sorted by value
+func (r *renderer) thresholds(types ...string) []cloudhub.CellColor {
+	colors := []cloudhub.CellColor{}
+	for _, c := range r.cell.CellColors {
+		for _, t := range types {
+			if c.Type == t {
+				colors = append(colors, c)
+			}
+		}
+	}
+	sort.SliceStable(colors, func(i, j int) bool { return colorValue(colors[i]) < colorValue(colors[j]) })
+	return colors
+}
+
+func colorValue(c cloudhub.CellColor) float64 {
+	v, _ := strconv.ParseFloat(c.Value, 64)
+	return v
+}
+
+// graph draws the lines, stacked lines, step plots or bars of the cell
+func (r *renderer) graph(area box) {
+	yAxis := r.cell.Axes["y"]
+	colors := r.colors()
+	legend := r.cell.Legend.Type == "static"
+
+	// the legend is beside or below the graph
+	if legend {
+		switch r.cell.Legend.Orientation {
+		case "left", "right":
+			width := 0.0
+			for _, l := range r.lines {
+				width = math.Max(width, textWidth(l.label, fontSize)+20)
+			}
+			width = math.Min(width, area.width()/3)
+			lb := box{area.x1 - width, area.y0, area.x1, area.y1}
+			if r.cell.Legend.Orientation == "left" {
+				lb = box{area.x0, area.y0, area.x0 + width, area.y1}
+				area.x0 += width + padding
+			} else {
+				area.x1 -= width + padding
+			}
+			r.legend(lb, colors, true)
+		default:
+			rows := math.Min(float64(len(r.lines)), 4)
+			height := rows * (fontSize + 4)
+			lb := box{area.x0, area.y1 - height, area.x1, area.y1}
+			if r.cell.Legend.Orientation == "top" {
+				lb = box{area.x0, area.y0, area.x1, area.y0 + height}
+				area.y0 += height + padding
+			} else {
+				area.y1 -= height + padding
+			}
+			r.legend(lb, colors, false)
+		}
+	}
+
+	ts := times(r.lines)
+	stacked := r.cell.Type == "line-stacked"
+	var stacks [][]float64
+	if stacked {
+		stacks = stackedValues(r.lines, ts)
+	}
+
+	// y range of the values, or of the bounds of the axis
+	min, max := math.Inf(1), math.Inf(-1)
+	for i, l := range r.lines {
+		values := l.values
+		if stacked {
+			values = stacks[i]
+		}
+		for _, v := range values {
+			min, max = math.Min(min, v), math.Max(max, v)
+		}
+	}
+	if r.cell.Type == "bar" || stacked {
+		min, max = math.Min(min, 0), math.Max(max, 0)
+	}
+	if len(yAxis.Bounds) == 2 {
+		if v, err := strconv.ParseFloat(yAxis.Bounds[0], 64); err == nil {
+			min = v
+		}
+		if v, err := strconv.ParseFloat(yAxis.Bounds[1], 64); err == nil {
+			max = v
+		}
+	}
+	if max <= min {
+		min, max = min-1, max+1
+	}
+	logScale := yAxis.Scale == "log" && min > 0
+	scaleY := func(v float64) float64 { return v }
+	ticks := niceTicks(min, max, 5)
+	if logScale {
+		scaleY = math.Log10
+		ticks = []float64{}
+		for e := math.Ceil(math.Log10(min)); e <= math.Floor(math.Log10(max)); e++ {
+			ticks = append(ticks, math.Pow(10, e))
+		}
+	}
+
+	// the labels of the y axis are left of the plot and the times below it
+	labelWidth := 0.0
+	for _, t := range ticks {
+		labelWidth = math.Max(labelWidth, textWidth(formatValue(t, yAxis, r.cell.DecimalPlaces), fontSize))
+	}
+	if yAxis.Label != "" {
+		r.chart.text(area.x0, area.y0, fitText(yAxis.Label, fontSize, area.width()), fontSize, axisColor, "start")
+		area.y0 += fontSize + 4
+	}
+	plot := box{area.x0 + labelWidth + 6, area.y0 + fontSize/2, area.x1, area.y1 - fontSize - 6}
+	if plot.width() < 10 || plot.height() < 10 {
+		return
+	}
+
+	lower, upper := r.timeRange(ts)
+	x := func(t int64) float64 {
+		return plot.x0 + float64(t-lower)/float64(upper-lower)*plot.width()
+	}
+	y := func(v float64) float64 {
+		if logScale && v <= 0 {
+			return plot.y1
+		}
+		f := (scaleY(v) - scaleY(min)) / (scaleY(max) - scaleY(min))
+		return plot.y1 - math.Max(0, math.Min(1, f))*plot.height()
+	}
+
+	for _, t := range ticks {
+		r.chart.line([]point{{plot.x0, y(t)}, {plot.x1, y(t)}}, gridColor, 1)
+		r.chart.text(plot.x0-6, y(t)-fontSize/2, formatValue(t, yAxis, r.cell.DecimalPlaces), fontSize, axisColor, "end")
+	}
+	r.timeAxis(plot, lower, upper, x)
+
+	switch {
+	case r.cell.Type == "bar":
+		r.bars(plot, ts, colors, x, y)
+	case stacked:
+		// areas are drawn from the top stack down, each over the one below
+		for i := len(r.lines) - 1; i >= 0; i-- {
+			color := colors[i%len(colors)]
+			points := make([]point, len(ts))
+			for j, t := range ts {
+				points[j] = point{x(t), y(stacks[i][j])}
+			}
+			r.chart.polygon(append(append([]point{}, points...), point{points[len(points)-1].X, plot.y1}, point{points[0].X, plot.y1}), color, 0.3)
+			r.chart.line(points, color, 2)
+		}
+	default:
+		for i, l := range r.lines {
+			points := []point{}
+			for j, t := range l.times {
+				p := point{x(t), y(l.values[j])}
+				if r.cell.Type == "line-stepplot" && j > 0 {
+					points = append(points, point{p.X, points[len(points)-1].Y})
+				}
+				points = append(points, p)
+			}
+			r.chart.line(points, colors[i%len(colors)], 2)
+		}
+	}
+}
+
+// timeRange returns the time range of the options, or else of the times, in
+// epoch milliseconds
+func (r *renderer) timeRange(ts []int64) (int64, int64) {
+	lower, upper := ts[0], ts[len(ts)-1]
+	if !r.opts.Lower.IsZero() && !r.opts.Upper.IsZero() {
+		lower = r.opts.Lower.UnixNano() / int64(time.Millisecond)
+		upper = r.opts.Upper.UnixNano() / int64(time.Millisecond)
+	}
+	if upper <= lower {
+		upper = lower + 1
+	}
+	return lower, upper
+}
+
+// timeAxis draws the times below the plot
+func (r *renderer) timeAxis(plot box, lower, upper int64, x func(int64) float64) {
+	format := "HH:mm"
+	switch d := time.Duration(upper-lower) * time.Millisecond; {
+	case d > 24*time.Hour:
+		format = "MM/DD HH:mm"
+	case d < 5*time.Minute:
+		format = "HH:mm:ss"
+	}
+	n := int(plot.width() / (textWidth(format, fontSize) + 30))
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i <= n; i++ {
+		t := lower + (upper-lower)*int64(i)/int64(n)
+		anchor := "middle"
+		switch i {
+		case 0:
+			anchor = "start"
+		case n:
+			anchor = "end"
+		}
+		r.chart.text(x(t), plot.y1+6, formatTime(t, format, r.opts.Location), fontSize, axisColor, anchor)
+	}
+}
+
+// bars draws the values of a time side by side
+func (r *renderer) bars(plot box, ts []int64, colors []string, x func(int64) float64, y func(float64) float64) {
+	group := plot.width() / float64(len(ts))
+	width := math.Max(1, group*0.8/float64(len(r.lines)))
+	index := map[int64]int{}
+	for i, t := range ts {
+		index[t] = i
+	}
+	zero := y(0)
+	for i, l := range r.lines {
+		for j, t := range l.times {
+			left := plot.x0 + float64(index[t])*group + group*0.1 + float64(i)*width
+			top := y(l.values[j])
+			r.chart.rect(left, math.Min(top, zero), width, math.Abs(zero-top), colors[i%len(colors)])
+		}
+	}
+}
+
+// stackedValues returns the sums of the values of the lines and the lines below
+// them at the times; lines without a value at a time add nothing
+func stackedValues(ls []line, ts []int64) [][]float64 {
+	stacks := make([][]float64, len(ls))
+	sums := make([]float64, len(ts))
+	for i, l := range ls {
+		values := map[int64]float64{}
+		for j, t := range l.times {
+			values[t] = l.values[j]
+		}
+		stacks[i] = make([]float64, len(ts))
+		for j, t := range ts {
+			sums[j] += values[t]
+			stacks[i][j] = sums[j]
+		}
+	}
+	return stacks
+}
+
+// legend draws the labels of the lines in rows, or in a column
+func (r *renderer) legend(area box, colors []string, column bool) {
+	x, y := area.x0, area.y0
+	for i, l := range r.lines {
+		label := fitText(l.label, fontSize, area.width()-16)
+		width := textWidth(label, fontSize) + 16 + padding
+		if !column && x > area.x0 && x+width > area.x1 {
+			x, y = area.x0, y+fontSize+4
+		}
+		if y+fontSize > area.y1 {
+			return
+		}
+		r.chart.rect(x, y+2, 10, fontSize-4, colors[i%len(colors)])
+		r.chart.text(x+14, y, label, fontSize, axisColor, "start")
+		if column {
+			y += fontSize + 4
+		} else {
+			x += width
+		}
+	}
+}
+
+// singleStat draws the latest value of the first series; the value alone fills
+// the area and the background, or the text, has the color of its threshold
+func (r *renderer) singleStat(area box, alone bool) {
+	v, ok := last(r.lines)
+	if !ok {
+		if alone {
+			r.noResults(area)
+		}
+		return
+	}
+	s := formatValue(v, r.cell.Axes["y"], r.cell.DecimalPlaces)
+
+	color := statColor
+	background := ""
+	for _, c := range r.thresholds("text", "background") {
+		if colorValue(c) <= v || c.ID == "base" {
+			color = c.Hex
+			background = ""
+			if c.Type == "background" {
+				background, color = c.Hex, titleColor
+			}
+		}
+	}
+	if alone && background != "" {
+		r.chart.rect(0, 0, float64(r.opts.Width), float64(r.opts.Height), background)
+		if r.cell.Name != "" {
+			r.chart.text(padding, padding, fitText(r.cell.Name, titleSize, float64(r.opts.Width-2*padding)), titleSize, titleColor, "start")
+		}
+	}
+
+	size := math.Min(area.height()*0.5, area.width()*0.8/(0.6*float64(len(s))))
+	if !alone {
+		size *= 0.6
+	}
+	r.chart.text((area.x0+area.x1)/2, (area.y0+area.y1)/2-size/2, s, size, color, "middle")
+}
+
+// gauge draws the latest value of the first series on an arc of the colors of
+// the cell, from its min to its max through its thresholds
+func (r *renderer) gauge(area box) {
+	v, ok := last(r.lines)
+	if !ok {
+		r.noResults(area)
+		return
+	}
+	colors := r.thresholds("min", "threshold", "max")
+	min, max := 0.0, 100.0
+	for _, c := range colors {
+		switch c.Type {
+		case "min":
+			min = colorValue(c)
+		case "max":
+			max = colorValue(c)
+		}
+	}
+	if max <= min {
+		max = min + 1
+	}
+	if len(colors) == 0 {
+		colors = []cloudhub.CellColor{{Type: "min", Hex: palette[0]}, {Type: "max", Hex: palette[0]}}
+	}
+
+	// the ends of the arc and their labels are below its center by about 0.7 radius
+	radius := math.Min(area.width()/2, (area.height()-fontSize-padding)/1.85) - padding
+	if radius < 10 {
+		return
+	}
+	thickness := math.Max(4, radius/8)
+	radius -= thickness / 2
+	cx, cy := (area.x0+area.x1)/2, area.y0+radius+thickness
+	// the arc turns clockwise from 225 degrees for the min to -45 degrees for the max
+	angle := func(value float64) float64 {
+		f := math.Max(0, math.Min(1, (value-min)/(max-min)))
+		return (225 - 270*f) * math.Pi / 180
+	}
+	at := func(a, rad float64) point {
+		return point{cx + rad*math.Cos(a), cy - rad*math.Sin(a)}
+	}
+
+	// each segment goes from the color of its start to the color of its end
+	for i := 0; i+1 < len(colors); i++ {
+		from, to := math.Max(min, colorValue(colors[i])), math.Min(max, colorValue(colors[i+1]))
+		if colors[i].Type == "min" {
+			from = min
+		}
+		if colors[i+1].Type == "max" {
+			to = max
+		}
+		const steps = 16
+		for s := 0; s < steps; s++ {
+			a0 := angle(from + (to-from)*float64(s)/steps)
+			a1 := angle(from + (to-from)*float64(s+1)/steps)
+			points := []point{}
+			for k := 0; k <= 4; k++ {
+				points = append(points, at(a0+(a1-a0)*float64(k)/4, radius))
+			}
+			r.chart.line(points, mixColors(colors[i].Hex, colors[i+1].Hex, (float64(s)+0.5)/steps), thickness)
+		}
+	}
+
+	axis := r.cell.Axes["y"]
+	r.chart.line([]point{{cx, cy}, at(angle(v), radius*0.85)}, titleColor, 3)
+	r.chart.text(at(angle(min), radius).X, at(angle(min), radius).Y+thickness, formatValue(min, axis, r.cell.DecimalPlaces), fontSize, axisColor, "middle")
+	r.chart.text(at(angle(max), radius).X, at(angle(max), radius).Y+thickness, formatValue(max, axis, r.cell.DecimalPlaces), fontSize, axisColor, "middle")
+	s := formatValue(v, axis, r.cell.DecimalPlaces)
+	size := math.Min(radius/3, radius*1.2/(0.6*float64(len(s))))
+	r.chart.text(cx, cy+radius*0.25, s, size, titleColor, "middle")
+}
+
+// table draws the rows of the series of the first query which fit in the area
+func (r *renderer) table(results []Results, area box) {
+	header, rows := r.tableRows(results)
+	if len(rows) == 0 {
+		r.noResults(area)
+		return
+	}
+	// the time axis of the tables of CloudHub is vertical unless set otherwise
+	if opts := r.cell.TableOptions; !opts.VerticalTimeAxis && opts != (cloudhub.TableOptions{}) {
+		header, rows = transpose(header, rows)
+	}
+
+	rowHeight := float64(fontSize + 8)
+	width := area.width() / float64(len(header))
+	draw := func(y float64, cells []string, color string) {
+		for i, c := range cells {
+			r.chart.text(area.x0+float64(i)*width+4, y+4, fitText(c, fontSize, width-8), fontSize, color, "start")
+		}
+	}
+	r.chart.rect(area.x0, area.y0, area.width(), rowHeight, gridColor)
+	draw(area.y0, header, titleColor)
+	for i, row := range rows {
+		y := area.y0 + float64(i+1)*rowHeight
+		if y+rowHeight > area.y1 {
+			break
+		}
+		draw(y, row, axisColor)
+	}
+}
+
+// tableRows returns the header and the rows of the series of the first query: the
+// tags of the series, then its columns, renamed, hidden and sorted as set by the
+// field options of the cell
+func (r *renderer) tableRows(results []Results) ([]string, [][]string) {
+	if len(results) == 0 || len(results[0]) == 0 {
+		return nil, nil
+	}
+	series := results[0]
+
+	tagKeys := []string{}
+	seen := map[string]bool{}
+	for _, s := range series {
+		for k := range s.Tags {
+			if !seen[k] {
+				seen[k] = true
+				tagKeys = append(tagKeys, k)
+			}
+		}
+	}
+	sort.Strings(tagKeys)
+
+	options := map[string]cloudhub.RenamableField{}
+	for _, f := range r.cell.FieldOptions {
+		options[f.InternalName] = f
+	}
+	option := func(s Series, column string) (cloudhub.RenamableField, bool) {
+		if f, ok := options[column]; ok {
+			return f, true
+		}
+		f, ok := options[s.Name+"."+column]
+		return f, ok
+	}
+
+	first := series[0]
+	columns := append(append([]string{}, tagKeys...), first.Columns...)
+	visible := []int{}
+	header := []string{}
+	sortColumn := -1
+	for i, c := range columns {
+		f, ok := option(first, c)
+		if ok && !f.Visible {
+			continue
+		}
+		name := c
+		if ok && f.DisplayName != "" {
+			name = f.DisplayName
+		}
+		if r.cell.TableOptions.SortBy.InternalName != "" && (c == r.cell.TableOptions.SortBy.InternalName || first.Name+"."+c == r.cell.TableOptions.SortBy.InternalName) {
+			sortColumn = len(visible)
+		}
+		visible = append(visible, i)
+		header = append(header, name)
+	}
+
+	type row struct {
+		cells []string
+		keys  []interface{}
+	}
+	rows := []row{}
+	for _, s := range series {
+		for _, values := range s.Values {
+			rw := row{}
+			for _, i := range visible {
+				var v interface{}
+				if i < len(tagKeys) {
+					v = s.Tags[tagKeys[i]]
+				} else if j := i - len(tagKeys); j < len(values) {
+					v = values[j]
+				}
+				rw.keys = append(rw.keys, v)
+				rw.cells = append(rw.cells, r.tableValue(columns[i], v))
+			}
+			rows = append(rows, rw)
+		}
+	}
+	if sortColumn >= 0 {
+		desc := r.cell.TableOptions.SortBy.Direction == "desc"
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i].keys[sortColumn], rows[j].keys[sortColumn]
+			if desc {
+				a, b = b, a
+			}
+			return less(a, b)
+		})
+	}
+
+	cells := make([][]string, len(rows))
+	for i, rw := range rows {
+		cells[i] = rw.cells
+	}
+	return header, cells
+}
+
+func (r *renderer) tableValue(column string, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		if column == "time" {
+			return formatTime(int64(v), r.cell.TimeFormat, r.opts.Location)
+		}
+		return formatNumber(v, r.cell.DecimalPlaces)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// less orders numbers before other values, and other values as text
+func less(a, b interface{}) bool {
+	fa, aok := a.(float64)
+	fb, bok := b.(float64)
+	switch {
+	case aok && bok:
+		return fa < fb
+	case aok != bok:
+		return aok
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// transpose turns the columns of the table into rows, for tables whose time axis is
+// horizontal
+func transpose(header []string, rows [][]string) ([]string, [][]string) {
+	table := append([][]string{header}, rows...)
+	out := make([][]string, len(header))
+	for i := range header {
+		out[i] = make([]string, len(table))
+		for j, row := range table {
+			if i < len(row) {
+				out[i][j] = row[i]
+			}
+		}
+	}
+	return out[0], out[1:]
+}
@@ -0,0 +1,174 @@
+package render
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+const results = `[{"series": [{
+	"name": "cpu",
+	"tags": {"host": "web01"},
+	"columns": ["time", "usage_user", "usage_system"],
+	"values": [[1700000000000, 10.5, 3], [1700000060000, 22, 4], [1700000120000, 35.25, null]]
+}]}]`
+
+func TestRender(t *testing.T) {
+	series, err := ParseResults([]byte(results))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lower := time.Unix(1700000000, 0)
+	opts := Options{Width: 400, Height: 200, Lower: lower, Upper: lower.Add(2 * time.Minute)}
+
+	tests := []struct {
+		name string
+		cell cloudhub.DashboardCell
+		want []string
+	}{
+		{
+			name: "line with a static legend",
+			cell: cloudhub.DashboardCell{
+				Name:   "CPU",
+				Type:   "line",
+				Axes:   map[string]cloudhub.Axis{"y": {Suffix: "%", Bounds: []string{"0", "40"}}},
+				Legend: cloudhub.Legend{Type: "static", Orientation: "bottom"},
+			},
+			want: []string{">CPU<", ">40%<", ">cpu.usage_user[host=web01]<", ">22:14:20<", "<polyline"},
+		},
+		{
+			name: "single stat with background thresholds",
+			cell: cloudhub.DashboardCell{
+				Type: "single-stat",
+				CellColors: []cloudhub.CellColor{
+					{ID: "base", Type: "background", Hex: "#00C9FF", Value: "-1000000000000000000"},
+					{ID: "1", Type: "background", Hex: "#DC4E58", Value: "30"},
+				},
+				DecimalPlaces: cloudhub.DecimalPlaces{IsEnforced: true, Digits: 1},
+			},
+			want: []string{`fill="#DC4E58"`, ">35.2<"},
+		},
+		{
+			name: "gauge",
+			cell: cloudhub.DashboardCell{
+				Type: "gauge",
+				CellColors: []cloudhub.CellColor{
+					{Type: "min", Hex: "#00C9FF", Value: "0"},
+					{Type: "max", Hex: "#DC4E58", Value: "50"},
+				},
+			},
+			want: []string{">0<", ">50<", ">35.25<"},
+		},
+		{
+			name: "table with renamed and hidden fields",
+			cell: cloudhub.DashboardCell{
+				Type: "table",
+				FieldOptions: []cloudhub.RenamableField{
+					{InternalName: "time", DisplayName: "Time", Visible: true},
+					{InternalName: "cpu.usage_user", DisplayName: "User", Visible: true},
+					{InternalName: "cpu.usage_system", Visible: false},
+				},
+				TableOptions: cloudhub.TableOptions{
+					VerticalTimeAxis: true,
+					SortBy:           cloudhub.RenamableField{InternalName: "cpu.usage_user", Direction: "desc"},
+				},
+				TimeFormat: "YYYY-MM-DD HH:mm",
+			},
+			want: []string{">host<", ">Time<", ">User<", ">2023-11-14 22:15<", ">35.25<"},
+		},
+		{
+			name: "bar without results",
+			cell: cloudhub.DashboardCell{Type: "bar"},
+			want: []string{">No Results<"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := []Results{series}
+			if tt.cell.Type == "bar" {
+				data = []Results{{}}
+			}
+			c, err := Render(tt.cell, data, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var svg bytes.Buffer
+			if err := c.SVG(&svg); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(svg.String(), want) {
+					t.Errorf("SVG() does not contain %s:\n%s", want, svg.String())
+				}
+			}
+			if strings.Contains(svg.String(), "usage_system") && tt.cell.Type == "table" {
+				t.Errorf("SVG() contains the hidden field usage_system")
+			}
+
+			var b bytes.Buffer
+			if err := c.PNG(&b); err != nil {
+				t.Fatal(err)
+			}
+			img, err := png.Decode(&b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if size := img.Bounds().Size(); size.X != 400 || size.Y != 200 {
+				t.Errorf("PNG() size = %v", size)
+			}
+		})
+	}
+
+	if _, err := Render(cloudhub.DashboardCell{Type: "note"}, nil, opts); err == nil {
+		t.Error("Render() rendered a note")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	lower := time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC)
+	templates := []cloudhub.Template{
+		{
+			TemplateVar: cloudhub.TemplateVar{Var: ":host:", Values: []cloudhub.TemplateValue{
+				{Value: "web01", Type: "tagValue"},
+				{Value: "web02", Type: "tagValue", Selected: true},
+			}},
+		},
+		{
+			TemplateVar: cloudhub.TemplateVar{Var: ":field:", Values: []cloudhub.TemplateValue{{Value: "usage_user", Type: "fieldKey"}}},
+		},
+	}
+	got := Query(
+		`SELECT mean(:field:) FROM "telegraf".."cpu" WHERE "host" = :host: AND "cpu" =~ /:host:/ AND time > :dashboardTime: AND time < :upperDashboardTime: GROUP BY time(:interval:)`,
+		templates, lower, lower.Add(time.Hour), 600,
+	)
+	want := `SELECT mean("usage_user") FROM "telegraf".."cpu" WHERE "host" = 'web02' AND "cpu" =~ /web02/ AND time > '2023-11-14T22:00:00Z' AND time < '2023-11-14T23:00:00Z' GROUP BY time(18000ms)`
+	if got != want {
+		t.Errorf("Query() = %s, want %s", got, want)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		v    float64
+		axis cloudhub.Axis
+		dp   cloudhub.DecimalPlaces
+		want string
+	}{
+		{1.2345, cloudhub.Axis{}, cloudhub.DecimalPlaces{}, "1.23"},
+		{1.2345, cloudhub.Axis{Prefix: "$"}, cloudhub.DecimalPlaces{IsEnforced: true, Digits: 3}, "$1.234"},
+		{2500000, cloudhub.Axis{Base: "10", Suffix: "B"}, cloudhub.DecimalPlaces{}, "2.5MB"},
+		{2048, cloudhub.Axis{Base: "2"}, cloudhub.DecimalPlaces{}, "2K"},
+	}
+	for _, tt := range tests {
+		if got := formatValue(tt.v, tt.axis, tt.dp); got != tt.want {
+			t.Errorf("formatValue(%v) = %s, want %s", tt.v, got, tt.want)
+		}
+	}
+	if got := timeLayout("MM/DD/YYYY HH:mm:ss.SSS"); got != "01/02/2006 15:04:05.000" {
+		t.Errorf("timeLayout() = %s", got)
+	}
+}
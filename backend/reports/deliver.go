@@ -0,0 +1,126 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Attachment is a rendered report: the file sent by e-mail or posted to webhooks
+type Attachment struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+// Mailer sends reports by e-mail through an SMTP server
+type Mailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Enabled returns true if an SMTP server is configured
+func (m *Mailer) Enabled() bool {
+	return m != nil && m.Host != "" && m.From != ""
+}
+
+// Send sends the attachment to the recipients in a message of the subject
+func (m *Mailer) Send(to []string, subject, body string, a Attachment) error {
+	if !m.Enabled() {
+		return fmt.Errorf("no SMTP server is configured to send e-mails")
+	}
+	msg, err := message(m.From, to, subject, body, a)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+	addr := net.JoinHostPort(m.Host, strconv.Itoa(m.Port))
+	if err := smtp.SendMail(addr, auth, m.From, to, msg); err != nil {
+		return fmt.Errorf("unable to send e-mail through %s: %v", addr, err)
+	}
+	return nil
+}
+
+// message returns a multipart MIME message of a text body and an attachment
+func message(from string, to []string, subject, body string, a Attachment) ([]byte, error) {
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	qp := quotedprintable.NewWriter(text)
+	qp.Write([]byte(body))
+	qp.Close()
+
+	file, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {a.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Name})},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoded := base64.StdEncoding.EncodeToString(a.Content)
+	for len(encoded) > 76 {
+		file.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	file.Write([]byte(encoded))
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Post posts the attachment to a webhook. The name of the report is in the
+// X-Report-Name header and the name of the file in the Content-Disposition header.
+func Post(ctx context.Context, client *http.Client, url, report string, a Attachment) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(a.Content))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", a.ContentType)
+	req.Header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	req.Header.Set("X-Report-Name", mime.QEncoding.Encode("utf-8", report))
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to post to webhook: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package reports
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image"
+	"image/png"
+	"strings"
+	"time"
+)
+
+// Document is a report assembled from the images of the cells of a dashboard
+type Document struct {
+	Title    string
+	Lower    time.Time
+	Upper    time.Time
+	Location *time.Location
+	Sections []Section
+}
+
+// Section is a cell of a dashboard in a report: its name with either its PNG image
+// or a note on why it is not rendered
+type Section struct {
+	Name string
+	PNG  []byte
+	Note string
+}
+
+// Range returns the time range of the document in its location
+func (d *Document) Range() string {
+	loc := d.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	const layout = "2006-01-02 15:04 MST"
+	return d.Lower.In(loc).Format(layout) + " - " + d.Upper.In(loc).Format(layout)
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"image": func(b []byte) template.URL {
+		return template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(b))
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="margin:0;padding:24px;background:#f4f5f7;font-family:Helvetica,Arial,sans-serif;color:#292933">
+<h1 style="margin:0 0 4px;font-size:22px">{{.Title}}</h1>
+<p style="margin:0 0 24px;color:#676978;font-size:13px">{{.Range}}</p>
+{{range .Sections}}<div style="margin:0 0 24px">
+<h2 style="margin:0 0 8px;font-size:15px">{{.Name}}</h2>
+{{if .PNG}}<img src="{{image .PNG}}" alt="{{.Name}}" style="max-width:100%">{{else}}<p style="margin:0;color:#676978;font-size:13px">{{.Note}}</p>{{end}}
+</div>
+{{end}}</body>
+</html>
+`))
+
+// HTML returns the document as an HTML page with its images inlined
+func (d *Document) HTML() ([]byte, error) {
+	var b bytes.Buffer
+	if err := htmlReport.Execute(&b, d); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// Layout of the pages of PDF documents: A4 portrait, in points
+const (
+	pageWidth  = 595
+	pageHeight = 842
+	pageMargin = 40
+)
+
+// PDF returns the document as a PDF file of A4 pages, the images of the sections
+// flowing down the pages
+func (d *Document) PDF() ([]byte, error) {
+	w := &pdfWriter{}
+	var pages [][]byte
+	var images [][]byte // the XObject of each image, referenced as /Im<index>
+	var page bytes.Buffer
+	y := float64(pageHeight - pageMargin)
+	width := float64(pageWidth - 2*pageMargin)
+
+	text := func(s string, size, x float64) {
+		fmt.Fprintf(&page, "BT /F1 %g Tf %g %g Td (%s) Tj ET\n", size, x, y, pdfString(s))
+	}
+	newPage := func() {
+		pages = append(pages, append([]byte(nil), page.Bytes()...))
+		page.Reset()
+		y = pageHeight - pageMargin
+	}
+
+	y -= 20
+	text(d.Title, 20, pageMargin)
+	y -= 18
+	page.WriteString("0.4 0.41 0.47 rg\n")
+	text(d.Range(), 10, pageMargin)
+	page.WriteString("0 0 0 rg\n")
+	y -= 16
+
+	for _, s := range d.Sections {
+		height := 14.0
+		var img image.Image
+		if len(s.PNG) > 0 {
+			var err error
+			if img, err = png.Decode(bytes.NewReader(s.PNG)); err != nil {
+				return nil, fmt.Errorf("image of %s: %v", s.Name, err)
+			}
+			size := img.Bounds().Size()
+			height = width * float64(size.Y) / float64(size.X)
+		}
+		if y-24-height < pageMargin {
+			newPage()
+		}
+
+		y -= 24
+		text(s.Name, 12, pageMargin)
+		y -= 8
+		if img == nil {
+			y -= 12
+			page.WriteString("0.4 0.41 0.47 rg\n")
+			text(s.Note, 10, pageMargin)
+			page.WriteString("0 0 0 rg\n")
+			continue
+		}
+		y -= height
+		fmt.Fprintf(&page, "q %g 0 0 %g %d %g cm /Im%d Do Q\n", width, height, pageMargin, y, len(images))
+		images = append(images, pdfImage(img))
+	}
+	newPage()
+
+	// objects: 1 catalog, 2 pages, 3 font, the images, then a page and its content per page
+	w.object([]byte("<< /Type /Catalog /Pages 2 0 R >>"))
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+len(images)+2*i)
+	}
+	w.object([]byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))))
+	w.object([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"))
+	var xobjects strings.Builder
+	for i, img := range images {
+		w.object(img)
+		fmt.Fprintf(&xobjects, "/Im%d %d 0 R ", i, 4+i)
+	}
+	for i, content := range pages {
+		w.object([]byte(fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> /XObject << %s>> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, xobjects.String(), 5+len(images)+2*i,
+		)))
+		w.object(pdfStream("", content))
+	}
+	return w.bytes(), nil
+}
+
+// pdfWriter writes the numbered objects of a PDF file and its cross-reference table
+type pdfWriter struct {
+	b       bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfWriter) object(content []byte) {
+	if w.b.Len() == 0 {
+		w.b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	}
+	w.offsets = append(w.offsets, w.b.Len())
+	fmt.Fprintf(&w.b, "%d 0 obj\n", len(w.offsets))
+	w.b.Write(content)
+	w.b.WriteString("\nendobj\n")
+}
+
+func (w *pdfWriter) bytes() []byte {
+	xref := w.b.Len()
+	fmt.Fprintf(&w.b, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+	return w.b.Bytes()
+}
+
+// pdfStream returns a stream object of the content, compressed
+func pdfStream(dict string, content []byte) []byte {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	zw.Write(content)
+	zw.Close()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "<< %s/Length %d /Filter /FlateDecode >>\nstream\n", dict, z.Len())
+	b.Write(z.Bytes())
+	b.WriteString("\nendstream")
+	return b.Bytes()
+}
+
+// pdfImage returns an image XObject of the RGB pixels of the image
+func pdfImage(img image.Image) []byte {
+	bounds := img.Bounds()
+	pixels := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// blend translucent pixels on white
+			r, g, b = r+0xffff-a, g+0xffff-a, b+0xffff-a
+			pixels = append(pixels, byte(r>>8), byte(g>>8), byte(b>>8))
+		}
+	}
+	dict := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 ", bounds.Dx(), bounds.Dy())
+	return pdfStream(dict, pixels)
+}
+
+// pdfString escapes a string of text of the WinAnsi encoding of the standard fonts,
+// replacing the characters it does not have
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
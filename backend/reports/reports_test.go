@@ -0,0 +1,169 @@
+package reports
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Next(t *testing.T) {
+	seoul, _ := time.LoadLocation("Asia/Seoul")
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 8 * * MON", time.Date(2023, 11, 14, 22, 0, 0, 0, time.UTC), time.Date(2023, 11, 20, 8, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2023, 11, 14, 22, 7, 30, 0, time.UTC), time.Date(2023, 11, 14, 22, 15, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 9 1-7 * 1-5", time.Date(2023, 11, 8, 0, 0, 0, 0, time.UTC), time.Date(2023, 11, 8, 9, 30, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 7 * * *", time.Date(2023, 11, 14, 21, 0, 0, 0, time.UTC).In(seoul), time.Date(2023, 11, 15, 7, 0, 0, 0, seoul)},
+		{"0 0 30 2 *", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%s) error = %v", tt.expr, err)
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Next(%s) of %s = %s, want %s", tt.from, tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * MOON", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) parsed an invalid schedule", expr)
+		}
+	}
+}
+
+func Test_parseField(t *testing.T) {
+	tests := []struct {
+		field   string
+		names   []string
+		want    uint64
+		wantErr bool
+	}{
+		{field: "*", want: 0x3ff},
+		{field: "?", want: 0x3ff},
+		{field: "3", want: 1 << 3},
+		{field: "1,3,9", want: 1<<1 | 1<<3 | 1<<9},
+		{field: "2-4", want: 1<<2 | 1<<3 | 1<<4},
+		{field: "4-4", want: 1 << 4},
+		{field: "*/3", want: 1<<0 | 1<<3 | 1<<6 | 1<<9},
+		{field: "1-7/3", want: 1<<1 | 1<<4 | 1<<7},
+		{field: "5/2", want: 1<<5 | 1<<7 | 1<<9},
+		{field: "0-1,8-9", want: 1<<0 | 1<<1 | 1<<8 | 1<<9},
+		{field: "MON-WED", names: weekdayNames, want: 1<<1 | 1<<2 | 1<<3},
+		{field: "sun,sat", names: weekdayNames, want: 1<<0 | 1<<6},
+		{field: "4-2", wantErr: true},
+		{field: "10", wantErr: true},
+		{field: "1-10", wantErr: true},
+		{field: "*/0", wantErr: true},
+		{field: "*/x", wantErr: true},
+		{field: "1-", wantErr: true},
+		{field: "", wantErr: true},
+		{field: "MON", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseField(tt.field, 0, 9, tt.names)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseField(%q) error = %v, wantErr %v", tt.field, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseField(%q) = %b, want %b", tt.field, got, tt.want)
+		}
+	}
+}
+
+// Days of month and days of week restricted both match either, as in cron
+func TestSchedule_Next_days(t *testing.T) {
+	from := time.Date(2023, 11, 14, 12, 0, 0, 0, time.UTC) // a Tuesday
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 0 13 * *", time.Date(2023, 12, 13, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * FRI", time.Date(2023, 11, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 13 * FRI", time.Date(2023, 11, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 16 * SUN", time.Date(2023, 11, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 0 ? * SUN", time.Date(2023, 11, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2023, 11, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */10 * *", time.Date(2023, 11, 21, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * MON-FRI", time.Date(2023, 11, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		s, err := ParseSchedule(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%s) error = %v", tt.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next(%s) of %s = %s, want %s", from, tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestDocument(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 80, 36))
+	for x := 0; x < 80; x++ {
+		img.Set(x, 18, color.RGBA{0, 201, 255, 255})
+	}
+	var b bytes.Buffer
+	if err := png.Encode(&b, img); err != nil {
+		t.Fatal(err)
+	}
+
+	lower := time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)
+	sections := []Section{{Name: "Notes", Note: "cells of type note are not rendered"}}
+	for i := 0; i < 5; i++ {
+		sections = append(sections, Section{Name: "CPU (user)", PNG: b.Bytes()})
+	}
+	doc := &Document{Title: "Weekly <CPU>", Lower: lower, Upper: lower.Add(24 * time.Hour), Sections: sections}
+
+	html, err := doc.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Weekly &lt;CPU&gt;", "2023-11-14 00:00 UTC - 2023-11-15 00:00 UTC", `src="data:image/png;base64,`, "cells of type note are not rendered"} {
+		if !strings.Contains(string(html), want) {
+			t.Errorf("HTML() does not contain %s", want)
+		}
+	}
+
+	pdf, err := doc.PDF()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("PDF() is not a PDF file")
+	}
+	// images of 80x36 scaled to the width of the page fit two per page
+	for _, want := range []string{"/Count 3", "/Width 80 /Height 36", "/Im4 "} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF() does not contain %s", want)
+		}
+	}
+}
+
+func TestMessage(t *testing.T) {
+	msg, err := message("cloudhub@example.com", []string{"a@example.com", "b@example.com"}, "Report: CPU", "CPU of the week", Attachment{
+		Name:        "cpu.pdf",
+		ContentType: "application/pdf",
+		Content:     bytes.Repeat([]byte{1}, 100),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"To: a@example.com, b@example.com\r\n", "Content-Disposition: attachment; filename=cpu.pdf", "multipart/mixed; boundary="} {
+		if !strings.Contains(string(msg), want) {
+			t.Errorf("message() does not contain %q:\n%s", want, msg)
+		}
+	}
+}
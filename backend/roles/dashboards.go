@@ -0,0 +1,160 @@
+package roles
+
+import (
+	"context"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ensure that DashboardsStore implements cloudhub.DashboardsStore
+var _ cloudhub.DashboardsStore = &DashboardsStore{}
+
+// DashboardsStore facade on a DashboardsStore that restricts the dashboards
+// to the ones the permissions of their folders allow to a role.
+type DashboardsStore struct {
+	store   cloudhub.DashboardsStore
+	folders cloudhub.FoldersStore
+	role    string
+}
+
+// NewDashboardsStore creates a new DashboardsStore from an existing
+// cloudhub.DashboardsStore, the folders of its dashboards and a role
+func NewDashboardsStore(s cloudhub.DashboardsStore, folders cloudhub.FoldersStore, role string) *DashboardsStore {
+	return &DashboardsStore{
+		store:   s,
+		folders: folders,
+		role:    role,
+	}
+}
+
+// permissions returns the function returning the permissions of the folders of the ID
+func (s *DashboardsStore) permissions(ctx context.Context) (func(id string) (cloudhub.FolderPermissions, bool), error) {
+	folders, err := s.folders.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(id string) (cloudhub.FolderPermissions, bool) {
+		if id == "" {
+			return cloudhub.FolderPermissions{}, true
+		}
+		for _, f := range folders {
+			if f.ID == id {
+				return FolderPermissions(folders, id), true
+			}
+		}
+		return cloudhub.FolderPermissions{}, false
+	}, nil
+}
+
+// All retrieves the dashboards of the underlying DashboardsStore the role may view.
+func (s *DashboardsStore) All(ctx context.Context) ([]cloudhub.Dashboard, error) {
+	ds, err := s.store.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	perms, err := s.permissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboards := ds[:0]
+	for _, d := range ds {
+		if p, _ := perms(d.Folder); CanView(s.role, p) {
+			dashboards = append(dashboards, d)
+		}
+	}
+
+	return dashboards, nil
+}
+
+// Page retrieves a page of the dashboards of the underlying DashboardsStore the role may view.
+func (s *DashboardsStore) Page(ctx context.Context, q cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error) {
+	perms, err := s.permissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	match := q.Match
+	q.Match = func(d cloudhub.Dashboard) bool {
+		p, _ := perms(d.Folder)
+		return CanView(s.role, p) && (match == nil || match(d))
+	}
+	return s.store.Page(ctx, q)
+}
+
+// Add creates a new Dashboard in the DashboardsStore if the role may edit the
+// dashboards of its folder.
+func (s *DashboardsStore) Add(ctx context.Context, d cloudhub.Dashboard) (cloudhub.Dashboard, error) {
+	if err := s.editable(ctx, d.Folder, true); err != nil {
+		return cloudhub.Dashboard{}, err
+	}
+
+	return s.store.Add(ctx, d)
+}
+
+// Get returns a Dashboard if the id exists and the role may view it.
+func (s *DashboardsStore) Get(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+	d, err := s.store.Get(ctx, id)
+	if err != nil {
+		return cloudhub.Dashboard{}, err
+	}
+	perms, err := s.permissions(ctx)
+	if err != nil {
+		return cloudhub.Dashboard{}, err
+	}
+
+	if p, _ := perms(d.Folder); !CanView(s.role, p) {
+		return cloudhub.Dashboard{}, cloudhub.ErrDashboardNotFound
+	}
+
+	return d, nil
+}
+
+// Update the dashboard in DashboardsStore if the role may edit the dashboards
+// of its folder, and of the folder it moves to.
+func (s *DashboardsStore) Update(ctx context.Context, d cloudhub.Dashboard) error {
+	orig, err := s.Get(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.editable(ctx, orig.Folder, false); err != nil {
+		return err
+	}
+	if d.Folder != orig.Folder {
+		if err := s.editable(ctx, d.Folder, true); err != nil {
+			return err
+		}
+	}
+
+	return s.store.Update(ctx, d)
+}
+
+// Delete the dashboard from DashboardsStore if the role may edit the dashboards of its folder.
+func (s *DashboardsStore) Delete(ctx context.Context, d cloudhub.Dashboard) error {
+	orig, err := s.Get(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.editable(ctx, orig.Folder, false); err != nil {
+		return err
+	}
+
+	return s.store.Delete(ctx, d)
+}
+
+// editable returns an error unless the role may edit the dashboards of the folder.
+// Dashboards may only be added or moved to folders which exist.
+func (s *DashboardsStore) editable(ctx context.Context, folder string, mustExist bool) error {
+	perms, err := s.permissions(ctx)
+	if err != nil {
+		return err
+	}
+	p, ok := perms(folder)
+	if !ok && mustExist {
+		return cloudhub.ErrFolderNotFound
+	}
+	if !CanEdit(s.role, p) {
+		return cloudhub.ErrFolderForbidden
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package roles
+
+import (
+	"context"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestFolderPermissions(t *testing.T) {
+	folders := []cloudhub.Folder{
+		{ID: "1", Permissions: cloudhub.FolderPermissions{View: ViewerRoleName, Edit: AdminRoleName}},
+		{ID: "2", Parent: "1", Permissions: cloudhub.FolderPermissions{Edit: EditorRoleName}},
+		{ID: "3", Parent: "2"},
+		{ID: "4", Parent: "5"},
+		{ID: "5", Parent: "4", Permissions: cloudhub.FolderPermissions{View: EditorRoleName}},
+	}
+
+	tests := []struct {
+		id   string
+		want cloudhub.FolderPermissions
+	}{
+		{"", cloudhub.FolderPermissions{}},
+		{"1", cloudhub.FolderPermissions{View: ViewerRoleName, Edit: AdminRoleName}},
+		{"2", cloudhub.FolderPermissions{View: ViewerRoleName, Edit: EditorRoleName}},
+		{"3", cloudhub.FolderPermissions{View: ViewerRoleName, Edit: EditorRoleName}},
+		{"4", cloudhub.FolderPermissions{View: EditorRoleName}},
+		{"missing", cloudhub.FolderPermissions{}},
+	}
+	for _, tt := range tests {
+		if got := FolderPermissions(folders, tt.id); got != tt.want {
+			t.Errorf("FolderPermissions(%q) = %#v, want %#v", tt.id, got, tt.want)
+		}
+	}
+
+	perms := cloudhub.FolderPermissions{View: ViewerRoleName, Edit: AdminRoleName}
+	if CanView(MemberRoleName, perms) || !CanView(ViewerRoleName, perms) {
+		t.Errorf("CanView() does not restrict the folder to viewers")
+	}
+	if CanEdit(EditorRoleName, perms) || !CanEdit(AdminRoleName, perms) {
+		t.Errorf("CanEdit() does not restrict the folder to admins")
+	}
+	if CanEdit(MemberRoleName, cloudhub.FolderPermissions{View: EditorRoleName, Edit: MemberRoleName}) {
+		t.Errorf("CanEdit() allows a role which cannot view the folder")
+	}
+}
+
+func TestDashboardsStore(t *testing.T) {
+	ctx := context.Background()
+	dashboards := []cloudhub.Dashboard{
+		{ID: 1, Name: "root"},
+		{ID: 2, Name: "shared", Folder: "1"},
+		{ID: 3, Name: "restricted", Folder: "2"},
+	}
+	var updated []cloudhub.DashboardID
+	store := &mocks.DashboardsStore{
+		AllF: func(ctx context.Context) ([]cloudhub.Dashboard, error) {
+			return append([]cloudhub.Dashboard{}, dashboards...), nil
+		},
+		GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+			for _, d := range dashboards {
+				if d.ID == id {
+					return d, nil
+				}
+			}
+			return cloudhub.Dashboard{}, cloudhub.ErrDashboardNotFound
+		},
+		UpdateF: func(ctx context.Context, d cloudhub.Dashboard) error {
+			updated = append(updated, d.ID)
+			return nil
+		},
+	}
+	folders := &mocks.FoldersStore{
+		AllF: func(ctx context.Context) ([]cloudhub.Folder, error) {
+			return []cloudhub.Folder{
+				{ID: "1", Permissions: cloudhub.FolderPermissions{Edit: AdminRoleName}},
+				{ID: "2", Parent: "1", Permissions: cloudhub.FolderPermissions{View: EditorRoleName}},
+			}, nil
+		},
+	}
+
+	viewer := NewDashboardsStore(store, folders, ViewerRoleName)
+	all, err := viewer.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].ID != 1 || all[1].ID != 2 {
+		t.Errorf("All() of a viewer = %#v", all)
+	}
+	if _, err := viewer.Get(ctx, 3); err != cloudhub.ErrDashboardNotFound {
+		t.Errorf("Get() of a restricted dashboard error = %v", err)
+	}
+
+	editor := NewDashboardsStore(store, folders, EditorRoleName)
+	if _, err := editor.Get(ctx, 3); err != nil {
+		t.Errorf("Get() of an editor error = %v", err)
+	}
+	// folder 2 inherits the edit role of folder 1
+	if err := editor.Update(ctx, cloudhub.Dashboard{ID: 3, Folder: "2"}); err != cloudhub.ErrFolderForbidden {
+		t.Errorf("Update() in a folder edited by admins error = %v", err)
+	}
+	if err := editor.Update(ctx, cloudhub.Dashboard{ID: 1, Folder: "1"}); err != cloudhub.ErrFolderForbidden {
+		t.Errorf("Update() moving to a folder edited by admins error = %v", err)
+	}
+	if err := editor.Update(ctx, cloudhub.Dashboard{ID: 1, Folder: "missing"}); err != cloudhub.ErrFolderNotFound {
+		t.Errorf("Update() moving to a missing folder error = %v", err)
+	}
+	if _, err := editor.Add(ctx, cloudhub.Dashboard{Folder: "2"}); err != cloudhub.ErrFolderForbidden {
+		t.Errorf("Add() in a folder edited by admins error = %v", err)
+	}
+
+	admin := NewDashboardsStore(store, folders, AdminRoleName)
+	if err := admin.Update(ctx, cloudhub.Dashboard{ID: 1, Folder: "2"}); err != nil {
+		t.Errorf("Update() of an admin error = %v", err)
+	}
+	if len(updated) != 1 || updated[0] != 1 {
+		t.Errorf("updated dashboards %v, want [1]", updated)
+	}
+}
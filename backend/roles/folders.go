@@ -0,0 +1,60 @@
+package roles
+
+import (
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// ranks orders the roles of the users in an organization, lowest first
+var ranks = map[string]int{
+	MemberRoleName: 1,
+	ViewerRoleName: 2,
+	EditorRoleName: 3,
+	AdminRoleName:  4,
+}
+
+// Allows returns true if role is lowest or a higher role. An empty lowest role
+// allows every role.
+func Allows(role, lowest string) bool {
+	if lowest == "" {
+		return true
+	}
+	return ranks[role] >= ranks[lowest] && ranks[role] > 0
+}
+
+// FolderPermissions returns the permissions of the folder of the ID with the
+// roles left empty inherited from its parent folders. Dashboards at the root,
+// or in folders not found, have no permissions.
+func FolderPermissions(folders []cloudhub.Folder, id string) cloudhub.FolderPermissions {
+	byID := make(map[string]cloudhub.Folder, len(folders))
+	for _, f := range folders {
+		byID[f.ID] = f
+	}
+
+	var perms cloudhub.FolderPermissions
+	seen := map[string]bool{}
+	for id != "" && !seen[id] {
+		seen[id] = true
+		f, ok := byID[id]
+		if !ok {
+			break
+		}
+		if perms.View == "" {
+			perms.View = f.Permissions.View
+		}
+		if perms.Edit == "" {
+			perms.Edit = f.Permissions.Edit
+		}
+		id = f.Parent
+	}
+	return perms
+}
+
+// CanView returns true if role may view the dashboards of a folder of the permissions
+func CanView(role string, perms cloudhub.FolderPermissions) bool {
+	return Allows(role, perms.View)
+}
+
+// CanEdit returns true if role may view and edit the dashboards of a folder of the permissions
+func CanEdit(role string, perms cloudhub.FolderPermissions) bool {
+	return CanView(role, perms) && Allows(role, perms.Edit)
+}
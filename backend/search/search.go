@@ -0,0 +1,327 @@
+// Package search is an in-memory inverted index of the text of CloudHub resources
+// supporting exact, prefix and fuzzy matching of the terms searched.
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// scores of the matches of a term of a query, before the weights of the fields
+const (
+	exactScore  = 1.0
+	prefixScore = 0.7
+	fuzzyScore  = 0.4
+
+	maxTerms      = 10  // terms of a query beyond are ignored
+	snippetLength = 120 // runes of the snippets of the hits
+)
+
+// Field is a text of a document searched
+type Field struct {
+	Name   string  // Name names the field matched in hits, e.g. "query"
+	Text   string  // Text is the text searched
+	Weight float64 // Weight multiplies the score of the matches in the field, 1 if zero
+}
+
+// Document is a resource found by searches
+type Document struct {
+	Kind         string  // Kind is the kind of resource, e.g. "dashboard" or "cell"
+	ID           string  // ID identifies the resource within its kind
+	Organization string  // Organization owns the resource, none if visible in every organization
+	Folder       string  // Folder is the folder of the dashboard of the resource, whose permissions restrict its hits
+	Title        string  // Title names the resource in hits
+	Context      string  // Context names the resource containing this one, e.g. the dashboard of a cell
+	Link         string  // Link is the location of the resource
+	Fields       []Field // Fields are the texts searched
+}
+
+// Hit is a document matching a query
+type Hit struct {
+	Document
+	Score   float64 // Score ranks the hits, higher first
+	Field   string  // Field is the name of the field matching best
+	Snippet string  // Snippet is the part of the field matching best
+}
+
+// Options filter and limit the hits of a search
+type Options struct {
+	Limit  int                      // Limit is the maximum number of hits, all of them if zero
+	Filter func(doc *Document) bool // Filter returns false for the documents not to find
+}
+
+// posting is an occurrence of a token in a field of a document
+type posting struct {
+	doc   int
+	field int
+}
+
+// Index is an immutable inverted index of documents, safe for concurrent searches
+type Index struct {
+	docs     []Document
+	postings map[string][]posting
+	tokens   []string // tokens sorted for prefix matching
+}
+
+// NewIndex indexes the documents
+func NewIndex(docs []Document) *Index {
+	idx := &Index{
+		docs:     docs,
+		postings: map[string][]posting{},
+	}
+	for d, doc := range docs {
+		for f, field := range doc.Fields {
+			for _, token := range Tokenize(field.Text) {
+				p := idx.postings[token]
+				if n := len(p); n > 0 && p[n-1].doc == d && p[n-1].field == f {
+					continue
+				}
+				idx.postings[token] = append(p, posting{doc: d, field: f})
+			}
+		}
+	}
+	idx.tokens = make([]string, 0, len(idx.postings))
+	for token := range idx.postings {
+		idx.tokens = append(idx.tokens, token)
+	}
+	sort.Strings(idx.tokens)
+	return idx
+}
+
+// Len returns the number of documents indexed
+func (idx *Index) Len() int {
+	return len(idx.docs)
+}
+
+// Search returns the documents matching every term of the query ranked by score.
+// Terms match the tokens they are equal to, prefixes of, or a few edits away from.
+func (idx *Index) Search(query string, opts Options) []Hit {
+	terms := Tokenize(query)
+	if len(terms) > maxTerms {
+		terms = terms[:maxTerms]
+	}
+	if len(terms) == 0 {
+		return []Hit{}
+	}
+
+	type match struct {
+		score float64
+		field int
+		token string
+	}
+	var matched map[int][]match // matches of the terms by document
+	for t, term := range terms {
+		best := map[int]match{}
+		for token, score := range idx.expand(term) {
+			for _, p := range idx.postings[token] {
+				if matched != nil && len(matched[p.doc]) != t {
+					continue
+				}
+				s := score * weight(idx.docs[p.doc].Fields[p.field])
+				if m, ok := best[p.doc]; !ok || s > m.score {
+					best[p.doc] = match{score: s, field: p.field, token: token}
+				}
+			}
+		}
+		next := make(map[int][]match, len(best))
+		for doc, m := range best {
+			next[doc] = append(matched[doc], m)
+		}
+		matched = next
+		if len(matched) == 0 {
+			return []Hit{}
+		}
+	}
+
+	hits := make([]Hit, 0, len(matched))
+	for d, matches := range matched {
+		doc := &idx.docs[d]
+		if opts.Filter != nil && !opts.Filter(doc) {
+			continue
+		}
+		hit := Hit{Document: *doc}
+		top := matches[0]
+		for _, m := range matches {
+			hit.Score += m.score
+			if m.score > top.score {
+				top = m
+			}
+		}
+		hit.Field = doc.Fields[top.field].Name
+		hit.Snippet = snippet(doc.Fields[top.field].Text, top.token)
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Title != hits[j].Title {
+			return hits[i].Title < hits[j].Title
+		}
+		if hits[i].Kind != hits[j].Kind {
+			return hits[i].Kind < hits[j].Kind
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if opts.Limit > 0 && len(hits) > opts.Limit {
+		hits = hits[:opts.Limit]
+	}
+	return hits
+}
+
+// expand returns the tokens indexed matching the term with the score of their match
+func (idx *Index) expand(term string) map[string]float64 {
+	res := map[string]float64{}
+	if _, ok := idx.postings[term]; ok {
+		res[term] = exactScore
+	}
+
+	// longer tokens of the same prefix score lower
+	for i := sort.SearchStrings(idx.tokens, term); i < len(idx.tokens) && strings.HasPrefix(idx.tokens[i], term); i++ {
+		token := idx.tokens[i]
+		if token != term {
+			res[token] = prefixScore * (0.5 + 0.5*float64(len(term))/float64(len(token)))
+		}
+	}
+
+	max := maxDistance(term)
+	if max == 0 {
+		return res
+	}
+	n := utf8.RuneCountInString(term)
+	for _, token := range idx.tokens {
+		if _, ok := res[token]; ok {
+			continue
+		}
+		if l := utf8.RuneCountInString(token); l < n-max || l > n+max {
+			continue
+		}
+		if d := distance(term, token, max); d <= max {
+			res[token] = fuzzyScore / float64(d)
+		}
+	}
+	return res
+}
+
+// maxDistance returns the number of edits a term tolerates in fuzzy matches. Terms
+// with digits, such as host names and addresses, do not match fuzzily.
+func maxDistance(term string) int {
+	if strings.IndexFunc(term, unicode.IsDigit) >= 0 {
+		return 0
+	}
+	switch n := utf8.RuneCountInString(term); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// distance returns the Levenshtein distance of a and b, or max+1 if it exceeds max
+func distance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		lowest := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if cur[j] < lowest {
+				lowest = cur[j]
+			}
+		}
+		if lowest > max {
+			return max + 1
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func weight(f Field) float64 {
+	if f.Weight == 0 {
+		return 1
+	}
+	return f.Weight
+}
+
+// Tokenize returns the lower case words of s. Words joined by '_' or '.', such as
+// field names and addresses, are tokens as a whole and by parts.
+func Tokenize(s string) []string {
+	var tokens []string
+	seen := map[string]bool{}
+	add := func(token string) {
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !isWordRune(r) && r != '_' && r != '.'
+	})
+	for _, word := range words {
+		word = strings.Trim(word, "_.")
+		parts := strings.FieldsFunc(word, func(r rune) bool { return r == '_' || r == '.' })
+		if len(parts) > 1 {
+			add(word)
+		}
+		for _, part := range parts {
+			add(part)
+		}
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// snippet returns the part of text around the first occurrence of token
+func snippet(text, token string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes := []rune(text)
+	if len(runes) <= snippetLength {
+		return text
+	}
+	start := 0
+	if lower := strings.ToLower(text); strings.Contains(lower, token) {
+		start = utf8.RuneCountInString(lower[:strings.Index(lower, token)]) - snippetLength/4
+		if start < 0 {
+			start = 0
+		}
+	}
+	end := start + snippetLength
+	if end > len(runes) {
+		end = len(runes)
+		start = end - snippetLength
+	}
+	res := string(runes[start:end])
+	if start > 0 {
+		res = "…" + res
+	}
+	if end < len(runes) {
+		res += "…"
+	}
+	return res
+}
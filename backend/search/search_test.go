@@ -0,0 +1,90 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize(`SELECT mean("disk_used_percent") FROM "disk" WHERE "host" = '10.0.0.1'`)
+	want := []string{"select", "mean", "disk_used_percent", "disk", "used", "percent", "from", "where", "host", "10.0.0.1", "10", "0", "1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize() = %q, want %q", got, want)
+	}
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx := NewIndex([]Document{
+		{Kind: "dashboard", ID: "1", Organization: "a", Title: "Servers", Fields: []Field{{Name: "name", Text: "Servers", Weight: 3}}},
+		{Kind: "cell", ID: "1/c1", Organization: "a", Title: "Disk usage", Context: "Servers", Fields: []Field{
+			{Name: "name", Text: "Disk usage", Weight: 2},
+			{Name: "query", Text: `SELECT mean("used_percent") FROM "disk" WHERE "host" = 'web01'`},
+		}},
+		{Kind: "cell", ID: "2/c1", Organization: "b", Title: "Disk IO", Fields: []Field{
+			{Name: "name", Text: "Disk IO", Weight: 2},
+			{Name: "query", Text: `SELECT derivative("read_bytes") FROM "diskio" WHERE "host" = 'web02'`},
+		}},
+		{Kind: "protoboard", ID: "p1", Title: "Nginx", Fields: []Field{{Name: "description", Text: "Requests and connections of the nginx servers"}}},
+	})
+	if idx.Len() != 4 {
+		t.Fatalf("Len() = %d", idx.Len())
+	}
+
+	ids := func(hits []Hit) []string {
+		res := []string{}
+		for _, h := range hits {
+			res = append(res, h.ID)
+		}
+		return res
+	}
+
+	// every term matches, the better matches first
+	if got := ids(idx.Search("disk web01", Options{})); !reflect.DeepEqual(got, []string{"1/c1"}) {
+		t.Errorf("Search(disk web01) = %v", got)
+	}
+	hits := idx.Search("disk", Options{})
+	if got := ids(hits); !reflect.DeepEqual(got, []string{"2/c1", "1/c1"}) {
+		t.Errorf("Search(disk) = %v", got)
+	}
+	if hits[1].Field != "name" || hits[1].Snippet != "Disk usage" || hits[1].Context != "Servers" {
+		t.Errorf("Search(disk) hit = %#v", hits[1])
+	}
+
+	// prefixes, the names weigh more than the descriptions
+	hits = idx.Search("serv", Options{})
+	if got := ids(hits); !reflect.DeepEqual(got, []string{"1", "p1"}) {
+		t.Errorf("Search(serv) = %v", got)
+	}
+	if got := ids(idx.Search("used_perc", Options{})); !reflect.DeepEqual(got, []string{"1/c1"}) {
+		t.Errorf("Search(used_perc) = %v", got)
+	}
+
+	// typos
+	if got := ids(idx.Search("servrs", Options{})); !reflect.DeepEqual(got, []string{"1", "p1"}) {
+		t.Errorf("Search(servrs) = %v", got)
+	}
+	if got := ids(idx.Search("dsk", Options{})); len(got) != 0 {
+		t.Errorf("Search(dsk) of a short term matched fuzzily %v", got)
+	}
+
+	// filters and limits
+	visible := Options{Filter: func(doc *Document) bool { return doc.Organization == "" || doc.Organization == "b" }}
+	if got := ids(idx.Search("servers disk", visible)); len(got) != 0 {
+		t.Errorf("Search() found documents filtered %v", got)
+	}
+	if got := ids(idx.Search("disk", Options{Limit: 1})); len(got) != 1 {
+		t.Errorf("Search() limited to 1 = %v", got)
+	}
+	if got := idx.Search("  ", Options{}); got == nil || len(got) != 0 {
+		t.Errorf("Search() of no terms = %v", got)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("a ", 100) + "disk " + strings.Repeat("b ", 100)
+	got := snippet(text, "disk")
+	if !strings.Contains(got, "disk") || !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("snippet() = %q", got)
+	}
+}
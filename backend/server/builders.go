@@ -44,7 +44,7 @@ func (builder *MultiLayoutBuilder) Build() (*multistore.Layouts, error) {
 
 // ProtoboardsBuilder is responsible for building Protoboards
 type ProtoboardsBuilder interface {
-	Build() (*multistore.Protoboards, error)
+	Build(cloudhub.ProtoboardsStore) (*multistore.Protoboards, error)
 }
 
 // MultiProtoboardsBuilder implements LayoutBuilder and will return a Layouts
@@ -54,20 +54,21 @@ type MultiProtoboardsBuilder struct {
 	ProtoboardsPath string
 }
 
-// Build will construct a Layouts of canned and db-backed personalized
-// layouts
-func (builder *MultiProtoboardsBuilder) Build() (*multistore.Protoboards, error) {
+// Build will construct a Protoboards of db-backed, filesystem and binary
+// protoboards
+func (builder *MultiProtoboardsBuilder) Build(db cloudhub.ProtoboardsStore) (*multistore.Protoboards, error) {
 	// These apps are those handled from a directory
 	filesystemPBs := filestore.NewProtoboards(builder.ProtoboardsPath, builder.UUID, builder.Logger)
 	// These apps are statically compiled into cloudhub
 	binPBs := &protoboards.BinProtoboardsStore{
 		Logger: builder.Logger,
 	}
-	// Acts as a front-end to both the bolt layouts, filesystem layouts and binary statically compiled layouts.
+	// Acts as a front-end to the db protoboards, filesystem protoboards and binary statically compiled protoboards.
 	// The idea here is that these stores form a hierarchy in which each is tried sequentially until
 	// the operation has success.  So, the database is preferred over filesystem over binary data.
 	protoboards := &multistore.Protoboards{
 		Stores: []cloudhub.ProtoboardsStore{
+			db,
 			filesystemPBs,
 			binPBs,
 		},
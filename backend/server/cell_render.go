@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/render"
+)
+
+// Size of the images of cells, by default and at most
+const (
+	defaultRenderWidth  = 800
+	defaultRenderHeight = 400
+	maxRenderSize       = 4000
+)
+
+// DashboardCellRender draws a cell of a dashboard as a PNG or SVG image, with the
+// results of its queries over a time range. The query parameters are:
+//
+//	format: png or svg; defaults to png
+//	width, height: the size of the image in pixels; defaults to 800x400
+//	lower: the start of the time range, RFC3339 or a duration before upper; defaults to 1h
+//	upper: the end of the time range, RFC3339; defaults to now
+//	tz: the time zone of the times, e.g. Asia/Seoul; defaults to UTC
+//	source: the ID of the source of the queries of templates; defaults to the default source
+//	:name:: the value to select of the template variable :name:, e.g. ?:host:=web01
+func (s *Service) DashboardCellRender(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+	format, opts, err := renderOptions(r.URL.Query(), time.Now())
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	dash, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+	cid := httprouter.GetParamFromContext(ctx, "cid")
+	var cell *cloudhub.DashboardCell
+	for i := range dash.Cells {
+		if dash.Cells[i].ID == cid {
+			cell = &dash.Cells[i]
+		}
+	}
+	if cell == nil {
+		notFound(w, cid, s.Logger)
+		return
+	}
+	if !render.Supported(cell.Type) {
+		invalidData(w, fmt.Errorf("cells of type %s are not rendered", cell.Type), s.Logger)
+		return
+	}
+
+	params := r.URL.Query()
+	templates, err := s.resolveTemplates(ctx, dash.Templates, templateSelections(params), templateSourceLink(params), opts.Lower, opts.Upper)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+
+	results, err := s.cellResults(ctx, cell, templates, opts)
+	if err == errFluxNotRendered {
+		invalidData(w, err, s.Logger)
+		return
+	} else if err == cloudhub.ErrUpstreamTimeout {
+		Error(w, http.StatusRequestTimeout, "Timeout waiting for Influx response", s.Logger)
+		return
+	} else if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+
+	chart, err := render.Render(*cell, results, opts)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	var b bytes.Buffer
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		err = chart.SVG(&b)
+	} else {
+		err = chart.PNG(&b)
+	}
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
+
+// errFluxNotRendered is returned for the cells with flux queries, which are not rendered
+var errFluxNotRendered = errors.New("flux queries are not rendered")
+
+// cellResults runs the queries of a cell over the time range of the options with
+// the values of the templates
+func (s *Service) cellResults(ctx context.Context, cell *cloudhub.DashboardCell, templates []cloudhub.Template, opts render.Options) ([]render.Results, error) {
+	results := make([]render.Results, len(cell.Queries))
+	for i, q := range cell.Queries {
+		if q.Type == "flux" {
+			return nil, errFluxNotRendered
+		}
+		command := render.Query(q.Command, templates, opts.Lower, opts.Upper, opts.Width)
+		var err error
+		if results[i], err = s.renderQuery(ctx, q.Source, command); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// renderQuery runs the query on the source of the link, or on the default source
+// of the organization for queries without source
+func (s *Service) renderQuery(ctx context.Context, link, command string) (render.Results, error) {
+	b, src, err := s.queryResults(ctx, link, command)
+	if err != nil {
+		return nil, err
+	}
+	results, err := render.ParseResults(b)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying source %d: %v", src.ID, err)
+	}
+	return results, nil
+}
+
+// queryResults returns the JSON results of the query on the source of the link, or
+// on the default source of the organization for queries without source, with times
+// in epoch milliseconds
+func (s *Service) queryResults(ctx context.Context, link, command string) ([]byte, *cloudhub.Source, error) {
+	src, err := s.querySource(ctx, link)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts, err := s.TimeSeries(src)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Unable to connect to source %d: %v", src.ID, err)
+	}
+	if err = ts.Connect(ctx, &src); err != nil {
+		return nil, nil, fmt.Errorf("Unable to connect to source %d: %v", src.ID, err)
+	}
+	res, err := ts.Query(ctx, cloudhub.Query{Command: command, Epoch: "ms"})
+	if err == cloudhub.ErrUpstreamTimeout {
+		return nil, nil, err
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("Error querying source %d: %v", src.ID, err)
+	}
+	b, err := res.MarshalJSON()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error querying source %d: %v", src.ID, err)
+	}
+	return b, &src, nil
+}
+
+// querySource returns the source of the link, or the default source of the
+// organization if the link is not to a source
+func (s *Service) querySource(ctx context.Context, link string) (cloudhub.Source, error) {
+	if id, _, ok := sourceLinkID(link); ok {
+		src, err := s.Store.Sources(ctx).Get(ctx, id)
+		if err != nil {
+			return src, fmt.Errorf("Unknown source %s of query", link)
+		}
+		return src, nil
+	}
+	sources, err := s.Store.Sources(ctx).All(ctx)
+	if err != nil || len(sources) == 0 {
+		return cloudhub.Source{}, fmt.Errorf("No source to query")
+	}
+	for _, src := range sources {
+		if src.Default {
+			return src, nil
+		}
+	}
+	return sources[0], nil
+}
+
+// renderOptions returns the format and the options of the images of cells of the
+// query parameters
+func renderOptions(params url.Values, now time.Time) (string, render.Options, error) {
+	opts := render.Options{
+		Width:    defaultRenderWidth,
+		Height:   defaultRenderHeight,
+		Upper:    now,
+		Location: time.UTC,
+	}
+	format := params.Get("format")
+	switch format {
+	case "":
+		format = "png"
+	case "png", "svg":
+	default:
+		return "", opts, fmt.Errorf("format %s is not png or svg", format)
+	}
+
+	for _, size := range []struct {
+		name  string
+		value *int
+	}{{"width", &opts.Width}, {"height", &opts.Height}} {
+		if v := params.Get(size.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 50 || n > maxRenderSize {
+				return "", opts, fmt.Errorf("%s must be a number of pixels from 50 to %d", size.name, maxRenderSize)
+			}
+			*size.value = n
+		}
+	}
+
+	var err error
+	if opts.Lower, opts.Upper, err = timeRange(params.Get("lower"), params.Get("upper"), now); err != nil {
+		return "", opts, err
+	}
+
+	if v := params.Get("tz"); v != "" {
+		loc, err := time.LoadLocation(v)
+		if err != nil {
+			return "", opts, fmt.Errorf("unknown time zone %s", v)
+		}
+		opts.Location = loc
+	}
+	return format, opts, nil
+}
+
+// timeRange parses the bounds of a time range. upper is an RFC3339 time and
+// defaults to now; lower is an RFC3339 time or a duration before upper and
+// defaults to an hour.
+func timeRange(lower, upper string, now time.Time) (time.Time, time.Time, error) {
+	u := now
+	if upper != "" {
+		t, err := time.Parse(time.RFC3339, upper)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("upper must be an RFC3339 time")
+		}
+		u = t
+	}
+	l := u.Add(-time.Hour)
+	if lower != "" {
+		if d, err := time.ParseDuration(lower); err == nil && d > 0 {
+			l = u.Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, lower); err == nil {
+			l = t
+		} else {
+			return time.Time{}, time.Time{}, fmt.Errorf("lower must be an RFC3339 time or a duration before upper")
+		}
+	}
+	if !l.Before(u) {
+		return time.Time{}, time.Time{}, fmt.Errorf("lower must be before upper")
+	}
+	return l, u, nil
+}
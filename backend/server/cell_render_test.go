@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestService_DashboardCellRender(t *testing.T) {
+	dashboard := cloudhub.Dashboard{
+		ID: 1,
+		Cells: []cloudhub.DashboardCell{
+			{
+				ID:   "c1",
+				Name: "CPU",
+				Type: "line",
+				Queries: []cloudhub.DashboardQuery{
+					{
+						Command: `SELECT mean("usage_user") FROM "telegraf".."cpu" WHERE "host" = :host: AND time > :dashboardTime: GROUP BY time(:interval:)`,
+						Source:  "/cloudhub/v1/sources/2",
+						Type:    "influxql",
+					},
+				},
+			},
+			{ID: "c2", Type: "note"},
+		},
+		Templates: []cloudhub.Template{
+			{TemplateVar: cloudhub.TemplateVar{Var: ":host:", Values: []cloudhub.TemplateValue{{Value: "web01", Type: "tagValue", Selected: true}}}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		cid         string
+		query       string
+		wantStatus  int
+		wantType    string
+		wantCommand string
+	}{
+		{
+			name:        "svg of a line cell",
+			cid:         "c1",
+			query:       "?format=svg&lower=2h&upper=2023-11-14T22:00:00Z",
+			wantStatus:  http.StatusOK,
+			wantType:    "image/svg+xml",
+			wantCommand: `SELECT mean("usage_user") FROM "telegraf".."cpu" WHERE "host" = 'web01' AND time > '2023-11-14T20:00:00Z' GROUP BY time(27067ms)`,
+		},
+		{
+			name:       "png by default",
+			cid:        "c1",
+			wantStatus: http.StatusOK,
+			wantType:   "image/png",
+		},
+		{
+			name:       "cell which is not rendered",
+			cid:        "c2",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unknown cell",
+			cid:        "c3",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "invalid format",
+			cid:        "c1",
+			query:      "?format=gif",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var command string
+			s := &Service{
+				Store: &mocks.Store{
+					DashboardsStore: &mocks.DashboardsStore{
+						GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+							return dashboard, nil
+						},
+					},
+					SourcesStore: &mocks.SourcesStore{
+						GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+							return cloudhub.Source{ID: id}, nil
+						},
+					},
+				},
+				TimeSeriesClient: &mocks.TimeSeries{
+					ConnectF: func(context.Context, *cloudhub.Source) error {
+						return nil
+					},
+					QueryF: func(ctx context.Context, q cloudhub.Query) (cloudhub.Response, error) {
+						command = q.Command
+						return mocks.NewResponse(`[{"series": [{"name": "cpu", "columns": ["time", "mean"], "values": [[1699992000000, 10], [1699995600000, 20]]}]}]`, nil), nil
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/dashboards/1/cells/"+tt.cid+"/render"+tt.query, nil)
+			r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{
+				{Key: "id", Value: "1"},
+				{Key: "cid", Value: tt.cid},
+			}))
+			s.DashboardCellRender(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("DashboardCellRender() status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("DashboardCellRender() content type = %s, want %s", got, tt.wantType)
+			}
+			if tt.wantCommand != "" && command != tt.wantCommand {
+				t.Errorf("DashboardCellRender() query = %s, want %s", command, tt.wantCommand)
+			}
+			if tt.wantType == "image/svg+xml" && !strings.Contains(w.Body.String(), ">CPU<") {
+				t.Errorf("DashboardCellRender() unexpected image %s", w.Body.String())
+			}
+		})
+	}
+}
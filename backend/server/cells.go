@@ -279,6 +279,11 @@ func (s *Service) NewDashboardCell(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.checkDashboardCells(ctx, dash.Organization, len(dash.Cells)+1); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+
 	ids := &idgen.UUID{}
 	cid, err := ids.Generate()
 	if err != nil {
@@ -289,7 +294,10 @@ func (s *Service) NewDashboardCell(w http.ResponseWriter, r *http.Request) {
 	cell.ID = cid
 
 	dash.Cells = append(dash.Cells, cell)
-	if err := s.Store.Dashboards(ctx).Update(ctx, dash); err != nil {
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), dash); err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error adding cell %s to dashboard %d: %v", cid, id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -327,6 +335,7 @@ func (s *Service) DashboardCellID(w http.ResponseWriter, r *http.Request) {
 	cid := httprouter.GetParamFromContext(ctx, "cid")
 	for _, cell := range boards.Cells {
 		if cell.ID == cid {
+			setETag(w, dash.Version)
 			encodeJSON(w, http.StatusOK, cell, s.Logger)
 			return
 		}
@@ -365,7 +374,10 @@ func (s *Service) RemoveDashboardCell(w http.ResponseWriter, r *http.Request) {
 	}
 
 	dash.Cells = append(dash.Cells[:cellid], dash.Cells[cellid+1:]...)
-	if err := s.Store.Dashboards(ctx).Update(ctx, dash); err != nil {
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), dash); err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error removing cell %s from dashboard %d: %v", cid, id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -427,8 +439,19 @@ func (s *Service) ReplaceDashboardCell(w http.ResponseWriter, r *http.Request) {
 	}
 	cell.ID = cid
 
+	if dash.Version, err = expectedVersion(r, dash.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+
 	dash.Cells[cellid] = cell
-	if err := s.Store.Dashboards(ctx).Update(ctx, dash); err != nil {
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), dash); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error updating cell %s in dashboard %d: %v", cid, id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -439,5 +462,6 @@ func (s *Service) ReplaceDashboardCell(w http.ResponseWriter, r *http.Request) {
 	s.logRegistration(ctx, "Dashboards Cells", msg)
 
 	res := newCellResponse(dash.ID, cell)
+	setETag(w, dash.Version+1)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/kv"
+)
+
+// Names of the locks and elections coordinating the CloudHub instances sharing a store
+const (
+	trashPurgeElection    = "trash-purge"
+	snapshotPurgeElection = "snapshot-purge"
+	reportsElection       = "reports"
+	collectorsLock        = "collectors"
+)
+
+// campaignRetryInterval is the time waited before campaigning again after a failed campaign
+var campaignRetryInterval = 5 * time.Second
+
+// jobLock is the lock held while a job runs
+func jobLock(id string) string {
+	return "jobs/" + id
+}
+
+// learningTaskLock is the lock held while the learning task of an organization is managed
+func learningTaskLock(org string) string {
+	return "learning-tasks/" + org
+}
+
+// localCoordinator coordinates the work within this process for a service without a Coordinator
+var localCoordinator = kv.NewLocalCoordinator()
+
+func (s *Service) coordinator() cloudhub.Coordinator {
+	if s.Coordinator == nil {
+		return localCoordinator
+	}
+	return s.Coordinator
+}
+
+// lock blocks until the named lock is held for the request. If the lock cannot be
+// taken the request is answered and ok is false.
+func (s *Service) lock(w http.ResponseWriter, r *http.Request, name string) (unlock func(), ok bool) {
+	unlock, err := s.coordinator().Lock(r.Context(), name)
+	if err != nil {
+		Error(w, http.StatusServiceUnavailable, fmt.Sprintf("Unable to acquire lock %s: %v", name, err), s.Logger)
+		return nil, false
+	}
+	return unlock, true
+}
+
+// lockAll blocks until the named locks are held for the request. The locks are
+// taken in order so that requests taking some of the same locks cannot deadlock.
+// If a lock cannot be taken the request is answered and ok is false.
+func (s *Service) lockAll(w http.ResponseWriter, r *http.Request, names ...string) (unlock func(), ok bool) {
+	names = append([]string{}, names...)
+	sort.Strings(names)
+	var unlocks []func()
+	unlock = func() {
+		for i := len(unlocks) - 1; i >= 0; i-- {
+			unlocks[i]()
+		}
+	}
+	for i, name := range names {
+		if i > 0 && name == names[i-1] {
+			continue
+		}
+		u, ok := s.lock(w, r, name)
+		if !ok {
+			unlock()
+			return nil, false
+		}
+		unlocks = append(unlocks, u)
+	}
+	return unlock, true
+}
+
+// runAsLeader runs fn while this instance is the leader of the named election
+// until ctx is done. fn must return once its context is done; if it returns
+// while leading, the leadership is given up and campaigned for again.
+func runAsLeader(ctx context.Context, coordinator cloudhub.Coordinator, name string, logger cloudhub.Logger, fn func(context.Context)) {
+	l := logger.
+		WithField("component", "coordinator").
+		WithField("election", name)
+
+	for ctx.Err() == nil {
+		leaderCtx, resign, err := coordinator.Campaign(ctx, name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			l.Error("Unable to campaign for leadership: ", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(campaignRetryInterval):
+			}
+			continue
+		}
+
+		l.Debug("Elected leader")
+		fn(leaderCtx)
+		resign()
+	}
+}
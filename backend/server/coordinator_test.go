@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+// electionCoordinator elects this instance on every campaign and counts them
+type electionCoordinator struct {
+	cloudhub.Coordinator
+	campaigns int
+	resigns   int
+}
+
+func (c *electionCoordinator) Campaign(ctx context.Context, name string) (context.Context, func(), error) {
+	c.campaigns++
+	leaderCtx, cancel := context.WithCancel(ctx)
+	return leaderCtx, func() {
+		c.resigns++
+		cancel()
+	}, nil
+}
+
+func Test_runAsLeader(t *testing.T) {
+	c := &electionCoordinator{}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	runs := 0
+	runAsLeader(ctx, c, trashPurgeElection, &mocks.TestLogger{}, func(leaderCtx context.Context) {
+		runs++
+		if leaderCtx.Err() != nil {
+			t.Error("runAsLeader() ran with a done leader context")
+		}
+		if runs == 2 {
+			cancel()
+		}
+	})
+
+	if runs != 2 || c.campaigns != 2 || c.resigns != 2 {
+		t.Errorf("runAsLeader() ran %d times with %d campaigns and %d resigns, want 2 of each", runs, c.campaigns, c.resigns)
+	}
+}
+
+func TestService_RetryJob_running(t *testing.T) {
+	s := &Service{Logger: &mocks.TestLogger{}}
+	unlock, ok, err := s.coordinator().TryLock(context.Background(), jobLock("7"))
+	if err != nil || !ok {
+		t.Fatalf("TryLock() = %v, %v", ok, err)
+	}
+	defer unlock()
+
+	if _, ok, _ := s.coordinator().TryLock(context.Background(), jobLock("7")); ok {
+		t.Error("the lock of a running job must not be acquired twice")
+	}
+}
+
+// lockCoordinator records the locks taken and fails to take the lock named fail
+type lockCoordinator struct {
+	cloudhub.Coordinator
+	fail     string
+	locked   []string
+	unlocked []string
+}
+
+func (c *lockCoordinator) Lock(ctx context.Context, name string) (func(), error) {
+	if name == c.fail {
+		return nil, fmt.Errorf("lock %s is held", name)
+	}
+	c.locked = append(c.locked, name)
+	return func() {
+		c.unlocked = append(c.unlocked, name)
+	}, nil
+}
+
+func TestService_lockAll(t *testing.T) {
+	c := &lockCoordinator{}
+	s := &Service{Coordinator: c, Logger: &mocks.TestLogger{}}
+	w := httptest.NewRecorder()
+	unlock, ok := s.lockAll(w, httptest.NewRequest("GET", "http://any.url", nil), "b", collectorsLock, "b", "a")
+	if !ok {
+		t.Fatalf("lockAll() status = %d", w.Code)
+	}
+	unlock()
+	if got := strings.Join(c.locked, ","); got != "a,b,collectors" {
+		t.Errorf("lockAll() locked %s, want a,b,collectors", got)
+	}
+	if got := strings.Join(c.unlocked, ","); got != "collectors,b,a" {
+		t.Errorf("lockAll() unlocked %s, want collectors,b,a", got)
+	}
+
+	c = &lockCoordinator{fail: "b"}
+	s.Coordinator = c
+	w = httptest.NewRecorder()
+	if _, ok := s.lockAll(w, httptest.NewRequest("GET", "http://any.url", nil), "a", "b", "c"); ok {
+		t.Fatal("lockAll() of a held lock must fail")
+	}
+	if w.Code != http.StatusServiceUnavailable || strings.Join(c.unlocked, ",") != "a" {
+		t.Errorf("lockAll() of a held lock status = %d, unlocked %v", w.Code, c.unlocked)
+	}
+}
+
+func TestService_NetworkDevices_locks(t *testing.T) {
+	store := &mocks.Store{
+		NetworkDeviceStore: &mocks.NetworkDeviceStore{
+			GetF: func(ctx context.Context, q cloudhub.NetworkDeviceQuery) (*cloudhub.NetworkDevice, error) {
+				return &cloudhub.NetworkDevice{ID: *q.ID, Organization: "1"}, nil
+			},
+		},
+		NetworkDeviceOrgStore: &mocks.NetworkDeviceOrgStore{
+			AllF: func(ctx context.Context) ([]cloudhub.NetworkDeviceOrg, error) {
+				// the device is still learned by the organization it was moved from
+				return []cloudhub.NetworkDeviceOrg{
+					{ID: "1"},
+					{ID: "2", LearnedDevicesIDs: []string{"10"}},
+					{ID: "3", LearnedDevicesIDs: []string{"11"}},
+				}, nil
+			},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		handler func(s *Service) http.HandlerFunc
+		body    string
+		want    string
+	}{
+		{
+			name:    "removing devices",
+			handler: func(s *Service) http.HandlerFunc { return s.RemoveDevices },
+			body:    `{"devices_ids": ["10"]}`,
+			want:    "collectors,learning-tasks/1",
+		},
+		{
+			name:    "learning devices",
+			handler: func(s *Service) http.HandlerFunc { return s.LearningDeviceManagement },
+			body:    `{"learning_devices": [{"device_id": "10", "is_learning": true}]}`,
+			want:    "learning-tasks/1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the last lock is held by another request
+			c := &lockCoordinator{fail: learningTaskLock("2")}
+			s := &Service{Store: store, Coordinator: c, Logger: &mocks.TestLogger{}}
+			w := httptest.NewRecorder()
+			tt.handler(s)(w, httptest.NewRequest("POST", "http://any.url", strings.NewReader(tt.body)))
+
+			if w.Code != http.StatusServiceUnavailable {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+			}
+			if got := strings.Join(c.locked, ","); got != tt.want {
+				t.Errorf("locked %s before the held lock, want %s", got, tt.want)
+			}
+			if len(c.unlocked) != len(c.locked) {
+				t.Errorf("unlocked %v, want %v", c.unlocked, c.locked)
+			}
+		})
+	}
+}
@@ -102,16 +102,30 @@ func newCSPsResponse(csps []cloudhub.CSP) *cspsResponse {
 	}
 }
 
+// cspListFields are the fields the list of CSP can be filtered and sorted by
+var cspListFields = listFields[cloudhub.CSP]{
+	"provider":     func(c cloudhub.CSP) string { return c.Provider },
+	"namespace":    func(c cloudhub.CSP) string { return c.NameSpace },
+	"organization": func(c cloudhub.CSP) string { return c.Organization },
+}
+
 // CSP returns all CSP within the store
 func (s *Service) CSP(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	csps, err := s.Store.CSP(ctx).All(ctx)
+	opts, err := newListOptions(r, cspListFields)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+
+	page, next, err := listPage(ctx, s.Store.CSP(ctx), opts, cspListFields, func(c cloudhub.CSP) string { return c.ID }, nil)
 	if err != nil {
 		Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
 		return
 	}
 
-	res := newCSPsResponse(csps)
+	res := newCSPsResponse(page)
+	setNextLink(w, r, next)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -245,7 +259,7 @@ func (s *Service) RemoveCSP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if err := s.Store.CSP(ctx).Delete(ctx, csp); err != nil {
+	if err := s.Store.CSP(ctx).Delete(trashContext(r), csp); err != nil {
 		unknownErrorWithMessage(w, err, s.Logger)
 		return
 	}
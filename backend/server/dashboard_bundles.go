@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// dashboardBundle is a self-contained export of a dashboard, which can be imported
+// into another organization or another CloudHub
+type dashboardBundle struct {
+	Dashboard cloudhub.Dashboard `json:"dashboard"`
+	// Sources describe the sources the queries of the dashboard refer to, so that
+	// they can be mapped to local sources on import
+	Sources []dashboardBundleSource `json:"sources"`
+	// Protoboard is the protoboard the dashboard was created from, if any
+	Protoboard *dashboardBundleProtoboard `json:"protoboard,omitempty"`
+}
+
+// dashboardBundleSource describes a source of a bundle, without its credentials
+type dashboardBundleSource struct {
+	ID        string `json:"id"` // ID is the ID of the source in the exported queries
+	Name      string `json:"name"`
+	Type      string `json:"type,omitempty"`
+	URL       string `json:"url,omitempty"`
+	Telegraf  string `json:"telegraf,omitempty"`
+	DefaultRP string `json:"defaultRP,omitempty"`
+}
+
+type dashboardBundleProtoboard struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// Dashboard name conflict resolutions of imports
+const (
+	conflictRename  = "rename"
+	conflictReplace = "replace"
+	conflictFail    = "fail"
+)
+
+// dashboardImportRequest imports a bundle into the current organization
+type dashboardImportRequest struct {
+	Bundle dashboardBundle `json:"bundle"`
+	// Sources maps the IDs of the sources of the bundle to the IDs of local sources.
+	// Sources which are not mapped are matched by name.
+	Sources map[string]string `json:"sources,omitempty"`
+	// Name replaces the name of the dashboard of the bundle
+	Name string `json:"name,omitempty"`
+	// Conflict resolves an existing dashboard of the same name: rename (default) the
+	// imported dashboard, replace the existing one or fail
+	Conflict string `json:"conflict,omitempty"`
+}
+
+func (r *dashboardImportRequest) Valid() error {
+	switch r.Conflict {
+	case "", conflictRename, conflictReplace, conflictFail:
+	default:
+		return fmt.Errorf("conflict must be one of %s, %s or %s", conflictRename, conflictReplace, conflictFail)
+	}
+	if r.Name == "" && r.Bundle.Dashboard.Name == "" {
+		return fmt.Errorf("name of the dashboard required on dashboard import request body")
+	}
+	return nil
+}
+
+// DashboardExport returns the dashboard as a bundle
+func (s *Service) DashboardExport(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	d, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	bundle, err := s.newDashboardBundle(ctx, d)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+	encodeJSON(w, http.StatusOK, bundle, s.Logger)
+}
+
+// newDashboardBundle describes the sources of the queries of d and the protoboard
+// d was created from
+func (s *Service) newDashboardBundle(ctx context.Context, d cloudhub.Dashboard) (*dashboardBundle, error) {
+	bundle := &dashboardBundle{
+		Dashboard: d,
+		Sources:   []dashboardBundleSource{},
+	}
+	bundle.Dashboard.ID = 0
+	bundle.Dashboard.Version = 0
+	bundle.Dashboard.Organization = ""
+
+	for _, id := range dashboardSourceIDs(d) {
+		desc := dashboardBundleSource{ID: strconv.Itoa(id)}
+		// sources deleted since are described by their ID only
+		if src, err := s.Store.Sources(ctx).Get(ctx, id); err == nil {
+			desc.Name = src.Name
+			desc.Type = src.Type
+			desc.URL = src.URL
+			desc.Telegraf = src.Telegraf
+			desc.DefaultRP = src.DefaultRP
+		}
+		bundle.Sources = append(bundle.Sources, desc)
+	}
+
+	// protoboards deleted since are described by their ID only
+	if d.Protoboard != "" {
+		bundle.Protoboard = &dashboardBundleProtoboard{ID: d.Protoboard}
+		if store := s.Store.Protoboards(ctx); store != nil {
+			if pb, err := store.Get(ctx, d.Protoboard); err == nil {
+				bundle.Protoboard.Name = pb.Meta.Name
+				bundle.Protoboard.Version = pb.Meta.Version
+			}
+		}
+	}
+	return bundle, nil
+}
+
+// dashboardSourceIDs returns the sorted IDs of the sources the queries of d refer to
+func dashboardSourceIDs(d cloudhub.Dashboard) []int {
+	seen := map[int]bool{}
+	ids := []int{}
+	for _, c := range d.Cells {
+		for _, q := range c.Queries {
+			if id, _, ok := sourceLinkID(q.Source); ok && !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// DashboardImport creates a dashboard in the current organization from a bundle
+func (s *Service) DashboardImport(w http.ResponseWriter, r *http.Request) {
+	var req dashboardImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+	if err := req.Valid(); err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	defaultOrg, err := s.Store.Organizations(ctx).DefaultOrganization(ctx)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	sources, err := s.Store.Sources(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading sources", s.Logger)
+		return
+	}
+	sourceIDs, databases, err := mapBundleSources(&req, sources)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	dashboard := req.Bundle.Dashboard
+	dashboard.ID = 0
+	dashboard.Version = 0
+	dashboard.Organization = defaultOrg.ID
+	// folders are not exported, imported dashboards land at the root
+	dashboard.Folder = ""
+	if req.Name != "" {
+		dashboard.Name = req.Name
+	}
+	remapDashboard(&dashboard, sourceIDs, databases)
+	if err := ValidDashboardRequest(&dashboard, defaultOrg.ID); err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	if err := s.checkDashboardCells(ctx, dashboard.Organization, len(dashboard.Cells)); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+
+	dashboards, err := s.Store.Dashboards(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading dashboards", s.Logger)
+		return
+	}
+	names := map[string]cloudhub.Dashboard{}
+	for _, d := range dashboards {
+		if d.Organization == defaultOrg.ID {
+			names[d.Name] = d
+		}
+	}
+
+	if existing, ok := names[dashboard.Name]; ok {
+		switch req.Conflict {
+		case conflictFail:
+			Error(w, http.StatusConflict, fmt.Sprintf("Dashboard %s already exists", dashboard.Name), s.Logger)
+			return
+		case conflictReplace:
+			s.replaceImportedDashboard(w, r, existing, dashboard)
+			return
+		default:
+			name := dashboard.Name
+			for i := 2; ; i++ {
+				dashboard.Name = fmt.Sprintf("%s (%d)", name, i)
+				if _, ok := names[dashboard.Name]; !ok {
+					break
+				}
+			}
+		}
+	}
+
+	if err := s.checkQuota(ctx, dashboard.Organization, quotaDashboards, 1); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+	if dashboard, err = s.Store.Dashboards(ctx).Add(ctx, dashboard); err != nil {
+		msg := fmt.Errorf("Error storing dashboard %v: %v", dashboard, err)
+		unknownErrorWithMessage(w, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgDashboardCreated.String(), dashboard.Name)
+	s.logRegistration(ctx, "Dashboards", msg)
+
+	res := newDashboardResponse(dashboard)
+	location(w, res.Links.Self)
+	encodeJSON(w, http.StatusCreated, res, s.Logger)
+}
+
+// replaceImportedDashboard replaces the cells and templates of the existing dashboard
+// with the imported ones
+func (s *Service) replaceImportedDashboard(w http.ResponseWriter, r *http.Request, existing, imported cloudhub.Dashboard) {
+	ctx := r.Context()
+	imported.ID = existing.ID
+	imported.Version = existing.Version
+	imported.Folder = existing.Folder
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), imported); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
+		msg := fmt.Sprintf("Error updating dashboard ID %d: %v", existing.ID, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgDashboardModified.String(), imported.Name)
+	s.logRegistration(ctx, "Dashboards", msg)
+
+	imported.Version++
+	res := newDashboardResponse(imported)
+	setETag(w, imported.Version)
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// mapBundleSources returns the local sources of the sources the queries of the bundle
+// refer to, and the databases of the bundle sources which differ from the databases
+// of their local sources. Sources are mapped by the request, or else by name.
+func mapBundleSources(req *dashboardImportRequest, local []cloudhub.Source) (map[int]int, map[string]string, error) {
+	byID := map[int]cloudhub.Source{}
+	byName := map[string][]cloudhub.Source{}
+	for _, src := range local {
+		byID[src.ID] = src
+		byName[src.Name] = append(byName[src.Name], src)
+	}
+	descs := map[string]dashboardBundleSource{}
+	for _, desc := range req.Bundle.Sources {
+		descs[desc.ID] = desc
+	}
+
+	sourceIDs := map[int]int{}
+	databases := map[string]string{}
+	unmapped := []string{}
+	for _, id := range dashboardSourceIDs(req.Bundle.Dashboard) {
+		key := strconv.Itoa(id)
+		desc := descs[key]
+
+		var src cloudhub.Source
+		if to, ok := req.Sources[key]; ok {
+			n, err := strconv.Atoi(to)
+			if err != nil {
+				return nil, nil, fmt.Errorf("source %s of the bundle is mapped to unknown source %s", key, to)
+			}
+			if src, ok = byID[n]; !ok {
+				return nil, nil, fmt.Errorf("source %s of the bundle is mapped to unknown source %s", key, to)
+			}
+		} else if matches := byName[desc.Name]; desc.Name != "" && len(matches) == 1 {
+			src = matches[0]
+		} else {
+			unmapped = append(unmapped, key)
+			continue
+		}
+
+		sourceIDs[id] = src.ID
+		if desc.Telegraf != "" && src.Telegraf != "" && desc.Telegraf != src.Telegraf {
+			databases[desc.Telegraf] = src.Telegraf
+		}
+	}
+	if len(unmapped) > 0 {
+		return nil, nil, fmt.Errorf("no local source matches the sources %v of the bundle; map them in sources", unmapped)
+	}
+	return sourceIDs, databases, nil
+}
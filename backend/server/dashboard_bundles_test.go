@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestService_DashboardExport(t *testing.T) {
+	s := &Service{
+		Store: &mocks.Store{
+			DashboardsStore: &mocks.DashboardsStore{
+				GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+					return cloudhub.Dashboard{
+						ID:           id,
+						Name:         "Web servers",
+						Organization: "1",
+						Version:      3,
+						Protoboard:   "system",
+						Cells: []cloudhub.DashboardCell{
+							{Queries: []cloudhub.DashboardQuery{{Command: "SELECT 1", Source: "/cloudhub/v1/sources/2"}}},
+							{Queries: []cloudhub.DashboardQuery{{Command: "SELECT 2", Source: "/cloudhub/v1/sources/5"}}},
+							{Queries: []cloudhub.DashboardQuery{{Command: "SELECT 3", Source: "/cloudhub/v1/sources/2"}}},
+						},
+					}, nil
+				},
+			},
+			SourcesStore: &mocks.SourcesStore{
+				GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+					if id != 2 {
+						return cloudhub.Source{}, cloudhub.ErrSourceNotFound
+					}
+					return cloudhub.Source{ID: 2, Name: "influx", URL: "http://influx:8086", Telegraf: "telegraf", Password: "secret"}, nil
+				},
+			},
+			ProtoboardsStore: &mocks.ProtoboardsStore{
+				GetF: func(ctx context.Context, id string) (cloudhub.Protoboard, error) {
+					if id != "system" {
+						return cloudhub.Protoboard{}, cloudhub.ErrProtoboardNotFound
+					}
+					return cloudhub.Protoboard{ID: "system", Meta: cloudhub.ProtoboardMeta{Name: "System", Version: "1.0"}}, nil
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/dashboards/4/export", nil)
+	r = r.WithContext(httprouter.WithParams(r.Context(), httprouter.Params{{Key: "id", Value: "4"}}))
+	s.DashboardExport(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("DashboardExport() status = %d, body %s", w.Code, w.Body.String())
+	}
+	if bytes.Contains(w.Body.Bytes(), []byte("secret")) {
+		t.Errorf("DashboardExport() must not export the credentials of sources")
+	}
+	var bundle dashboardBundle
+	if err := json.Unmarshal(w.Body.Bytes(), &bundle); err != nil {
+		t.Fatal(err)
+	}
+	if bundle.Dashboard.ID != 0 || bundle.Dashboard.Organization != "" || bundle.Dashboard.Name != "Web servers" || len(bundle.Dashboard.Cells) != 3 {
+		t.Errorf("DashboardExport() unexpected dashboard %#v", bundle.Dashboard)
+	}
+	want := []dashboardBundleSource{
+		{ID: "2", Name: "influx", URL: "http://influx:8086", Telegraf: "telegraf"},
+		{ID: "5"},
+	}
+	if fmt.Sprint(bundle.Sources) != fmt.Sprint(want) {
+		t.Errorf("DashboardExport() sources = %v, want %v", bundle.Sources, want)
+	}
+	if bundle.Protoboard == nil || bundle.Protoboard.ID != "system" || bundle.Protoboard.Name != "System" || bundle.Protoboard.Version != "1.0" {
+		t.Errorf("DashboardExport() unexpected protoboard %#v", bundle.Protoboard)
+	}
+}
+
+func TestService_DashboardImport(t *testing.T) {
+	bundle := dashboardBundle{
+		Dashboard: cloudhub.Dashboard{
+			Name: "System",
+			Cells: []cloudhub.DashboardCell{{
+				W: 4, H: 4,
+				Queries: []cloudhub.DashboardQuery{{
+					Command:     `SELECT mean("usage_user") FROM "telegraf"."autogen"."cpu"`,
+					Source:      "/cloudhub/v1/sources/2",
+					QueryConfig: cloudhub.QueryConfig{Database: "telegraf"},
+				}},
+			}},
+		},
+		Sources: []dashboardBundleSource{{ID: "2", Name: "influx", Telegraf: "telegraf"}},
+	}
+	existing := cloudhub.Dashboard{ID: 8, Name: "System", Organization: "1", Version: 2}
+
+	tests := []struct {
+		name       string
+		req        dashboardImportRequest
+		wantStatus int
+		wantSource string
+		wantName   string
+		wantCmd    string
+	}{
+		{
+			name:       "sources matched by name, conflicting name renamed",
+			req:        dashboardImportRequest{Bundle: bundle},
+			wantStatus: http.StatusCreated,
+			wantSource: "/cloudhub/v1/sources/7",
+			wantName:   "System (2)",
+			wantCmd:    `SELECT mean("usage_user") FROM "tenant"."autogen"."cpu"`,
+		},
+		{
+			name:       "sources mapped by the request",
+			req:        dashboardImportRequest{Bundle: bundle, Sources: map[string]string{"2": "9"}, Name: "Hosts"},
+			wantStatus: http.StatusCreated,
+			wantSource: "/cloudhub/v1/sources/9",
+			wantName:   "Hosts",
+			wantCmd:    `SELECT mean("usage_user") FROM "telegraf"."autogen"."cpu"`,
+		},
+		{
+			name:       "existing dashboard replaced",
+			req:        dashboardImportRequest{Bundle: bundle, Conflict: conflictReplace},
+			wantStatus: http.StatusOK,
+			wantSource: "/cloudhub/v1/sources/7",
+			wantName:   "System",
+			wantCmd:    `SELECT mean("usage_user") FROM "tenant"."autogen"."cpu"`,
+		},
+		{
+			name:       "conflicting name fails",
+			req:        dashboardImportRequest{Bundle: bundle, Conflict: conflictFail},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "unknown mapped source",
+			req:        dashboardImportRequest{Bundle: bundle, Sources: map[string]string{"2": "3"}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "unmatched source",
+			req: dashboardImportRequest{Bundle: dashboardBundle{
+				Dashboard: bundle.Dashboard,
+				Sources:   []dashboardBundleSource{{ID: "2", Name: "other"}},
+			}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid conflict resolution",
+			req:        dashboardImportRequest{Bundle: bundle, Conflict: "skip"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved *cloudhub.Dashboard
+			s := &Service{
+				Store: &mocks.Store{
+					OrganizationsStore: &mocks.OrganizationsStore{
+						DefaultOrganizationF: func(ctx context.Context) (*cloudhub.Organization, error) {
+							return &cloudhub.Organization{ID: "1"}, nil
+						},
+						GetF: func(ctx context.Context, q cloudhub.OrganizationQuery) (*cloudhub.Organization, error) {
+							return nil, cloudhub.ErrOrganizationNotFound
+						},
+					},
+					SourcesStore: &mocks.SourcesStore{
+						AllF: func(ctx context.Context) ([]cloudhub.Source, error) {
+							return []cloudhub.Source{
+								{ID: 7, Name: "influx", Telegraf: "tenant"},
+								{ID: 9, Name: "backup", Telegraf: "telegraf"},
+							}, nil
+						},
+					},
+					DashboardsStore: &mocks.DashboardsStore{
+						AllF: func(ctx context.Context) ([]cloudhub.Dashboard, error) {
+							return []cloudhub.Dashboard{existing}, nil
+						},
+						AddF: func(ctx context.Context, d cloudhub.Dashboard) (cloudhub.Dashboard, error) {
+							d.ID = 10
+							saved = &d
+							return d, nil
+						},
+						UpdateF: func(ctx context.Context, d cloudhub.Dashboard) error {
+							saved = &d
+							return nil
+						},
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			body, _ := json.Marshal(tt.req)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "http://any.url/cloudhub/v1/dashboard-imports", bytes.NewReader(body))
+			s.DashboardImport(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("DashboardImport() status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus >= 300 {
+				if saved != nil {
+					t.Errorf("DashboardImport() must not save the dashboard")
+				}
+				return
+			}
+			if saved == nil || saved.Organization != "1" || saved.Name != tt.wantName {
+				t.Fatalf("DashboardImport() unexpected dashboard %#v", saved)
+			}
+			if tt.wantStatus == http.StatusOK && saved.ID != existing.ID {
+				t.Errorf("DashboardImport() replaced dashboard ID = %d, want %d", saved.ID, existing.ID)
+			}
+			q := saved.Cells[0].Queries[0]
+			if q.Source != tt.wantSource || q.Command != tt.wantCmd {
+				t.Errorf("DashboardImport() query = %s %s, want %s %s", q.Source, q.Command, tt.wantSource, tt.wantCmd)
+			}
+		})
+	}
+	if bundle.Dashboard.Cells[0].Queries[0].Source != "/cloudhub/v1/sources/2" {
+		t.Errorf("DashboardImport() must not change the bundle")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 )
@@ -20,6 +22,10 @@ type dashboardResponse struct {
 	Templates    []templateResponse      `json:"templates"`
 	Name         string                  `json:"name"`
 	Organization string                  `json:"organization"`
+	Version      uint64                  `json:"version"`
+	Folder       string                  `json:"folder"`
+	Tags         []string                `json:"tags"`
+	Protoboard   string                  `json:"protoboard,omitempty"`
 	Links        dashboardLinks          `json:"links"`
 }
 
@@ -32,6 +38,10 @@ func newDashboardResponse(d cloudhub.Dashboard) *dashboardResponse {
 	dd := AddQueryConfigs(DashboardDefaults(d))
 	cells := newCellResponses(dd.ID, dd.Cells)
 	templates := newTemplateResponses(dd.ID, dd.Templates)
+	tags := d.Tags
+	if tags == nil {
+		tags = []string{}
+	}
 
 	return &dashboardResponse{
 		ID:           dd.ID,
@@ -39,6 +49,10 @@ func newDashboardResponse(d cloudhub.Dashboard) *dashboardResponse {
 		Cells:        cells,
 		Templates:    templates,
 		Organization: d.Organization,
+		Version:      d.Version,
+		Folder:       d.Folder,
+		Tags:         tags,
+		Protoboard:   d.Protoboard,
 		Links: dashboardLinks{
 			Self:      fmt.Sprintf("%s/%d", base, dd.ID),
 			Cells:     fmt.Sprintf("%s/%d/cells", base, dd.ID),
@@ -47,22 +61,40 @@ func newDashboardResponse(d cloudhub.Dashboard) *dashboardResponse {
 	}
 }
 
-// Dashboards returns all dashboards within the store
+// dashboardListFields are the fields the list of dashboards can be filtered and sorted by
+var dashboardListFields = listFields[cloudhub.Dashboard]{
+	"name":         func(d cloudhub.Dashboard) string { return d.Name },
+	"organization": func(d cloudhub.Dashboard) string { return d.Organization },
+	"folder":       func(d cloudhub.Dashboard) string { return d.Folder },
+}
+
+// Dashboards returns all dashboards within the store.
+// ?tag=a,b lists the dashboards tagged with one of the tags.
 func (s *Service) Dashboards(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	dashboards, err := s.Store.Dashboards(ctx).All(ctx)
+	opts, err := newListOptions(r, dashboardListFields)
 	if err != nil {
-		Error(w, http.StatusInternalServerError, "Error loading dashboards", s.Logger)
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
 		return
 	}
 
+	var tagged func(cloudhub.Dashboard) bool
+	if tags := r.URL.Query().Get("tag"); tags != "" {
+		tagged = taggedWith(strings.Split(tags, ","))
+	}
+	page, next, err := listPage(ctx, s.Store.Dashboards(ctx), opts, dashboardListFields, func(d cloudhub.Dashboard) string { return strconv.Itoa(int(d.ID)) }, tagged)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading dashboards", s.Logger)
+		return
+	}
 	res := getDashboardsResponse{
 		Dashboards: []*dashboardResponse{},
 	}
 
-	for _, dashboard := range dashboards {
+	for _, dashboard := range page {
 		res.Dashboards = append(res.Dashboards, newDashboardResponse(dashboard))
 	}
+	setNextLink(w, r, next)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -82,6 +114,7 @@ func (s *Service) DashboardID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res := newDashboardResponse(e)
+	setETag(w, e.Version)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -106,7 +139,22 @@ func (s *Service) NewDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if dashboard, err = s.Store.Dashboards(ctx).Add(r.Context(), dashboard); err != nil {
+	if err := s.checkQuota(ctx, dashboard.Organization, quotaDashboards, 1); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+	if err := s.checkDashboardCells(ctx, dashboard.Organization, len(dashboard.Cells)); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+
+	if dashboard, err = s.Store.Dashboards(ctx).Add(r.Context(), dashboard); err == cloudhub.ErrFolderNotFound {
+		invalidData(w, err, s.Logger)
+		return
+	} else if err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Errorf("Error storing dashboard %v: %v", dashboard, err)
 		unknownErrorWithMessage(w, msg, s.Logger)
 		return
@@ -136,7 +184,18 @@ func (s *Service) RemoveDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.Store.Dashboards(ctx).Delete(ctx, dashboard); err != nil {
+	if dashboard.Version, err = deleteVersion(r, dashboard.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+
+	if err := s.Store.Dashboards(ctx).Delete(trashContext(r), dashboard); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		unknownErrorWithMessage(w, err, s.Logger)
 		return
 	}
@@ -170,6 +229,15 @@ func (s *Service) ReplaceDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	req.ID = id
+	// dashboards move between folders by MoveDashboard, clients unaware of
+	// folders, tags and protoboards keep them
+	req.Folder = dashboard.Folder
+	if req.Tags == nil {
+		req.Tags = dashboard.Tags
+	}
+	if req.Protoboard == "" {
+		req.Protoboard = dashboard.Protoboard
+	}
 
 	defaultOrg, err := s.Store.Organizations(ctx).DefaultOrganization(ctx)
 	if err != nil {
@@ -182,7 +250,23 @@ func (s *Service) ReplaceDashboard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.Store.Dashboards(ctx).Update(ctx, req); err != nil {
+	if err := s.checkDashboardCells(ctx, dashboard.Organization, len(req.Cells)); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+
+	if req.Version, err = expectedVersion(r, dashboard.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), req); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error updating dashboard ID %d: %v", id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -192,11 +276,13 @@ func (s *Service) ReplaceDashboard(w http.ResponseWriter, r *http.Request) {
 	msg := fmt.Sprintf(MsgDashboardModified.String(), dashboard.Name)
 	s.logRegistration(ctx, "Dashboards", msg)
 
+	req.Version++
 	res := newDashboardResponse(req)
+	setETag(w, req.Version)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
-// UpdateDashboard completely updates either the dashboard name or the cells
+// UpdateDashboard completely updates either the dashboard name or the cells, and the tags
 func (s *Service) UpdateDashboard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	idParam, err := paramID("id", r)
@@ -220,6 +306,9 @@ func (s *Service) UpdateDashboard(w http.ResponseWriter, r *http.Request) {
 	}
 	req.ID = id
 
+	if req.Tags != nil {
+		orig.Tags = normalizeTags(req.Tags)
+	}
 	if req.Name != "" {
 		orig.Name = req.Name
 	} else if len(req.Cells) > 0 {
@@ -232,13 +321,28 @@ func (s *Service) UpdateDashboard(w http.ResponseWriter, r *http.Request) {
 			invalidData(w, err, s.Logger)
 			return
 		}
+		if err := s.checkDashboardCells(ctx, orig.Organization, len(req.Cells)); err != nil {
+			quotaExceeded(w, err, s.Logger)
+			return
+		}
 		orig.Cells = req.Cells
-	} else {
-		invalidData(w, fmt.Errorf("Update must include either name or cells"), s.Logger)
+	} else if req.Tags == nil {
+		invalidData(w, fmt.Errorf("Update must include either name, cells or tags"), s.Logger)
+		return
+	}
+
+	if orig.Version, err = expectedVersion(r, orig.Version); err != nil {
+		versionError(w, err, s.Logger)
 		return
 	}
 
-	if err := s.Store.Dashboards(ctx).Update(ctx, orig); err != nil {
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), orig); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error updating dashboard ID %d: %v", id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -248,7 +352,9 @@ func (s *Service) UpdateDashboard(w http.ResponseWriter, r *http.Request) {
 	msg := fmt.Sprintf(MsgDashboardModified.String(), orig.Name)
 	s.logRegistration(ctx, "Dashboards", msg)
 
+	orig.Version++
 	res := newDashboardResponse(orig)
+	setETag(w, orig.Version)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -268,6 +374,7 @@ func ValidDashboardRequest(d *cloudhub.Dashboard, defaultOrgID string) error {
 			return err
 		}
 	}
+	d.Tags = normalizeTags(d.Tags)
 	(*d) = DashboardDefaults(*d)
 	return nil
 }
@@ -279,6 +386,9 @@ func DashboardDefaults(d cloudhub.Dashboard) (newDash cloudhub.Dashboard) {
 	newDash.Templates = d.Templates
 	newDash.Name = d.Name
 	newDash.Organization = d.Organization
+	newDash.Folder = d.Folder
+	newDash.Tags = d.Tags
+	newDash.Protoboard = d.Protoboard
 	newDash.Cells = make([]cloudhub.DashboardCell, len(d.Cells))
 
 	for i, c := range d.Cells {
@@ -302,3 +412,31 @@ func AddQueryConfigs(d cloudhub.Dashboard) (newDash cloudhub.Dashboard) {
 	}
 	return
 }
+
+// normalizeTags trims the tags and removes the empty and duplicate ones
+func normalizeTags(tags []string) []string {
+	var res []string
+	seen := map[string]bool{}
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[t] {
+			seen[t] = true
+			res = append(res, t)
+		}
+	}
+	return res
+}
+
+// taggedWith returns whether a dashboard is tagged with one of the tags
+func taggedWith(tags []string) func(cloudhub.Dashboard) bool {
+	return func(d cloudhub.Dashboard) bool {
+		for _, t := range d.Tags {
+			for _, tag := range tags {
+				if t == strings.TrimSpace(tag) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+}
@@ -362,6 +362,7 @@ func Test_newDashboardResponse(t *testing.T) {
 						},
 					},
 				},
+				Tags: []string{},
 				Links: dashboardLinks{
 					Self:      "/cloudhub/v1/dashboards/0",
 					Cells:     "/cloudhub/v1/dashboards/0/cells",
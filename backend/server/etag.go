@@ -0,0 +1,81 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// etag formats the version of a resource as a strong entity tag
+func etag(version uint64) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// setETag sets the ETag header of the response to the version of a resource
+func setETag(w http.ResponseWriter, version uint64) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// ifMatch returns the versions listed in the If-Match header of the request.
+// ok is false when the header is absent or matches any version.
+func ifMatch(r *http.Request) (versions []uint64, ok bool, err error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, false, nil
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		v, err := strconv.ParseUint(strings.Trim(tag, `"`), 10, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid If-Match entity tag %s", tag)
+		}
+		versions = append(versions, v)
+	}
+	return versions, true, nil
+}
+
+// expectedVersion returns the version an update or delete of a resource whose
+// current version is `current` must compare against in the store. It returns
+// cloudhub.ErrVersionConflict when the If-Match header does not match current.
+// Without If-Match, the update still fails if the resource changes after it was read.
+func expectedVersion(r *http.Request, current uint64) (uint64, error) {
+	versions, ok, err := ifMatch(r)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return current, nil
+	}
+	for _, v := range versions {
+		if v == current {
+			return current, nil
+		}
+	}
+	return 0, cloudhub.ErrVersionConflict
+}
+
+// preconditionFailed writes a 412 response for an update of a modified resource
+func preconditionFailed(w http.ResponseWriter, logger cloudhub.Logger) {
+	Error(w, http.StatusPreconditionFailed, cloudhub.ErrVersionConflict.Error(), logger)
+}
+
+// versionError writes the response for an error returned by expectedVersion
+func versionError(w http.ResponseWriter, err error, logger cloudhub.Logger) {
+	if err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, logger)
+		return
+	}
+	invalidData(w, err, logger)
+}
+
+// deleteVersion is expectedVersion for deletes, which are unconditional without If-Match
+func deleteVersion(r *http.Request, current uint64) (uint64, error) {
+	if _, ok, err := ifMatch(r); err != nil || !ok {
+		return 0, err
+	}
+	return expectedVersion(r, current)
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func Test_expectedVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		current uint64
+		want    uint64
+		wantErr error
+	}{
+		{
+			name:    "no If-Match uses the current version",
+			current: 3,
+			want:    3,
+		},
+		{
+			name:    "wildcard uses the current version",
+			ifMatch: "*",
+			current: 3,
+			want:    3,
+		},
+		{
+			name:    "matching entity tag",
+			ifMatch: `"3"`,
+			current: 3,
+			want:    3,
+		},
+		{
+			name:    "one of several weak entity tags",
+			ifMatch: `W/"2", W/"3"`,
+			current: 3,
+			want:    3,
+		},
+		{
+			name:    "stale entity tag",
+			ifMatch: `"2"`,
+			current: 3,
+			wantErr: cloudhub.ErrVersionConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("PUT", "http://any.url", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			got, err := expectedVersion(r, tt.current)
+			if err != tt.wantErr {
+				t.Fatalf("expectedVersion() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("expectedVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	r := httptest.NewRequest("PUT", "http://any.url", nil)
+	r.Header.Set("If-Match", `"abc"`)
+	if _, err := expectedVersion(r, 1); err == nil || err == cloudhub.ErrVersionConflict {
+		t.Errorf("expectedVersion() expected a malformed entity tag error, got %v", err)
+	}
+}
+
+func Test_deleteVersion(t *testing.T) {
+	r := httptest.NewRequest("DELETE", "http://any.url", nil)
+	if got, err := deleteVersion(r, 4); err != nil || got != 0 {
+		t.Errorf("deleteVersion() without If-Match = %d, %v, want 0, nil", got, err)
+	}
+
+	r.Header.Set("If-Match", `"4"`)
+	if got, err := deleteVersion(r, 4); err != nil || got != 4 {
+		t.Errorf("deleteVersion() = %d, %v, want 4, nil", got, err)
+	}
+}
@@ -0,0 +1,260 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/influx"
+	"github.com/snetsystems/cloudhub/backend/querycache"
+)
+
+const maxExportChunkSize = 100000
+
+// exportTimeout bounds the time to stream an export. Exports are not limited in
+// points and series, so they are given longer than the queries of any role.
+const exportTimeout = 10 * time.Minute
+
+// fluxWrite matches the Flux functions writing data, e.g. to() and experimental.to()
+var fluxWrite = regexp.MustCompile(`\bto\s*\(`)
+
+// exportContentTypes are the content types of the export formats
+var exportContentTypes = map[string]string{
+	influx.ExportCSV:          "text/csv; charset=utf-8",
+	influx.ExportNDJSON:       "application/x-ndjson",
+	influx.ExportLineProtocol: "text/plain; charset=utf-8",
+}
+
+// seriesStreamer streams the results of queries in chunks, as influx.Client does
+type seriesStreamer interface {
+	QueryStream(ctx context.Context, q cloudhub.Query, chunkSize int, fn func(*influx.Series) error) error
+	FluxStream(ctx context.Context, org, query string, chunkSize int, fn func(*influx.Series) error) error
+}
+
+// exportWriter starts the response of an export with its first bytes, so that
+// the errors before them are still responded with their status
+type exportWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	gzip        bool
+	gz          *gzip.Writer
+	started     bool
+}
+
+func (e *exportWriter) start() {
+	e.started = true
+	h := e.w.Header()
+	h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename))
+	h.Set("X-Content-Type-Options", "nosniff")
+	if e.gzip {
+		h.Set("Content-Type", "application/gzip")
+	} else {
+		h.Set("Content-Type", e.contentType)
+	}
+	e.w.WriteHeader(http.StatusOK)
+	if e.gzip {
+		e.gz = gzip.NewWriter(e.w)
+	}
+}
+
+func (e *exportWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.start()
+	}
+	if e.gz != nil {
+		return e.gz.Write(p)
+	}
+	return e.w.Write(p)
+}
+
+// Flush sends the bytes written to the client
+func (e *exportWriter) Flush() error {
+	if !e.started {
+		return nil
+	}
+	if e.gz != nil {
+		if err := e.gz.Flush(); err != nil {
+			return err
+		}
+	}
+	if f, ok := e.w.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// Close ends the export, empty if nothing was written
+func (e *exportWriter) Close() error {
+	if !e.started {
+		e.start()
+	}
+	if e.gz != nil {
+		return e.gz.Close()
+	}
+	return nil
+}
+
+// Export streams the results of an InfluxQL or Flux query of a source as CSV,
+// NDJSON or line protocol, chunk by chunk, without holding them in memory.
+// Parameters are read from the URL or a form: q is the query, lang influxql
+// or flux, db and rp the database and retention policy of InfluxQL queries,
+// format csv, ndjson or lp, time rfc3339 or an epoch precision, tz the
+// timezone of RFC3339 times, chunkSize the points per chunk and gzip true to
+// compress the export. Exports only read data, with SELECT and SHOW statements
+// or Flux queries without to(), and are bounded by exportTimeout.
+func (s *Service) Export(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+
+	command := r.FormValue("q")
+	if command == "" {
+		invalidData(w, fmt.Errorf("q is required"), s.Logger)
+		return
+	}
+	lang := r.FormValue("lang")
+	if lang == "" {
+		lang = "influxql"
+	}
+	if lang != "influxql" && lang != "flux" {
+		invalidData(w, fmt.Errorf("invalid lang %q: use influxql or flux", lang), s.Logger)
+		return
+	}
+	opts := influx.ExportOptions{
+		Format:     r.FormValue("format"),
+		TimeFormat: r.FormValue("time"),
+	}
+	if opts.Format == "" {
+		opts.Format = influx.ExportCSV
+	}
+	if tz := r.FormValue("tz"); tz != "" {
+		if opts.Location, err = time.LoadLocation(tz); err != nil {
+			invalidData(w, fmt.Errorf("invalid timezone %q", tz), s.Logger)
+			return
+		}
+	}
+	chunkSize := influx.DefaultChunkSize
+	if c := r.FormValue("chunkSize"); c != "" {
+		if chunkSize, err = strconv.Atoi(c); err != nil || chunkSize < 1 || chunkSize > maxExportChunkSize {
+			invalidData(w, fmt.Errorf("chunkSize must be a number from 1 to %d", maxExportChunkSize), s.Logger)
+			return
+		}
+	}
+
+	ew := &exportWriter{
+		w:           w,
+		contentType: exportContentTypes[opts.Format],
+		filename:    "export." + opts.Format,
+		gzip:        r.FormValue("gzip") == "true",
+	}
+	if ew.gzip {
+		ew.filename += ".gz"
+	}
+	exporter, err := influx.NewExporter(ew, opts)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	src, err := s.Store.Sources(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	q := cloudhub.Query{
+		Command: command,
+		DB:      r.FormValue("db"),
+		RP:      r.FormValue("rp"),
+	}
+	if lang == "influxql" {
+		setupQueryFromCommand(&q)
+		// exports only read data, whatever the role of the user
+		if !querycache.Cacheable(querycache.Normalize(q.Command)) {
+			Error(w, http.StatusForbidden, "Exports may only run SELECT and SHOW statements reading data", s.Logger)
+			return
+		}
+		// exports are streamed in chunks, their points and series are not limited
+		policy := s.queryPolicy(ctx)
+		policy.MaxPoints, policy.MaxSeries = 0, 0
+		q.Command, _, err = policy.Apply(q.Command, time.Now())
+		if perr, ok := err.(*influx.PolicyError); ok {
+			queryPolicyError(w, perr, s.Logger)
+			return
+		} else if err != nil {
+			invalidData(w, err, s.Logger)
+			return
+		}
+	} else {
+		if fluxWrite.MatchString(q.Command) {
+			Error(w, http.StatusForbidden, "Exports may only run Flux queries reading data", s.Logger)
+			return
+		}
+		fluxEnabled, err := hasFlux(ctx, src)
+		if err != nil || !fluxEnabled {
+			Error(w, http.StatusBadRequest, fmt.Sprintf("Flux is not enabled on source %d", id), s.Logger)
+			return
+		}
+	}
+
+	ts, err := s.TimeSeries(src)
+	if err != nil {
+		msg := fmt.Sprintf("Unable to connect to source %d: %v", id, err)
+		Error(w, http.StatusBadRequest, msg, s.Logger)
+		return
+	}
+	if err = ts.Connect(ctx, &src); err != nil {
+		msg := fmt.Sprintf("Unable to connect to source %d: %v", id, err)
+		Error(w, http.StatusBadRequest, msg, s.Logger)
+		return
+	}
+	streamer, ok := ts.(seriesStreamer)
+	if !ok {
+		Error(w, http.StatusBadRequest, fmt.Sprintf("Source %d does not support exports", id), s.Logger)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+	write := func(series *influx.Series) error {
+		if err := exporter.Write(series); err != nil {
+			return err
+		}
+		if err := exporter.Flush(); err != nil {
+			return err
+		}
+		return ew.Flush()
+	}
+	if lang == "influxql" {
+		err = streamer.QueryStream(ctx, q, chunkSize, write)
+	} else {
+		err = streamer.FluxStream(ctx, src.Username, q.Command, chunkSize, write) // v2 organization name is stored in username
+	}
+	if err == nil {
+		if err = exporter.Flush(); err == nil {
+			err = ew.Close()
+		}
+	}
+	if err == nil {
+		return
+	}
+	if !ew.started {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+	// the export is aborted so that clients do not take it for complete
+	s.Logger.
+		WithField("component", "export").
+		WithField("source", id).
+		Error("Error exporting query results: ", err)
+	panic(http.ErrAbortHandler)
+}
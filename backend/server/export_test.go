@@ -0,0 +1,278 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bouk/httprouter"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/influx"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+// streamingTimeSeries streams its series as the chunks of every query
+type streamingTimeSeries struct {
+	mocks.TimeSeries
+	series   []*influx.Series
+	err      error
+	queried  cloudhub.Query
+	deadline time.Time
+}
+
+func (t *streamingTimeSeries) New(cloudhub.Source, cloudhub.Logger) (cloudhub.TimeSeries, error) {
+	return t, nil
+}
+
+func (t *streamingTimeSeries) QueryStream(ctx context.Context, q cloudhub.Query, chunkSize int, fn func(*influx.Series) error) error {
+	t.queried = q
+	t.deadline, _ = ctx.Deadline()
+	for _, s := range t.series {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return t.err
+}
+
+func (t *streamingTimeSeries) FluxStream(ctx context.Context, org, query string, chunkSize int, fn func(*influx.Series) error) error {
+	return t.QueryStream(ctx, cloudhub.Query{Command: query}, chunkSize, fn)
+}
+
+func TestService_Export(t *testing.T) {
+	cpu := &influx.Series{
+		Name:    "cpu",
+		Tags:    map[string]string{"host": "web01"},
+		Columns: []string{"time", "usage"},
+		Values: [][]interface{}{
+			{time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), 1.5},
+		},
+	}
+	tests := []struct {
+		name            string
+		url             string
+		role            string
+		series          []*influx.Series
+		err             error
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+		wantCommand     string
+		wantAbort       bool
+	}{
+		{
+			name:            "csv export",
+			url:             "/export?db=telegraf&q=SELECT+usage+FROM+cpu+WHERE+time+>+now()+-+1h&tz=Asia/Seoul",
+			series:          []*influx.Series{cpu},
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody:        "name,tags,time,usage\ncpu,host=web01,2020-01-01T09:00:00+09:00,1.5\n",
+			wantCommand:     "SELECT usage FROM cpu WHERE time > now() - 1h",
+		},
+		{
+			name:            "unbounded query exported for the last hour",
+			url:             "/export?format=ndjson&time=s&q=SELECT+*+FROM+cpu",
+			role:            roles.ViewerRoleName,
+			series:          []*influx.Series{cpu},
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantBody:        `{"name":"cpu","tags":{"host":"web01"},"time":1577836800,"usage":1.5}` + "\n",
+			wantCommand:     "SELECT * FROM cpu WHERE time > now() - 1h",
+		},
+		{
+			name:            "empty export",
+			url:             "/export?format=lp&q=SELECT+usage+FROM+cpu+WHERE+time+>+now()+-+1h",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/plain; charset=utf-8",
+			wantCommand:     "SELECT usage FROM cpu WHERE time > now() - 1h",
+		},
+		{
+			name:       "DDL of a viewer is forbidden",
+			url:        "/export?q=DROP+MEASUREMENT+cpu",
+			role:       roles.ViewerRoleName,
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"code":403,"message":"Exports may only run SELECT and SHOW statements reading data"}`,
+		},
+		{
+			name:       "DDL of an editor is forbidden",
+			url:        "/export?q=DROP+DATABASE+telegraf",
+			role:       roles.EditorRoleName,
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"code":403,"message":"Exports may only run SELECT and SHOW statements reading data"}`,
+		},
+		{
+			name:       "DDL without authentication is forbidden",
+			url:        "/export?q=" + url.QueryEscape("SHOW DATABASES; DROP DATABASE telegraf"),
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"code":403,"message":"Exports may only run SELECT and SHOW statements reading data"}`,
+		},
+		{
+			name:       "SELECT INTO of an admin is forbidden",
+			url:        "/export?q=SELECT+*+INTO+cpu_copy+FROM+cpu",
+			role:       roles.AdminRoleName,
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"code":403,"message":"Exports may only run SELECT and SHOW statements reading data"}`,
+		},
+		{
+			name:       "Flux writes are forbidden",
+			url:        "/export?lang=flux&q=" + url.QueryEscape(`from(bucket: "telegraf") |> range(start: -1h) |> to(bucket: "copy")`),
+			role:       roles.AdminRoleName,
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"code":403,"message":"Exports may only run Flux queries reading data"}`,
+		},
+		{
+			name:       "invalid format",
+			url:        "/export?format=xml&q=SELECT+usage+FROM+cpu",
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"code":422,"message":"invalid export format \"xml\": use csv, ndjson or lp"}`,
+		},
+		{
+			name:       "invalid timezone",
+			url:        "/export?tz=Mars/Olympus&q=SELECT+usage+FROM+cpu",
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"code":422,"message":"invalid timezone \"Mars/Olympus\""}`,
+		},
+		{
+			name:        "error before the first chunk",
+			url:         "/export?q=SELECT+usage+FROM+cpu+WHERE+time+>+now()+-+1h",
+			err:         errors.New("database not found: telegraf"),
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"code":400,"message":"database not found: telegraf"}`,
+			wantCommand: "SELECT usage FROM cpu WHERE time > now() - 1h",
+		},
+		{
+			name:        "error after the first chunk aborts the export",
+			url:         "/export?q=SELECT+usage+FROM+cpu+WHERE+time+>+now()+-+1h",
+			series:      []*influx.Series{cpu},
+			err:         errors.New("connection reset"),
+			wantCommand: "SELECT usage FROM cpu WHERE time > now() - 1h",
+			wantAbort:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := &streamingTimeSeries{
+				TimeSeries: mocks.TimeSeries{
+					ConnectF: func(ctx context.Context, src *cloudhub.Source) error {
+						return nil
+					},
+				},
+				series: tt.series,
+				err:    tt.err,
+			}
+			s := &Service{
+				Store: &mocks.Store{
+					SourcesStore: &mocks.SourcesStore{
+						GetF: func(ctx context.Context, ID int) (cloudhub.Source, error) {
+							return cloudhub.Source{ID: ID}, nil
+						},
+					},
+				},
+				TimeSeriesClient: ts,
+				Logger:           mocks.NewLogger(),
+			}
+
+			r := httptest.NewRequest("POST", tt.url, nil)
+			ctx := httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}})
+			if tt.role != "" {
+				ctx = context.WithValue(ctx, roles.ContextKey, tt.role)
+			}
+			r = r.WithContext(ctx)
+			w := httptest.NewRecorder()
+
+			aborted := func() (aborted bool) {
+				defer func() {
+					if rec := recover(); rec != nil {
+						if rec != http.ErrAbortHandler {
+							panic(rec)
+						}
+						aborted = true
+					}
+				}()
+				s.Export(w, r)
+				return false
+			}()
+			if aborted != tt.wantAbort {
+				t.Fatalf("Export() aborted = %v, want %v", aborted, tt.wantAbort)
+			}
+			if ts.queried.Command != tt.wantCommand {
+				t.Errorf("Export() queried %q, want %q", ts.queried.Command, tt.wantCommand)
+			}
+			if tt.wantCommand != "" && (ts.deadline.IsZero() || time.Until(ts.deadline) > exportTimeout) {
+				t.Errorf("Export() queried with deadline %v, want within %s", ts.deadline, exportTimeout)
+			}
+			if aborted {
+				return
+			}
+
+			resp := w.Result()
+			body, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Export() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("Export() body = %q, want %q", body, tt.wantBody)
+			}
+			if tt.wantContentType != "" && resp.Header.Get("Content-Type") != tt.wantContentType {
+				t.Errorf("Export() Content-Type = %q, want %q", resp.Header.Get("Content-Type"), tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestService_Export_Gzip(t *testing.T) {
+	s := &Service{
+		Store: &mocks.Store{
+			SourcesStore: &mocks.SourcesStore{
+				GetF: func(ctx context.Context, ID int) (cloudhub.Source, error) {
+					return cloudhub.Source{ID: ID}, nil
+				},
+			},
+		},
+		TimeSeriesClient: &streamingTimeSeries{
+			TimeSeries: mocks.TimeSeries{
+				ConnectF: func(ctx context.Context, src *cloudhub.Source) error {
+					return nil
+				},
+			},
+			series: []*influx.Series{
+				{
+					Name:    "cpu",
+					Columns: []string{"time", "usage"},
+					Values: [][]interface{}{
+						{time.Unix(1, 0), 1.5},
+					},
+				},
+			},
+		},
+		Logger: mocks.NewLogger(),
+	}
+	r := httptest.NewRequest("POST", "/export?format=lp&gzip=true&q=SELECT+usage+FROM+cpu+WHERE+time+>+now()+-+1h", nil)
+	r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}}))
+	w := httptest.NewRecorder()
+	s.Export(w, r)
+
+	resp := w.Result()
+	if got := resp.Header.Get("Content-Disposition"); got != `attachment; filename="export.lp.gz"` {
+		t.Errorf("Export() Content-Disposition = %q", got)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Export() is not gzipped: %v", err)
+	}
+	body, err := ioutil.ReadAll(gz)
+	if err != nil {
+		t.Fatalf("Export() is not gzipped: %v", err)
+	}
+	if want := "cpu usage=1.5 1000000000\n"; string(body) != want {
+		t.Errorf("Export() = %q, want %q", body, want)
+	}
+}
@@ -0,0 +1,373 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+type folderLinks struct {
+	Self       string `json:"self"`       // Self link mapping to this resource
+	Dashboards string `json:"dashboards"` // Dashboards link to the dashboards of the folder
+}
+
+type folderResponse struct {
+	cloudhub.Folder
+	Effective cloudhub.FolderPermissions `json:"effectivePermissions"` // Effective are the permissions with the roles inherited from the parents
+	Links     folderLinks                `json:"links"`
+}
+
+type foldersResponse struct {
+	Folders []folderResponse `json:"folders"`
+	Links   selfLinks        `json:"links"`
+}
+
+// folderRequest creates or updates a folder. Fields left null are not updated.
+type folderRequest struct {
+	Name        *string                     `json:"name"`
+	Parent      *string                     `json:"parent"`
+	Permissions *cloudhub.FolderPermissions `json:"permissions"`
+}
+
+type moveDashboardRequest struct {
+	Folder string `json:"folder"` // Folder is the ID of the folder to move the dashboard to, none for the root
+}
+
+func newFolderResponse(f cloudhub.Folder, folders []cloudhub.Folder) folderResponse {
+	return folderResponse{
+		Folder:    f,
+		Effective: roles.FolderPermissions(folders, f.ID),
+		Links: folderLinks{
+			Self:       fmt.Sprintf("/cloudhub/v1/folders/%s", f.ID),
+			Dashboards: fmt.Sprintf("/cloudhub/v1/dashboards?folder=%s", f.ID),
+		},
+	}
+}
+
+func folderForbidden(w http.ResponseWriter, logger cloudhub.Logger) {
+	Error(w, http.StatusForbidden, cloudhub.ErrFolderForbidden.Error(), logger)
+}
+
+// folderRole returns the role of the user on context, admin without one as
+// the DataStore then does not restrict the dashboards by folder either
+func folderRole(ctx context.Context) string {
+	if role, ok := hasRoleContext(ctx); ok {
+		return role
+	}
+	return roles.AdminRoleName
+}
+
+// folderVisible returns whether the role of the user on context may view the
+// resources of a folder. Resources outside of folders are visible to all roles.
+func (s *Service) folderVisible(ctx context.Context) (func(folder string) bool, error) {
+	role, ok := hasRoleContext(ctx)
+	if !ok {
+		return func(folder string) bool { return true }, nil
+	}
+	folders, err := s.Store.Folders(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(folder string) bool {
+		return folder == "" || roles.CanView(role, roles.FolderPermissions(folders, folder))
+	}, nil
+}
+
+// validFolderRole returns an error unless role may be the lowest role of a permission
+func validFolderRole(role string) error {
+	switch role {
+	case "", roles.ViewerRoleName, roles.EditorRoleName, roles.AdminRoleName:
+		return nil
+	}
+	return fmt.Errorf("invalid folder permission role %q", role)
+}
+
+// apply updates f with the fields of the request, validating them against the
+// other folders of the organization
+func (req *folderRequest) apply(f *cloudhub.Folder, folders []cloudhub.Folder) error {
+	if req.Name != nil {
+		f.Name = strings.TrimSpace(*req.Name)
+	}
+	if f.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if req.Permissions != nil {
+		if err := validFolderRole(req.Permissions.View); err != nil {
+			return err
+		}
+		if err := validFolderRole(req.Permissions.Edit); err != nil {
+			return err
+		}
+		f.Permissions = *req.Permissions
+	}
+	if req.Parent == nil {
+		return nil
+	}
+
+	byID := make(map[string]cloudhub.Folder, len(folders))
+	for _, folder := range folders {
+		byID[folder.ID] = folder
+	}
+	// the parent must exist and must not be the folder or one of its subfolders
+	for id := *req.Parent; id != ""; id = byID[id].Parent {
+		if _, ok := byID[id]; !ok {
+			return fmt.Errorf("parent folder %s not found", *req.Parent)
+		}
+		if f.ID != "" && id == f.ID {
+			return fmt.Errorf("folder %s cannot be moved into itself", f.ID)
+		}
+	}
+	f.Parent = *req.Parent
+	return nil
+}
+
+// Folders returns the folders of the organization whose dashboards the user may view
+func (s *Service) Folders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	folders, err := s.Store.Folders(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading folders", s.Logger)
+		return
+	}
+
+	role := folderRole(ctx)
+	res := foldersResponse{
+		Folders: []folderResponse{},
+		Links: selfLinks{
+			Self: "/cloudhub/v1/folders",
+		},
+	}
+	for _, f := range folders {
+		if roles.CanView(role, roles.FolderPermissions(folders, f.ID)) {
+			res.Folders = append(res.Folders, newFolderResponse(f, folders))
+		}
+	}
+
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// FolderID returns a single specified folder
+func (s *Service) FolderID(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	folders, err := s.Store.Folders(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading folders", s.Logger)
+		return
+	}
+	for _, f := range folders {
+		if f.ID == id && roles.CanView(folderRole(ctx), roles.FolderPermissions(folders, f.ID)) {
+			encodeJSON(w, http.StatusOK, newFolderResponse(f, folders), s.Logger)
+			return
+		}
+	}
+
+	notFound(w, id, s.Logger)
+}
+
+// NewFolder creates a folder in the organization
+func (s *Service) NewFolder(w http.ResponseWriter, r *http.Request) {
+	var req folderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	folders, err := s.Store.Folders(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading folders", s.Logger)
+		return
+	}
+
+	folder := &cloudhub.Folder{}
+	if err := req.apply(folder, folders); err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	if folder, err = s.Store.Folders(ctx).Add(ctx, folder); err != nil {
+		msg := fmt.Errorf("Error storing folder %v: %v", req, err)
+		unknownErrorWithMessage(w, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgFolderCreated.String(), folder.Name)
+	s.logRegistration(ctx, "Folders", msg)
+
+	res := newFolderResponse(*folder, append(folders, *folder))
+	location(w, res.Links.Self)
+	encodeJSON(w, http.StatusCreated, res, s.Logger)
+}
+
+// UpdateFolder renames a folder, moves it to another parent or changes its permissions
+func (s *Service) UpdateFolder(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	folder, err := s.Store.Folders(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	var req folderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+
+	folders, err := s.Store.Folders(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading folders", s.Logger)
+		return
+	}
+	if err := req.apply(folder, folders); err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	if err := s.Store.Folders(ctx).Update(ctx, folder); err != nil {
+		msg := fmt.Sprintf("Error updating folder %s: %v", id, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgFolderModified.String(), folder.Name)
+	s.logRegistration(ctx, "Folders", msg)
+
+	for i := range folders {
+		if folders[i].ID == folder.ID {
+			folders[i] = *folder
+		}
+	}
+	encodeJSON(w, http.StatusOK, newFolderResponse(*folder, folders), s.Logger)
+}
+
+// RemoveFolder deletes a folder without subfolders nor dashboards
+func (s *Service) RemoveFolder(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	folder, err := s.Store.Folders(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	folders, err := s.Store.Folders(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading folders", s.Logger)
+		return
+	}
+	for _, f := range folders {
+		if f.Parent == folder.ID {
+			Error(w, http.StatusConflict, fmt.Sprintf("Folder %s has subfolders", folder.Name), s.Logger)
+			return
+		}
+	}
+
+	// dashboards the user may not view keep the folder too
+	serverCtx := serverContext(ctx)
+	dashboards, err := s.Store.Dashboards(serverCtx).All(serverCtx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading dashboards", s.Logger)
+		return
+	}
+	for _, d := range dashboards {
+		if d.Organization == folder.Organization && d.Folder == folder.ID {
+			Error(w, http.StatusConflict, fmt.Sprintf("Folder %s has dashboards", folder.Name), s.Logger)
+			return
+		}
+	}
+
+	if err := s.Store.Folders(ctx).Delete(ctx, folder); err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgFolderDeleted.String(), folder.Name)
+	s.logRegistration(ctx, "Folders", msg)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MoveDashboard moves a dashboard to a folder, or to the root. The user must
+// be allowed to edit the dashboards of both folders.
+func (s *Service) MoveDashboard(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	dashboard, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	var req moveDashboardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+
+	name := "the root"
+	if req.Folder != "" {
+		folder, err := s.Store.Folders(ctx).Get(ctx, req.Folder)
+		if err != nil {
+			invalidData(w, fmt.Errorf("folder %s not found", req.Folder), s.Logger)
+			return
+		}
+		name = folder.Name
+	}
+
+	if dashboard.Version, err = expectedVersion(r, dashboard.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+	dashboard.Folder = req.Folder
+
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), dashboard); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
+		msg := fmt.Sprintf("Error moving dashboard ID %d: %v", id, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgDashboardMoved.String(), dashboard.Name, name)
+	s.logRegistration(ctx, "Dashboards", msg)
+
+	dashboard.Version++
+	setETag(w, dashboard.Version)
+	encodeJSON(w, http.StatusOK, newDashboardResponse(dashboard), s.Logger)
+}
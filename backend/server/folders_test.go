@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/organizations"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+// folderTestStore stores folders and dashboards in memory
+func folderTestStore(folders map[string]*cloudhub.Folder, dashboards map[cloudhub.DashboardID]cloudhub.Dashboard) *Store {
+	return &Store{
+		FoldersStore: &mocks.FoldersStore{
+			AllF: func(ctx context.Context) ([]cloudhub.Folder, error) {
+				res := []cloudhub.Folder{}
+				for i := 1; i <= len(folders)+10; i++ {
+					if f, ok := folders[strconv.Itoa(i)]; ok {
+						res = append(res, *f)
+					}
+				}
+				return res, nil
+			},
+			AddF: func(ctx context.Context, f *cloudhub.Folder) (*cloudhub.Folder, error) {
+				f.ID = strconv.Itoa(len(folders) + 1)
+				folders[f.ID] = f
+				return f, nil
+			},
+			GetF: func(ctx context.Context, id string) (*cloudhub.Folder, error) {
+				if f, ok := folders[id]; ok {
+					folder := *f
+					return &folder, nil
+				}
+				return nil, cloudhub.ErrFolderNotFound
+			},
+			UpdateF: func(ctx context.Context, f *cloudhub.Folder) error {
+				folders[f.ID] = f
+				return nil
+			},
+			DeleteF: func(ctx context.Context, f *cloudhub.Folder) error {
+				delete(folders, f.ID)
+				return nil
+			},
+		},
+		DashboardsStore: &mocks.DashboardsStore{
+			AllF: func(ctx context.Context) ([]cloudhub.Dashboard, error) {
+				res := []cloudhub.Dashboard{}
+				for i := 1; i <= len(dashboards); i++ {
+					res = append(res, dashboards[cloudhub.DashboardID(i)])
+				}
+				return res, nil
+			},
+			GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+				if d, ok := dashboards[id]; ok {
+					return d, nil
+				}
+				return cloudhub.Dashboard{}, cloudhub.ErrDashboardNotFound
+			},
+			UpdateF: func(ctx context.Context, d cloudhub.Dashboard) error {
+				dashboards[d.ID] = d
+				return nil
+			},
+		},
+	}
+}
+
+func folderTestRequest(method, path, body, role string, params httprouter.Params) *http.Request {
+	r := httptest.NewRequest(method, "http://any.url"+path, bytes.NewBufferString(body))
+	ctx := httprouter.WithParams(r.Context(), params)
+	ctx = context.WithValue(ctx, organizations.ContextKey, "default")
+	ctx = context.WithValue(ctx, roles.ContextKey, role)
+	return r.WithContext(ctx)
+}
+
+func TestService_Folders(t *testing.T) {
+	folders := map[string]*cloudhub.Folder{
+		"1": {ID: "1", Name: "Network", Organization: "default", Permissions: cloudhub.FolderPermissions{Edit: roles.AdminRoleName}},
+		"2": {ID: "2", Name: "Core", Parent: "1", Organization: "default", Permissions: cloudhub.FolderPermissions{View: roles.EditorRoleName}},
+		"3": {ID: "3", Name: "Other", Organization: "other"},
+	}
+	dashboards := map[cloudhub.DashboardID]cloudhub.Dashboard{
+		1: {ID: 1, Name: "Routers", Organization: "default", Folder: "1", Tags: []string{"network"}},
+		2: {ID: 2, Name: "Backbone", Organization: "default", Folder: "2", Tags: []string{"network", "core"}},
+		3: {ID: 3, Name: "Hosts", Organization: "default", Tags: []string{"hosts"}},
+	}
+	s := &Service{
+		Store:  folderTestStore(folders, dashboards),
+		Logger: &mocks.TestLogger{},
+	}
+
+	// viewers see neither the folder restricted to editors nor its dashboards
+	w := httptest.NewRecorder()
+	s.Folders(w, folderTestRequest("GET", "/cloudhub/v1/folders", "", roles.ViewerRoleName, nil))
+	var list foldersResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Folders) != 1 || list.Folders[0].ID != "1" || list.Folders[0].Links.Dashboards != "/cloudhub/v1/dashboards?folder=1" {
+		t.Errorf("Folders() of a viewer = %#v", list.Folders)
+	}
+
+	w = httptest.NewRecorder()
+	s.FolderID(w, folderTestRequest("GET", "/cloudhub/v1/folders/2", "", roles.EditorRoleName, httprouter.Params{{Key: "id", Value: "2"}}))
+	var folder folderResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &folder); err != nil {
+		t.Fatal(err)
+	}
+	if folder.Effective != (cloudhub.FolderPermissions{View: roles.EditorRoleName, Edit: roles.AdminRoleName}) {
+		t.Errorf("FolderID() effective permissions = %#v", folder.Effective)
+	}
+
+	dashboardsOf := func(query, role string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		s.Dashboards(w, folderTestRequest("GET", "/cloudhub/v1/dashboards?"+query, "", role, nil))
+		var res getDashboardsResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		names := []string{}
+		for _, d := range res.Dashboards {
+			names = append(names, d.Name)
+		}
+		return names
+	}
+	if got := dashboardsOf("tag=network", roles.ViewerRoleName); len(got) != 1 || got[0] != "Routers" {
+		t.Errorf("Dashboards() tagged network of a viewer = %v", got)
+	}
+	if got := dashboardsOf("tag=core,hosts", roles.EditorRoleName); len(got) != 2 || got[0] != "Backbone" || got[1] != "Hosts" {
+		t.Errorf("Dashboards() tagged core or hosts = %v", got)
+	}
+	if got := dashboardsOf("folder=2", roles.EditorRoleName); len(got) != 1 || got[0] != "Backbone" {
+		t.Errorf("Dashboards() of folder 2 = %v", got)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		role   string
+		id     string
+		handle func(http.ResponseWriter, *http.Request)
+		want   int
+	}{
+		{"create in a missing parent", "POST", `{"name":"Edge","parent":"9"}`, roles.AdminRoleName, "", s.NewFolder, http.StatusUnprocessableEntity},
+		{"create with an invalid role", "POST", `{"name":"Edge","permissions":{"view":"root"}}`, roles.AdminRoleName, "", s.NewFolder, http.StatusUnprocessableEntity},
+		{"create without name", "POST", `{"name":" "}`, roles.AdminRoleName, "", s.NewFolder, http.StatusUnprocessableEntity},
+		{"move into a subfolder", "PATCH", `{"parent":"2"}`, roles.AdminRoleName, "1", s.UpdateFolder, http.StatusUnprocessableEntity},
+		{"update a folder of another organization", "PATCH", `{"name":"Mine"}`, roles.AdminRoleName, "3", s.UpdateFolder, http.StatusNotFound},
+		{"remove a folder with subfolders", "DELETE", "", roles.AdminRoleName, "1", s.RemoveFolder, http.StatusConflict},
+		{"remove a folder with dashboards", "DELETE", "", roles.AdminRoleName, "2", s.RemoveFolder, http.StatusConflict},
+		{"move a dashboard to a folder edited by admins", "PUT", `{"folder":"1"}`, roles.EditorRoleName, "3", s.MoveDashboard, http.StatusForbidden},
+		{"move a dashboard to a missing folder", "PUT", `{"folder":"9"}`, roles.EditorRoleName, "3", s.MoveDashboard, http.StatusUnprocessableEntity},
+		{"move a dashboard hidden from viewers", "PUT", `{"folder":""}`, roles.ViewerRoleName, "2", s.MoveDashboard, http.StatusNotFound},
+		{"create", "POST", `{"name":"Edge","parent":"1","permissions":{"edit":"editor"}}`, roles.AdminRoleName, "", s.NewFolder, http.StatusCreated},
+		{"move a dashboard to the folder edited by editors", "PUT", `{"folder":"4"}`, roles.EditorRoleName, "3", s.MoveDashboard, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handle(w, folderTestRequest(tt.method, "/", tt.body, tt.role, httprouter.Params{{Key: "id", Value: tt.id}}))
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d: %s", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+
+	if f := folders["4"]; f == nil || f.Organization != "default" || f.Parent != "1" {
+		t.Errorf("NewFolder() stored %#v", f)
+	}
+	if d := dashboards[3]; d.Folder != "4" {
+		t.Errorf("MoveDashboard() stored %#v", d)
+	}
+
+	w = httptest.NewRecorder()
+	s.RemoveFolder(w, folderTestRequest("DELETE", "/", "", roles.AdminRoleName, httprouter.Params{{Key: "id", Value: "3"}}))
+	if w.Code != http.StatusNotFound || folders["3"] == nil {
+		t.Errorf("RemoveFolder() of another organization status = %d", w.Code)
+	}
+}
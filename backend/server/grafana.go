@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/grafana"
+)
+
+// grafanaImportRequest imports a dashboard of Grafana into the current organization
+type grafanaImportRequest struct {
+	// Dashboard is the JSON model of the Grafana dashboard, as exported by Grafana
+	Dashboard json.RawMessage `json:"dashboard"`
+	// Datasources maps the names or UIDs of the datasources of Grafana to the IDs of
+	// local sources
+	Datasources map[string]string `json:"datasources,omitempty"`
+	// Source is the ID of the source of the queries of other datasources. Defaults to
+	// the default source.
+	Source string `json:"source,omitempty"`
+	// Name replaces the title of the Grafana dashboard
+	Name string `json:"name,omitempty"`
+	// DryRun converts the dashboard without creating it
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+type grafanaImportResponse struct {
+	Dashboard *dashboardResponse `json:"dashboard"`
+	// Warnings report the parts of the Grafana dashboard which were not converted
+	Warnings []grafana.Warning `json:"warnings"`
+}
+
+// GrafanaDashboardImport converts a Grafana dashboard and creates it in the current organization
+func (s *Service) GrafanaDashboardImport(w http.ResponseWriter, r *http.Request) {
+	var req grafanaImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+	if len(req.Dashboard) == 0 {
+		invalidData(w, fmt.Errorf("dashboard required on Grafana import request body"), s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	defaultOrg, err := s.Store.Organizations(ctx).DefaultOrganization(ctx)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	sources, err := s.Store.Sources(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading sources", s.Logger)
+		return
+	}
+	opts, err := grafanaOptions(&req, sources)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	dashboard, warnings, err := grafana.Convert(req.Dashboard, opts)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	dashboard.Organization = defaultOrg.ID
+	if req.Name != "" {
+		dashboard.Name = req.Name
+	}
+	if err := ValidDashboardRequest(&dashboard, defaultOrg.ID); err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	if req.DryRun {
+		res := grafanaImportResponse{Dashboard: newDashboardResponse(dashboard), Warnings: warnings}
+		encodeJSON(w, http.StatusOK, res, s.Logger)
+		return
+	}
+
+	if err := s.checkQuota(ctx, dashboard.Organization, quotaDashboards, 1); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+	if err := s.checkDashboardCells(ctx, dashboard.Organization, len(dashboard.Cells)); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+	if dashboard, err = s.Store.Dashboards(ctx).Add(ctx, dashboard); err != nil {
+		msg := fmt.Errorf("Error storing dashboard %v: %v", dashboard, err)
+		unknownErrorWithMessage(w, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgDashboardCreated.String(), dashboard.Name)
+	s.logRegistration(ctx, "Dashboards", msg)
+
+	res := grafanaImportResponse{Dashboard: newDashboardResponse(dashboard), Warnings: warnings}
+	location(w, res.Dashboard.Links.Self)
+	encodeJSON(w, http.StatusCreated, res, s.Logger)
+}
+
+// grafanaOptions returns the links and the telegraf databases of the local sources
+// of the datasources of the request. The queries of other datasources use the
+// source of the request, or the default source.
+func grafanaOptions(req *grafanaImportRequest, local []cloudhub.Source) (grafana.Options, error) {
+	byID := map[string]cloudhub.Source{}
+	var def *cloudhub.Source
+	for i, src := range local {
+		byID[strconv.Itoa(src.ID)] = src
+		if src.Default && def == nil {
+			def = &local[i]
+		}
+	}
+	if def == nil && len(local) > 0 {
+		def = &local[0]
+	}
+
+	opts := grafana.Options{Sources: map[string]string{}, Databases: map[string]string{}}
+	for name, id := range req.Datasources {
+		src, ok := byID[id]
+		if !ok {
+			return opts, fmt.Errorf("datasource %s is mapped to unknown source %s", name, id)
+		}
+		opts.Sources[name] = fmt.Sprintf("%s%d", sourcesLinkPrefix, src.ID)
+		opts.Databases[name] = src.Telegraf
+	}
+	if req.Source != "" {
+		src, ok := byID[req.Source]
+		if !ok {
+			return opts, fmt.Errorf("unknown source %s", req.Source)
+		}
+		def = &src
+	}
+	if def != nil {
+		opts.Source = fmt.Sprintf("%s%d", sourcesLinkPrefix, def.ID)
+		opts.Database = def.Telegraf
+	}
+	return opts, nil
+}
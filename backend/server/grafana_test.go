@@ -0,0 +1,143 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestService_GrafanaDashboardImport(t *testing.T) {
+	dashboard := json.RawMessage(`{
+		"title": "Hosts",
+		"panels": [{
+			"type": "stat",
+			"title": "Uptime",
+			"gridPos": {"x": 0, "y": 0, "w": 6, "h": 4},
+			"datasource": "influx",
+			"targets": [{"refId": "A", "rawQuery": true, "query": "SELECT last(\"uptime\") FROM \"system\" WHERE $timeFilter"}]
+		}, {
+			"type": "graph",
+			"title": "Requests",
+			"gridPos": {"x": 6, "y": 0, "w": 6, "h": 4},
+			"datasource": "prometheus",
+			"targets": [{"refId": "A", "expr": "rate(requests[5m])"}]
+		}]
+	}`)
+
+	tests := []struct {
+		name       string
+		req        grafanaImportRequest
+		wantStatus int
+		wantSource string
+		wantDB     string
+		wantSaved  bool
+	}{
+		{
+			name:       "queries of the default source",
+			req:        grafanaImportRequest{Dashboard: dashboard},
+			wantStatus: http.StatusCreated,
+			wantSource: "/cloudhub/v1/sources/7",
+			wantDB:     "tenant",
+			wantSaved:  true,
+		},
+		{
+			name:       "datasource mapped by the request",
+			req:        grafanaImportRequest{Dashboard: dashboard, Datasources: map[string]string{"influx": "9"}},
+			wantStatus: http.StatusCreated,
+			wantSource: "/cloudhub/v1/sources/9",
+			wantDB:     "telegraf",
+			wantSaved:  true,
+		},
+		{
+			name:       "dry run",
+			req:        grafanaImportRequest{Dashboard: dashboard, DryRun: true},
+			wantStatus: http.StatusOK,
+			wantSource: "/cloudhub/v1/sources/7",
+			wantDB:     "tenant",
+		},
+		{
+			name:       "datasource mapped to unknown source",
+			req:        grafanaImportRequest{Dashboard: dashboard, Datasources: map[string]string{"influx": "3"}},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid dashboard",
+			req:        grafanaImportRequest{Dashboard: json.RawMessage(`[]`)},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var saved *cloudhub.Dashboard
+			s := &Service{
+				Store: &mocks.Store{
+					OrganizationsStore: &mocks.OrganizationsStore{
+						DefaultOrganizationF: func(ctx context.Context) (*cloudhub.Organization, error) {
+							return &cloudhub.Organization{ID: "1"}, nil
+						},
+						GetF: func(ctx context.Context, q cloudhub.OrganizationQuery) (*cloudhub.Organization, error) {
+							return nil, cloudhub.ErrOrganizationNotFound
+						},
+					},
+					SourcesStore: &mocks.SourcesStore{
+						AllF: func(ctx context.Context) ([]cloudhub.Source, error) {
+							return []cloudhub.Source{
+								{ID: 9, Name: "backup", Telegraf: "telegraf"},
+								{ID: 7, Name: "influx", Telegraf: "tenant", Default: true},
+							}, nil
+						},
+					},
+					DashboardsStore: &mocks.DashboardsStore{
+						AddF: func(ctx context.Context, d cloudhub.Dashboard) (cloudhub.Dashboard, error) {
+							d.ID = 10
+							saved = &d
+							return d, nil
+						},
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			body, _ := json.Marshal(tt.req)
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "http://any.url/cloudhub/v1/dashboard-imports/grafana", bytes.NewReader(body))
+			s.GrafanaDashboardImport(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("GrafanaDashboardImport() status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if (saved != nil) != tt.wantSaved {
+				t.Fatalf("GrafanaDashboardImport() saved = %v, want %v", saved != nil, tt.wantSaved)
+			}
+			if tt.wantStatus >= 300 {
+				return
+			}
+
+			var res struct {
+				Dashboard cloudhub.Dashboard `json:"dashboard"`
+				Warnings  []struct {
+					Panel string `json:"panel"`
+				} `json:"warnings"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Dashboard.Name != "Hosts" || len(res.Dashboard.Cells) != 2 {
+				t.Fatalf("GrafanaDashboardImport() unexpected dashboard %#v", res.Dashboard)
+			}
+			q := res.Dashboard.Cells[0].Queries[0]
+			if q.Source != tt.wantSource || q.QueryConfig.Database != tt.wantDB {
+				t.Errorf("GrafanaDashboardImport() query = %#v, want source %s and database %s", q, tt.wantSource, tt.wantDB)
+			}
+			if len(res.Warnings) != 1 || res.Warnings[0].Panel != "Requests" {
+				t.Errorf("GrafanaDashboardImport() warnings = %#v", res.Warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/influxdb/influxql"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/tempvars"
+)
+
+const (
+	// defaultHostAppsInterval is the interval in which measurements of a host
+	// must have data for its applications to be detected
+	defaultHostAppsInterval = 10 * time.Minute
+	maxHostAppsInterval     = 7 * 24 * time.Hour
+)
+
+// hostAppsCache keeps the measurements found for a host, per source
+var hostAppsCache = tempvars.NewCache(time.Minute)
+
+type hostAppsLinks struct {
+	Self   string `json:"self"`   // Self link mapping to this resource
+	Source string `json:"source"` // Source link to the source of the host
+}
+
+type hostAppsResponse struct {
+	Host         string               `json:"host"`
+	Tag          string               `json:"tag"`
+	Interval     string               `json:"interval"`
+	Measurements []string             `json:"measurements"`
+	Apps         []string             `json:"apps"`
+	Layouts      []layoutResponse     `json:"layouts"`
+	Protoboards  []protoboardResponse `json:"protoboards"`
+	Links        hostAppsLinks        `json:"links"`
+}
+
+// hostApps are the layouts and protoboards of the applications of a host
+type hostApps struct {
+	Measurements []string
+	Apps         []string
+	Layouts      []cloudhub.Layout
+	Protoboards  []cloudhub.Protoboard
+}
+
+// HostApps returns the applications of a host of a source, detected by the
+// measurements of the layouts and protoboards with data of the host in the
+// last interval. ?tag is the tag of the host, host by default, and ?interval
+// the interval, 10m by default.
+func (s *Service) HostApps(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+	host, err := paramStr("host", r)
+	if err != nil || host == "" {
+		invalidData(w, fmt.Errorf("host is required"), s.Logger)
+		return
+	}
+	tag := r.URL.Query().Get("tag")
+	if tag == "" {
+		tag = "host"
+	}
+	interval := defaultHostAppsInterval
+	if i := r.URL.Query().Get("interval"); i != "" {
+		if interval, err = time.ParseDuration(i); err != nil || interval < time.Second || interval > maxHostAppsInterval {
+			invalidData(w, fmt.Errorf("interval must be a duration from 1s to %s", maxHostAppsInterval), s.Logger)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	src, err := s.Store.Sources(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	apps, err := s.hostApps(ctx, src, host, tag, interval)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+
+	res := hostAppsResponse{
+		Host:         host,
+		Tag:          tag,
+		Interval:     interval.String(),
+		Measurements: apps.Measurements,
+		Apps:         apps.Apps,
+		Layouts:      make([]layoutResponse, 0, len(apps.Layouts)),
+		Protoboards:  make([]protoboardResponse, 0, len(apps.Protoboards)),
+		Links: hostAppsLinks{
+			Self:   r.URL.Path,
+			Source: fmt.Sprintf("/cloudhub/v1/sources/%d", src.ID),
+		},
+	}
+	if r.URL.RawQuery != "" {
+		res.Links.Self += "?" + r.URL.RawQuery
+	}
+	for _, l := range apps.Layouts {
+		res.Layouts = append(res.Layouts, newLayoutResponse(l))
+	}
+	for _, p := range apps.Protoboards {
+		res.Protoboards = append(res.Protoboards, newProtoboardResponse(p))
+	}
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// hostApps returns the layouts and protoboards of which measurements have data
+// of the host, identified by the tag, in the last interval
+func (s *Service) hostApps(ctx context.Context, src cloudhub.Source, host, tag string, interval time.Duration) (*hostApps, error) {
+	layouts, err := s.Store.Layouts(ctx).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading layouts: %v", err)
+	}
+	protoboards, err := s.Store.Protoboards(ctx).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading protoboards: %v", err)
+	}
+
+	var measurements []string
+	for _, l := range layouts {
+		measurements = append(measurements, l.Measurement)
+	}
+	for _, p := range protoboards {
+		measurements = append(measurements, p.Meta.Measurements...)
+	}
+	query := hostSeriesQuery(src, sortedUnique(measurements), host, tag, interval)
+	if query == "" {
+		return matchHostApps(layouts, protoboards, map[string]bool{}), nil
+	}
+
+	fetcher := hostAppsCache.Fetcher(strconv.Itoa(src.ID), s.sourceFetcher(src))
+	series, err := fetcher.Fetch(ctx, query, false)
+	if err != nil {
+		return nil, err
+	}
+	present := map[string]bool{}
+	for _, key := range series {
+		present[seriesMeasurement(key)] = true
+	}
+	return matchHostApps(layouts, protoboards, present), nil
+}
+
+// hostSeriesQuery returns the query of the series of the measurements of the host
+// in the last interval. SHOW SERIES only honors the time condition on sources
+// with a TSI index, others return the series of the host of any time.
+func hostSeriesQuery(src cloudhub.Source, measurements []string, host, tag string, interval time.Duration) string {
+	db := src.Telegraf
+	if db == "" {
+		db = "telegraf"
+	}
+	from := make([]string, 0, len(measurements))
+	for _, m := range measurements {
+		if m == "" {
+			continue
+		}
+		if src.DefaultRP != "" {
+			from = append(from, influxql.QuoteIdent(src.DefaultRP, m))
+		} else {
+			from = append(from, influxql.QuoteIdent(m))
+		}
+	}
+	if len(from) == 0 {
+		return ""
+	}
+	sort.Strings(from)
+	return fmt.Sprintf("SHOW SERIES ON %s FROM %s WHERE %s = %s AND time > now() - %ds",
+		influxql.QuoteIdent(db), strings.Join(from, ","), influxql.QuoteIdent(tag), influxql.QuoteString(host), int64(interval/time.Second))
+}
+
+// seriesMeasurement returns the measurement of a series key, such as cpu of
+// cpu,cpu=cpu0,host=web01
+func seriesMeasurement(key string) string {
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '\\':
+			i++
+		case ',':
+			return strings.ReplaceAll(key[:i], `\`, "")
+		}
+	}
+	return strings.ReplaceAll(key, `\`, "")
+}
+
+// matchHostApps returns the layouts and protoboards with one of the measurements present
+func matchHostApps(layouts []cloudhub.Layout, protoboards []cloudhub.Protoboard, present map[string]bool) *hostApps {
+	apps := &hostApps{
+		Measurements: []string{},
+		Apps:         []string{},
+		Layouts:      []cloudhub.Layout{},
+		Protoboards:  []cloudhub.Protoboard{},
+	}
+	seen := map[string]bool{}
+	for _, l := range layouts {
+		if !present[l.Measurement] || seen[l.ID] {
+			continue
+		}
+		seen[l.ID] = true
+		apps.Layouts = append(apps.Layouts, l)
+		apps.Apps = append(apps.Apps, l.Application)
+		apps.Measurements = append(apps.Measurements, l.Measurement)
+	}
+	for _, p := range protoboards {
+		matched := false
+		for _, m := range p.Meta.Measurements {
+			if present[m] {
+				matched = true
+				apps.Measurements = append(apps.Measurements, m)
+			}
+		}
+		if matched {
+			apps.Protoboards = append(apps.Protoboards, p)
+		}
+	}
+
+	apps.Apps = sortedUnique(apps.Apps)
+	apps.Measurements = sortedUnique(apps.Measurements)
+	return apps
+}
+
+// sortedUnique returns the sorted values without the duplicate ones
+func sortedUnique(values []string) []string {
+	res := []string{}
+	seen := map[string]bool{}
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			res = append(res, v)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestService_HostApps(t *testing.T) {
+	var queries []string
+	s := &Service{
+		Store: &mocks.Store{
+			SourcesStore: &mocks.SourcesStore{
+				GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+					return cloudhub.Source{ID: id, Telegraf: "telegraf", DefaultRP: "autogen"}, nil
+				},
+			},
+			LayoutsStore: &mocks.LayoutsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Layout, error) {
+					return []cloudhub.Layout{
+						{ID: "cpu", Application: "system", Measurement: "cpu"},
+						{ID: "disk", Application: "system", Measurement: "disk"},
+						{ID: "nginx", Application: "nginx", Measurement: "nginx"},
+						{ID: "redis", Application: "redis", Measurement: "redis"},
+					}, nil
+				},
+			},
+			ProtoboardsStore: &mocks.ProtoboardsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Protoboard, error) {
+					return []cloudhub.Protoboard{
+						{ID: "system", Meta: cloudhub.ProtoboardMeta{Name: "System", Measurements: []string{"cpu", "mem"}}},
+						{ID: "mysql", Meta: cloudhub.ProtoboardMeta{Name: "MySQL", Measurements: []string{"mysql"}}},
+					}, nil
+				},
+			},
+		},
+		TimeSeriesClient: &mocks.TimeSeries{
+			ConnectF: func(context.Context, *cloudhub.Source) error {
+				return nil
+			},
+			QueryF: func(ctx context.Context, q cloudhub.Query) (cloudhub.Response, error) {
+				queries = append(queries, q.Command)
+				return mocks.NewResponse(`[{"series": [{"columns": ["key"], "values": [["cpu,cpu=cpu0,host=web01"], ["cpu,cpu=cpu1,host=web01"], ["nginx,host=web01,port=80"]]}]}]`, nil), nil
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	apps := func(query string) (int, hostAppsResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/sources/1/hosts/web01/apps?"+query, nil)
+		r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}, {Key: "host", Value: "web01"}}))
+		s.HostApps(w, r)
+		var res hostAppsResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, res
+	}
+
+	code, res := apps("")
+	if code != http.StatusOK {
+		t.Fatalf("HostApps() status = %d", code)
+	}
+	want := `SHOW SERIES ON telegraf FROM "autogen".cpu,"autogen".disk,"autogen".mem,"autogen".mysql,"autogen".nginx,"autogen".redis WHERE host = 'web01' AND time > now() - 600s`
+	if len(queries) != 1 || queries[0] != want {
+		t.Errorf("HostApps() queries = %q, want %q", queries, want)
+	}
+	if len(res.Apps) != 2 || res.Apps[0] != "nginx" || res.Apps[1] != "system" {
+		t.Errorf("HostApps() apps = %v", res.Apps)
+	}
+	if len(res.Measurements) != 2 || res.Measurements[0] != "cpu" || res.Measurements[1] != "nginx" {
+		t.Errorf("HostApps() measurements = %v", res.Measurements)
+	}
+	if len(res.Layouts) != 2 || res.Layouts[0].ID != "cpu" || res.Layouts[1].ID != "nginx" {
+		t.Errorf("HostApps() layouts = %#v", res.Layouts)
+	}
+	if len(res.Protoboards) != 1 || res.Protoboards[0].ID != "system" || res.Protoboards[0].Links.Self != "/cloudhub/v1/protoboards/system" {
+		t.Errorf("HostApps() protoboards = %#v", res.Protoboards)
+	}
+
+	// the measurements of the host are cached
+	if _, res = apps(""); len(queries) != 1 || len(res.Layouts) != 2 {
+		t.Errorf("HostApps() queried the source again: %q", queries)
+	}
+
+	if _, res = apps("tag=agent_host&interval=1h"); len(queries) != 2 || res.Tag != "agent_host" || res.Interval != "1h0m0s" {
+		t.Errorf("HostApps() with a tag and interval = %#v, queries %q", res, queries)
+	}
+	if code, _ = apps("interval=forever"); code != http.StatusUnprocessableEntity {
+		t.Errorf("HostApps() with an invalid interval status = %d", code)
+	}
+}
+
+func Test_seriesMeasurement(t *testing.T) {
+	tests := map[string]string{
+		"cpu,cpu=cpu0,host=web01": "cpu",
+		"disk":                    "disk",
+		`net\,io,host=web01`:      "net,io",
+		`my\ app,host=web01`:      "my app",
+	}
+	for key, want := range tests {
+		if got := seriesMeasurement(key); got != want {
+			t.Errorf("seriesMeasurement(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/csv"
 	"encoding/json"
@@ -27,8 +28,9 @@ func ValidInfluxRequest(p cloudhub.Query) error {
 }
 
 type postInfluxResponse struct {
-	Results interface{} `json:"results"`        // results from influx
-	UUID    string      `json:"uuid,omitempty"` // uuid passed from client to identify results
+	Results interface{} `json:"results"`         // results from influx
+	UUID    string      `json:"uuid,omitempty"`  // uuid passed from client to identify results
+	Query   string      `json:"query,omitempty"` // query sent to influx when rewritten by the query policy of the role
 }
 
 // Influx proxies requests to influxdb.
@@ -71,10 +73,36 @@ func (s *Service) Influx(w http.ResponseWriter, r *http.Request) {
 
 	// inspect request command to specify additional request parameters
 	setupQueryFromCommand(&req)
-	response, err := ts.Query(ctx, req)
+
+	policy := s.queryPolicy(ctx)
+	command, rewritten, err := policy.Apply(req.Command, time.Now())
+	if perr, ok := err.(*influx.PolicyError); ok {
+		queryPolicyError(w, perr, s.Logger)
+		return
+	} else if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	req.Command = command
+
+	results, status, err := s.cachedInfluxQuery(r, src, req, func(ctx context.Context) (json.RawMessage, error) {
+		if policy.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+			defer cancel()
+		}
+		response, err := ts.Query(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(response)
+	})
 	if err != nil {
 		if err == cloudhub.ErrUpstreamTimeout {
 			msg := "Timeout waiting for Influx response"
+			if policy.Timeout > 0 {
+				msg = fmt.Sprintf("The query did not complete within the %s allowed to your role. Narrow its time range, group it by a longer time interval or select fewer series", policy.Timeout)
+			}
 			Error(w, http.StatusRequestTimeout, msg, s.Logger)
 			return
 		}
@@ -82,6 +110,7 @@ func (s *Service) Influx(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
 		return
 	}
+	w.Header().Set("X-Cache", string(status))
 
 	uniqueID := req.UUID
 	if uniqueID == "" {
@@ -94,9 +123,12 @@ func (s *Service) Influx(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res := postInfluxResponse{
-		Results: response,
+		Results: results,
 		UUID:    uniqueID,
 	}
+	if rewritten {
+		res.Query = command
+	}
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
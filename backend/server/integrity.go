@@ -0,0 +1,75 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+type integritySummary struct {
+	Errors   int `json:"errors"`
+	Warnings int `json:"warnings"`
+}
+
+type integrityResponse struct {
+	Issues  []cloudhub.IntegrityIssue `json:"issues"`
+	Summary integritySummary          `json:"summary"`
+	Links   selfLinks                 `json:"links"`
+}
+
+func newIntegrityResponse(issues []cloudhub.IntegrityIssue, severity string) *integrityResponse {
+	res := &integrityResponse{
+		Issues: []cloudhub.IntegrityIssue{},
+		Links: selfLinks{
+			Self: "/cloudhub/v1/integrity",
+		},
+	}
+	for _, issue := range issues {
+		if severity != "" && issue.Severity != severity {
+			continue
+		}
+		switch issue.Severity {
+		case cloudhub.IntegritySeverityError:
+			res.Summary.Errors++
+		case cloudhub.IntegritySeverityWarning:
+			res.Summary.Warnings++
+		}
+		res.Issues = append(res.Issues, issue)
+	}
+	return res
+}
+
+// Integrity reports the dangling references and orphaned resources of all stores.
+// The optional `severity` query parameter filters the issues by severity.
+func (s *Service) Integrity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	issues, err := s.Store.Integrity(ctx).Check(ctx)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	res := newIntegrityResponse(issues, r.URL.Query().Get("severity"))
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// RepairIntegrity removes or re-links the dangling references and orphaned
+// resources of all stores in a single transaction and reports the repairs.
+func (s *Service) RepairIntegrity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	issues, err := s.Store.Integrity(ctx).Repair(trashContext(r))
+	if err != nil {
+		msg := fmt.Sprintf("Error repairing integrity: %v", err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	if len(issues) > 0 {
+		// log registration
+		msg := fmt.Sprintf(MsgIntegrityRepaired.String(), len(issues))
+		s.logRegistration(ctx, "Integrity", msg)
+	}
+
+	encodeJSON(w, http.StatusOK, newIntegrityResponse(issues, ""), s.Logger)
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestService_Integrity(t *testing.T) {
+	issues := []cloudhub.IntegrityIssue{
+		{Severity: cloudhub.IntegritySeverityError, ResourceType: "user", ResourceID: "1", Reference: "gone"},
+		{Severity: cloudhub.IntegritySeverityWarning, ResourceType: "dashboard", ResourceID: "2", Reference: "gone"},
+		{Severity: cloudhub.IntegritySeverityError, ResourceType: "server", ResourceID: "3", Reference: "7"},
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		wantIssues  int
+		wantSummary integritySummary
+	}{
+		{
+			name:        "all issues",
+			url:         "http://any.url/cloudhub/v1/integrity",
+			wantIssues:  3,
+			wantSummary: integritySummary{Errors: 2, Warnings: 1},
+		},
+		{
+			name:        "filtered by severity",
+			url:         "http://any.url/cloudhub/v1/integrity?severity=warning",
+			wantIssues:  1,
+			wantSummary: integritySummary{Warnings: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				Store: &mocks.Store{
+					IntegrityChecker: &mocks.IntegrityChecker{
+						CheckF: func(ctx context.Context) ([]cloudhub.IntegrityIssue, error) {
+							return issues, nil
+						},
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tt.url, nil)
+			s.Integrity(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Integrity() status = %d: %s", w.Code, w.Body.String())
+			}
+			var res integrityResponse
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			if len(res.Issues) != tt.wantIssues {
+				t.Errorf("Integrity() returned %d issues, want %d", len(res.Issues), tt.wantIssues)
+			}
+			if res.Summary != tt.wantSummary {
+				t.Errorf("Integrity() summary = %+v, want %+v", res.Summary, tt.wantSummary)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+type jobLinks struct {
+	Self  string `json:"self"`  // Self link mapping to this resource
+	Retry string `json:"retry"` // Retry link to run the steps that have not succeeded again
+}
+
+type jobResponse struct {
+	cloudhub.Job
+	Links jobLinks `json:"links"`
+}
+
+type jobsResponse struct {
+	Jobs []jobResponse `json:"jobs"`
+}
+
+func newJobResponse(job cloudhub.Job) jobResponse {
+	self := fmt.Sprintf("/cloudhub/v1/jobs/%s", job.ID)
+	return jobResponse{
+		Job: job,
+		Links: jobLinks{
+			Self:  self,
+			Retry: self + "/retry",
+		},
+	}
+}
+
+// Jobs returns the tracked jobs, newest first.
+// The optional `type` and `status` query parameters filter the jobs.
+func (s *Service) Jobs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	jobs, err := s.Store.Jobs(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading jobs", s.Logger)
+		return
+	}
+
+	kind := r.URL.Query().Get("type")
+	status := r.URL.Query().Get("status")
+	res := jobsResponse{
+		Jobs: []jobResponse{},
+	}
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if kind != "" && jobs[i].Type != kind {
+			continue
+		}
+		if status != "" && jobs[i].Status != status {
+			continue
+		}
+		res.Jobs = append(res.Jobs, newJobResponse(jobs[i]))
+	}
+
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// JobID returns a single job with the status of its steps
+func (s *Service) JobID(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	job, err := s.Store.Jobs(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	encodeJSON(w, http.StatusOK, newJobResponse(*job), s.Logger)
+}
+
+// RetryJob runs the steps of a job that have not succeeded again
+func (s *Service) RetryJob(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	jobCtx := jobContext(r)
+
+	// the lock of the job is held by the instance running it
+	unlock, ok, err := s.coordinator().TryLock(jobCtx, jobLock(id))
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+	if !ok {
+		Error(w, http.StatusConflict, fmt.Sprintf("job %s is running", id), s.Logger)
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			unlock()
+		}
+	}()
+
+	job, err := s.Store.Jobs(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	if job.Status == cloudhub.JobSucceeded {
+		Error(w, http.StatusConflict, fmt.Sprintf("job %s has already succeeded", job.ID), s.Logger)
+		return
+	}
+
+	var run func()
+	switch job.Type {
+	case cloudhub.JobOrganizationDeletion:
+		run = func() { s.runOrgDeletion(jobCtx, job) }
+	default:
+		invalidData(w, fmt.Errorf("job type %q cannot be retried", job.Type), s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgJobRetried.String(), job.ID, job.Name)
+	s.logRegistration(ctx, "Jobs", msg)
+
+	encodeJSON(w, http.StatusAccepted, newJobResponse(*job), s.Logger)
+
+	started = true
+	go func() {
+		defer unlock()
+		run()
+	}()
+}
@@ -25,6 +25,10 @@ const (
 	MsgOrganizationModified = logMessage("%s has been modified.")
 	MsgOrganizationDeleted  = logMessage("%s has been deleted.")
 
+	// Organization Templates
+	MsgOrgTemplateCreated = logMessage("Template %s has been saved from %s.")
+	MsgOrgTemplateDeleted = logMessage("Template %s has been deleted.")
+
 	// Mappings
 	MsgMappingCreated  = logMessage("%s Mapping has been created.")
 	MsgMappingModified = logMessage("%s Mapping has been modified.")
@@ -71,6 +75,26 @@ const (
 	MsgDashboardModified = logMessage("%s has been modified.")
 	MsgDashboardDeleted  = logMessage("%s has been deleted.")
 
+	// Protoboards
+	MsgProtoboardCreated  = logMessage("Protoboard %s has been created.")
+	MsgProtoboardModified = logMessage("Protoboard %s has been modified to version %s.")
+	MsgProtoboardDeleted  = logMessage("Protoboard %s has been deleted.")
+
+	// Reports
+	MsgReportCreated  = logMessage("Report %s has been created.")
+	MsgReportModified = logMessage("Report %s has been modified.")
+	MsgReportDeleted  = logMessage("Report %s has been deleted.")
+	MsgReportSent     = logMessage("Report %s has been sent: %s.")
+
+	MsgSnapshotCreated = logMessage("Snapshot %s of dashboard %s has been created.")
+	MsgSnapshotDeleted = logMessage("Snapshot %s has been deleted.")
+
+	// Folders
+	MsgFolderCreated  = logMessage("Folder %s has been created.")
+	MsgFolderModified = logMessage("Folder %s has been modified.")
+	MsgFolderDeleted  = logMessage("Folder %s has been deleted.")
+	MsgDashboardMoved = logMessage("%s has been moved to %s.")
+
 	// Dashboards Cells
 	MsgDashboardCellCreated  = logMessage("%s has been created in %s.")
 	MsgDashboardCellModified = logMessage("%s has been modified in %s.")
@@ -132,6 +156,17 @@ const (
 	MsgNetWorkDeviceConfCreated  = logMessage("NetWorkDevice LogStash Config %s has been created.")
 	MsgNetWorkDeviceConfModified = logMessage("NetWorkDevice LogStash Config %s has been modified.")
 	MsgNetWorkDeviceConfgDeleted = logMessage("NetWorkDevice LogStash Config %s has been deleted.")
+
+	// Trash
+	MsgTrashRestored = logMessage("%s %s has been restored from the trash.")
+	MsgTrashRemoved  = logMessage("%s %s has been permanently deleted from the trash.")
+
+	// Integrity
+	MsgIntegrityRepaired = logMessage("%d integrity issues have been repaired.")
+
+	// Jobs
+	MsgOrganizationDeletionStarted = logMessage("Deletion of organization %s has been started.")
+	MsgJobRetried                  = logMessage("Job %s for %s has been retried.")
 )
 
 type proxyLogRequest struct {
@@ -173,6 +173,10 @@ func NewMux(opts MuxOpts, service Service) http.Handler {
 	// User password change
 	router.PATCH("/basic/password", service.UserPassword)
 
+	// Shared snapshots, read-only without login
+	router.GET("/public/snapshots/:token", service.PublicSnapshot)
+	router.GET("/public/snapshots/:token/cells/:cid/render", service.PublicSnapshotCellRender)
+
 	// User password change
 	router.PATCH("/cloudhub/v1/basic/password", EnsureAdmin(service.UserPassword))
 
@@ -188,6 +192,23 @@ func NewMux(opts MuxOpts, service Service) http.Handler {
 	router.GET("/cloudhub/v1/organizations/:oid", EnsureAdmin(service.OrganizationID))
 	router.PATCH("/cloudhub/v1/organizations/:oid", EnsureSuperAdmin(service.UpdateOrganization))
 	router.DELETE("/cloudhub/v1/organizations/:oid", EnsureSuperAdmin(service.RemoveOrganization))
+	router.GET("/cloudhub/v1/organizations/:oid/deletion-plan", EnsureSuperAdmin(service.OrganizationDeletionPlan))
+	router.POST("/cloudhub/v1/organizations/:oid/deletion", EnsureSuperAdmin(service.NewOrganizationDeletion))
+	router.GET("/cloudhub/v1/organizations/:oid/usage", EnsureSuperAdmin(service.OrganizationUsage))
+	router.POST("/cloudhub/v1/organizations/:oid/clone", EnsureSuperAdmin(service.CloneOrganization))
+
+	// Organization Templates
+	router.GET("/cloudhub/v1/org-templates", EnsureSuperAdmin(service.OrgTemplates))
+	router.POST("/cloudhub/v1/org-templates", EnsureSuperAdmin(service.NewOrgTemplate))
+	router.GET("/cloudhub/v1/org-templates/:id", EnsureSuperAdmin(service.OrgTemplateID))
+	router.DELETE("/cloudhub/v1/org-templates/:id", EnsureSuperAdmin(service.RemoveOrgTemplate))
+	router.POST("/cloudhub/v1/org-templates/:id/instantiate", EnsureSuperAdmin(service.InstantiateOrgTemplate))
+	router.GET("/cloudhub/v1/usage", EnsureViewer(service.OrganizationUsage))
+
+	// Jobs
+	router.GET("/cloudhub/v1/jobs", EnsureSuperAdmin(service.Jobs))
+	router.GET("/cloudhub/v1/jobs/:id", EnsureSuperAdmin(service.JobID))
+	router.POST("/cloudhub/v1/jobs/:id/retry", EnsureSuperAdmin(service.RetryJob))
 
 	// Mappings
 	router.GET("/cloudhub/v1/mappings", EnsureSuperAdmin(service.Mappings))
@@ -204,6 +225,7 @@ func NewMux(opts MuxOpts, service Service) http.Handler {
 	router.PATCH("/cloudhub/v1/sources/:id", EnsureEditor(service.UpdateSource))
 	router.DELETE("/cloudhub/v1/sources/:id", EnsureEditor(service.RemoveSource))
 	router.GET("/cloudhub/v1/sources/:id/health", EnsureViewer(service.SourceHealth))
+	router.GET("/cloudhub/v1/sources/:id/hosts/:host/apps", EnsureViewer(service.HostApps))
 
 	// Flux
 	router.GET("/cloudhub/v1/flux", EnsureViewer(service.Flux))
@@ -215,10 +237,17 @@ func NewMux(opts MuxOpts, service Service) http.Handler {
 	influx := gziphandler.GzipHandler(EnsureViewer(service.Influx))
 	router.Handler("POST", "/cloudhub/v1/sources/:id/proxy", influx)
 
+	// Metrics of the cache of the results of the queries proxied to Influx
+	router.GET("/cloudhub/v1/query-cache", EnsureSuperAdmin(service.QueryCacheStats))
+	router.DELETE("/cloudhub/v1/query-cache", EnsureSuperAdmin(service.FlushQueryCache))
+
 	// Source Proxy to Influx's flux endpoint; compression because the responses from
 	// flux could be large.
 	router.POST("/cloudhub/v1/sources/:id/proxy/flux", EnsureViewer(service.ProxyFlux))
 
+	// Export streams the results of queries of a source as CSV, NDJSON or line protocol
+	router.POST("/cloudhub/v1/sources/:id/export", EnsureViewer(service.Export))
+
 	// Write proxies line protocol write requests to InfluxDB
 	router.POST("/cloudhub/v1/sources/:id/write", EnsureViewer(service.Write))
 
@@ -301,7 +330,10 @@ func NewMux(opts MuxOpts, service Service) http.Handler {
 
 	// Protoboards
 	router.GET("/cloudhub/v1/protoboards", EnsureViewer(service.Protoboards))
+	router.POST("/cloudhub/v1/protoboards", EnsureSuperAdmin(service.NewProtoboard))
 	router.GET("/cloudhub/v1/protoboards/:id", EnsureViewer(service.ProtoboardsID))
+	router.PUT("/cloudhub/v1/protoboards/:id", EnsureSuperAdmin(service.ReplaceProtoboard))
+	router.DELETE("/cloudhub/v1/protoboards/:id", EnsureSuperAdmin(service.RemoveProtoboard))
 
 	// Users associated with CloudHub
 	router.GET("/cloudhub/v1/me", service.Me)
@@ -332,6 +364,12 @@ func NewMux(opts MuxOpts, service Service) http.Handler {
 	router.DELETE("/cloudhub/v1/dashboards/:id", EnsureEditor(service.RemoveDashboard))
 	router.PUT("/cloudhub/v1/dashboards/:id", EnsureEditor(service.ReplaceDashboard))
 	router.PATCH("/cloudhub/v1/dashboards/:id", EnsureEditor(service.UpdateDashboard))
+	router.GET("/cloudhub/v1/dashboards/:id/export", EnsureViewer(service.DashboardExport))
+	router.POST("/cloudhub/v1/dashboards/:id/protoboard", EnsureSuperAdmin(service.DashboardProtoboard))
+	router.PUT("/cloudhub/v1/dashboards/:id/folder", EnsureEditor(service.MoveDashboard))
+	// The path of imports cannot be under /dashboards, whose :id would match it
+	router.POST("/cloudhub/v1/dashboard-imports", EnsureEditor(service.DashboardImport))
+	router.POST("/cloudhub/v1/dashboard-imports/grafana", EnsureEditor(service.GrafanaDashboardImport))
 
 	// Dashboard Cells
 	router.GET("/cloudhub/v1/dashboards/:id/cells", EnsureViewer(service.DashboardCells))
@@ -340,15 +378,30 @@ func NewMux(opts MuxOpts, service Service) http.Handler {
 	router.GET("/cloudhub/v1/dashboards/:id/cells/:cid", EnsureViewer(service.DashboardCellID))
 	router.DELETE("/cloudhub/v1/dashboards/:id/cells/:cid", EnsureEditor(service.RemoveDashboardCell))
 	router.PUT("/cloudhub/v1/dashboards/:id/cells/:cid", EnsureEditor(service.ReplaceDashboardCell))
+	router.GET("/cloudhub/v1/dashboards/:id/cells/:cid/render", EnsureViewer(service.DashboardCellRender))
 
 	// Dashboard Templates
 	router.GET("/cloudhub/v1/dashboards/:id/templates", EnsureViewer(service.Templates))
 	router.POST("/cloudhub/v1/dashboards/:id/templates", EnsureEditor(service.NewTemplate))
 
 	router.GET("/cloudhub/v1/dashboards/:id/templates/:tid", EnsureViewer(service.TemplateID))
+	router.GET("/cloudhub/v1/dashboards/:id/templates/:tid/values", EnsureViewer(service.DashboardTemplateValues))
 	router.DELETE("/cloudhub/v1/dashboards/:id/templates/:tid", EnsureEditor(service.RemoveTemplate))
 	router.PUT("/cloudhub/v1/dashboards/:id/templates/:tid", EnsureEditor(service.ReplaceTemplate))
 
+	// Dashboard Revisions
+	router.GET("/cloudhub/v1/dashboards/:id/revisions", EnsureViewer(service.DashboardRevisions))
+	router.GET("/cloudhub/v1/dashboards/:id/revisions/:rid", EnsureViewer(service.DashboardRevisionID))
+	router.GET("/cloudhub/v1/dashboards/:id/revisions/:rid/diff", EnsureViewer(service.DashboardRevisionDiff))
+	router.POST("/cloudhub/v1/dashboards/:id/revisions/:rid/restore", EnsureEditor(service.RestoreDashboardRevision))
+
+	// Dashboard Folders
+	router.GET("/cloudhub/v1/folders", EnsureViewer(service.Folders))
+	router.POST("/cloudhub/v1/folders", EnsureAdmin(service.NewFolder))
+	router.GET("/cloudhub/v1/folders/:id", EnsureViewer(service.FolderID))
+	router.PATCH("/cloudhub/v1/folders/:id", EnsureAdmin(service.UpdateFolder))
+	router.DELETE("/cloudhub/v1/folders/:id", EnsureAdmin(service.RemoveFolder))
+
 	// Databases
 	router.GET("/cloudhub/v1/sources/:id/dbs", EnsureViewer(service.GetDatabases))
 	router.POST("/cloudhub/v1/sources/:id/dbs", EnsureEditor(service.NewDatabase))
@@ -384,12 +437,46 @@ func NewMux(opts MuxOpts, service Service) http.Handler {
 	router.DELETE("/cloudhub/v1/vspheres/:id", EnsureAdmin(service.RemoveVsphere))
 	router.PATCH("/cloudhub/v1/vspheres/:id", EnsureAdmin(service.UpdateVsphere))
 
+	// search
+	router.GET("/cloudhub/v1/search", EnsureViewer(service.Search))
+
 	// topologies
 	router.GET("/cloudhub/v1/topologies", EnsureViewer(service.Topology))
 	router.POST("/cloudhub/v1/topologies", EnsureViewer(service.NewTopology))
 	router.DELETE("/cloudhub/v1/topologies/:id", EnsureViewer(service.RemoveTopology))
 	router.PATCH("/cloudhub/v1/topologies/:id", EnsureViewer(service.UpdateTopology))
 
+	// Trash
+	router.GET("/cloudhub/v1/trash", EnsureEditor(service.Trash))
+	router.GET("/cloudhub/v1/trash/:id", EnsureEditor(service.TrashItemID))
+	router.POST("/cloudhub/v1/trash/:id/restore", EnsureEditor(service.RestoreTrashItem))
+	router.DELETE("/cloudhub/v1/trash/:id", EnsureAdmin(service.RemoveTrashItem))
+
+	// Reports
+	router.GET("/cloudhub/v1/reports", EnsureViewer(service.Reports))
+	router.POST("/cloudhub/v1/reports", EnsureEditor(service.NewReport))
+	router.GET("/cloudhub/v1/reports/:id", EnsureViewer(service.ReportID))
+	router.PUT("/cloudhub/v1/reports/:id", EnsureEditor(service.UpdateReport))
+	router.DELETE("/cloudhub/v1/reports/:id", EnsureEditor(service.RemoveReport))
+	router.POST("/cloudhub/v1/reports/:id/send", EnsureEditor(service.SendReport))
+	router.GET("/cloudhub/v1/reports/:id/runs", EnsureViewer(service.ReportRuns))
+
+	// Snapshots
+	router.POST("/cloudhub/v1/dashboards/:id/snapshots", EnsureEditor(service.NewDashboardSnapshot))
+	router.GET("/cloudhub/v1/snapshots", EnsureViewer(service.Snapshots))
+	router.GET("/cloudhub/v1/snapshots/:id", EnsureViewer(service.SnapshotID))
+	router.DELETE("/cloudhub/v1/snapshots/:id", EnsureEditor(service.RemoveSnapshot))
+
+	// Integrity
+	router.GET("/cloudhub/v1/integrity", EnsureSuperAdmin(service.Integrity))
+	router.POST("/cloudhub/v1/integrity/repair", EnsureSuperAdmin(service.RepairIntegrity))
+
+	// Topology Revisions
+	router.GET("/cloudhub/v1/topologies/:id/revisions", EnsureViewer(service.TopologyRevisions))
+	router.GET("/cloudhub/v1/topologies/:id/revisions/:rid", EnsureViewer(service.TopologyRevisionID))
+	router.GET("/cloudhub/v1/topologies/:id/revisions/:rid/diff", EnsureViewer(service.TopologyRevisionDiff))
+	router.POST("/cloudhub/v1/topologies/:id/revisions/:rid/restore", EnsureViewer(service.RestoreTopologyRevision))
+
 	// Cloud Solution Provider
 	router.GET("/cloudhub/v1/csp", EnsureViewer(service.CSP))
 	router.GET("/cloudhub/v1/csp/:id", EnsureViewer(service.CSPID))
@@ -9,6 +9,7 @@ import (
 	"path"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -260,20 +261,19 @@ func (s *Service) NewDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allDevices, err := s.Store.NetworkDevice(ctx).All(ctx)
-	if err != nil {
-		Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
+	if err := s.checkDeviceQuotas(ctx, reqs); err != nil {
+		quotaExceeded(w, err, s.Logger)
 		return
 	}
 
-	existingIPs := make(map[string]bool)
-	for _, device := range allDevices {
-		existingIPs[device.DeviceIP] = true
-	}
-
 	var failedDeviceList []createDeviceError
 	for i, req := range reqs {
-		if _, exists := existingIPs[req.DeviceIP]; exists {
+		existing, err := s.Store.NetworkDevice(ctx).Find(ctx, cloudhub.NetworkDeviceQuery{DeviceIP: &req.DeviceIP})
+		if err != nil {
+			Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
+			return
+		}
+		if len(existing) > 0 {
 			failedDeviceList = append(failedDeviceList, createDeviceError{
 				Index:        i,
 				DeviceIP:     req.DeviceIP,
@@ -310,24 +310,22 @@ func (s *Service) NewDevices(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	allDevices, err := s.Store.NetworkDevice(ctx).All(ctx)
-	if err != nil {
-		Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
+	if err := s.checkDeviceQuotas(ctx, reqs); err != nil {
+		quotaExceeded(w, err, s.Logger)
 		return
 	}
 
 	var failedDeviceList []createDeviceError
-	existingIPs := make(map[string]string)
-
-	for _, device := range allDevices {
-		existingIPs[device.DeviceIP] = device.ID
-	}
-
 	for i, req := range reqs {
 		currentReq := req
-		if id, exists := existingIPs[currentReq.DeviceIP]; exists {
+		existing, err := s.Store.NetworkDevice(ctx).Find(ctx, cloudhub.NetworkDeviceQuery{DeviceIP: &currentReq.DeviceIP})
+		if err != nil {
+			Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
+			return
+		}
+		if len(existing) > 0 {
 			_, err := s.UpdateDevice(ctx, &updateDeviceData{
-				id: id,
+				id: existing[0].ID,
 				updateDeviceRequest: updateDeviceRequest{
 					Organization: &currentReq.Organization,
 					DeviceIP:     &currentReq.DeviceIP,
@@ -367,32 +365,58 @@ func (s *Service) NewDevices(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// deviceListFields are the fields the list of devices can be filtered and sorted by
+var deviceListFields = listFields[cloudhub.NetworkDevice]{
+	"organization":    func(d cloudhub.NetworkDevice) string { return d.Organization },
+	"device_ip":       func(d cloudhub.NetworkDevice) string { return d.DeviceIP },
+	"hostname":        func(d cloudhub.NetworkDevice) string { return d.Hostname },
+	"device_type":     func(d cloudhub.NetworkDevice) string { return d.DeviceType },
+	"device_category": func(d cloudhub.NetworkDevice) string { return d.DeviceCategory },
+	"device_os":       func(d cloudhub.NetworkDevice) string { return d.DeviceOS },
+	"device_vendor":   func(d cloudhub.NetworkDevice) string { return d.DeviceVendor },
+	"learning_state":  func(d cloudhub.NetworkDevice) string { return d.LearningState },
+	"is_learning":     func(d cloudhub.NetworkDevice) string { return strconv.FormatBool(d.IsLearning) },
+}
+
 // AllDevices returns all devices within the store.
 func (s *Service) AllDevices(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	devices, err := s.Store.NetworkDevice(ctx).All(ctx)
+	opts, err := newListOptions(r, deviceListFields)
 	if err != nil {
-		Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
 		return
 	}
+
 	isSuperAdmin := hasSuperAdminContext(ctx)
 	currentOrg, ok := hasOrganizationContext(ctx)
 	if !ok {
 		Error(w, http.StatusInternalServerError, string(cloudhub.ErrOrganizationNotFound), s.Logger)
 		return
 	}
+
+	// the organization and device IP filters are looked up by the store indexes
+	q := cloudhub.NetworkDeviceQuery{}
 	if !(isSuperAdmin && currentOrg == DefaultOrganizationID) {
-		devicesByOrg := devices[:0]
-		for _, d := range devices {
-			if d.Organization == currentOrg {
-				devicesByOrg = append(devicesByOrg, d)
-			}
-		}
-		res := newDevicesResponse(ctx, s, devicesByOrg)
-		encodeJSON(w, http.StatusOK, res, s.Logger)
+		q.Organization = &currentOrg
+	} else if org, ok := opts.filter("organization"); ok {
+		q.Organization = &org
+	}
+	if ip, ok := opts.filter("device_ip"); ok {
+		q.DeviceIP = &ip
+	}
+
+	devices, err := s.Store.NetworkDevice(ctx).Find(ctx, q)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
 		return
 	}
-	res := newDevicesResponse(ctx, s, devices)
+	if q.Organization != nil {
+		opts.filters["organization"] = []string{*q.Organization}
+	}
+
+	page, next := paginate(devices, opts, deviceListFields, func(d cloudhub.NetworkDevice) string { return d.ID })
+	res := newDevicesResponse(ctx, s, page)
+	setNextLink(w, r, next)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -437,11 +461,20 @@ func (s *Service) RemoveDevices(w http.ResponseWriter, r *http.Request) {
 		device, err := s.Store.NetworkDevice(ctx).Get(ctx, cloudhub.NetworkDeviceQuery{ID: &deviceID})
 		if err != nil {
 			addFailedDevice(failedDevices, deviceID, err)
+			continue
 		}
 		devicesGroupByOrg[device.Organization] = append(devicesGroupByOrg[device.Organization], device.ID)
 		deviceOrgMap[deviceID] = device.Organization
 	}
 
+	// the Logstash configs and the device lists of the organizations are
+	// changed by one request at a time, like when they are managed
+	unlock, ok := s.lockAll(w, r, append(s.deviceOrgLocks(ctx, deviceOrgMap), collectorsLock)...)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	activeCollectorKeys := &sync.Map{}
 
 	for orgID, devices := range devicesGroupByOrg {
@@ -567,7 +600,7 @@ func (s *Service) RemoveDevices(w http.ResponseWriter, r *http.Request) {
 			addFailedDevice(failedDevices, id, err)
 			return
 		}
-		err = s.Store.NetworkDevice(ctx).Delete(ctx, device)
+		err = s.Store.NetworkDevice(ctx).Delete(trashContext(r), device)
 		if err != nil {
 			addFailedDevice(failedDevices, id, err)
 			return
@@ -786,6 +819,13 @@ func (s *Service) MonitoringConfigManagement(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// the collectors are balanced and their Logstash configs written by one instance at a time
+	unlock, ok := s.lock(w, r, collectorsLock)
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	devicesData := getDevicesGroupByOrg(ctx, s, request.CollectingDevices)
 	failedDevices := devicesData.failedDevices
 	restartCollectorServers := map[string]string{}
@@ -959,6 +999,14 @@ func (s *Service) LearningDeviceManagement(w http.ResponseWriter, r *http.Reques
 
 	devicesData := getLearnedDevicesGroupByOrg(ctx, s, request.IsLearningDevices)
 	failedDevices := devicesData.failedDevices
+
+	// the learned devices are checked against the quota and written while
+	// no other request changes the learning of their organizations
+	unlock, ok := s.lockAll(w, r, s.deviceOrgLocks(ctx, devicesData.deviceOrgMap)...)
+	if !ok {
+		return
+	}
+	defer unlock()
 	orgsToUpdate, err := removeDeviceIDsFromPreviousOrg(ctx, s, devicesData.deviceOrgMap)
 	if err != nil {
 		Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
@@ -996,6 +1044,13 @@ func (s *Service) LearningDeviceManagement(w http.ResponseWriter, r *http.Reques
 		orgsToUpdate[org] = orgInfo
 	}
 
+	for org, orgInfo := range orgsToUpdate {
+		if err := s.checkLearnedDevices(ctx, org, orgInfo.LearnedDevicesIDs); err != nil {
+			quotaExceeded(w, err, s.Logger)
+			return
+		}
+	}
+
 	// Update the store only for successful orgInfos
 	for org, orgInfo := range orgsToUpdate {
 		existOrg, err := s.Store.NetworkDeviceOrg(ctx).Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &org})
@@ -1039,6 +1094,29 @@ func addFailedDevice(failedDevices map[string]string, id string, err error) {
 	}
 }
 
+// deviceOrgLocks returns the learning task locks of the organizations of the
+// devices of deviceOrgMap and of the organizations listing them
+func (s *Service) deviceOrgLocks(ctx context.Context, deviceOrgMap map[string]string) []string {
+	orgs := map[string]bool{}
+	for _, org := range deviceOrgMap {
+		orgs[org] = true
+	}
+	if all, err := s.Store.NetworkDeviceOrg(ctx).All(ctx); err == nil {
+		for _, org := range all {
+			for _, id := range append(append([]string{}, org.LearnedDevicesIDs...), org.CollectedDevicesIDs...) {
+				if _, ok := deviceOrgMap[id]; ok {
+					orgs[org.ID] = true
+				}
+			}
+		}
+	}
+	locks := make([]string, 0, len(orgs))
+	for org := range orgs {
+		locks = append(locks, learningTaskLock(org))
+	}
+	return locks
+}
+
 // removeDeviceIDsFromPreviousOrg removes device IDs from their previous organizations
 func removeDeviceIDsFromPreviousOrg(ctx context.Context, s *Service, deviceOrgMap map[string]string) (map[string]cloudhub.NetworkDeviceOrg, error) {
 	orgsToUpdate := make(map[string]cloudhub.NetworkDeviceOrg)
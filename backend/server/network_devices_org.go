@@ -42,6 +42,7 @@ type deviceOrgResponse struct {
 	AIKapacitor         cloudhub.AIKapacitor `json:"ai_kapacitor"`
 	LearningCron        string               `json:"learning_cron"`
 	ProcCnt             int                  `json:"process_count"`
+	Version             uint64               `json:"version"`
 }
 type updateDeviceOrgRequest struct {
 	LoadModule          *string               `json:"load_module,omitempty"`
@@ -247,6 +248,7 @@ func newDeviceOrgResponse(deviceOrg *cloudhub.NetworkDeviceOrg) (*deviceOrgRespo
 		AIKapacitor:         deviceOrg.AIKapacitor,
 		LearningCron:        deviceOrg.LearningCron,
 		ProcCnt:             deviceOrg.ProcCnt,
+		Version:             deviceOrg.Version,
 	}
 
 	return resData, nil
@@ -277,6 +279,12 @@ func (s *Service) AddNetworkDeviceOrg(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	unlock, ok := s.lock(w, r, learningTaskLock(req.ID))
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	existingDeviceOrg, _ := s.Store.NetworkDeviceOrg(ctx).Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &req.ID})
 	if existingDeviceOrg != nil {
 		Error(w, http.StatusConflict, fmt.Sprintf("Device Org with ID %s already exists", req.ID), s.Logger)
@@ -349,6 +357,7 @@ func (s *Service) NetworkDeviceOrgID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, deviceOrg.Version)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -377,16 +386,27 @@ func (s *Service) UpdateNetworkDeviceOrg(w http.ResponseWriter, r *http.Request)
 	}
 	ctx := r.Context()
 
+	unlock, ok := s.lock(w, r, learningTaskLock(idStr))
+	if !ok {
+		return
+	}
+	defer unlock()
+
 	deviceOrg, err := s.Store.NetworkDeviceOrg(ctx).Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &idStr})
 	if err != nil {
 		notFound(w, idStr, s.Logger)
 		return
 	}
+	if deviceOrg.Version, err = expectedVersion(r, deviceOrg.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
 	org, err := s.Store.Organizations(ctx).Get(ctx, cloudhub.OrganizationQuery{ID: &idStr})
 	if err != nil {
 		notFound(w, idStr, s.Logger)
 		return
 	}
+	previous := *deviceOrg
 	previousAIKapacitor = deviceOrg.AIKapacitor
 	deviceOrg.ProcCnt = req.ProcCnt
 
@@ -403,6 +423,11 @@ func (s *Service) UpdateNetworkDeviceOrg(w http.ResponseWriter, r *http.Request)
 		deviceOrg.CollectedDevicesIDs = *req.CollectedDevicesIDs
 	}
 	if req.LearnedDevicesIDs != nil {
+		// the list replaces the learned devices, the lock keeps it within the quota until written
+		if err := s.checkLearnedDevices(ctx, idStr, *req.LearnedDevicesIDs); err != nil {
+			quotaExceeded(w, err, s.Logger)
+			return
+		}
 		deviceOrg.LearnedDevicesIDs = *req.LearnedDevicesIDs
 	}
 	if req.CollectorServer != nil {
@@ -425,8 +450,14 @@ func (s *Service) UpdateNetworkDeviceOrg(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	if isChangedKapaURL && previousAIKapacitor.KapaURL != "" {
-		deleteLearningTask(ctx, s, org, previousAIKapacitor)
+	// the versioned update comes first so that a conflicting update leaves Kapacitor unchanged
+	if err := s.Store.NetworkDeviceOrg(ctx).Update(ctx, deviceOrg); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err != nil {
+		msg := fmt.Sprintf("Error updating Device Org ID %s: %v", idStr, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
 	}
 
 	reqTask := deviceOrgRequest{
@@ -440,15 +471,18 @@ func (s *Service) UpdateNetworkDeviceOrg(w http.ResponseWriter, r *http.Request)
 	}
 	err = manageLearningTask(ctx, s, org, reqTask, deviceOrg)
 	if err != nil {
+		// the settings are put back as they were, along with the task they describe
+		previous.Version = deviceOrg.Version
+		if err := s.Store.NetworkDeviceOrg(ctx).Update(ctx, &previous); err != nil {
+			s.Logger.Error(fmt.Sprintf("Unable to restore Device Org ID %s: %v", idStr, err))
+		}
 		msg := fmt.Sprintf("Error updating TickSCript %s: %v", idStr, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
 	}
 
-	if err := s.Store.NetworkDeviceOrg(ctx).Update(ctx, deviceOrg); err != nil {
-		msg := fmt.Sprintf("Error updating Device Org ID %s: %v", idStr, err)
-		Error(w, http.StatusInternalServerError, msg, s.Logger)
-		return
+	if isChangedKapaURL && previousAIKapacitor.KapaURL != "" {
+		deleteLearningTask(ctx, s, org, previousAIKapacitor)
 	}
 
 	msg := fmt.Sprintf(MsgNetWorkDeviceModified.String(), idStr)
@@ -462,6 +496,7 @@ func (s *Service) UpdateNetworkDeviceOrg(w http.ResponseWriter, r *http.Request)
 
 	msg = fmt.Sprintf(MsgNetWorkDeviceOrgModified.String(), idStr)
 	s.logRegistration(ctx, "NetWorkDeviceOrg", msg)
+	setETag(w, deviceOrg.Version)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -481,7 +516,16 @@ func (s *Service) RemoveNetworkDeviceOrg(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := s.Store.NetworkDeviceOrg(ctx).Delete(ctx, &cloudhub.NetworkDeviceOrg{ID: deviceOrg.ID}); err != nil {
+	version, err := deleteVersion(r, deviceOrg.Version)
+	if err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+
+	if err := s.Store.NetworkDeviceOrg(ctx).Delete(ctx, &cloudhub.NetworkDeviceOrg{ID: deviceOrg.ID, Version: version}); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err != nil {
 		Error(w, http.StatusInternalServerError, fmt.Sprintf("Error removing Device Org ID %s: %v", idStr, err), s.Logger)
 		return
 	}
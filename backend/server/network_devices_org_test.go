@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestService_UpdateNetworkDeviceOrg_Kapacitor(t *testing.T) {
+	var kapacitorRequests int
+	kapacitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kapacitorRequests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer kapacitor.Close()
+
+	tests := []struct {
+		name        string
+		updateErr   error
+		wantStatus  int
+		wantUpdates []string
+	}{
+		{
+			name:        "conflicting update leaves Kapacitor unchanged",
+			updateErr:   cloudhub.ErrVersionConflict,
+			wantStatus:  http.StatusPreconditionFailed,
+			wantUpdates: []string{"0 0 * * *"},
+		},
+		{
+			name:        "failed learning task puts the settings back",
+			wantStatus:  http.StatusInternalServerError,
+			wantUpdates: []string{"0 0 * * *", "1 1 * * *"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kapacitorRequests = 0
+			var updates []string
+			s := &Service{
+				Store: &mocks.Store{
+					NetworkDeviceOrgStore: &mocks.NetworkDeviceOrgStore{
+						GetF: func(ctx context.Context, q cloudhub.NetworkDeviceOrgQuery) (*cloudhub.NetworkDeviceOrg, error) {
+							return &cloudhub.NetworkDeviceOrg{
+								ID:           "1",
+								LearningCron: "1 1 * * *",
+								AIKapacitor:  cloudhub.AIKapacitor{KapaURL: kapacitor.URL},
+								Version:      3,
+							}, nil
+						},
+						UpdateF: func(ctx context.Context, o *cloudhub.NetworkDeviceOrg) error {
+							updates = append(updates, o.LearningCron)
+							if tt.updateErr != nil {
+								return tt.updateErr
+							}
+							o.Version++
+							return nil
+						},
+					},
+					OrganizationsStore: &mocks.OrganizationsStore{
+						GetF: func(ctx context.Context, q cloudhub.OrganizationQuery) (*cloudhub.Organization, error) {
+							return &cloudhub.Organization{ID: "1", Name: "snet"}, nil
+						},
+					},
+					SourcesStore: &mocks.SourcesStore{
+						GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+							return cloudhub.Source{}, cloudhub.ErrSourceNotFound
+						},
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			body := `{"learning_cron": "0 0 * * *", "data_duration": 1}`
+			r := httptest.NewRequest("PATCH", "http://any.url", strings.NewReader(body))
+			r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}}))
+			w := httptest.NewRecorder()
+			s.UpdateNetworkDeviceOrg(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("UpdateNetworkDeviceOrg() status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if strings.Join(updates, ",") != strings.Join(tt.wantUpdates, ",") {
+				t.Errorf("UpdateNetworkDeviceOrg() updated %v, want %v", updates, tt.wantUpdates)
+			}
+			if kapacitorRequests != 0 {
+				t.Errorf("UpdateNetworkDeviceOrg() sent %d requests to Kapacitor, want none", kapacitorRequests)
+			}
+		})
+	}
+}
@@ -743,9 +743,20 @@ func MockDeviceStoreSetup(mockData []createDeviceRequest) *mocks.NetworkDeviceSt
 		return deviceList, nil
 	}
 
+	findFunc := func(ctx context.Context, q cloudhub.NetworkDeviceQuery) ([]cloudhub.NetworkDevice, error) {
+		var deviceList []cloudhub.NetworkDevice
+		for _, dev := range devices {
+			if q.DeviceIP == nil || *q.DeviceIP == dev.DeviceIP {
+				deviceList = append(deviceList, *dev)
+			}
+		}
+		return deviceList, nil
+	}
+
 	return &mocks.NetworkDeviceStore{
-		AddF: addFunc,
-		AllF: allFunc,
+		AddF:  addFunc,
+		AllF:  allFunc,
+		FindF: findFunc,
 	}
 }
 
@@ -0,0 +1,548 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	kapa "github.com/snetsystems/cloudhub/backend/kapacitor"
+	"github.com/snetsystems/cloudhub/backend/organizations"
+)
+
+// Kinds of organization deletion steps
+const (
+	deletionKapacitor = "kapacitor" // removes a Kapacitor task of the AI settings of the organization
+	deletionLogstash  = "logstash"  // removes the Logstash SNMP config from the collector server
+	deletionSalt      = "salt"      // removes the Salt provider and Telegraf configs of an OSP CSP
+	deletionStore     = "store"     // removes the resources of one store owned by the organization
+)
+
+// Resources removed by the store steps, in the order they are removed.
+// Device results are removed before the devices whose IPs they are keyed by,
+// and the organization itself is removed last but for the trash its
+// resources were moved to.
+const (
+	deletionMLNxRst          = "ml_nx_rst"
+	deletionDLNxRst          = "dl_nx_rst"
+	deletionNetworkDeviceOrg = "network_device_org"
+	deletionNetworkDevice    = "network_device"
+	deletionTopology         = "topology"
+	deletionCSP              = "csp"
+	deletionVsphere          = "vsphere"
+	deletionReport           = "report"
+	deletionSnapshot         = "snapshot"
+	deletionFolder           = "folder"
+	deletionOrganization     = "organization"
+	deletionTrash            = "trash"
+)
+
+type orgDeletionPlanResponse struct {
+	Organization string             `json:"organization"`
+	Name         string             `json:"name"`
+	Steps        []cloudhub.JobStep `json:"steps"`
+	Links        selfLinks          `json:"links"`
+}
+
+// orgResources is everything owned by an organization outside of the
+// resources removed by the OrganizationsStore itself
+type orgResources struct {
+	deviceOrg *cloudhub.NetworkDeviceOrg
+	devices   []cloudhub.NetworkDevice
+	mlNxRsts  []cloudhub.MLNxRst
+	dlNxRsts  []cloudhub.DLNxRst
+	topology  []cloudhub.Topology
+	csps      []cloudhub.CSP
+	vspheres  []cloudhub.Vsphere
+	reports   []cloudhub.Report
+	snapshots []cloudhub.Snapshot
+	folders   []cloudhub.Folder
+}
+
+// orgResources enumerates the resources of all stores owned by org.
+// ctx must be a server context.
+func (s *Service) orgResources(ctx context.Context, org string) (*orgResources, error) {
+	res := &orgResources{}
+
+	deviceOrg, err := s.Store.NetworkDeviceOrg(ctx).Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &org})
+	if err == nil {
+		res.deviceOrg = deviceOrg
+	}
+
+	devices, err := s.Store.NetworkDevice(ctx).Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &org})
+	if err != nil {
+		return nil, err
+	}
+	ips := map[string]bool{}
+	for _, device := range devices {
+		res.devices = append(res.devices, device)
+		ips[device.DeviceIP] = true
+	}
+
+	mlNxRsts, err := s.Store.MLNxRst(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rst := range mlNxRsts {
+		if ips[rst.Device] {
+			res.mlNxRsts = append(res.mlNxRsts, rst)
+		}
+	}
+
+	dlNxRsts, err := s.Store.DLNxRst(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, rst := range dlNxRsts {
+		if ips[rst.Device] {
+			res.dlNxRsts = append(res.dlNxRsts, rst)
+		}
+	}
+
+	topologies, err := s.Store.Topologies(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tp := range topologies {
+		if tp.Organization == org {
+			res.topology = append(res.topology, tp)
+		}
+	}
+
+	csps, err := s.Store.CSP(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, csp := range csps {
+		if csp.Organization == org {
+			res.csps = append(res.csps, csp)
+		}
+	}
+
+	vspheres, err := s.Store.Vspheres(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, vs := range vspheres {
+		if vs.Organization == org {
+			res.vspheres = append(res.vspheres, vs)
+		}
+	}
+
+	reports, err := s.Store.Reports(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reports {
+		if r.Organization == org {
+			res.reports = append(res.reports, r)
+		}
+	}
+
+	snapshots, err := s.Store.Snapshots(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, snap := range snapshots {
+		if snap.Organization == org {
+			res.snapshots = append(res.snapshots, snap)
+		}
+	}
+
+	folders, err := s.Store.Folders(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range folders {
+		if f.Organization == org {
+			res.folders = append(res.folders, f)
+		}
+	}
+
+	return res, nil
+}
+
+// planOrgDeletion returns the steps deleting org with everything it owns.
+// The steps cleaning up external systems come first so that the settings
+// they need are still stored when they are retried.
+func (s *Service) planOrgDeletion(ctx context.Context, org *cloudhub.Organization) ([]cloudhub.JobStep, error) {
+	res, err := s.orgResources(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := []cloudhub.JobStep{}
+	add := func(kind, resource, target, description string) {
+		steps = append(steps, cloudhub.JobStep{
+			ID:          len(steps),
+			Kind:        kind,
+			Resource:    resource,
+			Target:      target,
+			Description: description,
+			Status:      cloudhub.JobPending,
+		})
+	}
+
+	if res.deviceOrg != nil {
+		if res.deviceOrg.AIKapacitor.KapaURL != "" {
+			for _, prefix := range []string{cloudhub.LearnScriptPrefix, cloudhub.PredictScriptPrefix} {
+				id := prefix + org.ID
+				add(deletionKapacitor, "task", id, fmt.Sprintf("Delete Kapacitor task %s from %s", id, res.deviceOrg.AIKapacitor.KapaURL))
+			}
+		}
+		if res.deviceOrg.CollectorServer != "" {
+			add(deletionLogstash, "config", res.deviceOrg.CollectorServer,
+				fmt.Sprintf("Remove Logstash config %s from collector server %s", logstashConfigFile(org.Name), res.deviceOrg.CollectorServer))
+		}
+	}
+	for _, csp := range res.csps {
+		if csp.Provider == cloudhub.OSP {
+			add(deletionSalt, deletionCSP, csp.ID, fmt.Sprintf("Remove Salt and Telegraf configs of %s/%s", csp.Provider, csp.NameSpace))
+		}
+	}
+
+	counts := []struct {
+		resource string
+		n        int
+		name     string
+	}{
+		{deletionMLNxRst, len(res.mlNxRsts), "ML results"},
+		{deletionDLNxRst, len(res.dlNxRsts), "DL results"},
+		{deletionNetworkDeviceOrg, boolToInt(res.deviceOrg != nil), "network device org settings"},
+		{deletionNetworkDevice, len(res.devices), "network devices"},
+		{deletionTopology, len(res.topology), "topologies"},
+		{deletionCSP, len(res.csps), "CSP"},
+		{deletionVsphere, len(res.vspheres), "vSphere entries"},
+		{deletionReport, len(res.reports), "reports"},
+		{deletionSnapshot, len(res.snapshots), "snapshots"},
+		{deletionFolder, len(res.folders), "dashboard folders"},
+	}
+	for _, c := range counts {
+		if c.n > 0 {
+			add(deletionStore, c.resource, org.ID, fmt.Sprintf("Delete %d %s", c.n, c.name))
+		}
+	}
+	add(deletionStore, deletionOrganization, org.ID,
+		fmt.Sprintf("Delete organization %s with its sources, kapacitors, dashboards, user roles and mappings", org.Name))
+	add(deletionStore, deletionTrash, org.ID, "Purge the deleted resources of the organization from the trash")
+
+	return steps, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// logstashConfigFile is the name of the Logstash SNMP config of an organization
+func logstashConfigFile(orgName string) string {
+	return fmt.Sprintf("%s_snmp_nx.rb", orgName)
+}
+
+// runOrgDeletion runs the steps of an organization deletion job that have not succeeded yet.
+// External steps that fail do not stop the other external steps, but the store
+// steps only run once every external step has succeeded.
+// The caller holds the lock of the job so that it runs on a single instance.
+func (s *Service) runOrgDeletion(ctx context.Context, job *cloudhub.Job) {
+	l := s.Logger.
+		WithField("component", "jobs").
+		WithField("job", job.ID)
+
+	update := func() {
+		if err := s.Store.Jobs(ctx).Update(ctx, job); err != nil {
+			l.Error("Unable to update job: ", err)
+		}
+	}
+
+	job.Status = cloudhub.JobRunning
+	update()
+
+	failed := false
+	for i := range job.Steps {
+		step := &job.Steps[i]
+		if step.Status == cloudhub.JobSucceeded {
+			continue
+		}
+		if step.Kind == deletionStore && failed {
+			break
+		}
+
+		step.Status = cloudhub.JobRunning
+		step.Attempts++
+		update()
+
+		if err := s.runOrgDeletionStep(ctx, job, step); err != nil {
+			l.Error("Step ", step.ID, " failed: ", err)
+			step.Status = cloudhub.JobFailed
+			step.Error = err.Error()
+			failed = true
+		} else {
+			step.Status = cloudhub.JobSucceeded
+			step.Error = ""
+		}
+		update()
+
+		if failed && step.Kind == deletionStore {
+			break
+		}
+	}
+
+	if failed {
+		job.Status = cloudhub.JobFailed
+		update()
+		return
+	}
+
+	job.Status = cloudhub.JobSucceeded
+	update()
+
+	// log registration
+	msg := fmt.Sprintf(MsgOrganizationDeleted.String(), job.Name)
+	s.logRegistration(ctx, "Organizations", msg)
+}
+
+func (s *Service) runOrgDeletionStep(ctx context.Context, job *cloudhub.Job, step *cloudhub.JobStep) error {
+	switch step.Kind {
+	case deletionKapacitor:
+		deviceOrg, err := s.Store.NetworkDeviceOrg(ctx).Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &job.Organization})
+		if err != nil {
+			// without the AI settings the task cannot be located anymore
+			return nil
+		}
+		ai := deviceOrg.AIKapacitor
+		c := kapa.NewClient(ai.KapaURL, ai.Username, ai.Password, ai.InsecureSkipVerify)
+		// Kapacitor answers a delete of a missing task with 204, and listing
+		// the tasks first would hide an unreachable Kapacitor from the step.
+		return c.Delete(ctx, c.Href(step.Target))
+	case deletionLogstash:
+		filePath := path.Join(s.InternalENV.AIConfig.LogstashPath, logstashConfigFile(job.Name))
+		return saltResult(s.RemoveFileWithLocalClient(filePath, step.Target))
+	case deletionSalt:
+		csp, err := s.Store.CSP(ctx).Get(ctx, cloudhub.CSPQuery{ID: &step.Target})
+		if err != nil {
+			return nil
+		}
+		if err := saltResult(s.removeSaltConfigForOSP(csp)); err != nil {
+			return err
+		}
+		return saltResult(s.removeTelegrafConfigForOSP(csp))
+	case deletionStore:
+		return s.runOrgDeletionStoreStep(ctx, job.Organization, step.Resource)
+	}
+	return fmt.Errorf("unknown step kind %q", step.Kind)
+}
+
+// saltResult converts the results of a Salt API call into an error
+func saltResult(statusCode int, resp []byte, err error) error {
+	if err != nil {
+		return err
+	}
+	if statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("salt API returned %d: %s", statusCode, string(resp))
+	}
+	return nil
+}
+
+// runOrgDeletionStoreStep deletes the resources of one store owned by org.
+// The resources are enumerated again so that a retried step only deletes what is left.
+func (s *Service) runOrgDeletionStoreStep(ctx context.Context, org, resource string) error {
+	if resource == deletionOrganization {
+		o, err := s.Store.Organizations(ctx).Get(ctx, cloudhub.OrganizationQuery{ID: &org})
+		if err == cloudhub.ErrOrganizationNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		return s.Store.Organizations(ctx).Delete(ctx, o)
+	}
+	if resource == deletionTrash {
+		// the previous steps moved the dashboards, topologies, devices and CSPs
+		// of the organization to the trash, where nobody could restore them
+		orgCtx := context.WithValue(ctx, organizations.ContextKey, org)
+		_, err := organizations.NewTrashStore(s.Store.Trash(ctx), org).Purge(orgCtx, time.Now())
+		return err
+	}
+
+	res, err := s.orgResources(ctx, org)
+	if err != nil {
+		return err
+	}
+
+	switch resource {
+	case deletionMLNxRst:
+		for i := range res.mlNxRsts {
+			if err := s.Store.MLNxRst(ctx).Delete(ctx, &res.mlNxRsts[i]); err != nil {
+				return err
+			}
+		}
+	case deletionDLNxRst:
+		for i := range res.dlNxRsts {
+			if err := s.Store.DLNxRst(ctx).Delete(ctx, &res.dlNxRsts[i]); err != nil {
+				return err
+			}
+		}
+	case deletionNetworkDeviceOrg:
+		if res.deviceOrg != nil {
+			return s.Store.NetworkDeviceOrg(ctx).Delete(ctx, &cloudhub.NetworkDeviceOrg{ID: res.deviceOrg.ID})
+		}
+	case deletionNetworkDevice:
+		for i := range res.devices {
+			if err := s.Store.NetworkDevice(ctx).Delete(ctx, &res.devices[i]); err != nil {
+				return err
+			}
+		}
+	case deletionTopology:
+		for i := range res.topology {
+			if err := s.Store.Topologies(ctx).Delete(ctx, &res.topology[i]); err != nil {
+				return err
+			}
+		}
+	case deletionCSP:
+		for i := range res.csps {
+			if err := s.Store.CSP(ctx).Delete(ctx, &res.csps[i]); err != nil {
+				return err
+			}
+		}
+	case deletionVsphere:
+		for _, vs := range res.vspheres {
+			if err := s.Store.Vspheres(ctx).Delete(ctx, vs); err != nil {
+				return err
+			}
+		}
+	case deletionReport:
+		for i := range res.reports {
+			if err := s.Store.Reports(ctx).Delete(ctx, &res.reports[i]); err != nil {
+				return err
+			}
+		}
+	case deletionSnapshot:
+		for i := range res.snapshots {
+			if err := s.Store.Snapshots(ctx).Delete(ctx, &res.snapshots[i]); err != nil {
+				return err
+			}
+		}
+	case deletionFolder:
+		for i := range res.folders {
+			if err := s.Store.Folders(ctx).Delete(ctx, &res.folders[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown store resource %q", resource)
+	}
+	return nil
+}
+
+// jobContext returns a context for running a job outside of the request
+// that started it, carrying the user who started it.
+func jobContext(r *http.Request) context.Context {
+	ctx := serverContext(context.Background())
+	if u, ok := hasUserContext(r.Context()); ok {
+		ctx = context.WithValue(ctx, UserContextKey, u)
+		ctx = context.WithValue(ctx, cloudhub.TrashContextKey, u.Name)
+	}
+	return ctx
+}
+
+// OrganizationDeletionPlan returns a preview of the steps deleting an organization
+// with everything it owns across the stores, Kapacitor, Salt and Logstash.
+func (s *Service) OrganizationDeletionPlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := httprouter.GetParamFromContext(ctx, "oid")
+
+	org, err := s.Store.Organizations(ctx).Get(ctx, cloudhub.OrganizationQuery{ID: &id})
+	if err != nil {
+		Error(w, http.StatusNotFound, err.Error(), s.Logger)
+		return
+	}
+
+	steps, err := s.planOrgDeletion(serverContext(ctx), org)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	res := &orgDeletionPlanResponse{
+		Organization: org.ID,
+		Name:         org.Name,
+		Steps:        steps,
+		Links: selfLinks{
+			Self: fmt.Sprintf("/cloudhub/v1/organizations/%s/deletion-plan", org.ID),
+		},
+	}
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// NewOrganizationDeletion starts a job deleting an organization with everything it owns.
+// The job runs in the background; its progress is tracked under /cloudhub/v1/jobs.
+func (s *Service) NewOrganizationDeletion(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := httprouter.GetParamFromContext(ctx, "oid")
+
+	org, err := s.Store.Organizations(ctx).Get(ctx, cloudhub.OrganizationQuery{ID: &id})
+	if err != nil {
+		Error(w, http.StatusNotFound, err.Error(), s.Logger)
+		return
+	}
+	if org.ID == DefaultOrganizationID {
+		Error(w, http.StatusBadRequest, cloudhub.ErrCannotDeleteDefaultOrganization.Error(), s.Logger)
+		return
+	}
+
+	steps, err := s.planOrgDeletion(serverContext(ctx), org)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	job := &cloudhub.Job{
+		Type:         cloudhub.JobOrganizationDeletion,
+		Organization: org.ID,
+		Name:         org.Name,
+		Status:       cloudhub.JobPending,
+		Steps:        steps,
+	}
+	if u, ok := hasUserContext(ctx); ok {
+		job.CreatedBy = u.Name
+	}
+
+	jobCtx := jobContext(r)
+	job, err = s.Store.Jobs(jobCtx).Add(jobCtx, job)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	// the job is only known by its ID once stored, so it is locked afterwards and
+	// marked failed when it cannot start, to be retried instead of left pending
+	unlock, ok, err := s.coordinator().TryLock(jobCtx, jobLock(job.ID))
+	if err == nil && !ok {
+		err = fmt.Errorf("job %s is running", job.ID)
+	}
+	if err != nil {
+		job.Status = cloudhub.JobFailed
+		if err := s.Store.Jobs(jobCtx).Update(jobCtx, job); err != nil {
+			s.Logger.Error("Unable to update job ", job.ID, ": ", err)
+		}
+		Error(w, http.StatusServiceUnavailable, fmt.Sprintf("Unable to start job %s, retry it later: %v", job.ID, err), s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgOrganizationDeletionStarted.String(), org.Name)
+	s.logRegistration(ctx, "Organizations", msg)
+
+	res := newJobResponse(*job)
+	location(w, res.Links.Self)
+	encodeJSON(w, http.StatusAccepted, res, s.Logger)
+
+	go func() {
+		defer unlock()
+		s.runOrgDeletion(jobCtx, job)
+	}()
+}
@@ -0,0 +1,282 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func orgDeletionTestStore(deleted *[]string, kapaURL string) *mocks.Store {
+	record := func(resource string) {
+		*deleted = append(*deleted, resource)
+	}
+	return &mocks.Store{
+		OrganizationsStore: &mocks.OrganizationsStore{
+			GetF: func(ctx context.Context, q cloudhub.OrganizationQuery) (*cloudhub.Organization, error) {
+				return &cloudhub.Organization{ID: "1", Name: "snet"}, nil
+			},
+			DeleteF: func(ctx context.Context, o *cloudhub.Organization) error {
+				record(deletionOrganization)
+				return nil
+			},
+		},
+		NetworkDeviceOrgStore: &mocks.NetworkDeviceOrgStore{
+			GetF: func(ctx context.Context, q cloudhub.NetworkDeviceOrgQuery) (*cloudhub.NetworkDeviceOrg, error) {
+				return &cloudhub.NetworkDeviceOrg{
+					ID:              "1",
+					CollectorServer: "collector01",
+					AIKapacitor:     cloudhub.AIKapacitor{KapaURL: kapaURL},
+				}, nil
+			},
+			DeleteF: func(ctx context.Context, o *cloudhub.NetworkDeviceOrg) error {
+				record(deletionNetworkDeviceOrg)
+				return nil
+			},
+		},
+		NetworkDeviceStore: &mocks.NetworkDeviceStore{
+			FindF: func(ctx context.Context, q cloudhub.NetworkDeviceQuery) ([]cloudhub.NetworkDevice, error) {
+				var devices []cloudhub.NetworkDevice
+				for _, d := range []cloudhub.NetworkDevice{
+					{ID: "10", Organization: "1", DeviceIP: "10.0.0.1"},
+					{ID: "11", Organization: "default", DeviceIP: "10.0.0.2"},
+				} {
+					if q.Organization == nil || *q.Organization == d.Organization {
+						devices = append(devices, d)
+					}
+				}
+				return devices, nil
+			},
+			DeleteF: func(ctx context.Context, d *cloudhub.NetworkDevice) error {
+				record(deletionNetworkDevice)
+				return nil
+			},
+		},
+		MLNxRstStore: &mocks.MLNxRstStore{
+			AllF: func(ctx context.Context) ([]cloudhub.MLNxRst, error) {
+				return []cloudhub.MLNxRst{{Device: "10.0.0.1"}, {Device: "10.0.0.2"}}, nil
+			},
+			DeleteF: func(ctx context.Context, r *cloudhub.MLNxRst) error {
+				record(deletionMLNxRst)
+				return nil
+			},
+		},
+		DLNxRstStore: &mocks.DLNxRstStore{
+			AllF: func(ctx context.Context) ([]cloudhub.DLNxRst, error) {
+				return nil, nil
+			},
+		},
+		TopologiesStore: &mocks.TopologiesStore{
+			AllF: func(ctx context.Context) ([]cloudhub.Topology, error) {
+				return []cloudhub.Topology{{ID: "5", Organization: "1"}}, nil
+			},
+			DeleteF: func(ctx context.Context, tp *cloudhub.Topology) error {
+				record(deletionTopology)
+				return nil
+			},
+		},
+		CSPStore: &mocks.CSPStore{
+			AllF: func(ctx context.Context) ([]cloudhub.CSP, error) {
+				return nil, nil
+			},
+		},
+		VspheresStore: &mocks.VspheresStore{
+			AllF: func(ctx context.Context) ([]cloudhub.Vsphere, error) {
+				return nil, nil
+			},
+		},
+		ReportsStore: &mocks.ReportsStore{
+			AllF: func(ctx context.Context) ([]cloudhub.Report, error) {
+				return []cloudhub.Report{{ID: "3", Organization: "1"}, {ID: "4", Organization: "default"}}, nil
+			},
+			DeleteF: func(ctx context.Context, r *cloudhub.Report) error {
+				record(deletionReport)
+				return nil
+			},
+		},
+		SnapshotsStore: &mocks.SnapshotsStore{
+			AllF: func(ctx context.Context) ([]cloudhub.Snapshot, error) {
+				return []cloudhub.Snapshot{{ID: "5", Organization: "1"}}, nil
+			},
+			DeleteF: func(ctx context.Context, snap *cloudhub.Snapshot) error {
+				record(deletionSnapshot)
+				return nil
+			},
+		},
+		FoldersStore: &mocks.FoldersStore{
+			AllF: func(ctx context.Context) ([]cloudhub.Folder, error) {
+				return []cloudhub.Folder{{ID: "6", Organization: "1"}, {ID: "7", Organization: "default"}}, nil
+			},
+			DeleteF: func(ctx context.Context, f *cloudhub.Folder) error {
+				record(deletionFolder)
+				return nil
+			},
+		},
+		TrashStore: &mocks.TrashStore{
+			AllF: func(ctx context.Context) ([]cloudhub.TrashItem, error) {
+				return []cloudhub.TrashItem{
+					{ID: "8", Organization: "1", ResourceType: cloudhub.TrashTopology, ResourceID: "5"},
+					{ID: "9", Organization: "default", ResourceType: cloudhub.TrashTopology, ResourceID: "6"},
+				}, nil
+			},
+			DeleteF: func(ctx context.Context, item *cloudhub.TrashItem) error {
+				if item.Organization != "1" {
+					return fmt.Errorf("deleted trash item %s of organization %s", item.ID, item.Organization)
+				}
+				record(deletionTrash)
+				return nil
+			},
+		},
+		JobsStore: &mocks.JobsStore{
+			UpdateF: func(ctx context.Context, job *cloudhub.Job) error {
+				return nil
+			},
+		},
+	}
+}
+
+func TestService_planOrgDeletion(t *testing.T) {
+	var deleted []string
+	s := &Service{
+		Store:  orgDeletionTestStore(&deleted, "http://localhost:9092"),
+		Logger: &mocks.TestLogger{},
+	}
+
+	steps, err := s.planOrgDeletion(serverContext(context.Background()), &cloudhub.Organization{ID: "1", Name: "snet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		kind     string
+		resource string
+		target   string
+	}{
+		{deletionKapacitor, "task", cloudhub.LearnScriptPrefix + "1"},
+		{deletionKapacitor, "task", cloudhub.PredictScriptPrefix + "1"},
+		{deletionLogstash, "config", "collector01"},
+		{deletionStore, deletionMLNxRst, "1"},
+		{deletionStore, deletionNetworkDeviceOrg, "1"},
+		{deletionStore, deletionNetworkDevice, "1"},
+		{deletionStore, deletionTopology, "1"},
+		{deletionStore, deletionReport, "1"},
+		{deletionStore, deletionSnapshot, "1"},
+		{deletionStore, deletionFolder, "1"},
+		{deletionStore, deletionOrganization, "1"},
+		{deletionStore, deletionTrash, "1"},
+	}
+	if len(steps) != len(want) {
+		t.Fatalf("planOrgDeletion() returned %d steps, want %d: %#v", len(steps), len(want), steps)
+	}
+	for i, w := range want {
+		if steps[i].ID != i || steps[i].Kind != w.kind || steps[i].Resource != w.resource || steps[i].Target != w.target {
+			t.Errorf("planOrgDeletion() step %d = %#v, want %s/%s/%s", i, steps[i], w.kind, w.resource, w.target)
+		}
+		if steps[i].Status != cloudhub.JobPending {
+			t.Errorf("planOrgDeletion() step %d status = %s, want pending", i, steps[i].Status)
+		}
+	}
+	if len(deleted) != 0 {
+		t.Errorf("planOrgDeletion() must not delete anything, deleted %v", deleted)
+	}
+}
+
+func TestService_runOrgDeletion(t *testing.T) {
+	kapacitor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"unavailable"}`))
+	}))
+	defer kapacitor.Close()
+
+	var deleted []string
+	s := &Service{
+		Store:  orgDeletionTestStore(&deleted, kapacitor.URL),
+		Logger: &mocks.TestLogger{},
+	}
+	ctx := serverContext(context.Background())
+
+	job := &cloudhub.Job{
+		ID:           "1",
+		Type:         cloudhub.JobOrganizationDeletion,
+		Organization: "1",
+		Name:         "snet",
+		Steps: []cloudhub.JobStep{
+			{ID: 0, Kind: deletionKapacitor, Resource: "task", Target: cloudhub.LearnScriptPrefix + "1", Status: cloudhub.JobPending},
+			{ID: 1, Kind: deletionStore, Resource: deletionTopology, Target: "1", Status: cloudhub.JobPending},
+			{ID: 2, Kind: deletionStore, Resource: deletionOrganization, Target: "1", Status: cloudhub.JobPending},
+			{ID: 3, Kind: deletionStore, Resource: deletionTrash, Target: "1", Status: cloudhub.JobPending},
+		},
+	}
+
+	// the Kapacitor is unavailable so the store steps must not run
+	s.runOrgDeletion(ctx, job)
+	if job.Status != cloudhub.JobFailed {
+		t.Fatalf("runOrgDeletion() job status = %s, want failed: %#v", job.Status, job.Steps)
+	}
+	if job.Steps[0].Status != cloudhub.JobFailed || job.Steps[0].Error == "" || job.Steps[0].Attempts != 1 {
+		t.Errorf("runOrgDeletion() unexpected kapacitor step %#v", job.Steps[0])
+	}
+	if job.Steps[1].Status != cloudhub.JobPending || len(deleted) != 0 {
+		t.Errorf("runOrgDeletion() ran store steps after a failed external step: %v", deleted)
+	}
+
+	// once the external step has succeeded a retry runs the remaining steps
+	job.Steps[0].Status = cloudhub.JobSucceeded
+	s.runOrgDeletion(ctx, job)
+	if job.Status != cloudhub.JobSucceeded {
+		t.Fatalf("runOrgDeletion() job status = %s, want succeeded: %#v", job.Status, job.Steps)
+	}
+	if len(deleted) != 3 || deleted[0] != deletionTopology || deleted[1] != deletionOrganization || deleted[2] != deletionTrash {
+		t.Errorf("runOrgDeletion() deleted %v, want topology, organization then its trash", deleted)
+	}
+	if job.Steps[0].Attempts != 1 {
+		t.Errorf("runOrgDeletion() must not run succeeded steps again")
+	}
+}
+
+func TestService_NewOrganizationDeletion_locked(t *testing.T) {
+	var deleted []string
+	var updated []cloudhub.Job
+	store := orgDeletionTestStore(&deleted, "http://localhost:9092")
+	store.JobsStore = &mocks.JobsStore{
+		AddF: func(ctx context.Context, job *cloudhub.Job) (*cloudhub.Job, error) {
+			job.ID = "org-deletion-locked"
+			return job, nil
+		},
+		UpdateF: func(ctx context.Context, job *cloudhub.Job) error {
+			updated = append(updated, *job)
+			return nil
+		},
+	}
+	s := &Service{Store: store, Logger: &mocks.TestLogger{}}
+
+	unlock, ok, err := s.coordinator().TryLock(context.Background(), jobLock("org-deletion-locked"))
+	if err != nil || !ok {
+		t.Fatalf("TryLock() = %v, %v", ok, err)
+	}
+	defer unlock()
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("DELETE", "/cloudhub/v1/organizations/1", nil)
+	r = r.WithContext(httprouter.WithParams(r.Context(), httprouter.Params{{Key: "oid", Value: "1"}}))
+	s.NewOrganizationDeletion(w, r)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("NewOrganizationDeletion() status = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if body := w.Body.String(); strings.Contains(body, "<nil>") || !strings.Contains(body, "is running") {
+		t.Errorf("NewOrganizationDeletion() body = %s", body)
+	}
+	if len(updated) != 1 || updated[0].Status != cloudhub.JobFailed {
+		t.Errorf("NewOrganizationDeletion() must mark a job that cannot start failed, updated %#v", updated)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("NewOrganizationDeletion() must not delete anything, deleted %v", deleted)
+	}
+}
@@ -0,0 +1,528 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/organizations"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+const sourcesLinkPrefix = "/cloudhub/v1/sources/"
+
+type orgTemplateRequest struct {
+	Name         string `json:"name"`
+	Organization string `json:"organization"` // Organization is the ID of the organization to save
+}
+
+func (r *orgTemplateRequest) Valid() error {
+	if r.Name == "" {
+		return fmt.Errorf("Name required on CloudHub Organization Template request body")
+	}
+	if r.Organization == "" {
+		return fmt.Errorf("Organization required on CloudHub Organization Template request body")
+	}
+	return nil
+}
+
+// instantiateRequest names the organization created by a clone or from a template.
+// Quotas, if any, replace the quotas of the template. Telegraf optionally replaces
+// the database of the sources and of the dashboard queries so that the copy
+// points at the data of the new tenant.
+type instantiateRequest struct {
+	organizationRequest
+	Telegraf string `json:"telegraf,omitempty"`
+}
+
+type orgTemplateResponse struct {
+	cloudhub.OrgTemplate
+	Links orgTemplateLinks `json:"links"`
+}
+
+type orgTemplateLinks struct {
+	Self        string `json:"self"`        // Self link mapping to this resource
+	Instantiate string `json:"instantiate"` // Instantiate link to create an organization from the template
+}
+
+type orgTemplatesResponse struct {
+	Links     selfLinks             `json:"links"`
+	Templates []orgTemplateResponse `json:"templates"`
+}
+
+// newOrgTemplateResponse hides the credentials of the sources of t
+func newOrgTemplateResponse(t cloudhub.OrgTemplate) orgTemplateResponse {
+	srcs := make([]cloudhub.Source, len(t.Sources))
+	for i, src := range t.Sources {
+		src.Password = ""
+		src.SharedSecret = ""
+		srcs[i] = src
+	}
+	t.Sources = srcs
+
+	self := fmt.Sprintf("/cloudhub/v1/org-templates/%s", t.ID)
+	return orgTemplateResponse{
+		OrgTemplate: t,
+		Links: orgTemplateLinks{
+			Self:        self,
+			Instantiate: self + "/instantiate",
+		},
+	}
+}
+
+// snapshotOrganization captures the configuration of org as a template.
+// Network devices, users and the results of the learning are specific to the
+// tenant and are not part of the snapshot.
+func (s *Service) snapshotOrganization(ctx context.Context, org *cloudhub.Organization) (*cloudhub.OrgTemplate, error) {
+	ctx = serverContext(ctx)
+	t := &cloudhub.OrgTemplate{
+		Organization: org.ID,
+		DefaultRole:  org.DefaultRole,
+		Quotas:       org.Quotas,
+		Sources:      []cloudhub.Source{},
+		Dashboards:   []cloudhub.Dashboard{},
+		Topologies:   []cloudhub.Topology{},
+	}
+
+	srcs, err := s.Store.Sources(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range srcs {
+		if src.Organization == org.ID {
+			t.Sources = append(t.Sources, src)
+		}
+	}
+
+	dashboards, err := s.Store.Dashboards(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dashboards {
+		if d.Organization == org.ID {
+			t.Dashboards = append(t.Dashboards, d)
+		}
+	}
+
+	topologies, err := s.Store.Topologies(ctx).All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tp := range topologies {
+		if tp.Organization == org.ID {
+			t.Topologies = append(t.Topologies, tp)
+		}
+	}
+
+	orgCtx := context.WithValue(ctx, organizations.ContextKey, org.ID)
+	config, err := s.Store.OrganizationConfig(orgCtx).FindOrCreate(orgCtx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+	t.LogViewer = config.LogViewer
+
+	if deviceOrg, err := s.Store.NetworkDeviceOrg(ctx).Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &org.ID}); err == nil {
+		t.NetworkDeviceOrg = deviceOrg
+	}
+
+	return t, nil
+}
+
+// instantiateOrgTemplate creates the organization req from the template t and adds
+// creator, if any, as its admin. The IDs of the sources are remapped in the
+// dashboard queries. The organization is removed again if any resource cannot be created.
+func (s *Service) instantiateOrgTemplate(ctx context.Context, t *cloudhub.OrgTemplate, req *instantiateRequest, creator *cloudhub.User) (*cloudhub.Organization, error) {
+	quotas := t.Quotas
+	if req.Quotas != nil {
+		quotas = req.Quotas
+	}
+
+	reqCtx := ctx
+	ctx = serverContext(ctx)
+	org, err := s.Store.Organizations(ctx).Add(ctx, &cloudhub.Organization{
+		Name:        req.Name,
+		DefaultRole: req.DefaultRole,
+		Quotas:      quotas,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var topologies []*cloudhub.Topology
+	deviceOrg := false
+	cleanup := func() {
+		// Best attempt at cleanup, removing the organization also removes its sources, dashboards and users
+		for _, tp := range topologies {
+			_ = s.Store.Topologies(ctx).Delete(ctx, tp)
+		}
+		if deviceOrg {
+			_ = s.Store.NetworkDeviceOrg(ctx).Delete(ctx, &cloudhub.NetworkDeviceOrg{ID: org.ID})
+		}
+		_ = s.Store.Organizations(ctx).Delete(ctx, org)
+	}
+
+	if creator != nil {
+		user := *creator
+		user.Roles = []cloudhub.Role{
+			{
+				Organization: org.ID,
+				Name:         roles.AdminRoleName,
+			},
+		}
+		orgCtx := context.WithValue(reqCtx, organizations.ContextKey, org.ID)
+		if _, err := s.Store.Users(orgCtx).Add(orgCtx, &user); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	databases := map[string]string{}
+	sourceIDs := map[int]int{}
+	for _, src := range t.Sources {
+		oldID := src.ID
+		src.Organization = org.ID
+		// the default source of the application stays where it is
+		src.Default = false
+		if req.Telegraf != "" {
+			if src.Telegraf != "" {
+				databases[src.Telegraf] = req.Telegraf
+			}
+			src.Telegraf = req.Telegraf
+		}
+		created, err := s.Store.Sources(ctx).Add(ctx, src)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		sourceIDs[oldID] = created.ID
+	}
+
+	for _, d := range t.Dashboards {
+		d.ID = 0
+		d.Version = 0
+		d.Organization = org.ID
+		// folders belong to the organization of the template, dashboards land at the root
+		d.Folder = ""
+		remapDashboard(&d, sourceIDs, databases)
+		if _, err := s.Store.Dashboards(ctx).Add(ctx, d); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
+
+	for _, tp := range t.Topologies {
+		tp.ID = ""
+		tp.Version = 0
+		tp.Organization = org.ID
+		created, err := s.Store.Topologies(ctx).Add(ctx, &tp)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		topologies = append(topologies, created)
+	}
+
+	orgCtx := context.WithValue(ctx, organizations.ContextKey, org.ID)
+	if err := s.Store.OrganizationConfig(orgCtx).Put(orgCtx, &cloudhub.OrganizationConfig{
+		OrganizationID: org.ID,
+		LogViewer:      t.LogViewer,
+	}); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	if t.NetworkDeviceOrg != nil {
+		settings := *t.NetworkDeviceOrg
+		settings.ID = org.ID
+		settings.Version = 0
+		settings.LearnedDevicesIDs = []string{}
+		settings.CollectedDevicesIDs = []string{}
+		if _, err := s.Store.NetworkDeviceOrg(ctx).Add(ctx, &settings); err != nil {
+			cleanup()
+			return nil, err
+		}
+		deviceOrg = true
+	}
+
+	return org, nil
+}
+
+// remapDashboard points the queries of d at the new IDs of their sources and
+// replaces the databases of the queries, if any. Databases are replaced in the
+// query configs, the template queries and where a query quotes them, e.g. "telegraf"."autogen".
+// The cells and templates of d are copied so that the template d was taken from is left unchanged.
+func remapDashboard(d *cloudhub.Dashboard, sourceIDs map[int]int, databases map[string]string) {
+	replacer := databaseReplacer(databases)
+	d.Cells = append([]cloudhub.DashboardCell{}, d.Cells...)
+	d.Templates = append([]cloudhub.Template{}, d.Templates...)
+	for i := range d.Cells {
+		d.Cells[i].Queries = append([]cloudhub.DashboardQuery{}, d.Cells[i].Queries...)
+		for j := range d.Cells[i].Queries {
+			q := &d.Cells[i].Queries[j]
+			q.Source = remapSourceLink(q.Source, sourceIDs)
+			if db, ok := databases[q.QueryConfig.Database]; ok {
+				q.QueryConfig.Database = db
+			}
+			q.Command = replacer.Replace(q.Command)
+		}
+	}
+	for i := range d.Templates {
+		if q := d.Templates[i].Query; q != nil {
+			query := *q
+			if db, ok := databases[q.DB]; ok {
+				query.DB = db
+			}
+			query.Command = replacer.Replace(q.Command)
+			d.Templates[i].Query = &query
+		}
+	}
+}
+
+// databaseReplacer returns the replacer of the databases quoted by queries. The
+// databases are replaced in a single pass, so that databases swapped with each
+// other are not replaced twice.
+func databaseReplacer(databases map[string]string) *strings.Replacer {
+	from := make([]string, 0, len(databases))
+	for db := range databases {
+		from = append(from, db)
+	}
+	sort.Strings(from)
+	oldnew := make([]string, 0, 2*len(from))
+	for _, db := range from {
+		oldnew = append(oldnew, fmt.Sprintf("%q.", db), fmt.Sprintf("%q.", databases[db]))
+	}
+	return strings.NewReplacer(oldnew...)
+}
+
+// remapSourceLink rewrites a link to a source, e.g. /cloudhub/v1/sources/1,
+// to the new ID of the source. Other links are returned as they are.
+func remapSourceLink(link string, sourceIDs map[int]int) string {
+	old, tail, ok := sourceLinkID(link)
+	if !ok {
+		return link
+	}
+	if n, ok := sourceIDs[old]; ok {
+		return fmt.Sprintf("%s%d%s", sourcesLinkPrefix, n, tail)
+	}
+	return link
+}
+
+// sourceLinkID returns the ID of the source of a link to a source and the rest of
+// the link, e.g. 1 and /proxy for /cloudhub/v1/sources/1/proxy
+func sourceLinkID(link string) (int, string, bool) {
+	if !strings.HasPrefix(link, sourcesLinkPrefix) {
+		return 0, "", false
+	}
+	rest := strings.TrimPrefix(link, sourcesLinkPrefix)
+	id, tail := rest, ""
+	if i := strings.Index(rest, "/"); i >= 0 {
+		id, tail = rest[:i], rest[i:]
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return 0, "", false
+	}
+	return n, tail, true
+}
+
+// decodeInstantiateRequest reads and validates the organization to create from r
+func decodeInstantiateRequest(r *http.Request) (*instantiateRequest, error) {
+	var req instantiateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, fmt.Errorf("unparsable JSON")
+	}
+	if err := req.ValidCreate(); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// respondInstantiated creates the organization req from t and responds with it
+func (s *Service) respondInstantiated(w http.ResponseWriter, r *http.Request, t *cloudhub.OrgTemplate, req *instantiateRequest) {
+	ctx := r.Context()
+	var creator *cloudhub.User
+	if user, ok := hasUserContext(ctx); ok {
+		creator = user
+	}
+
+	org, err := s.instantiateOrgTemplate(ctx, t, req, creator)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgOrganizationCreated.String(), org.Name)
+	s.logRegistration(ctx, "Organizations", msg)
+
+	co := newOrganizationResponse(org)
+	location(w, co.Links.Self)
+	encodeJSON(w, http.StatusCreated, co, s.Logger)
+}
+
+// CloneOrganization creates a new organization with a copy of the sources,
+// dashboards, topologies, log viewer config and network device settings of an organization
+func (s *Service) CloneOrganization(w http.ResponseWriter, r *http.Request) {
+	req, err := decodeInstantiateRequest(r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	id := httprouter.GetParamFromContext(ctx, "oid")
+	org, err := s.Store.Organizations(ctx).Get(ctx, cloudhub.OrganizationQuery{ID: &id})
+	if err != nil {
+		Error(w, http.StatusNotFound, err.Error(), s.Logger)
+		return
+	}
+
+	t, err := s.snapshotOrganization(ctx, org)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	s.respondInstantiated(w, r, t, req)
+}
+
+// OrgTemplates returns all organization templates
+func (s *Service) OrgTemplates(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	templates, err := s.Store.OrgTemplates(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading organization templates", s.Logger)
+		return
+	}
+
+	res := orgTemplatesResponse{
+		Links: selfLinks{
+			Self: "/cloudhub/v1/org-templates",
+		},
+		Templates: []orgTemplateResponse{},
+	}
+	for _, t := range templates {
+		res.Templates = append(res.Templates, newOrgTemplateResponse(t))
+	}
+
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// NewOrgTemplate saves the configuration of an organization as a template
+func (s *Service) NewOrgTemplate(w http.ResponseWriter, r *http.Request) {
+	var req orgTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+	if err := req.Valid(); err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	org, err := s.Store.Organizations(ctx).Get(ctx, cloudhub.OrganizationQuery{ID: &req.Organization})
+	if err != nil {
+		Error(w, http.StatusNotFound, err.Error(), s.Logger)
+		return
+	}
+
+	t, err := s.snapshotOrganization(ctx, org)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+	t.Name = req.Name
+	if user, ok := hasUserContext(ctx); ok {
+		t.CreatedBy = user.Name
+	}
+
+	t, err = s.Store.OrgTemplates(ctx).Add(ctx, t)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgOrgTemplateCreated.String(), t.Name, org.Name)
+	s.logRegistration(ctx, "Organizations", msg)
+
+	res := newOrgTemplateResponse(*t)
+	location(w, res.Links.Self)
+	encodeJSON(w, http.StatusCreated, res, s.Logger)
+}
+
+// OrgTemplateID returns a single organization template
+func (s *Service) OrgTemplateID(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	t, err := s.Store.OrgTemplates(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	encodeJSON(w, http.StatusOK, newOrgTemplateResponse(*t), s.Logger)
+}
+
+// RemoveOrgTemplate deletes an organization template
+func (s *Service) RemoveOrgTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	t, err := s.Store.OrgTemplates(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	if err := s.Store.OrgTemplates(ctx).Delete(ctx, t); err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgOrgTemplateDeleted.String(), t.Name)
+	s.logRegistration(ctx, "Organizations", msg)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// InstantiateOrgTemplate creates a new organization from an organization template
+func (s *Service) InstantiateOrgTemplate(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	req, err := decodeInstantiateRequest(r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	t, err := s.Store.OrgTemplates(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	s.respondInstantiated(w, r, t, req)
+}
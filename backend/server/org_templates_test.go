@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func Test_remapSourceLink(t *testing.T) {
+	ids := map[int]int{1: 7}
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"/cloudhub/v1/sources/1", "/cloudhub/v1/sources/7"},
+		{"/cloudhub/v1/sources/1/proxy", "/cloudhub/v1/sources/7/proxy"},
+		{"/cloudhub/v1/sources/2", "/cloudhub/v1/sources/2"},
+		{"/cloudhub/v1/sources/x", "/cloudhub/v1/sources/x"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := remapSourceLink(tt.link, ids); got != tt.want {
+			t.Errorf("remapSourceLink(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestService_instantiateOrgTemplate(t *testing.T) {
+	var (
+		srcs       []cloudhub.Source
+		dashboards []cloudhub.Dashboard
+		deviceOrg  *cloudhub.NetworkDeviceOrg
+		config     *cloudhub.OrganizationConfig
+	)
+	s := &Service{
+		Store: &mocks.Store{
+			OrganizationsStore: &mocks.OrganizationsStore{
+				AddF: func(ctx context.Context, o *cloudhub.Organization) (*cloudhub.Organization, error) {
+					o.ID = "9"
+					return o, nil
+				},
+			},
+			SourcesStore: &mocks.SourcesStore{
+				AddF: func(ctx context.Context, src cloudhub.Source) (cloudhub.Source, error) {
+					src.ID = 20 + len(srcs)
+					srcs = append(srcs, src)
+					return src, nil
+				},
+			},
+			DashboardsStore: &mocks.DashboardsStore{
+				AddF: func(ctx context.Context, d cloudhub.Dashboard) (cloudhub.Dashboard, error) {
+					dashboards = append(dashboards, d)
+					return d, nil
+				},
+			},
+			OrganizationConfigStore: &mocks.OrganizationConfigStore{
+				PutF: func(ctx context.Context, c *cloudhub.OrganizationConfig) error {
+					config = c
+					return nil
+				},
+			},
+			NetworkDeviceOrgStore: &mocks.NetworkDeviceOrgStore{
+				AddF: func(ctx context.Context, o *cloudhub.NetworkDeviceOrg) (*cloudhub.NetworkDeviceOrg, error) {
+					deviceOrg = o
+					return o, nil
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	query := cloudhub.DashboardQuery{
+		Command:     `SELECT mean("usage_user") FROM "telegraf"."autogen"."cpu"`,
+		Source:      "/cloudhub/v1/sources/3",
+		QueryConfig: cloudhub.QueryConfig{Database: "telegraf"},
+	}
+	tmpl := &cloudhub.OrgTemplate{
+		Organization: "1",
+		Quotas:       &cloudhub.OrganizationQuotas{Dashboards: 5},
+		Sources: []cloudhub.Source{
+			{ID: 3, Name: "influx", Telegraf: "telegraf", Organization: "1", Default: true},
+		},
+		Dashboards: []cloudhub.Dashboard{
+			{ID: 4, Name: "hosts", Organization: "1", Folder: "2", Cells: []cloudhub.DashboardCell{{Queries: []cloudhub.DashboardQuery{query}}}},
+		},
+		LogViewer:        cloudhub.LogViewerConfig{Columns: []cloudhub.LogViewerColumn{{Name: "hostname"}}},
+		NetworkDeviceOrg: &cloudhub.NetworkDeviceOrg{ID: "1", LoadModule: "learn", LearnedDevicesIDs: []string{"10"}},
+	}
+	req := &instantiateRequest{
+		organizationRequest: organizationRequest{Name: "customer", DefaultRole: "viewer"},
+		Telegraf:            "customer_db",
+	}
+
+	org, err := s.instantiateOrgTemplate(context.Background(), tmpl, req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org.ID != "9" || org.Name != "customer" || org.Quotas == nil || org.Quotas.Dashboards != 5 {
+		t.Errorf("instantiateOrgTemplate() unexpected organization %#v", org)
+	}
+
+	if len(srcs) != 1 || srcs[0].Organization != "9" || srcs[0].Telegraf != "customer_db" || srcs[0].Default {
+		t.Fatalf("instantiateOrgTemplate() unexpected sources %#v", srcs)
+	}
+	if len(dashboards) != 1 || dashboards[0].Organization != "9" || dashboards[0].ID != 0 || dashboards[0].Folder != "" {
+		t.Fatalf("instantiateOrgTemplate() unexpected dashboards %#v", dashboards)
+	}
+	got := dashboards[0].Cells[0].Queries[0]
+	if got.Source != "/cloudhub/v1/sources/20" {
+		t.Errorf("query source = %s, want the new source", got.Source)
+	}
+	if got.QueryConfig.Database != "customer_db" || got.Command != `SELECT mean("usage_user") FROM "customer_db"."autogen"."cpu"` {
+		t.Errorf("query database not substituted: %#v", got)
+	}
+	if tmpl.Dashboards[0].Cells[0].Queries[0].Source != query.Source {
+		t.Errorf("instantiateOrgTemplate() must not change the template")
+	}
+
+	if config == nil || config.OrganizationID != "9" || len(config.LogViewer.Columns) != 1 {
+		t.Errorf("instantiateOrgTemplate() unexpected log viewer config %#v", config)
+	}
+	if deviceOrg == nil || deviceOrg.ID != "9" || deviceOrg.LoadModule != "learn" || len(deviceOrg.LearnedDevicesIDs) != 0 {
+		t.Errorf("instantiateOrgTemplate() unexpected network device settings %#v", deviceOrg)
+	}
+}
+
+func Test_remapDashboard(t *testing.T) {
+	template := cloudhub.Dashboard{
+		Cells: []cloudhub.DashboardCell{{Queries: []cloudhub.DashboardQuery{{
+			Command:     `SELECT "a" FROM "telegraf"."autogen"."cpu", "metrics"."autogen"."mem"`,
+			Source:      "/cloudhub/v1/sources/1",
+			QueryConfig: cloudhub.QueryConfig{Database: "telegraf"},
+		}}}},
+		Templates: []cloudhub.Template{{Query: &cloudhub.TemplateQuery{
+			Command: `SHOW TAG VALUES FROM "metrics"."autogen"."mem" WITH KEY = "host"`,
+			DB:      "metrics",
+		}}},
+	}
+	d := template
+	// databases swapped with each other are replaced once
+	remapDashboard(&d, map[int]int{1: 2}, map[string]string{"telegraf": "metrics", "metrics": "telegraf"})
+
+	q := d.Cells[0].Queries[0]
+	if q.Source != "/cloudhub/v1/sources/2" || q.QueryConfig.Database != "metrics" ||
+		q.Command != `SELECT "a" FROM "metrics"."autogen"."cpu", "telegraf"."autogen"."mem"` {
+		t.Errorf("remapDashboard() query = %#v", q)
+	}
+	if tq := d.Templates[0].Query; tq.DB != "telegraf" || tq.Command != `SHOW TAG VALUES FROM "telegraf"."autogen"."mem" WITH KEY = "host"` {
+		t.Errorf("remapDashboard() template query = %#v", tq)
+	}
+	if template.Cells[0].Queries[0].QueryConfig.Database != "telegraf" || template.Templates[0].Query.DB != "metrics" {
+		t.Errorf("remapDashboard() must not change the dashboard it was given a copy of")
+	}
+}
@@ -13,8 +13,9 @@ import (
 )
 
 type organizationRequest struct {
-	Name        string `json:"name"`
-	DefaultRole string `json:"defaultRole"`
+	Name        string                       `json:"name"`
+	DefaultRole string                       `json:"defaultRole"`
+	Quotas      *cloudhub.OrganizationQuotas `json:"quotas,omitempty"`
 }
 
 func (r *organizationRequest) ValidCreate() error {
@@ -22,14 +23,22 @@ func (r *organizationRequest) ValidCreate() error {
 		return fmt.Errorf("Name required on CloudHub Organization request body")
 	}
 
+	if err := validQuotas(r.Quotas); err != nil {
+		return err
+	}
+
 	return r.ValidDefaultRole()
 }
 
 func (r *organizationRequest) ValidUpdate() error {
-	if r.Name == "" && r.DefaultRole == "" {
+	if r.Name == "" && r.DefaultRole == "" && r.Quotas == nil {
 		return fmt.Errorf("No fields to update")
 	}
 
+	if err := validQuotas(r.Quotas); err != nil {
+		return err
+	}
+
 	if r.DefaultRole != "" {
 		return r.ValidDefaultRole()
 	}
@@ -116,6 +125,7 @@ func (s *Service) NewOrganization(w http.ResponseWriter, r *http.Request) {
 	org := &cloudhub.Organization{
 		Name:        req.Name,
 		DefaultRole: req.DefaultRole,
+		Quotas:      req.Quotas,
 	}
 
 	res, err := s.Store.Organizations(ctx).Add(ctx, org)
@@ -206,6 +216,10 @@ func (s *Service) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
 		org.DefaultRole = req.DefaultRole
 	}
 
+	if req.Quotas != nil {
+		org.Quotas = req.Quotas
+	}
+
 	err = s.Store.Organizations(ctx).Update(ctx, org)
 	if err != nil {
 		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
@@ -232,17 +246,15 @@ func (s *Service) RemoveOrganization(w http.ResponseWriter, r *http.Request) {
 		Error(w, http.StatusNotFound, err.Error(), s.Logger)
 		return
 	}
-	devices, err := s.Store.NetworkDevice(ctx).All(ctx)
+	devices, err := s.Store.NetworkDevice(ctx).Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &org.ID})
 	if err != nil {
 		Error(w, http.StatusInternalServerError, err.Error(), s.Logger)
 		return
 	}
-	for _, device := range devices {
-		if device.Organization == org.ID {
-			msg := "The organization cannot be deleted because there are registered devices associated with it."
-			Error(w, http.StatusConflict, msg, s.Logger)
-			return
-		}
+	if len(devices) > 0 {
+		msg := "The organization cannot be deleted because there are registered devices associated with it."
+		Error(w, http.StatusConflict, msg, s.Logger)
+		return
 	}
 
 	if err := s.Store.Organizations(ctx).Delete(ctx, org); err != nil {
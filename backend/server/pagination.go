@@ -0,0 +1,252 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// listFields maps the names of the fields a list can be filtered and sorted
+// by to the value of the field of an item.
+type listFields[T any] map[string]func(T) string
+
+// listOptions are the paging, filtering and sorting parameters of a list request:
+//
+//	?limit=50&cursor=<next cursor>&sort=-hostname&device_vendor=cisco,juniper
+//
+// Every query parameter named after a field filters the items to the ones
+// holding one of its comma separated values.
+type listOptions struct {
+	limit   int
+	cursor  *listCursor
+	sort    string
+	desc    bool
+	filters map[string][]string
+}
+
+// listCursor is the position of the last item of a page
+type listCursor struct {
+	Key string `json:"k"`
+	ID  string `json:"id"`
+}
+
+func (c *listCursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseListCursor(s string) (*listCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	var c listCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("invalid cursor %q", s)
+	}
+	return &c, nil
+}
+
+// newListOptions reads the list options of r for a list of fields.
+func newListOptions[T any](r *http.Request, fields listFields[T]) (*listOptions, error) {
+	q := r.URL.Query()
+	opts := &listOptions{
+		filters: map[string][]string{},
+	}
+
+	if s := q.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("invalid limit %q", s)
+		}
+		opts.limit = limit
+	}
+
+	if s := q.Get("cursor"); s != "" {
+		c, err := parseListCursor(s)
+		if err != nil {
+			return nil, err
+		}
+		opts.cursor = c
+	}
+
+	if s := q.Get("sort"); s != "" {
+		opts.desc = strings.HasPrefix(s, "-")
+		opts.sort = strings.TrimPrefix(s, "-")
+		if _, ok := fields[opts.sort]; !ok {
+			return nil, fmt.Errorf("unknown sort field %q", opts.sort)
+		}
+	}
+
+	for name := range fields {
+		if s := q.Get(name); s != "" {
+			opts.filters[name] = strings.Split(s, ",")
+		}
+	}
+
+	return opts, nil
+}
+
+// filter returns the single value a field is filtered by, if any
+func (o *listOptions) filter(name string) (string, bool) {
+	if values := o.filters[name]; len(values) == 1 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// compareListValues compares two field values numerically when both are numbers
+func compareListValues(a, b string) int {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// paginate filters, sorts and pages items by opts.
+// Sorted or paged items are ordered by the sort field and then by ID so that
+// a cursor points at a stable position. next is the cursor of the following page, if any.
+func paginate[T any](items []T, opts *listOptions, fields listFields[T], id func(T) string) (page []T, next string) {
+	page = make([]T, 0, len(items))
+	for _, item := range items {
+		if matchesListFilters(item, opts.filters, fields) {
+			page = append(page, item)
+		}
+	}
+
+	if opts.sort == "" && opts.limit == 0 && opts.cursor == nil {
+		// a plain list keeps the order of the store
+		return page, ""
+	}
+
+	key := func(T) string { return "" }
+	if opts.sort != "" {
+		key = fields[opts.sort]
+	}
+	less := func(a, b listCursor) bool {
+		c := compareListValues(a.Key, b.Key)
+		if c == 0 {
+			c = compareListValues(a.ID, b.ID)
+		}
+		if opts.desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	position := func(item T) listCursor {
+		return listCursor{Key: key(item), ID: id(item)}
+	}
+	sort.SliceStable(page, func(i, j int) bool {
+		return less(position(page[i]), position(page[j]))
+	})
+
+	if opts.cursor != nil {
+		i := sort.Search(len(page), func(i int) bool {
+			return less(*opts.cursor, position(page[i]))
+		})
+		page = page[i:]
+	}
+
+	if opts.limit > 0 && len(page) > opts.limit {
+		page = page[:opts.limit]
+		last := position(page[len(page)-1])
+		next = last.String()
+	}
+
+	return page, next
+}
+
+// pageStore is a store listing its records by pages
+type pageStore[T any] interface {
+	All(context.Context) ([]T, error)
+	Page(context.Context, cloudhub.PageQuery[T]) ([]T, error)
+}
+
+// listPage returns the page of the items of a store opts and match select.
+// Lists in the order of the store are paged by the store, which reads the
+// items of the page only. Lists sorted by a field read every item to sort them.
+func listPage[T any](ctx context.Context, store pageStore[T], opts *listOptions, fields listFields[T], id func(T) string, match func(T) bool) (page []T, next string, err error) {
+	if opts.sort != "" {
+		items, err := store.All(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		if match != nil {
+			matched := items[:0]
+			for _, item := range items {
+				if match(item) {
+					matched = append(matched, item)
+				}
+			}
+			items = matched
+		}
+		page, next = paginate(items, opts, fields, id)
+		return page, next, nil
+	}
+
+	q := cloudhub.PageQuery[T]{
+		Match: func(item T) bool {
+			return matchesListFilters(item, opts.filters, fields) && (match == nil || match(item))
+		},
+	}
+	if opts.cursor != nil {
+		q.After = opts.cursor.ID
+	}
+	if opts.limit > 0 {
+		// one more item tells whether a page follows
+		q.Limit = opts.limit + 1
+	}
+	if page, err = store.Page(ctx, q); err != nil {
+		return nil, "", err
+	}
+	if opts.limit > 0 && len(page) > opts.limit {
+		page = page[:opts.limit]
+		last := listCursor{ID: id(page[len(page)-1])}
+		next = last.String()
+	}
+	return page, next, nil
+}
+
+func matchesListFilters[T any](item T, filters map[string][]string, fields listFields[T]) bool {
+	for name, values := range filters {
+		value := fields[name](item)
+		matched := false
+		for _, v := range values {
+			if v == value {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// setNextLink sets the Link header pointing at the following page of a list
+func setNextLink(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	q := r.URL.Query()
+	q.Set("cursor", next)
+	u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, u.String()))
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func Test_paginate(t *testing.T) {
+	devices := []cloudhub.NetworkDevice{
+		{ID: "10", Organization: "default", Hostname: "b", DeviceVendor: "cisco"},
+		{ID: "2", Organization: "default", Hostname: "a", DeviceVendor: "cisco"},
+		{ID: "3", Organization: "1", Hostname: "c", DeviceVendor: "juniper"},
+		{ID: "4", Organization: "default", Hostname: "a", DeviceVendor: "juniper"},
+		{ID: "5", Organization: "default", Hostname: "d", DeviceVendor: "arista"},
+	}
+	id := func(d cloudhub.NetworkDevice) string { return d.ID }
+
+	list := func(query string) ([]string, string) {
+		r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/ai/network/managements/devices?"+query, nil)
+		opts, err := newListOptions(r, deviceListFields)
+		if err != nil {
+			t.Fatalf("newListOptions(%q) error = %v", query, err)
+		}
+		page, next := paginate(devices, opts, deviceListFields, id)
+		ids := []string{}
+		for _, d := range page {
+			ids = append(ids, d.ID)
+		}
+		return ids, next
+	}
+
+	if ids, next := list(""); !reflect.DeepEqual(ids, []string{"10", "2", "3", "4", "5"}) || next != "" {
+		t.Errorf("plain list = %v, %q, want the store order", ids, next)
+	}
+	if ids, _ := list("device_vendor=cisco,arista&organization=default"); !reflect.DeepEqual(ids, []string{"10", "2", "5"}) {
+		t.Errorf("filtered list = %v", ids)
+	}
+	if ids, _ := list("sort=-hostname"); !reflect.DeepEqual(ids, []string{"5", "3", "10", "4", "2"}) {
+		t.Errorf("sorted list = %v", ids)
+	}
+
+	// walking the pages returns every device once in order
+	var got []string
+	query := "sort=hostname&limit=2"
+	for i := 0; i < 5; i++ {
+		ids, next := list(query)
+		got = append(got, ids...)
+		if next == "" {
+			break
+		}
+		query = "sort=hostname&limit=2&cursor=" + url.QueryEscape(next)
+	}
+	if want := []string{"2", "4", "10", "3", "5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paged list = %v, want %v", got, want)
+	}
+
+	for _, query := range []string{"limit=0", "limit=x", "sort=password", "cursor=!!"} {
+		r := httptest.NewRequest("GET", "http://any.url/?"+query, nil)
+		if _, err := newListOptions(r, deviceListFields); err == nil {
+			t.Errorf("newListOptions(%q) expected an error", query)
+		}
+	}
+}
+
+func Test_setNextLink(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/csp?limit=2", nil)
+	setNextLink(w, r, "abc")
+	if got, want := w.Header().Get("Link"), `</cloudhub/v1/csp?cursor=abc&limit=2>; rel="next"`; got != want {
+		t.Errorf("setNextLink() Link = %s, want %s", got, want)
+	}
+}
+
+func Test_listPage(t *testing.T) {
+	dashboards := []cloudhub.Dashboard{
+		{ID: 1, Name: "b", Organization: "default"},
+		{ID: 2, Name: "a", Organization: "1"},
+		{ID: 3, Name: "c", Organization: "default"},
+		{ID: 4, Name: "a", Organization: "default"},
+	}
+	var paged []cloudhub.PageQuery[cloudhub.Dashboard]
+	var all int
+	store := &mocks.DashboardsStore{
+		AllF: func(ctx context.Context) ([]cloudhub.Dashboard, error) {
+			all++
+			return append([]cloudhub.Dashboard{}, dashboards...), nil
+		},
+		PageF: func(ctx context.Context, q cloudhub.PageQuery[cloudhub.Dashboard]) ([]cloudhub.Dashboard, error) {
+			paged = append(paged, q)
+			return cloudhub.PageOf(dashboards, q, func(d cloudhub.Dashboard) string { return strconv.Itoa(int(d.ID)) }), nil
+		},
+	}
+	id := func(d cloudhub.Dashboard) string { return strconv.Itoa(int(d.ID)) }
+	notNamed := func(name string) func(cloudhub.Dashboard) bool {
+		return func(d cloudhub.Dashboard) bool { return d.Name != name }
+	}
+
+	list := func(query string, match func(cloudhub.Dashboard) bool) ([]string, string) {
+		r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/dashboards?"+query, nil)
+		opts, err := newListOptions(r, dashboardListFields)
+		if err != nil {
+			t.Fatalf("newListOptions(%q) error = %v", query, err)
+		}
+		page, next, err := listPage(context.Background(), store, opts, dashboardListFields, id, match)
+		if err != nil {
+			t.Fatalf("listPage(%q) error = %v", query, err)
+		}
+		ids := []string{}
+		for _, d := range page {
+			ids = append(ids, id(d))
+		}
+		return ids, next
+	}
+
+	// walking the pages of the store order reads the pages from the store
+	var got []string
+	query := "organization=default&limit=2"
+	for i := 0; i < 3; i++ {
+		ids, next := list(query, nil)
+		got = append(got, ids...)
+		if next == "" {
+			break
+		}
+		query = "organization=default&limit=2&cursor=" + url.QueryEscape(next)
+	}
+	if want := []string{"1", "3", "4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paged list = %v, want %v", got, want)
+	}
+	if all != 0 || len(paged) != 2 || paged[0].Limit != 3 || paged[1].After != "3" {
+		t.Errorf("paged list read %d lists and the pages %+v, want 2 pages from the store", all, paged)
+	}
+
+	if ids, next := list("", notNamed("c")); !reflect.DeepEqual(ids, []string{"1", "2", "4"}) || next != "" {
+		t.Errorf("matched list = %v, %q", ids, next)
+	}
+
+	// sorting reads every dashboard
+	if ids, _ := list("sort=name&limit=2", notNamed("b")); !reflect.DeepEqual(ids, []string{"2", "4"}) || all != 1 {
+		t.Errorf("sorted list = %v after %d lists, want 2, 4 after 1", ids, all)
+	}
+}
@@ -1,8 +1,13 @@
 package server
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/bouk/httprouter"
 	cloudhub "github.com/snetsystems/cloudhub/backend"
@@ -74,3 +79,353 @@ func (s *Service) ProtoboardsID(w http.ResponseWriter, r *http.Request) {
 	res := newProtoboardResponse(protoboard)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
+
+// ValidProtoboardRequest checks the protoboard of create and update requests and
+// sets the defaults of its missing fields
+func ValidProtoboardRequest(p *cloudhub.Protoboard) error {
+	if p.Meta.Name == "" {
+		return fmt.Errorf("name of the protoboard required on protoboard request body")
+	}
+	if p.Meta.Measurements == nil {
+		p.Meta.Measurements = []string{}
+	}
+	if p.Data.Cells == nil {
+		p.Data.Cells = []cloudhub.ProtoboardCell{}
+	}
+	if p.Data.Templates == nil {
+		p.Data.Templates = []cloudhub.Template{}
+	}
+	for i, c := range p.Data.Cells {
+		cell := dashboardCell(c)
+		if err := ValidDashboardCellRequest(&cell); err != nil {
+			return err
+		}
+		p.Data.Cells[i] = protoboardCell(cell)
+	}
+	for _, t := range p.Data.Templates {
+		if err := ValidTemplateRequest(&t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nextProtoboardVersion returns the version following v, whose last number is
+// incremented, e.g. 1.1 follows 1.0
+func nextProtoboardVersion(v string) string {
+	parts := strings.Split(v, ".")
+	n, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		return "1.0"
+	}
+	parts[len(parts)-1] = strconv.Itoa(n + 1)
+	return strings.Join(parts, ".")
+}
+
+// NewProtoboard adds a protoboard to the protoboards stored in the database
+func (s *Service) NewProtoboard(w http.ResponseWriter, r *http.Request) {
+	var protoboard cloudhub.Protoboard
+	if err := json.NewDecoder(r.Body).Decode(&protoboard); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+	s.addProtoboard(w, r, protoboard)
+}
+
+func (s *Service) addProtoboard(w http.ResponseWriter, r *http.Request, protoboard cloudhub.Protoboard) {
+	if err := ValidProtoboardRequest(&protoboard); err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	if protoboard.Meta.Version == "" {
+		protoboard.Meta.Version = "1.0"
+	}
+
+	ctx := r.Context()
+	protoboard, err := s.Store.Protoboards(ctx).Add(ctx, protoboard)
+	if err != nil {
+		msg := fmt.Errorf("Error storing protoboard %s: %v", protoboard.Meta.Name, err)
+		unknownErrorWithMessage(w, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgProtoboardCreated.String(), protoboard.Meta.Name)
+	s.logRegistration(ctx, "Protoboards", msg)
+
+	res := newProtoboardResponse(protoboard)
+	location(w, res.Links.Self)
+	encodeJSON(w, http.StatusCreated, res, s.Logger)
+}
+
+// ReplaceProtoboard replaces a protoboard stored in the database. The version of
+// the protoboard is incremented unless the request sets a new version.
+func (s *Service) ReplaceProtoboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := httprouter.GetParamFromContext(ctx, "id")
+
+	existing, err := s.Store.Protoboards(ctx).Get(ctx, id)
+	if err != nil {
+		Error(w, http.StatusNotFound, fmt.Sprintf("ID %s not found", id), s.Logger)
+		return
+	}
+
+	var protoboard cloudhub.Protoboard
+	if err := json.NewDecoder(r.Body).Decode(&protoboard); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+	protoboard.ID = id
+	if err := ValidProtoboardRequest(&protoboard); err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	if v := protoboard.Meta.Version; v == "" || v == existing.Meta.Version {
+		protoboard.Meta.Version = nextProtoboardVersion(existing.Meta.Version)
+	}
+
+	if err := s.Store.Protoboards(ctx).Update(ctx, protoboard); err == cloudhub.ErrProtoboardReadOnly {
+		readOnlyProtoboard(w, id, s.Logger)
+		return
+	} else if err != nil {
+		msg := fmt.Sprintf("Error updating protoboard ID %s: %v", id, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgProtoboardModified.String(), protoboard.Meta.Name, protoboard.Meta.Version)
+	s.logRegistration(ctx, "Protoboards", msg)
+
+	encodeJSON(w, http.StatusOK, newProtoboardResponse(protoboard), s.Logger)
+}
+
+// RemoveProtoboard deletes a protoboard stored in the database
+func (s *Service) RemoveProtoboard(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := httprouter.GetParamFromContext(ctx, "id")
+
+	protoboard, err := s.Store.Protoboards(ctx).Get(ctx, id)
+	if err != nil {
+		Error(w, http.StatusNotFound, fmt.Sprintf("ID %s not found", id), s.Logger)
+		return
+	}
+
+	if err := s.Store.Protoboards(ctx).Delete(ctx, protoboard); err == cloudhub.ErrProtoboardReadOnly {
+		readOnlyProtoboard(w, id, s.Logger)
+		return
+	} else if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgProtoboardDeleted.String(), protoboard.Meta.Name)
+	s.logRegistration(ctx, "Protoboards", msg)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func readOnlyProtoboard(w http.ResponseWriter, id string, logger cloudhub.Logger) {
+	Error(w, http.StatusForbidden, fmt.Sprintf("Protoboard %s is shipped with CloudHub and cannot be changed", id), logger)
+}
+
+// dashboardProtoboardRequest saves a dashboard as a protoboard
+type dashboardProtoboardRequest struct {
+	// Meta describes the protoboard; the name defaults to the name of the dashboard
+	Meta cloudhub.ProtoboardMeta `json:"meta"`
+	// Tags are the tags whose values in the queries are replaced by template
+	// variables. Defaults to host.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// DashboardProtoboard saves a dashboard as a protoboard. The queries are changed to
+// query the database of the source the protoboard is instantiated for, and the values
+// of the tags of the request are replaced by template variables.
+func (s *Service) DashboardProtoboard(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+
+	var req dashboardProtoboardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+	if len(req.Tags) == 0 {
+		req.Tags = []string{"host"}
+	}
+
+	ctx := r.Context()
+	d, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	protoboard := newDashboardProtoboard(d, req.Tags)
+	measurements := protoboard.Meta.Measurements
+	protoboard.Meta = req.Meta
+	if protoboard.Meta.Name == "" {
+		protoboard.Meta.Name = d.Name
+	}
+	if len(protoboard.Meta.Measurements) == 0 {
+		protoboard.Meta.Measurements = measurements
+	}
+	s.addProtoboard(w, r, protoboard)
+}
+
+var (
+	// qualifiedMeasurement matches the database and retention policy of the
+	// measurements of FROM clauses
+	qualifiedMeasurement = regexp.MustCompile(`(?i)(\bFROM\s+)(?:"(?:[^"\\]|\\.)*"|\w+)\.(?:"(?:[^"\\]|\\.)*"|\w+)?\.("(?:[^"\\]|\\.)*"|\w+)`)
+	// exactRegex matches the regexes matching a single value, e.g. /^server01$/
+	exactRegex = regexp.MustCompile(`^/\^([\w.\-]+)\$/$`)
+)
+
+// newDashboardProtoboard returns the protoboard of the dashboard. The queries of the
+// protoboard have no source, query the :db: and :rp: of the source the protoboard is
+// instantiated for, and have template variables in place of the values of the tags.
+func newDashboardProtoboard(d cloudhub.Dashboard, tags []string) cloudhub.Protoboard {
+	conditions := map[string]*regexp.Regexp{}
+	for _, tag := range tags {
+		name := regexp.QuoteMeta(tag)
+		conditions[tag] = regexp.MustCompile(`("` + name + `"|\b` + name + `\b)\s*(=~|=)\s*('(?:[^'\\]|\\.)*'|/(?:[^/\\]|\\.)*/)`)
+	}
+
+	p := cloudhub.Protoboard{
+		Meta: cloudhub.ProtoboardMeta{
+			Measurements:     []string{},
+			DashboardVersion: "1.x",
+		},
+		Data: cloudhub.ProtoboardData{
+			Cells:     []cloudhub.ProtoboardCell{},
+			Templates: []cloudhub.Template{},
+		},
+	}
+	measurements := map[string]bool{}
+	// templated are the tags replaced by template variables with their first measurement
+	templated := map[string]string{}
+
+	for _, c := range d.Cells {
+		cell := protoboardCell(c)
+		cell.Queries = make([]cloudhub.DashboardQuery, len(c.Queries))
+		for i, q := range c.Queries {
+			q.Source = ""
+			if q.Type != "flux" {
+				measurement := ""
+				q.Command = qualifiedMeasurement.ReplaceAllStringFunc(q.Command, func(match string) string {
+					m := qualifiedMeasurement.FindStringSubmatch(match)
+					if measurement == "" {
+						measurement = strings.Trim(m[2], `"`)
+					}
+					measurements[strings.Trim(m[2], `"`)] = true
+					return m[1] + `":db:".":rp:".` + m[2]
+				})
+				for _, tag := range tags {
+					q.Command = conditions[tag].ReplaceAllStringFunc(q.Command, func(match string) string {
+						m := conditions[tag].FindStringSubmatch(match)
+						if m[2] == "=~" && !exactRegex.MatchString(m[3]) {
+							return match
+						}
+						if _, ok := templated[tag]; !ok {
+							templated[tag] = measurement
+						}
+						return m[1] + " = :" + tag + ":"
+					})
+				}
+				q.QueryConfig = ToQueryConfig(q.Command)
+			}
+			cell.Queries[i] = q
+		}
+		p.Data.Cells = append(p.Data.Cells, cell)
+	}
+
+	vars := map[string]bool{}
+	for _, t := range d.Templates {
+		vars[t.Var] = true
+		t.ID = ""
+		switch t.Type {
+		case "csv", "map", "constant", "text":
+		default:
+			// the values of the other templates are queried from the source
+			t.Values = []cloudhub.TemplateValue{}
+		}
+		p.Data.Templates = append(p.Data.Templates, t)
+	}
+	for _, tag := range tags {
+		measurement, ok := templated[tag]
+		if !ok || vars[":"+tag+":"] {
+			continue
+		}
+		p.Data.Templates = append(p.Data.Templates, cloudhub.Template{
+			TemplateVar: cloudhub.TemplateVar{
+				Var:    ":" + tag + ":",
+				Values: []cloudhub.TemplateValue{},
+			},
+			Type: "tagValues",
+			Query: &cloudhub.TemplateQuery{
+				Command:     "SHOW TAG VALUES ON :database: FROM :measurement: WITH KEY=:tagKey:",
+				DB:          "telegraf",
+				Measurement: measurement,
+				TagKey:      tag,
+			},
+		})
+	}
+
+	for m := range measurements {
+		p.Meta.Measurements = append(p.Meta.Measurements, m)
+	}
+	sort.Strings(p.Meta.Measurements)
+	return p
+}
+
+func protoboardCell(c cloudhub.DashboardCell) cloudhub.ProtoboardCell {
+	return cloudhub.ProtoboardCell{
+		X:              c.X,
+		Y:              c.Y,
+		W:              c.W,
+		H:              c.H,
+		MinW:           c.MinW,
+		MinH:           c.MinH,
+		Name:           c.Name,
+		Queries:        c.Queries,
+		Axes:           c.Axes,
+		Type:           c.Type,
+		CellColors:     c.CellColors,
+		Legend:         c.Legend,
+		TableOptions:   c.TableOptions,
+		FieldOptions:   c.FieldOptions,
+		TimeFormat:     c.TimeFormat,
+		DecimalPlaces:  c.DecimalPlaces,
+		Note:           c.Note,
+		NoteVisibility: c.NoteVisibility,
+		GraphOptions:   c.GraphOptions,
+	}
+}
+
+func dashboardCell(c cloudhub.ProtoboardCell) cloudhub.DashboardCell {
+	return cloudhub.DashboardCell{
+		X:              c.X,
+		Y:              c.Y,
+		W:              c.W,
+		H:              c.H,
+		MinW:           c.MinW,
+		MinH:           c.MinH,
+		Name:           c.Name,
+		Queries:        c.Queries,
+		Axes:           c.Axes,
+		Type:           c.Type,
+		CellColors:     c.CellColors,
+		Legend:         c.Legend,
+		TableOptions:   c.TableOptions,
+		FieldOptions:   c.FieldOptions,
+		TimeFormat:     c.TimeFormat,
+		DecimalPlaces:  c.DecimalPlaces,
+		Note:           c.Note,
+		NoteVisibility: c.NoteVisibility,
+		GraphOptions:   c.GraphOptions,
+	}
+}
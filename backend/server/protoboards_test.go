@@ -332,3 +332,177 @@ func Test_ProtoboardsID(t *testing.T) {
 		})
 	}
 }
+
+func TestService_ReplaceProtoboard(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          string
+		body        string
+		readOnly    bool
+		wantStatus  int
+		wantVersion string
+	}{
+		{
+			name:        "version incremented",
+			id:          "1",
+			body:        `{"meta": {"name": "nginx", "version": "1.2"}}`,
+			wantStatus:  http.StatusOK,
+			wantVersion: "1.3",
+		},
+		{
+			name:        "version set by the request",
+			id:          "1",
+			body:        `{"meta": {"name": "nginx", "version": "2.0"}}`,
+			wantStatus:  http.StatusOK,
+			wantVersion: "2.0",
+		},
+		{
+			name:       "protoboard shipped with CloudHub",
+			id:         "1",
+			body:       `{"meta": {"name": "nginx"}}`,
+			readOnly:   true,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "protoboard without name",
+			id:         "1",
+			body:       `{"meta": {}}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unknown protoboard",
+			id:         "2",
+			body:       `{"meta": {"name": "nginx"}}`,
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *cloudhub.Protoboard
+			s := &Service{
+				Store: &mocks.Store{
+					ProtoboardsStore: &mocks.ProtoboardsStore{
+						GetF: func(ctx context.Context, id string) (cloudhub.Protoboard, error) {
+							if id != "1" {
+								return cloudhub.Protoboard{}, cloudhub.ErrProtoboardNotFound
+							}
+							return cloudhub.Protoboard{ID: "1", Meta: cloudhub.ProtoboardMeta{Name: "nginx", Version: "1.2"}}, nil
+						},
+						UpdateF: func(ctx context.Context, p cloudhub.Protoboard) error {
+							if tt.readOnly {
+								return cloudhub.ErrProtoboardReadOnly
+							}
+							updated = &p
+							return nil
+						},
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("PUT", "http://any.url/cloudhub/v1/protoboards/"+tt.id, strings.NewReader(tt.body))
+			r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: tt.id}}))
+			s.ReplaceProtoboard(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("ReplaceProtoboard() status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantVersion == "" {
+				return
+			}
+			if updated == nil || updated.ID != "1" || updated.Meta.Version != tt.wantVersion {
+				t.Errorf("ReplaceProtoboard() updated %#v, want version %s", updated, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestService_DashboardProtoboard(t *testing.T) {
+	var added *cloudhub.Protoboard
+	s := &Service{
+		Store: &mocks.Store{
+			DashboardsStore: &mocks.DashboardsStore{
+				GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+					return cloudhub.Dashboard{
+						ID:   id,
+						Name: "web servers",
+						Cells: []cloudhub.DashboardCell{
+							{
+								Name: "Requests",
+								W:    24,
+								H:    12,
+								Queries: []cloudhub.DashboardQuery{
+									{
+										Command: `SELECT mean("requests") FROM "telegraf"."autogen"."nginx" WHERE "host" = 'web01' AND time > :dashboardTime: GROUP BY time(:interval:)`,
+										Source:  "/cloudhub/v1/sources/1",
+										Type:    "influxql",
+									},
+									{
+										Command: `SELECT mean("usage_user") FROM telegraf.."cpu" WHERE "host" =~ /^web01$/ AND "cpu" =~ /total|cpu0/`,
+										Source:  "/cloudhub/v1/sources/1",
+										Type:    "influxql",
+									},
+								},
+							},
+						},
+						Templates: []cloudhub.Template{
+							{
+								ID:          "t1",
+								TemplateVar: cloudhub.TemplateVar{Var: ":env:", Values: []cloudhub.TemplateValue{{Value: "prod", Type: "csv", Selected: true}}},
+								Type:        "csv",
+							},
+						},
+					}, nil
+				},
+			},
+			ProtoboardsStore: &mocks.ProtoboardsStore{
+				AddF: func(ctx context.Context, p cloudhub.Protoboard) (cloudhub.Protoboard, error) {
+					p.ID = "pb1"
+					added = &p
+					return p, nil
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "http://any.url/cloudhub/v1/dashboards/3/protoboard", strings.NewReader(`{"meta": {"icon": "nginx"}}`))
+	r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "3"}}))
+	s.DashboardProtoboard(w, r)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("DashboardProtoboard() status = %d, body %s", w.Code, w.Body.String())
+	}
+	if added == nil {
+		t.Fatal("DashboardProtoboard() did not add the protoboard")
+	}
+	if m := added.Meta; m.Name != "web servers" || m.Icon != "nginx" || m.Version != "1.0" || strings.Join(m.Measurements, ",") != "cpu,nginx" {
+		t.Errorf("DashboardProtoboard() unexpected meta %#v", m)
+	}
+
+	queries := []string{
+		`SELECT mean("requests") FROM ":db:".":rp:"."nginx" WHERE "host" = :host: AND time > :dashboardTime: GROUP BY time(:interval:)`,
+		`SELECT mean("usage_user") FROM ":db:".":rp:"."cpu" WHERE "host" = :host: AND "cpu" =~ /total|cpu0/`,
+	}
+	for i, q := range added.Data.Cells[0].Queries {
+		if q.Command != queries[i] || q.Source != "" {
+			t.Errorf("DashboardProtoboard() query %d = %s %s, want %s", i, q.Source, q.Command, queries[i])
+		}
+	}
+
+	templates := added.Data.Templates
+	if len(templates) != 2 || templates[0].ID != "" || templates[0].Var != ":env:" || len(templates[0].Values) != 1 {
+		t.Fatalf("DashboardProtoboard() templates = %#v", templates)
+	}
+	wantQuery := cloudhub.TemplateQuery{
+		Command:     "SHOW TAG VALUES ON :database: FROM :measurement: WITH KEY=:tagKey:",
+		DB:          "telegraf",
+		Measurement: "nginx",
+		TagKey:      "host",
+	}
+	if host := templates[1]; host.Var != ":host:" || host.Type != "tagValues" || host.Query == nil || *host.Query != wantQuery {
+		t.Errorf("DashboardProtoboard() host template = %#v", host)
+	}
+}
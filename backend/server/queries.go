@@ -27,7 +27,9 @@ type QueriesRequest struct {
 }
 
 // QueryResponse is the return result of a QueryRequest including
-// the raw query, the templated query, the queryConfig and the queryAST
+// the raw query, the templated query, the queryConfig and the queryAST.
+// QueryRewritten is the query proxied once rewritten by the query policy of
+// the role, and PolicyError the reason the policy rejects the query.
 type QueryResponse struct {
 	Duration       int64                    `json:"durationMs"`
 	ID             string                   `json:"id"`
@@ -35,6 +37,8 @@ type QueryResponse struct {
 	QueryConfig    cloudhub.QueryConfig     `json:"queryConfig"`
 	QueryAST       *queries.SelectStatement `json:"queryAST,omitempty"`
 	QueryTemplated *string                  `json:"queryTemplated,omitempty"`
+	QueryRewritten *string                  `json:"queryRewritten,omitempty"`
+	PolicyError    string                   `json:"policyError,omitempty"`
 }
 
 // QueriesResponse is the response for a QueriesRequest
@@ -65,6 +69,7 @@ func (s *Service) Queries(w http.ResponseWriter, r *http.Request) {
 	res := QueriesResponse{
 		Queries: make([]QueryResponse, len(req.Queries)),
 	}
+	policy := s.queryPolicy(ctx)
 
 	for i, q := range req.Queries {
 		qr := QueryResponse{
@@ -93,6 +98,12 @@ func (s *Service) Queries(w http.ResponseWriter, r *http.Request) {
 			qr.Duration = ms
 		}
 
+		if command, rewritten, err := policy.Apply(q.Query, time.Now()); err != nil {
+			qr.PolicyError = err.Error()
+		} else if rewritten {
+			qr.QueryRewritten = &command
+		}
+
 		qr.QueryConfig.ID = q.ID
 		res.Queries[i] = qr
 
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/querycache"
+)
+
+const (
+	// defaultQueryCacheTTL is the time the results of queries proxied without
+	// refresh interval are cached
+	defaultQueryCacheTTL = 10 * time.Second
+	minQueryCacheTTL     = time.Second
+	maxQueryCacheTTL     = 5 * time.Minute
+)
+
+// queryCacheTTL returns the time for which the results of the queries of r are
+// cached: the refresh interval of the client, as a duration or milliseconds in
+// the refresh parameter, within bounds
+func queryCacheTTL(r *http.Request) (time.Duration, error) {
+	refresh := r.URL.Query().Get("refresh")
+	if refresh == "" {
+		return defaultQueryCacheTTL, nil
+	}
+	ttl, err := time.ParseDuration(refresh)
+	if err != nil {
+		ms, err := strconv.ParseInt(refresh, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid refresh interval %q", refresh)
+		}
+		ttl = time.Duration(ms) * time.Millisecond
+	}
+	if ttl < minQueryCacheTTL {
+		ttl = minQueryCacheTTL
+	}
+	if ttl > maxQueryCacheTTL {
+		ttl = maxQueryCacheTTL
+	}
+	return ttl, nil
+}
+
+// cachedInfluxQuery returns the results of the query of the request, shared with
+// the identical queries of the source proxied within the TTL. The results of
+// queries grouped by time are only shared within an interval of GROUP BY time().
+func (s *Service) cachedInfluxQuery(r *http.Request, src cloudhub.Source, q cloudhub.Query, query func(context.Context) (json.RawMessage, error)) (json.RawMessage, querycache.Status, error) {
+	ctx := r.Context()
+	command := querycache.Normalize(q.Command)
+	if s.QueryCache == nil || !querycache.Cacheable(command) || strings.Contains(r.Header.Get("Cache-Control"), "no-cache") {
+		res, err := query(ctx)
+		return res, querycache.Miss, err
+	}
+	ttl, err := queryCacheTTL(r)
+	if err != nil {
+		return nil, querycache.Miss, err
+	}
+
+	now := s.QueryCache.Now()
+	expires := now.Add(ttl)
+	key := strings.Join([]string{strconv.Itoa(src.ID), q.DB, q.RP, q.Epoch, command}, "\x00")
+	if interval := querycache.GroupByInterval(command); interval > 0 {
+		start, end := querycache.Bucket(now, interval)
+		key += "\x00" + strconv.FormatInt(start.UnixNano(), 10)
+		if end.Before(expires) {
+			expires = end
+		}
+	}
+
+	res, status, err := s.QueryCache.Get(key, expires, func() ([]byte, error) {
+		// requests waiting for the results must not fail when the first one is canceled
+		return query(context.WithoutCancel(ctx))
+	})
+	return res, status, err
+}
+
+// QueryCacheStats returns the metrics of the cache of the results of the queries proxied
+func (s *Service) QueryCacheStats(w http.ResponseWriter, r *http.Request) {
+	if s.QueryCache == nil {
+		Error(w, http.StatusNotFound, "The query cache is disabled", s.Logger)
+		return
+	}
+	encodeJSON(w, http.StatusOK, s.QueryCache.Stats(), s.Logger)
+}
+
+// FlushQueryCache removes the results cached of the queries proxied
+func (s *Service) FlushQueryCache(w http.ResponseWriter, r *http.Request) {
+	if s.QueryCache != nil {
+		s.QueryCache.Flush()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/querycache"
+)
+
+func TestService_InfluxQueryCache(t *testing.T) {
+	now := time.Date(2023, 11, 20, 8, 0, 5, 0, time.UTC)
+	cache := querycache.New(1 << 20)
+	cache.Now = func() time.Time { return now }
+
+	var queries []string
+	s := &Service{
+		Store: &mocks.Store{
+			SourcesStore: &mocks.SourcesStore{
+				GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+					return cloudhub.Source{ID: id, URL: "http://any.url"}, nil
+				},
+			},
+		},
+		TimeSeriesClient: &mocks.TimeSeries{
+			ConnectF: func(ctx context.Context, src *cloudhub.Source) error {
+				return nil
+			},
+			QueryF: func(ctx context.Context, q cloudhub.Query) (cloudhub.Response, error) {
+				queries = append(queries, q.Command)
+				return mocks.NewResponse(`[{"statement_id":0}]`, nil), nil
+			},
+		},
+		Logger:     &mocks.TestLogger{},
+		QueryCache: cache,
+	}
+
+	proxy := func(source, params, body string, header http.Header) (string, postInfluxResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("POST", "http://any.url/cloudhub/v1/sources/"+source+"/proxy?"+params, strings.NewReader(body))
+		for k := range header {
+			r.Header.Set(k, header.Get(k))
+		}
+		r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: source}}))
+		s.Influx(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Influx() status = %d: %s", w.Code, w.Body.String())
+		}
+		var res postInfluxResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return w.Header().Get("X-Cache"), res
+	}
+
+	grouped := `{"db":"telegraf","uuid":"a","query":"SELECT mean(usage) FROM cpu WHERE time > now() - 1h GROUP BY time(1m)"}`
+	if status, res := proxy("1", "refresh=10000", grouped, nil); status != "MISS" || res.UUID != "a" {
+		t.Errorf("Influx() = %s, %#v", status, res)
+	}
+	// identical queries formatted differently share the results, with their own uuid
+	sameQuery := `{"db":"telegraf","uuid":"b","query":"SELECT  mean(usage)\nFROM cpu WHERE time > now() - 1h GROUP BY time(1m);"}`
+	if status, res := proxy("1", "refresh=10s", sameQuery, nil); status != "HIT" || res.UUID != "b" {
+		t.Errorf("Influx() of an identical query = %s, %#v", status, res)
+	}
+	for _, tt := range []struct {
+		name   string
+		source string
+		body   string
+		header http.Header
+	}{
+		{"of another source", "2", grouped, nil},
+		{"of another database", "1", strings.Replace(grouped, "telegraf", "other", 1), nil},
+		{"writing data", "1", `{"query":"SELECT * INTO copy FROM cpu"}`, nil},
+		{"without cache", "1", grouped, http.Header{"Cache-Control": []string{"no-cache"}}},
+	} {
+		if status, _ := proxy(tt.source, "", tt.body, tt.header); status != "MISS" {
+			t.Errorf("Influx() %s = %s", tt.name, status)
+		}
+	}
+
+	// the results of queries grouped by time expire with their interval
+	now = now.Add(55 * time.Second)
+	if status, _ := proxy("1", "refresh=10s", grouped, nil); status != "MISS" {
+		t.Errorf("Influx() in the next interval of GROUP BY time() = %s", status)
+	}
+	if len(queries) != 6 {
+		t.Errorf("Influx() queried %d times, want 6", len(queries))
+	}
+
+	w := httptest.NewRecorder()
+	s.QueryCacheStats(w, httptest.NewRequest("GET", "http://any.url/cloudhub/v1/query-cache", nil))
+	var stats querycache.Stats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Hits != 1 || stats.Misses != 4 || stats.Entries != 4 {
+		t.Errorf("QueryCacheStats() = %#v", stats)
+	}
+}
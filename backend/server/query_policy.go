@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/influx"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+// defaultQueryPolicies are the limits of the queries proxied to InfluxDB per
+// role, unless changed by the options of the server
+var defaultQueryPolicies = map[string]influx.QueryPolicy{
+	roles.ViewerRoleName: {
+		ReadOnly:     true,
+		DefaultRange: time.Hour,
+		MaxRange:     90 * 24 * time.Hour,
+		MaxPoints:    10000,
+		MaxSeries:    1000,
+		Timeout:      30 * time.Second,
+	},
+	roles.EditorRoleName: {
+		DefaultRange: time.Hour,
+		MaxRange:     365 * 24 * time.Hour,
+		MaxPoints:    50000,
+		MaxSeries:    5000,
+		Timeout:      time.Minute,
+	},
+	roles.AdminRoleName: {
+		Timeout: 2 * time.Minute,
+	},
+}
+
+// QueryPolicies returns the default query policies of the roles with the limits
+// of the options of the server, keyed by role. Options are default-range,
+// max-range and timeout durations, and max-points and max-series numbers, 0
+// meaning unlimited.
+func QueryPolicies(options map[string]map[string]string) (map[string]influx.QueryPolicy, error) {
+	policies := map[string]influx.QueryPolicy{}
+	for role, policy := range defaultQueryPolicies {
+		for key, value := range options[role] {
+			var err error
+			switch key {
+			case "default-range":
+				policy.DefaultRange, err = time.ParseDuration(value)
+			case "max-range":
+				policy.MaxRange, err = time.ParseDuration(value)
+			case "timeout":
+				policy.Timeout, err = time.ParseDuration(value)
+			case "max-points":
+				policy.MaxPoints, err = strconv.Atoi(value)
+			case "max-series":
+				policy.MaxSeries, err = strconv.Atoi(value)
+			default:
+				return nil, fmt.Errorf("unknown query policy option %q of the %s role", key, role)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid query policy option %s:%s of the %s role: %v", key, value, role, err)
+			}
+		}
+		policies[role] = policy
+	}
+	return policies, nil
+}
+
+// queryPolicy returns the policy of the queries of the role of the user on
+// context, the one of viewers for other roles. Queries are not restricted
+// without authentication.
+func (s *Service) queryPolicy(ctx context.Context) influx.QueryPolicy {
+	role, ok := hasRoleContext(ctx)
+	if !ok {
+		return influx.QueryPolicy{}
+	}
+	policies := s.QueryPolicies
+	if policies == nil {
+		policies = defaultQueryPolicies
+	}
+	if p, ok := policies[role]; ok {
+		return p
+	}
+	return policies[roles.ViewerRoleName]
+}
+
+// queryPolicyError responds with a query rejected by its policy
+func queryPolicyError(w http.ResponseWriter, err *influx.PolicyError, logger cloudhub.Logger) {
+	code := http.StatusUnprocessableEntity
+	if err.Forbidden {
+		code = http.StatusForbidden
+	}
+	Error(w, code, err.Message, logger)
+}
@@ -0,0 +1,201 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bouk/httprouter"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/protoboards"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+func TestService_Influx_QueryPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		role        string
+		query       string
+		wantStatus  int
+		wantCommand string
+		wantBody    string
+	}{
+		{
+			name:        "bounded query of a viewer is proxied",
+			role:        roles.ViewerRoleName,
+			query:       `SELECT mean(usage_user) FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`,
+			wantStatus:  http.StatusOK,
+			wantCommand: `SELECT mean(usage_user) FROM cpu WHERE time > now() - 1h GROUP BY time(1m)`,
+			wantBody: `{"results":{"results":[]},"uuid":"bob"}
+`,
+		},
+		{
+			name:        "unbounded raw query of a viewer is rewritten",
+			role:        roles.ViewerRoleName,
+			query:       `SELECT * FROM cpu`,
+			wantStatus:  http.StatusOK,
+			wantCommand: `SELECT * FROM cpu WHERE time > now() - 1h LIMIT 10000`,
+			wantBody: `{"results":{"results":[]},"uuid":"bob","query":"SELECT * FROM cpu WHERE time \u003e now() - 1h LIMIT 10000"}
+`,
+		},
+		{
+			name:        "unbounded raw query without authentication is proxied",
+			query:       `SELECT * FROM cpu`,
+			wantStatus:  http.StatusOK,
+			wantCommand: `SELECT * FROM cpu`,
+			wantBody: `{"results":{"results":[]},"uuid":"bob"}
+`,
+		},
+		{
+			name:        "unbounded raw query of an admin is proxied",
+			role:        roles.AdminRoleName,
+			query:       `SELECT * FROM cpu`,
+			wantStatus:  http.StatusOK,
+			wantCommand: `SELECT * FROM cpu`,
+			wantBody: `{"results":{"results":[]},"uuid":"bob"}
+`,
+		},
+		{
+			name:        "subquery of a viewer is proxied",
+			role:        roles.ViewerRoleName,
+			query:       `SELECT max(x) FROM (SELECT * FROM cpu) WHERE time > now() - 1000d`,
+			wantStatus:  http.StatusOK,
+			wantCommand: `SELECT max(x) FROM (SELECT * FROM cpu) WHERE time > now() - 1000d`,
+			wantBody: `{"results":{"results":[]},"uuid":"bob"}
+`,
+		},
+		{
+			name:       "DDL of a viewer is forbidden",
+			role:       roles.ViewerRoleName,
+			query:      `DROP MEASUREMENT cpu`,
+			wantStatus: http.StatusForbidden,
+			wantBody:   `{"code":403,"message":"DROP MEASUREMENT statements are not allowed to your role, which may only run SELECT and SHOW statements reading data"}`,
+		},
+		{
+			name:        "DDL of an editor is proxied",
+			role:        roles.EditorRoleName,
+			query:       `DROP MEASUREMENT cpu`,
+			wantStatus:  http.StatusOK,
+			wantCommand: `DROP MEASUREMENT cpu`,
+			wantBody: `{"results":{"results":[]},"uuid":"bob"}
+`,
+		},
+		{
+			name:       "time range above the range of the role",
+			role:       roles.ViewerRoleName,
+			query:      `SELECT mean(usage_user) FROM cpu WHERE time > now() - 365d GROUP BY time(1d)`,
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   `{"code":422,"message":"The time range of the query, 365d, exceeds the 90d allowed to your role. Narrow it, e.g. WHERE time \u003e now() - 90d"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var command string
+			s := &Service{
+				Store: &mocks.Store{
+					SourcesStore: &mocks.SourcesStore{
+						GetF: func(ctx context.Context, ID int) (cloudhub.Source, error) {
+							return cloudhub.Source{ID: ID}, nil
+						},
+					},
+				},
+				TimeSeriesClient: &mocks.TimeSeries{
+					ConnectF: func(ctx context.Context, src *cloudhub.Source) error {
+						return nil
+					},
+					QueryF: func(ctx context.Context, q cloudhub.Query) (cloudhub.Response, error) {
+						command = q.Command
+						return mocks.NewResponse(`{"results":[]}`, nil), nil
+					},
+				},
+				Logger: mocks.NewLogger(),
+			}
+
+			body, _ := json.Marshal(cloudhub.Query{Command: tt.query, UUID: "bob"})
+			r := httptest.NewRequest("POST", "http://any.url", bytes.NewReader(body))
+			ctx := httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}})
+			r = r.WithContext(context.WithValue(ctx, roles.ContextKey, tt.role))
+			w := httptest.NewRecorder()
+			s.Influx(w, r)
+
+			resp := w.Result()
+			got, _ := ioutil.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Influx() status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if string(got) != tt.wantBody {
+				t.Errorf("Influx() body = %s, want %s", got, tt.wantBody)
+			}
+			if command != tt.wantCommand {
+				t.Errorf("Influx() proxied %q, want %q", command, tt.wantCommand)
+			}
+		})
+	}
+}
+
+func TestQueryPolicies(t *testing.T) {
+	policies, err := QueryPolicies(map[string]map[string]string{
+		roles.ViewerRoleName: {"max-range": "720h", "max-points": "0", "timeout": "10s"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	viewer := policies[roles.ViewerRoleName]
+	if viewer.MaxRange != 720*time.Hour || viewer.MaxPoints != 0 || viewer.Timeout != 10*time.Second ||
+		!viewer.ReadOnly || viewer.DefaultRange != time.Hour || viewer.MaxSeries != 1000 {
+		t.Errorf("QueryPolicies() viewer = %#v", viewer)
+	}
+	if policies[roles.EditorRoleName] != defaultQueryPolicies[roles.EditorRoleName] {
+		t.Errorf("QueryPolicies() editor = %#v, want the default", policies[roles.EditorRoleName])
+	}
+
+	if _, err := QueryPolicies(map[string]map[string]string{roles.EditorRoleName: {"max-rows": "10"}}); err == nil {
+		t.Error("QueryPolicies() must reject unknown options")
+	}
+	if _, err := QueryPolicies(map[string]map[string]string{roles.EditorRoleName: {"timeout": "soon"}}); err == nil {
+		t.Error("QueryPolicies() must reject invalid durations")
+	}
+}
+
+// protoboardVariables are the values the UI gives to the template variables
+// of the protoboards
+var protoboardVariables = strings.NewReplacer(
+	":db:", "telegraf",
+	":rp:", "autogen",
+	":dashboardTime:", "now() - 1h",
+	":upperDashboardTime:", "now()",
+	":interval:", "1m",
+)
+
+func TestQueryPolicy_Protoboards(t *testing.T) {
+	pbs, err := (&protoboards.BinProtoboardsStore{Logger: mocks.NewLogger()}).All(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := defaultQueryPolicies[roles.ViewerRoleName]
+	otherVariables := regexp.MustCompile(`:[a-zA-Z0-9_]+:`)
+	for _, pb := range pbs {
+		for _, cell := range pb.Data.Cells {
+			for _, q := range cell.Queries {
+				if q.Command == "" {
+					continue
+				}
+				replaced := otherVariables.ReplaceAllString(protoboardVariables.Replace(q.Command), "x")
+				for _, command := range []string{q.Command, replaced} {
+					if _, _, err := policy.Apply(command, time.Now()); err != nil {
+						t.Errorf("query of cell %q of protoboard %s is rejected for viewers: %v\n%s", cell.Name, pb.Meta.Name, err, command)
+					}
+				}
+			}
+		}
+	}
+}
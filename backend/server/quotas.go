@@ -0,0 +1,313 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// Resources limited by the quotas of an organization
+const (
+	quotaDashboards        = "dashboards"
+	quotaCellsPerDashboard = "cellsPerDashboard"
+	quotaSources           = "sources"
+	quotaNetworkDevices    = "networkDevices"
+	quotaLearnedDevices    = "learnedDevices"
+	quotaTopologies        = "topologies"
+	quotaTerminalSessions  = "terminalSessions"
+)
+
+var quotaResources = []string{
+	quotaDashboards,
+	quotaCellsPerDashboard,
+	quotaSources,
+	quotaNetworkDevices,
+	quotaLearnedDevices,
+	quotaTopologies,
+	quotaTerminalSessions,
+}
+
+// quotaLimit returns the limit of resource in q; zero is unlimited
+func quotaLimit(q *cloudhub.OrganizationQuotas, resource string) int {
+	if q == nil {
+		return 0
+	}
+	switch resource {
+	case quotaDashboards:
+		return q.Dashboards
+	case quotaCellsPerDashboard:
+		return q.CellsPerDashboard
+	case quotaSources:
+		return q.Sources
+	case quotaNetworkDevices:
+		return q.NetworkDevices
+	case quotaLearnedDevices:
+		return q.LearnedDevices
+	case quotaTopologies:
+		return q.Topologies
+	case quotaTerminalSessions:
+		return q.TerminalSessions
+	}
+	return 0
+}
+
+func validQuotas(q *cloudhub.OrganizationQuotas) error {
+	for _, resource := range quotaResources {
+		if quotaLimit(q, resource) < 0 {
+			return fmt.Errorf("quota of %s must not be negative", resource)
+		}
+	}
+	return nil
+}
+
+// quotaError is returned when a request would exceed a quota of an organization
+type quotaError struct {
+	Organization string
+	Resource     string
+	Limit        int
+}
+
+func (e *quotaError) Error() string {
+	return fmt.Sprintf("Quota exceeded: organization %s allows at most %d %s", e.Organization, e.Limit, e.Resource)
+}
+
+// quotaExceeded responds to a request exceeding a quota.
+// Concurrent terminal sessions are answered with 429 as they free up over time,
+// stored resources with 403.
+func quotaExceeded(w http.ResponseWriter, err error, logger cloudhub.Logger) {
+	qe, ok := err.(*quotaError)
+	if !ok {
+		unknownErrorWithMessage(w, err, logger)
+		return
+	}
+	code := http.StatusForbidden
+	if qe.Resource == quotaTerminalSessions {
+		code = http.StatusTooManyRequests
+	}
+	Error(w, code, qe.Error(), logger)
+}
+
+// orgQuotas returns the quotas of the organization org, nil when it is unlimited.
+// Requests naming an unknown organization are left to the validation of the handlers.
+func (s *Service) orgQuotas(ctx context.Context, org string) *cloudhub.OrganizationQuotas {
+	ctx = serverContext(ctx)
+	o, err := s.Store.Organizations(ctx).Get(ctx, cloudhub.OrganizationQuery{ID: &org})
+	if err != nil {
+		return nil
+	}
+	return o.Quotas
+}
+
+// checkQuota returns a *quotaError if n more of resource would exceed the quota of org.
+func (s *Service) checkQuota(ctx context.Context, org, resource string, n int) error {
+	limit := quotaLimit(s.orgQuotas(ctx, org), resource)
+	if limit == 0 || n == 0 {
+		return nil
+	}
+	used, err := s.quotaUsage(ctx, org, resource)
+	if err != nil {
+		return err
+	}
+	if used+n > limit {
+		return &quotaError{Organization: org, Resource: resource, Limit: limit}
+	}
+	return nil
+}
+
+// checkDashboardCells returns a *quotaError if a dashboard of org with the
+// given number of cells would exceed the cells per dashboard quota.
+func (s *Service) checkDashboardCells(ctx context.Context, org string, cells int) error {
+	if limit := quotaLimit(s.orgQuotas(ctx, org), quotaCellsPerDashboard); limit != 0 && cells > limit {
+		return &quotaError{Organization: org, Resource: quotaCellsPerDashboard, Limit: limit}
+	}
+	return nil
+}
+
+// checkLearnedDevices returns a *quotaError if learning the devices ids, the
+// complete list of the learned devices of org, would exceed its quota.
+func (s *Service) checkLearnedDevices(ctx context.Context, org string, ids []string) error {
+	if limit := quotaLimit(s.orgQuotas(ctx, org), quotaLearnedDevices); limit != 0 && len(ids) > limit {
+		return &quotaError{Organization: org, Resource: quotaLearnedDevices, Limit: limit}
+	}
+	return nil
+}
+
+// quotaUsage returns how much of resource org consumes.
+// The usage of cells per dashboard is the number of cells of its largest dashboard.
+func (s *Service) quotaUsage(ctx context.Context, org, resource string) (int, error) {
+	ctx = serverContext(ctx)
+	switch resource {
+	case quotaDashboards, quotaCellsPerDashboard:
+		dashboards, err := s.Store.Dashboards(ctx).All(ctx)
+		if err != nil {
+			return 0, err
+		}
+		used := 0
+		for _, d := range dashboards {
+			if d.Organization != org {
+				continue
+			}
+			if resource == quotaDashboards {
+				used++
+			} else if len(d.Cells) > used {
+				used = len(d.Cells)
+			}
+		}
+		return used, nil
+	case quotaSources:
+		srcs, err := s.Store.Sources(ctx).All(ctx)
+		if err != nil {
+			return 0, err
+		}
+		used := 0
+		for _, src := range srcs {
+			if src.Organization == org {
+				used++
+			}
+		}
+		return used, nil
+	case quotaNetworkDevices:
+		devices, err := s.Store.NetworkDevice(ctx).Find(ctx, cloudhub.NetworkDeviceQuery{Organization: &org})
+		if err != nil {
+			return 0, err
+		}
+		return len(devices), nil
+	case quotaLearnedDevices:
+		deviceOrg, err := s.Store.NetworkDeviceOrg(ctx).Get(ctx, cloudhub.NetworkDeviceOrgQuery{ID: &org})
+		if err != nil {
+			return 0, nil
+		}
+		return len(deviceOrg.LearnedDevicesIDs), nil
+	case quotaTopologies:
+		topologies, err := s.Store.Topologies(ctx).All(ctx)
+		if err != nil {
+			return 0, err
+		}
+		used := 0
+		for _, tp := range topologies {
+			if tp.Organization == org {
+				used++
+			}
+		}
+		return used, nil
+	case quotaTerminalSessions:
+		return terminalSessions.count(org), nil
+	}
+	return 0, fmt.Errorf("unknown quota resource %q", resource)
+}
+
+// checkDeviceQuotas returns a *quotaError if registering the devices of reqs
+// that are not registered yet would exceed the network device quota of their organization.
+func (s *Service) checkDeviceQuotas(ctx context.Context, reqs []createDeviceRequest) error {
+	added := map[string]int{}
+	for _, req := range reqs {
+		if req.DeviceIP == "" {
+			continue
+		}
+		existing, err := s.Store.NetworkDevice(ctx).Find(ctx, cloudhub.NetworkDeviceQuery{DeviceIP: &req.DeviceIP})
+		if err != nil {
+			return err
+		}
+		if len(existing) == 0 {
+			added[req.Organization]++
+		}
+	}
+	for org, n := range added {
+		if err := s.checkQuota(ctx, org, quotaNetworkDevices, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sessionCounter counts the concurrent sessions of each organization
+type sessionCounter struct {
+	mu       sync.Mutex
+	sessions map[string]int
+}
+
+var terminalSessions = &sessionCounter{sessions: map[string]int{}}
+
+// acquire starts a session of org unless it already has limit sessions; zero is unlimited
+func (c *sessionCounter) acquire(org string, limit int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if limit != 0 && c.sessions[org] >= limit {
+		return false
+	}
+	c.sessions[org]++
+	return true
+}
+
+func (c *sessionCounter) release(org string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sessions[org]--; c.sessions[org] <= 0 {
+		delete(c.sessions, org)
+	}
+}
+
+func (c *sessionCounter) count(org string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessions[org]
+}
+
+type quotaUsageResponse struct {
+	Resource string `json:"resource"`
+	Limit    int    `json:"limit"`
+	Used     int    `json:"used"`
+}
+
+type orgUsageResponse struct {
+	Organization string               `json:"organization"`
+	Usage        []quotaUsageResponse `json:"usage"`
+	Links        selfLinks            `json:"links"`
+}
+
+// OrganizationUsage returns the consumption of the resources of an organization
+// along with their quotas. A limit of zero is unlimited.
+func (s *Service) OrganizationUsage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := httprouter.GetParamFromContext(ctx, "oid")
+	if id == "" {
+		// the usage of the current organization
+		var ok bool
+		if id, ok = hasOrganizationContext(ctx); !ok {
+			Error(w, http.StatusBadRequest, cloudhub.ErrOrganizationNotFound.Error(), s.Logger)
+			return
+		}
+	}
+
+	sctx := serverContext(ctx)
+	org, err := s.Store.Organizations(sctx).Get(sctx, cloudhub.OrganizationQuery{ID: &id})
+	if err != nil {
+		Error(w, http.StatusNotFound, err.Error(), s.Logger)
+		return
+	}
+
+	res := &orgUsageResponse{
+		Organization: org.ID,
+		Usage:        []quotaUsageResponse{},
+		Links: selfLinks{
+			Self: fmt.Sprintf("/cloudhub/v1/organizations/%s/usage", org.ID),
+		},
+	}
+	for _, resource := range quotaResources {
+		used, err := s.quotaUsage(ctx, org.ID, resource)
+		if err != nil {
+			unknownErrorWithMessage(w, err, s.Logger)
+			return
+		}
+		res.Usage = append(res.Usage, quotaUsageResponse{
+			Resource: resource,
+			Limit:    quotaLimit(org.Quotas, resource),
+			Used:     used,
+		})
+	}
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func TestService_checkQuota(t *testing.T) {
+	s := &Service{
+		Store: &mocks.Store{
+			OrganizationsStore: &mocks.OrganizationsStore{
+				GetF: func(ctx context.Context, q cloudhub.OrganizationQuery) (*cloudhub.Organization, error) {
+					if *q.ID != "1" {
+						return nil, cloudhub.ErrOrganizationNotFound
+					}
+					return &cloudhub.Organization{
+						ID:     "1",
+						Quotas: &cloudhub.OrganizationQuotas{Dashboards: 2, CellsPerDashboard: 3, LearnedDevices: 2},
+					}, nil
+				},
+			},
+			DashboardsStore: &mocks.DashboardsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Dashboard, error) {
+					return []cloudhub.Dashboard{
+						{ID: 1, Organization: "1"},
+						{ID: 2, Organization: "default"},
+					}, nil
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+	ctx := context.Background()
+
+	if err := s.checkQuota(ctx, "1", quotaDashboards, 1); err != nil {
+		t.Errorf("checkQuota() of the second dashboard error = %v", err)
+	}
+	err := s.checkQuota(ctx, "1", quotaDashboards, 2)
+	if qe, ok := err.(*quotaError); !ok || qe.Limit != 2 || qe.Error() != "Quota exceeded: organization 1 allows at most 2 dashboards" {
+		t.Errorf("checkQuota() of the third dashboard error = %v, want a quota error", err)
+	}
+	if err := s.checkQuota(ctx, "default", quotaDashboards, 10); err != nil {
+		t.Errorf("checkQuota() of an organization without quotas error = %v", err)
+	}
+	if err := s.checkDashboardCells(ctx, "1", 4); err == nil {
+		t.Errorf("checkDashboardCells() of 4 cells expected a quota error")
+	}
+	if err := s.checkLearnedDevices(ctx, "1", []string{"1", "2"}); err != nil {
+		t.Errorf("checkLearnedDevices() of 2 devices error = %v", err)
+	}
+	if err := s.checkLearnedDevices(ctx, "1", []string{"1", "2", "3"}); err == nil {
+		t.Errorf("checkLearnedDevices() of 3 devices expected a quota error")
+	}
+}
+
+func Test_sessionCounter(t *testing.T) {
+	c := &sessionCounter{sessions: map[string]int{}}
+	if !c.acquire("1", 1) {
+		t.Fatal("acquire() of the first session failed")
+	}
+	if c.acquire("1", 1) {
+		t.Error("acquire() of a session beyond the limit succeeded")
+	}
+	if !c.acquire("2", 1) || !c.acquire("1", 0) {
+		t.Error("acquire() must only limit the sessions of the same organization")
+	}
+	c.release("1")
+	c.release("1")
+	if got := c.count("1"); got != 0 {
+		t.Errorf("count() after release = %d, want 0", got)
+	}
+}
+
+func Test_quotaExceeded(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{&quotaError{Organization: "1", Resource: quotaDashboards, Limit: 1}, http.StatusForbidden},
+		{&quotaError{Organization: "1", Resource: quotaTerminalSessions, Limit: 1}, http.StatusTooManyRequests},
+		{cloudhub.ErrDashboardNotFound, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		quotaExceeded(w, tt.err, &mocks.TestLogger{})
+		if w.Code != tt.want {
+			t.Errorf("quotaExceeded(%v) status = %d, want %d", tt.err, w.Code, tt.want)
+		}
+	}
+}
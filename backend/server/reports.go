@@ -0,0 +1,577 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/organizations"
+	"github.com/snetsystems/cloudhub/backend/render"
+	"github.com/snetsystems/cloudhub/backend/reports"
+)
+
+// Size of the images of the cells of reports
+const (
+	reportCellWidth  = 800
+	reportCellHeight = 360
+)
+
+// defaultReportTimeRange is the time range of reports which do not set one
+const defaultReportTimeRange = "24h"
+
+// reportsInterval is how often the schedules of the reports are checked
+var reportsInterval = time.Minute
+
+// reportWebhookClient posts reports to webhooks
+var reportWebhookClient = &http.Client{Timeout: 30 * time.Second}
+
+type reportRequest struct {
+	Name       string               `json:"name"`
+	Dashboard  cloudhub.DashboardID `json:"dashboard"`
+	Variables  map[string]string    `json:"variables"`
+	TimeRange  string               `json:"timeRange"`
+	Schedule   string               `json:"schedule"`
+	TimeZone   string               `json:"timeZone"`
+	Format     string               `json:"format"`
+	Recipients []string             `json:"recipients"`
+	Webhook    string               `json:"webhook"`
+}
+
+type reportLinks struct {
+	Self string `json:"self"` // Self link mapping to this resource
+	Send string `json:"send"` // Send link to run the report now
+	Runs string `json:"runs"` // Runs link to the history of the runs of the report
+}
+
+type reportResponse struct {
+	cloudhub.Report
+	Links reportLinks `json:"links"`
+}
+
+type reportsResponse struct {
+	Links   selfLinks        `json:"links"`
+	Reports []reportResponse `json:"reports"`
+}
+
+type reportRunsResponse struct {
+	Runs []cloudhub.ReportRun `json:"runs"`
+}
+
+func newReportResponse(r cloudhub.Report) reportResponse {
+	if r.Variables == nil {
+		r.Variables = map[string]string{}
+	}
+	if r.Recipients == nil {
+		r.Recipients = []string{}
+	}
+	if r.Runs == nil {
+		r.Runs = []cloudhub.ReportRun{}
+	}
+	self := fmt.Sprintf("/cloudhub/v1/reports/%s", r.ID)
+	return reportResponse{
+		Report: r,
+		Links: reportLinks{
+			Self: self,
+			Send: self + "/send",
+			Runs: self + "/runs",
+		},
+	}
+}
+
+// validReport checks the request, sets its defaults and returns the report it describes
+func (s *Service) validReport(ctx context.Context, req *reportRequest) (*cloudhub.Report, error) {
+	if strings.TrimSpace(req.Name) == "" {
+		return nil, fmt.Errorf("Name required on CloudHub Report request body")
+	}
+	if _, err := s.Store.Dashboards(ctx).Get(ctx, req.Dashboard); err != nil {
+		return nil, fmt.Errorf("Unknown dashboard %d", req.Dashboard)
+	}
+
+	if req.TimeRange == "" {
+		req.TimeRange = defaultReportTimeRange
+	}
+	if d, err := time.ParseDuration(req.TimeRange); err != nil || d <= 0 {
+		return nil, fmt.Errorf("timeRange must be a positive duration, e.g. 24h")
+	}
+	if req.TimeZone == "" {
+		req.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.TimeZone); err != nil {
+		return nil, fmt.Errorf("Unknown time zone %s", req.TimeZone)
+	}
+	if _, err := reports.ParseSchedule(req.Schedule); err != nil {
+		return nil, fmt.Errorf("Invalid schedule: %v", err)
+	}
+
+	switch req.Format {
+	case "":
+		req.Format = cloudhub.ReportPDF
+	case cloudhub.ReportHTML, cloudhub.ReportPDF:
+	default:
+		return nil, fmt.Errorf("format must be %s or %s", cloudhub.ReportHTML, cloudhub.ReportPDF)
+	}
+
+	if len(req.Recipients) == 0 && req.Webhook == "" {
+		return nil, fmt.Errorf("At least one recipient or a webhook is required to deliver the report")
+	}
+	for _, to := range req.Recipients {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return nil, fmt.Errorf("Invalid recipient %s", to)
+		}
+	}
+	if len(req.Recipients) > 0 && !s.Mailer.Enabled() {
+		return nil, fmt.Errorf("No SMTP server is configured to send reports by e-mail")
+	}
+	if req.Webhook != "" {
+		u, err := url.Parse(req.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("webhook must be an http or https URL")
+		}
+	}
+
+	return &cloudhub.Report{
+		Name:       req.Name,
+		Dashboard:  req.Dashboard,
+		Variables:  req.Variables,
+		TimeRange:  req.TimeRange,
+		Schedule:   req.Schedule,
+		TimeZone:   req.TimeZone,
+		Format:     req.Format,
+		Recipients: req.Recipients,
+		Webhook:    req.Webhook,
+	}, nil
+}
+
+// nextReportRun returns the first time of the schedule of the report after now,
+// in the time zone of the report
+func nextReportRun(r *cloudhub.Report, now time.Time) time.Time {
+	schedule, err := reports.ParseSchedule(r.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+	loc, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	next := schedule.Next(now.In(loc))
+	if next.IsZero() {
+		return next
+	}
+	return next.UTC()
+}
+
+// Reports returns all reports within the store.
+func (s *Service) Reports(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	rs, err := s.Store.Reports(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading reports", s.Logger)
+		return
+	}
+
+	res := reportsResponse{
+		Links: selfLinks{
+			Self: "/cloudhub/v1/reports",
+		},
+		Reports: []reportResponse{},
+	}
+	for _, report := range rs {
+		res.Reports = append(res.Reports, newReportResponse(report))
+	}
+
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// ReportID returns a single specified report
+func (s *Service) ReportID(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	report, err := s.Store.Reports(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	encodeJSON(w, http.StatusOK, newReportResponse(*report), s.Logger)
+}
+
+// NewReport adds a new report of a dashboard to the organization
+func (s *Service) NewReport(w http.ResponseWriter, r *http.Request) {
+	var req reportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	report, err := s.validReport(ctx, &req)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	if user, ok := hasUserContext(ctx); ok {
+		report.CreatedBy = user.Name
+	}
+	report.NextRun = nextReportRun(report, time.Now())
+
+	res, err := s.Store.Reports(ctx).Add(ctx, report)
+	if err != nil {
+		msg := fmt.Errorf("Error storing report %v: %v", req, err)
+		unknownErrorWithMessage(w, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgReportCreated.String(), res.Name)
+	s.logRegistration(ctx, "Reports", msg)
+
+	rr := newReportResponse(*res)
+	location(w, rr.Links.Self)
+	encodeJSON(w, http.StatusCreated, rr, s.Logger)
+}
+
+// UpdateReport replaces the definition of a report, keeping its history
+func (s *Service) UpdateReport(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	orig, err := s.Store.Reports(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	var req reportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+	report, err := s.validReport(ctx, &req)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	report.ID = orig.ID
+	report.Organization = orig.Organization
+	report.CreatedBy = orig.CreatedBy
+	report.CreatedAt = orig.CreatedAt
+	report.Runs = orig.Runs
+	report.NextRun = nextReportRun(report, time.Now())
+
+	if err := s.Store.Reports(ctx).Update(ctx, report); err != nil {
+		msg := fmt.Sprintf("Error updating report ID %s: %v", id, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgReportModified.String(), report.Name)
+	s.logRegistration(ctx, "Reports", msg)
+
+	encodeJSON(w, http.StatusOK, newReportResponse(*report), s.Logger)
+}
+
+// RemoveReport deletes a report
+func (s *Service) RemoveReport(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	report, err := s.Store.Reports(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	if err := s.Store.Reports(ctx).Delete(ctx, report); err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgReportDeleted.String(), report.Name)
+	s.logRegistration(ctx, "Reports", msg)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ReportRuns returns the history of the runs of a report, newest first
+func (s *Service) ReportRuns(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	report, err := s.Store.Reports(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	res := reportRunsResponse{
+		Runs: []cloudhub.ReportRun{},
+	}
+	for i := len(report.Runs) - 1; i >= 0; i-- {
+		res.Runs = append(res.Runs, report.Runs[i])
+	}
+
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// SendReport runs a report now, outside of its schedule, and returns the run.
+// A run which fails to be delivered is answered with 502.
+func (s *Service) SendReport(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	report, err := s.Store.Reports(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	run := s.runReport(ctx, report, cloudhub.ReportTriggerManual, time.Now())
+	if err := s.Store.Reports(ctx).Update(ctx, report); err != nil {
+		msg := fmt.Sprintf("Error updating report ID %s: %v", id, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgReportSent.String(), report.Name, run.Status)
+	s.logRegistration(ctx, "Reports", msg)
+
+	status := http.StatusOK
+	if run.Status == cloudhub.JobFailed {
+		status = http.StatusBadGateway
+	}
+	encodeJSON(w, status, run, s.Logger)
+}
+
+// runReport renders and delivers the report and records the run in its history.
+// ctx must be a context of the organization of the report. The report is not stored.
+func (s *Service) runReport(ctx context.Context, report *cloudhub.Report, trigger string, now time.Time) cloudhub.ReportRun {
+	run := cloudhub.ReportRun{
+		Time:    now.UTC(),
+		Trigger: trigger,
+		Status:  cloudhub.JobSucceeded,
+	}
+	if err := s.deliverReport(ctx, report, now); err != nil {
+		run.Status = cloudhub.JobFailed
+		run.Error = err.Error()
+	}
+
+	addReportRun(report, run)
+	return run
+}
+
+// addReportRun appends the run to the history of the report, which keeps the last
+// MaxReportRuns runs
+func addReportRun(report *cloudhub.Report, run cloudhub.ReportRun) {
+	report.Runs = append(report.Runs, run)
+	if len(report.Runs) > cloudhub.MaxReportRuns {
+		report.Runs = report.Runs[len(report.Runs)-cloudhub.MaxReportRuns:]
+	}
+}
+
+// deliverReport renders the report over its time range before now and sends it
+// to its recipients and to its webhook
+func (s *Service) deliverReport(ctx context.Context, report *cloudhub.Report, now time.Time) error {
+	doc, err := s.reportDocument(ctx, report, now)
+	if err != nil {
+		return err
+	}
+
+	a := reports.Attachment{Name: reportFileName(report.Name, doc.Upper.In(doc.Location))}
+	if report.Format == cloudhub.ReportHTML {
+		a.Name += ".html"
+		a.ContentType = "text/html; charset=utf-8"
+		a.Content, err = doc.HTML()
+	} else {
+		a.Name += ".pdf"
+		a.ContentType = "application/pdf"
+		a.Content, err = doc.PDF()
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to assemble the report: %v", err)
+	}
+
+	var errs []string
+	if len(report.Recipients) > 0 {
+		subject := fmt.Sprintf("CloudHub report: %s", report.Name)
+		body := fmt.Sprintf("%s\n%s\n%s\n", report.Name, doc.Title, doc.Range())
+		if err := s.Mailer.Send(report.Recipients, subject, body, a); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if report.Webhook != "" {
+		if err := reports.Post(ctx, reportWebhookClient, report.Webhook, report.Name, a); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// reportDocument renders the cells of the dashboard of the report, sorted by their
+// position. The cells which are not rendered are noted in the document.
+func (s *Service) reportDocument(ctx context.Context, report *cloudhub.Report, now time.Time) (*reports.Document, error) {
+	dash, err := s.Store.Dashboards(ctx).Get(ctx, report.Dashboard)
+	if err != nil {
+		return nil, fmt.Errorf("Unknown dashboard %d", report.Dashboard)
+	}
+	d, err := time.ParseDuration(report.TimeRange)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid time range %s", report.TimeRange)
+	}
+	loc, err := time.LoadLocation(report.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	opts := render.Options{
+		Width:    reportCellWidth,
+		Height:   reportCellHeight,
+		Lower:    now.Add(-d),
+		Upper:    now,
+		Location: loc,
+	}
+	doc := &reports.Document{
+		Title:    dash.Name,
+		Lower:    opts.Lower,
+		Upper:    opts.Upper,
+		Location: loc,
+		Sections: []reports.Section{},
+	}
+
+	cells := append([]cloudhub.DashboardCell(nil), dash.Cells...)
+	sort.SliceStable(cells, func(i, j int) bool {
+		if cells[i].Y != cells[j].Y {
+			return cells[i].Y < cells[j].Y
+		}
+		return cells[i].X < cells[j].X
+	})
+	templates, err := s.resolveTemplates(ctx, dash.Templates, report.Variables, "", opts.Lower, opts.Upper)
+	if err != nil {
+		return nil, err
+	}
+	for i := range cells {
+		cell := &cells[i]
+		section := reports.Section{Name: cell.Name}
+		if section.Name == "" {
+			section.Name = "Untitled Cell"
+		}
+		if err := s.renderReportCell(ctx, cell, templates, opts, &section); err != nil {
+			section.Note = err.Error()
+		}
+		doc.Sections = append(doc.Sections, section)
+	}
+	return doc, nil
+}
+
+// renderReportCell renders the image of a cell of a report into the section
+func (s *Service) renderReportCell(ctx context.Context, cell *cloudhub.DashboardCell, templates []cloudhub.Template, opts render.Options, section *reports.Section) error {
+	if !render.Supported(cell.Type) {
+		return fmt.Errorf("Cells of type %s are not rendered", cell.Type)
+	}
+	results, err := s.cellResults(ctx, cell, templates, opts)
+	if err != nil {
+		return err
+	}
+	chart, err := render.Render(*cell, results, opts)
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	if err := chart.PNG(&b); err != nil {
+		return err
+	}
+	section.PNG = b.Bytes()
+	return nil
+}
+
+var unsafeFileName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// reportFileName returns the name, without extension, of the file of a report at a time
+func reportFileName(name string, t time.Time) string {
+	name = strings.Trim(unsafeFileName.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "report"
+	}
+	return name + "-" + t.Format("20060102-1504")
+}
+
+// runScheduledReports runs the reports whose next run is due every reportsInterval
+// until ctx is done
+func (s *Service) runScheduledReports(ctx context.Context) {
+	l := s.Logger.WithField("component", "reports")
+	serverCtx := serverContext(ctx)
+
+	ticker := time.NewTicker(reportsInterval)
+	defer ticker.Stop()
+	for {
+		rs, err := s.Store.Reports(serverCtx).All(serverCtx)
+		if err != nil {
+			l.Error("Unable to load reports: ", err)
+		}
+		now := time.Now()
+		for i := range rs {
+			report := &rs[i]
+			if report.NextRun.IsZero() || report.NextRun.After(now) {
+				continue
+			}
+
+			orgCtx := context.WithValue(ctx, organizations.ContextKey, report.Organization)
+			run := s.runReport(orgCtx, report, cloudhub.ReportTriggerSchedule, now)
+			if run.Status == cloudhub.JobFailed {
+				l.Error("Report ", report.Name, " of organization ", report.Organization, " failed: ", run.Error)
+			}
+
+			// the report may have been modified or run by others while it ran
+			current, err := s.Store.Reports(serverCtx).Get(serverCtx, report.ID)
+			if err != nil {
+				continue
+			}
+			addReportRun(current, run)
+			current.NextRun = nextReportRun(current, now)
+			if err := s.Store.Reports(serverCtx).Update(serverCtx, current); err != nil {
+				l.Error("Unable to update report ", report.Name, ": ", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/reports"
+)
+
+func TestService_NewReport(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		mailer     *reports.Mailer
+		wantStatus int
+		wantNext   time.Time
+	}{
+		{
+			name:       "report to a webhook",
+			body:       `{"name": "CPU", "dashboard": 1, "schedule": "0 8 * * MON", "timeZone": "Asia/Seoul", "webhook": "https://example.com/hook"}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "report by e-mail",
+			body:       `{"name": "CPU", "dashboard": 1, "schedule": "@daily", "format": "html", "recipients": ["ops@example.com"]}`,
+			mailer:     &reports.Mailer{Host: "localhost", Port: 25, From: "cloudhub@example.com"},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "e-mail without SMTP server",
+			body:       `{"name": "CPU", "dashboard": 1, "schedule": "@daily", "recipients": ["ops@example.com"]}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unknown dashboard",
+			body:       `{"name": "CPU", "dashboard": 2, "schedule": "@daily", "webhook": "https://example.com/hook"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid schedule",
+			body:       `{"name": "CPU", "dashboard": 1, "schedule": "every monday", "webhook": "https://example.com/hook"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "no delivery",
+			body:       `{"name": "CPU", "dashboard": 1, "schedule": "@daily"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid webhook",
+			body:       `{"name": "CPU", "dashboard": 1, "schedule": "@daily", "webhook": "ftp://example.com"}`,
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var added *cloudhub.Report
+			s := &Service{
+				Store: &mocks.Store{
+					DashboardsStore: &mocks.DashboardsStore{
+						GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+							if id != 1 {
+								return cloudhub.Dashboard{}, cloudhub.ErrDashboardNotFound
+							}
+							return cloudhub.Dashboard{ID: 1}, nil
+						},
+					},
+					ReportsStore: &mocks.ReportsStore{
+						AddF: func(ctx context.Context, r *cloudhub.Report) (*cloudhub.Report, error) {
+							r.ID = "1"
+							added = r
+							return r, nil
+						},
+					},
+					SourcesStore: &mocks.SourcesStore{
+						GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+							return cloudhub.Source{}, cloudhub.ErrSourceNotFound
+						},
+					},
+				},
+				Mailer: tt.mailer,
+				Logger: &mocks.TestLogger{},
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "http://any.url/cloudhub/v1/reports", strings.NewReader(tt.body))
+			s.NewReport(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("NewReport() status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+			if got := w.Header().Get("Location"); got != "/cloudhub/v1/reports/1" {
+				t.Errorf("NewReport() location = %s", got)
+			}
+			if added.TimeRange != defaultReportTimeRange || added.NextRun.IsZero() || added.NextRun.Location() != time.UTC {
+				t.Errorf("NewReport() added %#v", added)
+			}
+		})
+	}
+}
+
+func TestService_SendReport(t *testing.T) {
+	var posted []byte
+	var contentType string
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		posted, _ = ioutil.ReadAll(r.Body)
+		contentType = r.Header.Get("Content-Type")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer hook.Close()
+
+	dashboard := cloudhub.Dashboard{
+		ID:   1,
+		Name: "Servers",
+		Cells: []cloudhub.DashboardCell{
+			{ID: "c2", Y: 4, Name: "Notes", Type: "note"},
+			{
+				ID:   "c1",
+				Name: "CPU",
+				Type: "line",
+				Queries: []cloudhub.DashboardQuery{
+					{Command: `SELECT mean("usage_user") FROM "cpu" WHERE "host" = :host: AND time > :dashboardTime: GROUP BY time(:interval:)`, Type: "influxql"},
+				},
+			},
+		},
+		Templates: []cloudhub.Template{
+			{TemplateVar: cloudhub.TemplateVar{Var: ":host:", Values: []cloudhub.TemplateValue{{Value: "web01", Type: "tagValue", Selected: true}}}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		webhook    string
+		format     string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "pdf to a webhook",
+			webhook:    hook.URL + "/ok",
+			format:     cloudhub.ReportPDF,
+			wantStatus: http.StatusOK,
+			want:       "%PDF-1.4",
+		},
+		{
+			name:       "html to a webhook",
+			webhook:    hook.URL + "/ok",
+			format:     cloudhub.ReportHTML,
+			wantStatus: http.StatusOK,
+			want:       "Cells of type note are not rendered",
+		},
+		{
+			name:       "webhook failing",
+			webhook:    hook.URL + "/fail",
+			format:     cloudhub.ReportHTML,
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var command string
+			report := &cloudhub.Report{
+				ID:        "1",
+				Name:      "Servers",
+				Dashboard: 1,
+				Variables: map[string]string{":host:": "web02"},
+				TimeRange: "24h",
+				Schedule:  "@daily",
+				TimeZone:  "UTC",
+				Format:    tt.format,
+				Webhook:   tt.webhook,
+			}
+			for i := 0; i < cloudhub.MaxReportRuns; i++ {
+				report.Runs = append(report.Runs, cloudhub.ReportRun{Trigger: cloudhub.ReportTriggerSchedule, Status: cloudhub.JobSucceeded})
+			}
+			var updated *cloudhub.Report
+			s := &Service{
+				Store: &mocks.Store{
+					DashboardsStore: &mocks.DashboardsStore{
+						GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+							return dashboard, nil
+						},
+					},
+					ReportsStore: &mocks.ReportsStore{
+						GetF: func(ctx context.Context, id string) (*cloudhub.Report, error) {
+							return report, nil
+						},
+						UpdateF: func(ctx context.Context, r *cloudhub.Report) error {
+							updated = r
+							return nil
+						},
+					},
+					SourcesStore: &mocks.SourcesStore{
+						AllF: func(ctx context.Context) ([]cloudhub.Source, error) {
+							return []cloudhub.Source{{ID: 1, Default: true}}, nil
+						},
+						GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+							return cloudhub.Source{}, cloudhub.ErrSourceNotFound
+						},
+					},
+				},
+				TimeSeriesClient: &mocks.TimeSeries{
+					ConnectF: func(context.Context, *cloudhub.Source) error {
+						return nil
+					},
+					QueryF: func(ctx context.Context, q cloudhub.Query) (cloudhub.Response, error) {
+						command = q.Command
+						return mocks.NewResponse(`[{"series": [{"name": "cpu", "columns": ["time", "mean"], "values": [[1699992000000, 10], [1699995600000, 20]]}]}]`, nil), nil
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "http://any.url/cloudhub/v1/reports/1/send", nil)
+			r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}}))
+			s.SendReport(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("SendReport() status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			var run cloudhub.ReportRun
+			if err := json.Unmarshal(w.Body.Bytes(), &run); err != nil {
+				t.Fatal(err)
+			}
+			if run.Trigger != cloudhub.ReportTriggerManual || (run.Status == cloudhub.JobFailed) != (tt.wantStatus != http.StatusOK) {
+				t.Errorf("SendReport() run = %#v", run)
+			}
+			if updated == nil || len(updated.Runs) != cloudhub.MaxReportRuns || updated.Runs[len(updated.Runs)-1].Trigger != cloudhub.ReportTriggerManual {
+				t.Fatalf("SendReport() did not record the run in the history")
+			}
+			if !strings.Contains(command, `"host" = 'web02'`) {
+				t.Errorf("SendReport() query = %s", command)
+			}
+			if tt.want != "" && !bytes.Contains(posted, []byte(tt.want)) {
+				t.Errorf("SendReport() posted %s report without %s", contentType, tt.want)
+			}
+		})
+	}
+}
+
+func TestService_ReportRuns(t *testing.T) {
+	first := time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)
+	s := &Service{
+		Store: &mocks.Store{
+			ReportsStore: &mocks.ReportsStore{
+				GetF: func(ctx context.Context, id string) (*cloudhub.Report, error) {
+					return &cloudhub.Report{ID: id, Runs: []cloudhub.ReportRun{
+						{Time: first, Status: cloudhub.JobSucceeded},
+						{Time: first.Add(time.Hour), Status: cloudhub.JobFailed, Error: "webhook answered 500"},
+					}}, nil
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/reports/1/runs", nil)
+	r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}}))
+	s.ReportRuns(w, r)
+
+	var res reportRunsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Runs) != 2 || res.Runs[0].Status != cloudhub.JobFailed {
+		t.Errorf("ReportRuns() = %#v, want newest first", res.Runs)
+	}
+}
+
+func TestService_runScheduledReports(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	manual := cloudhub.ReportRun{Time: past, Trigger: cloudhub.ReportTriggerManual, Status: cloudhub.JobSucceeded}
+	var updated *cloudhub.Report
+	s := &Service{
+		Store: &mocks.Store{
+			ReportsStore: &mocks.ReportsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Report, error) {
+					return []cloudhub.Report{{ID: "1", Name: "Servers", Schedule: "@daily", NextRun: past}}, nil
+				},
+				// the report was sent while its scheduled run ran
+				GetF: func(ctx context.Context, id string) (*cloudhub.Report, error) {
+					return &cloudhub.Report{ID: id, Name: "Servers", Schedule: "@daily", NextRun: past, Runs: []cloudhub.ReportRun{manual}}, nil
+				},
+				UpdateF: func(ctx context.Context, r *cloudhub.Report) error {
+					updated = r
+					return nil
+				},
+			},
+			DashboardsStore: &mocks.DashboardsStore{
+				GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+					return cloudhub.Dashboard{}, cloudhub.ErrDashboardNotFound
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	// a done context returns after the first pass
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.runScheduledReports(ctx)
+
+	if updated == nil || len(updated.Runs) != 2 || updated.Runs[0] != manual || updated.Runs[1].Trigger != cloudhub.ReportTriggerSchedule {
+		t.Fatalf("runScheduledReports() updated %#v, want the scheduled run added to the runs of the report", updated)
+	}
+	if !updated.NextRun.After(past) {
+		t.Errorf("runScheduledReports() next run = %s, want a time after %s", updated.NextRun, past)
+	}
+}
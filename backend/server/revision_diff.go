@@ -0,0 +1,207 @@
+package server
+
+import (
+	"encoding/xml"
+	"reflect"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// valueChange is a scalar value that differs between two revisions
+type valueChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// itemChange identifies an element added, removed or changed between two revisions.
+// Fields lists the JSON names of the properties that changed.
+type itemChange struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+// dashboardDiff is the structural difference between two dashboard revisions
+type dashboardDiff struct {
+	Name             *valueChange `json:"name,omitempty"`
+	CellsAdded       []itemChange `json:"cellsAdded"`
+	CellsRemoved     []itemChange `json:"cellsRemoved"`
+	CellsChanged     []itemChange `json:"cellsChanged"`
+	TemplatesAdded   []itemChange `json:"templatesAdded"`
+	TemplatesRemoved []itemChange `json:"templatesRemoved"`
+	TemplatesChanged []itemChange `json:"templatesChanged"`
+}
+
+// topologyDiff is the structural difference between two topology revisions
+type topologyDiff struct {
+	NodesAdded         []itemChange `json:"nodesAdded"`
+	NodesRemoved       []itemChange `json:"nodesRemoved"`
+	NodesChanged       []itemChange `json:"nodesChanged"`
+	PreferencesChanged bool         `json:"preferencesChanged"`
+	OptionsChanged     []string     `json:"optionsChanged,omitempty"`
+}
+
+// diffDashboards compares the cells, templates and name of two dashboards
+func diffDashboards(from, to cloudhub.Dashboard) dashboardDiff {
+	diff := dashboardDiff{
+		CellsAdded:       []itemChange{},
+		CellsRemoved:     []itemChange{},
+		CellsChanged:     []itemChange{},
+		TemplatesAdded:   []itemChange{},
+		TemplatesRemoved: []itemChange{},
+		TemplatesChanged: []itemChange{},
+	}
+	if from.Name != to.Name {
+		diff.Name = &valueChange{From: from.Name, To: to.Name}
+	}
+
+	fromCells := make(map[string]cloudhub.DashboardCell, len(from.Cells))
+	for _, c := range from.Cells {
+		fromCells[c.ID] = c
+	}
+	toCells := make(map[string]bool, len(to.Cells))
+	for _, c := range to.Cells {
+		toCells[c.ID] = true
+		prev, ok := fromCells[c.ID]
+		if !ok {
+			diff.CellsAdded = append(diff.CellsAdded, itemChange{ID: c.ID, Name: c.Name})
+			continue
+		}
+		if fields := changedFields(prev, c); len(fields) > 0 {
+			diff.CellsChanged = append(diff.CellsChanged, itemChange{ID: c.ID, Name: c.Name, Fields: fields})
+		}
+	}
+	for _, c := range from.Cells {
+		if !toCells[c.ID] {
+			diff.CellsRemoved = append(diff.CellsRemoved, itemChange{ID: c.ID, Name: c.Name})
+		}
+	}
+
+	fromTemplates := make(map[cloudhub.TemplateID]cloudhub.Template, len(from.Templates))
+	for _, t := range from.Templates {
+		fromTemplates[t.ID] = t
+	}
+	toTemplates := make(map[cloudhub.TemplateID]bool, len(to.Templates))
+	for _, t := range to.Templates {
+		toTemplates[t.ID] = true
+		prev, ok := fromTemplates[t.ID]
+		if !ok {
+			diff.TemplatesAdded = append(diff.TemplatesAdded, itemChange{ID: string(t.ID), Name: t.Var})
+			continue
+		}
+		if fields := changedFields(prev, t); len(fields) > 0 {
+			diff.TemplatesChanged = append(diff.TemplatesChanged, itemChange{ID: string(t.ID), Name: t.Var, Fields: fields})
+		}
+	}
+	for _, t := range from.Templates {
+		if !toTemplates[t.ID] {
+			diff.TemplatesRemoved = append(diff.TemplatesRemoved, itemChange{ID: string(t.ID), Name: t.Var})
+		}
+	}
+
+	return diff
+}
+
+// mxCell is a node or an edge of an mxGraph diagram
+type mxCell struct {
+	ID       string `xml:"id,attr"`
+	Value    string `xml:"value,attr"`
+	Style    string `xml:"style,attr"`
+	Parent   string `xml:"parent,attr"`
+	Source   string `xml:"source,attr"`
+	Target   string `xml:"target,attr"`
+	Vertex   string `xml:"vertex,attr"`
+	Edge     string `xml:"edge,attr"`
+	Geometry struct {
+		X      string `xml:"x,attr"`
+		Y      string `xml:"y,attr"`
+		Width  string `xml:"width,attr"`
+		Height string `xml:"height,attr"`
+	} `xml:"mxGeometry"`
+}
+
+// mxGraphModel is the XML document stored in Topology.Diagram
+type mxGraphModel struct {
+	Cells []mxCell `xml:"root>mxCell"`
+}
+
+// parseDiagram decodes the cells of a topology diagram. An empty diagram has no cells.
+func parseDiagram(diagram string) ([]mxCell, error) {
+	if strings.TrimSpace(diagram) == "" {
+		return nil, nil
+	}
+	var model mxGraphModel
+	if err := xml.Unmarshal([]byte(diagram), &model); err != nil {
+		return nil, err
+	}
+	return model.Cells, nil
+}
+
+// diffTopologies compares the diagram nodes, preferences and options of two topologies
+func diffTopologies(from, to cloudhub.Topology) (topologyDiff, error) {
+	diff := topologyDiff{
+		NodesAdded:   []itemChange{},
+		NodesRemoved: []itemChange{},
+		NodesChanged: []itemChange{},
+	}
+
+	fromCells, err := parseDiagram(from.Diagram)
+	if err != nil {
+		return diff, err
+	}
+	toCells, err := parseDiagram(to.Diagram)
+	if err != nil {
+		return diff, err
+	}
+
+	prev := make(map[string]mxCell, len(fromCells))
+	for _, c := range fromCells {
+		prev[c.ID] = c
+	}
+	next := make(map[string]bool, len(toCells))
+	for _, c := range toCells {
+		next[c.ID] = true
+		p, ok := prev[c.ID]
+		if !ok {
+			diff.NodesAdded = append(diff.NodesAdded, itemChange{ID: c.ID, Name: c.Value})
+			continue
+		}
+		if fields := changedFields(p, c); len(fields) > 0 {
+			diff.NodesChanged = append(diff.NodesChanged, itemChange{ID: c.ID, Name: c.Value, Fields: fields})
+		}
+	}
+	for _, c := range fromCells {
+		if !next[c.ID] {
+			diff.NodesRemoved = append(diff.NodesRemoved, itemChange{ID: c.ID, Name: c.Value})
+		}
+	}
+
+	diff.PreferencesChanged = !reflect.DeepEqual(from.Preferences, to.Preferences)
+	diff.OptionsChanged = changedFields(from.TopologyOptions, to.TopologyOptions)
+
+	return diff, nil
+}
+
+// changedFields returns the names of the fields that differ between two structs of the same type.
+// Names are taken from the json tag, then the xml tag, then the Go field name.
+func changedFields(a, b interface{}) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			continue
+		}
+		f := t.Field(i)
+		name := f.Name
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			name = tag
+		} else if tag := strings.Split(f.Tag.Get("xml"), ",")[0]; tag != "" {
+			name = tag
+		}
+		fields = append(fields, name)
+	}
+	return fields
+}
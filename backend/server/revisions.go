@@ -0,0 +1,436 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+type revisionLinks struct {
+	Self    string `json:"self"`    // Self link mapping to this resource
+	Diff    string `json:"diff"`    // Diff link to the difference between this revision and the current resource
+	Restore string `json:"restore"` // Restore link to restore the resource to this revision
+}
+
+type revisionResponse struct {
+	ID           string        `json:"id"`
+	ResourceType string        `json:"resourceType"`
+	ResourceID   string        `json:"resourceID"`
+	Organization string        `json:"organization"`
+	Author       string        `json:"author"`
+	Message      string        `json:"message"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	Links        revisionLinks `json:"links"`
+}
+
+type revisionsResponse struct {
+	Revisions []revisionResponse `json:"revisions"`
+}
+
+type dashboardRevisionResponse struct {
+	revisionResponse
+	Dashboard *dashboardResponse `json:"dashboard"`
+}
+
+type topologyRevisionResponse struct {
+	revisionResponse
+	Topology *topologyResponse `json:"topology"`
+}
+
+type revisionDiffResponse struct {
+	From string      `json:"from"`
+	To   string      `json:"to"`
+	Diff interface{} `json:"diff"`
+}
+
+func newRevisionResponse(r cloudhub.Revision) revisionResponse {
+	base := "/cloudhub/v1/dashboards"
+	if r.ResourceType == cloudhub.RevisionTopology {
+		base = "/cloudhub/v1/topologies"
+	}
+	self := fmt.Sprintf("%s/%s/revisions/%s", base, r.ResourceID, r.ID)
+
+	return revisionResponse{
+		ID:           r.ID,
+		ResourceType: r.ResourceType,
+		ResourceID:   r.ResourceID,
+		Organization: r.Organization,
+		Author:       r.Author,
+		Message:      r.Message,
+		CreatedAt:    r.CreatedAt,
+		Links: revisionLinks{
+			Self:    self,
+			Diff:    self + "/diff",
+			Restore: self + "/restore",
+		},
+	}
+}
+
+func newRevisionsResponse(revs []cloudhub.Revision) *revisionsResponse {
+	res := &revisionsResponse{
+		Revisions: make([]revisionResponse, 0, len(revs)),
+	}
+	// newest revision first
+	for i := len(revs) - 1; i >= 0; i-- {
+		res.Revisions = append(res.Revisions, newRevisionResponse(revs[i]))
+	}
+	return res
+}
+
+// revisionContext returns the request context carrying the author and the optional
+// `message` query parameter recorded with the revisions created by the request.
+func revisionContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	info := cloudhub.RevisionInfo{
+		Message: r.URL.Query().Get("message"),
+	}
+	if u, ok := hasUserContext(ctx); ok {
+		info.Author = u.Name
+	}
+	return context.WithValue(ctx, cloudhub.RevisionContextKey, info)
+}
+
+// restoreContext is a revisionContext with a default message naming the restored revision
+func restoreContext(r *http.Request, rid string) context.Context {
+	ctx := revisionContext(r)
+	info := ctx.Value(cloudhub.RevisionContextKey).(cloudhub.RevisionInfo)
+	if info.Message == "" {
+		info.Message = fmt.Sprintf("Restored revision %s", rid)
+	}
+	return context.WithValue(ctx, cloudhub.RevisionContextKey, info)
+}
+
+// dashboardRevision returns the dashboard stored in the revision named by the `rid` parameter
+func (s *Service) dashboardRevision(ctx context.Context, id cloudhub.DashboardID, rid string) (*cloudhub.Revision, cloudhub.Dashboard, error) {
+	q := cloudhub.RevisionQuery{
+		ResourceType: cloudhub.RevisionDashboard,
+		ResourceID:   strconv.Itoa(int(id)),
+	}
+	rev, err := s.Store.Revisions(ctx).Get(ctx, q, rid)
+	if err != nil {
+		return nil, cloudhub.Dashboard{}, err
+	}
+
+	var dash cloudhub.Dashboard
+	if err := json.Unmarshal(rev.Content, &dash); err != nil {
+		return nil, cloudhub.Dashboard{}, err
+	}
+	dash.ID = id
+	return rev, dash, nil
+}
+
+// DashboardRevisions returns the revisions of a dashboard, newest first
+func (s *Service) DashboardRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id)); err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	revs, err := s.Store.Revisions(ctx).All(ctx, cloudhub.RevisionQuery{
+		ResourceType: cloudhub.RevisionDashboard,
+		ResourceID:   strconv.Itoa(id),
+	})
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading revisions", s.Logger)
+		return
+	}
+
+	encodeJSON(w, http.StatusOK, newRevisionsResponse(revs), s.Logger)
+}
+
+// DashboardRevisionID returns a single revision of a dashboard including its content
+func (s *Service) DashboardRevisionID(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+	rid := httprouter.GetParamFromContext(r.Context(), "rid")
+
+	ctx := r.Context()
+	if _, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id)); err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	rev, dash, err := s.dashboardRevision(ctx, cloudhub.DashboardID(id), rid)
+	if err != nil {
+		notFound(w, rid, s.Logger)
+		return
+	}
+
+	res := dashboardRevisionResponse{
+		revisionResponse: newRevisionResponse(*rev),
+		Dashboard:        newDashboardResponse(dash),
+	}
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// DashboardRevisionDiff returns the structural difference between a dashboard revision
+// and the revision given by the `to` query parameter, or the current dashboard if absent
+func (s *Service) DashboardRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+	rid := httprouter.GetParamFromContext(r.Context(), "rid")
+
+	ctx := r.Context()
+	current, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	_, from, err := s.dashboardRevision(ctx, current.ID, rid)
+	if err != nil {
+		notFound(w, rid, s.Logger)
+		return
+	}
+
+	to, toID := current, "current"
+	if tid := r.URL.Query().Get("to"); tid != "" {
+		if _, to, err = s.dashboardRevision(ctx, current.ID, tid); err != nil {
+			notFound(w, tid, s.Logger)
+			return
+		}
+		toID = tid
+	}
+
+	res := revisionDiffResponse{
+		From: rid,
+		To:   toID,
+		Diff: diffDashboards(from, to),
+	}
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// RestoreDashboardRevision replaces a dashboard with the content of one of its revisions
+func (s *Service) RestoreDashboardRevision(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+	rid := httprouter.GetParamFromContext(r.Context(), "rid")
+
+	ctx := r.Context()
+	current, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	_, dash, err := s.dashboardRevision(ctx, current.ID, rid)
+	if err != nil {
+		notFound(w, rid, s.Logger)
+		return
+	}
+	dash.Organization = current.Organization
+	if dash.Version, err = expectedVersion(r, current.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+
+	if err := s.Store.Dashboards(ctx).Update(restoreContext(r, rid), dash); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
+		msg := fmt.Sprintf("Error restoring dashboard ID %d: %v", id, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgDashboardModified.String(), dash.Name)
+	s.logRegistration(ctx, "Dashboards", msg)
+
+	dash.Version++
+	res := newDashboardResponse(dash)
+	setETag(w, dash.Version)
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// topologyRevision returns the topology stored in the revision named by rid
+func (s *Service) topologyRevision(ctx context.Context, id, rid string) (*cloudhub.Revision, *cloudhub.Topology, error) {
+	q := cloudhub.RevisionQuery{
+		ResourceType: cloudhub.RevisionTopology,
+		ResourceID:   id,
+	}
+	rev, err := s.Store.Revisions(ctx).Get(ctx, q, rid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var tp cloudhub.Topology
+	if err := json.Unmarshal(rev.Content, &tp); err != nil {
+		return nil, nil, err
+	}
+	tp.ID = id
+	return rev, &tp, nil
+}
+
+// TopologyRevisions returns the revisions of a topology, newest first
+func (s *Service) TopologyRevisions(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := s.Store.Topologies(ctx).Get(ctx, cloudhub.TopologyQuery{ID: &id}); err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	revs, err := s.Store.Revisions(ctx).All(ctx, cloudhub.RevisionQuery{
+		ResourceType: cloudhub.RevisionTopology,
+		ResourceID:   id,
+	})
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading revisions", s.Logger)
+		return
+	}
+
+	encodeJSON(w, http.StatusOK, newRevisionsResponse(revs), s.Logger)
+}
+
+// TopologyRevisionID returns a single revision of a topology including its diagram
+func (s *Service) TopologyRevisionID(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	rid := httprouter.GetParamFromContext(r.Context(), "rid")
+
+	ctx := r.Context()
+	if _, err := s.Store.Topologies(ctx).Get(ctx, cloudhub.TopologyQuery{ID: &id}); err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	rev, tp, err := s.topologyRevision(ctx, id, rid)
+	if err != nil {
+		notFound(w, rid, s.Logger)
+		return
+	}
+
+	res := topologyRevisionResponse{
+		revisionResponse: newRevisionResponse(*rev),
+		Topology:         newTopologyResponse(tp, true),
+	}
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// TopologyRevisionDiff returns the structural difference between a topology revision
+// and the revision given by the `to` query parameter, or the current topology if absent
+func (s *Service) TopologyRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	rid := httprouter.GetParamFromContext(r.Context(), "rid")
+
+	ctx := r.Context()
+	current, err := s.Store.Topologies(ctx).Get(ctx, cloudhub.TopologyQuery{ID: &id})
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	_, from, err := s.topologyRevision(ctx, id, rid)
+	if err != nil {
+		notFound(w, rid, s.Logger)
+		return
+	}
+
+	to, toID := current, "current"
+	if tid := r.URL.Query().Get("to"); tid != "" {
+		if _, to, err = s.topologyRevision(ctx, id, tid); err != nil {
+			notFound(w, tid, s.Logger)
+			return
+		}
+		toID = tid
+	}
+
+	diff, err := diffTopologies(*from, *to)
+	if err != nil {
+		invalidData(w, fmt.Errorf("Invalid topology diagram: %v", err), s.Logger)
+		return
+	}
+
+	res := revisionDiffResponse{
+		From: rid,
+		To:   toID,
+		Diff: diff,
+	}
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// RestoreTopologyRevision replaces a topology with the content of one of its revisions
+func (s *Service) RestoreTopologyRevision(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	rid := httprouter.GetParamFromContext(r.Context(), "rid")
+
+	ctx := r.Context()
+	current, err := s.Store.Topologies(ctx).Get(ctx, cloudhub.TopologyQuery{ID: &id})
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	_, tp, err := s.topologyRevision(ctx, id, rid)
+	if err != nil {
+		notFound(w, rid, s.Logger)
+		return
+	}
+	tp.Organization = current.Organization
+	if tp.Version, err = expectedVersion(r, current.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+
+	if err := s.Store.Topologies(ctx).Update(restoreContext(r, rid), tp); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err != nil {
+		msg := fmt.Sprintf("Error restoring topology ID %s: %v", id, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	org, _ := s.Store.Organizations(ctx).Get(ctx, cloudhub.OrganizationQuery{ID: &tp.Organization})
+	if org != nil {
+		msg := fmt.Sprintf(MsgTopologyModified.String(), org.Name)
+		s.logRegistration(ctx, "Topologies", msg)
+	}
+
+	res := newTopologyResponse(tp, false)
+	setETag(w, tp.Version)
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+)
+
+func Test_diffDashboards(t *testing.T) {
+	from := cloudhub.Dashboard{
+		Name: "old",
+		Cells: []cloudhub.DashboardCell{
+			{ID: "a", Name: "cpu", W: 4},
+			{ID: "b", Name: "mem"},
+		},
+		Templates: []cloudhub.Template{
+			{ID: "t1", TemplateVar: cloudhub.TemplateVar{Var: ":host:"}},
+		},
+	}
+	to := cloudhub.Dashboard{
+		Name: "new",
+		Cells: []cloudhub.DashboardCell{
+			{ID: "a", Name: "cpu", W: 6},
+			{ID: "c", Name: "disk"},
+		},
+		Templates: []cloudhub.Template{
+			{ID: "t1", TemplateVar: cloudhub.TemplateVar{Var: ":host:"}},
+		},
+	}
+
+	want := dashboardDiff{
+		Name:             &valueChange{From: "old", To: "new"},
+		CellsAdded:       []itemChange{{ID: "c", Name: "disk"}},
+		CellsRemoved:     []itemChange{{ID: "b", Name: "mem"}},
+		CellsChanged:     []itemChange{{ID: "a", Name: "cpu", Fields: []string{"w"}}},
+		TemplatesAdded:   []itemChange{},
+		TemplatesRemoved: []itemChange{},
+		TemplatesChanged: []itemChange{},
+	}
+	if got := diffDashboards(from, to); !cmp.Equal(got, want) {
+		t.Errorf("diffDashboards() = %s", cmp.Diff(got, want))
+	}
+}
+
+func Test_diffTopologies(t *testing.T) {
+	from := cloudhub.Topology{
+		Diagram: `<mxGraphModel><root>
+			<mxCell id="0"/>
+			<mxCell id="n1" value="router" vertex="1"><mxGeometry x="10" y="10" width="40" height="40"/></mxCell>
+			<mxCell id="n2" value="switch" vertex="1"/>
+		</root></mxGraphModel>`,
+		Preferences: []string{"type:inlet,active:1"},
+	}
+	to := cloudhub.Topology{
+		Diagram: `<mxGraphModel><root>
+			<mxCell id="0"/>
+			<mxCell id="n1" value="router" vertex="1"><mxGeometry x="80" y="10" width="40" height="40"/></mxCell>
+			<mxCell id="n3" value="server" vertex="1"/>
+		</root></mxGraphModel>`,
+		Preferences: []string{"type:inlet,active:1"},
+		TopologyOptions: cloudhub.TopologyOptions{
+			MinimapVisible: true,
+		},
+	}
+
+	got, err := diffTopologies(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := topologyDiff{
+		NodesAdded:     []itemChange{{ID: "n3", Name: "server"}},
+		NodesRemoved:   []itemChange{{ID: "n2", Name: "switch"}},
+		NodesChanged:   []itemChange{{ID: "n1", Name: "router", Fields: []string{"mxGeometry"}}},
+		OptionsChanged: []string{"minimapVisible"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("diffTopologies() = %s", cmp.Diff(got, want))
+	}
+
+	if _, err := diffTopologies(cloudhub.Topology{Diagram: "<mxGraphModel>"}, to); err == nil {
+		t.Error("diffTopologies() expected an error for an invalid diagram")
+	}
+}
+
+func TestService_RestoreDashboardRevision(t *testing.T) {
+	old, _ := json.Marshal(cloudhub.Dashboard{
+		ID:           1,
+		Name:         "before",
+		Organization: "default",
+	})
+
+	tests := []struct {
+		name     string
+		rid      string
+		wantCode int
+		wantName string
+		wantMsg  string
+	}{
+		{
+			name:     "restores a revision",
+			rid:      "7",
+			wantCode: http.StatusOK,
+			wantName: "before",
+			wantMsg:  "Restored revision 7",
+		},
+		{
+			name:     "unknown revision",
+			rid:      "8",
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated *cloudhub.Dashboard
+			var info cloudhub.RevisionInfo
+			s := &Service{
+				Store: &mocks.Store{
+					DashboardsStore: &mocks.DashboardsStore{
+						GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+							return cloudhub.Dashboard{ID: id, Name: "after", Organization: "default"}, nil
+						},
+						UpdateF: func(ctx context.Context, d cloudhub.Dashboard) error {
+							updated = &d
+							info, _ = ctx.Value(cloudhub.RevisionContextKey).(cloudhub.RevisionInfo)
+							return nil
+						},
+					},
+					RevisionsStore: &mocks.RevisionsStore{
+						GetF: func(ctx context.Context, q cloudhub.RevisionQuery, id string) (*cloudhub.Revision, error) {
+							if q.ResourceType != cloudhub.RevisionDashboard || q.ResourceID != "1" || id != "7" {
+								return nil, cloudhub.ErrRevisionNotFound
+							}
+							return &cloudhub.Revision{ID: id, Content: old}, nil
+						},
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "http://any.url", nil)
+			r = WithContext(r.Context(), r, map[string]string{
+				"id":  "1",
+				"rid": tt.rid,
+			})
+			s.RestoreDashboardRevision(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("RestoreDashboardRevision() status = %d, want %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantCode != http.StatusOK {
+				if updated != nil {
+					t.Error("RestoreDashboardRevision() updated the dashboard on error")
+				}
+				return
+			}
+			if updated == nil || updated.Name != tt.wantName || updated.ID != 1 {
+				t.Errorf("RestoreDashboardRevision() updated = %#v", updated)
+			}
+			if info.Message != tt.wantMsg {
+				t.Errorf("RestoreDashboardRevision() message = %q, want %q", info.Message, tt.wantMsg)
+			}
+		})
+	}
+}
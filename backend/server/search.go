@@ -0,0 +1,340 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/search"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+	// defaultSearchMaxAge bounds the age of the search index, which only learns of
+	// the changes made by other instances sharing the store by being rebuilt
+	defaultSearchMaxAge = 5 * time.Minute
+)
+
+// searchedResources are the resources of the store indexed for search
+var searchedResources = map[string]bool{
+	"dashboards":    true,
+	"protoboards":   true,
+	"topologies":    true,
+	"organizations": true,
+}
+
+// htmlTags matches the markup of the labels of the nodes of topologies
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// SearchIndex is the search index of the dashboards, protoboards and topologies
+// of the store. It is rebuilt when searched after they changed.
+type SearchIndex struct {
+	MaxAge time.Duration    // MaxAge is the age after which the index is rebuilt even if unchanged
+	Now    func() time.Time // Now returns the current time
+
+	stale   int32 // stale is 1 once resources changed since the index was built
+	mu      sync.Mutex
+	index   *search.Index
+	builtAt time.Time
+}
+
+// NewSearchIndex returns a SearchIndex rebuilt on the changes published by feed
+func NewSearchIndex(feed cloudhub.ChangeFeed) *SearchIndex {
+	idx := &SearchIndex{
+		MaxAge: defaultSearchMaxAge,
+		Now:    time.Now,
+	}
+	if feed != nil {
+		feed.Subscribe(func(c cloudhub.Change) {
+			if searchedResources[c.Resource] {
+				atomic.StoreInt32(&idx.stale, 1)
+			}
+		})
+	}
+	return idx
+}
+
+// get returns the index, rebuilding it with build if stale or too old
+func (i *SearchIndex) get(ctx context.Context, build func(context.Context) (*search.Index, error)) (*search.Index, error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.Now()
+	if i.index != nil && atomic.LoadInt32(&i.stale) == 0 && now.Sub(i.builtAt) < i.MaxAge {
+		return i.index, nil
+	}
+	// changes made while building mark the new index stale
+	atomic.StoreInt32(&i.stale, 0)
+	index, err := build(ctx)
+	if err != nil {
+		atomic.StoreInt32(&i.stale, 1)
+		return nil, err
+	}
+	i.index = index
+	i.builtAt = now
+	return index, nil
+}
+
+type searchHit struct {
+	Kind    string    `json:"kind"`
+	ID      string    `json:"id"`
+	Title   string    `json:"title"`
+	Context string    `json:"context,omitempty"`
+	Field   string    `json:"field"`
+	Snippet string    `json:"snippet"`
+	Score   float64   `json:"score"`
+	Links   selfLinks `json:"links"`
+}
+
+type searchResponse struct {
+	Query string      `json:"query"`
+	Hits  []searchHit `json:"hits"`
+	Links selfLinks   `json:"links"`
+}
+
+// Search returns the dashboards, cells, templates, protoboards and topology nodes
+// of the current organization matching the query, best matches first
+func (s *Service) Search(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		invalidData(w, fmt.Errorf("the query q is required"), s.Logger)
+		return
+	}
+	limit := defaultSearchLimit
+	if l := r.URL.Query().Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 1 {
+			invalidData(w, fmt.Errorf("invalid limit %s", l), s.Logger)
+			return
+		}
+		limit = n
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	kinds := map[string]bool{}
+	for _, k := range strings.Split(r.URL.Query().Get("kinds"), ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			kinds[k] = true
+		}
+	}
+
+	ctx := r.Context()
+	orgID, ok := hasOrganizationContext(ctx)
+	if !ok {
+		Error(w, http.StatusBadRequest, "Unable to find organization of the user", s.Logger)
+		return
+	}
+
+	// dashboards, cells and templates are found by the roles their folders allow
+	visible, err := s.folderVisible(ctx)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	var index *search.Index
+	if s.SearchIndex != nil {
+		index, err = s.SearchIndex.get(ctx, s.buildSearchIndex)
+	} else {
+		index, err = s.buildSearchIndex(ctx)
+	}
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	hits := index.Search(query, search.Options{
+		Limit: limit,
+		Filter: func(doc *search.Document) bool {
+			if len(kinds) > 0 && !kinds[doc.Kind] {
+				return false
+			}
+			if !visible(doc.Folder) {
+				return false
+			}
+			// resources without organization, such as protoboards, are visible in every organization
+			return doc.Organization == "" || doc.Organization == orgID
+		},
+	})
+
+	res := searchResponse{
+		Query: query,
+		Hits:  make([]searchHit, 0, len(hits)),
+		Links: selfLinks{
+			Self: "/cloudhub/v1/search?" + r.URL.RawQuery,
+		},
+	}
+	for _, h := range hits {
+		res.Hits = append(res.Hits, searchHit{
+			Kind:    h.Kind,
+			ID:      h.ID,
+			Title:   h.Title,
+			Context: h.Context,
+			Field:   h.Field,
+			Snippet: h.Snippet,
+			Score:   h.Score,
+			Links:   selfLinks{Self: h.Link},
+		})
+	}
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// buildSearchIndex indexes the dashboards, protoboards and topologies of every organization
+func (s *Service) buildSearchIndex(ctx context.Context) (*search.Index, error) {
+	ctx = serverContext(ctx)
+	var docs []search.Document
+
+	dashboards, err := s.Store.Dashboards(ctx).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading dashboards: %v", err)
+	}
+	for _, d := range dashboards {
+		docs = append(docs, dashboardDocuments(d)...)
+	}
+
+	protoboards, err := s.Store.Protoboards(ctx).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading protoboards: %v", err)
+	}
+	for _, p := range protoboards {
+		docs = append(docs, protoboardDocument(p))
+	}
+
+	topologies, err := s.Store.Topologies(ctx).All(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error loading topologies: %v", err)
+	}
+	for _, t := range topologies {
+		nodes, err := topologyDocuments(t)
+		if err != nil {
+			// a diagram which cannot be parsed only leaves its nodes unsearchable
+			s.Logger.Error(fmt.Sprintf("Unable to index topology %s: %v", t.ID, err))
+			continue
+		}
+		docs = append(docs, nodes...)
+	}
+
+	return search.NewIndex(docs), nil
+}
+
+// dashboardDocuments returns the documents of a dashboard, its cells and its templates
+func dashboardDocuments(d cloudhub.Dashboard) []search.Document {
+	id := strconv.Itoa(int(d.ID))
+	link := fmt.Sprintf("/cloudhub/v1/dashboards/%d", d.ID)
+
+	templates := make([]string, 0, len(d.Templates))
+	for _, t := range d.Templates {
+		templates = append(templates, t.Var, t.Label)
+	}
+	docs := []search.Document{{
+		Kind:         "dashboard",
+		ID:           id,
+		Organization: d.Organization,
+		Folder:       d.Folder,
+		Title:        d.Name,
+		Link:         link,
+		Fields: []search.Field{
+			{Name: "name", Text: d.Name, Weight: 3},
+			{Name: "template", Text: strings.Join(templates, " "), Weight: 0.5},
+		},
+	}}
+
+	for _, c := range d.Cells {
+		queries := make([]string, 0, len(c.Queries))
+		for _, q := range c.Queries {
+			queries = append(queries, q.Command)
+		}
+		docs = append(docs, search.Document{
+			Kind:         "cell",
+			ID:           id + "/" + c.ID,
+			Organization: d.Organization,
+			Folder:       d.Folder,
+			Title:        c.Name,
+			Context:      d.Name,
+			Link:         link + "/cells/" + c.ID,
+			Fields: []search.Field{
+				{Name: "name", Text: c.Name, Weight: 2},
+				{Name: "note", Text: c.Note},
+				{Name: "query", Text: strings.Join(queries, "\n")},
+			},
+		})
+	}
+
+	for _, t := range d.Templates {
+		var query string
+		if t.Query != nil {
+			query = t.Query.Command + " " + t.Query.Flux
+		}
+		docs = append(docs, search.Document{
+			Kind:         "template",
+			ID:           id + "/" + string(t.ID),
+			Organization: d.Organization,
+			Folder:       d.Folder,
+			Title:        t.Var,
+			Context:      d.Name,
+			Link:         link + "/templates/" + string(t.ID),
+			Fields: []search.Field{
+				{Name: "name", Text: t.Var + " " + t.Label, Weight: 2},
+				{Name: "query", Text: query},
+			},
+		})
+	}
+	return docs
+}
+
+// protoboardDocument returns the document of a protoboard, visible in every organization
+func protoboardDocument(p cloudhub.Protoboard) search.Document {
+	return search.Document{
+		Kind:  "protoboard",
+		ID:    p.ID,
+		Title: p.Meta.Name,
+		Link:  "/cloudhub/v1/protoboards/" + url.PathEscape(p.ID),
+		Fields: []search.Field{
+			{Name: "name", Text: p.Meta.Name, Weight: 3},
+			{Name: "description", Text: p.Meta.Description},
+			{Name: "measurements", Text: strings.Join(p.Meta.Measurements, " "), Weight: 1.5},
+			{Name: "author", Text: p.Meta.Author, Weight: 0.5},
+		},
+	}
+}
+
+// topologyDocuments returns the documents of the labeled nodes of a topology
+func topologyDocuments(t cloudhub.Topology) ([]search.Document, error) {
+	cells, err := parseDiagram(t.Diagram)
+	if err != nil {
+		return nil, err
+	}
+	var docs []search.Document
+	for _, c := range cells {
+		if c.Vertex != "1" {
+			continue
+		}
+		label := strings.Join(strings.Fields(html.UnescapeString(htmlTags.ReplaceAllString(c.Value, " "))), " ")
+		if label == "" {
+			continue
+		}
+		docs = append(docs, search.Document{
+			Kind:         "node",
+			ID:           t.ID + "/" + c.ID,
+			Organization: t.Organization,
+			Title:        label,
+			Context:      "Topology",
+			Link:         "/cloudhub/v1/topologies#" + url.PathEscape(c.ID),
+			Fields: []search.Field{
+				{Name: "label", Text: label, Weight: 2},
+			},
+		})
+	}
+	return docs, nil
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/organizations"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+type testChangeFeed struct {
+	subscribers []func(cloudhub.Change)
+}
+
+func (f *testChangeFeed) Subscribe(fn func(cloudhub.Change)) func() {
+	f.subscribers = append(f.subscribers, fn)
+	return func() {}
+}
+
+func TestService_Search(t *testing.T) {
+	dashboards := []cloudhub.Dashboard{
+		{
+			ID:           1,
+			Name:         "Servers",
+			Organization: "default",
+			Cells: []cloudhub.DashboardCell{
+				{ID: "c1", Name: "Disk usage", Note: "Alert above 90%", Queries: []cloudhub.DashboardQuery{{Command: `SELECT mean("used_percent") FROM "disk" WHERE "host" = 'web01'`}}},
+				{ID: "c2", Name: "CPU", Queries: []cloudhub.DashboardQuery{{Command: `SELECT mean("usage_user") FROM "cpu"`}}},
+			},
+			Templates: []cloudhub.Template{
+				{TemplateVar: cloudhub.TemplateVar{Var: ":host:"}, ID: "t1", Label: "Host name", Query: &cloudhub.TemplateQuery{Command: `SHOW TAG VALUES WITH KEY = "host"`}},
+			},
+		},
+		{
+			ID:           2,
+			Name:         "Other disks",
+			Organization: "other",
+			Cells:        []cloudhub.DashboardCell{{ID: "c1", Name: "Disk"}},
+		},
+	}
+	loads := 0
+	feed := &testChangeFeed{}
+	s := &Service{
+		Store: &mocks.Store{
+			DashboardsStore: &mocks.DashboardsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Dashboard, error) {
+					loads++
+					return dashboards, nil
+				},
+			},
+			ProtoboardsStore: &mocks.ProtoboardsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Protoboard, error) {
+					return []cloudhub.Protoboard{{ID: "system", Meta: cloudhub.ProtoboardMeta{Name: "System", Description: "Disk, CPU and memory", Measurements: []string{"disk", "cpu"}}}}, nil
+				},
+			},
+			TopologiesStore: &mocks.TopologiesStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Topology, error) {
+					return []cloudhub.Topology{{
+						ID:           "1",
+						Organization: "default",
+						Diagram:      `<mxGraphModel><root><mxCell id="0"/><mxCell id="n1" value="&lt;b&gt;web01&lt;/b&gt; storage" vertex="1" parent="0"/><mxCell id="e1" value="web01 link" edge="1" parent="0"/></root></mxGraphModel>`,
+					}}, nil
+				},
+			},
+		},
+		Logger:      &mocks.TestLogger{},
+		SearchIndex: NewSearchIndex(feed),
+	}
+
+	searchFor := func(query string) (int, searchResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/search?"+query, nil)
+		r = r.WithContext(context.WithValue(r.Context(), organizations.ContextKey, "default"))
+		s.Search(w, r)
+		var res searchResponse
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, res
+	}
+	kinds := func(res searchResponse) []string {
+		var k []string
+		for _, h := range res.Hits {
+			k = append(k, h.Kind+":"+h.ID)
+		}
+		return k
+	}
+
+	code, res := searchFor("q=disk")
+	if code != http.StatusOK {
+		t.Fatalf("Search() status = %d", code)
+	}
+	// the dashboard of the other organization is not found
+	if got := kinds(res); len(got) != 2 || got[0] != "cell:1/c1" || got[1] != "protoboard:system" {
+		t.Errorf("Search(disk) = %v", got)
+	}
+	if hit := res.Hits[0]; hit.Links.Self != "/cloudhub/v1/dashboards/1/cells/c1" || hit.Context != "Servers" || hit.Field != "name" {
+		t.Errorf("Search(disk) hit = %#v", hit)
+	}
+
+	if _, res = searchFor("q=web01"); len(res.Hits) != 2 || res.Hits[0].Kind != "node" || res.Hits[0].Title != "web01 storage" || res.Hits[0].Links.Self != "/cloudhub/v1/topologies#n1" {
+		t.Errorf("Search(web01) = %#v", res.Hits)
+	}
+	if _, res = searchFor("q=serv&kinds=dashboard,template"); len(res.Hits) != 1 || res.Hits[0].Kind != "dashboard" {
+		t.Errorf("Search(serv) of dashboards = %#v", res.Hits)
+	}
+	if _, res = searchFor("q=hostname+usag&limit=1"); len(res.Hits) != 0 {
+		t.Errorf("Search() matched documents without every term %#v", res.Hits)
+	}
+	if _, res = searchFor("q=host&limit=1"); len(res.Hits) != 1 || res.Hits[0].Kind != "template" {
+		t.Errorf("Search(host) = %#v", res.Hits)
+	}
+	if code, _ = searchFor("q=+"); code != http.StatusUnprocessableEntity {
+		t.Errorf("Search() without query status = %d", code)
+	}
+	if code, _ = searchFor("q=disk&limit=0"); code != http.StatusUnprocessableEntity {
+		t.Errorf("Search() with an invalid limit status = %d", code)
+	}
+
+	// the index is rebuilt once the resources change
+	if loads != 1 {
+		t.Errorf("Search() loaded the dashboards %d times, want 1", loads)
+	}
+	dashboards[0].Name = "Fleet"
+	for _, fn := range feed.subscribers {
+		fn(cloudhub.Change{Resource: "sources", ID: "1"})
+	}
+	if _, res = searchFor("q=fleet&kinds=dashboard"); len(res.Hits) != 0 {
+		t.Errorf("Search() rebuilt the index after an unrelated change")
+	}
+	for _, fn := range feed.subscribers {
+		fn(cloudhub.Change{Resource: "dashboards", ID: "1"})
+	}
+	if _, res = searchFor("q=fleet&kinds=dashboard"); len(res.Hits) != 1 || loads != 2 {
+		t.Errorf("Search() after the dashboard changed = %#v, loads %d", res.Hits, loads)
+	}
+}
+
+func TestService_Search_FolderPermissions(t *testing.T) {
+	s := &Service{
+		Store: &mocks.Store{
+			DashboardsStore: &mocks.DashboardsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Dashboard, error) {
+					return []cloudhub.Dashboard{
+						{ID: 1, Name: "Public disks", Organization: "default"},
+						{ID: 2, Name: "Editor disks", Organization: "default", Folder: "2", Cells: []cloudhub.DashboardCell{{ID: "c1", Name: "Disk"}}},
+						{ID: 3, Name: "Nested disks", Organization: "default", Folder: "3"},
+					}, nil
+				},
+			},
+			FoldersStore: &mocks.FoldersStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Folder, error) {
+					return []cloudhub.Folder{
+						{ID: "2", Organization: "default", Permissions: cloudhub.FolderPermissions{View: roles.EditorRoleName}},
+						{ID: "3", Organization: "default", Parent: "2"},
+					}, nil
+				},
+			},
+			ProtoboardsStore: &mocks.ProtoboardsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Protoboard, error) {
+					return nil, nil
+				},
+			},
+			TopologiesStore: &mocks.TopologiesStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Topology, error) {
+					return nil, nil
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	searchAs := func(role string) []string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/search?q=disk", nil)
+		ctx := context.WithValue(r.Context(), organizations.ContextKey, "default")
+		r = r.WithContext(context.WithValue(ctx, roles.ContextKey, role))
+		s.Search(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Search() status = %d", w.Code)
+		}
+		var res searchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, h := range res.Hits {
+			ids = append(ids, h.Kind+":"+h.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	// the dashboards, cells and templates of folders the role may not view are not found
+	if got, want := searchAs(roles.ViewerRoleName), []string{"dashboard:1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search() of a viewer = %v, want %v", got, want)
+	}
+	if got, want := searchAs(roles.EditorRoleName), []string{"cell:2/c1", "dashboard:1", "dashboard:2", "dashboard:3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Search() of an editor = %v, want %v", got, want)
+	}
+}
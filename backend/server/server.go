@@ -32,6 +32,9 @@ import (
 	"github.com/snetsystems/cloudhub/backend/kv/etcd"
 	clog "github.com/snetsystems/cloudhub/backend/log"
 	"github.com/snetsystems/cloudhub/backend/oauth2"
+	"github.com/snetsystems/cloudhub/backend/querycache"
+	"github.com/snetsystems/cloudhub/backend/reports"
+	"github.com/snetsystems/cloudhub/backend/roles"
 	"github.com/snetsystems/cloudhub/backend/server/config"
 )
 
@@ -161,6 +164,22 @@ type Server struct {
 	AI map[string]string `long:"ai" description:"The Information to access to cloudhub AI. '--ai=docker-path:{specifies the path to the Docker Compose file used for restarting the Logstash container} --ai=docker-cmd:{docker restart command} --ai=logstash-path:{The logstash-path variable is used to specify the directory where your Logstash pipeline configuration} --ai=prediction-regex:{parsing tickScript}'. E.g. via environment variable" env:"AI" env-delim:","`
 
 	TemplatesPath string `long:"template-path" description:"Path to directory of config template (/usr/share/cloudhub/cloudhub-templates)" env:"TEMPLATES_PATH" default:"templates"`
+
+	MaxRevisions int `long:"max-revisions" description:"Maximum number of revisions kept for each dashboard and topology. 0 keeps every revision." env:"MAX_REVISIONS" default:"50"`
+
+	SMTPHost     string `long:"smtp-host" description:"Host of the SMTP server sending the reports of dashboards by e-mail" env:"SMTP_HOST"`
+	SMTPPort     int    `long:"smtp-port" description:"Port of the SMTP server" env:"SMTP_PORT" default:"25"`
+	SMTPUsername string `long:"smtp-username" description:"Username to authenticate to the SMTP server" env:"SMTP_USERNAME"`
+	SMTPPassword string `long:"smtp-password" description:"Password to authenticate to the SMTP server" env:"SMTP_PASSWORD"`
+	SMTPFrom     string `long:"smtp-from" description:"Sender address of the reports sent by e-mail" env:"SMTP_FROM"`
+
+	TrashRetention time.Duration `long:"trash-retention" description:"Duration for which deleted dashboards, topologies, network devices and CSP are kept in the trash. 0 keeps them until removed by hand." env:"TRASH_RETENTION" default:"720h"`
+
+	ViewerQueryPolicy map[string]string `long:"viewer-query-policy" description:"Limits of the InfluxQL queries of viewers, overriding the defaults. 'default-range' bounds the queries without time condition, 'max-range' is the longest time range, 'max-points' and 'max-series' cap the LIMIT and SLIMIT, 'timeout' bounds the time to run a query, 0 meaning unlimited. E.g. via flags: '--viewer-query-policy=max-range:720h --viewer-query-policy=timeout:10s'. E.g. via environment variable: 'export VIEWER_QUERY_POLICY=max-range:720h,timeout:10s'" env:"VIEWER_QUERY_POLICY" env-delim:","`
+	EditorQueryPolicy map[string]string `long:"editor-query-policy" description:"Limits of the InfluxQL queries of editors, overriding the defaults. The limits are the ones of --viewer-query-policy. E.g. via environment variable: 'export EDITOR_QUERY_POLICY=max-points:100000'" env:"EDITOR_QUERY_POLICY" env-delim:","`
+	AdminQueryPolicy  map[string]string `long:"admin-query-policy" description:"Limits of the InfluxQL queries of admins, overriding the defaults. The limits are the ones of --viewer-query-policy. E.g. via environment variable: 'export ADMIN_QUERY_POLICY=timeout:5m'" env:"ADMIN_QUERY_POLICY" env-delim:","`
+
+	QueryCacheSize int `long:"query-cache-size" description:"Maximum size in megabytes of the results of the queries proxied to InfluxDB cached for the clients sending identical queries, such as the browsers of a dashboard. 0 disables the cache." env:"QUERY_CACHE_SIZE" default:"64"`
 }
 
 func provide(p oauth2.Provider, m oauth2.Mux, ok func() error) func(func(oauth2.Provider, oauth2.Mux)) {
@@ -669,10 +688,36 @@ func (s *Server) Serve(ctx context.Context) {
 		s.AddonURLs,
 		s.AddonTokens,
 		osp,
+		s.MaxRevisions,
 	)
 	service.SuperAdminProviderGroups = superAdminProviderGroups{
 		auth0: s.Auth0SuperAdminOrg,
 	}
+	service.Mailer = &reports.Mailer{
+		Host:     s.SMTPHost,
+		Port:     s.SMTPPort,
+		Username: s.SMTPUsername,
+		Password: s.SMTPPassword,
+		From:     s.SMTPFrom,
+	}
+
+	if s.QueryCacheSize > 0 {
+		service.QueryCache = querycache.New(int64(s.QueryCacheSize) << 20)
+	}
+
+	queryPolicies, err := QueryPolicies(map[string]map[string]string{
+		roles.ViewerRoleName: s.ViewerQueryPolicy,
+		roles.EditorRoleName: s.EditorQueryPolicy,
+		roles.AdminRoleName:  s.AdminQueryPolicy,
+	})
+	if err != nil {
+		logger.
+			WithField("component", "server").
+			WithField("query-policy", "invalid").
+			Error(err)
+		return
+	}
+	service.QueryPolicies = queryPolicies
 
 	service.Env = cloudhub.Environment{
 		TelegrafSystemInterval: s.TelegrafSystemInterval,
@@ -774,6 +819,16 @@ func (s *Server) Serve(ctx context.Context) {
 	}
 	httpServer.SetKeepAlivesEnabled(true)
 
+	if s.TrashRetention > 0 {
+		go runAsLeader(ctx, service.coordinator(), trashPurgeElection, logger, func(ctx context.Context) {
+			purgeTrash(ctx, service.Store, s.TrashRetention, logger)
+		})
+	}
+	go runAsLeader(ctx, service.coordinator(), snapshotPurgeElection, logger, func(ctx context.Context) {
+		purgeSnapshots(ctx, service.Store, logger)
+	})
+	go runAsLeader(ctx, service.coordinator(), reportsElection, logger, service.runScheduledReports)
+
 	// Not in cloudhub
 	// if !s.ReportingDisabled {
 	// 	go reportUsageStats(s.BuildInfo, logger)
@@ -840,9 +895,10 @@ func openService(
 	addonURLs map[string]string,
 	addonTokens map[string]string,
 	osp OSP,
+	maxRevisions int,
 ) Service {
 
-	svc, err := kv.NewService(ctx, db, kv.WithLogger(logger))
+	svc, err := kv.NewService(ctx, db, kv.WithLogger(logger), kv.WithMaxRevisions(maxRevisions))
 	if err != nil {
 		logger.Error("Unable to create kv service", err)
 		os.Exit(1)
@@ -880,7 +936,7 @@ func openService(
 		os.Exit(1)
 	}
 
-	protoboards, err := builder.Protoboards.Build()
+	protoboards, err := builder.Protoboards.Build(svc.ProtoboardsStore())
 	if err != nil {
 		logger.
 			WithField("component", "Protoboards").
@@ -896,6 +952,12 @@ func openService(
 		os.Exit(1)
 	}
 
+	// stores shared by several instances coordinate their work, others run it in this process only
+	coordinator, ok := db.(cloudhub.Coordinator)
+	if !ok {
+		coordinator = kv.NewLocalCoordinator()
+	}
+
 	return Service{
 		TimeSeriesClient: &InfluxClient{},
 		Store: &Store{
@@ -917,6 +979,14 @@ func openService(
 			MLNxRstStore:            svc.MLNxRstStore(),
 			DLNxRstStore:            svc.DLNxRstStore(),
 			DLNxRstStgStore:         svc.DLNxRstStgStore(),
+			RevisionsStore:          svc.RevisionsStore(),
+			TrashStore:              svc.TrashStore(),
+			IntegrityChecker:        svc.IntegrityChecker(),
+			JobsStore:               svc.JobsStore(),
+			OrgTemplatesStore:       svc.OrgTemplatesStore(),
+			ReportsStore:            svc.ReportsStore(),
+			SnapshotsStore:          svc.SnapshotsStore(),
+			FoldersStore:            svc.FoldersStore(),
 		},
 		Logger:                 logger,
 		UseAuth:                useAuth,
@@ -931,6 +1001,8 @@ func openService(
 		AddonURLs:              addonURLs,
 		AddonTokens:            addonTokens,
 		OSP:                    osp,
+		Coordinator:            coordinator,
+		SearchIndex:            NewSearchIndex(svc.ChangeFeed()),
 	}
 }
 
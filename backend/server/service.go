@@ -5,6 +5,8 @@ import (
 
 	cloudhub "github.com/snetsystems/cloudhub/backend"
 	"github.com/snetsystems/cloudhub/backend/influx"
+	"github.com/snetsystems/cloudhub/backend/querycache"
+	"github.com/snetsystems/cloudhub/backend/reports"
 )
 
 // Service handles REST calls to the persistence
@@ -27,6 +29,11 @@ type Service struct {
 	AddonTokens              map[string]string // Tokens to access to Addon Features API, as passed in via CLI/ENV
 	OSP                      OSP
 	InternalENV              cloudhub.InternalEnvironment
+	Coordinator              cloudhub.Coordinator          // Coordinator runs background work once across the instances sharing the store
+	Mailer                   *reports.Mailer               // Mailer sends reports by e-mail
+	SearchIndex              *SearchIndex                  // SearchIndex is the index of the resources searched, built on each search if nil
+	QueryCache               *querycache.Cache             // QueryCache caches the results of the queries proxied to InfluxDB, disabled if nil
+	QueryPolicies            map[string]influx.QueryPolicy // QueryPolicies are the limits of the queries proxied to InfluxDB per role, the defaults if nil
 }
 
 type superAdminProviderGroups struct {
@@ -0,0 +1,515 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/render"
+)
+
+// Validity of the share links of snapshots, by default and at most
+const (
+	defaultSnapshotExpiry = 7 * 24 * time.Hour
+	maxSnapshotExpiry     = 90 * 24 * time.Hour
+)
+
+// errFluxNotCaptured is the error of the cells of snapshots with flux queries
+const errFluxNotCaptured = "flux queries are not captured"
+
+// snapshotPurgeInterval is how often expired snapshots are purged
+const snapshotPurgeInterval = time.Hour
+
+// maxSnapshotResultsSize is the most bytes of results captured in a snapshot,
+// which is stored as a single record
+var maxSnapshotResultsSize = 1 << 20
+
+// errSnapshotTooLarge is returned when the results of a snapshot exceed maxSnapshotResultsSize
+var errSnapshotTooLarge = errors.New("results of the snapshot are too large, capture a shorter time range")
+
+type snapshotRequest struct {
+	Name      string            `json:"name"`
+	Lower     string            `json:"lower"`
+	Upper     string            `json:"upper"`
+	Variables map[string]string `json:"variables"`
+	ExpiresIn string            `json:"expiresIn"`
+}
+
+type snapshotLinks struct {
+	Self   string `json:"self"`             // Self link mapping to this resource
+	Public string `json:"public,omitempty"` // Public link to share the snapshot, only known when it is created
+}
+
+type snapshotResponse struct {
+	cloudhub.Snapshot
+	Expired bool          `json:"expired"`
+	Links   snapshotLinks `json:"links"`
+}
+
+type snapshotsResponse struct {
+	Links     selfLinks          `json:"links"`
+	Snapshots []snapshotResponse `json:"snapshots"`
+}
+
+// publicSnapshotResponse is a snapshot as seen through its share link
+type publicSnapshotResponse struct {
+	Name      string                  `json:"name"`
+	Dashboard cloudhub.Dashboard      `json:"dashboard"`
+	Lower     time.Time               `json:"lower"`
+	Upper     time.Time               `json:"upper"`
+	Cells     []cloudhub.SnapshotCell `json:"cells"`
+	ExpiresAt time.Time               `json:"expiresAt"`
+}
+
+func newSnapshotResponse(s cloudhub.Snapshot, now time.Time) snapshotResponse {
+	if s.Cells == nil {
+		s.Cells = []cloudhub.SnapshotCell{}
+	}
+	return snapshotResponse{
+		Snapshot: s,
+		Expired:  !now.Before(s.ExpiresAt),
+		Links: snapshotLinks{
+			Self: fmt.Sprintf("/cloudhub/v1/snapshots/%s", s.ID),
+		},
+	}
+}
+
+// snapshotToken returns a new random token of a share link and its hash
+func snapshotToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, snapshotTokenHash(token), nil
+}
+
+func snapshotTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// captureDashboard returns the copy of a dashboard kept in its snapshots: the
+// templates resolved only have their selected values and the queries have no source
+func captureDashboard(dash cloudhub.Dashboard, resolved []cloudhub.Template) cloudhub.Dashboard {
+	templates := append([]cloudhub.Template(nil), resolved...)
+	for i, t := range templates {
+		if v, ok := selectedTemplateValue(t); ok {
+			t.Values = []cloudhub.TemplateValue{v}
+		} else {
+			t.Values = []cloudhub.TemplateValue{}
+		}
+		t.Query = nil
+		templates[i] = t
+	}
+	dash.Templates = templates
+
+	cells := make([]cloudhub.DashboardCell, len(dash.Cells))
+	for i, cell := range dash.Cells {
+		queries := make([]cloudhub.DashboardQuery, len(cell.Queries))
+		for j, q := range cell.Queries {
+			q.Source = ""
+			queries[j] = q
+		}
+		cell.Queries = queries
+		cells[i] = cell
+	}
+	dash.Cells = cells
+	return dash
+}
+
+func selectedTemplateValue(t cloudhub.Template) (cloudhub.TemplateValue, bool) {
+	for _, v := range t.Values {
+		if v.Selected {
+			return v, true
+		}
+	}
+	return cloudhub.TemplateValue{}, false
+}
+
+// captureCells runs the queries of the cells of a dashboard over the time range
+// and returns their results. Cells whose results could not be captured have the
+// reason as error. Results beyond maxSnapshotResultsSize are errSnapshotTooLarge.
+func (s *Service) captureCells(ctx context.Context, dash cloudhub.Dashboard, templates []cloudhub.Template, lower, upper time.Time) ([]cloudhub.SnapshotCell, error) {
+	var size int
+	cells := make([]cloudhub.SnapshotCell, len(dash.Cells))
+	for i, cell := range dash.Cells {
+		cells[i] = cloudhub.SnapshotCell{CellID: cell.ID, Results: []json.RawMessage{}}
+		for _, q := range cell.Queries {
+			if q.Type == "flux" {
+				cells[i] = cloudhub.SnapshotCell{CellID: cell.ID, Results: []json.RawMessage{}, Error: errFluxNotCaptured}
+				break
+			}
+			command := render.Query(q.Command, templates, lower, upper, defaultRenderWidth)
+			b, _, err := s.queryResults(ctx, q.Source, command)
+			if err != nil {
+				cells[i] = cloudhub.SnapshotCell{CellID: cell.ID, Results: []json.RawMessage{}, Error: err.Error()}
+				break
+			}
+			if size += len(b); size > maxSnapshotResultsSize {
+				return nil, errSnapshotTooLarge
+			}
+			cells[i].Results = append(cells[i].Results, b)
+		}
+	}
+	return cells, nil
+}
+
+// NewDashboardSnapshot captures a dashboard with the results of its queries over a
+// time range into a snapshot shared through a secret link, returned only once
+func (s *Service) NewDashboardSnapshot(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+
+	var req snapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		invalidJSON(w, s.Logger)
+		return
+	}
+
+	now := time.Now().UTC()
+	lower, upper, err := timeRange(req.Lower, req.Upper, now)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	expiry := defaultSnapshotExpiry
+	if req.ExpiresIn != "" {
+		expiry, err = time.ParseDuration(req.ExpiresIn)
+		if err != nil || expiry <= 0 || expiry > maxSnapshotExpiry {
+			invalidData(w, fmt.Errorf("expiresIn must be a positive duration of at most %s", maxSnapshotExpiry), s.Logger)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	dash, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		req.Name = dash.Name
+	}
+
+	token, hash, err := snapshotToken()
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+	templates, err := s.resolveTemplates(ctx, dash.Templates, req.Variables, "", lower, upper)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+	cells, err := s.captureCells(ctx, dash, templates, lower, upper)
+	if err != nil {
+		Error(w, http.StatusRequestEntityTooLarge, err.Error(), s.Logger)
+		return
+	}
+	snapshot := &cloudhub.Snapshot{
+		Name:      req.Name,
+		Dashboard: captureDashboard(dash, templates),
+		Lower:     lower.UTC(),
+		Upper:     upper.UTC(),
+		Cells:     cells,
+		TokenHash: hash,
+		ExpiresAt: now.Add(expiry),
+	}
+	if user, ok := hasUserContext(ctx); ok {
+		snapshot.CreatedBy = user.Name
+	}
+
+	res, err := s.Store.Snapshots(ctx).Add(ctx, snapshot)
+	if err != nil {
+		msg := fmt.Errorf("Error storing snapshot of dashboard %d: %v", id, err)
+		unknownErrorWithMessage(w, msg, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgSnapshotCreated.String(), res.Name, dash.Name)
+	s.logRegistration(ctx, "Snapshots", msg)
+
+	sr := newSnapshotResponse(*res, now)
+	sr.Links.Public = "/public/snapshots/" + token
+	location(w, sr.Links.Self)
+	encodeJSON(w, http.StatusCreated, sr, s.Logger)
+}
+
+// Snapshots returns all snapshots of the organization, without their results,
+// but the ones of dashboards in folders the role of the user may not view
+func (s *Service) Snapshots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	snapshots, err := s.Store.Snapshots(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading snapshots", s.Logger)
+		return
+	}
+	visible, err := s.folderVisible(ctx)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	now := time.Now()
+	res := snapshotsResponse{
+		Links: selfLinks{
+			Self: "/cloudhub/v1/snapshots",
+		},
+		Snapshots: []snapshotResponse{},
+	}
+	for _, snapshot := range snapshots {
+		if !visible(snapshot.Dashboard.Folder) {
+			continue
+		}
+		snapshot.Cells = nil
+		res.Snapshots = append(res.Snapshots, newSnapshotResponse(snapshot, now))
+	}
+
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// visibleSnapshot returns a snapshot unless its dashboard is in a folder the
+// role of the user may not view, responding with the error otherwise
+func (s *Service) visibleSnapshot(ctx context.Context, w http.ResponseWriter, id string) (*cloudhub.Snapshot, bool) {
+	snapshot, err := s.Store.Snapshots(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return nil, false
+	}
+	visible, err := s.folderVisible(ctx)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return nil, false
+	}
+	if !visible(snapshot.Dashboard.Folder) {
+		notFound(w, id, s.Logger)
+		return nil, false
+	}
+	return snapshot, true
+}
+
+// SnapshotID returns a single specified snapshot with its results
+func (s *Service) SnapshotID(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	snapshot, ok := s.visibleSnapshot(ctx, w, id)
+	if !ok {
+		return
+	}
+
+	encodeJSON(w, http.StatusOK, newSnapshotResponse(*snapshot, time.Now()), s.Logger)
+}
+
+// RemoveSnapshot deletes a snapshot, revoking its share link
+func (s *Service) RemoveSnapshot(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	snapshot, ok := s.visibleSnapshot(ctx, w, id)
+	if !ok {
+		return
+	}
+
+	if err := s.Store.Snapshots(ctx).Delete(ctx, snapshot); err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgSnapshotDeleted.String(), snapshot.Name)
+	s.logRegistration(ctx, "Snapshots", msg)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publicSnapshot returns the snapshot of the token of a share link. Snapshots
+// whose links have expired are deleted.
+func (s *Service) publicSnapshot(ctx context.Context, token string) (*cloudhub.Snapshot, error) {
+	ctx = serverContext(ctx)
+	snapshot, err := s.Store.Snapshots(ctx).GetByTokenHash(ctx, snapshotTokenHash(token))
+	if err != nil {
+		return nil, err
+	}
+	if !time.Now().Before(snapshot.ExpiresAt) {
+		if err := s.Store.Snapshots(ctx).Delete(ctx, snapshot); err != nil {
+			s.Logger.Error("Unable to delete expired snapshot ", snapshot.ID, ": ", err)
+		}
+		return nil, cloudhub.ErrSnapshotNotFound
+	}
+	return snapshot, nil
+}
+
+// purgeSnapshots periodically removes the snapshots whose share links have expired
+func purgeSnapshots(ctx context.Context, store DataStore, logger cloudhub.Logger) {
+	l := logger.WithField("component", "snapshots")
+	serverCtx := serverContext(ctx)
+
+	ticker := time.NewTicker(snapshotPurgeInterval)
+	defer ticker.Stop()
+	for {
+		n, err := purgeExpiredSnapshots(serverCtx, store.Snapshots(serverCtx), time.Now())
+		if err != nil {
+			l.Error("Unable to purge snapshots: ", err)
+		} else if n > 0 {
+			l.Info("Purged ", n, " expired snapshots")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpiredSnapshots removes the snapshots expired at now and returns how many were removed
+func purgeExpiredSnapshots(ctx context.Context, store cloudhub.SnapshotsStore, now time.Time) (int, error) {
+	snapshots, err := store.All(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	for i := range snapshots {
+		if now.Before(snapshots[i].ExpiresAt) {
+			continue
+		}
+		if err := store.Delete(ctx, &snapshots[i]); err != nil && err != cloudhub.ErrSnapshotNotFound {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// publicHeaders keeps share links out of caches, referrers and search engines
+func publicHeaders(w http.ResponseWriter) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("X-Robots-Tag", "noindex")
+}
+
+// PublicSnapshot returns the snapshot of a share link, without login. Unknown,
+// revoked and expired links are not found.
+func (s *Service) PublicSnapshot(w http.ResponseWriter, r *http.Request) {
+	publicHeaders(w)
+	token := httprouter.GetParamFromContext(r.Context(), "token")
+	snapshot, err := s.publicSnapshot(r.Context(), token)
+	if err != nil {
+		Error(w, http.StatusNotFound, "Snapshot not found", s.Logger)
+		return
+	}
+
+	dash := snapshot.Dashboard
+	dash.ID = 0
+	dash.Organization = ""
+	encodeJSON(w, http.StatusOK, publicSnapshotResponse{
+		Name:      snapshot.Name,
+		Dashboard: dash,
+		Lower:     snapshot.Lower,
+		Upper:     snapshot.Upper,
+		Cells:     snapshot.Cells,
+		ExpiresAt: snapshot.ExpiresAt,
+	}, s.Logger)
+}
+
+// PublicSnapshotCellRender draws a cell of the snapshot of a share link as a PNG or
+// SVG image with the results captured. The query parameters are the ones of
+// DashboardCellRender but the time range, which is the one of the snapshot.
+func (s *Service) PublicSnapshotCellRender(w http.ResponseWriter, r *http.Request) {
+	publicHeaders(w)
+	ctx := r.Context()
+	snapshot, err := s.publicSnapshot(ctx, httprouter.GetParamFromContext(ctx, "token"))
+	if err != nil {
+		Error(w, http.StatusNotFound, "Snapshot not found", s.Logger)
+		return
+	}
+
+	params := r.URL.Query()
+	params.Del("lower")
+	params.Del("upper")
+	format, opts, err := renderOptions(params, snapshot.Upper)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	opts.Lower, opts.Upper = snapshot.Lower, snapshot.Upper
+
+	cid := httprouter.GetParamFromContext(ctx, "cid")
+	var cell *cloudhub.DashboardCell
+	for i := range snapshot.Dashboard.Cells {
+		if snapshot.Dashboard.Cells[i].ID == cid {
+			cell = &snapshot.Dashboard.Cells[i]
+		}
+	}
+	var captured *cloudhub.SnapshotCell
+	for i := range snapshot.Cells {
+		if snapshot.Cells[i].CellID == cid {
+			captured = &snapshot.Cells[i]
+		}
+	}
+	if cell == nil || captured == nil {
+		notFound(w, cid, s.Logger)
+		return
+	}
+	if !render.Supported(cell.Type) {
+		invalidData(w, fmt.Errorf("cells of type %s are not rendered", cell.Type), s.Logger)
+		return
+	}
+	if captured.Error != "" {
+		invalidData(w, fmt.Errorf("results of cell %s were not captured: %s", cid, captured.Error), s.Logger)
+		return
+	}
+
+	results := make([]render.Results, len(captured.Results))
+	for i, b := range captured.Results {
+		if results[i], err = render.ParseResults(b); err != nil {
+			unknownErrorWithMessage(w, err, s.Logger)
+			return
+		}
+	}
+	chart, err := render.Render(*cell, results, opts)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+	var b bytes.Buffer
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		err = chart.SVG(&b)
+	} else {
+		err = chart.PNG(&b)
+	}
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
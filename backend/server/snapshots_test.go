@@ -0,0 +1,307 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+func TestService_DashboardSnapshots(t *testing.T) {
+	dashboard := cloudhub.Dashboard{
+		ID:           1,
+		Name:         "Servers",
+		Organization: "default",
+		Cells: []cloudhub.DashboardCell{
+			{
+				ID:   "c1",
+				Name: "CPU",
+				Type: "line",
+				Queries: []cloudhub.DashboardQuery{
+					{Command: `SELECT mean("usage_user") FROM "cpu" WHERE "host" = :host: AND time > :dashboardTime: GROUP BY time(:interval:)`, Source: "/cloudhub/v1/sources/1", Type: "influxql"},
+				},
+			},
+			{
+				ID:      "c2",
+				Name:    "Memory",
+				Type:    "line",
+				Queries: []cloudhub.DashboardQuery{{Command: `from(bucket: "telegraf")`, Type: "flux"}},
+			},
+		},
+		Templates: []cloudhub.Template{
+			{
+				TemplateVar: cloudhub.TemplateVar{Var: ":host:", Values: []cloudhub.TemplateValue{
+					{Value: "web01", Type: "tagValue", Selected: true},
+					{Value: "web02", Type: "tagValue"},
+				}},
+				Type:  "tagValues",
+				Query: &cloudhub.TemplateQuery{Command: `SHOW TAG VALUES WITH KEY = "host"`},
+			},
+		},
+	}
+
+	var snapshots []cloudhub.Snapshot
+	var queries []string
+	s := &Service{
+		Store: &mocks.Store{
+			DashboardsStore: &mocks.DashboardsStore{
+				GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+					if id != 1 {
+						return cloudhub.Dashboard{}, cloudhub.ErrDashboardNotFound
+					}
+					return dashboard, nil
+				},
+			},
+			SnapshotsStore: &mocks.SnapshotsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Snapshot, error) {
+					return snapshots, nil
+				},
+				AddF: func(ctx context.Context, snap *cloudhub.Snapshot) (*cloudhub.Snapshot, error) {
+					snap.ID = "1"
+					snapshots = append(snapshots, *snap)
+					return snap, nil
+				},
+				GetF: func(ctx context.Context, id string) (*cloudhub.Snapshot, error) {
+					for _, snap := range snapshots {
+						if snap.ID == id {
+							return &snap, nil
+						}
+					}
+					return nil, cloudhub.ErrSnapshotNotFound
+				},
+				GetByTokenHashF: func(ctx context.Context, hash string) (*cloudhub.Snapshot, error) {
+					for _, snap := range snapshots {
+						if snap.TokenHash == hash {
+							return &snap, nil
+						}
+					}
+					return nil, cloudhub.ErrSnapshotNotFound
+				},
+				DeleteF: func(ctx context.Context, snap *cloudhub.Snapshot) error {
+					snapshots = nil
+					return nil
+				},
+			},
+			SourcesStore: &mocks.SourcesStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Source, error) {
+					return []cloudhub.Source{{ID: 1, Default: true}}, nil
+				},
+				GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+					if id != 1 {
+						return cloudhub.Source{}, cloudhub.ErrSourceNotFound
+					}
+					return cloudhub.Source{ID: 1}, nil
+				},
+			},
+		},
+		TimeSeriesClient: &mocks.TimeSeries{
+			ConnectF: func(context.Context, *cloudhub.Source) error {
+				return nil
+			},
+			QueryF: func(ctx context.Context, q cloudhub.Query) (cloudhub.Response, error) {
+				if strings.HasPrefix(q.Command, "SHOW TAG VALUES") {
+					return mocks.NewResponse(`[{"series": [{"name": "cpu", "columns": ["key", "value"], "values": [["host", "web02"], ["host", "web01"]]}]}]`, nil), nil
+				}
+				queries = append(queries, q.Command)
+				return mocks.NewResponse(`[{"series": [{"name": "cpu", "columns": ["time", "mean"], "values": [[1699992000000, 10], [1699995600000, 20]]}]}]`, nil), nil
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	public := func(path string) *httptest.ResponseRecorder {
+		t.Helper()
+		parts := strings.Split(strings.TrimPrefix(path, "/public/snapshots/"), "/")
+		params := httprouter.Params{{Key: "token", Value: parts[0]}}
+		if len(parts) > 2 {
+			params = append(params, httprouter.Param{Key: "cid", Value: parts[2]})
+		}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://any.url"+path, nil)
+		r = r.WithContext(httprouter.WithParams(context.Background(), params))
+		if len(parts) > 2 {
+			s.PublicSnapshotCellRender(w, r)
+		} else {
+			s.PublicSnapshot(w, r)
+		}
+		return w
+	}
+
+	// capture
+	body := `{"lower": "2023-11-14T00:00:00Z", "upper": "2023-11-15T00:00:00Z", "variables": {":host:": "web02"}, "expiresIn": "24h"}`
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest("POST", "http://any.url/cloudhub/v1/dashboards/1/snapshots", strings.NewReader(body))
+	r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}}))
+	s.NewDashboardSnapshot(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("NewDashboardSnapshot() status = %d, body %s", w.Code, w.Body.String())
+	}
+	var created snapshotResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Name != "Servers" || !strings.HasPrefix(created.Links.Public, "/public/snapshots/") {
+		t.Fatalf("NewDashboardSnapshot() = %#v", created)
+	}
+	if len(queries) != 1 || !strings.Contains(queries[0], `"host" = 'web02'`) || !strings.Contains(queries[0], "'2023-11-14T00:00:00Z'") {
+		t.Errorf("NewDashboardSnapshot() queries = %v", queries)
+	}
+	stored := snapshots[0]
+	if stored.TokenHash == "" || strings.Contains(created.Links.Public, stored.TokenHash) {
+		t.Errorf("NewDashboardSnapshot() must only store the hash of the token")
+	}
+	if stored.Cells[0].Error != "" || len(stored.Cells[0].Results) != 1 || stored.Cells[1].Error != errFluxNotCaptured {
+		t.Errorf("NewDashboardSnapshot() cells = %#v", stored.Cells)
+	}
+
+	// public access
+	w = public(created.Links.Public)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PublicSnapshot() status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("PublicSnapshot() may be cached")
+	}
+	var shared publicSnapshotResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &shared); err != nil {
+		t.Fatal(err)
+	}
+	if shared.Dashboard.ID != 0 || shared.Dashboard.Organization != "" || shared.Dashboard.Cells[0].Queries[0].Source != "" {
+		t.Errorf("PublicSnapshot() exposes the dashboard or its sources: %#v", shared.Dashboard)
+	}
+	if tmpl := shared.Dashboard.Templates[0]; tmpl.Query != nil || len(tmpl.Values) != 1 || tmpl.Values[0].Value != "web02" {
+		t.Errorf("PublicSnapshot() templates = %#v", tmpl)
+	}
+	if w = public(created.Links.Public + "/cells/c1/render?format=svg"); w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("PublicSnapshotCellRender() status = %d, body %s", w.Code, w.Body.String())
+	}
+	if w = public(created.Links.Public + "/cells/c2/render"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("PublicSnapshotCellRender() of a cell not captured status = %d", w.Code)
+	}
+	if len(queries) != 1 {
+		t.Errorf("public snapshots must not query sources, queried %v", queries)
+	}
+	if w = public("/public/snapshots/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("PublicSnapshot() of an unknown token status = %d", w.Code)
+	}
+
+	// expiry
+	snapshots[0].ExpiresAt = time.Now().Add(-time.Minute)
+	if w = public(created.Links.Public); w.Code != http.StatusNotFound || len(snapshots) != 0 {
+		t.Errorf("PublicSnapshot() of an expired snapshot status = %d, snapshots %d", w.Code, len(snapshots))
+	}
+
+	// revocation
+	snapshots = []cloudhub.Snapshot{stored}
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("DELETE", "http://any.url/cloudhub/v1/snapshots/1", nil)
+	r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}}))
+	s.RemoveSnapshot(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("RemoveSnapshot() status = %d", w.Code)
+	}
+	if w = public(created.Links.Public); w.Code != http.StatusNotFound {
+		t.Errorf("PublicSnapshot() of a revoked snapshot status = %d", w.Code)
+	}
+
+	// results too large to be stored
+	defer func(max int) { maxSnapshotResultsSize = max }(maxSnapshotResultsSize)
+	maxSnapshotResultsSize = 16
+	snapshots = nil
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "http://any.url/cloudhub/v1/dashboards/1/snapshots", strings.NewReader(body))
+	r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}}))
+	s.NewDashboardSnapshot(w, r)
+	if w.Code != http.StatusRequestEntityTooLarge || len(snapshots) != 0 {
+		t.Errorf("NewDashboardSnapshot() of too large results status = %d, snapshots %d", w.Code, len(snapshots))
+	}
+}
+
+func Test_purgeExpiredSnapshots(t *testing.T) {
+	now := time.Now()
+	var deleted []string
+	store := &mocks.SnapshotsStore{
+		AllF: func(ctx context.Context) ([]cloudhub.Snapshot, error) {
+			return []cloudhub.Snapshot{
+				{ID: "1", ExpiresAt: now.Add(-time.Hour)},
+				{ID: "2", ExpiresAt: now.Add(time.Hour)},
+				{ID: "3", ExpiresAt: now},
+			}, nil
+		},
+		DeleteF: func(ctx context.Context, snap *cloudhub.Snapshot) error {
+			deleted = append(deleted, snap.ID)
+			return nil
+		},
+	}
+
+	n, err := purgeExpiredSnapshots(context.Background(), store, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || strings.Join(deleted, ",") != "1,3" {
+		t.Errorf("purgeExpiredSnapshots() = %d, deleted %v, want the snapshots 1 and 3", n, deleted)
+	}
+}
+
+func TestService_Snapshots_FolderPermissions(t *testing.T) {
+	snapshots := []cloudhub.Snapshot{
+		{ID: "1", Name: "servers", Organization: "default", Dashboard: cloudhub.Dashboard{ID: 1}},
+		{ID: "2", Name: "secrets", Organization: "default", Dashboard: cloudhub.Dashboard{ID: 2, Folder: "2"}},
+	}
+	s := &Service{
+		Store: &mocks.Store{
+			SnapshotsStore: &mocks.SnapshotsStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Snapshot, error) {
+					return snapshots, nil
+				},
+				GetF: func(ctx context.Context, id string) (*cloudhub.Snapshot, error) {
+					for _, snap := range snapshots {
+						if snap.ID == id {
+							return &snap, nil
+						}
+					}
+					return nil, cloudhub.ErrSnapshotNotFound
+				},
+			},
+			FoldersStore: &mocks.FoldersStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Folder, error) {
+					return []cloudhub.Folder{
+						{ID: "2", Organization: "default", Permissions: cloudhub.FolderPermissions{View: roles.AdminRoleName}},
+					}, nil
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+	asRole := func(r *http.Request, role string) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), roles.ContextKey, role))
+	}
+
+	w := httptest.NewRecorder()
+	s.Snapshots(w, asRole(httptest.NewRequest("GET", "http://any.url/cloudhub/v1/snapshots", nil), roles.EditorRoleName))
+	var res snapshotsResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Snapshots) != 1 || res.Snapshots[0].ID != "1" {
+		t.Errorf("Snapshots() of an editor = %#v, want the snapshot outside of the restricted folder", res.Snapshots)
+	}
+
+	for role, want := range map[string]int{roles.EditorRoleName: http.StatusNotFound, roles.AdminRoleName: http.StatusOK} {
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://any.url", nil)
+		r = r.WithContext(httprouter.WithParams(r.Context(), httprouter.Params{{Key: "id", Value: "2"}}))
+		s.SnapshotID(w, asRole(r, role))
+		if w.Code != want {
+			t.Errorf("SnapshotID() of a restricted snapshot for the %s role status = %d, want %d", role, w.Code, want)
+		}
+	}
+}
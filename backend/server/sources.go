@@ -160,6 +160,13 @@ func (s *Service) NewSource(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, dryRun := r.URL.Query()["dryRun"]; !dryRun {
+		if err := s.checkQuota(ctx, src.Organization, quotaSources, 1); err != nil {
+			quotaExceeded(w, err, s.Logger)
+			return
+		}
+	}
+
 	// By default the telegraf database will be telegraf
 	if src.Telegraf == "" {
 		src.Telegraf = "telegraf"
@@ -101,6 +101,14 @@ type DataStore interface {
 	MLNxRst(ctx context.Context) cloudhub.MLNxRstStore
 	DLNxRst(ctx context.Context) cloudhub.DLNxRstStore
 	DLNxRstStg(ctx context.Context) cloudhub.DLNxRstStgStore
+	Revisions(ctx context.Context) cloudhub.RevisionsStore
+	Trash(ctx context.Context) cloudhub.TrashStore
+	Integrity(ctx context.Context) cloudhub.IntegrityChecker
+	Jobs(ctx context.Context) cloudhub.JobsStore
+	OrgTemplates(ctx context.Context) cloudhub.OrgTemplatesStore
+	Reports(ctx context.Context) cloudhub.ReportsStore
+	Snapshots(ctx context.Context) cloudhub.SnapshotsStore
+	Folders(ctx context.Context) cloudhub.FoldersStore
 }
 
 // ensure that Store implements a DataStore
@@ -126,6 +134,14 @@ type Store struct {
 	MLNxRstStore            cloudhub.MLNxRstStore
 	DLNxRstStore            cloudhub.DLNxRstStore
 	DLNxRstStgStore         cloudhub.DLNxRstStgStore
+	RevisionsStore          cloudhub.RevisionsStore
+	TrashStore              cloudhub.TrashStore
+	IntegrityChecker        cloudhub.IntegrityChecker
+	JobsStore               cloudhub.JobsStore
+	OrgTemplatesStore       cloudhub.OrgTemplatesStore
+	ReportsStore            cloudhub.ReportsStore
+	SnapshotsStore          cloudhub.SnapshotsStore
+	FoldersStore            cloudhub.FoldersStore
 }
 
 // Sources returns a noop.SourcesStore if the context has no organization specified
@@ -182,13 +198,18 @@ func (s *Store) Users(ctx context.Context) cloudhub.UsersStore {
 }
 
 // Dashboards returns a noop.DashboardsStore if the context has no organization specified
-// and an organization.DashboardsStore otherwise.
+// and an organization.DashboardsStore otherwise. If a role is specified too, the
+// dashboards are restricted by the permissions of their folders.
 func (s *Store) Dashboards(ctx context.Context) cloudhub.DashboardsStore {
 	if isServer := hasServerContext(ctx); isServer {
 		return s.DashboardsStore
 	}
 	if org, ok := hasOrganizationContext(ctx); ok {
-		return organizations.NewDashboardsStore(s.DashboardsStore, org)
+		store := organizations.NewDashboardsStore(s.DashboardsStore, org)
+		if role, ok := hasRoleContext(ctx); ok && s.FoldersStore != nil {
+			return roles.NewDashboardsStore(store, organizations.NewFoldersStore(s.FoldersStore, org), role)
+		}
+		return store
 	}
 
 	return &noop.DashboardsStore{}
@@ -334,3 +355,107 @@ func (s *Store) DLNxRstStg(ctx context.Context) cloudhub.DLNxRstStgStore {
 
 	return &noop.DLNxRstStgStore{}
 }
+
+// Revisions returns a noop.RevisionsStore if the context has no organization specified
+// and an organization.RevisionsStore otherwise.
+func (s *Store) Revisions(ctx context.Context) cloudhub.RevisionsStore {
+	if isServer := hasServerContext(ctx); isServer {
+		return s.RevisionsStore
+	}
+	if org, ok := hasOrganizationContext(ctx); ok {
+		return organizations.NewRevisionsStore(s.RevisionsStore, org)
+	}
+
+	return &noop.RevisionsStore{}
+}
+
+// Trash returns a noop.TrashStore if the context has no organization specified
+// and an organization.TrashStore otherwise.
+func (s *Store) Trash(ctx context.Context) cloudhub.TrashStore {
+	if isServer := hasServerContext(ctx); isServer {
+		return s.TrashStore
+	}
+	if org, ok := hasOrganizationContext(ctx); ok {
+		return organizations.NewTrashStore(s.TrashStore, org)
+	}
+
+	return &noop.TrashStore{}
+}
+
+// Integrity returns the underlying IntegrityChecker if the context is a server
+// or super admin context, and a noop.IntegrityChecker otherwise.
+func (s *Store) Integrity(ctx context.Context) cloudhub.IntegrityChecker {
+	if isServer := hasServerContext(ctx); isServer {
+		return s.IntegrityChecker
+	}
+	if isSuperAdmin := hasSuperAdminContext(ctx); isSuperAdmin {
+		return s.IntegrityChecker
+	}
+
+	return &noop.IntegrityChecker{}
+}
+
+// Jobs returns the underlying JobsStore if the context is a server
+// or super admin context, and a noop.JobsStore otherwise.
+func (s *Store) Jobs(ctx context.Context) cloudhub.JobsStore {
+	if isServer := hasServerContext(ctx); isServer {
+		return s.JobsStore
+	}
+	if isSuperAdmin := hasSuperAdminContext(ctx); isSuperAdmin {
+		return s.JobsStore
+	}
+
+	return &noop.JobsStore{}
+}
+
+// OrgTemplates returns the underlying OrgTemplatesStore if the context is a server
+// or super admin context, and a noop.OrgTemplatesStore otherwise.
+func (s *Store) OrgTemplates(ctx context.Context) cloudhub.OrgTemplatesStore {
+	if isServer := hasServerContext(ctx); isServer {
+		return s.OrgTemplatesStore
+	}
+	if isSuperAdmin := hasSuperAdminContext(ctx); isSuperAdmin {
+		return s.OrgTemplatesStore
+	}
+
+	return &noop.OrgTemplatesStore{}
+}
+
+// Reports returns a noop.ReportsStore if the context has no organization specified
+// and an organization.ReportsStore otherwise.
+func (s *Store) Reports(ctx context.Context) cloudhub.ReportsStore {
+	if isServer := hasServerContext(ctx); isServer {
+		return s.ReportsStore
+	}
+	if org, ok := hasOrganizationContext(ctx); ok {
+		return organizations.NewReportsStore(s.ReportsStore, org)
+	}
+
+	return &noop.ReportsStore{}
+}
+
+// Snapshots returns a noop.SnapshotsStore if the context has no organization specified
+// and an organization.SnapshotsStore otherwise.
+func (s *Store) Snapshots(ctx context.Context) cloudhub.SnapshotsStore {
+	if isServer := hasServerContext(ctx); isServer {
+		return s.SnapshotsStore
+	}
+	if org, ok := hasOrganizationContext(ctx); ok {
+		return organizations.NewSnapshotsStore(s.SnapshotsStore, org)
+	}
+
+	return &noop.SnapshotsStore{}
+}
+
+// Folders returns a noop.FoldersStore if the context has no organization specified
+// and an organization.FoldersStore otherwise.
+func (s *Store) Folders(ctx context.Context) cloudhub.FoldersStore {
+	if isServer := hasServerContext(ctx); isServer {
+		return s.FoldersStore
+	}
+	if org, ok := hasOrganizationContext(ctx); ok {
+		return organizations.NewFoldersStore(s.FoldersStore, org)
+	}
+
+	return &noop.FoldersStore{}
+}
@@ -0,0 +1,211 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/influx"
+	"github.com/snetsystems/cloudhub/backend/render"
+	"github.com/snetsystems/cloudhub/backend/tempvars"
+)
+
+// templateValuesCache keeps the values of the queries of templates, per source
+var templateValuesCache = tempvars.NewCache(time.Minute)
+
+// fluxQueryTimeout bounds the flux queries of templates
+const fluxQueryTimeout = 30 * time.Second
+
+// templateSelections returns the values selected for template variables by the
+// query parameters named after them, e.g. ?:host:=web01
+func templateSelections(params url.Values) map[string]string {
+	selections := map[string]string{}
+	for name, values := range params {
+		if len(name) > 2 && strings.HasPrefix(name, ":") && strings.HasSuffix(name, ":") && len(values) > 0 {
+			selections[name] = values[0]
+		}
+	}
+	return selections
+}
+
+// templateSourceLink returns the link of the source of the source query parameter
+func templateSourceLink(params url.Values) string {
+	if id := params.Get("source"); id != "" {
+		return "/cloudhub/v1/sources/" + id
+	}
+	return ""
+}
+
+// resolveTemplates resolves the values of the templates of a dashboard as the UI
+// does, running their queries on the source of the link, or on the default source
+// of the organization, over the time range
+func (s *Service) resolveTemplates(ctx context.Context, templates []cloudhub.Template, selections map[string]string, link string, lower, upper time.Time) ([]cloudhub.Template, error) {
+	expand := func(query string, resolved []cloudhub.Template) string {
+		return render.Query(query, resolved, lower, upper, defaultRenderWidth)
+	}
+	return tempvars.Resolve(ctx, templates, selections, expand, s.templateFetcher(link))
+}
+
+// templateFetcher returns a fetcher of the values of the queries of templates on
+// the source of the link, which is only looked up when a query runs
+func (s *Service) templateFetcher(link string) tempvars.Fetcher {
+	var fetcher tempvars.Fetcher
+	return tempvars.FetcherFunc(func(ctx context.Context, query string, flux bool) ([]string, error) {
+		if fetcher == nil {
+			src, err := s.querySource(ctx, link)
+			if err != nil {
+				return nil, err
+			}
+			fetcher = templateValuesCache.Fetcher(strconv.Itoa(src.ID), s.sourceFetcher(src))
+		}
+		return fetcher.Fetch(ctx, query, flux)
+	})
+}
+
+// sourceFetcher returns a fetcher of the values of the queries of templates on src
+func (s *Service) sourceFetcher(src cloudhub.Source) tempvars.Fetcher {
+	return tempvars.FetcherFunc(func(ctx context.Context, query string, flux bool) ([]string, error) {
+		if flux {
+			b, err := fluxQuery(ctx, src, query)
+			if err != nil {
+				return nil, err
+			}
+			return tempvars.ParseFlux(b)
+		}
+
+		if !tempvars.IsMetaQuery(query) {
+			return nil, fmt.Errorf("%s is not a meta query", query)
+		}
+		ts, err := s.TimeSeries(src)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to connect to source %d: %v", src.ID, err)
+		}
+		if err = ts.Connect(ctx, &src); err != nil {
+			return nil, fmt.Errorf("Unable to connect to source %d: %v", src.ID, err)
+		}
+		res, err := ts.Query(ctx, cloudhub.Query{Command: query})
+		if err != nil {
+			return nil, fmt.Errorf("Error querying source %d: %v", src.ID, err)
+		}
+		b, err := res.MarshalJSON()
+		if err != nil {
+			return nil, fmt.Errorf("Error querying source %d: %v", src.ID, err)
+		}
+		return tempvars.ParseMetaQuery(query, b)
+	})
+}
+
+// fluxQuery runs a flux query on src and returns its annotated CSV results
+func fluxQuery(ctx context.Context, src cloudhub.Source, query string) ([]byte, error) {
+	u, err := url.Parse(singleJoiningSlash(src.URL, "/api/v2/query"))
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing flux url: %v", err)
+	}
+	params := u.Query()
+	params.Set("org", src.Username) // v2 organization name is stored in username
+	u.RawQuery = params.Encode()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query": query,
+		"dialect": map[string]interface{}{
+			"annotations": []string{"group", "datatype", "default"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if err := influx.DefaultAuthorization(&src).Set(req); err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Timeout:   fluxQueryTimeout,
+		Transport: influx.SharedTransport(src.InsecureSkipVerify),
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying source %d: %v", src.ID, err)
+	}
+	defer res.Body.Close()
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Error querying source %d: %v", src.ID, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		var e struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(b, &e)
+		if e.Error == "" {
+			e.Error = e.Message
+		}
+		return nil, fmt.Errorf("Error querying source %d: %s %s", src.ID, res.Status, e.Error)
+	}
+	return b, nil
+}
+
+// DashboardTemplateValues resolves the values of a template of a dashboard on the
+// server, running its query with the values of the templates it depends on. The
+// query parameters are:
+//
+//	source: the ID of the source of the queries; defaults to the default source
+//	lower, upper: the time range of :dashboardTime: and :upperDashboardTime:; defaults to the last hour
+//	:name:: the value to select of the template variable :name:, e.g. ?:host:=web01
+func (s *Service) DashboardTemplateValues(w http.ResponseWriter, r *http.Request) {
+	id, err := paramID("id", r)
+	if err != nil {
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
+		return
+	}
+	params := r.URL.Query()
+	lower, upper, err := timeRange(params.Get("lower"), params.Get("upper"), time.Now())
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	dash, err := s.Store.Dashboards(ctx).Get(ctx, cloudhub.DashboardID(id))
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+	tid := cloudhub.TemplateID(httprouter.GetParamFromContext(ctx, "tid"))
+	required, err := tempvars.Required(dash.Templates, tid)
+	if err == cloudhub.ErrTemplateNotFound {
+		notFound(w, tid, s.Logger)
+		return
+	} else if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	templates, err := s.resolveTemplates(ctx, required, templateSelections(params), templateSourceLink(params), lower, upper)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+	for _, t := range templates {
+		if t.ID == tid {
+			encodeJSON(w, http.StatusOK, newTemplateResponse(dash.ID, t), s.Logger)
+			return
+		}
+	}
+	notFound(w, tid, s.Logger)
+}
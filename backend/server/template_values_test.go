@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bouk/httprouter"
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/tempvars"
+)
+
+func TestService_DashboardTemplateValues(t *testing.T) {
+	templateValuesCache = tempvars.NewCache(time.Minute)
+
+	var fluxQueries []string
+	influxdb := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		fluxQueries = append(fluxQueries, body.Query)
+		if r.URL.Path != "/api/v2/query" || r.URL.Query().Get("org") != "snet" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("#datatype,string,long,string\r\n,result,table,_value\r\n,_result,0,web01\r\n,_result,0,web02\r\n\r\n"))
+	}))
+	defer influxdb.Close()
+
+	dashboard := cloudhub.Dashboard{
+		ID: 1,
+		Templates: []cloudhub.Template{
+			{
+				TemplateVar: cloudhub.TemplateVar{Var: ":host:", Values: []cloudhub.TemplateValue{}},
+				ID:          "host",
+				Type:        "influxql",
+				Query:       &cloudhub.TemplateQuery{Command: `SHOW TAG VALUES ON "telegraf" FROM :measurement: WITH KEY = "host"`},
+			},
+			{
+				TemplateVar: cloudhub.TemplateVar{Var: ":measurement:", Values: []cloudhub.TemplateValue{{Value: "cpu", Type: "measurement", Selected: true}}},
+				ID:          "measurement",
+				Type:        "measurements",
+				Query:       &cloudhub.TemplateQuery{Command: "SHOW MEASUREMENTS ON :database:", DB: "telegraf"},
+			},
+			{
+				TemplateVar: cloudhub.TemplateVar{Var: ":fluxhost:", Values: []cloudhub.TemplateValue{}},
+				ID:          "fluxhost",
+				Type:        "flux",
+				Query:       &cloudhub.TemplateQuery{Flux: `from(bucket: "telegraf") |> filter(fn: (r) => r._measurement == :measurement:)`},
+			},
+			{
+				TemplateVar: cloudhub.TemplateVar{Var: ":a:", Values: []cloudhub.TemplateValue{}},
+				ID:          "a",
+				Type:        "influxql",
+				Query:       &cloudhub.TemplateQuery{Command: "SHOW TAG KEYS FROM :b:"},
+			},
+			{
+				TemplateVar: cloudhub.TemplateVar{Var: ":b:", Values: []cloudhub.TemplateValue{}},
+				ID:          "b",
+				Type:        "influxql",
+				Query:       &cloudhub.TemplateQuery{Command: "SHOW MEASUREMENTS WITH MEASUREMENT =~ /:a:/"},
+			},
+		},
+	}
+
+	var queries []string
+	s := &Service{
+		Store: &mocks.Store{
+			DashboardsStore: &mocks.DashboardsStore{
+				GetF: func(ctx context.Context, id cloudhub.DashboardID) (cloudhub.Dashboard, error) {
+					return dashboard, nil
+				},
+			},
+			SourcesStore: &mocks.SourcesStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Source, error) {
+					return []cloudhub.Source{{ID: 7, Default: true, URL: influxdb.URL, Username: "snet"}}, nil
+				},
+				GetF: func(ctx context.Context, id int) (cloudhub.Source, error) {
+					return cloudhub.Source{}, cloudhub.ErrSourceNotFound
+				},
+			},
+		},
+		TimeSeriesClient: &mocks.TimeSeries{
+			ConnectF: func(context.Context, *cloudhub.Source) error {
+				return nil
+			},
+			QueryF: func(ctx context.Context, q cloudhub.Query) (cloudhub.Response, error) {
+				queries = append(queries, q.Command)
+				if strings.HasPrefix(q.Command, "SHOW MEASUREMENTS") {
+					return mocks.NewResponse(`[{"series": [{"name": "measurements", "columns": ["name"], "values": [["cpu"], ["mem"]]}]}]`, nil), nil
+				}
+				return mocks.NewResponse(`[{"series": [{"name": "mem", "columns": ["key", "value"], "values": [["host", "db01"], ["host", "db02"]]}]}]`, nil), nil
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+
+	values := func(tid, params string) (int, templateResponse) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "http://any.url/cloudhub/v1/dashboards/1/templates/"+tid+"/values?"+params, nil)
+		r = r.WithContext(httprouter.WithParams(context.Background(), httprouter.Params{{Key: "id", Value: "1"}, {Key: "tid", Value: tid}}))
+		s.DashboardTemplateValues(w, r)
+		var res templateResponse
+		if w.Code == http.StatusOK {
+			body, _ := ioutil.ReadAll(w.Body)
+			if err := json.Unmarshal(body, &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, res
+	}
+
+	code, res := values("host", ":measurement:=mem&:host:=db02")
+	if code != http.StatusOK {
+		t.Fatalf("DashboardTemplateValues() status = %d", code)
+	}
+	want := []string{`SHOW MEASUREMENTS ON "telegraf"`, `SHOW TAG VALUES ON "telegraf" FROM "mem" WITH KEY = "host"`}
+	if strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DashboardTemplateValues() queries = %q, want %q", queries, want)
+	}
+	if len(res.Values) != 2 || res.Values[1].Value != "db02" || !res.Values[1].Selected || res.Values[1].Type != "influxql" {
+		t.Errorf("DashboardTemplateValues() values = %#v", res.Values)
+	}
+	if res.Links.Self != "/cloudhub/v1/dashboards/1/templates/host" {
+		t.Errorf("DashboardTemplateValues() links = %#v", res.Links)
+	}
+
+	// the values of meta queries are cached
+	if code, _ = values("measurement", ""); code != http.StatusOK || len(queries) != 2 {
+		t.Errorf("DashboardTemplateValues() status = %d, queries %q", code, queries)
+	}
+
+	code, res = values("fluxhost", "")
+	if code != http.StatusOK || len(res.Values) != 2 || !res.Values[0].Selected || res.Values[0].Type != "flux" {
+		t.Errorf("DashboardTemplateValues() of flux status = %d, values %#v", code, res.Values)
+	}
+	if len(fluxQueries) != 1 || !strings.Contains(fluxQueries[0], `r._measurement == "cpu"`) {
+		t.Errorf("DashboardTemplateValues() flux queries = %q", fluxQueries)
+	}
+
+	if code, _ = values("a", ""); code != http.StatusUnprocessableEntity {
+		t.Errorf("DashboardTemplateValues() of cyclic templates status = %d", code)
+	}
+	if code, _ = values("unknown", ""); code != http.StatusNotFound {
+		t.Errorf("DashboardTemplateValues() of an unknown template status = %d", code)
+	}
+}
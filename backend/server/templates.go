@@ -125,7 +125,10 @@ func (s *Service) NewTemplate(w http.ResponseWriter, r *http.Request) {
 	template.ID = cloudhub.TemplateID(tid)
 
 	dash.Templates = append(dash.Templates, template)
-	if err := s.Store.Dashboards(ctx).Update(ctx, dash); err != nil {
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), dash); err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error adding template %s to dashboard %d: %v", tid, id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -197,7 +200,10 @@ func (s *Service) RemoveTemplate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	dash.Templates = append(dash.Templates[:pos], dash.Templates[pos+1:]...)
-	if err := s.Store.Dashboards(ctx).Update(ctx, dash); err != nil {
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), dash); err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error removing template %s from dashboard %d: %v", tid, id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -251,7 +257,10 @@ func (s *Service) ReplaceTemplate(w http.ResponseWriter, r *http.Request) {
 	template.ID = cloudhub.TemplateID(tid)
 
 	dash.Templates[pos] = template
-	if err := s.Store.Dashboards(ctx).Update(ctx, dash); err != nil {
+	if err := s.Store.Dashboards(ctx).Update(revisionContext(r), dash); err == cloudhub.ErrFolderForbidden {
+		folderForbidden(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error updating template %s in dashboard %d: %v", tid, id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -53,6 +53,15 @@ type WindowResize struct {
 
 // WebTerminalHandler connects websocket and remote ssh
 func (s *Service) WebTerminalHandler(w http.ResponseWriter, r *http.Request) {
+	if org, ok := hasOrganizationContext(r.Context()); ok {
+		limit := quotaLimit(s.orgQuotas(r.Context(), org), quotaTerminalSessions)
+		if !terminalSessions.acquire(org, limit) {
+			quotaExceeded(w, &quotaError{Organization: org, Resource: quotaTerminalSessions, Limit: limit}, s.Logger)
+			return
+		}
+		defer terminalSessions.release(org)
+	}
+
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		s.Logger.
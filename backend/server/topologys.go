@@ -18,6 +18,7 @@ type topologyResponse struct {
 	Diagram         string                   `json:"diagram,omitempty"`
 	Preferences     []string                 `json:"preferences,omitempty"`
 	TopologyOptions cloudhub.TopologyOptions `json:"topologyOptions,omitempty"`
+	Version         uint64                   `json:"version"`
 }
 
 // RequestBody represents the structure of the request payload
@@ -42,6 +43,7 @@ func newTopologyResponse(t *cloudhub.Topology, resDiagram bool) *topologyRespons
 			IPMIVisible:       t.TopologyOptions.IPMIVisible,
 			LinkVisible:       t.TopologyOptions.LinkVisible,
 		},
+		Version: t.Version,
 	}
 
 	if resDiagram {
@@ -82,6 +84,7 @@ func (s *Service) Topology(w http.ResponseWriter, r *http.Request) {
 	}
 
 	res := newTopologyResponse(topology, true)
+	setETag(w, topology.Version)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -131,6 +134,11 @@ func (s *Service) NewTopology(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.checkQuota(ctx, topology.Organization, quotaTopologies, 1); err != nil {
+		quotaExceeded(w, err, s.Logger)
+		return
+	}
+
 	res, err := s.Store.Topologies(ctx).Add(ctx, topology)
 	if err != nil {
 		invalidData(w, err, s.Logger)
@@ -162,7 +170,15 @@ func (s *Service) RemoveTopology(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.Store.Topologies(ctx).Delete(ctx, topology); err != nil {
+	if topology.Version, err = deleteVersion(r, topology.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+
+	if err := s.Store.Topologies(ctx).Delete(trashContext(r), topology); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err != nil {
 		unknownErrorWithMessage(w, err, s.Logger)
 		return
 	}
@@ -219,7 +235,15 @@ func (s *Service) UpdateTopology(w http.ResponseWriter, r *http.Request) {
 		LinkVisible:       requestData.TopologyOptions.LinkVisible,
 	}
 
-	if err := s.Store.Topologies(ctx).Update(ctx, topology); err != nil {
+	if topology.Version, err = expectedVersion(r, topology.Version); err != nil {
+		versionError(w, err, s.Logger)
+		return
+	}
+
+	if err := s.Store.Topologies(ctx).Update(revisionContext(r), topology); err == cloudhub.ErrVersionConflict {
+		preconditionFailed(w, s.Logger)
+		return
+	} else if err != nil {
 		msg := fmt.Sprintf("Error updating topology ID %s: %v", id, err)
 		Error(w, http.StatusInternalServerError, msg, s.Logger)
 		return
@@ -231,6 +255,7 @@ func (s *Service) UpdateTopology(w http.ResponseWriter, r *http.Request) {
 	s.logRegistration(ctx, "Topologies", msg)
 
 	res := newTopologyResponse(topology, false)
+	setETag(w, topology.Version)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
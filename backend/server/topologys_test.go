@@ -76,7 +76,7 @@ func TestTopology(t *testing.T) {
 			},
 			wantStatus:      http.StatusOK,
 			wantContentType: "application/json",
-			wantBody:        `{"id":"1","organization":"225","links":{"self":"/cloudhub/v1/topologies/1"},"diagram":"\u003cxml\u003e\u003c/xml\u003e","preferences":["type:inlet,active:1,min:15,max:30","type:inside,active:0,min:38,max:55","type:outlet,active:0,min:30,max:50"],"topologyOptions":{"minimapVisible":true,"hostStatusVisible":false,"ipmiVisible":true,"linkVisible":true}}`,
+			wantBody:        `{"id":"1","organization":"225","links":{"self":"/cloudhub/v1/topologies/1"},"diagram":"\u003cxml\u003e\u003c/xml\u003e","preferences":["type:inlet,active:1,min:15,max:30","type:inside,active:0,min:38,max:55","type:outlet,active:0,min:30,max:50"],"topologyOptions":{"minimapVisible":true,"hostStatusVisible":false,"ipmiVisible":true,"linkVisible":true},"version":0}`,
 		},
 	}
 
@@ -177,7 +177,7 @@ func TestUpdateTopology(t *testing.T) {
 			id:              "1",
 			wantStatus:      http.StatusOK,
 			wantContentType: "application/json",
-			wantBody:        `{"id":"1","organization":"225","links":{"self":"/cloudhub/v1/topologies/1"},"preferences":["type:inlet,active:1,min:15,max:30","type:inside,active:0,min:38,max:55","type:outlet,active:0,min:30,max:50"],"topologyOptions":{"minimapVisible":true,"hostStatusVisible":false,"ipmiVisible":true,"linkVisible":true}}`,
+			wantBody:        `{"id":"1","organization":"225","links":{"self":"/cloudhub/v1/topologies/1"},"preferences":["type:inlet,active:1,min:15,max:30","type:inside,active:0,min:38,max:55","type:outlet,active:0,min:30,max:50"],"topologyOptions":{"minimapVisible":true,"hostStatusVisible":false,"ipmiVisible":true,"linkVisible":true},"version":0}`,
 		},
 	}
 
@@ -393,7 +393,7 @@ func TestNewTopology(t *testing.T) {
 			},
 			wantStatus:      http.StatusCreated,
 			wantContentType: "application/json",
-			wantBody:        `{"id":"1","links":{"self":"/cloudhub/v1/topologies/1"},"organization":"225","preferences":["type:inlet,active:1,min:15,max:30","type:inside,active:0,min:38,max:55","type:outlet,active:0,min:30,max:50"],"topologyOptions":{"minimapVisible":true,"hostStatusVisible":false,"ipmiVisible":true,"linkVisible":true}}`,
+			wantBody:        `{"id":"1","links":{"self":"/cloudhub/v1/topologies/1"},"organization":"225","preferences":["type:inlet,active:1,min:15,max:30","type:inside,active:0,min:38,max:55","type:outlet,active:0,min:30,max:50"],"topologyOptions":{"minimapVisible":true,"hostStatusVisible":false,"ipmiVisible":true,"linkVisible":true},"version":0}`,
 		},
 		{
 			name: "Fail to create topology - no body",
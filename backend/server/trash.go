@@ -0,0 +1,231 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// trashPurgeInterval is how often expired trash items are purged
+const trashPurgeInterval = time.Hour
+
+type trashItemLinks struct {
+	Self    string `json:"self"`    // Self link mapping to this resource
+	Restore string `json:"restore"` // Restore link to put the resource back
+}
+
+type trashItemResponse struct {
+	cloudhub.TrashItem
+	Links trashItemLinks `json:"links"`
+}
+
+type trashResponse struct {
+	Items []trashItemResponse `json:"items"`
+}
+
+func newTrashItemResponse(t cloudhub.TrashItem) trashItemResponse {
+	self := fmt.Sprintf("/cloudhub/v1/trash/%s", t.ID)
+	return trashItemResponse{
+		TrashItem: t,
+		Links: trashItemLinks{
+			Self:    self,
+			Restore: self + "/restore",
+		},
+	}
+}
+
+// trashContext returns the request context carrying the name of the user
+// recorded as the deleter of the resources trashed by the request.
+func trashContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	var deleter string
+	if u, ok := hasUserContext(ctx); ok {
+		deleter = u.Name
+	}
+	return context.WithValue(ctx, cloudhub.TrashContextKey, deleter)
+}
+
+// Trash returns the deleted resources of the current organization, newest first,
+// but the dashboards of the folders the role of the user may not view.
+// The optional `type` query parameter filters the items by resource type.
+func (s *Service) Trash(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	items, err := s.Store.Trash(ctx).All(ctx)
+	if err != nil {
+		Error(w, http.StatusInternalServerError, "Error loading trash", s.Logger)
+		return
+	}
+	visible, err := s.folderVisible(ctx)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	kind := r.URL.Query().Get("type")
+	res := trashResponse{
+		Items: []trashItemResponse{},
+	}
+	for i := len(items) - 1; i >= 0; i-- {
+		if (kind != "" && items[i].ResourceType != kind) || !visible(items[i].Folder) {
+			continue
+		}
+		res.Items = append(res.Items, newTrashItemResponse(items[i]))
+	}
+
+	encodeJSON(w, http.StatusOK, res, s.Logger)
+}
+
+// visibleTrashItem returns a trash item unless it is a dashboard of a folder
+// the role of the user may not view, responding with the error otherwise
+func (s *Service) visibleTrashItem(ctx context.Context, w http.ResponseWriter, id string) (*cloudhub.TrashItem, bool) {
+	item, err := s.Store.Trash(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return nil, false
+	}
+	visible, err := s.folderVisible(ctx)
+	if err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return nil, false
+	}
+	if !visible(item.Folder) {
+		notFound(w, id, s.Logger)
+		return nil, false
+	}
+	return item, true
+}
+
+// TrashItemID returns a single deleted resource
+func (s *Service) TrashItemID(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	item, ok := s.visibleTrashItem(ctx, w, id)
+	if !ok {
+		return
+	}
+
+	encodeJSON(w, http.StatusOK, newTrashItemResponse(*item), s.Logger)
+}
+
+// RestoreTrashItem puts a deleted resource back under its original ID, with the
+// telegraf config of OSP CSPs
+func (s *Service) RestoreTrashItem(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	item, ok := s.visibleTrashItem(ctx, w, id)
+	if !ok {
+		return
+	}
+
+	if err := s.Store.Trash(ctx).Restore(ctx, item); err == cloudhub.ErrTrashRestoreConflict {
+		Error(w, http.StatusConflict, err.Error(), s.Logger)
+		return
+	} else if err != nil {
+		msg := fmt.Sprintf("Error restoring trash item %s: %v", id, err)
+		Error(w, http.StatusInternalServerError, msg, s.Logger)
+		return
+	}
+
+	// the telegraf config of an OSP CSP removed on delete is generated again, as when it is added
+	if item.ResourceType == cloudhub.TrashCSP {
+		csp, err := s.Store.CSP(ctx).Get(ctx, cloudhub.CSPQuery{ID: &item.ResourceID})
+		if err != nil {
+			unknownErrorWithMessage(w, err, s.Logger)
+			return
+		}
+		if csp.Provider == cloudhub.OSP {
+			statusCode, resp, err := s.generateTelegrafConfigForOSP(ctx, csp)
+			if err != nil {
+				unknownErrorWithMessage(w, err, s.Logger)
+				return
+			} else if statusCode < http.StatusOK || statusCode >= http.StatusMultipleChoices {
+				Error(w, statusCode, string(resp), s.Logger)
+				return
+			}
+		}
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgTrashRestored.String(), item.ResourceType, trashItemName(item))
+	s.logRegistration(ctx, "Trash", msg)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveTrashItem permanently deletes a resource from the trash
+func (s *Service) RemoveTrashItem(w http.ResponseWriter, r *http.Request) {
+	id, err := paramStr("id", r)
+	if err != nil {
+		invalidData(w, err, s.Logger)
+		return
+	}
+
+	ctx := r.Context()
+	item, err := s.Store.Trash(ctx).Get(ctx, id)
+	if err != nil {
+		notFound(w, id, s.Logger)
+		return
+	}
+
+	if err := s.Store.Trash(ctx).Delete(ctx, item); err != nil {
+		unknownErrorWithMessage(w, err, s.Logger)
+		return
+	}
+
+	// log registration
+	msg := fmt.Sprintf(MsgTrashRemoved.String(), item.ResourceType, trashItemName(item))
+	s.logRegistration(ctx, "Trash", msg)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// trashItemName returns the name of the deleted resource, or its ID if it has none
+func trashItemName(t *cloudhub.TrashItem) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.ResourceID
+}
+
+// purgeTrash periodically removes the trash items deleted longer than retention ago.
+// A retention of zero or less disables purging.
+func purgeTrash(ctx context.Context, store DataStore, retention time.Duration, logger cloudhub.Logger) {
+	if retention <= 0 {
+		return
+	}
+
+	l := logger.
+		WithField("component", "trash").
+		WithField("retention", retention.String())
+	serverCtx := serverContext(ctx)
+
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		n, err := store.Trash(serverCtx).Purge(serverCtx, time.Now().Add(-retention))
+		if err != nil {
+			l.Error("Unable to purge trash: ", err)
+		} else if n > 0 {
+			l.Info("Purged ", n, " expired trash items")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
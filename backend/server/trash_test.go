@@ -0,0 +1,213 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+	"github.com/snetsystems/cloudhub/backend/mocks"
+	"github.com/snetsystems/cloudhub/backend/roles"
+)
+
+func TestService_Trash(t *testing.T) {
+	items := []cloudhub.TrashItem{
+		{ID: "1", ResourceType: cloudhub.TrashDashboard, ResourceID: "3", Name: "cpu"},
+		{ID: "2", ResourceType: cloudhub.TrashCSP, ResourceID: "7", Name: "aws/seoul"},
+		{ID: "3", ResourceType: cloudhub.TrashDashboard, ResourceID: "4", Name: "mem"},
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantIDs []string
+	}{
+		{
+			name:    "newest first",
+			url:     "http://any.url/cloudhub/v1/trash",
+			wantIDs: []string{"3", "2", "1"},
+		},
+		{
+			name:    "filtered by type",
+			url:     "http://any.url/cloudhub/v1/trash?type=dashboard",
+			wantIDs: []string{"3", "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				Store: &mocks.Store{
+					TrashStore: &mocks.TrashStore{
+						AllF: func(ctx context.Context) ([]cloudhub.TrashItem, error) {
+							return items, nil
+						},
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("GET", tt.url, nil)
+			s.Trash(w, r)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Trash() status = %d: %s", w.Code, w.Body.String())
+			}
+			var res trashResponse
+			if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range res.Items {
+				got = append(got, item.ID)
+			}
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("Trash() ids = %v, want %v", got, tt.wantIDs)
+			}
+			for i := range got {
+				if got[i] != tt.wantIDs[i] {
+					t.Errorf("Trash() ids = %v, want %v", got, tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestService_RestoreTrashItem(t *testing.T) {
+	tests := []struct {
+		name         string
+		resourceType string
+		provider     string
+		restore      error
+		wantCode     int
+	}{
+		{
+			name:     "restores an item",
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:         "restores a CSP",
+			resourceType: cloudhub.TrashCSP,
+			provider:     cloudhub.AWS,
+			wantCode:     http.StatusNoContent,
+		},
+		{
+			name:         "OSP CSP fails to restore without Salt to generate its telegraf config",
+			resourceType: cloudhub.TrashCSP,
+			provider:     cloudhub.OSP,
+			wantCode:     http.StatusInternalServerError,
+		},
+		{
+			name:     "conflicting resource",
+			restore:  cloudhub.ErrTrashRestoreConflict,
+			wantCode: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{
+				Store: &mocks.Store{
+					TrashStore: &mocks.TrashStore{
+						GetF: func(ctx context.Context, id string) (*cloudhub.TrashItem, error) {
+							return &cloudhub.TrashItem{ID: id, ResourceType: tt.resourceType, ResourceID: "7", DeletedAt: time.Now()}, nil
+						},
+						RestoreF: func(ctx context.Context, item *cloudhub.TrashItem) error {
+							return tt.restore
+						},
+					},
+					CSPStore: &mocks.CSPStore{
+						GetF: func(ctx context.Context, q cloudhub.CSPQuery) (*cloudhub.CSP, error) {
+							return &cloudhub.CSP{ID: *q.ID, Provider: tt.provider, NameSpace: "tenant"}, nil
+						},
+					},
+				},
+				Logger: &mocks.TestLogger{},
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest("POST", "http://any.url", nil)
+			r = WithContext(r.Context(), r, map[string]string{
+				"id": "1",
+			})
+			s.RestoreTrashItem(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("RestoreTrashItem() status = %d, want %d", w.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestService_Trash_FolderPermissions(t *testing.T) {
+	items := []cloudhub.TrashItem{
+		{ID: "1", ResourceType: cloudhub.TrashDashboard, ResourceID: "3", Name: "cpu"},
+		{ID: "2", ResourceType: cloudhub.TrashDashboard, ResourceID: "4", Name: "secrets", Folder: "2"},
+	}
+	var restored []string
+	s := &Service{
+		Store: &mocks.Store{
+			TrashStore: &mocks.TrashStore{
+				AllF: func(ctx context.Context) ([]cloudhub.TrashItem, error) {
+					return items, nil
+				},
+				GetF: func(ctx context.Context, id string) (*cloudhub.TrashItem, error) {
+					for _, item := range items {
+						if item.ID == id {
+							return &item, nil
+						}
+					}
+					return nil, cloudhub.ErrTrashItemNotFound
+				},
+				RestoreF: func(ctx context.Context, item *cloudhub.TrashItem) error {
+					restored = append(restored, item.ID)
+					return nil
+				},
+			},
+			FoldersStore: &mocks.FoldersStore{
+				AllF: func(ctx context.Context) ([]cloudhub.Folder, error) {
+					return []cloudhub.Folder{
+						{ID: "2", Organization: "default", Permissions: cloudhub.FolderPermissions{View: roles.AdminRoleName}},
+					}, nil
+				},
+			},
+		},
+		Logger: &mocks.TestLogger{},
+	}
+	asRole := func(r *http.Request, role string) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), roles.ContextKey, role))
+	}
+
+	w := httptest.NewRecorder()
+	s.Trash(w, asRole(httptest.NewRequest("GET", "http://any.url/cloudhub/v1/trash", nil), roles.EditorRoleName))
+	var res trashResponse
+	if err := json.NewDecoder(w.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Items) != 1 || res.Items[0].ID != "1" {
+		t.Errorf("Trash() of an editor = %#v, want the dashboard outside of the restricted folder", res.Items)
+	}
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest("GET", "http://any.url", nil)
+	s.TrashItemID(w, asRole(WithContext(r.Context(), r, map[string]string{"id": "2"}), roles.EditorRoleName))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("TrashItemID() of a restricted dashboard status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "http://any.url", nil)
+	s.RestoreTrashItem(w, asRole(WithContext(r.Context(), r, map[string]string{"id": "2"}), roles.EditorRoleName))
+	if w.Code != http.StatusNotFound || len(restored) != 0 {
+		t.Errorf("RestoreTrashItem() of a restricted dashboard status = %d, restored %v", w.Code, restored)
+	}
+
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest("POST", "http://any.url", nil)
+	s.RestoreTrashItem(w, asRole(WithContext(r.Context(), r, map[string]string{"id": "2"}), roles.AdminRoleName))
+	if w.Code != http.StatusNoContent || len(restored) != 1 {
+		t.Errorf("RestoreTrashItem() of an admin status = %d, restored %v", w.Code, restored)
+	}
+}
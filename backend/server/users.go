@@ -8,7 +8,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"sort"
 	"strconv"
 	"time"
 
@@ -182,10 +181,6 @@ func newUsersResponse(users []cloudhub.User, org string) *usersResponse {
 	for i, user := range users {
 		usersResp[i] = newUserResponse(&user, org, "")
 	}
-	sort.Slice(usersResp, func(i, j int) bool {
-		return usersResp[i].ID < usersResp[j].ID
-	})
-
 	var selfLink string
 	if org != "" {
 		selfLink = fmt.Sprintf("/cloudhub/v1/organizations/%s/users", org)
@@ -760,18 +755,46 @@ func (s *Service) OrganizationUpdateUser(w http.ResponseWriter, r *http.Request)
 	encodeJSON(w, http.StatusOK, cu, s.Logger)
 }
 
+// userListFields are the fields the list of users can be filtered and sorted by.
+// role is the role of a user in the organization of the request.
+func userListFields(org string) listFields[cloudhub.User] {
+	return listFields[cloudhub.User]{
+		"name":       func(u cloudhub.User) string { return u.Name },
+		"provider":   func(u cloudhub.User) string { return u.Provider },
+		"scheme":     func(u cloudhub.User) string { return u.Scheme },
+		"email":      func(u cloudhub.User) string { return u.Email },
+		"superAdmin": func(u cloudhub.User) string { return strconv.FormatBool(u.SuperAdmin) },
+		"locked":     func(u cloudhub.User) string { return strconv.FormatBool(u.Locked) },
+		"role": func(u cloudhub.User) string {
+			for _, role := range u.Roles {
+				if role.Organization == org {
+					return role.Name
+				}
+			}
+			return ""
+		},
+	}
+}
+
 // Users retrieves all CloudHub users from store
 func (s *Service) Users(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	users, err := s.Store.Users(ctx).All(ctx)
+	orgID := httprouter.GetParamFromContext(ctx, "oid")
+	fields := userListFields(orgID)
+	opts, err := newListOptions(r, fields)
 	if err != nil {
-		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		Error(w, http.StatusUnprocessableEntity, err.Error(), s.Logger)
 		return
 	}
 
-	orgID := httprouter.GetParamFromContext(ctx, "oid")
-	res := newUsersResponse(users, orgID)
+	page, next, err := listPage(ctx, s.Store.Users(ctx), opts, fields, func(u cloudhub.User) string { return strconv.FormatUint(u.ID, 10) }, nil)
+	if err != nil {
+		Error(w, http.StatusBadRequest, err.Error(), s.Logger)
+		return
+	}
+	res := newUsersResponse(page, orgID)
+	setNextLink(w, r, next)
 	encodeJSON(w, http.StatusOK, res, s.Logger)
 }
 
@@ -0,0 +1,79 @@
+package tempvars
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the values kept by caches
+const maxCacheEntries = 1000
+
+type cacheEntry struct {
+	values  []string
+	expires time.Time
+}
+
+// Cache keeps the values of the queries of templates for a while, as the UI does
+type Cache struct {
+	TTL time.Duration
+	Now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+// NewCache returns a cache of the values of queries for ttl
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{TTL: ttl, Now: time.Now}
+}
+
+// Fetcher returns a fetcher of values caching the values fetched by f in the scope,
+// e.g. the source the queries run on
+func (c *Cache) Fetcher(scope string, f Fetcher) Fetcher {
+	return FetcherFunc(func(ctx context.Context, query string, flux bool) ([]string, error) {
+		key := scope + "\x00" + query
+		if flux {
+			key = "flux\x00" + key
+		}
+		if values, ok := c.get(key); ok {
+			return values, nil
+		}
+		values, err := f.Fetch(ctx, query, flux)
+		if err != nil {
+			return nil, err
+		}
+		c.set(key, values)
+		return append([]string(nil), values...), nil
+	})
+}
+
+func (c *Cache) get(key string) ([]string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || !c.Now().Before(e.expires) {
+		return nil, false
+	}
+	return append([]string(nil), e.values...), true
+}
+
+func (c *Cache) set(key string, values []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.Now()
+	if c.entries == nil {
+		c.entries = map[string]cacheEntry{}
+	}
+	if len(c.entries) >= maxCacheEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			c.entries = map[string]cacheEntry{}
+		}
+	}
+	c.entries[key] = cacheEntry{values: append([]string(nil), values...), expires: now.Add(c.TTL)}
+}
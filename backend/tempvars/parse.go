@@ -0,0 +1,214 @@
+package tempvars
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type metaResult struct {
+	Series []struct {
+		Name    string          `json:"name"`
+		Columns []string        `json:"columns"`
+		Values  [][]interface{} `json:"values"`
+	} `json:"series"`
+	Error string `json:"error"`
+}
+
+// metaQueries are the InfluxQL meta queries of templates
+var metaQueries = []string{
+	"SHOW DATABASES",
+	"SHOW MEASUREMENTS",
+	"SHOW SERIES",
+	"SHOW TAG VALUES",
+	"SHOW FIELD KEYS",
+	"SHOW TAG KEYS",
+}
+
+// metaQueryPrefix returns the meta query the query starts with
+func metaQueryPrefix(query string) (string, bool) {
+	words := strings.Split(strings.ToUpper(strings.TrimSpace(query)), " ")
+	for _, n := range []int{2, 3} {
+		if len(words) < n {
+			break
+		}
+		prefix := strings.Join(words[:n], " ")
+		for _, q := range metaQueries {
+			if q == prefix {
+				return q, true
+			}
+		}
+	}
+	return "", false
+}
+
+// IsMetaQuery returns true if the query is an InfluxQL meta query of which values
+// of templates are parsed
+func IsMetaQuery(query string) bool {
+	_, ok := metaQueryPrefix(query)
+	return ok
+}
+
+// ParseMetaQuery returns the values of templates of the results of an InfluxQL meta
+// query, as returned by InfluxDB
+func ParseMetaQuery(query string, data []byte) ([]string, error) {
+	prefix, ok := metaQueryPrefix(query)
+	if !ok {
+		return nil, fmt.Errorf("could not find parser for meta query")
+	}
+	var results []metaResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return []string{}, nil
+	}
+
+	values := []string{}
+	switch prefix {
+	case "SHOW DATABASES", "SHOW SERIES", "SHOW TAG KEYS":
+		r := results[0]
+		if r.Error != "" {
+			return nil, fmt.Errorf("%s", r.Error)
+		}
+		if len(r.Series) > 0 {
+			values = column(r.Series[0].Columns, r.Series[0].Values, "")
+		}
+	case "SHOW MEASUREMENTS", "SHOW FIELD KEYS":
+		name := "name"
+		if prefix == "SHOW FIELD KEYS" {
+			name = "fieldKey"
+		}
+		for _, r := range results {
+			if r.Error != "" {
+				return nil, fmt.Errorf("%s", r.Error)
+			}
+			if len(r.Series) > 0 {
+				values = append(values, column(r.Series[0].Columns, r.Series[0].Values, name)...)
+			}
+		}
+	case "SHOW TAG VALUES":
+		// the values of each key are sorted and unique, the keys in order
+		var keys []string
+		tags := map[string][]string{}
+		for _, s := range results[0].Series {
+			ks := column(s.Columns, s.Values, "key")
+			vs := column(s.Columns, s.Values, "value")
+			for i := range ks {
+				if i >= len(vs) {
+					break
+				}
+				if _, ok := tags[ks[i]]; !ok {
+					keys = append(keys, ks[i])
+				}
+				tags[ks[i]] = append(tags[ks[i]], vs[i])
+			}
+		}
+		for _, k := range keys {
+			values = append(values, unique(tags[k])...)
+		}
+	}
+	return values, nil
+}
+
+// column returns the values of the named column, or of the first column if name is
+// empty, as strings
+func column(columns []string, rows [][]interface{}, name string) []string {
+	index := 0
+	if name != "" {
+		index = -1
+		for i, c := range columns {
+			if c == name {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil
+		}
+	}
+	values := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if index < len(row) {
+			values = append(values, fmt.Sprint(row[index]))
+		}
+	}
+	return values
+}
+
+func unique(values []string) []string {
+	sort.Strings(values)
+	res := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// ParseFlux returns the values of templates of the annotated CSV results of a flux
+// query: the _value column of the first table
+func ParseFlux(data []byte) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	var header []string
+	valueIndex, tableIndex := -1, -1
+	table := ""
+	values := []string{}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(row) == 0 || strings.HasPrefix(row[0], "#") {
+			continue
+		}
+		if header == nil {
+			header = row
+			for i, c := range header {
+				switch c {
+				case "_value":
+					valueIndex = i
+				case "table":
+					tableIndex = i
+				case "error":
+					if i == 1 {
+						return nil, parseFluxError(r)
+					}
+				}
+			}
+			if valueIndex < 0 {
+				return nil, fmt.Errorf("no _value column found")
+			}
+			continue
+		}
+		if len(row) <= valueIndex {
+			// the header of another table
+			break
+		}
+		if tableIndex >= 0 && tableIndex < len(row) {
+			if table == "" {
+				table = row[tableIndex]
+			} else if row[tableIndex] != table {
+				break
+			}
+		}
+		values = append(values, row[valueIndex])
+	}
+	return values, nil
+}
+
+// parseFluxError returns the error of the CSV results of a failed flux query
+func parseFluxError(r *csv.Reader) error {
+	row, err := r.Read()
+	if err != nil || len(row) < 2 {
+		return fmt.Errorf("flux query failed")
+	}
+	return fmt.Errorf("%s", row[1])
+}
@@ -0,0 +1,325 @@
+// Package tempvars resolves the values of the template variables of dashboards as
+// the CloudHub UI does: the templates are ordered by their dependencies, the
+// queries of the templates run with the values selected for the templates they
+// depend on, and the selections are applied to the values found.
+package tempvars
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+// valueTypes are the types of the values of the templates, by template type
+var valueTypes = map[string]string{
+	"csv":          "csv",
+	"map":          "map",
+	"databases":    "database",
+	"measurements": "measurement",
+	"fieldKeys":    "fieldKey",
+	"tagKeys":      "tagKey",
+	"tagValues":    "tagValue",
+	"influxql":     "influxql",
+	"flux":         "flux",
+	"text":         "constant",
+}
+
+// Fetcher runs the query of a template and returns the values it finds
+type Fetcher interface {
+	Fetch(ctx context.Context, query string, flux bool) ([]string, error)
+}
+
+// FetcherFunc is a function fetching the values of queries
+type FetcherFunc func(ctx context.Context, query string, flux bool) ([]string, error)
+
+// Fetch calls f
+func (f FetcherFunc) Fetch(ctx context.Context, query string, flux bool) ([]string, error) {
+	return f(ctx, query, flux)
+}
+
+// HasQuery returns true if the values of the template are the results of its query
+func HasQuery(t cloudhub.Template) bool {
+	switch t.Type {
+	case "databases", "measurements", "fieldKeys", "tagKeys", "tagValues", "influxql":
+		return t.Query != nil && t.Query.Command != ""
+	case "flux":
+		return t.Query != nil && t.Query.Flux != ""
+	}
+	return false
+}
+
+// MetaQuery returns the query of the template with its database, measurement and
+// tag key, before the template variables it refers to are replaced
+func MetaQuery(t cloudhub.Template) string {
+	switch {
+	case t.Query == nil:
+		return ""
+	case t.Type == "influxql":
+		// the database, measurement and tag key are always empty in custom meta
+		// queries, which may refer to templates of the same names
+		return t.Query.Command
+	case t.Type == "flux":
+		return t.Query.Flux
+	}
+	q := t.Query.Command
+	q = strings.Replace(q, ":database:", `"`+t.Query.DB+`"`, 1)
+	q = strings.Replace(q, ":measurement:", `"`+t.Query.Measurement+`"`, 1)
+	q = strings.Replace(q, ":tagKey:", `"`+t.Query.TagKey+`"`, 1)
+	return q
+}
+
+// Dependencies returns the names of the template variables the template refers
+// to in its meta query, or in its values if it has no query
+func Dependencies(t cloudhub.Template) []string {
+	if q := MetaQuery(t); q != "" {
+		return variableNames(q)
+	}
+	var names []string
+	seen := map[string]bool{}
+	for _, v := range t.Values {
+		for _, name := range variableNames(v.Value) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// variableNames returns the names of the template variables in s, e.g. :host:
+func variableNames(s string) []string {
+	var names []string
+	var name strings.Builder
+	inName := false
+	for _, c := range s {
+		switch {
+		case inName && c == ':':
+			name.WriteRune(c)
+			names = append(names, name.String())
+			inName = false
+		case inName && (c == '\n' || (c == ' ' && name.Len() == 1)):
+			// names do not start with a space nor have new lines, as in flux
+			// where ':' separates parameter names and record values
+			inName = false
+		case inName:
+			name.WriteRune(c)
+		case c == ':':
+			name.Reset()
+			name.WriteRune(c)
+			inName = true
+		}
+	}
+	return names
+}
+
+// Sort returns the templates in the order of their resolution: each template comes
+// after the templates it depends on. Templates with cyclic dependencies are an error.
+func Sort(templates []cloudhub.Template) ([]cloudhub.Template, error) {
+	byVar := map[string]int{}
+	for i, t := range templates {
+		byVar[t.Var] = i
+	}
+
+	const (
+		unseen = iota
+		visiting
+		done
+	)
+	state := make([]int, len(templates))
+	sorted := make([]cloudhub.Template, 0, len(templates))
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch state[i] {
+		case visiting:
+			return fmt.Errorf("cyclic dependency in template %s", templates[i].Var)
+		case done:
+			return nil
+		}
+		state[i] = visiting
+		for _, name := range Dependencies(templates[i]) {
+			if j, ok := byVar[name]; ok {
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		state[i] = done
+		sorted = append(sorted, templates[i])
+		return nil
+	}
+	for i := range templates {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// Required returns the template of the ID and the templates it depends on, directly
+// or through other templates, in the order of their resolution
+func Required(templates []cloudhub.Template, id cloudhub.TemplateID) ([]cloudhub.Template, error) {
+	byVar := map[string]cloudhub.Template{}
+	for _, t := range templates {
+		byVar[t.Var] = t
+	}
+
+	required := map[string]bool{}
+	var require func(t cloudhub.Template)
+	require = func(t cloudhub.Template) {
+		if required[t.Var] {
+			return
+		}
+		required[t.Var] = true
+		for _, name := range Dependencies(t) {
+			if dep, ok := byVar[name]; ok {
+				require(dep)
+			}
+		}
+	}
+	found := false
+	for _, t := range templates {
+		if t.ID == id {
+			require(t)
+			found = true
+		}
+	}
+	if !found {
+		return nil, cloudhub.ErrTemplateNotFound
+	}
+
+	var res []cloudhub.Template
+	for _, t := range templates {
+		if required[t.Var] {
+			res = append(res, t)
+		}
+	}
+	return Sort(res)
+}
+
+// Resolve returns the templates with their values resolved in the order of their
+// dependencies. The queries of the templates are expanded by expand with the
+// templates resolved before them and run by fetcher. selections are the values to
+// select by template variable, the keys of the values for maps.
+func Resolve(ctx context.Context, templates []cloudhub.Template, selections map[string]string, expand func(query string, resolved []cloudhub.Template) string, fetcher Fetcher) ([]cloudhub.Template, error) {
+	sorted, err := Sort(templates)
+	if err != nil {
+		return nil, err
+	}
+
+	byVar := map[string]cloudhub.Template{}
+	resolved := make([]cloudhub.Template, 0, len(sorted))
+	for _, t := range sorted {
+		selection, hasSelection := selections[t.Var]
+		if HasQuery(t) {
+			query := expand(MetaQuery(t), resolved)
+			values, err := fetcher.Fetch(ctx, query, t.Type == "flux")
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %v", t.Var, err)
+			}
+			t.Values = queryValues(t, values, selection)
+		} else {
+			t.Values = constantValues(t, selection, hasSelection)
+		}
+		byVar[t.Var] = t
+		resolved = append(resolved, t)
+	}
+
+	res := make([]cloudhub.Template, len(templates))
+	for i, t := range templates {
+		res[i] = byVar[t.Var]
+	}
+	return res, nil
+}
+
+// queryValues returns the values found by the query of a template. The selection
+// is selected if found, else the value selected before, else the first value.
+func queryValues(t cloudhub.Template, values []string, selection string) []cloudhub.TemplateValue {
+	res := []cloudhub.TemplateValue{}
+	if len(values) == 0 {
+		return res
+	}
+	selected := values[0]
+	if previous, ok := selectedValue(t); ok && contains(values, previous.Value) {
+		selected = previous.Value
+	}
+	if contains(values, selection) {
+		selected = selection
+	}
+	for _, v := range values {
+		res = append(res, cloudhub.TemplateValue{
+			Value:    v,
+			Type:     valueTypes[t.Type],
+			Selected: v == selected,
+		})
+	}
+	return res
+}
+
+// constantValues returns the values of a template without query with the selection
+// selected. Templates of text take any value, csv and maps one of their own.
+func constantValues(t cloudhub.Template, selection string, hasSelection bool) []cloudhub.TemplateValue {
+	switch t.Type {
+	case "csv", "map", "constant":
+		if len(t.Values) == 0 {
+			return []cloudhub.TemplateValue{}
+		}
+		selected := -1
+		for i, v := range t.Values {
+			key := v.Value
+			if t.Type == "map" {
+				key = v.Key
+			}
+			if hasSelection && key == selection {
+				selected = i
+			}
+		}
+		if selected < 0 {
+			selected = 0
+			for i, v := range t.Values {
+				if v.Selected {
+					selected = i
+					break
+				}
+			}
+		}
+		res := make([]cloudhub.TemplateValue, len(t.Values))
+		for i, v := range t.Values {
+			v.Selected = i == selected
+			res[i] = v
+		}
+		return res
+	}
+
+	if !hasSelection {
+		if len(t.Values) == 0 {
+			return []cloudhub.TemplateValue{}
+		}
+		return t.Values
+	}
+	valueType := valueTypes[t.Type]
+	if len(t.Values) > 0 {
+		valueType = t.Values[0].Type
+	}
+	return []cloudhub.TemplateValue{{Value: selection, Type: valueType, Selected: true}}
+}
+
+func selectedValue(t cloudhub.Template) (cloudhub.TemplateValue, bool) {
+	for _, v := range t.Values {
+		if v.Selected {
+			return v, true
+		}
+	}
+	return cloudhub.TemplateValue{}, false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
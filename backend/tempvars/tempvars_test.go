@@ -0,0 +1,218 @@
+package tempvars
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	cloudhub "github.com/snetsystems/cloudhub/backend"
+)
+
+func template(id, name, typ, query string, values ...string) cloudhub.Template {
+	t := cloudhub.Template{
+		TemplateVar: cloudhub.TemplateVar{Var: name, Values: []cloudhub.TemplateValue{}},
+		ID:          cloudhub.TemplateID(id),
+		Type:        typ,
+	}
+	if query != "" {
+		t.Query = &cloudhub.TemplateQuery{Command: query}
+	}
+	for i, v := range values {
+		t.Values = append(t.Values, cloudhub.TemplateValue{Value: v, Type: valueTypes[typ], Selected: i == 0})
+	}
+	return t
+}
+
+func TestSort(t *testing.T) {
+	templates := []cloudhub.Template{
+		template("3", ":host:", "influxql", `SHOW TAG VALUES ON :db: FROM :measurement: WITH KEY = "host"`),
+		template("2", ":measurement:", "influxql", "SHOW MEASUREMENTS ON :db:"),
+		template("1", ":db:", "databases", "SHOW DATABASES"),
+		template("4", ":region:", "csv", "", "eu", "us"),
+	}
+	sorted, err := Sort(templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, t := range sorted {
+		got = append(got, t.Var)
+	}
+	if want := []string{":db:", ":measurement:", ":host:", ":region:"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort() = %v, want %v", got, want)
+	}
+
+	required, err := Required(templates, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(required) != 2 || required[0].Var != ":db:" || required[1].Var != ":measurement:" {
+		t.Errorf("Required() = %v", required)
+	}
+	if _, err := Required(templates, "5"); err != cloudhub.ErrTemplateNotFound {
+		t.Errorf("Required() of an unknown template error = %v", err)
+	}
+
+	cyclic := []cloudhub.Template{
+		template("1", ":a:", "influxql", "SHOW TAG VALUES WITH KEY = :b:"),
+		template("2", ":b:", "influxql", "SHOW TAG KEYS FROM :a:"),
+	}
+	if _, err := Sort(cyclic); err == nil || !strings.Contains(err.Error(), "cyclic") {
+		t.Errorf("Sort() of cyclic templates error = %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	db := template("1", ":db:", "databases", "SHOW DATABASES", "telegraf")
+	db.Query.DB = "ignored"
+	templates := []cloudhub.Template{
+		template("2", ":host:", "tagValues", "SHOW TAG VALUES ON :database: FROM :measurement: WITH KEY=:tagKey:", "web01"),
+		db,
+		template("3", ":region:", "map", ""),
+		template("4", ":text:", "text", "", "hello"),
+	}
+	templates[0].Query.DB = ":db:"
+	templates[0].Query.Measurement = "cpu"
+	templates[0].Query.TagKey = "host"
+	templates[2].Values = []cloudhub.TemplateValue{
+		{Key: "Europe", Value: "eu", Type: "map", Selected: true},
+		{Key: "America", Value: "us", Type: "map"},
+	}
+
+	var fetched []string
+	fetcher := FetcherFunc(func(ctx context.Context, query string, flux bool) ([]string, error) {
+		fetched = append(fetched, query)
+		if strings.HasPrefix(query, "SHOW DATABASES") {
+			return []string{"_internal", "telegraf"}, nil
+		}
+		return []string{"web01", "web02", "web03"}, nil
+	})
+	expand := func(query string, resolved []cloudhub.Template) string {
+		for _, t := range resolved {
+			for _, v := range t.Values {
+				if v.Selected {
+					query = strings.Replace(query, t.Var, v.Value, -1)
+				}
+			}
+		}
+		return query
+	}
+
+	selections := map[string]string{":host:": "web02", ":region:": "America", ":text:": "anything"}
+	resolved, err := Resolve(context.Background(), templates, selections, expand, fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SHOW DATABASES", `SHOW TAG VALUES ON "telegraf" FROM "cpu" WITH KEY="host"`}
+	if !reflect.DeepEqual(fetched, want) {
+		t.Errorf("Resolve() fetched %q, want %q", fetched, want)
+	}
+
+	selected := map[string]string{}
+	for _, t := range resolved {
+		for _, v := range t.Values {
+			if v.Selected {
+				selected[t.Var] = v.Value
+			}
+		}
+	}
+	if want := map[string]string{":db:": "telegraf", ":host:": "web02", ":region:": "us", ":text:": "anything"}; !reflect.DeepEqual(selected, want) {
+		t.Errorf("Resolve() selected %v, want %v", selected, want)
+	}
+	if len(resolved[0].Values) != 3 || resolved[0].Values[0].Type != "tagValue" || resolved[0].ID != "2" {
+		t.Errorf("Resolve() host = %#v", resolved[0])
+	}
+
+	// selections missing from the values found fall back to the values selected before
+	resolved, err = Resolve(context.Background(), templates, map[string]string{":db:": "unknown"}, expand, fetcher)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := resolved[1].Values; len(v) != 2 || v[0].Selected || !v[1].Selected {
+		t.Errorf("Resolve() db = %#v", v)
+	}
+}
+
+func TestParseMetaQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		data  string
+		want  []string
+	}{
+		{"SHOW DATABASES", `[{"series": [{"name": "databases", "columns": ["name"], "values": [["_internal"], ["telegraf"]]}]}]`, []string{"_internal", "telegraf"}},
+		{"show measurements on telegraf", `[{"series": [{"name": "measurements", "columns": ["name"], "values": [["cpu"], ["mem"]]}]}]`, []string{"cpu", "mem"}},
+		{"SHOW FIELD KEYS FROM cpu", `[{"series": [{"name": "cpu", "columns": ["fieldKey", "fieldType"], "values": [["usage_user", "float"]]}]}]`, []string{"usage_user"}},
+		{"SHOW TAG KEYS FROM cpu", `[{"series": [{"name": "cpu", "columns": ["tagKey"], "values": [["cpu"], ["host"]]}]}]`, []string{"cpu", "host"}},
+		{"SHOW TAG VALUES WITH KEY = host", `[{"series": [{"name": "cpu", "columns": ["key", "value"], "values": [["host", "web02"], ["host", "web01"]]}, {"name": "mem", "columns": ["key", "value"], "values": [["host", "web01"]]}]}]`, []string{"web01", "web02"}},
+		{"SHOW TAG VALUES WITH KEY = host", `[{}]`, []string{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseMetaQuery(tt.query, []byte(tt.data))
+		if err != nil {
+			t.Fatalf("ParseMetaQuery(%s) error = %v", tt.query, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseMetaQuery(%s) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+
+	if _, err := ParseMetaQuery("SELECT * FROM cpu", []byte(`[]`)); err == nil {
+		t.Errorf("ParseMetaQuery() parsed a query which is not a meta query")
+	}
+	if _, err := ParseMetaQuery("SHOW DATABASES", []byte(`[{"error": "unauthorized"}]`)); err == nil || err.Error() != "unauthorized" {
+		t.Errorf("ParseMetaQuery() error = %v", err)
+	}
+}
+
+func TestParseFlux(t *testing.T) {
+	data := "#group,false,false,true,false\r\n" +
+		"#datatype,string,long,string,string\r\n" +
+		"#default,_result,,,\r\n" +
+		",result,table,_field,_value\r\n" +
+		",,0,host,web01\r\n" +
+		",,0,host,web02\r\n" +
+		",,1,host,db01\r\n" +
+		"\r\n"
+	got, err := ParseFlux([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"web01", "web02"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFlux() = %q, want %q", got, want)
+	}
+
+	if _, err := ParseFlux([]byte(",result,table,host\r\n,,0,web01\r\n")); err == nil {
+		t.Errorf("ParseFlux() of results without _value column did not fail")
+	}
+	if _, err := ParseFlux([]byte(",error,reference\r\n,bucket not found,\r\n")); err == nil || err.Error() != "bucket not found" {
+		t.Errorf("ParseFlux() error = %v", err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	now := time.Date(2023, 11, 14, 0, 0, 0, 0, time.UTC)
+	c := NewCache(time.Minute)
+	c.Now = func() time.Time { return now }
+
+	calls := 0
+	f := c.Fetcher("1", FetcherFunc(func(ctx context.Context, query string, flux bool) ([]string, error) {
+		calls++
+		return []string{query}, nil
+	}))
+	for i := 0; i < 2; i++ {
+		f.Fetch(context.Background(), "SHOW DATABASES", false)
+	}
+	f.Fetch(context.Background(), "SHOW DATABASES", true)
+	c.Fetcher("2", f).Fetch(context.Background(), "SHOW DATABASES", false)
+	if calls != 2 {
+		t.Errorf("Fetcher() fetched %d times, want 2", calls)
+	}
+
+	now = now.Add(time.Minute)
+	f.Fetch(context.Background(), "SHOW DATABASES", false)
+	if calls != 3 {
+		t.Errorf("Fetcher() did not fetch values expired")
+	}
+}
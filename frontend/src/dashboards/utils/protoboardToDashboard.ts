@@ -44,8 +44,8 @@ const createTemplatesForProtoboard = (pbTemplates, source): Template[] => {
 
 const replaceQuery = (q: string, source: Source) =>
   q
-    .replace(':db:', source.telegraf || 'telegraf')
-    .replace(':rp:', source.defaultRP || 'autogen')
+    .replace(/:db:/g, source.telegraf || 'telegraf')
+    .replace(/:rp:/g, source.defaultRP || 'autogen')
 
 const replaceDbRp = (queries: CellQuery[], source: Source) =>
   queries.map(q => ({...q, query: replaceQuery(q.query, source)}))
@@ -76,6 +76,7 @@ export const instantiateProtoboard = (
     name: protoboard.meta.name,
     cells,
     templates,
+    protoboard: protoboard.id,
   }
 
   return dashboard
@@ -140,6 +140,7 @@ export interface Dashboard {
   templates: Template[]
   name: string
   organization: string
+  protoboard?: string
   links?: DashboardLinks
 }
 